@@ -9,6 +9,7 @@ import os
 from datetime import datetime
 from pathlib import Path
 from typing import Dict, List, Optional
+from zoneinfo import ZoneInfo
 
 import httpx
 from flask import Flask, jsonify, redirect, render_template, request, session
@@ -29,6 +30,8 @@ DOMINO_API_URL = os.environ.get("DOMINO_API_URL", "")
 # (runs, workspaces, projects, etc.) in reports.  Should be the external hostname,
 # e.g. https://my-domino.example.com.
 DOMINO_URL = os.environ.get("DOMINO_URL", "")
+# Time zone report times are shown in (IANA name, as reports.timezone in config.yaml)
+REPORT_TIMEZONE = ZoneInfo(os.environ.get("REPORT_TIMEZONE") or "UTC")
 
 
 # Flask app setup
@@ -140,6 +143,7 @@ _USER_FACING_REPORT_PREFIXES = (
     "old-revisions",
     "image-size-report",
     "user-size-report",
+    "chargeback-report",
 )
 
 
@@ -159,7 +163,7 @@ def get_report_files() -> List[Dict]:
             {
                 "name": file_path.name,
                 "size": format_bytes(stat.st_size),
-                "modified": datetime.fromtimestamp(stat.st_mtime).strftime("%Y-%m-%d %H:%M:%S"),
+                "modified": datetime.fromtimestamp(stat.st_mtime, REPORT_TIMEZONE).strftime("%Y-%m-%d %H:%M:%S %Z"),
                 "timestamp": stat.st_mtime,
             }
         )
@@ -231,6 +235,8 @@ def view_report(filename):
         report_type = "archived_tags"
     elif "unused-environments" in filename:
         report_type = "unused_environments"
+    elif "chargeback" in filename:
+        report_type = "chargeback"
     elif "deletion" in filename:
         report_type = "deletion_results"
     elif "final-report" in filename:
@@ -315,6 +321,43 @@ def proxy_cancel_job(job_id):
         return jsonify({"error": "Backend API is unavailable"}), 503
 
 
+@app.route("/api/schedules", methods=["GET"])
+def proxy_list_schedules():
+    """Proxy: GET /api/schedules → backend"""
+    try:
+        resp = httpx.get(f"{BACKEND_API_URL}/api/schedules", headers=_backend_headers(), timeout=10)
+        return jsonify(resp.json()), resp.status_code
+    except httpx.ConnectError:
+        return jsonify({"error": "Backend API is unavailable"}), 503
+
+
+@app.route("/api/deletion-queue", methods=["GET"])
+def proxy_list_deletion_queue():
+    """Proxy: GET /api/deletion-queue → backend"""
+    try:
+        resp = httpx.get(
+            f"{BACKEND_API_URL}/api/deletion-queue",
+            headers=_backend_headers(),
+            params=request.args,
+            timeout=10,
+        )
+        return jsonify(resp.json()), resp.status_code
+    except httpx.ConnectError:
+        return jsonify({"error": "Backend API is unavailable"}), 503
+
+
+@app.route("/api/deletion-queue/<item_id>", methods=["DELETE"])
+def proxy_cancel_queued_deletion(item_id):
+    """Proxy: DELETE /api/deletion-queue/{item_id} → backend"""
+    try:
+        resp = httpx.delete(
+            f"{BACKEND_API_URL}/api/deletion-queue/{item_id}", headers=_backend_headers(), timeout=10
+        )
+        return jsonify(resp.json()), resp.status_code
+    except httpx.ConnectError:
+        return jsonify({"error": "Backend API is unavailable"}), 503
+
+
 # ── Health ─────────────────────────────────────────────────────────────────────
 
 
@@ -13,6 +13,26 @@ unset, auth is skipped (useful for local development).
 Jobs are tracked in memory; the last MAX_JOBS entries are kept. Restarting the
 container clears the history — that is intentional for a single-replica
 StatefulSet.
+
+Schedules: phases configured under schedule in config.yaml (scan, plan,
+apply, mirror) are started as jobs at their cron times; GET /api/schedules lists them.
+
+Deletion queue: when security.deletion_delay_hours is set, approved deletions
+(destructive operations requested with apply) are not started at once but
+queued, and started once the delay has passed. The queue is kept on the
+reports volume so that it survives restarts; pending items can be listed with
+GET /api/deletion-queue and cancelled with DELETE /api/deletion-queue/{item_id}.
+
+Alerting: when PagerDuty or Opsgenie is configured under alerting, every
+finished scheduled run is checked for failure, its registry request error rate
+and the space the latest plan would reclaim, and alerts are raised and resolved
+accordingly (see utils/alerting.py).
+
+Registry notifications: when registry_events.enabled is set, a self-hosted
+registry can POST its push and delete notifications to /api/registry-events.
+The server scans once on startup and then keeps its image index up to date from
+those events, saving scan snapshots as it changes (see utils/registry_events.py);
+GET /api/registry-events reports the listener's state.
 """
 
 import json
@@ -23,16 +43,26 @@ import subprocess
 import sys
 import tempfile
 import threading
+import time
 import uuid
 from collections import OrderedDict
-from datetime import datetime, timezone
+from datetime import datetime, timedelta, timezone
 from pathlib import Path
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, List, Optional, Tuple
 
-from fastapi import Depends, FastAPI, Header, HTTPException, Response, status
+from fastapi import Depends, FastAPI, Header, HTTPException, Request, Response, status
 from prometheus_client import CONTENT_TYPE_LATEST, Gauge, generate_latest
 from pydantic import BaseModel
 
+# Add python directory to path so we can import utils
+_python_dir = Path(__file__).parent.absolute()
+if str(_python_dir) not in sys.path:
+    sys.path.insert(0, str(_python_dir))
+
+from utils.alerting import Alert, evaluate_run, send_alert
+from utils.deletion_queue import DeletionQueue
+from utils.report_utils import REPORT_COMPRESSIONS, find_report_file, load_json
+
 _API_KEY_HEADER: Optional[str] = Header(default=None)
 
 # ── Configuration ─────────────────────────────────────────────────────────────
@@ -41,6 +71,8 @@ BACKEND_API_KEY: str = os.environ.get("BACKEND_API_KEY", "")
 _MAIN_PY: Path = Path(__file__).parent / "main.py"
 MAX_JOBS: int = 50
 OUTPUT_DIR: Path = Path(os.environ.get("OUTPUT_DIR", "/data/reports"))
+QUEUE_POLL_SECONDS: int = 60
+SCHEDULE_POLL_SECONDS: int = 20
 
 # Detect once at startup whether the Docker registry is running inside the cluster.
 # Operations that require exec'ing into the registry pod (run_registry_gc) are only
@@ -57,6 +89,31 @@ except Exception as _e:
     logging.warning(f"Could not determine if registry is in-cluster, defaulting to False: {_e}")
     _REGISTRY_IN_CLUSTER = False
 
+try:
+    _DELETION_DELAY_HOURS: float = _cfg.get_deletion_delay_hours()
+except Exception as _e:
+    logging.warning(f"Could not read security.deletion_delay_hours, deletions run without delay: {_e}")
+    _DELETION_DELAY_HOURS = 0.0
+
+try:
+    _SCHEDULES: Dict[str, Dict[str, Any]] = _cfg.get_schedules()
+except Exception as _e:
+    logging.error(f"Invalid schedule configuration, nothing is scheduled: {_e}")
+    _SCHEDULES = {}
+
+try:
+    _ALERT_DESTINATIONS: Dict[str, Dict[str, str]] = _cfg.get_alert_destinations()
+    _ALERT_THRESHOLDS: Dict[str, Any] = _cfg.get_alert_thresholds()
+except Exception as _e:
+    logging.error(f"Invalid alerting configuration, no alerts are sent: {_e}")
+    _ALERT_DESTINATIONS, _ALERT_THRESHOLDS = {}, {}
+
+try:
+    _REGISTRY_EVENTS: Optional[Dict[str, Any]] = _cfg.get_registry_event_settings()
+except Exception as _e:
+    logging.error(f"Invalid registry_events configuration, registry notifications are ignored: {_e}")
+    _REGISTRY_EVENTS = None
+
 # ── Prometheus metrics ─────────────────────────────────────────────────────────
 
 _tags_pending = Gauge(
@@ -79,6 +136,11 @@ _jobs_gauge = Gauge(
     "Tracked jobs by operation and status",
     ["operation", "status"],
 )
+_queue_gauge = Gauge(
+    "registry_cleaner_deletion_queue_pending",
+    "Approved deletions waiting in the deletion queue per operation",
+    ["operation"],
+)
 
 # Map operation name → (report filename, summary.tags_key, summary.space_gb_key)
 # Both report types nest their summary under data["summary"].
@@ -92,11 +154,11 @@ _REPORT_FIELDS: Dict[str, tuple] = {
 def _refresh_report_metrics() -> None:
     """Read the latest dry-run report files and update pending-deletion gauges."""
     for operation, (filename, tags_key, space_key) in _REPORT_FIELDS.items():
-        path = OUTPUT_DIR / filename
-        if not path.exists():
+        path = find_report_file(OUTPUT_DIR / filename)
+        if path is None:
             continue
         try:
-            data = json.loads(path.read_text())
+            data = load_json(path)
             summary = data.get("summary", data)
             _tags_pending.labels(operation=operation).set(summary.get(tags_key, 0))
             _space_recoverable.labels(operation=operation).set(summary.get(space_key, 0) * 1024**3)
@@ -117,6 +179,15 @@ def _refresh_job_metrics() -> None:
         _jobs_gauge.labels(operation=operation, status=job_status).set(count)
 
 
+def _refresh_queue_metrics() -> None:
+    """Count pending deletion queue items by operation and update the queue gauge."""
+    counts: Dict[str, int] = {operation: 0 for operation in OPERATIONS if OPERATIONS[operation]["destructive"]}
+    for item in _deletion_queue.list_items(pending_only=True):
+        counts[item["operation"]] = counts.get(item["operation"], 0) + 1
+    for operation, count in counts.items():
+        _queue_gauge.labels(operation=operation).set(count)
+
+
 # ── Operation catalogue ────────────────────────────────────────────────────────
 # Each entry declares:
 #   description  – shown in the UI
@@ -153,6 +224,20 @@ OPERATIONS: Dict[str, Dict[str, Any]] = {
                 "default": False,
                 "help": "Force regeneration of image analysis",
             },
+            {
+                "name": "fast",
+                "flag": "--fast",
+                "type": "bool",
+                "default": False,
+                "help": "Approximate triage scan using manifests and cached inspections",
+            },
+            {
+                "name": "sizes_only",
+                "flag": "--sizes-only",
+                "type": "bool",
+                "default": False,
+                "help": "Storage accounting scan: layer sizes from one manifest request per tag",
+            },
         ],
     },
     "user_size_report": {
@@ -168,6 +253,140 @@ OPERATIONS: Dict[str, Dict[str, Any]] = {
             },
         ],
     },
+    "repository_summary_report": {
+        "description": "Summarize each repository: tag count, unique vs shared layer bytes, and largest image",
+        "destructive": False,
+        "params": [
+            {
+                "name": "image_types",
+                "flag": "--image-types",
+                "type": "str",
+                "default": None,
+                "help": "Restrict to one image type (environment or model)",
+            },
+            {
+                "name": "fast",
+                "flag": "--fast",
+                "type": "bool",
+                "default": False,
+                "help": "Approximate triage scan using manifests and cached inspections",
+            },
+            {
+                "name": "sizes_only",
+                "flag": "--sizes-only",
+                "type": "bool",
+                "default": False,
+                "help": "Storage accounting scan: layer sizes from one manifest request per tag",
+            },
+        ],
+    },
+    "orphans_report": {
+        "description": "Report untagged manifests, broken manifests, and unreferenced blobs",
+        "destructive": False,
+        "params": [
+            {
+                "name": "storage_bucket",
+                "flag": "--storage-bucket",
+                "type": "str",
+                "default": None,
+                "help": "S3 bucket the registry stores its data in (enables the full scan)",
+            },
+            {
+                "name": "storage_prefix",
+                "flag": "--storage-prefix",
+                "type": "str",
+                "default": None,
+                "help": "Path of the registry data in the bucket",
+            },
+        ],
+    },
+    "candidates_report": {
+        "description": "Rank images as deletion candidates with estimated and cumulative savings",
+        "destructive": False,
+        "params": [
+            {
+                "name": "image_types",
+                "flag": "--image-types",
+                "type": "str",
+                "default": None,
+                "help": "Restrict to one image type (environment or model)",
+            },
+            {
+                "name": "top",
+                "flag": "--top",
+                "type": "int",
+                "default": None,
+                "help": "Number of candidates to print",
+            },
+            {
+                "name": "skip_usage",
+                "flag": "--skip-usage",
+                "type": "bool",
+                "default": False,
+                "help": "Rank by age and size only, without MongoDB usage data",
+            },
+            {
+                "name": "vulnerabilities",
+                "flag": "--vulnerabilities",
+                "type": "str",
+                "default": None,
+                "help": "JSON file mapping images to known vulnerability counts",
+            },
+        ],
+    },
+    "duplicate_images_report": {
+        "description": "Report duplicate images across namespaces, tag aliases, cross-repository manifests, and duplicate layers",
+        "destructive": False,
+        "params": [
+            {
+                "name": "image_types",
+                "flag": "--image-types",
+                "type": "str",
+                "default": None,
+                "help": "Restrict to one image type (environment or model)",
+            },
+            {
+                "name": "skip_layers",
+                "flag": "--skip-layers",
+                "type": "bool",
+                "default": False,
+                "help": "Skip finding duplicate layers by diffID",
+            },
+        ],
+    },
+    "simulate_deletion": {
+        "description": "Simulate deleting one image: freed layers and bytes, and remaining references for shared layers",
+        "destructive": False,
+        "params": [
+            {
+                "name": "image",
+                "flag": "",
+                "type": "str",
+                "required": True,
+                "help": "Image to simulate deleting, as <type>:<tag> (e.g. environment:<tag>)",
+            },
+        ],
+    },
+    "inspect-size": {
+        "description": "Break an image down layer by layer: size, share of the image, sharing and build steps",
+        "destructive": False,
+        "params": [
+            {
+                "name": "image",
+                "flag": "",
+                "type": "str",
+                "required": True,
+                "help": "Image to break down, as <type>:<tag> (e.g. environment:<tag>)",
+            },
+            {
+                "name": "deep",
+                "flag": "--deep",
+                "type": "bool",
+                "default": False,
+                "help": "Read the image config for build steps and size-reduction suggestions",
+            },
+        ],
+    },
     "find_environment_usage": {
         "description": "Find where a specific environment ID is used across projects, jobs, workspaces, and runs",
         "destructive": False,
@@ -181,6 +400,74 @@ OPERATIONS: Dict[str, Dict[str, Any]] = {
             },
         ],
     },
+    "plan": {
+        "description": "Write a reviewable cleanup plan of unused images (tags, digests, expected bytes freed)",
+        "destructive": False,
+        "params": [
+            {
+                "name": "unused",
+                "flag": "--unused",
+                "type": "bool",
+                "default": True,
+                "help": "Plan images not used by any Domino workload",
+            },
+            {
+                "name": "unused_since_days",
+                "flag": "--unused-since-days",
+                "type": "int",
+                "default": None,
+                "help": "Only consider images unused if not used in the last N days",
+            },
+            {
+                "name": "ignore_expiry",
+                "flag": "--ignore-expiry",
+                "type": "bool",
+                "default": False,
+                "help": "Do not also plan images past the expiry set at build time",
+            },
+            {
+                "name": "generate_reports",
+                "flag": "--generate-reports",
+                "type": "bool",
+                "default": False,
+                "help": "Force regeneration of MongoDB usage reports",
+            },
+        ],
+    },
+    "apply": {
+        "description": "Apply a cleanup plan written by plan, re-checking each image for use and digest changes",
+        "destructive": True,
+        "params": [
+            {
+                "name": "plan_file",
+                "flag": "",
+                "type": "str",
+                "required": True,
+                "help": "Plan file written by plan (e.g. /data/reports/cleanup-plan-<timestamp>.json)",
+            },
+            {
+                "name": "apply",
+                "flag": "--apply",
+                "type": "bool",
+                "default": False,
+                "help": "Actually delete images (default is dry-run)",
+            },
+            {
+                "name": "force",
+                "flag": "--force",
+                "type": "bool",
+                "default": False,
+                "help": "Skip the confirmation prompt, which jobs cannot answer (required with apply)",
+            },
+            {
+                "name": "resume",
+                "flag": "--resume",
+                "type": "bool",
+                "default": False,
+                "help": "Skip the items earlier runs of this plan deleted",
+            },
+        ],
+    },
     "delete_archived_tags": {
         "description": "Find (or delete) Docker tags associated with archived environments and/or models",
         "destructive": True,
@@ -497,6 +784,21 @@ def _trim_jobs() -> None:
         _jobs.popitem(last=False)
 
 
+# ── Deletion queue ─────────────────────────────────────────────────────────────
+
+_deletion_queue = DeletionQueue(OUTPUT_DIR / "deletion-queue.json", max_finished=MAX_JOBS)
+
+
+def _is_deletion(operation: str, params: Dict[str, Any]) -> bool:
+    """Whether a job deletes data: a destructive operation run with apply, or one without a dry-run mode."""
+    op_def = OPERATIONS[operation]
+    if not op_def["destructive"]:
+        return False
+    if any(spec["name"] == "apply" for spec in op_def["params"]):
+        return bool(params.get("apply"))
+    return True
+
+
 # ── Argument builder ───────────────────────────────────────────────────────────
 
 
@@ -516,11 +818,44 @@ def _build_args(operation: str, params: Dict[str, Any]) -> List[str]:
                 args.append(flag)
         elif param_type in ("int", "str", "id_list"):
             if value is not None and str(value).strip() != "":
-                args.extend([flag, str(value)])
+                # An empty flag marks a positional argument
+                args.extend([flag, str(value)] if flag else [str(value)])
 
     return args
 
 
+def _prepare_job(operation: str, params: Dict[str, Any]) -> Tuple[List[str], Optional[str]]:
+    """Validate an operation's params and build its CLI args.
+
+    For id_list params the content is validated and written to a temp file.
+    The temp file path replaces the raw text in params so _build_args can pass
+    it as a --input flag; it is also returned so that the caller can delete it
+    after the process finishes.
+    """
+    # Validate required params
+    for spec in OPERATIONS[operation]["params"]:
+        if spec.get("required") and params.get(spec["name"]) in (None, ""):
+            raise HTTPException(
+                status_code=status.HTTP_400_BAD_REQUEST,
+                detail=f"Missing required parameter '{spec['name']}'",
+            )
+
+    input_tmp_path: Optional[str] = None
+    for spec in OPERATIONS[operation]["params"]:
+        if spec["type"] == "id_list":
+            raw = params.get(spec["name"]) or ""
+            if raw.strip():
+                try:
+                    input_tmp_path = _write_validated_input(raw)
+                    params[spec["name"]] = input_tmp_path
+                except ValueError as exc:
+                    raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc))
+            else:
+                params[spec["name"]] = None  # omit --input flag
+
+    return _build_args(operation, params), input_tmp_path
+
+
 # ── Background job runner ──────────────────────────────────────────────────────
 
 
@@ -565,6 +900,13 @@ def _run_job(job_id: str, cli_args: List[str]) -> None:
             _jobs[job_id]["finished_at"] = datetime.now(timezone.utc).isoformat()
 
     finally:
+        if job.get("phase"):
+            with _jobs_lock:
+                finished_job = {**job, "logs": list(job["logs"])}
+            try:
+                _check_alerts(finished_job)
+            except Exception as exc:
+                logging.error(f"Could not check alerts of scheduled {job['phase']}: {exc}")
         if input_tmp_path:
             try:
                 os.unlink(input_tmp_path)
@@ -572,6 +914,125 @@ def _run_job(job_id: str, cli_args: List[str]) -> None:
                 pass
 
 
+def _start_job(
+    operation: str,
+    params: Dict[str, Any],
+    cli_args: List[str],
+    input_tmp_path: Optional[str],
+    phase: Optional[str] = None,
+) -> str:
+    """Record a new job and run it in a background thread. Returns the job ID.
+
+    phase is set for jobs started by a schedule, whose results are checked for alerts.
+    """
+    job_id = str(uuid.uuid4())
+    now = datetime.now(timezone.utc).isoformat()
+
+    with _jobs_lock:
+        _jobs[job_id] = {
+            "job_id": job_id,
+            "operation": operation,
+            "params": params,
+            "cli_args": cli_args,
+            "status": "pending",
+            "started_at": now,
+            "finished_at": None,
+            "returncode": None,
+            "pid": None,
+            "logs": [],
+            "input_tmp_path": input_tmp_path,
+            "phase": phase,
+        }
+        _trim_jobs()
+
+    thread = threading.Thread(target=_run_job, args=(job_id, cli_args), daemon=True)
+    thread.start()
+    return job_id
+
+
+def _start_due_deletions() -> None:
+    """Start a job for every queued deletion whose delay has passed."""
+    for item in _deletion_queue.take_due():
+        try:
+            cli_args, input_tmp_path = _prepare_job(item["operation"], item["params"])
+            job_id = _start_job(item["operation"], item["params"], cli_args, input_tmp_path)
+            _deletion_queue.set_job(item["item_id"], job_id)
+        except Exception as exc:
+            detail = exc.detail if isinstance(exc, HTTPException) else str(exc)
+            logging.error(f"Could not start queued {item['operation']} ({item['item_id']}): {detail}")
+            _deletion_queue.set_failed(item["item_id"], str(detail))
+
+
+def _run_deletion_queue() -> None:
+    """Start queued deletions as they become due, for the lifetime of the server."""
+    while True:
+        try:
+            _start_due_deletions()
+        except Exception as exc:
+            logging.error(f"Deletion queue error: {exc}")
+        time.sleep(QUEUE_POLL_SECONDS)
+
+
+# ── Alerting ───────────────────────────────────────────────────────────────────
+
+# Report each scheduled phase writes, read for its runStats (and the plan's reclaimable space and owner quotas)
+_PHASE_REPORTS: Dict[str, str] = {
+    "scan": "images-report*.json",
+    "plan": "cleanup-plan*.json",
+    "apply": "plan-apply-results*.json",
+    "mirror": "mirror-results*.json",
+}
+
+# Alert key -> alert currently open in the on-call services
+_open_alerts: Dict[str, Alert] = {}
+_alerts_lock = threading.Lock()
+
+
+def _phase_report(phase: str, since: datetime) -> Optional[Dict[str, Any]]:
+    """Return the newest report a scheduled phase wrote after it started, if any."""
+    patterns = [_PHASE_REPORTS[phase]] + [_PHASE_REPORTS[phase] + suffix for suffix in REPORT_COMPRESSIONS.values()]
+    reports = [p for pattern in patterns for p in OUTPUT_DIR.glob(pattern) if p.stat().st_mtime >= since.timestamp()]
+    if not reports:
+        return None
+    try:
+        return load_json(max(reports, key=lambda p: p.stat().st_mtime))
+    except (OSError, ValueError):
+        return None
+
+
+def _check_alerts(job: Dict[str, Any]) -> None:
+    """Raise or resolve alerts for a finished scheduled run."""
+    if not _ALERT_DESTINATIONS:
+        return
+    phase = job["phase"]
+    report = _phase_report(phase, datetime.fromisoformat(job["started_at"])) or {}
+    summary = report.get("summary") or {}
+    run_stats = report.get("runStats") or summary.get("runStats")
+    reclaimable = report.get("expected_freed_bytes") if phase == "plan" else None
+    owner_quotas = report.get("owner_quotas") if phase == "plan" else None
+
+    registry_url = _cfg.get_registry_url()
+    results = evaluate_run(
+        registry_url,
+        phase,
+        job["returncode"],
+        _ALERT_THRESHOLDS,
+        run_stats,
+        reclaimable,
+        job["logs"][-20:],
+        owner_quotas,
+    )
+    with _alerts_lock:
+        for key, alert in results.items():
+            if alert is not None and key not in _open_alerts:
+                logging.warning(f"Alert: {alert.summary}")
+                if send_alert(_ALERT_DESTINATIONS, alert, registry_url):
+                    _open_alerts[key] = alert
+            elif alert is None and key in _open_alerts:
+                if send_alert(_ALERT_DESTINATIONS, _open_alerts[key], registry_url, resolve=True):
+                    del _open_alerts[key]
+
+
 # ── FastAPI app ────────────────────────────────────────────────────────────────
 
 app = FastAPI(
@@ -582,6 +1043,109 @@ app = FastAPI(
 )
 
 
+# ── Schedules ──────────────────────────────────────────────────────────────────
+
+# CLI args of the scan, plan and mirror phases, before the args configured for them.
+# The apply phase applies the latest plan file.
+_PHASE_ARGS: Dict[str, List[str]] = {
+    "scan": ["reports", "--generate-reports"],
+    "plan": ["plan", "--unused"],
+    "mirror": ["mirror", "--latest-plan"],
+}
+
+# Phase -> next_run (datetime), last_run (ISO string) and last_job_id
+_schedule_state: Dict[str, Dict[str, Any]] = {}
+
+
+def _latest_plan() -> Optional[Path]:
+    """Return the most recent plan file written by plan, if any."""
+    plans = list(OUTPUT_DIR.glob("cleanup-plan*.json"))
+    return max(plans, key=lambda p: p.stat().st_mtime) if plans else None
+
+
+def _run_phase(phase: str) -> None:
+    """Start the job of a scheduled phase, unless its previous run has not finished."""
+    state = _schedule_state[phase]
+    with _jobs_lock:
+        previous = _jobs.get(state["last_job_id"] or "")
+    if previous and previous["status"] in ("pending", "running"):
+        logging.warning(f"Skipping scheduled {phase}: the previous run ({previous['job_id']}) has not finished")
+        return
+
+    if phase == "apply":
+        plan_file = _latest_plan()
+        if plan_file is None:
+            logging.warning(f"Skipping scheduled apply: no plan file in {OUTPUT_DIR}")
+            return
+        params: Dict[str, Any] = {"plan_file": str(plan_file), "apply": True, "force": True}
+        if _DELETION_DELAY_HOURS > 0:
+            _deletion_queue.add("apply", params, timedelta(hours=_DELETION_DELAY_HOURS))
+            return
+        operation, cli_args = "apply", _build_args("apply", params)
+    else:
+        params = {}
+        operation, cli_args = _PHASE_ARGS[phase][0], _PHASE_ARGS[phase] + _SCHEDULES[phase]["args"]
+
+    logging.info(f"Starting scheduled {phase}: {' '.join(cli_args)}")
+    state["last_job_id"] = _start_job(operation, params, cli_args, None, phase=phase)
+
+
+def _run_schedules() -> None:
+    """Start scheduled phases at their cron times, for the lifetime of the server."""
+    while True:
+        now = datetime.now(timezone.utc)
+        for phase, state in _schedule_state.items():
+            if state["next_run"] <= now:
+                state["last_run"] = state["next_run"].isoformat()
+                try:
+                    _run_phase(phase)
+                except Exception as exc:
+                    logging.error(f"Could not start scheduled {phase}: {exc}")
+                state["next_run"] = _SCHEDULES[phase]["cron"].next_after(now)
+        time.sleep(SCHEDULE_POLL_SECONDS)
+
+
+@app.on_event("startup")
+def _start_schedules() -> None:
+    """Start the thread that runs the configured phases at their cron times."""
+    now = datetime.now(timezone.utc)
+    for phase in _SCHEDULES:
+        cron = _SCHEDULES[phase]["cron"]
+        _schedule_state[phase] = {"next_run": cron.next_after(now), "last_run": None, "last_job_id": None}
+        logging.info(f"Scheduled {phase} at '{cron}' (UTC), next run {_schedule_state[phase]['next_run']}")
+    if _schedule_state:
+        threading.Thread(target=_run_schedules, daemon=True).start()
+
+
+@app.on_event("startup")
+def _start_deletion_queue() -> None:
+    """Start the thread that runs queued deletions once their delay has passed."""
+    if _DELETION_DELAY_HOURS > 0 or _deletion_queue.list_items(pending_only=True):
+        threading.Thread(target=_run_deletion_queue, daemon=True).start()
+
+
+# Keeps an image index up to date from registry notifications (registry_events.enabled)
+_event_listener: Optional[Any] = None
+
+
+@app.on_event("startup")
+def _start_registry_event_listener() -> None:
+    """Start the thread that scans the registry once, then applies its push and delete notifications."""
+    global _event_listener
+    if not _REGISTRY_EVENTS:
+        return
+    from utils.image_data_analysis import ImageAnalyzer
+    from utils.registry_events import RegistryEventListener
+
+    _event_listener = RegistryEventListener(
+        ImageAnalyzer(_cfg.get_registry_url(), _cfg.get_repository()),
+        _REGISTRY_EVENTS["image_types"],
+        _REGISTRY_EVENTS["snapshot_interval_seconds"],
+        save_snapshot=ImageAnalyzer.save_snapshot,
+    )
+    threading.Thread(target=_event_listener.run, daemon=True).start()
+
+
 # ── Auth dependency ────────────────────────────────────────────────────────────
 
 
@@ -617,6 +1181,18 @@ class JobDetail(JobSummary):
     pid: Optional[int]
 
 
+class QueueItemSummary(BaseModel):
+    item_id: str
+    operation: str
+    params: Dict[str, Any]
+    status: str
+    queued_at: str
+    execute_after: str
+    finished_at: Optional[str]
+    job_id: Optional[str]
+    error: Optional[str]
+
+
 # ── Routes ─────────────────────────────────────────────────────────────────────
 
 
@@ -637,6 +1213,7 @@ def metrics() -> Response:
     """
     _refresh_report_metrics()
     _refresh_job_metrics()
+    _refresh_queue_metrics()
     return Response(content=generate_latest(), media_type=CONTENT_TYPE_LATEST)
 
 
@@ -661,63 +1238,28 @@ def list_operations() -> Dict[str, Any]:
 
 
 @app.post("/api/jobs", status_code=status.HTTP_202_ACCEPTED, dependencies=[Depends(_check_api_key)])
-def create_job(req: JobRequest) -> Dict[str, str]:
-    """Start a new job and return its ID. The job runs asynchronously."""
+def create_job(req: JobRequest) -> Dict[str, Any]:
+    """Start a new job and return its ID. The job runs asynchronously.
+
+    Approved deletions are queued instead when security.deletion_delay_hours
+    is set; the response then carries the queue item ID and when it will run.
+    """
     if req.operation not in OPERATIONS:
         raise HTTPException(
             status_code=status.HTTP_400_BAD_REQUEST,
             detail=f"Unknown operation '{req.operation}'. Valid operations: {list(OPERATIONS.keys())}",
         )
 
-    # Validate required params
-    for spec in OPERATIONS[req.operation]["params"]:
-        if spec.get("required") and req.params.get(spec["name"]) in (None, ""):
-            raise HTTPException(
-                status_code=status.HTTP_400_BAD_REQUEST,
-                detail=f"Missing required parameter '{spec['name']}'",
-            )
-
-    # For id_list params: validate content and write to a temp file.
-    # The temp file path replaces the raw text in params so _build_args can pass
-    # it as a --input flag.  The path is also stored in the job record so the
-    # background runner can delete it after the process finishes.
-    input_tmp_path: Optional[str] = None
-    for spec in OPERATIONS[req.operation]["params"]:
-        if spec["type"] == "id_list":
-            raw = req.params.get(spec["name"]) or ""
-            if raw.strip():
-                try:
-                    input_tmp_path = _write_validated_input(raw)
-                    req.params[spec["name"]] = input_tmp_path
-                except ValueError as exc:
-                    raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=str(exc))
-            else:
-                req.params[spec["name"]] = None  # omit --input flag
-
-    cli_args = _build_args(req.operation, req.params)
-    job_id = str(uuid.uuid4())
-    now = datetime.now(timezone.utc).isoformat()
-
-    with _jobs_lock:
-        _jobs[job_id] = {
-            "job_id": job_id,
-            "operation": req.operation,
-            "params": req.params,
-            "cli_args": cli_args,
-            "status": "pending",
-            "started_at": now,
-            "finished_at": None,
-            "returncode": None,
-            "pid": None,
-            "logs": [],
-            "input_tmp_path": input_tmp_path,
-        }
-        _trim_jobs()
-
-    thread = threading.Thread(target=_run_job, args=(job_id, cli_args), daemon=True)
-    thread.start()
+    if _DELETION_DELAY_HOURS > 0 and _is_deletion(req.operation, req.params):
+        # Validate now so that a bad request fails here rather than when the delay has passed
+        _, input_tmp_path = _prepare_job(req.operation, dict(req.params))
+        if input_tmp_path:
+            os.unlink(input_tmp_path)
+        item = _deletion_queue.add(req.operation, req.params, timedelta(hours=_DELETION_DELAY_HOURS))
+        return {"queue_item_id": item["item_id"], "execute_after": item["execute_after"]}
 
-    return {"job_id": job_id}
+    cli_args, input_tmp_path = _prepare_job(req.operation, req.params)
+    return {"job_id": _start_job(req.operation, req.params, cli_args, input_tmp_path)}
 
 
 @app.get("/api/jobs", dependencies=[Depends(_check_api_key)])
@@ -791,3 +1333,65 @@ def cancel_job(job_id: str) -> Dict[str, str]:
         _jobs[job_id]["finished_at"] = datetime.now(timezone.utc).isoformat()
 
     return {"message": "Job marked as cancelled"}
+
+
+@app.get("/api/schedules", dependencies=[Depends(_check_api_key)])
+def list_schedules() -> Dict[str, Any]:
+    """Return the cron schedule, next run and last run of each scheduled phase."""
+    return {
+        phase: {
+            "cron": str(_SCHEDULES[phase]["cron"]),
+            "args": _SCHEDULES[phase]["args"],
+            "next_run": state["next_run"].isoformat(),
+            "last_run": state["last_run"],
+            "last_job_id": state["last_job_id"],
+        }
+        for phase, state in _schedule_state.items()
+    }
+
+
+@app.get("/api/deletion-queue", dependencies=[Depends(_check_api_key)])
+def list_deletion_queue(pending_only: bool = True) -> Dict[str, Any]:
+    """Return queued deletions, soonest first (add ?pending_only=false to include finished items)."""
+    items = sorted(_deletion_queue.list_items(pending_only=pending_only), key=lambda item: item["execute_after"])
+    return {
+        "delay_hours": _DELETION_DELAY_HOURS,
+        "items": [QueueItemSummary(**item) for item in items],
+    }
+
+
+@app.delete("/api/deletion-queue/{item_id}", status_code=status.HTTP_200_OK, dependencies=[Depends(_check_api_key)])
+def cancel_queued_deletion(item_id: str) -> Dict[str, str]:
+    """Cancel a queued deletion before it runs."""
+    try:
+        item = _deletion_queue.cancel(item_id)
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_409_CONFLICT, detail=str(exc))
+
+    if item is None:
+        raise HTTPException(status_code=status.HTTP_404_NOT_FOUND, detail="Queue item not found")
+
+    return {"message": f"Queued {item['operation']} cancelled"}
+
+
+@app.post("/api/registry-events", dependencies=[Depends(_check_api_key)])
+async def receive_registry_events(request: Request) -> Dict[str, int]:
+    """Queue the manifest pushes and deletes of a Docker distribution notification envelope."""
+    if _event_listener is None:
+        raise HTTPException(
+            status_code=status.HTTP_503_SERVICE_UNAVAILABLE,
+            detail="Registry notifications are not enabled (registry_events.enabled in config.yaml)",
+        )
+    try:
+        queued = _event_listener.submit(json.loads(await request.body()))
+    except ValueError as exc:
+        raise HTTPException(status_code=status.HTTP_400_BAD_REQUEST, detail=f"Invalid notification: {exc}")
+    return {"queued": queued}
+
+
+@app.get("/api/registry-events", dependencies=[Depends(_check_api_key)])
+def registry_event_status() -> Dict[str, Any]:
+    """Return the state of the registry notification listener."""
+    if _event_listener is None:
+        return {"enabled": False}
+    return {"enabled": True, **_event_listener.status()}
@@ -1,8 +1,11 @@
 import argparse
+import json
 import logging
 import os
 import subprocess
 import sys
+import time
+from datetime import datetime, timezone
 from pathlib import Path
 from typing import Dict, List, Optional
 
@@ -11,36 +14,86 @@ _python_dir = Path(__file__).parent.absolute()
 if str(_python_dir) not in sys.path:
     sys.path.insert(0, str(_python_dir))
 
-from utils.config_manager import config_manager
+from utils.anonymize import ANONYMIZED_DIR, Anonymizer, anonymize_reports, salt_or_random
+from utils.build_info import format_build_info, get_build_info
+from utils.config_manager import ConfigValidationError, config_manager
+from utils.environment_batch import (
+    consolidate_reports,
+    load_environments_file,
+    print_batch_summary,
+    run_environments,
+)
 from utils.health_checks import HealthChecker
 from utils.logging_utils import setup_logging
 from utils.object_id_utils import read_typed_object_ids_from_file
+from utils.read_only import command_refusal
+from utils.report_upload import ReportUploadError, find_run_reports, upload_reports
+from utils.report_utils import save_json
+from utils.run_metadata import start_run
+from utils.shell_completion import LIST_KINDS, SHELLS, collect_scripts, generate_completion, list_cached
+from utils.statsd_metrics import get_statsd
 
 
 def load_script_paths() -> Dict[str, Optional[str]]:
     return {
+        "apply": "scripts/apply.py",
         "archive_unused_environments": "scripts/archive_unused_environments.py",
+        "bench": "scripts/bench.py",
+        "candidates_report": "scripts/candidates_report.py",
+        "chargeback_report": "scripts/chargeback_report.py",
+        "clean": "scripts/clean.py",
+        "compare": "scripts/compare.py",
+        "completion": None,  # Special: prints a shell completion script
         "delete_archived_tags": "scripts/delete_archived_tags.py",
         "delete_image": "scripts/delete_image.py",
         "delete_unused_environments": "scripts/delete_unused_environments.py",
         "delete_unused_private_environments": "scripts/delete_unused_private_environments.py",
         "delete_all_unused_environments": None,  # Special: runs multiple scripts
         "delete_old_revisions": "scripts/delete_old_revisions.py",
+        "diff-registries": "scripts/compare.py",  # Same as compare
         "delete_unused_references": "scripts/delete_unused_references.py",
+        "duplicate_images_report": "scripts/duplicate_images_report.py",
+        "environment_revisions_report": "scripts/environment_revisions_report.py",
         "find_environment_usage": "scripts/find_environment_usage.py",
         "health_check": None,  # Special: runs health checks
+        "hot_repos_report": "scripts/hot_repos_report.py",
         "image_size_report": "scripts/image_size_report.py",
+        "immutability_audit": "scripts/immutability_audit.py",
+        "inspect-size": "scripts/inspect_size.py",
+        "model_versions_report": "scripts/model_versions_report.py",
+        "mirror": "scripts/mirror.py",
         "mongo_cleanup": "scripts/mongo_cleanup.py",
+        "mutable_tags_report": "scripts/mutable_tags_report.py",
+        "naming_audit": "scripts/naming_audit.py",
+        "orphans_report": "scripts/orphans_report.py",
+        "owner_usage_report": "scripts/owner_usage_report.py",
+        "plan": "scripts/plan.py",
+        "policy": "scripts/policy.py",
+        "pull_time_report": "scripts/pull_time_report.py",
+        "query": "scripts/query.py",
         "reports": "scripts/reports.py",
+        "rescan": "scripts/rescan.py",
+        "repository_summary_report": "scripts/repository_summary_report.py",
         "reset_default_environments": "scripts/reset_default_environments.py",
         "run_registry_gc": "scripts/run_registry_gc.py",
+        "set": "scripts/image_set.py",
+        "simulate_deletion": "scripts/simulate_deletion.py",
         "user_size_report": "scripts/user_size_report.py",
+        "version": None,  # Special: prints version and build metadata
+        "watch": "scripts/watch.py",
     }
 
 
 def get_script_descriptions() -> Dict[str, str]:
     return {
+        "apply": "Apply a reviewed cleanup plan produced by plan (dry-run by default)",
+        "clean": "Delete the images a retention policy or tag list selects in one step: plan, then apply (dry-run by default, printing the bytes reclaimed)",
         "archive_unused_environments": "Mark unused environments as archived in MongoDB",
+        "bench": "Benchmark scan, index and plan throughput on a synthetic registry (in memory or pushed to a local registry)",
+        "candidates_report": "Rank images as deletion candidates by age, exclusive size, usage frequency and recency, with estimated and cumulative savings",
+        "chargeback_report": "Bill owners (from image labels) for their amortized registry storage, with cost, exclusive bytes and growth since the previous snapshot; CSV export",
+        "compare": "Compare repositories, tags and digests between two registries (e.g. primary and mirror) and report missing or mismatched content",
+        "completion": "Print a shell completion script (completion bash|zsh|fish)",
         "delete_archived_tags": "Find and optionally delete Docker tags associated with archived environments and/or models",
         "delete_image": "Delete specific Docker image or analyze/delete unused images",
         "delete_unused_environments": "Find and optionally delete environments not used in workspaces, models, or project defaults (auto-generates reports)",
@@ -48,14 +101,36 @@ def get_script_descriptions() -> Dict[str, str]:
         "delete_all_unused_environments": "Run comprehensive unused environment cleanup (unused environments + deactivated user private environments)",
         "delete_old_revisions": "Delete old environment revisions, keeping only the N most recent per environment (default: 5)",
         "delete_unused_references": "Find and optionally delete MongoDB references to non-existent Docker images",
+        "diff-registries": "Same as compare: tags only in one registry and digests that differ per tag, or with --plan whether a backup registry holds every image a cleanup plan deletes",
+        "duplicate_images_report": "Find identical images (same digest) pushed under different environment/model namespaces and suggest a canonicalization plan",
+        "environment_revisions_report": "Map environment image tags to Domino environment names and revision numbers, and whether each revision is still selectable",
         "find_environment_usage": "Find where a specific environment ID is used (projects, jobs, workspaces, runs, workloads)",
         "health_check": "Run health checks and verify system connectivity (registry, MongoDB, Kubernetes, S3)",
         "image_size_report": "Generate a report of the largest images sorted by total size, showing space that would be freed if deleted",
+        "hot_repos_report": "Rank repositories by churn across scan snapshots (tags added and removed, layer bytes added per week) to show where retention policies have the most impact",
+        "immutability_audit": "Cross-check ECR/Harbor tag immutability settings with tags whose digest changed between scan snapshots",
+        "inspect-size": "Break an image down layer by layer: size, share of the image, whether other images share each layer and, with --deep, the build step behind it and size-reduction suggestions",
+        "model_versions_report": "Map model image tags to Domino model names, version numbers and deployment status (running model APIs pin their images)",
+        "mirror": "Copy the images a cleanup plan keeps to a secondary registry (skopeo copy by digest or skopeo sync), e.g. on a schedule",
         "mongo_cleanup": "Simple tag/ObjectID-based Mongo cleanup (consider using delete_unused_references for advanced features)",
+        "mutable_tags_report": "Report floating (latest, stable, prod, ...), re-pushed and aliasing tags, and the retention policy rules that depend on them",
+        "naming_audit": "Report tags that do not follow the expected naming convention (a regex; default: Domino's <ObjectID>-<revision> scheme), per repository",
+        "orphans_report": "Report untagged manifests, broken manifests, and unreferenced blobs (full scan needs registry storage access)",
+        "owner_usage_report": "Attribute images and their exclusive, shared and amortized registry bytes to owners from image labels, for chargeback",
+        "plan": "Select images for deletion and write them to a versioned plan file for review",
+        "policy": "Validate a retention policy or test it against a saved scan snapshot offline (policy validate|test)",
+        "pull_time_report": "Estimate cold-pull time and bytes per image from compressed layer sizes and a link speed, and list the slowest images to pull",
         "reports": "Generate tag usage reports from analysis data (auto-generates metadata)",
+        "repository_summary_report": "Generate a per-repository summary of tags, unique and shared layer bytes, and largest image (environment vs model)",
         "reset_default_environments": "Unset default environments for users and organizations (userPreferences.defaultEnvironmentId, organizations.defaultV2EnvironmentId)",
         "run_registry_gc": "Run Docker registry garbage collection inside the registry pod",
+        "set": "Combine saved image selections (snapshots, plans, candidates reports, protected images ConfigMaps, candidate files) by tag or digest (set union|intersect|subtract)",
+        "simulate_deletion": "Simulate deleting an image: layers that become unreferenced, bytes freed, and remaining references for shared layers",
         "user_size_report": "Generate a report of image sizes grouped by user/owner, showing who is using the most space",
+        "version": "Print the version, commit, build date and detected skopeo version (version [--json])",
+        "rescan": "Refresh only the given repositories or tags of the latest saved scan snapshot against the registry and regenerate reports, without a full scan (rescan --repo environment --tags tagA,tagB)",
+        "query": "Query images (by size, age, repository, tag) and layers (by frequency) of the latest saved scan snapshot without touching the registry (query images|layers)",
+        "watch": "Rescan the registry at an interval (incrementally) and print only new, removed and re-pushed tags and the storage delta",
     }
 
 
@@ -83,16 +158,92 @@ def run_script(script_path: str, args: List[str], dry_run: bool = True) -> None:
         logging.info(f"Running script: {script_path}")
         logging.info(f"Arguments: {args}")
 
-        subprocess.run([sys.executable, script_path] + args, check=True)
+        started = time.monotonic()
+        result = subprocess.run([sys.executable, script_path] + args)
+        statsd = get_statsd()
+        if statsd is not None:
+            tags = {"command": Path(script_path).stem}
+            statsd.timing("run.duration", (time.monotonic() - started) * 1000, tags)
+            statsd.count("run.completed", 1, {**tags, "status": "success" if result.returncode == 0 else "failure"})
+        result.check_returncode()
 
     except subprocess.CalledProcessError as e:
         logging.error(f"Error running script {script_path}: {e}")
-        sys.exit(1)
+        # Keep the script's status, which tells automation what kind of failure it was
+        sys.exit(e.returncode if e.returncode > 0 else 1)
     except FileNotFoundError:
         logging.error(f"Script not found: {script_path}")
         sys.exit(1)
 
 
+def pop_option(args: List[str], option: str) -> Optional[str]:
+    """Remove --option VALUE or --option=VALUE from a script's arguments, returning the value"""
+    for i, arg in enumerate(args):
+        if arg.startswith(f"{option}="):
+            del args[i]
+            return arg.split("=", 1)[1]
+        if arg == option and i + 1 < len(args):
+            value = args[i + 1]
+            del args[i : i + 2]
+            return value
+    return None
+
+
+def pop_anonymize(args: List[str]) -> bool:
+    """Remove --anonymize from a script's arguments, returning whether it was there"""
+    if "--anonymize" in args:
+        args.remove("--anonymize")
+        return True
+    return False
+
+
+def anonymize_run_reports(run_started: datetime) -> Path:
+    """Save anonymized copies of the reports a run wrote, returning their folder"""
+    reports_dir = Path(config_manager.get_output_dir())
+    anonymized_dir = reports_dir / ANONYMIZED_DIR
+    hosts = [config_manager.get_registry_url()]
+    anonymizer = Anonymizer(salt_or_random(config_manager.get_anonymize_salt()), hosts)
+    copies = anonymize_reports(anonymizer, find_run_reports(reports_dir, run_started.timestamp()), anonymized_dir)
+    logging.info(f"Saved {len(copies)} anonymized report(s) to {anonymized_dir}")
+    return anonymized_dir
+
+
+def upload_run_reports(
+    upload_url: str, command: str, run_started: datetime, reports_dir: Optional[Path] = None
+) -> None:
+    """Upload the reports a run wrote to reports_dir (default: reports directory), exiting with status 1 on failure"""
+    reports = find_run_reports(reports_dir or Path(config_manager.get_output_dir()), run_started.timestamp())
+    if not reports:
+        logging.info("No reports written by this run, nothing to upload")
+        return
+    try:
+        uploaded = upload_reports(upload_url, reports, command, run_started)
+    except ReportUploadError as e:
+        logging.error(f"Report upload failed: {e}")
+        sys.exit(1)
+    logging.info(f"Uploaded {len(uploaded)} report(s) to {upload_url}")
+
+
+def run_environments_batch(args: argparse.Namespace, environments_file: str) -> None:
+    """Run the command once per install listed in an environments file and save a consolidated report"""
+    try:
+        environments = load_environments_file(environments_file)
+    except (OSError, ValueError) as e:
+        logging.error(f"Invalid environments file: {e}")
+        sys.exit(1)
+    logging.info(f"Running {args.script_keyword} against {len(environments)} environment(s) from {environments_file}")
+    reports_dir = Path(config_manager.get_output_dir())
+    command = [os.path.abspath(__file__), args.script_keyword, *args.additional_args]
+    runs = run_environments(environments, command, reports_dir, max(args.environments_parallel, 1))
+    consolidated = consolidate_reports(runs, args.script_keyword, args.per_environment_reports)
+    consolidated["summary"]["build"] = get_build_info()
+    output_path = str(reports_dir / f"environments-{args.script_keyword}.json")
+    saved_path = save_json(output_path, consolidated, timestamp=True)
+    print_batch_summary(runs, saved_path)
+    if consolidated["summary"]["failed"]:
+        sys.exit(1)
+
+
 def validate_script_requirements(script_keyword: str, args: List[str]) -> None:
     """Validate required arguments for specific scripts"""
 
@@ -150,6 +301,44 @@ def validate_script_requirements(script_keyword: str, args: List[str]) -> None:
             )
 
 
+def run_completion(parser: argparse.ArgumentParser, args: List[str]) -> None:
+    """Print a shell completion script, or cached names for a completion in progress"""
+    completion_parser = argparse.ArgumentParser(prog="main.py completion")
+    target = completion_parser.add_mutually_exclusive_group(required=True)
+    target.add_argument("shell", nargs="?", choices=SHELLS, help="Shell to print a completion script for")
+    target.add_argument(
+        "--list", choices=LIST_KINDS, help="Print cached names to complete (used by the completion scripts)"
+    )
+    completion_args = completion_parser.parse_args(args)
+
+    if completion_args.list:
+        for value in list_cached(completion_args.list, config_manager.get_inspect_cache_path()):
+            print(value)
+        return
+
+    python_dir = os.path.dirname(os.path.abspath(__file__))
+    scripts = collect_scripts(load_script_paths(), get_script_descriptions(), python_dir)
+    main_flags = [flag for action in parser._actions for flag in action.option_strings]
+    print(generate_completion(completion_args.shell, scripts, main_flags), end="")
+
+
+def run_version(args: List[str]) -> None:
+    """Print version and build metadata"""
+    version_parser = argparse.ArgumentParser(prog="main.py version")
+    version_parser.add_argument("--json", action="store_true", help="Print the metadata as JSON")
+    version_args = version_parser.parse_args(args)
+
+    info = get_build_info()
+    if version_args.json:
+        print(json.dumps(info, indent=2))
+        return
+    print(format_build_info(info))
+    print(f"  version:        {info['version']}")
+    print(f"  commit:         {info['commit'] or 'unknown'}")
+    print(f"  build date:     {info['build_date'] or 'unknown'}")
+    print(f"  skopeo version: {info['skopeo_version'] or 'not found'}")
+
+
 def main():
     setup_logging()
     script_paths = load_script_paths()
@@ -166,6 +355,28 @@ Available scripts:
   reports                            - Generate tag usage reports from analysis data (auto-generates metadata)
   image_size_report                  - Generate a report of the largest images sorted by total size, showing space that would be freed if deleted
   user_size_report                   - Generate a report of image sizes grouped by user/owner, showing who is using the most space
+  repository_summary_report          - Generate a per-repository summary of tags, unique and shared layer bytes, and largest image (environment vs model)
+  duplicate_images_report            - Find identical images (same digest) pushed under different environment/model namespaces and suggest a canonicalization plan
+  simulate_deletion                  - Simulate deleting an image: layers that become unreferenced, bytes freed, and remaining references for shared layers
+  inspect-size <image> [--deep]      - Break an image down layer by layer to show why it is big, with size-reduction suggestions
+  orphans_report                     - Report untagged manifests, broken manifests, and unreferenced blobs (full scan needs registry storage access)
+  policy validate                    - Check a retention policy for errors and overlapping rules
+  policy test                        - Test a retention policy against a saved scan snapshot offline (no registry access)
+  query images|layers                - Query images and layers of the latest saved scan snapshot (no registry access)
+  rescan --repo R [--tags A,B]       - Refresh only some repositories or tags of the latest snapshot and regenerate reports
+  set union|intersect|subtract A B   - Combine saved snapshots, plans, reports and candidate files by tag or digest
+  candidates_report                  - Rank images as deletion candidates by age, exclusive size, usage frequency and recency, with estimated and cumulative savings
+  pull_time_report                   - Estimate cold-pull time and bytes per image and list the slowest images to pull
+  owner_usage_report                 - Attribute images and their registry bytes to owners from image labels, for chargeback
+  chargeback_report [--csv]          - Bill owners for their registry storage: bytes, cost and growth per owner
+  compare <registry-a> <registry-b>  - Compare repositories, tags and digests between two registries and report differences
+  diff-registries <a> <b> [--plan F] - Same as compare; with --plan, check that a backup registry holds a plan's images
+  naming_audit [--pattern REGEX]     - Report tags per repository that do not follow the expected naming convention
+  immutability_audit                 - Report repositories whose mutable tags changed digest between scan snapshots (ECR/Harbor settings)
+  hot_repos_report [--sort tags]     - Rank repositories by tags and layer bytes added or removed per week across scan snapshots
+  mutable_tags_report [--policy F]   - Report floating and re-pushed tags and the policy rules that depend on them
+  environment_revisions_report       - Map environment tags to Domino environment revisions and whether they are still selectable
+  model_versions_report              - Map model tags to Domino model versions and whether a running model API is pinned to them
   delete_image [image]               - Delete specific Docker image or analyze/delete unused images
   delete_archived_tags               - Find and optionally delete Docker tags associated with archived environments and/or models
   archive_unused_environments        - Mark unused environments as archived in MongoDB
@@ -173,6 +384,14 @@ Available scripts:
   delete_unused_private_environments - Find and optionally delete private environments owned by deactivated Keycloak users
   delete_all_unused_environments     - Run comprehensive unused environment cleanup (unused environments + deactivated user private environments)
   delete_unused_references           - Find and optionally delete MongoDB references to non-existent Docker images
+  version [--json]                   - Print the version, commit, build date and detected skopeo version
+  completion bash|zsh|fish           - Print a shell completion script for scripts, flags and cached repositories/tags
+  plan                               - Select images for deletion and write them to a versioned plan file for review
+  apply                              - Apply a reviewed cleanup plan produced by plan (dry-run by default)
+  clean --policy F | --tags T ...    - Plan and apply in one step: delete what a policy or tag list selects (dry-run by default)
+  mirror --to <registry>             - Copy the images a cleanup plan keeps to a secondary registry
+  bench [--repos N] [--tags M]       - Benchmark scan, index and plan throughput on a synthetic registry
+  watch [--interval 1h]              - Rescan at an interval and print only what changed since the last scan
 
 Configuration:
   The tool uses config.yaml for default settings. You can also use environment variables:
@@ -195,6 +414,12 @@ Examples:
   # Delete a specific image (actual deletion)
   python main.py delete_image environment:abc-123 --apply
 
+  # Delete a manifest by digest, with all of its alias tags
+  python main.py delete_image --digest sha256:<digest> --apply
+
+  # Delete tags selected with jq, read from stdin
+  jq -r '.unused_images[].tag' reports/deletion-analysis.json | python main.py delete_image --stdin --apply --force
+
   # Delete unused images (dry run - default, safe)
   python main.py delete_image
 
@@ -310,6 +535,98 @@ Examples:
   # Find where a specific environment ID is used (projects, scheduler jobs, workspaces, runs, workloads)
   python main.py find_environment_usage --environment-id 5f9d88f5b1e3c40012d3cabc
 
+  # Compare environment vs model repository composition (unique vs shared bytes)
+  python main.py repository_summary_report
+
+  # Find identical images pushed under different environment namespaces
+  python main.py duplicate_images_report
+
+  # What-if: see which layers deleting one image would free
+  python main.py simulate_deletion environment:507f1f77bcf86cd799439011-3
+
+  # What-if for a batch of candidates, accounting for layers shared between them
+  python main.py simulate_deletion --input candidates.txt
+
+  # Why is this environment image so big? Layers, build steps and suggestions
+  python main.py inspect-size environment:507f1f77bcf86cd799439011-3 --deep
+
+  # Find untagged manifests and unreferenced blobs in an S3-backed registry
+  python main.py orphans_report --storage-bucket my-registry-bucket
+  python main.py orphans_report --inventory s3://inventory-bucket/my-registry-bucket/daily/2026-01-01T01-00Z/manifest.json
+
+  # Compare the primary registry with its pull-through mirror
+  python main.py compare docker-registry:5000 mirror.example.com
+
+  # Check that a backup registry holds every image a cleanup plan deletes
+  python main.py diff-registries docker-registry:5000 backup.example.com --plan reports/cleanup-plan-<timestamp>.json
+
+  # Rank deletion candidates and see how savings accumulate down the list
+  python main.py candidates_report --top 50
+
+  # Find the images that slow down workspace startup on fresh nodes
+  python main.py pull_time_report --link-speed 250
+
+  # Registry storage per owner label, for chargeback
+  python main.py owner_usage_report --label team
+
+  # Monthly storage bill per team, exported as CSV
+  python main.py chargeback_report --label team --csv
+
+  # Repositories that grew fastest across the saved scan snapshots
+  python main.py hot_repos_report --top 5
+
+  # Iterate on a retention policy offline against a saved scan
+  python main.py policy validate --policy policy.yaml
+  python python/utils/image_data_analysis.py --mode snapshot
+  python main.py policy test --snapshot reports/scan-snapshot-<timestamp>.json --policy policy.yaml
+
+  # Explore the latest saved scan instantly: large old images, layers only one image uses
+  python main.py query images --min-size 10GB --older-than 180d
+  python main.py query layers --frequency 1
+
+  # Bring reports up to date after re-pushing two environment tags, without a full scan
+  python main.py rescan --repo environment --tags tagA,tagB
+
+  # Candidates of a policy plan minus the images protected in the cluster, as a candidate file
+  python main.py set subtract reports/cleanup-plan-<timestamp>.json protected-images.yaml --by digest --list to-delete.txt
+
+  # Show the version and build metadata (include this in bug reports)
+  python main.py version
+
+  # Enable shell completion (repositories and tags complete from the last scan's cache)
+  source <(docker-registry-cleaner completion bash)
+
+  # Copy the images the latest plan keeps to a disaster-recovery registry
+  python main.py mirror --latest-plan --to dr-registry.example.com:5000
+
+  # Tail new, removed and re-pushed tags during a heavy build period
+  python main.py watch --interval 15m
+
+  # Measure scan, index and plan throughput on a reproducible synthetic registry
+  python main.py bench --repos 200 --tags 100 --latency-ms 20
+
+  # Write a reviewable cleanup plan, then apply it after approval
+  python main.py plan --unused --unused-since-days 30
+  python main.py apply reports/cleanup-plan-<timestamp>.json --apply
+
+  # Delete what a retention policy selects in one step (drop --apply for a dry run)
+  python main.py clean --policy policy.yaml --apply
+
+  # Keep a history of plans in object storage (also gs:// and az://account/container/)
+  python main.py --report-upload s3://my-bucket/registry-cleaner plan --unused
+
+  # Print timestamps in local time, with image ages
+  python main.py --timezone Europe/Berlin repository_summary_report
+
+  # Save large reports gzip-compressed
+  python main.py --compress gzip image_size_report
+
+  # Size report of every install an operator manages, 4 at a time, in one consolidated report
+  python main.py --environments-file installs.txt --environments-parallel 4 image_size_report
+
+  # Share reports with support without exposing tags, environment names or registry addresses
+  python main.py --anonymize repository_summary_report
+
 Backup Examples (all delete scripts support backup to S3 before deletion):
   # Backup images to S3 before deleting archived tags
   python main.py delete_archived_tags --environment --apply --backup --s3-bucket my-backup-bucket
@@ -380,12 +697,92 @@ Safety Notes:
         "and MongoDB usage reports before running, even if fresh reports already exist on disk.",
     )
 
+    parser.add_argument(
+        "--report-upload",
+        dest="report_upload",
+        metavar="URL",
+        help="Upload the reports of the run to object storage (s3://bucket/prefix, gs://bucket/prefix or "
+        "az://account/container/prefix), timestamped per run. Overrides reports.upload_url in config.yaml.",
+    )
+
+    parser.add_argument(
+        "--timezone",
+        metavar="ZONE",
+        help="Time zone to print timestamps in, e.g. Europe/Berlin or America/New_York (IANA name). "
+        "Overrides reports.timezone in config.yaml (default: UTC).",
+    )
+
+    parser.add_argument(
+        "--compress",
+        choices=["gzip", "zstd"],
+        help="Compress the reports of the run (.json.gz/.csv.gz or .json.zst/.csv.zst; zstd needs the "
+        "zstandard package). Plans and scan snapshots are not compressed. Overrides reports.compress in config.yaml.",
+    )
+
+    parser.add_argument(
+        "--anonymize",
+        action="store_true",
+        help="Also save the reports of the run with tags, names and registry addresses replaced by hashes, "
+        "to the anonymized/ folder of the reports directory, for sharing with support. "
+        "With --report-upload, only the anonymized copies are uploaded.",
+    )
+
+    parser.add_argument(
+        "--profile",
+        metavar="NAME",
+        help="Defaults profile: the registry URL, repository, image types and tag naming pattern to assume "
+        "(built-in: domino, or a custom profile from profiles in config.yaml). Overrides profile in config.yaml.",
+    )
+
+    parser.add_argument(
+        "--read-only",
+        dest="read_only",
+        action="store_true",
+        help="Refuse every change to the registry, the cluster and MongoDB, whatever the configuration says. "
+        "Commands that would change something (--apply, mirror, registry GC) are refused before they start.",
+    )
+
+    parser.add_argument(
+        "--environments-file",
+        dest="environments_file",
+        metavar="FILE",
+        help="Run the command once per Domino install listed in FILE ('[name] <config file>' per line) and save "
+        "one consolidated report (environments-<command>-<timestamp>.json).",
+    )
+
+    parser.add_argument(
+        "--environments-parallel",
+        dest="environments_parallel",
+        type=int,
+        default=1,
+        metavar="N",
+        help="With --environments-file, run the command against N installs at a time (default: 1).",
+    )
+
+    parser.add_argument(
+        "--per-environment-reports",
+        dest="per_environment_reports",
+        action="store_true",
+        help="With --environments-file, also keep each install's own reports in environments/<name>/ "
+        "of the reports directory.",
+    )
+
     parser.add_argument("--config", action="store_true", help="Show current configuration and exit")
 
     parser.add_argument("additional_args", nargs=argparse.REMAINDER, help="Additional arguments for the script")
 
     args = parser.parse_args()
 
+    # Scripts run as subprocesses and read the profile from the environment
+    profile = pop_option(args.additional_args, "--profile") or args.profile
+    if profile:
+        config_manager.select_profile(profile)
+    try:
+        config_manager.get_profile()
+    except ConfigValidationError as e:
+        logging.error(f"Invalid configuration: {e}")
+        sys.exit(1)
+
     # Show configuration if requested
     if args.config:
         config_manager.print_config()
@@ -396,6 +793,16 @@ Safety Notes:
         parser.print_help()
         sys.exit(1)
 
+    # Special handling for completion (prints to stdout, no logging)
+    if args.script_keyword == "completion":
+        run_completion(parser, args.additional_args)
+        sys.exit(0)
+
+    # Special handling for version
+    if args.script_keyword == "version":
+        run_version(args.additional_args)
+        sys.exit(0)
+
     # Special handling for health_check
     if args.script_keyword == "health_check":
         health_checker = HealthChecker()
@@ -403,6 +810,69 @@ Safety Notes:
         all_healthy = health_checker.print_health_report(results)
         sys.exit(0 if all_healthy else 1)
 
+    # Reports are uploaded after the run, whether or not it succeeded
+    report_upload_url = pop_option(args.additional_args, "--report-upload") or args.report_upload
+    if not report_upload_url:
+        try:
+            report_upload_url = config_manager.get_report_upload_url()
+        except ConfigValidationError as e:
+            logging.error(f"Invalid configuration: {e}")
+            sys.exit(1)
+    anonymize = pop_anonymize(args.additional_args) or args.anonymize
+    # Scripts run as subprocesses and read the display time zone from the environment
+    display_timezone = pop_option(args.additional_args, "--timezone") or args.timezone
+    if display_timezone:
+        os.environ["REPORT_TIMEZONE"] = display_timezone
+    compression = pop_option(args.additional_args, "--compress") or args.compress
+    if compression:
+        os.environ["REPORT_COMPRESS"] = compression
+    try:
+        config_manager.get_display_timezone()
+        config_manager.get_report_compression()
+    except ConfigValidationError as e:
+        logging.error(f"Invalid configuration: {e}")
+        sys.exit(1)
+    # Scripts and the clients they use read read-only mode from the environment
+    if "--read-only" in args.additional_args:
+        args.additional_args.remove("--read-only")
+        args.read_only = True
+    if args.read_only:
+        os.environ["READ_ONLY"] = "true"
+    if config_manager.is_read_only():
+        # The top-level --apply is only forwarded to the script further down
+        script_args = args.additional_args + (["--apply"] if args.apply else [])
+        refusal = command_refusal(args.script_keyword, script_args)
+        if refusal:
+            logging.error(f"Read-only mode: {refusal}")
+            sys.exit(1)
+        logging.info("Read-only mode: changes to the registry, the cluster and MongoDB are refused")
+    environments_file = pop_option(args.additional_args, "--environments-file") or args.environments_file
+    if environments_file:
+        parallel = pop_option(args.additional_args, "--environments-parallel")
+        if parallel:
+            if not parallel.isdigit():
+                logging.error(f"--environments-parallel must be a number, got: {parallel}")
+                sys.exit(1)
+            args.environments_parallel = int(parallel)
+        if "--per-environment-reports" in args.additional_args:
+            args.additional_args.remove("--per-environment-reports")
+            args.per_environment_reports = True
+    run_started = datetime.now(timezone.utc)
+    # Scripts read the run ID from the environment, so every report of the run carries it
+    logging.info(f"Run ID: {start_run()}")
+    try:
+        if environments_file:
+            run_environments_batch(args, environments_file)
+        else:
+            run_command(args, script_paths)
+    finally:
+        anonymized_dir = anonymize_run_reports(run_started) if anonymize else None
+        if report_upload_url:
+            upload_run_reports(report_upload_url, args.script_keyword, run_started, anonymized_dir)
+
+
+def run_command(args: argparse.Namespace, script_paths: Dict[str, Optional[str]]) -> None:
+    """Run the selected script, or the scripts of a combined command"""
     # Special handling for delete_all_unused_environments (runs multiple scripts)
     if args.script_keyword == "delete_all_unused_environments":
         # Run both unused environment scripts sequentially
@@ -434,7 +904,7 @@ Safety Notes:
             logging.info("✅ Comprehensive unused environment analysis completed!")
             logging.info("   Use --apply to actually delete the identified environments")
         logging.info("=" * 60)
-        sys.exit(0)
+        return
 
     # Validate script requirements
     validate_script_requirements(args.script_keyword, args.additional_args)
@@ -481,6 +951,19 @@ Safety Notes:
         if args.apply or "--apply" in args.additional_args:
             dry_run = False
 
+    if args.script_keyword in ("apply", "clean"):
+        # Forward top-level flags to the apply.py and clean.py scripts
+        if args.apply and "--apply" not in args.additional_args:
+            args.additional_args.append("--apply")
+        if args.force and "--force" not in args.additional_args:
+            args.additional_args.append("--force")
+
+    if args.script_keyword == "plan":
+        if args.generate_reports and "--generate-reports" not in args.additional_args:
+            args.additional_args.append("--generate-reports")
+        if args.unused_since_days is not None and "--unused-since-days" not in args.additional_args:
+            args.additional_args.extend(["--unused-since-days", str(args.unused_since_days)])
+
     # Run the script
     run_script(script_path, args.additional_args, dry_run=dry_run)
 
@@ -0,0 +1,613 @@
+#!/usr/bin/env python3
+"""
+Cleanup Plan Applier
+
+This script executes a plan file produced by `plan`. Planning and execution are
+separate steps so a plan can be reviewed and approved before anything is
+deleted, possibly by a different person.
+
+Before deletion, a real-time usage check is performed and any image that has
+become in-use since the plan was made is skipped, and so is any image the
+configured protection providers (protection.providers in config.yaml) protect
+now, such as one a pod started running. Each tag is also re-inspected
+and skipped if it no longer points to the digest recorded in the plan, so an
+image re-pushed after the plan was approved is never deleted. Deleting a tag
+deletes its manifest, so an image is also skipped if another tag that is not
+in the plan points to the same manifest now. Tags signed with
+Docker Content Trust are skipped unless --allow-signed is given (see
+security.content_trust in config.yaml). Images a policy rule marked with
+replicate_to are first copied to that archive registry, and kept if the copy
+fails or its digest does not match. Runs in dry-run mode unless --apply is
+given.
+
+Items are applied in dependency order: the tags of one manifest together, and
+the signatures, attestations and SBOMs attached to an image before the image.
+Each item's progress is logged as it completes, and deleted items are
+checkpointed, so a large plan interrupted or partly failed can be applied
+again with --resume, skipping what earlier runs deleted.
+
+Usage examples:
+  # Dry-run: show what the plan would delete
+  python apply.py reports/cleanup-plan-2026-01-01-00-00-00.json
+
+  # Apply the plan (requires confirmation)
+  python apply.py reviewed-plan.json --apply
+
+  # Apply without confirmation prompt
+  python apply.py reviewed-plan.json --apply --force
+
+  # Continue a plan whose earlier apply was interrupted
+  python apply.py reviewed-plan.json --apply --resume
+"""
+
+import argparse
+import sys
+from collections import Counter
+from concurrent.futures import ThreadPoolExecutor
+from datetime import datetime
+from functools import partial
+from pathlib import Path
+from typing import Any, Callable, Dict, List, Optional, Set, Tuple
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.blob_mount import empty_copy_stats
+from utils.build_info import get_build_info
+from utils.cleanup_plan import (
+    ApplyUnit,
+    CleanupPlan,
+    PlanFormatError,
+    PlanItem,
+    apply_order,
+    check_item_digest,
+    load_plan,
+    replicate_item,
+)
+from utils.config_manager import SkopeoClient, config_manager
+from utils.deletion_base import BaseDeletionScript
+from utils.error_utils import ERROR_PARSE, ERROR_UNKNOWN, classify_error, error_exit_status
+from utils.image_data_analysis import ImageAnalyzer
+from utils.image_metadata import ModelVersion, build_model_version_mapping
+from utils.logging_utils import get_logger, setup_logging
+from utils.progress_events import PHASE_DELETE, PHASE_USAGE_CHECK, ProgressEvents
+from utils.protection_providers import (
+    DOMINO_PROVIDER,
+    Protections,
+    collect_protections,
+    configured_providers,
+    describe_protection,
+)
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+# Checkpoint of the items each plan's runs deleted, by plan_id, for --resume
+CHECKPOINT_OPERATION = "apply"
+
+
+class PlanApplier(BaseDeletionScript):
+    """Apply a reviewed cleanup plan to the registry."""
+
+    def __init__(self, *args, **kwargs):
+        super().__init__(*args, **kwargs)
+        self._archive_clients: Dict[str, SkopeoClient] = {}
+        # Blobs of archive copies within the registry, mounted rather than transferred where possible
+        self.copy_stats = empty_copy_stats()
+        # Receives structured progress events, for applications embedding the applier
+        self.progress: Optional[ProgressEvents] = None
+        # repository -> tag -> digest it points to, resolved when a unit of the repository is checked
+        self._tag_digests: Dict[str, Dict[str, Optional[str]]] = {}
+
+    def replicate(self, item: PlanItem) -> Optional[str]:
+        """Copy an item to its archive registry before deletion.
+
+        Returns:
+            A reason the item must not be deleted, or None if the archive holds a verified copy
+        """
+        archive_client = self._archive_clients.get(item.replicate_to)
+        if archive_client is None:
+            try:
+                archive_client = SkopeoClient(config_manager, registry_url=item.replicate_to)
+            except Exception as e:
+                return f"cannot connect to {item.replicate_to}: {e}"
+            self._archive_clients[item.replicate_to] = archive_client
+        return replicate_item(item, self.skopeo_client, archive_client, self.copy_stats)
+
+    def find_pinned_model_tags(self, items: List[PlanItem]) -> Optional[Dict[str, ModelVersion]]:
+        """Model images of the plan that a running model API is pinned to.
+
+        Returns:
+            Dict mapping tag -> model version, or None if model deployments could not be looked up
+        """
+        model_tags = {item.tag for item in items if item.image_id.startswith("model:")}
+        try:
+            versions = build_model_version_mapping(model_tags)
+        except Exception as e:
+            self.logger.warning(f"Could not look up model deployments, skipping every model image: {e}")
+            return None
+        return {tag: version for tag, version in versions.items() if version["pinned"]}
+
+    def find_protected_items(self, plan: CleanupPlan) -> Protections:
+        """Items of the plan the configured protection providers protect now.
+
+        The domino provider is left out, since the real-time usage check
+        already covers Domino's configuration.
+
+        Raises:
+            ProtectionProviderError: If a provider fails
+        """
+        names = [name for name in config_manager.get_protection_providers() if name != DOMINO_PROVIDER]
+        providers = configured_providers(names)
+        if not providers:
+            return {}
+        analyzer = ImageAnalyzer(plan.registry_url, plan.repository, skopeo_client=self.skopeo_client)
+        for item in plan.items:
+            analyzer.index.add_image(item.image_id, item.repository, item.tag, item.digest, [])
+        return collect_protections(analyzer, providers)
+
+    def find_alias_tags(self, repository: str, digest: str) -> List[str]:
+        """Tags that currently point to a manifest, all of which deleting it removes.
+
+        Every tag of the repository is resolved with a manifest HEAD request the
+        first time one of its manifests is looked up.
+        """
+        if repository not in self._tag_digests:
+            tags = self.skopeo_client.list_tags(repository)
+            with ThreadPoolExecutor(max_workers=config_manager.get_max_workers()) as executor:
+                digests = executor.map(lambda tag: self.skopeo_client.get_manifest_digest(repository, tag), tags)
+                self._tag_digests[repository] = dict(zip(tags, digests))
+        return sorted(tag for tag, tag_digest in self._tag_digests[repository].items() if tag_digest == digest)
+
+    def check_aliases(self, subjects: List[PlanItem]) -> Optional[str]:
+        """Why the manifest of a unit's subjects must not be deleted because of its other tags.
+
+        Args:
+            subjects: Tags of one manifest to delete, which passed their own checks
+
+        Returns:
+            The reason, or None if every tag pointing to the manifest is among the subjects
+        """
+        planned = {item.tag for item in subjects}
+        aliases = self.find_alias_tags(subjects[0].repository, subjects[0].digest)
+        if not planned & set(aliases):
+            return f"tags pointing to {subjects[0].digest} in {subjects[0].repository} could not be listed"
+        unplanned = [tag for tag in aliases if tag not in planned]
+        if unplanned:
+            return f"deleting the manifest would also remove {', '.join(unplanned)}, not in the plan"
+        return None
+
+    def check_item(
+        self,
+        item: PlanItem,
+        in_use: Dict[str, str],
+        pinned_model_tags: Optional[Dict[str, ModelVersion]],
+        protections: Protections,
+    ) -> Optional[Tuple[str, str]]:
+        """Why a plan item must not be deleted now.
+
+        Args:
+            item: Plan item to check
+            in_use: Usage summary of each tag in use
+            pinned_model_tags: Model images a running model API is pinned to, or None if unknown
+            protections: Items the configured protection providers protect (find_protected_items)
+
+        Returns:
+            (status, reason), or None if the item can be deleted
+        """
+        if item.tag in in_use:
+            return "skipped", f"in use: {in_use[item.tag]}"
+
+        if item.image_id in protections:
+            return "skipped", f"protected by {describe_protection(protections[item.image_id])}"
+
+        if item.image_id.startswith("model:") and (pinned_model_tags is None or item.tag in pinned_model_tags):
+            if pinned_model_tags is None:
+                return "skipped", "model deployments could not be checked"
+            version = pinned_model_tags[item.tag]
+            reason = f"pinned by running model API {version['model_name'] or version['model_id']}"
+            if version["version"] is not None:
+                reason += f" version {version['version']}"
+            return "skipped", reason
+
+        refusal = self.skopeo_client.signed_tag_refusal(item.repository, item.tag)
+        if refusal:
+            return "signed", refusal
+
+        mismatch = check_item_digest(item, self.skopeo_client.get_image_digest(item.repository, item.tag))
+        if mismatch:
+            return "digest_mismatch", mismatch
+        return None
+
+    def delete_unit(self, unit: ApplyUnit, items: List[PlanItem]) -> Dict[str, Optional[Tuple[str, str]]]:
+        """Delete the items of a unit that passed their checks, referrers first.
+
+        Aliases are deleted at once by deleting their manifest, which removes
+        every tag pointing to it; check_aliases makes sure they are all in the unit.
+
+        Args:
+            unit: Unit being applied
+            items: Items of the unit to delete
+
+        Returns:
+            image_id -> None if deleted, or (reason, error code) if the deletion failed
+        """
+        outcome: Dict[str, Optional[Tuple[str, str]]] = {}
+
+        def delete(description: str, deleted: List[PlanItem], action: Callable[[], bool]) -> None:
+            self.logger.info(f"  Deleting: {description}")
+            error = None
+            try:
+                if not action():
+                    error = ("delete returned failure", self.skopeo_client.last_error_code() or ERROR_UNKNOWN)
+            except Exception as e:
+                self.logger.error(f"    Error deleting: {e}")
+                error = (str(e), classify_error(e))
+            for item in deleted:
+                outcome[item.image_id] = error
+
+        subjects = [item for item in unit.subjects if item in items]
+        for item in unit.referrers:
+            if item in items:
+                action = partial(self.skopeo_client.delete_image, item.repository, item.tag)
+                delete(f"{item.repository}:{item.tag}", [item], action)
+        if len(subjects) == 1:
+            item = subjects[0]
+            action = partial(self.skopeo_client.delete_image, item.repository, item.tag)
+            delete(f"{item.repository}:{item.tag}", subjects, action)
+        elif subjects:
+            tags = [item.tag for item in subjects]
+            delete(
+                f"{subjects[0].repository}@{subjects[0].digest} (tags {', '.join(tags)})",
+                subjects,
+                partial(self.skopeo_client.delete_manifest, subjects[0].repository, subjects[0].digest, tags),
+            )
+        return outcome
+
+    def apply_plan(self, plan: CleanupPlan, dry_run: bool = True, resume: bool = False) -> Dict[str, Any]:
+        """Delete every image in the plan, skipping images that are now in use.
+
+        Items are applied in dependency order (see cleanup_plan.apply_order):
+        the tags of one manifest together, after the signatures, attestations
+        and SBOMs that refer to it. Every tag is re-inspected immediately before
+        deletion; items whose tag is gone or points to a different digest than
+        recorded are skipped with status "digest_mismatch". Tags whose deletion
+        Docker Content Trust checks refuse are skipped with status "signed".
+        Items with replicate_to are copied to that archive registry first, and
+        skipped with status "replication_failed" unless the copy is verified.
+        Model images a running model API is pinned to are skipped, and so are
+        all model images if model deployments cannot be looked up. Items the
+        configured protection providers protect are skipped too, and so are
+        tags whose manifest has another tag now that is not in the plan. When
+        one tag of a manifest is skipped, its aliases and referrers are kept too.
+
+        Deleted items are recorded in a checkpoint as they complete, so an
+        interrupted or partly failed run can be resumed without re-checking them.
+
+        Args:
+            plan: Plan to apply
+            dry_run: If True, only report what would be deleted
+            resume: Skip items an earlier run of the plan deleted (status "already_deleted")
+
+        Returns:
+            Dict with per-item results, in the order they were applied, and summary counts
+        """
+        from utils.image_usage import ImageUsageService
+
+        results: List[Dict[str, Any]] = []
+        summary = {
+            "total": len(plan.items),
+            "deleted": 0,
+            "failed": 0,
+            "skipped": 0,
+            "digest_mismatch": 0,
+            "signed": 0,
+            "replicated": 0,
+            "replication_failed": 0,
+            "already_deleted": 0,
+            "zero_impact": 0,
+        }
+        freed_bytes = 0
+
+        completed: Set[str] = set()
+        if resume:
+            checkpoint = self.checkpoint_manager.load_checkpoint(CHECKPOINT_OPERATION, plan.plan_id)
+            completed = set(checkpoint.completed_items) if checkpoint else set()
+            self.logger.info(f"Resuming plan {plan.plan_id}: {len(completed)} item(s) deleted by earlier runs")
+
+        def finish(item: PlanItem, status: str, reason: str = "", **fields: Any) -> None:
+            """Record an item's result and report progress"""
+            nonlocal freed_bytes
+            result = {"image_id": item.image_id, "tag": item.tag, "digest": item.digest, "status": status}
+            if reason:
+                result["reason"] = reason
+            result.update(fields)
+            results.append(result)
+            if status in ("deleted", "would_delete"):
+                summary["deleted"] += 1
+                freed_bytes += item.expected_freed_bytes
+                if item.pullable_from:
+                    summary["zero_impact"] += 1
+            elif status == "failed":
+                summary["failed"] += 1
+            elif status == "already_deleted":
+                summary["already_deleted"] += 1
+            else:
+                summary["skipped"] += 1
+                if status != "skipped":
+                    summary[status] += 1
+            progress = f"[{len(results)}/{summary['total']}] {item.image_id}: {status.replace('_', ' ')}"
+            if status in ("deleted", "would_delete"):
+                progress += f" (~{sizeof_fmt(freed_bytes)} {'would be ' if dry_run else ''}freed so far)"
+            elif reason:
+                progress += f" ({reason})"
+            if status in ("deleted", "would_delete", "already_deleted"):
+                self.logger.info(progress)
+            else:
+                self.logger.warning(progress)
+            if self.progress:
+                image_type = item.image_id.split(":", 1)[0]
+                if status in ("failed", "replication_failed"):
+                    self.progress.error(reason, image_type, item.tag, fields.get("error_code"))
+                self.progress.tag_done(image_type, item.tag, len(results), summary["total"], status, reason or None)
+
+        if self.progress:
+            self.progress.phase_changed(PHASE_USAGE_CHECK)
+        self.logger.info("Performing real-time usage check before deletion...")
+        service = ImageUsageService()
+        in_use_tags, usage_info = service.check_tags_in_use([item.tag for item in plan.items])
+        in_use = {tag: service.generate_usage_summary(usage_info.get(tag, {})) for tag in in_use_tags}
+        pinned_model_tags = self.find_pinned_model_tags(plan.items)
+        protections = self.find_protected_items(plan)
+
+        registry_enabled = False
+        if not dry_run and self.skopeo_client.is_registry_in_cluster():
+            registry_enabled = self.enable_registry_deletion()
+
+        if self.progress:
+            self.progress.phase_changed(PHASE_DELETE)
+
+        try:
+            for unit in apply_order(plan.items):
+                pending: List[PlanItem] = []
+                for item in unit.items:
+                    if item.image_id in completed:
+                        finish(item, "already_deleted", "deleted by an earlier run of this plan")
+                    else:
+                        pending.append(item)
+
+                refusals = {
+                    item.image_id: self.check_item(item, in_use, pinned_model_tags, protections) for item in pending
+                }
+                subjects = [item for item in unit.subjects if item in pending]
+                if subjects and not any(refusals[item.image_id] for item in subjects):
+                    alias_refusal = self.check_aliases(subjects)
+                    if alias_refusal:
+                        for item in subjects:
+                            refusals[item.image_id] = ("skipped", alias_refusal)
+                copied: Set[str] = set()
+                if not dry_run and not any(refusals[item.image_id] for item in unit.subjects if item in pending):
+                    for item in pending:
+                        if item.replicate_to and not refusals[item.image_id]:
+                            self.logger.info(f"  Copying {item.repository}:{item.tag} to {item.replicate_to}")
+                            failure = self.replicate(item)
+                            if failure:
+                                refusals[item.image_id] = ("replication_failed", failure)
+                            else:
+                                copied.add(item.image_id)
+                                summary["replicated"] += 1
+
+                # A manifest is deleted with all of its tags, so one kept tag keeps its aliases and referrers
+                kept = next((item for item in unit.subjects if item in pending and refusals[item.image_id]), None)
+                deletable = [item for item in pending if not refusals[item.image_id] and kept is None]
+                outcome: Dict[str, Optional[Tuple[str, str]]] = {}
+                if deletable and not dry_run:
+                    outcome = self.delete_unit(unit, deletable)
+                    self.checkpoint_manager.save_checkpoint(
+                        CHECKPOINT_OPERATION,
+                        [image_id for image_id, error in outcome.items() if error is None],
+                        len(plan.items),
+                        operation_id=plan.plan_id,
+                    )
+
+                for item in pending:
+                    refusal = refusals[item.image_id]
+                    replicated = {"replicated_to": item.replicate_to} if item.image_id in copied else {}
+                    if refusal:
+                        status, reason = refusal
+                        error = {"error_code": classify_error(reason)} if status == "replication_failed" else {}
+                        finish(item, status, reason, **error)
+                    elif kept is not None:
+                        finish(item, "skipped", f"{kept.image_id} is kept", **replicated)
+                    elif dry_run:
+                        copy = f" (after copying it to {item.replicate_to})" if item.replicate_to else ""
+                        if item.pullable_from:
+                            copy += f" - zero user impact, still pullable from {', '.join(item.pullable_from)}"
+                        self.logger.info(f"  Would delete: {item.repository}:{item.tag}{copy}")
+                        finish(item, "would_delete")
+                    elif outcome[item.image_id] is None:
+                        finish(item, "deleted", **replicated)
+                    else:
+                        reason, code = outcome[item.image_id]
+                        finish(item, "failed", reason, error_code=code, **replicated)
+        finally:
+            if registry_enabled:
+                self.disable_registry_deletion()
+
+        if not dry_run:
+            if summary["failed"] or summary["replication_failed"]:
+                self.logger.info("Deleted items are checkpointed; run again with --resume to retry the others")
+            else:
+                self.checkpoint_manager.delete_checkpoint(CHECKPOINT_OPERATION, plan.plan_id)
+        if any(self.copy_stats.values()):
+            summary["copy_blobs"] = dict(self.copy_stats)
+        summary["error_codes"] = dict(Counter(r["error_code"] for r in results if "error_code" in r))
+        return {"summary": {"build": get_build_info(), "runStats": get_run_stats(), **summary}, "results": results}
+
+
+def check_plan_target(plan: CleanupPlan, registry_url: str, repository: str) -> Optional[str]:
+    """Check that a plan was made for the configured registry and repository.
+
+    Returns:
+        An error message if the plan targets a different registry, otherwise None
+    """
+    if plan.registry_url != registry_url or plan.repository != repository:
+        return (
+            f"Plan targets {plan.registry_url}/{plan.repository}, but the configured registry is "
+            f"{registry_url}/{repository}"
+        )
+    return None
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Apply a reviewed cleanup plan produced by `plan`",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Dry-run: show what the plan would delete
+  python apply.py cleanup-plan.json
+
+  # Apply the plan (requires confirmation)
+  python apply.py cleanup-plan.json --apply
+
+  # Apply without confirmation prompt
+  python apply.py cleanup-plan.json --apply --force
+
+  # Also delete tags signed with Docker Content Trust
+  python apply.py cleanup-plan.json --apply --allow-signed
+
+  # Continue a plan whose earlier apply was interrupted
+  python apply.py cleanup-plan.json --apply --resume
+        """,
+    )
+
+    parser.add_argument("plan_file", help="Plan file produced by `plan`")
+    parser.add_argument("--apply", action="store_true", help="Actually delete images (default is dry-run)")
+    parser.add_argument("--force", action="store_true", help="Skip confirmation prompt when using --apply")
+    parser.add_argument(
+        "--allow-signed",
+        action="store_true",
+        help="Delete tags signed with Docker Content Trust (their signatures must then be removed from Notary)",
+    )
+    parser.add_argument(
+        "--resume",
+        action="store_true",
+        help="Skip the items earlier runs of this plan deleted, e.g. after an interrupted or partly failed apply",
+    )
+    parser.add_argument("--output", help="Results file (default: plan-apply-results.json in reports directory)")
+    parser.add_argument(
+        "--enable-docker-deletion",
+        action="store_true",
+        help="Enable registry deletion by treating registry as in-cluster (overrides auto-detection)",
+    )
+    parser.add_argument(
+        "--registry-statefulset",
+        default="docker-registry",
+        help="Name of registry StatefulSet/Deployment to modify for deletion (default: docker-registry)",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+    dry_run = not args.apply
+
+    try:
+        plan = load_plan(args.plan_file)
+
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+        mismatch = check_plan_target(plan, registry_url, repository)
+        if mismatch:
+            logger.error(f"❌ {mismatch}")
+            sys.exit(1)
+
+        logger.info("=" * 60)
+        logger.info(f"   Apply Cleanup Plan ({'DRY RUN' if dry_run else 'DELETE MODE'})")
+        logger.info("=" * 60)
+        logger.info(f"Plan ID:    {plan.plan_id}")
+        created = format_timestamp(plan.created_at, config_manager.get_display_timezone(), with_age=True)
+        logger.info(f"Created:    {created} by {plan.created_by}")
+        logger.info(f"Policy:     {plan.policy.source} - {plan.policy.description}")
+        logger.info(f"Images:     {len(plan.items)}")
+        logger.info(f"Expected:   {sizeof_fmt(plan.expected_freed_bytes)} freed")
+        logger.info("=" * 60)
+
+        if not plan.items:
+            logger.info("Plan is empty - nothing to do.")
+            sys.exit(0)
+
+        applier = PlanApplier(
+            registry_url=registry_url,
+            repository=repository,
+            enable_docker_deletion=args.enable_docker_deletion,
+            registry_statefulset=args.registry_statefulset,
+        )
+        applier.skopeo_client.allow_signed_deletion = args.allow_signed
+        checkpoint = applier.checkpoint_manager.load_checkpoint(CHECKPOINT_OPERATION, plan.plan_id)
+        if checkpoint and not args.resume:
+            logger.warning(
+                f"⚠️  Earlier runs of this plan deleted {len(checkpoint.completed_items)} item(s) "
+                f"(last {checkpoint.last_updated}); use --resume to skip them"
+            )
+
+        if not dry_run and not applier.confirm_deletion(len(plan.items), "images", force=args.force):
+            logger.info("Deletion cancelled.")
+            sys.exit(0)
+
+        outcome = applier.apply_plan(plan, dry_run=dry_run, resume=args.resume)
+        outcome["plan_id"] = plan.plan_id
+        outcome["plan_file"] = args.plan_file
+        outcome["dry_run"] = dry_run
+        outcome["resumed"] = args.resume
+        outcome["applied_at"] = datetime.now().isoformat()
+
+        output_path = args.output or str(Path(config_manager.get_output_dir()) / "plan-apply-results.json")
+        saved_path = save_json(output_path, outcome, timestamp=not args.output)
+
+        applier.log_summary({**outcome["summary"], "results_file": saved_path}, dry_run=dry_run)
+        if outcome["summary"]["already_deleted"]:
+            logger.info(f"   Deleted by earlier runs: {outcome['summary']['already_deleted']}")
+        if outcome["summary"]["zero_impact"]:
+            logger.info(f"   Zero user impact (still pullable elsewhere): {outcome['summary']['zero_impact']}")
+        copy_blobs = outcome["summary"].get("copy_blobs")
+        if copy_blobs:
+            logger.info(
+                f"   Archive copies: {sizeof_fmt(copy_blobs['mounted_bytes'])} mounted, "
+                f"{sizeof_fmt(copy_blobs['existing_bytes'])} already archived, "
+                f"{sizeof_fmt(copy_blobs['transferred_bytes'])} transferred"
+            )
+        if dry_run:
+            logger.info("\nDRY RUN complete - no images were deleted. Use --apply to perform deletion.")
+
+        status = error_exit_status(outcome["summary"]["error_codes"])
+        if status:
+            codes = ", ".join(f"{code}: {count}" for code, count in outcome["summary"]["error_codes"].items())
+            logger.error(f"\n❌ Some images could not be deleted ({codes}); exiting with status {status}")
+            sys.exit(status)
+
+    except PlanFormatError as e:
+        logger.error(f"\n❌ Invalid plan file: {e}")
+        sys.exit(error_exit_status([ERROR_PARSE]))
+    except FileNotFoundError as e:
+        logger.error(f"\n❌ Missing required file: {e}")
+        sys.exit(1)
+    except Exception as e:
+        logger.error(f"\n❌ Applying plan failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(error_exit_status([classify_error(e)]))
+
+
+if __name__ == "__main__":
+    main()
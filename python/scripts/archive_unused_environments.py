@@ -37,10 +37,12 @@ if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
 from scripts.delete_unused_environments import UnusedEnvInfo, UnusedEnvironmentsFinder
+from utils.build_info import get_build_info
 from utils.config_manager import config_manager
 from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
 from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -256,6 +258,8 @@ def main():
             # Still write an empty report
             empty_report = {
                 "summary": {
+                    "build": get_build_info(),
+                    "runStats": get_run_stats(),
                     "total_candidates": 0,
                     "would_archive": 0,
                     "actually_archived": 0,
@@ -303,6 +307,8 @@ def main():
 
         report = {
             "summary": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "total_candidates": len(env_summaries),
                 "would_archive": len(env_summaries),
                 "actually_archived": actually_archived,
@@ -59,6 +59,7 @@ if str(_parent_dir) not in sys.path:
 from utils.config_manager import ConfigManager, SkopeoClient
 from utils.logging_utils import get_logger
 from utils.object_id_utils import read_typed_object_ids_from_file
+from utils.read_only import ensure_writable
 
 logger = get_logger(__name__)
 
@@ -408,6 +409,7 @@ def process_backup(
             # If deletion is requested, remove the tag even if it was backed up by a previous run
             if delete:
                 try:
+                    ensure_writable(f"deleting {image} from ECR")
                     ecr_client.batch_delete_image(
                         repositoryName=repository,
                         imageIds=[{"imageTag": tag}],
@@ -434,6 +436,7 @@ def process_backup(
                     if s3_checksum_matches(s3_client, s3_bucket, s3_key, checksum):
                         logger.info(f"✅ Backed up {image}")
                         if delete:
+                            ensure_writable(f"deleting {image} from ECR")
                             ecr_client.batch_delete_image(
                                 repositoryName=repository,
                                 imageIds=[{"imageTag": tag}],
@@ -655,6 +658,7 @@ def main():
             image = f"{full_repo}:{tag}"
             try:
                 if not args.dry_run:
+                    ensure_writable(f"deleting {image} from ECR")
                     ecr.batch_delete_image(repositoryName=repo, imageIds=[{"imageTag": tag}])
                 logger.info(f"🗑️  {'(dry-run) would delete' if args.dry_run else 'Deleted'} {image}")
             except Exception as e:
@@ -0,0 +1,306 @@
+#!/usr/bin/env python3
+"""
+Scan, Index and Plan Benchmark
+
+This script generates a synthetic registry (see utils/synthetic_registry.py)
+and measures how fast the cleaner works through it, so the effect of a
+performance change can be measured reproducibly: the same --seed always
+produces the same repositories, tags and layer sharing.
+
+Stages timed:
+  generate  Build the synthetic dataset
+  push      Push it to a local registry (--registry only)
+  scan      Inspect every tag and build the image index (tags/s)
+  index     Query the index: bytes freed per image, summary statistics,
+            per-repository composition and duplicate images (images/s)
+  plan      Evaluate a retention policy deleting images older than
+            --plan-older-than-days and build the cleanup plan (images/s)
+
+By default the dataset is served in memory, without a registry; --latency-ms
+adds a fixed delay to every registry call to mimic network round trips. With
+--registry the dataset is pushed to a registry that accepts anonymous pushes
+(e.g. `docker run -d -p 5000:5000 registry:2`) and scanned through skopeo
+like a real one. Layers pushed are small placeholder blobs, so only the layer
+sharing, not the layer sizes, carries over to a registry scan.
+
+Nothing is deleted, and the inspect cache is not read or written.
+
+Usage examples:
+  # Benchmark 50 repositories of 40 tags each, in memory
+  python bench.py
+
+  # A larger dataset, with 20 ms per registry call and 16 workers
+  python bench.py --repos 200 --tags 100 --latency-ms 20 --max-workers 16
+
+  # Use the on-disk (SQLite) image index
+  python bench.py --disk-index
+
+  # Push the dataset to a local registry and benchmark against it
+  python bench.py --repos 10 --tags 20 --registry localhost:5000
+"""
+
+import argparse
+import logging
+import sys
+import tempfile
+import time
+from datetime import datetime
+from pathlib import Path
+from typing import Any, Callable, Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from scripts.plan import build_plan
+from utils.build_info import get_build_info
+from utils.cache_utils import DigestInspectCache
+from utils.cleanup_plan import PolicyProvenance
+from utils.config_manager import SkopeoClient, config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.image_index import SqliteImageIndex
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.retention_policy import PolicyRule, RetentionPolicy, evaluate_policy
+from utils.synthetic_registry import SyntheticDataset, SyntheticSkopeoClient, generate_dataset, push_dataset
+
+logger = get_logger(__name__)
+
+# Repository the synthetic repositories are served or pushed under
+BENCH_REPOSITORY = "bench"
+
+
+def timed(stage: str, items: int, func: Callable[[], Any], results: List[Dict[str, Any]]) -> Any:
+    """Run one benchmark stage and record its duration and throughput.
+
+    Args:
+        stage: Stage name
+        items: Number of items (tags or images) the stage processes
+        func: Stage to run
+        results: Stage results to append to
+
+    Returns:
+        What func returned
+    """
+    start = time.perf_counter()
+    value = func()
+    seconds = time.perf_counter() - start
+    results.append(
+        {
+            "stage": stage,
+            "seconds": round(seconds, 4),
+            "items": items,
+            "per_second": round(items / seconds, 1) if seconds > 0 else None,
+        }
+    )
+    logger.info(f"  {stage:<10} {seconds:8.3f}s  {items} items")
+    return value
+
+
+def scan(analyzer: ImageAnalyzer, dataset: SyntheticDataset, max_workers: int) -> int:
+    """Scan every synthetic repository; returns the number of repositories scanned"""
+    return len(analyzer.analyze_images(list(dataset.repositories), max_workers=max_workers))
+
+
+def query_index(analyzer: ImageAnalyzer) -> None:
+    """Run the index queries reports and plans depend on"""
+    for image_id in list(analyzer.images):
+        analyzer.freed_space_if_deleted([image_id])
+    analyzer.generate_summary_stats()
+    analyzer.generate_repository_summary()
+    analyzer.find_duplicate_images()
+
+
+def plan(analyzer: ImageAnalyzer, older_than_days: int) -> int:
+    """Evaluate an age-based retention policy and build its cleanup plan; returns the number of plan items"""
+    policy = RetentionPolicy(
+        rules=[PolicyRule(name="delete-old", action="delete", older_than_days=older_than_days)], default="keep"
+    )
+    image_ids = [d["image_id"] for d in evaluate_policy(policy, analyzer) if d["action"] == "delete"]
+    provenance = PolicyProvenance(source="bench", description=f"Synthetic images older than {older_than_days} days")
+    return len(build_plan(analyzer, image_ids, provenance, reason="bench").items)
+
+
+def run_bench(args: argparse.Namespace, index_dir: str) -> Dict[str, Any]:
+    """Run every benchmark stage.
+
+    Args:
+        args: Parsed command line arguments
+        index_dir: Folder for the on-disk index (--disk-index)
+
+    Returns:
+        Benchmark result: summary and per-stage timings
+    """
+    stages: List[Dict[str, Any]] = []
+    tags = args.repos * args.tags
+
+    dataset = timed(
+        "generate",
+        tags,
+        lambda: generate_dataset(args.repos, args.tags, seed=args.seed, base_images=args.base_images),
+        stages,
+    )
+    stats = dataset.stats()
+
+    if args.registry:
+        timed("push", tags, lambda: push_dataset(dataset, args.registry, BENCH_REPOSITORY, args.max_workers), stages)
+        skopeo_client = SkopeoClient(config_manager, registry_url=args.registry)
+        registry_url = args.registry
+    else:
+        skopeo_client = SyntheticSkopeoClient(dataset, BENCH_REPOSITORY, latency=args.latency_ms / 1000)
+        registry_url = "synthetic"
+
+    analyzer = ImageAnalyzer(registry_url, BENCH_REPOSITORY, skopeo_client=skopeo_client)
+    # Every run starts cold, whatever the cache settings
+    analyzer.inspect_cache = DigestInspectCache(None)
+    if args.disk_index:
+        analyzer.index = SqliteImageIndex(directory=index_dir)
+    if not args.verbose:
+        analyzer.logger.setLevel(logging.WARNING)
+
+    scanned = timed("scan", tags, lambda: scan(analyzer, dataset, args.max_workers), stages)
+    if scanned < len(dataset.repositories):
+        logger.warning(f"⚠️  Only {scanned}/{len(dataset.repositories)} repositories could be scanned")
+
+    images = len(analyzer.images)
+    timed("index", images, lambda: query_index(analyzer), stages)
+    planned = timed("plan", images, lambda: plan(analyzer, args.plan_older_than_days), stages)
+
+    return {
+        "summary": {
+            "mode": "registry" if args.registry else "memory",
+            "registry_url": args.registry,
+            "seed": args.seed,
+            "max_workers": args.max_workers,
+            "latency_ms": 0 if args.registry else args.latency_ms,
+            "index": "disk" if args.disk_index else "memory",
+            "dataset": stats,
+            "images_scanned": images,
+            "layers_indexed": len(analyzer.layers),
+            "plan_items": planned,
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        },
+        "stages": stages,
+    }
+
+
+def print_report_summary(result: Dict[str, Any]) -> None:
+    """Print a human-readable summary of the benchmark"""
+    summary = result["summary"]
+    dataset = summary["dataset"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Benchmark Results")
+    logger.info("=" * 80)
+    logger.info(
+        f"Dataset (seed {summary['seed']}): {dataset['repositories']} repositories, {dataset['tags']} tags, "
+        f"{dataset['manifests']} manifests, {dataset['layers']} layers"
+    )
+    logger.info(f"Logical size {sizeof_fmt(dataset['logical_bytes'])}, stored {sizeof_fmt(dataset['stored_bytes'])}")
+    logger.info(
+        f"Mode: {summary['mode']}, {summary['index']} index, {summary['max_workers']} workers"
+        + (f", {summary['latency_ms']} ms per call" if summary["latency_ms"] else "")
+    )
+    logger.info(f"\n{'Stage':<10} {'Seconds':>10} {'Items':>10} {'Items/s':>12}")
+    for stage in result["stages"]:
+        per_second = f"{stage['per_second']:.1f}" if stage["per_second"] is not None else "-"
+        logger.info(f"{stage['stage']:<10} {stage['seconds']:>10.3f} {stage['items']:>10} {per_second:>12}")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Benchmark scan, index and plan throughput on a synthetic registry",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Benchmark 50 repositories of 40 tags each, in memory
+  python bench.py
+
+  # A larger dataset, with 20 ms per registry call and 16 workers
+  python bench.py --repos 200 --tags 100 --latency-ms 20 --max-workers 16
+
+  # Use the on-disk (SQLite) image index
+  python bench.py --disk-index
+
+  # Push the dataset to a local registry and benchmark against it
+  python bench.py --repos 10 --tags 20 --registry localhost:5000
+        """,
+    )
+
+    parser.add_argument("--repos", type=int, default=50, help="Number of repositories (default: 50)")
+    parser.add_argument("--tags", type=int, default=40, help="Number of tags per repository (default: 40)")
+    parser.add_argument("--seed", type=int, default=0, help="Seed of the synthetic dataset (default: 0)")
+    parser.add_argument(
+        "--base-images", type=int, default=4, help="Number of base images the repositories share (default: 4)"
+    )
+    parser.add_argument(
+        "--latency-ms",
+        type=float,
+        default=0.0,
+        help="Delay added to every in-memory registry call, in milliseconds (default: 0)",
+    )
+    parser.add_argument(
+        "--registry",
+        help="Push the dataset to this registry (host:port, accepting anonymous pushes) and scan it there",
+    )
+    parser.add_argument("--disk-index", action="store_true", help="Use the on-disk (SQLite) image index")
+    parser.add_argument(
+        "--plan-older-than-days",
+        type=int,
+        default=365,
+        help="Age above which the benchmark policy deletes images (default: 365)",
+    )
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for scanning (default: from config)"
+    )
+    parser.add_argument("--verbose", action="store_true", help="Show the scan's progress messages")
+    parser.add_argument("--output", help="Output file path (default: reports/bench-results.json)")
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        if args.repos < 1 or args.tags < 1:
+            raise ValueError("--repos and --tags must be at least 1")
+        if args.max_workers is None:
+            args.max_workers = config_manager.get_max_workers()
+
+        logger.info("=" * 80)
+        logger.info("   Scan, Index and Plan Benchmark")
+        logger.info("=" * 80)
+
+        with tempfile.TemporaryDirectory(prefix="bench-index-") as index_dir:
+            result = run_bench(args, index_dir)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "bench-results.json")
+
+        saved_path = save_json(output_path, result, timestamp=True)
+        logger.info(f"\nResults saved to: {saved_path}")
+
+        print_report_summary(result)
+
+    except Exception as e:
+        logger.error(f"\n❌ Benchmark failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
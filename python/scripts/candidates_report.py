@@ -0,0 +1,400 @@
+#!/usr/bin/env python3
+"""
+Deletion Candidates Report
+
+This script ranks every analyzed image as a deletion candidate. Each image is
+scored on its age, the bytes only it holds, how often and how recently Domino
+workloads used it, and whether current configuration (workspaces, models,
+scheduler jobs, project and organization defaults, app versions) still
+references it. Referenced images are protected and listed separately, as are
+images the protection providers in protection.providers protect - running
+pods, a reference file, recent ECR pulls (see utils/protection_providers.py).
+Each protected image lists the providers that protected it.
+
+Factor weights come from analysis.candidate_weights in config.yaml. Vulnerability
+counts from an external scanner can be supplied with --vulnerabilities to rank
+images with more known vulnerabilities higher. Images built with an old Docker
+engine or on an end-of-life base image (analysis.toolchain) are ranked higher
+and listed as rebuild recommendations. Images past the expiry set at build time
+(analysis.expiry_keys, e.g. a quay.expires-after label) are ranked first.
+
+Each candidate carries its estimated savings if deleted on its own, and the
+cumulative savings of deleting it together with every higher-ranked candidate.
+The cumulative savings curve shows how far down the list is worth going.
+
+--configmap FILE also writes the protected images as a Kubernetes ConfigMap
+manifest for admission controllers or other cluster-side tooling (see
+utils/protection_manifest.py).
+
+Usage examples:
+  # Rank all images
+  python candidates_report.py
+
+  # Rank by age and size only, without MongoDB usage data
+  python candidates_report.py --skip-usage
+
+  # Show the top 50 candidates and save to a specific file
+  python candidates_report.py --top 50 --output candidates.json
+
+  # Include vulnerability counts from a scanner export
+  python candidates_report.py --vulnerabilities cve-counts.json
+
+  # Also write the protected images as a ConfigMap and apply it
+  python candidates_report.py --configmap protected-images.yaml && kubectl apply -f protected-images.yaml
+"""
+
+import argparse
+import json
+import sys
+from collections import Counter
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.deletion_candidates import TagUsage, rank_candidates, savings_curve
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.protection_manifest import DEFAULT_CONFIGMAP_NAME, protected_references, protection_configmap, save_manifest
+from utils.protection_providers import DOMINO_PROVIDER, Protections, collect_protections, configured_providers
+from utils.report_utils import ensure_mongodb_reports, save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def load_vulnerability_counts(analyzer: ImageAnalyzer, input_file: str, image_types: List[str]) -> Dict[str, int]:
+    """Load vulnerability counts exported from an image scanner.
+
+    The file is a JSON object mapping images (<type>:<tag> or bare tag) to their
+    number of known vulnerabilities.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        input_file: JSON file path
+        image_types: Image types searched for bare tags
+
+    Returns:
+        Dict of image_id -> vulnerability count for images found in the registry
+    """
+    with open(input_file, "r") as f:
+        data = json.load(f)
+    if not isinstance(data, dict):
+        raise ValueError(f"{input_file} must contain a JSON object mapping images to vulnerability counts")
+
+    counts: Dict[str, int] = {}
+    for reference, count in data.items():
+        image_id = analyzer.resolve_image_id(reference, image_types)
+        if not image_id:
+            logger.debug(f"Vulnerability count for '{reference}' does not match an analyzed image")
+            continue
+        try:
+            counts[image_id] = int(count)
+        except (ValueError, TypeError):
+            raise ValueError(f"Vulnerability count for '{reference}' must be an integer, got: {count}")
+    logger.info(f"Loaded vulnerability counts for {len(counts)} of {len(data)} images in {input_file}")
+    return counts
+
+
+def generate_candidates_report(
+    analyzer: ImageAnalyzer,
+    usage: Optional[Dict[str, TagUsage]] = None,
+    weights: Optional[Dict[str, float]] = None,
+    vulnerabilities: Optional[Dict[str, int]] = None,
+    protections: Optional[Protections] = None,
+) -> Dict:
+    """Generate the ranked deletion candidates report.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        usage: Usage by tag from ImageUsageService.summarize_tag_usage, or None without usage data
+        weights: Factor weights (default: analysis.candidate_weights from config)
+        vulnerabilities: Vulnerability counts by image_id, if available
+        protections: Images the configured protection providers protect (collect_protections)
+
+    Returns:
+        Dict with summary, weights, candidates, savings_curve, protected images and
+        rebuild recommendations (protected images built with an outdated toolchain)
+    """
+    weights = weights or config_manager.get_candidate_score_weights()
+    expiries = analyzer.image_expiries()
+    candidates, protected = rank_candidates(
+        analyzer,
+        usage,
+        weights,
+        vulnerabilities=vulnerabilities,
+        outdated_toolchain=analyzer.toolchain_status(),
+        expiries=expiries,
+        protections=protections,
+    )
+    # Outdated images in use cannot simply be deleted, so they are worth rebuilding on a current base
+    outdated = analyzer.outdated_toolchains()
+    protected_ids = {image["image_id"] for image in protected}
+    rebuild = [image for image in outdated if image["image_id"] in protected_ids]
+    total_savings = candidates[-1]["cumulative_savings_bytes"] if candidates else 0
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_images": len(analyzer.images),
+            "candidates": len(candidates),
+            "protected_images": len(protected),
+            "protected_by_provider": dict(Counter(provider for image in protected for provider in image["providers"])),
+            "usage_data": usage is not None,
+            "vulnerability_data": bool(vulnerabilities),
+            "outdated_toolchain_images": len(outdated),
+            "expired_images": sum(1 for expiry in expiries.values() if expiry["expired"]),
+            "zero_impact_candidates": sum(1 for candidate in candidates if candidate["pullable_from"]),
+            "total_savings_bytes": total_savings,
+            "total_savings_gb": round(total_savings / (1024**3), 2),
+            "generated_at": datetime.now().isoformat(),
+        },
+        "weights": dict(weights),
+        "candidates": candidates,
+        "savings_curve": savings_curve(candidates),
+        "protected": protected,
+        "rebuild_recommendations": rebuild,
+    }
+
+
+def save_protection_configmap(
+    analyzer: ImageAnalyzer, report_data: Dict, output_path: str, namespace: str, name: str
+) -> int:
+    """Write the protected images of a report as a Kubernetes ConfigMap manifest.
+
+    Args:
+        analyzer: ImageAnalyzer instance the report was generated from
+        report_data: Report from generate_candidates_report
+        output_path: Manifest file path
+        namespace: Namespace of the ConfigMap
+        name: Name of the ConfigMap
+
+    Returns:
+        Number of protected images listed
+    """
+    digests = {image_id: image_data.get("digest", "") for image_id, image_data in analyzer.images.items()}
+    references = protected_references(report_data["protected"], analyzer.registry_url, digests)
+    save_manifest(protection_configmap(references, analyzer.registry_url, namespace, name), output_path)
+    return len(references)
+
+
+def print_report_summary(report_data: Dict, top: int) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Deletion Candidates")
+    logger.info("=" * 80)
+    logger.info(f"Images analyzed: {summary['total_images']}")
+    logger.info(f"Candidates: {summary['candidates']}")
+    logger.info(f"Protected: {summary['protected_images']}")
+    for provider, count in sorted(summary["protected_by_provider"].items()):
+        logger.info(f"   by {provider}: {count}")
+    if summary["expired_images"]:
+        logger.info(f"Past the expiry set at build time: {summary['expired_images']} (ranked first unless protected)")
+    if summary["zero_impact_candidates"]:
+        logger.info(
+            f"Zero user impact (content still pullable from a protected image): {summary['zero_impact_candidates']}"
+        )
+    logger.info(f"Savings if every candidate is deleted: {sizeof_fmt(summary['total_savings_bytes'])}")
+    if not summary["usage_data"]:
+        logger.info("Usage data was not loaded: ranked by age and size only, in-use images not protected")
+    if report_data["rebuild_recommendations"]:
+        logger.warning(
+            f"Protected images built with an outdated toolchain, worth rebuilding: "
+            f"{len(report_data['rebuild_recommendations'])}"
+        )
+
+    logger.info(f"\nTop {min(top, summary['candidates'])} candidates:")
+    logger.info(f"{'Rank':>5}  {'Score':>6}  {'Age':>7}  {'Uses':>5}  {'Savings':>10}  {'Cumulative':>10}  Image")
+    for candidate in report_data["candidates"][:top]:
+        age = f"{candidate['age_days']:.0f}d" if candidate["age_days"] is not None else "-"
+        uses = candidate["use_count"] if candidate["use_count"] is not None else "-"
+        notes = f" (expired {candidate['expires_at'][:10]})" if candidate["expired"] else ""
+        if candidate["pullable_from"]:
+            notes += " (zero user impact)"
+        logger.info(
+            f"{candidate['rank']:>5}  {candidate['score']:>6.3f}  {age:>7}  {uses:>5}  "
+            f"{sizeof_fmt(candidate['estimated_savings_bytes']):>10}  "
+            f"{sizeof_fmt(candidate['cumulative_savings_bytes']):>10}  {candidate['image_id']}{notes}"
+        )
+
+    if report_data["savings_curve"]:
+        logger.info("\nCumulative savings curve:")
+        for point in report_data["savings_curve"]:
+            logger.info(f"   Top {point['candidates']:>6}: {sizeof_fmt(point['savings_bytes'])}")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Rank images as deletion candidates with estimated and cumulative savings",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Rank all images
+  python candidates_report.py
+
+  # Rank by age and size only, without MongoDB usage data
+  python candidates_report.py --skip-usage
+
+  # Rank environment images only
+  python candidates_report.py --image-types environment
+
+  # Show the top 50 candidates and save to a specific file
+  python candidates_report.py --top 50 --output candidates.json
+
+  # Include vulnerability counts from a scanner export
+  python candidates_report.py --vulnerabilities cve-counts.json
+
+  # Also write the protected images as a Kubernetes ConfigMap
+  python candidates_report.py --configmap protected-images.yaml
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: candidates-report.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to analyze (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--top", type=int, default=20, metavar="N", help="Number of candidates to print (default: 20)"
+    )
+
+    parser.add_argument(
+        "--skip-usage",
+        action="store_true",
+        help="Do not load MongoDB usage data; rank by age and size only (protection.providers still protect images)",
+    )
+
+    parser.add_argument(
+        "--vulnerabilities",
+        metavar="FILE",
+        help="JSON file mapping images (<type>:<tag> or bare tag) to known vulnerability counts",
+    )
+
+    parser.add_argument(
+        "--configmap",
+        metavar="FILE",
+        help="Also write the protected images as a Kubernetes ConfigMap manifest to FILE",
+    )
+
+    parser.add_argument(
+        "--configmap-name",
+        default=DEFAULT_CONFIGMAP_NAME,
+        help=f"Name of the ConfigMap (default: {DEFAULT_CONFIGMAP_NAME})",
+    )
+
+    parser.add_argument(
+        "--configmap-namespace",
+        help="Namespace of the ConfigMap (default: the Domino platform namespace)",
+    )
+
+    parser.add_argument(
+        "--generate-reports",
+        action="store_true",
+        help="Force regeneration of MongoDB usage reports",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info("=" * 80)
+        logger.info("   Deletion Candidates Report")
+        logger.info("=" * 80)
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        logger.info("=" * 80)
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"Analyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        usage = None
+        if args.skip_usage:
+            logger.warning("⚠️  Skipping usage data: images in use will not be protected")
+        else:
+            ensure_mongodb_reports(force=args.generate_reports)
+            from utils.image_usage import ImageUsageService
+
+            tags = [image_data["tag"] for image_data in analyzer.images.values()]
+            usage = ImageUsageService().summarize_tag_usage(tags)
+
+        # Domino usage protects images whenever it is loaded; the domino provider adds nothing here
+        names = [name for name in config_manager.get_protection_providers() if name != DOMINO_PROVIDER]
+        protections = collect_protections(analyzer, configured_providers(names))
+
+        vulnerabilities = None
+        if args.vulnerabilities:
+            vulnerabilities = load_vulnerability_counts(analyzer, args.vulnerabilities, args.image_types)
+
+        weights = config_manager.get_candidate_score_weights()
+        logger.info("Score weights: " + ", ".join(f"{name}={weight:g}" for name, weight in weights.items()))
+        report_data = generate_candidates_report(analyzer, usage, weights, vulnerabilities, protections)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "candidates-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        if args.configmap:
+            if not report_data["summary"]["usage_data"]:
+                logger.warning("⚠️  Usage data was not loaded: the ConfigMap lists no images Domino uses")
+            namespace = args.configmap_namespace or config_manager.get_domino_platform_namespace()
+            count = save_protection_configmap(analyzer, report_data, args.configmap, namespace, args.configmap_name)
+            logger.info(
+                f"ConfigMap {namespace}/{args.configmap_name} ({count} protected images) saved to: {args.configmap}"
+            )
+
+        print_report_summary(report_data, args.top)
+
+        logger.info("\n✅ Candidates report generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,250 @@
+#!/usr/bin/env python3
+"""
+Chargeback Report
+
+This script produces a per-owner storage bill for the registry. Images are
+attributed to owners from their labels, as in owner_usage_report, and each
+owner is billed for its amortized bytes (every layer's size split evenly
+between the images using it, so the bills add up to the registry's total) at
+analysis.storage_cost_per_gb_month from config.yaml (or --cost-per-gb).
+
+Next to the bill, the report lists each owner's exclusive bytes (what deleting
+all of its images would free), its growth since an earlier scan snapshot (by
+default the newest saved snapshot older than the scan being billed) and, when
+owner quotas are configured (quotas in config.yaml), its quota. Owners over
+quota are listed in the summary and logged as warnings.
+
+Usage examples:
+  # Bill owners for the current registry contents
+  python chargeback_report.py
+
+  # Bill from a saved snapshot, with growth since a chosen earlier one
+  python chargeback_report.py --snapshot reports/scan-snapshot-<new>.json --previous reports/scan-snapshot-<old>.json
+
+  # Also export the bill as CSV, at a different storage price
+  python chargeback_report.py --csv --cost-per-gb 0.05
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List, Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.chargeback import CSV_FIELDS, build_chargeback, storage_cost
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.ownership import UNKNOWN, UNLABELED
+from utils.report_utils import save_csv, save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import find_snapshots, load_snapshot
+
+logger = get_logger(__name__)
+
+
+def find_previous_snapshot(current: Optional[str]) -> Optional[str]:
+    """Newest saved snapshot older than the current one (any saved snapshot for a live scan)"""
+    snapshots = [path for path, _ in find_snapshots(config_manager.get_snapshot_path())]
+    if current is None:
+        return str(snapshots[0]) if snapshots else None
+    resolved = [path.resolve() for path in snapshots]
+    current_path = Path(current).resolve()
+    if current_path not in resolved:
+        return None
+    index = resolved.index(current_path)
+    return str(snapshots[index + 1]) if index + 1 < len(snapshots) else None
+
+
+def scan_registry(image_types: List[str], max_workers: Optional[int]) -> ImageAnalyzer:
+    """Analyze the configured repository's images of the given types"""
+    analyzer = ImageAnalyzer(config_manager.get_registry_url(), config_manager.get_repository())
+    success_count = 0
+    for image_type in image_types:
+        logger.info(f"\nAnalyzing {image_type} images...")
+        if analyzer.analyze_image(image_type, object_ids=None, max_workers=max_workers):
+            success_count += 1
+    if success_count == 0:
+        raise RuntimeError("No image data found. Check your registry access.")
+    return analyzer
+
+
+def print_report_summary(report_data: Dict, top: int) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+    owners = report_data["owners"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Registry Storage Chargeback")
+    logger.info("=" * 80)
+    logger.info(f"Owners: {summary['total_owners']}")
+    logger.info(f"Registry storage: {sizeof_fmt(summary['total_bytes'])}")
+    logger.info(f"Monthly cost: {summary['total_cost']:.2f} at {summary['cost_per_gb_month']:g} per GB-month")
+    if summary["previous_snapshot"]:
+        logger.info(f"Growth since {summary['previous_snapshot']}: {_signed_size(summary['growth_bytes'])}")
+    else:
+        logger.info("Growth: no earlier snapshot to compare with")
+
+    logger.info(f"\nTop {min(top, len(owners))} owners by cost:")
+    logger.info(f"{'Cost':>10}  {'Billed':>10}  {'Exclusive':>10}  {'Growth':>11}  {'Images':>6}  Owner")
+    for entry in owners[:top]:
+        growth = _signed_size(entry["growth_bytes"]) if entry["growth_bytes"] is not None else "—"
+        logger.info(
+            f"{entry['cost']:>10.2f}  {sizeof_fmt(entry['amortized_bytes']):>10}  "
+            f"{sizeof_fmt(entry['exclusive_bytes']):>10}  {growth:>11}  {entry['images']:>6}  {entry['owner']}"
+        )
+    logger.info("=" * 80)
+
+
+def _signed_size(size_bytes: int) -> str:
+    """Human-readable size with a sign"""
+    return ("-" if size_bytes < 0 else "+") + sizeof_fmt(abs(size_bytes))
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Bill owners (from image labels) for their registry storage, with cost and growth per owner",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Bill owners for the current registry contents
+  python chargeback_report.py
+
+  # Bill from a saved snapshot, with growth since a chosen earlier one
+  python chargeback_report.py --snapshot reports/scan-snapshot-<new>.json --previous reports/scan-snapshot-<old>.json
+
+  # Also export the bill as CSV, at a different storage price
+  python chargeback_report.py --csv --cost-per-gb 0.05
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: chargeback-report.json in reports directory)"
+    )
+    parser.add_argument("--csv", action="store_true", help="Also save the bill as CSV, next to the JSON report")
+    parser.add_argument("--snapshot", metavar="FILE", help="Bill from a saved scan snapshot instead of the registry")
+    parser.add_argument(
+        "--previous",
+        metavar="FILE",
+        help="Earlier scan snapshot to compute growth from (default: newest saved snapshot older than the scan)",
+    )
+    parser.add_argument(
+        "--cost-per-gb",
+        type=float,
+        metavar="PRICE",
+        help="Storage price per GB and month (default: analysis.storage_cost_per_gb_month from config)",
+    )
+    parser.add_argument(
+        "--label",
+        action="append",
+        dest="labels",
+        metavar="KEY",
+        help="Owner label key; repeat to fall back to further labels (default: analysis.owner_labels from config)",
+    )
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to scan when not billing from a snapshot (default: the profile's image types)",
+    )
+    parser.add_argument("--top", type=int, default=20, metavar="N", help="Number of owners to print (default: 20)")
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    args = parser.parse_args()
+    if args.cost_per_gb is not None and args.cost_per_gb < 0:
+        parser.error("--cost-per-gb must not be negative")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        label_keys = args.labels or config_manager.get_owner_label_keys()
+        cost_per_gb = args.cost_per_gb
+        if cost_per_gb is None:
+            cost_per_gb = config_manager.get_storage_cost_per_gb_month()
+
+        logger.info("=" * 80)
+        logger.info("   Chargeback Report")
+        logger.info("=" * 80)
+        logger.info(f"Source: {args.snapshot or 'registry'}")
+        logger.info(f"Owner labels: {', '.join(label_keys)}")
+        logger.info("=" * 80)
+
+        if args.snapshot:
+            analyzer, _, _ = load_snapshot(args.snapshot)
+        else:
+            analyzer = scan_registry(args.image_types, args.max_workers)
+
+        previous_snapshot = args.previous or find_previous_snapshot(args.snapshot)
+        previous_usage = None
+        if previous_snapshot:
+            logger.info(f"Computing growth since {previous_snapshot}")
+            previous_analyzer, _, _ = load_snapshot(previous_snapshot)
+            previous_usage = previous_analyzer.owner_usage(label_keys)
+        else:
+            logger.warning("No earlier scan snapshot found; growth is not reported")
+
+        quotas = config_manager.get_owner_quotas()
+        owners = build_chargeback(analyzer.owner_usage(label_keys), previous_usage, cost_per_gb, quotas)
+        over_quota = [entry for entry in owners if entry["over_quota_bytes"]]
+        total_bytes = sum(entry["amortized_bytes"] for entry in owners)
+        summary = {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "source": args.snapshot or "registry",
+            "previous_snapshot": previous_snapshot,
+            "owner_labels": label_keys,
+            "cost_per_gb_month": cost_per_gb,
+            "total_owners": len([entry for entry in owners if entry["owner"] not in (UNLABELED, UNKNOWN)]),
+            "total_bytes": total_bytes,
+            "total_cost": storage_cost(total_bytes, cost_per_gb),
+            "owners_over_quota": [entry["owner"] for entry in over_quota],
+            "growth_bytes": sum(entry["growth_bytes"] or 0 for entry in owners) if previous_usage is not None else None,
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {"summary": summary, "owners": owners}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "chargeback-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+        if args.csv:
+            csv_path = save_csv(str(Path(saved_path).with_suffix(".csv")), owners, CSV_FIELDS)
+            logger.info(f"CSV saved to: {csv_path}")
+
+        print_report_summary(report_data, args.top)
+        for entry in sorted(over_quota, key=lambda entry: -entry["over_quota_bytes"]):
+            logger.warning(
+                f"⚠️  Owner {entry['owner']} is {sizeof_fmt(entry['over_quota_bytes'])} over its quota of "
+                f"{sizeof_fmt(entry['quota_bytes'])}"
+            )
+
+        logger.info("\n✅ Chargeback report completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Chargeback report failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,189 @@
+#!/usr/bin/env python3
+"""
+Clean
+
+This script deletes images from the registry in one step: it plans the
+deletion of the images a retention policy or an explicit tag list selects
+(plan.py), then applies that plan (apply.py). Every safeguard of the two steps
+applies - protection providers, the real-time usage check, digest re-checks,
+Docker Content Trust, archive copies, checkpoints - and the plan is kept in the
+reports directory as the record of what was deleted.
+
+Runs in dry-run mode unless --apply is given: the dry run prints the images
+that would be deleted and the estimated bytes reclaimed, and deletes nothing.
+
+Images are selected by one of:
+  --policy FILE   Images a retention policy deletes, evaluated against a scan
+                  snapshot (--snapshot, default: the newest saved snapshot)
+  --tags TAG ...  The listed images (<type>:<tag>, or a bare tag)
+  --input FILE    The images of a candidate file (one <type>:<tag> or bare tag per line)
+
+Usage examples:
+  # Dry-run: what a retention policy would delete, and the bytes reclaimed
+  python clean.py --policy policy.yaml
+
+  # Delete what the policy selects (requires confirmation)
+  python clean.py --policy policy.yaml --apply
+
+  # Delete two images without confirmation prompt
+  python clean.py --tags environment:abc-1 model:def-2 --apply --force
+"""
+
+import argparse
+import subprocess
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.config_manager import config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.scan_snapshot import find_snapshots
+
+logger = get_logger(__name__)
+
+_SCRIPT_DIR = Path(__file__).parent
+
+
+def plan_arguments(args: argparse.Namespace, plan_path: str, tags_path: str) -> List[str]:
+    """Arguments of the plan step
+
+    Args:
+        args: Parsed arguments of clean
+        plan_path: Where the plan step saves the plan
+        tags_path: Candidate file --tags are written to
+    """
+    if args.policy:
+        snapshot = args.snapshot
+        if not snapshot:
+            snapshots = find_snapshots(config_manager.get_snapshot_path())
+            if not snapshots:
+                raise RuntimeError(
+                    "No scan snapshots found for --policy; save one with image_data_analysis.py --mode snapshot "
+                    "or name one with --snapshot"
+                )
+            snapshot = str(snapshots[0][0])
+            logger.info(f"Evaluating the policy against the newest snapshot: {snapshot}")
+        selection = ["--policy", args.policy, "--snapshot", snapshot]
+    elif args.tags:
+        Path(tags_path).parent.mkdir(parents=True, exist_ok=True)
+        with open(tags_path, "w") as f:
+            f.writelines(f"{tag}\n" for tag in args.tags)
+        selection = ["--input", tags_path]
+    else:
+        selection = ["--input", args.input]
+    plan_args = selection + ["--output", plan_path]
+    if args.image_types:
+        plan_args += ["--image-types"] + args.image_types
+    return plan_args
+
+
+def apply_arguments(args: argparse.Namespace, plan_path: str) -> List[str]:
+    """Arguments of the apply step"""
+    apply_args = [plan_path]
+    if args.apply:
+        apply_args.append("--apply")
+    if args.force:
+        apply_args.append("--force")
+    if args.allow_signed:
+        apply_args.append("--allow-signed")
+    return apply_args
+
+
+def run_step(script: str, arguments: List[str]) -> None:
+    """Run plan.py or apply.py, exiting with its status if it fails"""
+    result = subprocess.run([sys.executable, str(_SCRIPT_DIR / script)] + arguments)
+    if result.returncode != 0:
+        logger.error(f"\n❌ {script} failed with status {result.returncode}")
+        sys.exit(result.returncode)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Delete the images a retention policy or tag list selects (plan, then apply); dry run by default",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Dry-run: what a retention policy would delete, and the bytes reclaimed
+  python clean.py --policy policy.yaml
+
+  # Delete what the policy selects, evaluated against a named snapshot
+  python clean.py --policy policy.yaml --snapshot reports/scan-snapshot-<timestamp>.json --apply
+
+  # Delete two images without confirmation prompt
+  python clean.py --tags environment:abc-1 model:def-2 --apply --force
+
+  # Delete the images of a candidate file
+  python clean.py --input candidates.txt --apply
+        """,
+    )
+
+    source = parser.add_mutually_exclusive_group(required=True)
+    source.add_argument("--policy", metavar="FILE", help="Delete the images a retention policy file deletes")
+    source.add_argument("--tags", nargs="+", metavar="TAG", help="Delete these images (<type>:<tag> or bare tag)")
+    source.add_argument("--input", metavar="FILE", help="Delete the images of a candidate file")
+
+    parser.add_argument(
+        "--snapshot", help="With --policy: scan snapshot to evaluate the policy against (default: the newest saved)"
+    )
+    parser.add_argument(
+        "--image-types", nargs="+", help="Image types to look --tags and --input up in (default: the profile's)"
+    )
+
+    mode = parser.add_mutually_exclusive_group()
+    mode.add_argument(
+        "--dry-run",
+        action="store_true",
+        help="Print what would be deleted and the estimated bytes reclaimed, without deleting (default)",
+    )
+    mode.add_argument("--apply", action="store_true", help="Delete the images (default is dry-run)")
+
+    parser.add_argument("--force", action="store_true", help="With --apply: skip the confirmation prompt")
+    parser.add_argument("--allow-signed", action="store_true", help="Also delete tags signed with Docker Content Trust")
+    parser.add_argument(
+        "--plan-output", metavar="FILE", help="Where to keep the plan (default: clean-plan-<timestamp>.json in reports)"
+    )
+
+    args = parser.parse_args()
+    if args.snapshot and not args.policy:
+        parser.error("--snapshot only applies to --policy")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        timestamp = datetime.now().strftime("%Y-%m-%d-%H-%M-%S")
+        reports_dir = Path(config_manager.get_output_dir())
+        plan_path = args.plan_output or str(reports_dir / f"clean-plan-{timestamp}.json")
+        plan_args = plan_arguments(args, plan_path, str(reports_dir / f"clean-tags-{timestamp}.txt"))
+    except Exception as e:
+        logger.error(f"\n❌ Clean failed: {e}")
+        sys.exit(1)
+
+    logger.info("=" * 60)
+    logger.info(f"   Clean ({'DELETE MODE' if args.apply else 'DRY RUN'})")
+    logger.info("=" * 60)
+
+    logger.info("Step 1/2: Plan the deletion")
+    run_step("plan.py", plan_args)
+
+    logger.info("\nStep 2/2: Apply the plan")
+    run_step("apply.py", apply_arguments(args, plan_path))
+
+    logger.info(f"\nPlan kept as the record of this clean: {plan_path}")
+    if not args.apply:
+        logger.info("DRY RUN complete - no images were deleted. Use --apply to delete them.")
+
+
+if __name__ == "__main__":
+    main()
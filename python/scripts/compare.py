@@ -0,0 +1,255 @@
+#!/usr/bin/env python3
+"""
+Registry Comparison
+
+This script compares the same repositories in two registries — for example a
+primary registry and its pull-through mirror, or a registry and the registry it
+is being migrated to — and reports missing and mismatched content:
+
+- Repositories that only one registry has
+- Tags that only one registry has
+- Tags both registries have that point to different manifest digests
+
+With --plan, only the images of a cleanup plan are compared, against the
+digests the plan recorded, to validate a backup registry before the plan is
+applied.
+
+Each registry is authenticated like the configured one; use credential
+profiles (see docs/configuration.md) when the two need different credentials.
+The comparison is read-only. The command is also available as diff-registries.
+
+Usage examples:
+  # Compare the environment and model repositories of two registries
+  python compare.py docker-registry:5000 mirror.example.com
+
+  # Compare every repository either registry lists in its catalog
+  python compare.py docker-registry:5000 mirror.example.com --all-repositories
+
+  # Compare tag lists only, without reading manifest digests
+  python compare.py docker-registry:5000 mirror.example.com --tags-only
+
+  # Check that a backup registry holds every image a cleanup plan deletes
+  python compare.py docker-registry:5000 backup.example.com --plan reports/cleanup-plan-<timestamp>.json
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.cleanup_plan import load_plan
+from utils.config_manager import SkopeoClient, config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.registry_compare import ONLY_IN_A, ONLY_IN_B, compare_plan, compare_repository, summarize
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def normalize_registry(registry: str) -> str:
+    """Registry host[:port] from a registry argument, which may include a scheme"""
+    return registry.split("://", 1)[-1].rstrip("/")
+
+
+def select_repositories(args: argparse.Namespace, client_a: SkopeoClient, client_b: SkopeoClient) -> List[str]:
+    """Repositories to compare, from the arguments or the registries' catalogs"""
+    if args.repositories:
+        return sorted(set(args.repositories))
+    if args.all_repositories:
+        repositories = set()
+        for client in (client_a, client_b):
+            listed = client.list_repositories()
+            if listed is None:
+                raise RuntimeError(
+                    f"{client.registry_url} does not allow listing its repositories; name them with --repositories"
+                )
+            repositories.update(listed)
+        return sorted(repositories)
+    repository = args.repository or config_manager.get_repository()
+    return [f"{repository}/{image_type}" for image_type in args.image_types]
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+    registry_a, registry_b = summary["registry_a"], summary["registry_b"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info(f"   Registry Comparison: {registry_a} (A) vs {registry_b} (B)")
+    logger.info("=" * 80)
+    logger.info(f"Repositories compared: {summary['repositories']} ({summary['repositories_matching']} identical)")
+    logger.info(f"Repositories only in A: {summary['repositories_only_in_a']}")
+    logger.info(f"Repositories only in B: {summary['repositories_only_in_b']}")
+    logger.info(f"Tags only in A: {summary['tags_only_in_a']}")
+    logger.info(f"Tags only in B: {summary['tags_only_in_b']}")
+    if summary["digests_compared"]:
+        logger.info(f"Tags with different digests: {summary['digest_mismatches']}")
+        logger.info(f"Tags whose digest could not be read: {summary['unreadable_tags']}")
+
+    for comparison in report_data["repositories"]:
+        if comparison["status"] in (ONLY_IN_A, ONLY_IN_B):
+            side = "A" if comparison["status"] == ONLY_IN_A else "B"
+            logger.info(f"\n{comparison['repository']}: only in {side}")
+            continue
+        if not (comparison["tags_only_in_a"] or comparison["tags_only_in_b"] or comparison["digest_mismatches"]):
+            continue
+        logger.info(f"\n{comparison['repository']}: {comparison['tags_a']} tags in A, {comparison['tags_b']} in B")
+        for label, tags in (("only in A", comparison["tags_only_in_a"]), ("only in B", comparison["tags_only_in_b"])):
+            if tags:
+                more = f" (+{len(tags) - 10} more)" if len(tags) > 10 else ""
+                logger.info(f"   {label}: {', '.join(tags[:10])}{more}")
+        for mismatch in comparison["digest_mismatches"][:10]:
+            logger.info(f"   {mismatch['tag']}: {mismatch['digest_a']} (A) != {mismatch['digest_b']} (B)")
+
+    logger.info("=" * 80)
+    if summary["identical"]:
+        logger.info("The registries hold the same tags and digests.")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Compare repositories, tags and digests between two registries",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Compare the environment and model repositories of two registries
+  python compare.py docker-registry:5000 mirror.example.com
+
+  # Compare every repository either registry lists in its catalog
+  python compare.py docker-registry:5000 mirror.example.com --all-repositories
+
+  # Compare named repositories, tag lists only
+  python compare.py docker-registry:5000 mirror.example.com --repositories dominodatalab/environment --tags-only
+
+  # Fail (exit code 1) when the registries differ, e.g. in a migration check
+  python compare.py docker-registry:5000 new-registry.example.com --fail-on-difference
+
+  # Validate the backup of a cleanup plan before applying it
+  python compare.py docker-registry:5000 backup.example.com --plan reports/cleanup-plan-<timestamp>.json \\
+      --fail-on-difference
+        """,
+    )
+
+    parser.add_argument("registry_a", help="First registry (host[:port]), e.g. the primary registry")
+    parser.add_argument("registry_b", help="Second registry (host[:port]), e.g. a mirror")
+
+    selection = parser.add_mutually_exclusive_group()
+    selection.add_argument("--repositories", nargs="+", help="Repositories to compare (full names)")
+    selection.add_argument(
+        "--all-repositories",
+        action="store_true",
+        help="Compare every repository either registry lists (needs the registry catalog API)",
+    )
+    selection.add_argument(
+        "--plan",
+        metavar="FILE",
+        help="Compare only the images of a cleanup plan of registry A, at their planned digests, "
+        "e.g. to check that registry B holds a backup of everything the plan deletes",
+    )
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to compare when no repositories are given (default: the profile's image types)",
+    )
+    parser.add_argument("--repository", help="Base repository of the image types (default: from config)")
+
+    parser.add_argument(
+        "--tags-only", action="store_true", help="Compare tag lists only, without reading manifest digests"
+    )
+    parser.add_argument(
+        "--fail-on-difference", action="store_true", help="Exit with code 1 if the registries differ"
+    )
+    parser.add_argument(
+        "--max-workers",
+        type=int,
+        help="Maximum number of parallel manifest requests per registry (default: from config)",
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: registry-comparison.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        registry_a = normalize_registry(args.registry_a)
+        registry_b = normalize_registry(args.registry_b)
+        logger.info("=" * 80)
+        logger.info(f"   Comparing {registry_a} (A) with {registry_b} (B)")
+        logger.info("=" * 80)
+
+        client_a = SkopeoClient(config_manager, registry_url=registry_a)
+        client_b = SkopeoClient(config_manager, registry_url=registry_b)
+
+        max_workers = args.max_workers or config_manager.get_max_workers()
+        if args.plan:
+            plan = load_plan(args.plan)
+            if normalize_registry(plan.registry_url) != registry_a:
+                raise RuntimeError(f"Plan {args.plan} is for registry {plan.registry_url}, not {registry_a}")
+            logger.info(f"Comparing the {len(plan.items)} image(s) of plan {plan.plan_id} with {registry_b}...")
+            comparisons = compare_plan(client_b, plan.items, max_workers, compare_digests=not args.tags_only)
+        else:
+            comparisons = []
+            for repository in select_repositories(args, client_a, client_b):
+                logger.info(f"Comparing {repository}...")
+                comparisons.append(
+                    compare_repository(
+                        client_a, client_b, repository, max_workers, compare_digests=not args.tags_only
+                    )
+                )
+
+        summary = summarize(comparisons)
+        summary.update(
+            {
+                "registry_a": registry_a,
+                "registry_b": registry_b,
+                "digests_compared": not args.tags_only,
+                "plan": args.plan,
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
+                "generated_at": datetime.now().isoformat(),
+            }
+        )
+        report_data = {"summary": summary, "repositories": comparisons}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "registry-comparison.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        if args.fail_on_difference and not summary["identical"]:
+            logger.error("\n❌ The registries differ")
+            sys.exit(1)
+        logger.info("\n✅ Registry comparison completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Registry comparison failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -47,7 +47,6 @@ Usage examples:
 """
 
 import argparse
-import json
 import sys
 from dataclasses import dataclass
 from datetime import datetime
@@ -62,6 +61,7 @@ if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
 from scripts.backup_restore import process_backup
+from utils.build_info import get_build_info
 from utils.config_manager import ConfigManager, SkopeoClient, config_manager
 from utils.deletion_base import BaseDeletionScript
 from utils.image_data_analysis import ImageAnalyzer
@@ -69,7 +69,15 @@ from utils.image_metadata import extract_model_tag_from_version_doc
 from utils.image_usage import ImageUsageService
 from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
-from utils.report_utils import ensure_mongodb_reports, get_timestamp_suffix, save_json, sizeof_fmt
+from utils.report_utils import (
+    ensure_mongodb_reports,
+    find_report_file,
+    get_timestamp_suffix,
+    load_json,
+    save_json,
+    sizeof_fmt,
+)
+from utils.request_stats import get_run_stats
 from utils.tag_matching import model_tags_match
 
 logger = get_logger(__name__)
@@ -683,7 +691,7 @@ class ArchivedTagsFinder(BaseDeletionScript):
             # self.image_types. If we only analyze the type we're deleting (e.g. --environment),
             # layer ref_count only counts references from that type. Layers shared with model
             # images would then be wrongly counted as "freed", overestimating by a large margin.
-            all_registry_image_types = ["environment", "model"]
+            all_registry_image_types = config_manager.get_image_types()
             for image_type in all_registry_image_types:
                 self.logger.info(f"Analyzing ALL {image_type} images...")
                 success = analyzer.analyze_image(image_type, object_ids=None)
@@ -1397,6 +1405,8 @@ class ArchivedTagsFinder(BaseDeletionScript):
             "summary": summary,
             "archived_tags": detailed_tags,
             "metadata": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "registry_url": self.registry_url,
                 "repository": self.repository,
                 "image_types_scanned": self.image_types,
@@ -1411,8 +1421,8 @@ class ArchivedTagsFinder(BaseDeletionScript):
     def load_archived_tags_from_file(self, file_path: str) -> List[ArchivedTagInfo]:
         """Load archived tags from a pre-generated report file"""
         try:
-            with open(file_path, "r") as f:
-                report = json.load(f)
+            path = Path(file_path)
+            report = load_json(find_report_file(path) or path)
 
             archived_tags = []
             for tag_data in report.get("archived_tags", []):
@@ -1645,6 +1655,8 @@ def main():
                     },
                     "archived_tags": [],
                     "metadata": {
+                        "build": get_build_info(),
+                        "runStats": get_run_stats(),
                         "registry_url": registry_url,
                         "repository": repository,
                         "image_types_scanned": finder.image_types,
@@ -1653,8 +1665,8 @@ def main():
                         "analysis_timestamp": datetime.now().isoformat(),
                     },
                 }
-                save_json(output_file, empty_report)
-                logger.info(f"Empty report written to {output_file}")
+                saved_path = save_json(output_file, empty_report)
+                logger.info(f"Empty report written to {saved_path}")
                 sys.exit(0)
 
             # Filter out archived environment/revision IDs that are still in use
@@ -1700,8 +1712,8 @@ def main():
                     environment_to_revisions=environment_to_revisions,
                     model_to_versions=model_to_versions,
                 )
-                save_json(output_file, report)
-                logger.info(f"Report written to {output_file}")
+                saved_path = save_json(output_file, report)
+                logger.info(f"Report written to {saved_path}")
                 sys.exit(0)
 
         # Backup-only mode: allow backing up without deletion when --backup is provided without --apply
@@ -1826,7 +1838,7 @@ def main():
             )
 
             # Save report
-            save_json(output_file, report)
+            saved_path = save_json(output_file, report)
 
             # Print summary
             summary = report["summary"]
@@ -1849,7 +1861,7 @@ def main():
             logger.info(f"ObjectIDs with tags: {summary['object_ids_with_tags']}")
             logger.info(f"ObjectIDs without tags: {summary['object_ids_without_tags']}")
 
-            logger.info(f"\nDetailed report saved to: {output_file}")
+            logger.info(f"\nDetailed report saved to: {saved_path}")
 
             if archived_tags:
                 logger.warning(
@@ -26,6 +26,18 @@ Usage examples:
   # Force deletion without confirmation
   python delete_image.py --apply --force
 
+  # Also delete tags signed with Docker Content Trust
+  python delete_image.py dominodatalab/environment:abc-123 --apply --allow-signed
+
+  # Delete a manifest by digest, with every tag pointing to it
+  python delete_image.py --digest sha256:<digest> --apply
+
+  # Delete an untagged manifest
+  python delete_image.py --digest dominodatalab/environment@sha256:<digest> --apply
+
+  # Delete tags and digests selected with jq from the analysis output
+  jq -r '.unused_images[].tag' reports/deletion-analysis.json | python delete_image.py --stdin --apply --force
+
   # Back up images to S3 before deletion
   python delete_image.py --apply --backup
 
@@ -45,13 +57,14 @@ python delete_image.py --apply --mongo-cleanup
 import argparse
 import json
 import os
+import re
 import subprocess
 import sys
 from concurrent.futures import ThreadPoolExecutor
 from dataclasses import dataclass
 from datetime import datetime, timedelta, timezone
 from pathlib import Path
-from typing import Dict, List, Optional, Set, Tuple
+from typing import Dict, List, Optional, Set, TextIO, Tuple
 
 # Add parent directory to path for imports
 _parent_dir = Path(__file__).parent.parent.absolute()
@@ -59,13 +72,15 @@ if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
 from scripts.backup_restore import process_backup
+from utils.build_info import get_build_info
 from utils.config_manager import ConfigManager, SkopeoClient, config_manager
 from utils.deletion_base import BaseDeletionScript
 from utils.image_data_analysis import ImageAnalyzer
-from utils.image_usage import ImageUsageService
+from utils.image_usage import ImageUsageService, usage_window_days
 from utils.logging_utils import get_logger, setup_logging
 from utils.object_id_utils import normalize_object_id, read_typed_object_ids_from_file
 from utils.report_utils import ensure_image_analysis_reports, ensure_mongodb_reports, save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
 
 
 @dataclass
@@ -146,12 +161,13 @@ class IntelligentImageDeleter(BaseDeletionScript):
         Supports both timestamped and non-timestamped report files.
         If exact file doesn't exist, finds the most recent timestamped version.
         """
-        from utils.report_utils import get_latest_report, get_reports_dir
+        from utils.report_utils import find_report_file, get_latest_report, get_reports_dir, load_json
 
         if report_path is None:
             report_path = config_manager.get_image_analysis_path()
 
         report_file = Path(report_path)
+        report_file = find_report_file(report_file) or report_file
 
         # If exact file doesn't exist, try to find latest timestamped version
         if not report_file.exists():
@@ -165,8 +181,7 @@ class IntelligentImageDeleter(BaseDeletionScript):
                 self.logger.info(f"Using latest timestamped report: {report_file.name}")
 
         try:
-            with open(report_file, "r") as f:
-                return json.load(f)
+            return load_json(report_file)
         except FileNotFoundError:
             self.logger.error(f"Image analysis report not found: {report_path}")
             return {}
@@ -333,6 +348,7 @@ class IntelligentImageDeleter(BaseDeletionScript):
                     "projects": [],
                     "organizations": [],
                     "app_versions": [],
+                    "references": [],
                 }
 
                 # Filter runs
@@ -352,13 +368,14 @@ class IntelligentImageDeleter(BaseDeletionScript):
                         # No timestamp - keep it (conservative)
                         filtered_info["workspaces"].append(workspace)
 
-                # Keep models, scheduler_jobs, projects, organizations, app_versions as-is
+                # Keep models, scheduler_jobs, projects, organizations, app_versions, references as-is
                 # (these don't have timestamps in the usage info, so we keep them if the tag has recent usage)
                 filtered_info["models"] = usage_info.get("models", [])
                 filtered_info["scheduler_jobs"] = usage_info.get("scheduler_jobs", [])
                 filtered_info["projects"] = usage_info.get("projects", [])
                 filtered_info["organizations"] = usage_info.get("organizations", [])
                 filtered_info["app_versions"] = usage_info.get("app_versions", [])
+                filtered_info["references"] = usage_info.get("references", [])
 
                 filtered_usage[tag] = filtered_info
 
@@ -546,6 +563,7 @@ class IntelligentImageDeleter(BaseDeletionScript):
             )
 
         # Filter usage by age if recent_days is specified
+        recent_days = usage_window_days(recent_days)
         if recent_days is not None and recent_days > 0:
             self.logger.info(f"Filtering usage to only include activity within the last {recent_days} days")
             threshold = datetime.now(timezone.utc) - timedelta(days=recent_days)
@@ -1018,7 +1036,7 @@ class IntelligentImageDeleter(BaseDeletionScript):
 
             # CRITICAL: Analyze ALL images (not just unused ones) to get accurate reference counts
             # This ensures that shared layers between unused and used images are properly accounted for
-            image_types = ["environment", "model"]
+            image_types = config_manager.get_image_types()
             for image_type in image_types:
                 self.logger.info(f"Analyzing ALL {image_type} images...")
                 success = analyzer.analyze_image(image_type, object_ids=None)
@@ -1077,6 +1095,8 @@ class IntelligentImageDeleter(BaseDeletionScript):
         """Generate a detailed deletion analysis report"""
         report = {
             "summary": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "total_images_analyzed": len(analysis.used_images) + len(analysis.unused_images),
                 "used_images": len(analysis.used_images),
                 "unused_images": len(analysis.unused_images),
@@ -1190,6 +1210,8 @@ class IntelligentImageDeleter(BaseDeletionScript):
             "dry_run": dry_run,
             "timestamp": datetime.now(timezone.utc).isoformat() + "Z",
             "summary": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "total_images_analyzed": len(analysis.used_images) + len(analysis.unused_images),
                 "used_images": len(analysis.used_images),
                 "unused_images": len(analysis.unused_images),
@@ -1429,9 +1451,9 @@ class IntelligentImageDeleter(BaseDeletionScript):
                         self.logger.warning("    ❌ Failed to delete")
                         failed_deletions += 1
                 else:
-                    # Try both environment and model repositories
+                    # Try the repository of each image type
                     deleted = False
-                    for repo_type in ["environment", "model"]:
+                    for repo_type in config_manager.get_image_types():
                         try_repo = f"{self.repository}/{repo_type}"
                         self.logger.info(f"  Trying to delete: {try_repo}:{tag}")
                         if self.skopeo_client.delete_image(try_repo, tag):
@@ -1593,6 +1615,210 @@ class IntelligentImageDeleter(BaseDeletionScript):
                 pass  # Ignore cleanup errors
 
 
+DIGEST_PATTERN = re.compile(r"^(?:(?P<repository>[^@\s]+)@)?(?P<digest>sha256:[0-9a-f]{64})$")
+
+
+def parse_digest_reference(reference: str, registry_url: str) -> Tuple[Optional[str], str]:
+    """Parse a --digest value: "sha256:<hex>" or "<repository>@sha256:<hex>".
+
+    Returns:
+        (repository or None, digest); a registry host prefix is removed from the repository
+
+    Raises:
+        ValueError: If the value is not a sha256 digest reference
+    """
+    match = DIGEST_PATTERN.match(reference.strip())
+    if not match:
+        raise ValueError(f"Expected sha256:<64 hex digits> or <repository>@sha256:<64 hex digits>, got: {reference}")
+    repository = match.group("repository")
+    if repository and repository.startswith(f"{registry_url}/"):
+        repository = repository[len(registry_url) + 1 :]
+    return repository, match.group("digest")
+
+
+def parse_tag_reference(reference: str, registry_url: str, repository: str) -> Tuple[str, str]:
+    """Parse a tag reference: "<repository>:<tag>" or "<type>:<tag>" (e.g. environment:abc-123).
+
+    Returns:
+        (repository, tag); a registry host prefix is removed from the repository,
+        and an image type is expanded to its repository under the configured one
+
+    Raises:
+        ValueError: If the value has no tag
+    """
+    reference = reference.strip()
+    if reference.startswith(f"{registry_url}/"):
+        reference = reference[len(registry_url) + 1 :]
+    name, _, tag = reference.rpartition(":")
+    if not name or not tag or "/" in tag:
+        raise ValueError(f"Expected <repository>:<tag> or <type>:<tag>, got: {reference}")
+    return (name if "/" in name else f"{repository}/{name}"), tag
+
+
+def is_digest_reference(reference: str) -> bool:
+    """Whether a reference names a manifest digest rather than a tag"""
+    return "@" in reference or reference.strip().startswith("sha256:")
+
+
+def read_references(stream: TextIO) -> List[str]:
+    """Read newline-delimited references, skipping blank lines, # comments and duplicates"""
+    references: List[str] = []
+    for line in stream:
+        line = line.strip().strip('"')
+        if line and not line.startswith("#") and line not in references:
+            references.append(line)
+    return references
+
+
+def tag_digests(
+    skopeo_client: SkopeoClient, repository: str, max_workers: Optional[int] = None
+) -> Dict[str, Optional[str]]:
+    """Resolve every tag in a repository to the manifest digest it points to.
+
+    Each tag is resolved with a manifest HEAD request, so the result reflects
+    the registry now rather than the last scan.
+    """
+    tags = skopeo_client.list_tags(repository)
+    with ThreadPoolExecutor(max_workers=max_workers or config_manager.get_max_workers()) as executor:
+        digests = list(executor.map(lambda tag: skopeo_client.get_manifest_digest(repository, tag), tags))
+    return dict(zip(tags, digests))
+
+
+def resolve_manifests(
+    deleter: "IntelligentImageDeleter", references: List[str]
+) -> Tuple[Dict[Tuple[str, str], List[str]], List[str]]:
+    """Resolve references (tags or digests) to the manifests deleting them removes.
+
+    Registries delete manifests, not tags: deleting a tag deletes the manifest
+    it points to, and with it every other tag pointing to the same digest. Each
+    reference is therefore resolved to its manifest together with all of its
+    alias tags. A digest without a repository is looked up in the environment
+    and model repositories; an untagged manifest (an orphan) can only be found
+    with an explicit repository.
+
+    All references are parsed before anything is looked up, so a malformed line
+    in a batch fails it as a whole.
+
+    Returns:
+        ({(repository, digest): alias tags}, references that could not be found)
+
+    Raises:
+        ValueError: If a reference is malformed
+    """
+    logger = get_logger(__name__)
+    parsed: List[Tuple[str, Optional[str], Optional[str], Optional[str]]] = []
+    for reference in references:
+        if is_digest_reference(reference):
+            repository, digest = parse_digest_reference(reference, deleter.registry_url)
+            parsed.append((reference, repository, None, digest))
+        else:
+            repository, tag = parse_tag_reference(reference, deleter.registry_url, deleter.repository)
+            parsed.append((reference, repository, tag, None))
+
+    index: Dict[str, Dict[str, Optional[str]]] = {}
+
+    def digests_of(repository: str) -> Dict[str, Optional[str]]:
+        if repository not in index:
+            logger.info(f"Resolving tags of {repository}...")
+            index[repository] = tag_digests(deleter.skopeo_client, repository)
+        return index[repository]
+
+    manifests: Dict[Tuple[str, str], List[str]] = {}
+    unresolved: List[str] = []
+    for reference, repository, tag, digest in parsed:
+        if tag is not None:
+            digest = digests_of(repository).get(tag)
+        if repository:
+            candidates = [repository]
+        else:
+            candidates = [f"{deleter.repository}/{image_type}" for image_type in config_manager.get_image_types()]
+
+        found = False
+        for candidate in candidates:
+            tags = sorted(t for t, d in digests_of(candidate).items() if d == digest) if digest else []
+            if tags or (repository and tag is None):
+                manifests[(candidate, digest)] = tags
+                found = True
+        if found:
+            continue
+        if tag is not None:
+            logger.error(f"❌ {reference}: tag not found")
+        else:
+            logger.error(
+                f"❌ No tag points to {digest}. To delete an untagged manifest, name its repository: "
+                f"<repository>@{digest}"
+            )
+        unresolved.append(reference)
+    return manifests, unresolved
+
+
+def delete_references(
+    deleter: "IntelligentImageDeleter", references: List[str], dry_run: bool, mongo_cleanup: bool
+) -> bool:
+    """Delete the manifests that references (tags or digests) point to, with all of their alias tags.
+
+    Each manifest is deleted in one registry request, which removes every tag
+    pointing to it at once. Before that, all alias tags are checked: a manifest
+    with an alias tag that is in use or refused by Docker Content Trust checks
+    is not deleted. Other manifests are still deleted.
+
+    Returns:
+        True if every reference was deleted (or would be, in dry-run mode)
+
+    Raises:
+        ValueError: If a reference is malformed (nothing is deleted)
+    """
+    logger = get_logger(__name__)
+    manifests, unresolved = resolve_manifests(deleter, references)
+
+    all_tags = sorted({tag for tags in manifests.values() for tag in tags})
+    in_use_tags, usage_info = ImageUsageService().check_tags_in_use(all_tags) if all_tags else (set(), {})
+    blocked: Set[Tuple[str, str]] = set()
+    for (repository, digest), tags in manifests.items():
+        logger.info(f"  {repository}@{digest}: {', '.join(tags) if tags else 'untagged'}")
+        for tag in tags:
+            if tag in in_use_tags:
+                logger.error(f"    ❌ {tag} is in use: {deleter._generate_usage_summary(usage_info.get(tag, {}))}")
+                blocked.add((repository, digest))
+            refusal = deleter.skopeo_client.signed_tag_refusal(repository, tag)
+            if refusal:
+                logger.error(f"    ❌ {tag}: {refusal}")
+                blocked.add((repository, digest))
+        if (repository, digest) in blocked:
+            logger.error("    ❌ Not deleting: the manifest has alias tags that must be kept")
+    targets = {key: tags for key, tags in manifests.items() if key not in blocked}
+
+    if dry_run:
+        for (repository, digest), tags in targets.items():
+            logger.info(f"  Would delete manifest {repository}@{digest} ({len(tags)} tag(s))")
+        return not (unresolved or blocked)
+
+    deleted_tags: List[str] = []
+    failed = 0
+    if targets:
+        deleter.enable_deletion_of_docker_images()
+        try:
+            for (repository, digest), tags in targets.items():
+                logger.info(f"  Deleting manifest {repository}@{digest}")
+                if deleter.skopeo_client.delete_manifest(repository, digest, tags):
+                    logger.info(f"    ✅ Deleted (removed tags: {', '.join(tags) if tags else 'none'})")
+                    deleted_tags.extend(tags)
+                else:
+                    logger.warning("    ❌ Failed to delete")
+                    failed += 1
+        finally:
+            deleter.disable_deletion_of_docker_images()
+
+    if len(references) > 1:
+        logger.info(
+            f"Deleted {len(targets) - failed} manifest(s) ({len(deleted_tags)} tags); "
+            f"{len(blocked)} kept, {len(unresolved)} not found, {failed} failed"
+        )
+    if deleted_tags and mongo_cleanup:
+        deleter.cleanup_mongo_references(deleted_tags)
+    return not (unresolved or blocked or failed)
+
+
 def parse_arguments():
     """Parse command line arguments"""
     parser = argparse.ArgumentParser(description="Intelligent Docker image deletion with workload analysis")
@@ -1601,10 +1827,27 @@ def parse_arguments():
         nargs="?",
         help="Specific image to delete (format: repository/type:tag, e.g., dominodatalab/environment:abc-123)",
     )
+    parser.add_argument(
+        "--digest",
+        metavar="[REPOSITORY@]DIGEST",
+        help="Delete a manifest by digest (sha256:...) together with all tags pointing to it; name the repository "
+        "(e.g. dominodatalab/environment@sha256:...) to delete an untagged manifest",
+    )
+    parser.add_argument(
+        "--stdin",
+        action="store_true",
+        help="Delete newline-delimited references read from stdin: tags (repository:tag or type:tag) or digests "
+        "([repository@]sha256:...); --apply requires --force",
+    )
     parser.add_argument(
         "--apply", action="store_true", help="Actually apply changes and delete images (default is dry-run)"
     )
     parser.add_argument("--force", action="store_true", help="Skip confirmation prompt when using --apply")
+    parser.add_argument(
+        "--allow-signed",
+        action="store_true",
+        help="Delete tags signed with Docker Content Trust (their signatures must then be removed from Notary)",
+    )
     parser.add_argument(
         "--image-analysis", default=config_manager.get_image_analysis_path(), help="Path to image analysis report"
     )
@@ -1696,6 +1939,25 @@ def main():
         logger.error("   You can provide it via --s3-bucket flag, S3_BUCKET env var, or config.yaml")
         sys.exit(1)
 
+    references: List[str] = []
+    if args.digest or args.stdin:
+        try:
+            if sum(bool(source) for source in (args.image, args.digest, args.stdin)) > 1:
+                raise ValueError("Give only one of an image, --digest or --stdin")
+            if args.stdin and args.apply and not args.force:
+                raise ValueError("--stdin --apply requires --force, since stdin is not available for confirmation")
+            references = read_references(sys.stdin) if args.stdin else [args.digest]
+            if not references:
+                raise ValueError("No references read from stdin")
+            for reference in references:
+                if is_digest_reference(reference):
+                    parse_digest_reference(reference, config_manager.get_registry_url())
+                else:
+                    parse_tag_reference(reference, config_manager.get_registry_url(), config_manager.get_repository())
+        except ValueError as e:
+            get_logger(__name__).error(f"❌ Error: {e}")
+            sys.exit(1)
+
     # Parse ObjectIDs (typed) from file if provided
     object_ids_map = None
     if args.input:
@@ -1742,6 +2004,13 @@ def main():
         deleter = IntelligentImageDeleter(
             enable_docker_deletion=args.enable_docker_deletion, registry_statefulset=args.registry_statefulset
         )
+        deleter.skopeo_client.allow_signed_deletion = args.allow_signed
+
+        # Delete manifests by digest or tag (--digest, --stdin), with all of their alias tags
+        if references:
+            if not delete_references(deleter, references, dry_run, args.mongo_cleanup):
+                sys.exit(1)
+            return
 
         # Handle direct image deletion if image argument is provided
         if args.image:
@@ -1775,7 +2044,10 @@ def main():
                 # Delete the image
                 deleted_tags = []
                 logger = get_logger(__name__)
-                if dry_run:
+                refusal = deleter.skopeo_client.signed_tag_refusal(repository, tag)
+                if dry_run and refusal:
+                    logger.warning(f"  Would refuse to delete {args.image}: {refusal}")
+                elif dry_run:
                     logger.info(f"  Would delete: {args.image}")
                     deleted_tags = [args.image]
                 else:
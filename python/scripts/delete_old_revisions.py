@@ -59,6 +59,7 @@ _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.build_info import get_build_info
 from utils.config_manager import config_manager
 from utils.deletion_base import BaseDeletionScript
 from utils.image_data_analysis import ImageAnalyzer
@@ -67,6 +68,7 @@ from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
 from utils.object_id_utils import read_object_ids_from_file
 from utils.report_utils import ensure_mongodb_reports, save_json
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -481,6 +483,8 @@ class OldRevisionCleaner(BaseDeletionScript):
             "summary": summary,
             "grouped_by_environment": grouped,
             "metadata": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "registry_url": self.registry_url,
                 "repository": self.repository,
                 "analysis_timestamp": datetime.now().isoformat(),
@@ -56,13 +56,22 @@ _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.build_info import get_build_info
 from utils.config_manager import config_manager
 from utils.deletion_base import BaseDeletionScript
 from utils.image_data_analysis import ImageAnalyzer
-from utils.image_usage import ImageUsageService
+from utils.image_usage import ImageUsageService, usage_window_days
 from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
-from utils.report_utils import ensure_mongodb_reports, get_timestamp_suffix, save_json, sizeof_fmt
+from utils.report_utils import (
+    ensure_mongodb_reports,
+    find_report_file,
+    get_timestamp_suffix,
+    load_json,
+    save_json,
+    sizeof_fmt,
+)
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -357,6 +366,7 @@ class UnusedEnvironmentsFinder(BaseDeletionScript):
         # From runs history - add environmentId and environmentRevisionId
         # If recent_days is provided, only count runs whose 'started' is within the window
         threshold = None
+        recent_days = usage_window_days(recent_days)
         if recent_days is not None and recent_days > 0:
             threshold = datetime.now(timezone.utc) - timedelta(days=recent_days)
 
@@ -1207,6 +1217,8 @@ class UnusedEnvironmentsFinder(BaseDeletionScript):
             "summary": summary,
             "grouped_by_object_id": grouped_data,
             "metadata": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "registry_url": self.registry_url,
                 "repository": self.repository,
                 "image_types_scanned": self.image_types,
@@ -1219,8 +1231,8 @@ class UnusedEnvironmentsFinder(BaseDeletionScript):
     def load_unused_tags_from_file(self, file_path: str) -> List[UnusedEnvInfo]:
         """Load unused tags from a pre-generated report file"""
         try:
-            with open(file_path, "r") as f:
-                report = json.load(f)
+            path = Path(file_path)
+            report = load_json(find_report_file(path) or path)
 
             unused_tags = []
 
@@ -1444,14 +1456,16 @@ def main():
                     },
                     "grouped_by_object_id": {},
                     "metadata": {
+                        "build": get_build_info(),
+                        "runStats": get_run_stats(),
                         "registry_url": registry_url,
                         "repository": repository,
                         "image_types_scanned": finder.image_types,
                         "analysis_timestamp": datetime.now().isoformat(),
                     },
                 }
-                save_json(output_file, empty_report)
-                logger.info(f"Empty report written to {output_file}")
+                saved_path = save_json(output_file, empty_report)
+                logger.info(f"Empty report written to {saved_path}")
                 sys.exit(0)
 
             logger.info("Finding matching Docker tags...")
@@ -1461,8 +1475,8 @@ def main():
                 logger.info("No matching Docker tags found for unused environments")
                 # Still create a report with the environment IDs but no tags
                 report = finder.generate_report(unused_envs, [], freed_space_bytes=0)
-                save_json(output_file, report)
-                logger.info(f"Report written to {output_file}")
+                saved_path = save_json(output_file, report)
+                logger.info(f"Report written to {saved_path}")
                 sys.exit(0)
 
         # Backup-only mode: allow backing up without deletion when --backup is provided without --apply
@@ -1571,7 +1585,7 @@ def main():
             report = finder.generate_report(unused_envs, unused_tags, freed_space_bytes)
 
             # Save report
-            save_json(output_file, report)
+            saved_path = save_json(output_file, report)
 
             # Print summary
             summary = report["summary"]
@@ -1585,7 +1599,7 @@ def main():
             logger.info(f"Environment IDs with tags: {summary['object_ids_with_tags']}")
             logger.info(f"Environment IDs without tags: {summary['object_ids_without_tags']}")
 
-            logger.info(f"\nDetailed report saved to: {output_file}")
+            logger.info(f"\nDetailed report saved to: {saved_path}")
 
             if unused_tags:
                 logger.warning(f"\n⚠️  Found {len(unused_tags)} unused environment tags that may need cleanup!")
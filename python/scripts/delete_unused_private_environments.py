@@ -42,7 +42,6 @@ Usage examples:
 """
 
 import argparse
-import json
 import os
 import sys
 from dataclasses import dataclass
@@ -60,13 +59,22 @@ if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
 from scripts.backup_restore import process_backup
+from utils.build_info import get_build_info
 from utils.config_manager import ConfigManager, SkopeoClient, config_manager
 from utils.deletion_base import BaseDeletionScript
 from utils.image_data_analysis import ImageAnalyzer
 from utils.image_usage import ImageUsageService
 from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
-from utils.report_utils import ensure_mongodb_reports, get_timestamp_suffix, save_json, sizeof_fmt
+from utils.report_utils import (
+    ensure_mongodb_reports,
+    find_report_file,
+    get_timestamp_suffix,
+    load_json,
+    save_json,
+    sizeof_fmt,
+)
+from utils.request_stats import get_run_stats
 
 # Disable SSL warnings for Keycloak
 requests.packages.urllib3.disable_warnings()
@@ -877,6 +885,8 @@ class DeactivatedUserEnvFinder(BaseDeletionScript):
             "summary": summary,
             "grouped_by_user": grouped_data,
             "metadata": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "registry_url": self.registry_url,
                 "repository": self.repository,
                 "image_types_scanned": self.image_types,
@@ -889,8 +899,8 @@ class DeactivatedUserEnvFinder(BaseDeletionScript):
     def load_report_from_file(self, file_path: str) -> Tuple[List[str], List[str], List[DeactivatedUserEnvInfo]]:
         """Load deactivated user environments from a pre-generated report file"""
         try:
-            with open(file_path, "r") as f:
-                report = json.load(f)
+            path = Path(file_path)
+            report = load_json(find_report_file(path) or path)
 
             report.get("summary", {})
             # For backwards compatibility, we can derive environment_ids and revision_ids if needed
@@ -1103,14 +1113,16 @@ def main():
                     },
                     "grouped_by_user": {},
                     "metadata": {
+                        "build": get_build_info(),
+                        "runStats": get_run_stats(),
                         "registry_url": registry_url,
                         "repository": repository,
                         "image_types_scanned": finder.image_types,
                         "analysis_timestamp": datetime.now().isoformat(),
                     },
                 }
-                save_json(output_file, empty_report)
-                logger.info(f"Empty report written to {output_file}")
+                saved_path = save_json(output_file, empty_report)
+                logger.info(f"Empty report written to {saved_path}")
                 sys.exit(0)
 
             logger.info("Finding matching Docker tags...")
@@ -1121,8 +1133,8 @@ def main():
                 logger.info("No matching Docker tags found for deactivated user environments")
                 # Still create a report with the IDs but no tags
                 report = finder.generate_report(environment_ids, revision_ids, [], freed_space_bytes=0)
-                save_json(output_file, report)
-                logger.info(f"Report written to {output_file}")
+                saved_path = save_json(output_file, report)
+                logger.info(f"Report written to {saved_path}")
                 sys.exit(0)
 
         # Backup-only mode: allow backing up without deletion when --backup is provided without --apply
@@ -1233,7 +1245,7 @@ def main():
             report = finder.generate_report(environment_ids, revision_ids, deactivated_user_tags, freed_space_bytes)
 
             # Save report
-            save_json(output_file, report)
+            saved_path = save_json(output_file, report)
 
             # Print summary
             summary = report["summary"]
@@ -1247,7 +1259,7 @@ def main():
             freed_space_bytes = summary.get("freed_space_bytes", summary.get("freed_space_gb", 0) * (1024**3))
             logger.info(f"Space that would be freed: {sizeof_fmt(freed_space_bytes)}")
 
-            logger.info(f"\nDetailed report saved to: {output_file}")
+            logger.info(f"\nDetailed report saved to: {saved_path}")
 
             if deactivated_user_tags:
                 logger.warning(
@@ -25,7 +25,6 @@ Usage examples:
 """
 
 import argparse
-import json
 import sys
 from collections import defaultdict
 from dataclasses import dataclass
@@ -40,10 +39,12 @@ _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.build_info import get_build_info
 from utils.config_manager import SkopeoClient, config_manager
 from utils.logging_utils import get_logger, setup_logging
 from utils.mongo_utils import get_mongo_client
-from utils.report_utils import save_json
+from utils.report_utils import find_report_file, load_json, open_report, save_json
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -71,7 +72,7 @@ class UnusedReferencesFinder:
         self.logger = get_logger(__name__)
 
         # Image type mappings for registry queries
-        self.image_types = ["environment", "model"]
+        self.image_types = config_manager.get_image_types()
 
         # Collections and their image reference field patterns
         self.collection_patterns = {
@@ -363,6 +364,8 @@ class UnusedReferencesFinder:
             "unused_references": unused_details,
             "used_references": used_details,
             "metadata": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
                 "registry_url": self.registry_url,
                 "repository": self.repository,
                 "analysis_timestamp": datetime.now().isoformat(),
@@ -446,8 +449,8 @@ class UnusedReferencesFinder:
     def load_unused_references_from_file(self, file_path: str) -> List[ImageReference]:
         """Load unused references from a pre-generated report file"""
         try:
-            with open(file_path, "r") as f:
-                report = json.load(f)
+            path = Path(file_path)
+            report = load_json(find_report_file(path) or path)
 
             unused_refs = []
             for ref_data in report.get("unused_references", []):
@@ -547,7 +550,8 @@ class UnusedReferencesFinder:
         tags_or_ids = []
 
         try:
-            with open(file_path, "r") as f:
+            path = Path(file_path)
+            with open_report(find_report_file(path) or path) as f:
                 for line in f:
                     line = line.strip()
                     if not line or line.startswith("#"):
@@ -702,7 +706,7 @@ def main():
             report = finder.generate_report(unused_refs, used_refs)
 
             # Save report
-            save_json(output_file, report)
+            saved_path = save_json(output_file, report)
 
             # Print summary
             summary = report["summary"]
@@ -720,7 +724,7 @@ def main():
                     f"  {collection}: {stats['unused_references']}/{stats['total_references']} unused ({stats['unused_percentage']}%)"
                 )
 
-            logger.info(f"\nDetailed report saved to: {output_file}")
+            logger.info(f"\nDetailed report saved to: {saved_path}")
 
             if unused_refs:
                 logger.warning(f"\n⚠️  Found {len(unused_refs)} unused references that may need cleanup!")
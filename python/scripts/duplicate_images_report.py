@@ -0,0 +1,260 @@
+#!/usr/bin/env python3
+"""
+Duplicate Image Report Generator
+
+This script finds identical images (same manifest digest) that were pushed under
+different environment or model namespaces, reports the duplicated bytes, and
+suggests a canonicalization plan: one canonical image per digest that the other
+namespaces can be pointed at before their copies are cleaned up.
+
+The same report also lists the other kinds of duplication in the registry:
+tag aliases (several tags of one repository on the same digest), identical
+manifests stored in more than one repository, and layers whose uncompressed
+content (diffID) is identical but which are stored as different blobs.
+
+Usage examples:
+  # Scan all environment and model images
+  python duplicate_images_report.py
+
+  # Only scan the environments listed in a file
+  python duplicate_images_report.py --file environments --image-types environment
+
+  # Skip the diffID comparison, which reads every image config
+  python duplicate_images_report.py --skip-layers
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.object_id_utils import read_image_object_id_filters
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def generate_duplicate_images_report(analyzer: ImageAnalyzer, diff_ids: Optional[Dict[str, str]] = None) -> Dict:
+    """Generate a consolidated duplication report from analyzed images.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        diff_ids: layer_id -> diffID from analyzer.collect_layer_diff_ids (None skips duplicate layers)
+
+    Returns:
+        Dict with 'summary' totals, the 'duplicates' namespace groups, 'tag_aliases',
+        'cross_repository' duplicates, and 'duplicate_layers'
+    """
+    groups = analyzer.find_duplicate_images()
+    duplicated_bytes = sum(g["duplicated_bytes"] for g in groups)
+    aliases = analyzer.find_tag_aliases()
+    cross_repository = analyzer.find_cross_repository_duplicates()
+    duplicate_layers = analyzer.find_duplicate_layers(diff_ids) if diff_ids is not None else []
+    duplicate_layer_bytes = sum(g["duplicated_bytes"] for g in duplicate_layers)
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_images": len(analyzer.images),
+            "duplicate_groups": len(groups),
+            "duplicate_images": sum(len(g["canonicalization_plan"]) for g in groups),
+            "duplicated_bytes": duplicated_bytes,
+            "duplicated_gb": round(duplicated_bytes / (1024**3), 2),
+            "tag_alias_groups": len(aliases),
+            "alias_tags": sum(len(g["tags"]) - 1 for g in aliases),
+            "cross_repository_manifests": len(cross_repository),
+            "duplicate_layer_groups": len(duplicate_layers) if diff_ids is not None else None,
+            "duplicate_layer_bytes": duplicate_layer_bytes if diff_ids is not None else None,
+            "encrypted_images": len(analyzer.images_with_encrypted_layers()),
+            "generated_at": datetime.now().isoformat(),
+        },
+        "duplicates": groups,
+        "tag_aliases": aliases,
+        "cross_repository": cross_repository,
+        "duplicate_layers": duplicate_layers,
+    }
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+    groups = report_data["duplicates"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Duplicate Image Report Summary")
+    logger.info("=" * 80)
+    logger.info(f"Images scanned: {summary['total_images']}")
+    logger.info(f"Duplicate digests: {summary['duplicate_groups']}")
+    logger.info(f"Non-canonical copies: {summary['duplicate_images']}")
+    logger.info(f"Duplicated bytes: {sizeof_fmt(summary['duplicated_bytes'])} ({summary['duplicated_gb']} GB)")
+    logger.info("=" * 80)
+
+    for group in groups[:20]:
+        logger.info(f"\n📦 {group['digest']} ({sizeof_fmt(group['size_bytes'])} x {len(group['namespaces'])} namespaces)")
+        logger.info(f"   Canonical: {group['canonical_image_id']}")
+        for step in group["canonicalization_plan"]:
+            logger.info(f"   → {step['image_id']} can be replaced by {step['replace_with']}")
+
+    if len(groups) > 20:
+        logger.info(f"\n... and {len(groups) - 20} more duplicate digests")
+
+    logger.info(f"\n🏷️  Tag aliases: {summary['alias_tags']} extra tags in {summary['tag_alias_groups']} groups")
+    for group in report_data["tag_aliases"][:10]:
+        logger.info(f"   {group['repository']} {group['digest'][:19]}: {', '.join(group['tags'][:5])}")
+
+    logger.info(f"\n🔁 Manifests stored in more than one repository: {summary['cross_repository_manifests']}")
+    for duplicate in report_data["cross_repository"][:10]:
+        logger.info(
+            f"   {duplicate['digest'][:19]} ({sizeof_fmt(duplicate['size_bytes'])}): "
+            f"{', '.join(duplicate['repositories'])}"
+        )
+
+    if summary["duplicate_layer_groups"] is None:
+        logger.info("\n🧱 Duplicate layers (diffID): skipped")
+    else:
+        logger.info(
+            f"\n🧱 Duplicate layers (same diffID, different blobs): {summary['duplicate_layer_groups']} "
+            f"({sizeof_fmt(summary['duplicate_layer_bytes'])} duplicated)"
+        )
+        for group in report_data["duplicate_layers"][:10]:
+            logger.info(
+                f"   {group['diff_id'][:19]}: {len(group['layer_ids'])} blobs, "
+                f"{sizeof_fmt(group['duplicated_bytes'])} duplicated"
+            )
+        if summary["encrypted_images"]:
+            logger.info(f"   {summary['encrypted_images']} image(s) with encrypted layers were not compared")
+
+    logger.info("\n" + "=" * 80)
+    logger.info("Note: the registry stores blobs once per digest, so duplicated bytes are logical")
+    logger.info("      copies; they are only reclaimed once every non-canonical tag is removed.")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Report duplicate images, tag aliases, cross-repository manifests, and duplicate layers",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Scan all environment and model images
+  python duplicate_images_report.py
+
+  # Only scan the environments listed in a file
+  python duplicate_images_report.py --file environments --image-types environment
+
+  # Specify output file
+  python duplicate_images_report.py --output duplicates.json
+
+  # Skip the diffID comparison, which reads every image config
+  python duplicate_images_report.py --skip-layers
+        """,
+    )
+
+    parser.add_argument(
+        "--file",
+        help="File containing typed ObjectIDs (environment:, environmentRevision:, model:, modelVersion:) to restrict the scan",
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: duplicate-images.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    parser.add_argument(
+        "--skip-layers",
+        action="store_true",
+        help="Skip finding duplicate layers by diffID (avoids reading the config of every distinct image)",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Duplicate Image Report Generator")
+        logger.info("=" * 80)
+
+        object_ids_map = None
+        if args.file:
+            object_ids_map = read_image_object_id_filters(args.file)
+            if not any(object_ids_map.values()):
+                logger.error(f"No valid typed ObjectIDs found in file '{args.file}'")
+                sys.exit(1)
+
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        logger.info(f"Image Types: {', '.join(args.image_types)}")
+        logger.info("=" * 80)
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+
+        success_count = 0
+        for image_type in args.image_types:
+            per_image_oids = object_ids_map.get(image_type) if object_ids_map else None
+            if object_ids_map is not None and not per_image_oids:
+                continue
+            logger.info(f"\nAnalyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=per_image_oids, max_workers=args.max_workers):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your ObjectID filters or registry access.")
+            sys.exit(1)
+
+        diff_ids = None if args.skip_layers else analyzer.collect_layer_diff_ids(args.max_workers)
+        report_data = generate_duplicate_images_report(analyzer, diff_ids)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "duplicate-images.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        logger.info("\n✅ Duplicate image report generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
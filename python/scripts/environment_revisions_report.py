@@ -0,0 +1,160 @@
+#!/usr/bin/env python3
+"""
+Environment Revisions Report
+
+This script maps each environment image tag to the Domino environment revision
+it was built for: the environment's name, the revision number, and whether
+users can still select the revision. Domino tags environment images as
+<environmentId>-<revision>; the revision records in MongoDB give the
+environment and revision, and the environment record tells whether it is
+archived and which revision is active.
+
+A revision is selectable when its environment exists and is not archived and
+its build succeeded. Images of revisions that cannot be selected can only be
+used by existing runs, workspaces and models pinned to them.
+
+Usage examples:
+  # Map the tags of the environment repository
+  python environment_revisions_report.py
+
+  # Map the environment tags of a saved scan snapshot (MongoDB is still queried)
+  python environment_revisions_report.py --snapshot reports/scan-snapshot-<timestamp>.json
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import SkopeoClient, config_manager
+from utils.image_metadata import build_environment_revision_mapping
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import read_snapshot
+
+logger = get_logger(__name__)
+
+
+def environment_tags(args: argparse.Namespace) -> List[str]:
+    """Tags of the environment repository, from a snapshot or the registry"""
+    if args.snapshot:
+        images = read_snapshot(args.snapshot)["images"]
+        return sorted(image["tag"] for image_id, image in images.items() if image_id.startswith("environment:"))
+    repository = args.repository or config_manager.get_repository()
+    logger.info(f"Listing tags of {repository}/environment...")
+    return sorted(SkopeoClient(config_manager).list_tags(f"{repository}/environment"))
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Environment Revisions Report")
+    logger.info("=" * 80)
+    logger.info(f"Environment tags: {summary['tags']}")
+    logger.info(f"Mapped to revisions of {summary['environments']} environment(s)")
+    for status, count in summary["statuses"].items():
+        logger.info(f"   {status}: {count}")
+    logger.info(f"Not Domino environment tags: {summary['unmapped_tags']}")
+
+    not_selectable = [image for image in report_data["images"] if not image["selectable"]]
+    for image in not_selectable[:20]:
+        name = image["environment_name"] or image["environment_id"]
+        logger.info(f"   {image['tag']}: {name} revision {image['revision']} ({image['status']})")
+    if len(not_selectable) > 20:
+        logger.info(f"   ... and {len(not_selectable) - 20} more")
+
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Map environment image tags to Domino environment revisions and whether they are still selectable",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Map the tags of the environment repository
+  python environment_revisions_report.py
+
+  # Map the environment tags of a saved scan snapshot
+  python environment_revisions_report.py --snapshot reports/scan-snapshot-<timestamp>.json
+        """,
+    )
+
+    parser.add_argument("--snapshot", metavar="FILE", help="Map the environment tags of a saved scan snapshot instead")
+    parser.add_argument("--repository", help="Base repository of the environment images (default: from config)")
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: environment-revisions.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Environment Revisions Report")
+        logger.info("=" * 80)
+
+        tags = environment_tags(args)
+        logger.info(f"Looking up the revisions of {len(tags)} environment tag(s) in MongoDB...")
+        revisions = build_environment_revision_mapping(set(tags))
+
+        images = [{"tag": tag, **revisions[tag]} for tag in tags if tag in revisions]
+        statuses: Dict[str, int] = {}
+        for image in images:
+            statuses[image["status"]] = statuses.get(image["status"], 0) + 1
+
+        summary = {
+            "source": args.snapshot or "registry",
+            "tags": len(tags),
+            "environments": len({image["environment_id"] for image in images}),
+            "statuses": dict(sorted(statuses.items())),
+            "selectable": sum(1 for image in images if image["selectable"]),
+            "unmapped_tags": len(tags) - len(images),
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {
+            "summary": summary,
+            "images": images,
+            "unmapped_tags": [tag for tag in tags if tag not in revisions],
+        }
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "environment-revisions.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+        logger.info("\n✅ Environment revisions report completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Environment revisions report failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
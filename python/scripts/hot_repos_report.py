@@ -0,0 +1,178 @@
+#!/usr/bin/env python3
+"""
+Hot Repositories Report
+
+This script ranks repositories by churn over the saved scan snapshot history:
+the tags added and removed per week, and the layer bytes added per week (see
+utils/repo_churn.py). The hottest repositories are where retention policies
+will have the most impact, and are listed in the report summary.
+
+Snapshots are saved by image_data_analysis --mode snapshot; the report needs
+at least two of them, taken some time apart. It reads no registry.
+
+Usage examples:
+  # Rank the repositories of every saved snapshot by bytes added per week
+  python hot_repos_report.py
+
+  # Rank by tags added and removed per week, listing the top 5
+  python hot_repos_report.py --sort tags --top 5
+
+  # Rank over named snapshots
+  python hot_repos_report.py --snapshots reports/scan-snapshot-2026-01-01-02-00-00.json \\
+      reports/scan-snapshot-2026-02-01-02-00-00.json
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.repo_churn import SORT_BY_BYTES, SORT_KEYS, rank_repositories, repository_churn
+from utils.report_utils import save_json, sizeof_fmt
+from utils.scan_snapshot import find_snapshots, read_snapshot
+
+logger = get_logger(__name__)
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Hot Repositories")
+    logger.info("=" * 80)
+    logger.info(
+        f"Snapshots: {len(summary['snapshots'])} over {summary['weeks']:g} week(s) "
+        f"({summary['start']} to {summary['end']})"
+    )
+    logger.info(f"Repositories: {summary['repositories']} ({summary['changed_repositories']} changed)")
+    logger.info(
+        f"Tags added: {summary['tags_added']}, removed: {summary['tags_removed']}, "
+        f"layer bytes added: {sizeof_fmt(summary['bytes_added'])}"
+    )
+
+    logger.info(f"\nHottest repositories by {summary['sort_by']}:")
+    logger.info(f"{'Added/wk':>9}  {'Removed/wk':>10}  {'Bytes/wk':>10}  {'Tags':>6}  Repository")
+    for entry in summary["hottest"]:
+        logger.info(
+            f"{entry['tags_added_per_week']:>9g}  {entry['tags_removed_per_week']:>10g}  "
+            f"{sizeof_fmt(entry['bytes_added_per_week']):>10}  {entry['tags']:>6}  {entry['repository']}"
+        )
+    logger.info("=" * 80)
+    if not summary["hottest"]:
+        logger.info("No repository changed between the snapshots.")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Rank repositories by tags and bytes added or removed per week across scan snapshots",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Rank the repositories of every saved snapshot by bytes added per week
+  python hot_repos_report.py
+
+  # Rank by tags added and removed per week, listing the top 5
+  python hot_repos_report.py --sort tags --top 5
+
+  # Rank over named snapshots, oldest first
+  python hot_repos_report.py --snapshots old-snapshot.json new-snapshot.json
+        """,
+    )
+
+    parser.add_argument(
+        "--snapshots",
+        nargs="+",
+        metavar="FILE",
+        help="Scan snapshots to compare, oldest first (default: every saved snapshot in the reports directory)",
+    )
+    parser.add_argument(
+        "--sort",
+        choices=SORT_KEYS,
+        default=SORT_BY_BYTES,
+        help="Rank by layer bytes added per week or by tags added and removed per week (default: bytes)",
+    )
+    parser.add_argument(
+        "--top", type=int, default=10, metavar="N", help="Number of hottest repositories in the summary (default: 10)"
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: hot-repos-report.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Hot Repositories Report")
+        logger.info("=" * 80)
+
+        if args.snapshots:
+            snapshot_paths: List[str] = list(args.snapshots)
+        else:
+            snapshot_paths = [str(path) for path, _ in reversed(find_snapshots(config_manager.get_snapshot_path()))]
+        if len(snapshot_paths) < 2:
+            raise RuntimeError(
+                f"Found {len(snapshot_paths)} scan snapshot(s); churn needs at least two taken apart "
+                "(save them with image_data_analysis.py --mode snapshot)"
+            )
+
+        # Snapshots are read one at a time; a long history need not fit in memory at once
+        churn = repository_churn(read_snapshot(path) for path in snapshot_paths)
+        ranked = rank_repositories(churn["repositories"], args.sort)
+        logger.info(f"Compared {churn['snapshots']} snapshot(s) covering {len(churn['repositories'])} repositories")
+
+        summary = {
+            "snapshots": snapshot_paths,
+            "start": churn["start"],
+            "end": churn["end"],
+            "weeks": churn["weeks"],
+            "repositories": len(churn["repositories"]),
+            "changed_repositories": len(ranked),
+            "tags_added": sum(entry["tags_added"] for entry in ranked),
+            "tags_removed": sum(entry["tags_removed"] for entry in ranked),
+            "bytes_added": sum(entry["bytes_added"] for entry in ranked),
+            "sort_by": args.sort,
+            "hottest": ranked[: args.top],
+            "build": get_build_info(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {"summary": summary, "repositories": ranked}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "hot-repos-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+        logger.info("\n✅ Hot repositories report completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Hot repositories report failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
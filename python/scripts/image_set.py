@@ -0,0 +1,155 @@
+#!/usr/bin/env python3
+"""
+Image Set Algebra
+
+This script combines saved image selections - scan snapshots, cleanup plans,
+candidates reports, protected images ConfigMaps, earlier set results and
+candidate files - with set operations, without touching the registry:
+
+    union      images of any operand
+    intersect  images of every operand
+    subtract   images of the first operand that no other operand has
+
+Images are compared by tag (<type>:<tag>, default) or by manifest digest
+(--by digest). Reports with several lists of images are read from the list
+named after '#', e.g. candidates-report.json#protected (see
+utils/image_sets.py for the formats read).
+
+The result is printed to stdout (as JSON with --json; logs go to stderr),
+saved as a selection other set commands can read with --output, and written
+as a candidate file plan --input reads with --list.
+
+Usage examples:
+  # Candidates of a policy plan minus the images the cluster scan protects
+  python image_set.py subtract reports/cleanup-plan-<timestamp>.json protected-images.yaml --by digest
+
+  # Images two candidates reports agree on, as a candidate file for plan --input
+  python image_set.py intersect candidates-a.json candidates-b.json --list agreed.txt
+
+  # Everything a candidates report protected in either of two runs
+  python image_set.py union run1.json#protected run2.json#protected --output protected.json
+"""
+
+import argparse
+import json
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.image_sets import BY_TAG, OPERATIONS, SET_KEYS, SetMember, combine, load_selection
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json
+
+logger = get_logger(__name__)
+
+
+def format_members(members: List[SetMember]) -> List[str]:
+    """Render set members as table lines"""
+    lines = [f"{'DIGEST':<19}  IMAGE"]
+    for member in members:
+        lines.append(f"{(member['digest'] or '-')[:19]:<19}  {member['image_id']}")
+    return lines
+
+
+def write_list(members: List[SetMember], path: str) -> None:
+    """Write set members as a candidate file, one <type>:<tag> per line (plan --input)"""
+    Path(path).parent.mkdir(parents=True, exist_ok=True)
+    with open(path, "w") as f:
+        f.writelines(f"{member['image_id']}\n" for member in members)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Combine saved image selections (snapshots, plans, reports, ConfigMaps, lists) with set operations",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Candidates of a policy plan minus the images the cluster scan protects
+  python image_set.py subtract reports/cleanup-plan-<timestamp>.json protected-images.yaml --by digest
+
+  # Images two candidates reports agree on, as a candidate file for plan --input
+  python image_set.py intersect candidates-a.json candidates-b.json --list agreed.txt
+
+  # Everything a candidates report protected in either of two runs
+  python image_set.py union run1.json#protected run2.json#protected --output protected.json
+        """,
+    )
+
+    parser.add_argument("operation", choices=OPERATIONS, help="Set operation")
+    parser.add_argument(
+        "operands",
+        nargs="+",
+        metavar="FILE",
+        help="Selections to combine (at least two); FILE#KEY reads the list of images under KEY of a JSON report",
+    )
+    parser.add_argument(
+        "--by", choices=SET_KEYS, default=BY_TAG, help="Compare images by tag (<type>:<tag>) or digest (default: tag)"
+    )
+    parser.add_argument("--json", action="store_true", help="Print the result as JSON instead of a table")
+    parser.add_argument("--output", help="Save the result as a selection other set commands can read")
+    parser.add_argument(
+        "--list", metavar="FILE", help="Write the result as a candidate file, one <type>:<tag> per line"
+    )
+
+    args = parser.parse_args()
+    if len(args.operands) < 2:
+        parser.error(f"{args.operation} needs at least two operands")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        operands = []
+        for path in args.operands:
+            members = load_selection(path)
+            logger.info(f"{path}: {len(members)} image(s)")
+            operands.append((path, members))
+
+        result = combine(args.operation, operands, args.by)
+
+        if args.json:
+            print(json.dumps(result, indent=2))
+        else:
+            print("\n".join(format_members(result)))
+        logger.info(f"{args.operation} by {args.by}: {len(result)} image(s)")
+
+        if args.output:
+            selection = {
+                "summary": {
+                    "build": get_build_info(),
+                    "operation": args.operation,
+                    "by": args.by,
+                    "operands": {path: len(members) for path, members in operands},
+                    "images": len(result),
+                    "generated_at": datetime.now().isoformat(),
+                },
+                "images": result,
+            }
+            saved_path = save_json(args.output, selection)
+            logger.info(f"Selection saved to: {saved_path}")
+        if args.list:
+            write_list(result, args.list)
+            logger.info(f"Candidate file saved to: {args.list}")
+
+    except Exception as e:
+        logger.error(f"\n❌ Set operation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -25,11 +25,13 @@ _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.build_info import get_build_info
 from utils.config_manager import config_manager
 from utils.image_data_analysis import ImageAnalyzer
 from utils.image_metadata import build_environment_tag_to_metadata_mapping, build_model_tag_to_metadata_mapping
 from utils.logging_utils import get_logger, setup_logging
 from utils.report_utils import ensure_image_analysis_reports, save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -87,16 +89,20 @@ def generate_image_size_report(analyzer: ImageAnalyzer, image_types: List[str] =
         Dict with report data including sorted list of images
     """
     if image_types is None:
-        image_types = ["environment", "model"]
+        image_types = config_manager.get_image_types()
 
     report_data = {
         "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
             "total_images": 0,
             "total_size_bytes": 0,
             "total_size_gb": 0.0,
             "total_freed_if_all_deleted_bytes": 0,
             "total_freed_if_all_deleted_gb": 0.0,
             "image_types": image_types,
+            "oversized_layer_threshold_bytes": None,
+            "images_with_oversized_layers": 0,
             "generated_at": datetime.now().isoformat(),
         },
         "images": [],
@@ -106,6 +112,15 @@ def generate_image_size_report(analyzer: ImageAnalyzer, image_types: List[str] =
     logger.info("Looking up image names and owners from MongoDB...")
     tag_to_metadata = build_image_metadata_mapping(analyzer)
 
+    # Layers over the oversized-layer threshold, by image
+    oversized_by_image: Dict[str, List[str]] = {}
+    oversized_policy = config_manager.get_oversized_layer_policy()
+    if oversized_policy:
+        report_data["summary"]["oversized_layer_threshold_bytes"] = oversized_policy["threshold_bytes"]
+        for layer in analyzer.oversized_layers(oversized_policy["threshold_bytes"]):
+            for image_id in layer["image_ids"]:
+                oversized_by_image.setdefault(image_id, []).append(layer["layer_id"])
+
     # Collect all images
     images_list = []
     total_size = 0
@@ -148,6 +163,7 @@ def generate_image_size_report(analyzer: ImageAnalyzer, image_types: List[str] =
                 "freed_space_gb": round(freed_space_bytes / (1024**3), 2),
                 "shared_layers_size_bytes": total_size_bytes - freed_space_bytes,
                 "shared_layers_size_gb": round((total_size_bytes - freed_space_bytes) / (1024**3), 2),
+                "oversized_layers": oversized_by_image.get(image_id, []),
             }
         )
 
@@ -170,6 +186,7 @@ def generate_image_size_report(analyzer: ImageAnalyzer, image_types: List[str] =
             "total_size_gb": round(total_size / (1024**3), 2),
             "total_freed_if_all_deleted_bytes": total_freed_if_all_deleted,
             "total_freed_if_all_deleted_gb": round(total_freed_if_all_deleted / (1024**3), 2),
+            "images_with_oversized_layers": sum(1 for image in images_list if image["oversized_layers"]),
         }
     )
 
@@ -191,6 +208,11 @@ def print_report_summary(report_data: Dict) -> None:
     logger.info(
         f"Total Space Freed (if all deleted): {sizeof_fmt(summary['total_freed_if_all_deleted_bytes'])} ({summary['total_freed_if_all_deleted_gb']} GB)"
     )
+    if summary.get("images_with_oversized_layers"):
+        logger.warning(
+            f"Images With Oversized Layers: {summary['images_with_oversized_layers']} "
+            f"(layers larger than {sizeof_fmt(summary['oversized_layer_threshold_bytes'])})"
+        )
     logger.info("=" * 80)
 
     # Print top 20 largest images
@@ -247,6 +269,12 @@ Examples:
 
   # Specify output file
   python image_size_report.py --output custom-report.json
+
+  # Quick approximate triage scan
+  python image_size_report.py --fast
+
+  # Layer sizes only, for storage accounting
+  python image_size_report.py --sizes-only
         """,
     )
 
@@ -263,14 +291,27 @@ Examples:
     parser.add_argument(
         "--image-types",
         nargs="+",
-        default=["environment", "model"],
-        help="Image types to include in report (default: environment model)",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
     )
 
     parser.add_argument(
         "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
     )
 
+    parser.add_argument(
+        "--fast",
+        action="store_true",
+        help="Approximate triage scan: fetch only manifests and reuse cached inspections instead of inspecting every tag",
+    )
+
+    parser.add_argument(
+        "--sizes-only",
+        action="store_true",
+        help="Storage accounting scan: one manifest request per tag for its layer sizes, without labels, env or "
+        "creation times (takes precedence over --fast)",
+    )
+
     return parser.parse_args()
 
 
@@ -317,7 +358,9 @@ def main():
         success_count = 0
         for image_type in args.image_types:
             logger.info(f"\nAnalyzing {image_type} images...")
-            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+            if analyzer.analyze_image(
+                image_type, object_ids=None, max_workers=args.max_workers, fast=args.fast, sizes_only=args.sizes_only
+            ):
                 success_count += 1
 
         if success_count == 0:
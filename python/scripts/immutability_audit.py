@@ -0,0 +1,192 @@
+#!/usr/bin/env python3
+"""
+Tag Immutability Audit
+
+This script reports repositories where mutable tags make retention ambiguous.
+For ECR and Harbor registries it reads each repository's tag immutability
+setting (ECR imageTagMutability, Harbor tag immutability rules) and
+cross-checks it with the digests the repository's tags had in saved scan
+snapshots: a tag that pointed to different digests in successive snapshots was
+re-pushed, so decisions based on its age or usage may be about another image.
+
+Snapshots are saved by image_data_analysis --mode snapshot; the audit needs at
+least two of them, taken some time apart, to observe drift.
+
+Usage examples:
+  # Audit the repositories of every saved snapshot
+  python immutability_audit.py
+
+  # Audit named snapshots
+  python immutability_audit.py --snapshots reports/scan-snapshot-2026-01-01-02-00-00.json \\
+      reports/scan-snapshot-2026-02-01-02-00-00.json
+
+  # Report digest drift only, without reading immutability settings
+  python immutability_audit.py --drift-only
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import SkopeoClient, config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import find_snapshots, read_snapshot
+from utils.tag_immutability import (
+    IMMUTABLE_DRIFT,
+    MUTABLE_DRIFT,
+    audit_repositories,
+    summarize_audits,
+    tag_digest_history,
+)
+
+logger = get_logger(__name__)
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Tag Immutability Audit")
+    logger.info("=" * 80)
+    logger.info(f"Snapshots compared: {len(summary['snapshots'])}")
+    logger.info(f"Repositories: {summary['repositories']}")
+    for finding, count in summary["findings"].items():
+        logger.info(f"   {finding}: {count}")
+    logger.info(f"Tags that changed digest: {summary['changed_tags']}")
+
+    for audit in report_data["repositories"]:
+        if audit["finding"] not in (MUTABLE_DRIFT, IMMUTABLE_DRIFT):
+            continue
+        logger.info(f"\n{audit['repository']} ({audit['immutability']}): {len(audit['changed_tags'])} tag(s) re-pushed")
+        for change in audit["changed_tags"][:10]:
+            logger.info(f"   {change['tag']}: {' -> '.join(digest[:19] for digest in change['digests'])}")
+        if len(audit["changed_tags"]) > 10:
+            logger.info(f"   ... and {len(audit['changed_tags']) - 10} more")
+
+    logger.info("=" * 80)
+    if not summary["ambiguous_repositories"]:
+        logger.info("No tag changed digest between the snapshots.")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Cross-check tag immutability settings with digest drift observed in scan snapshots",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Audit the repositories of every saved snapshot
+  python immutability_audit.py
+
+  # Audit named snapshots, oldest first
+  python immutability_audit.py --snapshots old-snapshot.json new-snapshot.json
+
+  # Report digest drift only, without reading immutability settings
+  python immutability_audit.py --drift-only
+
+  # Fail (exit code 1) if mutable tags were re-pushed
+  python immutability_audit.py --fail-on-drift
+        """,
+    )
+
+    parser.add_argument(
+        "--snapshots",
+        nargs="+",
+        metavar="FILE",
+        help="Scan snapshots to compare, oldest first (default: every saved snapshot in the reports directory)",
+    )
+    parser.add_argument(
+        "--drift-only",
+        action="store_true",
+        help="Only report digest drift; do not read immutability settings from the registry",
+    )
+    parser.add_argument(
+        "--fail-on-drift", action="store_true", help="Exit with code 1 if any repository's tags changed digest"
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: immutability-audit.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Tag Immutability Audit")
+        logger.info("=" * 80)
+
+        if args.snapshots:
+            snapshot_paths: List[str] = list(args.snapshots)
+        else:
+            snapshot_paths = [str(path) for path, _ in reversed(find_snapshots(config_manager.get_snapshot_path()))]
+        if not snapshot_paths:
+            raise RuntimeError("No scan snapshots found; save one with image_data_analysis.py --mode snapshot")
+        if len(snapshot_paths) < 2:
+            logger.warning("Only one snapshot: digest drift needs at least two snapshots taken apart")
+
+        snapshots = [read_snapshot(path) for path in snapshot_paths]
+        history = tag_digest_history(snapshots)
+        logger.info(f"Read {len(snapshots)} snapshot(s) covering {len(history)} repositories")
+
+        immutability: Dict[str, str] = {}
+        if not args.drift_only:
+            registry_url = snapshots[-1]["registry_url"]
+            skopeo_client = SkopeoClient(config_manager, registry_url=registry_url)
+            immutability = skopeo_client.get_tag_immutability(sorted(history))
+
+        audits = audit_repositories(history, immutability)
+        summary = summarize_audits(audits)
+        summary.update(
+            {
+                "registry_url": snapshots[-1]["registry_url"],
+                "snapshots": snapshot_paths,
+                "settings_read": not args.drift_only,
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
+                "generated_at": datetime.now().isoformat(),
+            }
+        )
+        report_data = {"summary": summary, "repositories": audits}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "immutability-audit.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        if args.fail_on_drift and summary["ambiguous_repositories"]:
+            logger.error("\n❌ Tags changed digest between snapshots")
+            sys.exit(1)
+        logger.info("\n✅ Tag immutability audit completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Tag immutability audit failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
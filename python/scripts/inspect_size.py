@@ -0,0 +1,190 @@
+#!/usr/bin/env python3
+"""
+Image Size Drill-down
+
+This script explains why an image is big. It breaks one image down layer by
+layer: each layer's size, its share of the image, and whether other images
+share it. Shared layers (the base image, packages other environments also
+install) cost the registry nothing extra; unique layers are what the image
+adds. With --deep, the image config is also read to show the build step
+(Dockerfile instruction) that created each layer, and to suggest how to make
+the image smaller, e.g. cleaning package caches in the same RUN.
+
+The registry is scanned to find which layers are shared, as for
+simulate_deletion; with the inspection cache enabled, only new tags are
+inspected.
+
+Usage examples:
+  # Break down an environment image, with build steps and suggestions
+  python inspect_size.py environment:507f1f77bcf86cd799439011-3 --deep
+
+  # Tag without a type prefix is looked up in all image types
+  python inspect_size.py 507f1f77bcf86cd799439011-3
+
+  # Save the breakdown as JSON for the environment's authors
+  python inspect_size.py 507f1f77bcf86cd799439011-3 --deep --output size-breakdown.json
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import List, Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_config import HistoryEntry, config_details
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.size_breakdown import SizeBreakdown
+
+logger = get_logger(__name__)
+
+
+def read_history(analyzer: ImageAnalyzer, image_id: str) -> Optional[List[HistoryEntry]]:
+    """Build steps of an image, from its config blob"""
+    image_data = analyzer.images[image_id]
+    image_config = analyzer.skopeo_client.get_image_config(image_data["repository"], image_data["tag"])
+    details = config_details(image_config, config_manager.get_redact_key_patterns())
+    return details["history"] if details else None
+
+
+def format_breakdown(breakdown: SizeBreakdown) -> List[str]:
+    """Render a breakdown as table lines, base layer first"""
+    lines = [f"{'#':>3}  {'SIZE':>10}  {'%':>5}  {'SHARED':<12}  COMMAND"]
+    for layer in breakdown["layers"]:
+        if layer["shared_with"] is None:
+            shared = "?"
+        elif layer["shared_with"]:
+            shared = f"{layer['shared_with']} image(s)"
+        else:
+            shared = "no"
+        command = layer["command"] or layer["digest"]
+        if len(command) > 100:
+            command = command[:97] + "..."
+        size = sizeof_fmt(layer["size_bytes"])
+        lines.append(f"{layer['index']:>3}  {size:>10}  {layer['percent']:>5.1f}  {shared:<12}  {command}")
+    return lines
+
+
+def print_breakdown(breakdown: SizeBreakdown, deep: bool) -> None:
+    """Print the breakdown table to stdout and its summary and suggestions to the log"""
+    print("\n".join(format_breakdown(breakdown)))
+
+    logger.info("\n" + "=" * 80)
+    logger.info(f"   {breakdown['image_id']} ({breakdown['digest']})")
+    logger.info("=" * 80)
+    logger.info(f"Total size:  {sizeof_fmt(breakdown['total_bytes'])} in {len(breakdown['layers'])} layer(s)")
+    if breakdown["unique_bytes"] is not None:
+        logger.info(f"Unique:      {sizeof_fmt(breakdown['unique_bytes'])} (layers no other image uses)")
+        logger.info(f"Shared:      {sizeof_fmt(breakdown['shared_bytes'])}")
+    if deep and not breakdown["history_matched"]:
+        logger.info("The image's build history does not match its layers; build steps are not shown")
+    if not deep:
+        logger.info("Run with --deep to see the build step behind each layer and size-reduction suggestions")
+    if breakdown["suggestions"]:
+        logger.info("\n💡 Ways to make the image smaller:")
+        for suggestion in breakdown["suggestions"]:
+            logger.info(f"   {suggestion}")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Break an image down layer by layer to show why it is big",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Break down an environment image, with build steps and suggestions
+  python inspect_size.py environment:507f1f77bcf86cd799439011-3 --deep
+
+  # Tag without a type prefix is looked up in all image types
+  python inspect_size.py 507f1f77bcf86cd799439011-3
+
+  # Save the breakdown as JSON for the environment's authors
+  python inspect_size.py 507f1f77bcf86cd799439011-3 --deep --output size-breakdown.json
+        """,
+    )
+
+    parser.add_argument("image", help="Image to break down, as <type>:<tag> or a bare tag")
+    parser.add_argument(
+        "--deep",
+        action="store_true",
+        help="Also read the image config for the build step behind each layer and size-reduction suggestions",
+    )
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to analyze; layers used by these count as shared (default: the profile's image types)",
+    )
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the breakdown (default: size-breakdown.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        analyzer = ImageAnalyzer(config_manager.get_registry_url(), config_manager.get_repository())
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"Analyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        image_id = analyzer.resolve_image_id(args.image, args.image_types)
+        if not image_id:
+            logger.error(f"❌ Image '{args.image}' not found in {', '.join(args.image_types)} images")
+            sys.exit(1)
+
+        history = None
+        if args.deep:
+            history = read_history(analyzer, image_id)
+            if history is None:
+                logger.warning(f"⚠️  Could not read the image config of {image_id}; build steps are not shown")
+
+        breakdown = analyzer.size_breakdown(image_id, history)
+        print_breakdown(breakdown, args.deep)
+
+        report = {
+            **breakdown,
+            "deep": args.deep,
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        output_path = args.output or str(Path(config_manager.get_output_dir()) / "size-breakdown.json")
+        saved_path = save_json(output_path, report, timestamp=not args.output)
+        logger.info(f"\nBreakdown saved to: {saved_path}")
+
+    except Exception as e:
+        logger.error(f"\n❌ Size breakdown failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
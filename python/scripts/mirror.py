@@ -0,0 +1,239 @@
+#!/usr/bin/env python3
+"""
+Keep-Set Mirror
+
+This script copies the images a cleanup plan keeps to a secondary registry,
+making the cleaner a combined retention and mirroring tool: the secondary
+receives every image the primary still holds once the plan is applied, and
+none of the images the plan deletes. Without a plan, every scanned image is
+mirrored.
+
+By default each image is copied by digest with skopeo copy, images the
+destination already has at that digest are skipped, and every copy is verified
+by reading its digest back. --method skopeo-sync instead runs one skopeo sync
+over a generated YAML source file. The destination is only ever added to.
+
+The destination defaults to mirror.destination in config.yaml. With
+schedule.mirror set, the API server runs this command at its cron times
+against the latest plan.
+
+Usage examples:
+  # Mirror the images the latest plan keeps
+  python mirror.py --latest-plan --to dr-registry.example.com:5000
+
+  # Mirror the images a given plan keeps, from a saved scan snapshot
+  python mirror.py --plan reports/cleanup-plan-<timestamp>.json --snapshot reports/scan-snapshot-<timestamp>.json
+
+  # Show what would be copied, without copying
+  python mirror.py --latest-plan --dry-run
+
+  # Mirror every environment image with skopeo sync
+  python mirror.py --image-types environment --method skopeo-sync
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.cleanup_plan import CleanupPlan, load_plan
+from utils.config_manager import SkopeoClient, config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.keep_set import keep_set
+from utils.logging_utils import get_logger, setup_logging
+from utils.mirror import METHOD_COPY, MIRROR_METHODS, mirror_images, sync_images
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import load_snapshot
+
+logger = get_logger(__name__)
+
+
+def find_latest_plan() -> Optional[Path]:
+    """Return the most recent plan file in the reports directory, if any"""
+    plans = list(Path(config_manager.get_output_dir()).glob("cleanup-plan*.json"))
+    return max(plans, key=lambda p: p.stat().st_mtime) if plans else None
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Copy the images a cleanup plan keeps to a secondary registry",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Mirror the images the latest plan keeps
+  python mirror.py --latest-plan --to dr-registry.example.com:5000
+
+  # Mirror the images a given plan keeps, from a saved scan snapshot
+  python mirror.py --plan reports/cleanup-plan-<timestamp>.json --snapshot reports/scan-snapshot-<timestamp>.json
+
+  # Show what would be copied, without copying
+  python mirror.py --latest-plan --dry-run
+
+  # Mirror every environment image with skopeo sync
+  python mirror.py --image-types environment --method skopeo-sync
+        """,
+    )
+
+    parser.add_argument("--to", dest="destination", help="Registry to mirror to (default: config mirror.destination)")
+
+    plan_source = parser.add_mutually_exclusive_group()
+    plan_source.add_argument("--plan", help="Plan file whose deletions are not mirrored")
+    plan_source.add_argument(
+        "--latest-plan",
+        action="store_true",
+        help="Leave out the deletions of the newest cleanup-plan*.json in the reports directory, if there is one",
+    )
+
+    parser.add_argument("--snapshot", help="Scan snapshot to take the images from instead of scanning the registry")
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to mirror (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--method",
+        choices=MIRROR_METHODS,
+        default=METHOD_COPY,
+        help="copy: skopeo copy per image by digest, skipping up-to-date images; "
+        "skopeo-sync: one skopeo sync of every kept tag (default: copy)",
+    )
+
+    parser.add_argument("--dry-run", action="store_true", help="Report what would be copied without copying")
+
+    parser.add_argument(
+        "--output", help="Output file for the results (default: mirror-results.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--max-workers",
+        type=int,
+        help="Maximum number of parallel workers for analysis and copies (default: from config)",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+        destination = args.destination or config_manager.get_mirror_destination()
+        if not destination:
+            raise ValueError("No destination registry: pass --to or set mirror.destination in config.yaml")
+        if destination == registry_url:
+            raise ValueError(f"The destination is the registry being mirrored ({registry_url})")
+
+        plan: Optional[CleanupPlan] = None
+        plan_path = args.plan or (find_latest_plan() if args.latest_plan else None)
+        if args.latest_plan and plan_path is None:
+            logger.warning("⚠️  No plan file in the reports directory; mirroring every scanned image")
+        if plan_path:
+            plan = load_plan(str(plan_path))
+            if plan.registry_url != registry_url:
+                raise ValueError(f"Plan {plan_path} is for {plan.registry_url}, not {registry_url}")
+
+        logger.info("=" * 60)
+        logger.info("   Keep-Set Mirror")
+        logger.info("=" * 60)
+        logger.info(f"Source: {registry_url}/{repository}")
+        logger.info(f"Destination: {destination}")
+        logger.info(f"Plan: {plan_path or '(none, every image is kept)'}")
+        logger.info(f"Method: {args.method}{' (dry run)' if args.dry_run else ''}")
+        logger.info("=" * 60)
+
+        if args.snapshot:
+            analyzer, _, _ = load_snapshot(args.snapshot)
+        else:
+            analyzer = ImageAnalyzer(registry_url, repository)
+            success_count = 0
+            for image_type in args.image_types:
+                logger.info(f"Analyzing {image_type} images...")
+                if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+                    success_count += 1
+            if success_count == 0:
+                logger.error("No image data found. Check your registry access.")
+                sys.exit(1)
+
+        images = keep_set(analyzer, [item.image_id for item in plan.items] if plan else [])
+        logger.info(f"Mirroring {len(images)} kept images ({len(analyzer.images) - len(images)} deleted by the plan)")
+
+        source_client = SkopeoClient(config_manager)
+        if args.method == METHOD_COPY:
+            destination_client = SkopeoClient(config_manager, registry_url=destination)
+            result = mirror_images(
+                images,
+                source_client,
+                destination_client,
+                args.max_workers or config_manager.get_max_workers(),
+                dry_run=args.dry_run,
+            )
+        else:
+            # Logging in to the destination stores its credentials in the auth file skopeo sync reads
+            SkopeoClient(config_manager, registry_url=destination)
+            result = sync_images(images, source_client, destination, dry_run=args.dry_run)
+
+        report = {
+            "summary": {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
+                "source": registry_url,
+                "destination": destination,
+                "plan": str(plan_path) if plan_path else None,
+                "method": args.method,
+                "dry_run": args.dry_run,
+                "kept_images": len(images),
+                "copied": len(result["copied"]),
+                "up_to_date": len(result["up_to_date"]),
+                "failed": len(result["failed"]),
+                "generated_at": datetime.now().isoformat(),
+            },
+            **result,
+        }
+        output_path = args.output or str(Path(config_manager.get_output_dir()) / "mirror-results.json")
+        saved_path = save_json(output_path, report, timestamp=not args.output)
+
+        verb = "Would copy" if args.dry_run else "Copied"
+        logger.info("\n📊 Mirror Summary:")
+        logger.info(f"   {verb}: {len(result['copied'])}")
+        logger.info(f"   Already up to date: {len(result['up_to_date'])}")
+        logger.info(f"   Failed: {len(result['failed'])}")
+        copy_blobs = result["copy_blobs"]
+        if any(copy_blobs.values()):
+            logger.info(
+                f"   Blobs: {sizeof_fmt(copy_blobs['mounted_bytes'])} mounted, "
+                f"{sizeof_fmt(copy_blobs['existing_bytes'])} already present, "
+                f"{sizeof_fmt(copy_blobs['transferred_bytes'])} transferred"
+            )
+        for failure in result["failed"][:20]:
+            logger.info(f"      {failure['image_id']}: {failure['reason']}")
+        logger.info(f"   Results: {saved_path}")
+
+        if result["failed"]:
+            sys.exit(1)
+
+    except Exception as e:
+        logger.error(f"\n❌ Mirror failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
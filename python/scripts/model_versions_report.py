@@ -0,0 +1,160 @@
+#!/usr/bin/env python3
+"""
+Model Versions Report
+
+This script maps each model image tag to the Domino model version it was built
+for: the model's name, the version number, and the version's deployment
+status. A version is running when the latest completed deployment saga of the
+version started its model API, stopped when it stopped it, and not deployed
+when it has no such saga.
+
+Model images of running versions are pinned: a model API serves them, so
+apply never deletes them (see plan / apply).
+
+Usage examples:
+  # Map the tags of the model repository
+  python model_versions_report.py
+
+  # Map the model tags of a saved scan snapshot (MongoDB is still queried)
+  python model_versions_report.py --snapshot reports/scan-snapshot-<timestamp>.json
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import SkopeoClient, config_manager
+from utils.image_metadata import build_model_version_mapping
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import read_snapshot
+
+logger = get_logger(__name__)
+
+
+def model_tags(args: argparse.Namespace) -> List[str]:
+    """Tags of the model repository, from a snapshot or the registry"""
+    if args.snapshot:
+        images = read_snapshot(args.snapshot)["images"]
+        return sorted(image["tag"] for image_id, image in images.items() if image_id.startswith("model:"))
+    repository = args.repository or config_manager.get_repository()
+    logger.info(f"Listing tags of {repository}/model...")
+    return sorted(SkopeoClient(config_manager).list_tags(f"{repository}/model"))
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Model Versions Report")
+    logger.info("=" * 80)
+    logger.info(f"Model tags: {summary['tags']}")
+    logger.info(f"Mapped to versions of {summary['models']} model(s)")
+    for status, count in summary["deployment_statuses"].items():
+        logger.info(f"   {status}: {count}")
+    logger.info(f"Tags matching no model version: {summary['unmapped_tags']}")
+
+    pinned = [image for image in report_data["images"] if image["pinned"]]
+    if pinned:
+        logger.info(f"\nPinned by running model APIs: {len(pinned)}")
+    for image in pinned[:20]:
+        name = image["model_name"] or image["model_id"]
+        logger.info(f"   {image['tag']}: {name} version {image['version']}")
+    if len(pinned) > 20:
+        logger.info(f"   ... and {len(pinned) - 20} more")
+
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Map model image tags to Domino model versions and their deployment status",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Map the tags of the model repository
+  python model_versions_report.py
+
+  # Map the model tags of a saved scan snapshot
+  python model_versions_report.py --snapshot reports/scan-snapshot-<timestamp>.json
+        """,
+    )
+
+    parser.add_argument("--snapshot", metavar="FILE", help="Map the model tags of a saved scan snapshot instead")
+    parser.add_argument("--repository", help="Base repository of the model images (default: from config)")
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: model-versions.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Model Versions Report")
+        logger.info("=" * 80)
+
+        tags = model_tags(args)
+        logger.info(f"Looking up the versions of {len(tags)} model tag(s) in MongoDB...")
+        versions = build_model_version_mapping(set(tags))
+
+        images = [{"tag": tag, **versions[tag]} for tag in tags if tag in versions]
+        statuses: Dict[str, int] = {}
+        for image in images:
+            statuses[image["deployment_status"]] = statuses.get(image["deployment_status"], 0) + 1
+
+        summary = {
+            "source": args.snapshot or "registry",
+            "tags": len(tags),
+            "models": len({image["model_id"] for image in images}),
+            "deployment_statuses": dict(sorted(statuses.items())),
+            "pinned": sum(1 for image in images if image["pinned"]),
+            "unmapped_tags": len(tags) - len(images),
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {
+            "summary": summary,
+            "images": images,
+            "unmapped_tags": [tag for tag in tags if tag not in versions],
+        }
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "model-versions.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+        logger.info("\n✅ Model versions report completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Model versions report failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
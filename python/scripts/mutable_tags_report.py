@@ -0,0 +1,206 @@
+#!/usr/bin/env python3
+"""
+Mutable Tags Report
+
+This script reports tags whose image is not fixed: tags that pointed to
+different digests in successive scan snapshots, tags with floating names such
+as latest, stable or prod, and tags outside the naming convention that alias
+other tags of their repository. Retention rules that keep or delete images
+through such tags act on whatever image the tag points to at the time, so
+age-based policies built on them are unreliable.
+
+Given a retention policy, the report also lists the rules whose tag patterns
+select mutable tags, age-based rules first.
+
+Snapshots are saved by image_data_analysis --mode snapshot; digest changes can
+only be observed with at least two of them, taken some time apart.
+
+Usage examples:
+  # Report mutable tags in every saved snapshot
+  python mutable_tags_report.py
+
+  # Also flag the rules of a retention policy that depend on them
+  python mutable_tags_report.py --policy policy.yaml
+
+  # Treat more tag names as floating
+  python mutable_tags_report.py --floating-tags 'release-*' current
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.mutable_tags import ALIAS, DIGEST_CHANGED, FLOATING_NAME, find_mutable_tags, rules_depending_on
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.retention_policy import load_policy
+from utils.scan_snapshot import find_snapshots, read_snapshot
+from utils.tag_immutability import tag_digest_history
+
+logger = get_logger(__name__)
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Mutable Tags Report")
+    logger.info("=" * 80)
+    logger.info(f"Snapshots compared: {len(summary['snapshots'])}")
+    logger.info(f"Mutable tags: {summary['mutable_tags']}")
+    for reason, count in summary["reasons"].items():
+        logger.info(f"   {reason}: {count}")
+
+    for entry in report_data["mutable_tags"][:20]:
+        logger.info(f"   {entry['repository']}:{entry['tag']} ({', '.join(entry['reasons'])})")
+    if len(report_data["mutable_tags"]) > 20:
+        logger.info(f"   ... and {len(report_data['mutable_tags']) - 20} more")
+
+    if summary["policy"]:
+        logger.info(f"\nPolicy rules depending on mutable tags: {len(report_data['dependent_rules'])}")
+        for rule in report_data["dependent_rules"]:
+            age = ", age-based" if rule["age_based"] else ""
+            logger.info(f"   {rule['rule']} ({rule['action']}{age}): {len(rule['tags'])} tag(s)")
+
+    logger.info("=" * 80)
+    if not summary["mutable_tags"]:
+        logger.info("No mutable tags found.")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Report floating and re-pushed tags, and the retention rules that depend on them",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Report mutable tags in every saved snapshot
+  python mutable_tags_report.py
+
+  # Report mutable tags in named snapshots, oldest first
+  python mutable_tags_report.py --snapshots old-snapshot.json new-snapshot.json
+
+  # Also flag the rules of a retention policy that depend on them
+  python mutable_tags_report.py --policy policy.yaml
+
+  # Fail (exit code 1) if an age-based policy rule depends on mutable tags
+  python mutable_tags_report.py --policy policy.yaml --fail-on-risk
+        """,
+    )
+
+    parser.add_argument(
+        "--snapshots",
+        nargs="+",
+        metavar="FILE",
+        help="Scan snapshots to compare, oldest first (default: every saved snapshot in the reports directory)",
+    )
+    parser.add_argument("--policy", metavar="FILE", help="Retention policy file whose rules to check")
+    parser.add_argument(
+        "--floating-tags",
+        nargs="+",
+        metavar="PATTERN",
+        help="Additional floating tag patterns (default: analysis.floating_tags; latest, stable, prod, ... "
+        "are built in)",
+    )
+    parser.add_argument(
+        "--fail-on-risk",
+        action="store_true",
+        help="Exit with code 1 if an age-based policy rule depends on mutable tags (requires --policy)",
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: mutable-tags-report.json in reports directory)"
+    )
+
+    args = parser.parse_args()
+    if args.fail_on_risk and not args.policy:
+        parser.error("--fail-on-risk requires --policy")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Mutable Tags Report")
+        logger.info("=" * 80)
+
+        if args.snapshots:
+            snapshot_paths: List[str] = list(args.snapshots)
+        else:
+            snapshot_paths = [str(path) for path, _ in reversed(find_snapshots(config_manager.get_snapshot_path()))]
+        if not snapshot_paths:
+            raise RuntimeError("No scan snapshots found; save one with image_data_analysis.py --mode snapshot")
+        if len(snapshot_paths) < 2:
+            logger.warning("Only one snapshot: digest changes need at least two snapshots taken apart")
+
+        floating_patterns = config_manager.get_floating_tag_patterns()
+        if args.floating_tags:
+            floating_patterns += [p for p in args.floating_tags if p not in floating_patterns]
+        naming_pattern = config_manager.get_tag_naming_pattern()
+
+        snapshots = [read_snapshot(path) for path in snapshot_paths]
+        history = tag_digest_history(snapshots)
+        mutable_tags = find_mutable_tags(history, snapshots[-1]["images"], floating_patterns, naming_pattern)
+        dependent_rules = rules_depending_on(load_policy(args.policy), mutable_tags) if args.policy else []
+
+        reasons = {reason: 0 for reason in (DIGEST_CHANGED, FLOATING_NAME, ALIAS)}
+        for entry in mutable_tags:
+            for reason in entry["reasons"]:
+                reasons[reason] += 1
+
+        summary = {
+            "registry_url": snapshots[-1]["registry_url"],
+            "snapshots": snapshot_paths,
+            "floating_tags": floating_patterns,
+            "naming_pattern": naming_pattern.pattern,
+            "policy": args.policy,
+            "mutable_tags": len(mutable_tags),
+            "reasons": reasons,
+            "dependent_rules": len(dependent_rules),
+            "age_based_dependent_rules": sum(1 for rule in dependent_rules if rule["age_based"]),
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {"summary": summary, "mutable_tags": mutable_tags, "dependent_rules": dependent_rules}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "mutable-tags-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        if args.fail_on_risk and summary["age_based_dependent_rules"]:
+            logger.error("\n❌ Age-based policy rules depend on mutable tags")
+            sys.exit(1)
+        logger.info("\n✅ Mutable tags report completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Mutable tags report failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,213 @@
+#!/usr/bin/env python3
+"""
+Tag Naming Audit
+
+This script reports tags that do not follow the expected naming convention,
+per repository. Domino pushes environment and model images under tags built
+from the environment or model ObjectID and its revision, so tags that do not
+match that scheme were usually pushed by hand: they have no MongoDB record,
+the usage-based cleanups cannot attribute them, and they are frequent cleanup
+blockers.
+
+The convention is a regular expression every tag must match in full; it
+defaults to Domino's tag scheme and can be set with --pattern or
+analysis.tag_naming_pattern. Signature, attestation and SBOM tags, and tags
+matching analysis.exclude_tags, are not checked.
+
+Usage examples:
+  # Audit the environment and model repositories
+  python naming_audit.py
+
+  # Audit with a custom convention
+  python naming_audit.py --pattern '^(v[0-9]+\\.[0-9]+\\.[0-9]+|[0-9a-f]{24}-[0-9]+)$'
+
+  # Audit the tags of a saved scan snapshot, without registry access
+  python naming_audit.py --snapshot reports/scan-snapshot-<timestamp>.json
+"""
+
+import argparse
+import re
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import SkopeoClient, config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.scan_snapshot import read_snapshot
+from utils.tag_matching import find_nonconforming_tags
+
+logger = get_logger(__name__)
+
+
+def tags_from_registry(args: argparse.Namespace) -> Dict[str, List[str]]:
+    """Tags of each audited repository, listed from the registry"""
+    skopeo_client = SkopeoClient(config_manager)
+    if args.repositories:
+        repositories = sorted(set(args.repositories))
+    else:
+        repository = args.repository or config_manager.get_repository()
+        repositories = [f"{repository}/{image_type}" for image_type in args.image_types]
+    tags: Dict[str, List[str]] = {}
+    for repository in repositories:
+        logger.info(f"Listing tags of {repository}...")
+        tags[repository] = skopeo_client.list_tags(repository)
+    return tags
+
+
+def tags_from_snapshot(path: str) -> Dict[str, List[str]]:
+    """Tags of each repository in a saved scan snapshot"""
+    tags: Dict[str, List[str]] = {}
+    for image in read_snapshot(path)["images"].values():
+        tags.setdefault(image["repository"], []).append(image["tag"])
+    return tags
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Tag Naming Audit")
+    logger.info("=" * 80)
+    logger.info(f"Convention: {summary['pattern']}")
+    logger.info(f"Tags checked: {summary['tags_checked']}")
+    logger.info(f"Non-conforming tags: {summary['nonconforming_tags']}")
+
+    for audit in report_data["repositories"]:
+        if not audit["nonconforming"]:
+            continue
+        logger.info(f"\n{audit['repository']}: {len(audit['nonconforming'])} of {audit['tags']} tag(s)")
+        tags = audit["nonconforming"]
+        more = f" (+{len(tags) - 20} more)" if len(tags) > 20 else ""
+        logger.info(f"   {', '.join(tags[:20])}{more}")
+
+    logger.info("=" * 80)
+    if not summary["nonconforming_tags"]:
+        logger.info("Every tag follows the naming convention.")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Report tags that do not follow the expected naming convention, per repository",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Audit the environment and model repositories against Domino's tag scheme
+  python naming_audit.py
+
+  # Audit named repositories with a custom convention
+  python naming_audit.py --repositories team/base-images --pattern 'v[0-9]+\\.[0-9]+\\.[0-9]+'
+
+  # Audit a saved scan snapshot, without registry access
+  python naming_audit.py --snapshot reports/scan-snapshot-<timestamp>.json
+
+  # Fail (exit code 1) when any tag does not conform, e.g. in a CI check
+  python naming_audit.py --fail-on-violation
+        """,
+    )
+
+    source = parser.add_mutually_exclusive_group()
+    source.add_argument("--repositories", nargs="+", help="Repositories to audit (full names)")
+    source.add_argument("--snapshot", metavar="FILE", help="Audit the tags of a saved scan snapshot instead")
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to audit when no repositories are given (default: the profile's image types)",
+    )
+    parser.add_argument("--repository", help="Base repository of the image types (default: from config)")
+    parser.add_argument(
+        "--pattern",
+        help="Regular expression every tag must match in full (default: analysis.tag_naming_pattern, "
+        "which defaults to Domino's <ObjectID>-<revision> scheme)",
+    )
+    parser.add_argument(
+        "--fail-on-violation", action="store_true", help="Exit with code 1 if any tag does not conform"
+    )
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: naming-audit.json in reports directory)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Tag Naming Audit")
+        logger.info("=" * 80)
+
+        if args.pattern:
+            try:
+                pattern = re.compile(args.pattern)
+            except re.error as e:
+                raise ValueError(f"--pattern is not a valid regular expression: {e}") from e
+        else:
+            pattern = config_manager.get_tag_naming_pattern()
+        excluded_patterns = config_manager.get_excluded_tag_patterns()
+
+        tags = tags_from_snapshot(args.snapshot) if args.snapshot else tags_from_registry(args)
+
+        audits = []
+        for repository, repository_tags in sorted(tags.items()):
+            audits.append(
+                {
+                    "repository": repository,
+                    "tags": len(repository_tags),
+                    "nonconforming": find_nonconforming_tags(repository_tags, pattern, excluded_patterns),
+                }
+            )
+
+        summary = {
+            "pattern": pattern.pattern,
+            "source": args.snapshot or "registry",
+            "repositories": len(audits),
+            "repositories_with_violations": sum(1 for audit in audits if audit["nonconforming"]),
+            "tags_checked": sum(audit["tags"] for audit in audits),
+            "nonconforming_tags": sum(len(audit["nonconforming"]) for audit in audits),
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "generated_at": datetime.now().isoformat(),
+        }
+        report_data = {"summary": summary, "repositories": audits}
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "naming-audit.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        if args.fail_on_violation and summary["nonconforming_tags"]:
+            logger.error("\n❌ Tags do not follow the naming convention")
+            sys.exit(1)
+        logger.info("\n✅ Tag naming audit completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Tag naming audit failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
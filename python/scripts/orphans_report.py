@@ -0,0 +1,311 @@
+#!/usr/bin/env python3
+"""
+Orphaned Registry Content Report
+
+This script reports registry content that tag-based retention never sees:
+untagged manifests, broken manifests, and blobs no manifest references. These
+are cleaned up by registry garbage collection rather than by deleting tags, so
+they are reported separately from the image reports.
+
+Untagged manifests and unreferenced blobs can only be found by reading the
+registry's storage directly, so they require read access to it (a mounted
+filesystem or the registry's S3 bucket). Without storage access, only broken
+manifests are reported: tags the registry lists but whose manifest cannot be
+fetched.
+
+With an S3 Inventory report of the registry's bucket (--inventory), the bucket
+is not listed; the inventory's object list is used instead, and the bytes the
+bucket actually stores are reconciled against the bytes manifests reference.
+The gap is attributed to orphaned blobs, upload debris left by interrupted
+pushes, and replication copies (noncurrent versions and replicated objects).
+
+Usage examples:
+  # Report broken manifests through the registry API
+  python orphans_report.py
+
+  # Full orphan scan of an S3-backed registry
+  python orphans_report.py --storage-bucket my-registry-bucket
+
+  # Full orphan scan of a registry volume mounted at /var/lib/registry
+  python orphans_report.py --storage-path /var/lib/registry
+
+  # Reconcile stored bytes using the bucket's S3 Inventory report
+  python orphans_report.py --inventory s3://inventory-bucket/my-registry-bucket/daily/2026-01-01T01-00Z/manifest.json
+"""
+
+import argparse
+import concurrent.futures
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import SkopeoClient, config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.registry_storage import (
+    BrokenManifest,
+    FilesystemRegistryStorage,
+    S3RegistryStorage,
+    find_orphans,
+)
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.s3_inventory import InventoryRegistryStorage, load_inventory, reconcile
+
+logger = get_logger(__name__)
+
+
+def find_broken_tags(
+    skopeo_client: SkopeoClient, repository: str, image_types: List[str], max_workers: int
+) -> List[BrokenManifest]:
+    """Find tags the registry lists but whose manifest cannot be fetched.
+
+    Args:
+        skopeo_client: Registry client
+        repository: Base repository (e.g. "dominodatalab")
+        image_types: Image types to check
+        max_workers: Number of parallel manifest requests
+
+    Returns:
+        Broken manifests, one per affected tag
+    """
+    broken: List[BrokenManifest] = []
+    for image_type in image_types:
+        image_repository = f"{repository}/{image_type}"
+        tags = skopeo_client.list_tags(image_repository)
+        logger.info(f"Checking {len(tags)} {image_type} tags...")
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_tag = {
+                executor.submit(skopeo_client.get_manifest_digest, image_repository, tag): tag for tag in tags
+            }
+            for future in concurrent.futures.as_completed(future_to_tag):
+                tag = future_to_tag[future]
+                try:
+                    digest = future.result()
+                except Exception as e:
+                    logger.debug(f"Manifest lookup for {image_repository}:{tag} failed: {e}")
+                    digest = None
+                if not digest:
+                    broken.append(
+                        {
+                            "repository": image_repository,
+                            "digest": "",
+                            "tags": [tag],
+                            "reason": "tag is listed but its manifest cannot be fetched",
+                        }
+                    )
+    broken.sort(key=lambda entry: (entry["repository"], entry["tags"]))
+    return broken
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Orphaned Registry Content")
+    logger.info("=" * 80)
+    logger.info(f"Scan source: {summary['source']}")
+    logger.info(f"Broken manifests: {summary['broken_manifests']}")
+    for entry in report_data["broken_manifests"][:20]:
+        tags = ", ".join(entry["tags"]) or "(untagged)"
+        logger.info(f"   {entry['repository']} {entry['digest'] or ''} [{tags}]: {entry['reason']}")
+
+    if summary["source"] == "registry_api":
+        logger.info("\nUntagged manifests and unreferenced blobs require storage access")
+        logger.info("(--storage-path or --storage-bucket); they were not scanned.")
+    else:
+        untagged_bytes = sum(m["size_bytes"] for m in report_data["untagged_manifests"])
+        logger.info(f"Untagged manifests: {summary['untagged_manifests']} ({sizeof_fmt(untagged_bytes)} of manifests)")
+        logger.info(
+            f"Unreferenced blobs: {summary['unreferenced_blobs']} ({sizeof_fmt(summary['unreferenced_bytes'])})"
+        )
+        for blob in report_data["unreferenced_blobs"][:10]:
+            logger.info(f"   {blob['digest']}  {sizeof_fmt(blob['size_bytes'])}")
+    if "reconciliation" in report_data:
+        print_reconciliation(report_data["reconciliation"])
+    logger.info("=" * 80)
+    logger.info("Orphaned content is removed by registry garbage collection (see run_registry_gc).")
+
+
+def print_reconciliation(reconciliation: Dict) -> None:
+    """Print where the bytes stored in the bucket go"""
+    summary = reconciliation["summary"]
+    categories = reconciliation["categories"]
+    logger.info("\nStorage reconciliation (S3 Inventory):")
+    logger.info(f"   Stored in bucket:        {sizeof_fmt(summary['stored_bytes'])} in {summary['objects']} objects")
+    logger.info(f"   Referenced by manifests: {sizeof_fmt(summary['referenced_bytes'])}")
+    logger.info(f"   Gap:                     {sizeof_fmt(summary['gap_bytes'])}")
+    for name, label in (
+        ("orphaned_blobs", "Orphaned blobs"),
+        ("upload_debris", "Upload debris"),
+        ("replication_copies", "Replication copies"),
+        ("registry_metadata", "Registry metadata"),
+        ("other", "Other objects"),
+    ):
+        category = categories[name]
+        logger.info(f"      {label + ':':<20} {sizeof_fmt(category['bytes'])} in {category['objects']} objects")
+    if summary["noncurrent_versions"]:
+        logger.info(
+            f"   {summary['noncurrent_versions']} noncurrent versions ({sizeof_fmt(summary['noncurrent_bytes'])}) "
+            "are kept by bucket versioning; a lifecycle rule expiring noncurrent versions frees them"
+        )
+    for entry in reconciliation["upload_debris"][:10]:
+        logger.info(f"   {entry['repository']}: {entry['uploads']} partial upload(s), {sizeof_fmt(entry['bytes'])}")
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Report untagged manifests, broken manifests, and unreferenced blobs",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Report broken manifests through the registry API
+  python orphans_report.py
+
+  # Full orphan scan of an S3-backed registry
+  python orphans_report.py --storage-bucket my-registry-bucket
+
+  # Full orphan scan of a registry volume mounted at /var/lib/registry
+  python orphans_report.py --storage-path /var/lib/registry
+
+  # Reconcile stored bytes using the bucket's S3 Inventory report
+  python orphans_report.py --inventory s3://inventory-bucket/my-registry-bucket/daily/2026-01-01T01-00Z/manifest.json
+
+  # Specify output file
+  python orphans_report.py --storage-bucket my-registry-bucket --output orphans.json
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: orphans-report.json in reports directory)"
+    )
+
+    storage = parser.add_mutually_exclusive_group()
+    storage.add_argument(
+        "--storage-path", help="Mounted root directory of the registry storage (default: config registry_storage.path)"
+    )
+    storage.add_argument(
+        "--storage-bucket", help="S3 bucket the registry stores its data in (default: config registry_storage.s3_bucket)"
+    )
+    parser.add_argument(
+        "--inventory",
+        metavar="MANIFEST",
+        help="manifest.json of an S3 Inventory report of the bucket (s3:// URL or local path); "
+        "lists the bucket from the inventory and reconciles stored bytes",
+    )
+    parser.add_argument(
+        "--storage-prefix",
+        help="Path of the registry data below the storage root (default: config registry_storage.prefix)",
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to check through the registry API when storage is not accessible "
+        "(default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel manifest requests (default: from config)"
+    )
+
+    args = parser.parse_args()
+    if args.inventory and args.storage_path:
+        parser.error("--inventory reconciles an S3 bucket and cannot be combined with --storage-path")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Orphaned Registry Content Report")
+        logger.info("=" * 80)
+
+        storage_prefix = args.storage_prefix or config_manager.get_registry_storage_prefix()
+        # Flags take precedence over config; a bucket flag overrides a configured path and vice versa
+        storage_path = args.storage_path
+        storage_bucket = args.storage_bucket
+        if not storage_path and not storage_bucket:
+            storage_path = config_manager.get_registry_storage_path()
+            storage_bucket = config_manager.get_registry_storage_bucket()
+
+        if args.inventory:
+            logger.info(f"Reading S3 Inventory report: {args.inventory}")
+            manifest, objects = load_inventory(args.inventory)
+            # Manifests are read from the inventoried bucket unless another one is given
+            storage_bucket = args.storage_bucket or manifest.get("sourceBucket") or storage_bucket
+            if not storage_bucket:
+                raise ValueError("The inventory names no source bucket; set --storage-bucket")
+            reader = S3RegistryStorage(storage_bucket, storage_prefix)
+            report_data = find_orphans(InventoryRegistryStorage(objects, reader, storage_prefix))
+            report_data["reconciliation"] = reconcile(objects, storage_prefix, report_data)
+            source = "s3_inventory"
+        elif storage_path:
+            logger.info(f"Reading registry storage at: {storage_path}")
+            report_data = find_orphans(FilesystemRegistryStorage(storage_path, storage_prefix))
+            source = "filesystem"
+        elif storage_bucket:
+            logger.info(f"Reading registry storage in bucket: {storage_bucket}")
+            report_data = find_orphans(S3RegistryStorage(storage_bucket, storage_prefix))
+            source = "s3"
+        else:
+            logger.info("No registry storage configured; checking tags through the registry API")
+            broken = find_broken_tags(
+                SkopeoClient(config_manager),
+                config_manager.get_repository(),
+                args.image_types,
+                args.max_workers or config_manager.get_max_workers(),
+            )
+            report_data = {
+                "summary": {"broken_manifests": len(broken)},
+                "untagged_manifests": [],
+                "broken_manifests": broken,
+                "unreferenced_blobs": [],
+            }
+            source = "registry_api"
+
+        report_data["summary"].update(
+            {
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
+                "source": source,
+                "generated_at": datetime.now().isoformat(),
+            }
+        )
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "orphans-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        logger.info("\n✅ Orphan report generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,227 @@
+#!/usr/bin/env python3
+"""
+Owner Usage Report
+
+This script attributes every image, and the registry storage it uses, to an
+owner taken from the image's labels, for chargeback and for finding who to ask
+about large images. The owner is the value of the first label from
+analysis.owner_labels in config.yaml (or --label) that the image has.
+
+For each owner the report lists:
+  - exclusive bytes: layers only the owner's images use, i.e. what deleting all
+    of them would free
+  - shared bytes: layers the owner's images share with other owners' images
+  - amortized bytes: every layer's size split evenly between the images using
+    it, which adds up to the registry's total across owners
+
+Images without any of the labels are attributed to "(unlabeled)". Labels are
+read from the image config, so images sized from their manifest in a --fast
+scan have no labels unless an earlier full scan cached them, and are
+attributed to "(unknown)".
+
+Usage examples:
+  # Attribute images to owners using the configured owner labels
+  python owner_usage_report.py
+
+  # Use the "team" label, falling back to "maintainer"
+  python owner_usage_report.py --label team --label maintainer
+
+  # Environment images only, and show the 50 largest owners
+  python owner_usage_report.py --image-types environment --top 50
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.ownership import UNKNOWN, UNLABELED
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def generate_owner_usage_report(analyzer: ImageAnalyzer, label_keys: List[str], image_types: List[str]) -> Dict:
+    """Generate the owner usage report.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        label_keys: Owner label keys, in order of preference
+        image_types: Image types the analyzer scanned
+
+    Returns:
+        Dict with summary, usage per owner and the owner of every image
+    """
+    owners = analyzer.owner_usage(label_keys)
+    images = analyzer.image_attribution(label_keys)
+    images_by_owner = {entry["owner"]: entry["images"] for entry in owners}
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_images": len(images),
+            "image_types": image_types,
+            "owner_labels": label_keys,
+            "total_owners": len([entry for entry in owners if entry["owner"] not in (UNLABELED, UNKNOWN)]),
+            "total_bytes": sum(entry["amortized_bytes"] for entry in owners),
+            "unlabeled_images": images_by_owner.get(UNLABELED, 0),
+            "unknown_images": images_by_owner.get(UNKNOWN, 0),
+            "generated_at": datetime.now().isoformat(),
+        },
+        "owners": owners,
+        "images": images,
+    }
+
+
+def print_report_summary(report_data: Dict, top: int) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+    owners = report_data["owners"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Registry Usage by Owner")
+    logger.info("=" * 80)
+    logger.info(f"Images: {summary['total_images']}")
+    logger.info(f"Owner labels: {', '.join(summary['owner_labels'])}")
+    logger.info(f"Owners: {summary['total_owners']}")
+    logger.info(f"Registry storage: {sizeof_fmt(summary['total_bytes'])}")
+
+    logger.info(f"\nTop {min(top, len(owners))} owners by amortized storage:")
+    logger.info(f"{'Amortized':>10}  {'Exclusive':>10}  {'Shared':>10}  {'Images':>6}  Owner")
+    for entry in owners[:top]:
+        logger.info(
+            f"{sizeof_fmt(entry['amortized_bytes']):>10}  {sizeof_fmt(entry['exclusive_bytes']):>10}  "
+            f"{sizeof_fmt(entry['shared_bytes']):>10}  {entry['images']:>6}  {entry['owner']}"
+        )
+
+    if summary["unlabeled_images"]:
+        logger.info(f"\n⚠️  {summary['unlabeled_images']} image(s) have none of the owner labels")
+    if summary["unknown_images"]:
+        logger.info(f"⚠️  {summary['unknown_images']} image(s) were not fully inspected; their labels are unknown")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Attribute images and their registry storage to owners from image labels",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Attribute images to owners using the configured owner labels
+  python owner_usage_report.py
+
+  # Use the "team" label, falling back to "maintainer"
+  python owner_usage_report.py --label team --label maintainer
+
+  # Environment images only, and show the 50 largest owners
+  python owner_usage_report.py --image-types environment --top 50
+
+  # Specify output file
+  python owner_usage_report.py --output owner-usage.json
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: owner-usage-report.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--label",
+        action="append",
+        dest="labels",
+        metavar="KEY",
+        help="Owner label key; repeat to fall back to further labels (default: analysis.owner_labels from config)",
+    )
+
+    parser.add_argument("--top", type=int, default=20, metavar="N", help="Number of owners to print (default: 20)")
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    parser.add_argument(
+        "--fast",
+        action="store_true",
+        help="Approximate triage scan: fetch only manifests and reuse cached inspections (labels of uncached "
+        "images are unknown)",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        label_keys = args.labels or config_manager.get_owner_label_keys()
+
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info("=" * 80)
+        logger.info("   Owner Usage Report")
+        logger.info("=" * 80)
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        logger.info(f"Image Types: {', '.join(args.image_types)}")
+        logger.info(f"Owner labels: {', '.join(label_keys)}")
+        logger.info("=" * 80)
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"\nAnalyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers, fast=args.fast):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        report_data = generate_owner_usage_report(analyzer, label_keys, args.image_types)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "owner-usage-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data, args.top)
+
+        logger.info("\n✅ Owner usage report generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
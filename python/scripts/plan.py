@@ -0,0 +1,653 @@
+#!/usr/bin/env python3
+"""
+Cleanup Plan Generator
+
+This script selects images for deletion and writes them to a versioned plan file
+instead of deleting them. The plan records each target tag, the digest it points
+to now, the expected bytes freed, and the policy that selected it, so it can be
+reviewed and approved before someone else runs `apply` against it.
+
+Selection sources:
+  --input FILE   Images listed in a candidate file (one <type>:<tag> or bare tag per line)
+  --unused       Images whose tags are not referenced by any Domino workload,
+                 and images past the expiry set at build time (analysis.expiry_keys,
+                 e.g. a quay.expires-after label) that current configuration does
+                 not reference and no run used recently (--ignore-expiry to skip)
+  --policy FILE  Images a retention policy deletes, evaluated against a saved
+                 scan snapshot (--snapshot); delete rules with replicate_to
+                 mark their images for copying to an archive registry first
+
+--annotation KEY=PATTERN narrows either source to images whose OCI annotations
+match (repeatable; created, source and revision stand for the
+org.opencontainers.image.* keys).
+
+Images the protection providers in protection.providers protect - Domino
+usage, running pods, a reference file, recent ECR pulls - are dropped from
+every source, with the providers that protected them logged (see
+utils/protection_providers.py).
+
+Signatures, attestations and SBOMs attached to a planned image under
+sha256-<digest>.<kind> tags are planned with it when every tag of its manifest
+is planned, so apply deletes them before the image they describe.
+
+With owner quotas configured (quotas in config.yaml), the plan records every
+owner's storage against its quota, and --prioritize-over-quota (or
+quotas.prioritize_plans) lists the images of owners over quota first.
+
+--keep-set FILE also writes the images the plan keeps as a runnable script
+(one docker pull, or skopeo copy with --keep-set-action copy, per image) or a
+skopeo sync YAML file (--keep-set-format skopeo-sync), for disaster-recovery
+rehearsals and registry migrations (see utils/keep_set.py).
+
+Usage examples:
+  # Plan deletion of all unused images
+  python plan.py --unused
+
+  # Plan deletion of images not used in the last 30 days
+  python plan.py --unused --unused-since-days 30
+
+  # Plan deletion of hand-picked candidates
+  python plan.py --input candidates.txt --output reviewed-plan.json
+
+  # Plan deletion of unused images built from one source repository
+  python plan.py --unused --annotation source=https://github.com/example/app
+
+  # Plan what a retention policy deletes from a saved snapshot
+  python plan.py --policy policy.yaml --snapshot reports/scan-snapshot-<timestamp>.json
+
+  # Also write a script copying every kept image to a new registry
+  python plan.py --unused --keep-set migrate.sh --keep-set-action copy --copy-to new-registry.example.com
+"""
+
+import argparse
+import os
+import sys
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Dict, List, Optional, Tuple
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.cleanup_plan import CleanupPlan, PlanItem, PolicyProvenance, save_plan
+from utils.config_manager import config_manager
+from utils.deletion_candidates import TagUsage, days_since
+from utils.image_data_analysis import ImageAnalyzer
+from utils.image_expiry import ImageExpiry
+from utils.keep_set import (
+    ACTION_COPY,
+    ACTION_PULL,
+    FORMAT_SCRIPT,
+    FORMAT_SKOPEO_SYNC,
+    KEEP_SET_ACTIONS,
+    KEEP_SET_FORMATS,
+    keep_set,
+    render_keep_set,
+)
+from utils.logging_utils import get_logger, setup_logging
+from utils.object_id_utils import read_image_references_from_file
+from utils.oci_annotations import annotations_match, parse_annotation_filters
+from utils.protection_providers import Protections, collect_protections, configured_providers, describe_protection
+from utils.pull_availability import pull_alternatives
+from utils.quotas import check_owner_quotas, over_quota_owners, prioritize_over_quota
+from utils.report_utils import ensure_mongodb_reports, sizeof_fmt
+from utils.retention_policy import evaluate_policy, load_policy
+from utils.scan_snapshot import load_snapshot
+from utils.statsd_metrics import emit_run_metrics
+
+logger = get_logger(__name__)
+
+
+def select_candidate_file_images(analyzer: ImageAnalyzer, input_file: str, image_types: List[str]) -> List[str]:
+    """Select analyzed images listed in a candidate file.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        input_file: Candidate file path
+        image_types: Image types searched for bare tags
+
+    Returns:
+        List of image_ids found in the registry
+    """
+    image_ids: List[str] = []
+    for reference in read_image_references_from_file(input_file):
+        image_id = analyzer.resolve_image_id(reference, image_types)
+        if image_id:
+            image_ids.append(image_id)
+        else:
+            logger.warning(f"⚠️  Candidate '{reference}' not found in registry, skipping")
+    return image_ids
+
+
+def select_unused_images(analyzer: ImageAnalyzer, unused_since_days: Optional[int] = None) -> List[str]:
+    """Select analyzed images whose tags are not in use by any Domino workload.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        unused_since_days: If set, usage older than N days does not count as in-use
+
+    Returns:
+        List of image_ids of unused images
+    """
+    from utils.image_usage import ImageUsageService
+
+    tags = [image_data["tag"] for image_data in analyzer.images.values()]
+    in_use_tags, _ = ImageUsageService().check_tags_in_use(tags, recent_days=unused_since_days)
+    return [image["image_id"] for image in analyzer.get_unused_images(list(in_use_tags))]
+
+
+def select_expired_images(analyzer: ImageAnalyzer) -> Dict[str, ImageExpiry]:
+    """Select analyzed images past the expiry set at build time that nothing still needs.
+
+    Images referenced by current configuration, or used by a run or workspace
+    within analysis.recent_run_protection_days, are kept whatever their expiry.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+
+    Returns:
+        Expiry of each selected image, by image_id
+    """
+    from utils.image_usage import ImageUsageService
+
+    expired = {image_id: expiry for image_id, expiry in analyzer.image_expiries().items() if expiry["expired"]}
+    if not expired:
+        return {}
+    usage = ImageUsageService().summarize_tag_usage([analyzer.images[image_id]["tag"] for image_id in expired])
+    protection_days = config_manager.get_recent_run_protection_days()
+    now = datetime.now(timezone.utc)
+
+    selected: Dict[str, ImageExpiry] = {}
+    for image_id, expiry in expired.items():
+        tag_usage = usage.get(analyzer.images[image_id]["tag"])
+        if tag_usage and tag_usage["protected_by"]:
+            logger.info(f"Expired image {image_id} is still referenced by {', '.join(tag_usage['protected_by'])}")
+            continue
+        idle_days = days_since(tag_usage["last_used"], now) if tag_usage else None
+        if protection_days and idle_days is not None and idle_days < protection_days:
+            continue
+        selected[image_id] = expiry
+    return selected
+
+
+def select_policy_images(
+    analyzer: ImageAnalyzer, usage: Optional[Dict[str, TagUsage]], policy_file: str
+) -> Dict[str, str]:
+    """Select analyzed images a retention policy deletes.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images (e.g. from a snapshot)
+        usage: Usage by tag from the snapshot, or None if it has none
+        policy_file: Retention policy file
+
+    Returns:
+        Archive registry each selected image must be copied to before deletion
+        ("" if none), by image_id
+    """
+    decisions = evaluate_policy(
+        load_policy(policy_file), analyzer, usage, protection_days=config_manager.get_recent_run_protection_days()
+    )
+    return {d["image_id"]: d["replicate_to"] for d in decisions if d["action"] == "delete"}
+
+
+def filter_by_annotations(analyzer: ImageAnalyzer, image_ids: List[str], filters: Dict[str, str]) -> List[str]:
+    """Keep the selected images whose OCI annotations match every filter.
+
+    Images whose annotations were not collected cannot be matched and are dropped.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        image_ids: Selected image_ids
+        filters: Full annotation key -> pattern (see oci_annotations.parse_annotation_filters)
+
+    Returns:
+        The matching image_ids, in their original order
+    """
+    selected: List[str] = []
+    unknown = 0
+    for image_id in image_ids:
+        matched = annotations_match(analyzer.annotations.get(image_id), filters)
+        if matched:
+            selected.append(image_id)
+        elif matched is None:
+            unknown += 1
+    if unknown:
+        logger.warning(f"⚠️  {unknown} image(s) have no collected annotations and were not selected")
+    return selected
+
+
+def drop_protected(image_ids: List[str], protections: Protections) -> List[str]:
+    """Drop the selected images protection providers protect.
+
+    Args:
+        image_ids: Selected image_ids
+        protections: Images protection providers protect (collect_protections)
+
+    Returns:
+        The unprotected image_ids, in their original order
+    """
+    selected: List[str] = []
+    for image_id in image_ids:
+        if image_id in protections:
+            logger.info(f"Not planning {image_id}: protected by {describe_protection(protections[image_id])}")
+        else:
+            selected.append(image_id)
+    if len(selected) < len(image_ids):
+        logger.info(f"{len(image_ids) - len(selected)} selected image(s) are protected and were not planned")
+    return selected
+
+
+def attached_artifact_items(analyzer: ImageAnalyzer, items: List[PlanItem]) -> List[PlanItem]:
+    """Plan items for the artifacts attached to planned images.
+
+    A signature, attestation or SBOM is only planned when every analyzed tag
+    of the manifest it describes is planned; it is copied to the same archive
+    registry as its image.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images and attached artifacts
+        items: Planned images
+
+    Returns:
+        One item per attached artifact, with subject_digest set
+    """
+    planned = {item.image_id: item for item in items}
+    tags_by_manifest: Dict[Tuple[str, str], List[str]] = {}
+    for image_id, image_data in analyzer.images.items():
+        tags_by_manifest.setdefault((image_data["repository"], image_data["digest"]), []).append(image_id)
+
+    artifact_items: List[PlanItem] = []
+    seen = set()
+    for item in items:
+        for artifact in analyzer.attached_artifacts.get(item.image_id, []):
+            key = (artifact["repository"], artifact["tag"])
+            manifest_tags = tags_by_manifest.get((artifact["repository"], artifact["subject_digest"]), [])
+            if key in seen or not all(image_id in planned for image_id in manifest_tags):
+                continue
+            seen.add(key)
+            digest = analyzer.skopeo_client.get_image_digest(artifact["repository"], artifact["tag"])
+            if not digest:
+                logger.warning(f"⚠️  Could not resolve {artifact['repository']}:{artifact['tag']}, not planning it")
+                continue
+            image_type = item.image_id.split(":", 1)[0]
+            artifact_items.append(
+                PlanItem(
+                    image_id=f"{image_type}:{artifact['tag']}",
+                    repository=artifact["repository"],
+                    tag=artifact["tag"],
+                    digest=digest,
+                    reason=f"attached to {item.image_id}",
+                    replicate_to=item.replicate_to,
+                    subject_digest=artifact["subject_digest"],
+                )
+            )
+    return artifact_items
+
+
+def build_plan(
+    analyzer: ImageAnalyzer,
+    image_ids: List[str],
+    policy: PolicyProvenance,
+    reason: str = "",
+    replicate_to: Optional[Dict[str, str]] = None,
+    reasons: Optional[Dict[str, str]] = None,
+) -> CleanupPlan:
+    """Build a cleanup plan for the selected images.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        image_ids: Selected image_ids
+        policy: Provenance of the selection
+        reason: Reason recorded on every item without its own in reasons
+        replicate_to: Archive registry to copy images to before deletion, by image_id
+        reasons: Reason recorded on an item instead of reason, by image_id
+
+    Returns:
+        CleanupPlan with one item per image, sorted by expected bytes freed, followed
+        by the artifacts attached to them; items whose content a surviving image still
+        provides list it in pullable_from (see utils.pull_availability)
+    """
+    alternatives = pull_alternatives(analyzer, image_ids)
+    items: List[PlanItem] = []
+    for image_id in sorted(set(image_ids)):
+        image_data = analyzer.images[image_id]
+        items.append(
+            PlanItem(
+                image_id=image_id,
+                repository=image_data["repository"],
+                tag=image_data["tag"],
+                digest=image_data["digest"],
+                size_bytes=analyzer.get_image_total_size(image_id),
+                expected_freed_bytes=analyzer.freed_space_if_deleted([image_id]),
+                reason=(reasons or {}).get(image_id, reason),
+                replicate_to=(replicate_to or {}).get(image_id, ""),
+                pullable_from=alternatives.get(image_id, []),
+            )
+        )
+    items.sort(key=lambda item: item.expected_freed_bytes, reverse=True)
+    expected_freed_bytes = analyzer.freed_space_if_deleted([item.image_id for item in items])
+
+    return CleanupPlan(
+        registry_url=analyzer.registry_url,
+        repository=analyzer.repository,
+        policy=policy,
+        items=items + attached_artifact_items(analyzer, items),
+        expected_freed_bytes=expected_freed_bytes,
+    )
+
+
+def apply_owner_quotas(plan: CleanupPlan, analyzer: ImageAnalyzer, prioritize: bool) -> None:
+    """Record owner quota status in a plan and, if asked, put images of owners over quota first.
+
+    Args:
+        plan: Plan to update
+        analyzer: ImageAnalyzer instance with analyzed images
+        prioritize: List the images of owners over quota first (also quotas.prioritize_plans)
+    """
+    quotas = config_manager.get_owner_quotas()
+    if quotas is None:
+        if prioritize:
+            logger.warning("⚠️  No owner quotas configured (quotas in config.yaml); plan order unchanged")
+        return
+
+    label_keys = config_manager.get_owner_label_keys()
+    plan.owner_quotas = check_owner_quotas(analyzer.owner_usage(label_keys), quotas)
+    over_owners = over_quota_owners(plan.owner_quotas)
+    for status in plan.owner_quotas[: len(over_owners)]:
+        logger.warning(
+            f"⚠️  Owner {status['owner']} uses {sizeof_fmt(status['used_bytes'])} of registry storage "
+            f"(quota {sizeof_fmt(status['quota_bytes'])})"
+        )
+    if over_owners and (prioritize or quotas["prioritize_plans"]):
+        plan.items = prioritize_over_quota(plan.items, analyzer.image_owners(label_keys), over_owners)
+        plan.policy.options["prioritized_owners"] = over_owners
+
+
+def write_keep_set(
+    analyzer: ImageAnalyzer,
+    plan: CleanupPlan,
+    output_path: str,
+    output_format: str,
+    action: str,
+    destination: Optional[str] = None,
+) -> int:
+    """Write the images a plan keeps as a script or skopeo sync file.
+
+    Args:
+        analyzer: ImageAnalyzer instance the plan was built from
+        plan: The cleanup plan
+        output_path: File to write
+        output_format: One of KEEP_SET_FORMATS
+        action: With FORMAT_SCRIPT, one of KEEP_SET_ACTIONS
+        destination: Registry to copy to (ACTION_COPY)
+
+    Returns:
+        Number of kept images written
+    """
+    images = keep_set(analyzer, [item.image_id for item in plan.items])
+    content = render_keep_set(images, analyzer.registry_url, output_format, action, destination)
+    Path(output_path).parent.mkdir(parents=True, exist_ok=True)
+    Path(output_path).write_text(content)
+    if output_format == FORMAT_SCRIPT:
+        os.chmod(output_path, 0o755)
+    return len(images)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Select images for deletion and write them to a reviewable plan file",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Plan deletion of all unused images
+  python plan.py --unused
+
+  # Plan deletion of images not used in the last 30 days
+  python plan.py --unused --unused-since-days 30
+
+  # Plan deletion of hand-picked candidates
+  python plan.py --input candidates.txt --output reviewed-plan.json
+
+  # Plan deletion of unused images built from one source repository
+  python plan.py --unused --annotation source=https://github.com/example/app
+
+  # Plan what a retention policy deletes from a saved snapshot
+  python plan.py --policy policy.yaml --snapshot reports/scan-snapshot-<timestamp>.json
+
+  # Also write a script pulling every image the plan keeps (DR rehearsal)
+  python plan.py --unused --keep-set keep-set.sh
+
+  # Or a skopeo sync file for a registry migration
+  python plan.py --unused --keep-set keep-set.yaml --keep-set-format skopeo-sync
+
+  # Apply the plan after review
+  python apply.py reviewed-plan.json --apply
+        """,
+    )
+
+    source = parser.add_mutually_exclusive_group(required=True)
+    source.add_argument("--input", help="Candidate file (one <type>:<tag> or bare tag per line)")
+    source.add_argument("--unused", action="store_true", help="Select images not used by any Domino workload")
+    source.add_argument("--policy", help="Select images a retention policy file deletes (requires --snapshot)")
+
+    parser.add_argument(
+        "--snapshot", help="With --policy: scan snapshot saved by image_data_analysis --mode snapshot"
+    )
+
+    parser.add_argument(
+        "--unused-since-days",
+        dest="unused_since_days",
+        type=int,
+        metavar="N",
+        help="With --unused: only consider images in-use if used in the last N days",
+    )
+
+    parser.add_argument(
+        "--generate-reports",
+        action="store_true",
+        help="With --unused: force regeneration of MongoDB usage reports",
+    )
+
+    parser.add_argument(
+        "--ignore-expiry",
+        action="store_true",
+        help="With --unused: do not also select images past the expiry set at build time",
+    )
+
+    parser.add_argument(
+        "--annotation",
+        action="append",
+        default=[],
+        metavar="KEY=PATTERN",
+        help="Only select images whose OCI annotation KEY matches PATTERN (repeatable; "
+        "KEY may be created, source or revision)",
+    )
+
+    parser.add_argument(
+        "--prioritize-over-quota",
+        action="store_true",
+        help="List the images of owners over their storage quota first (default: quotas.prioritize_plans)",
+    )
+
+    parser.add_argument("--output", help="Output plan file (default: cleanup-plan.json in reports directory)")
+
+    parser.add_argument("--keep-set", metavar="FILE", help="Also write the images the plan keeps to FILE")
+
+    parser.add_argument(
+        "--keep-set-format",
+        choices=KEEP_SET_FORMATS,
+        default=FORMAT_SCRIPT,
+        help="With --keep-set: a runnable script, or a skopeo sync YAML file (default: script)",
+    )
+
+    parser.add_argument(
+        "--keep-set-action",
+        choices=KEEP_SET_ACTIONS,
+        default=ACTION_PULL,
+        help="With --keep-set-format script: docker pull each image, or skopeo copy it to --copy-to (default: pull)",
+    )
+
+    parser.add_argument("--copy-to", metavar="REGISTRY", help="With --keep-set-action copy: registry to copy to")
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to analyze (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    args = parser.parse_args()
+    if args.policy and not args.snapshot:
+        parser.error("--policy requires --snapshot")
+    if args.keep_set_action == ACTION_COPY and not args.copy_to:
+        parser.error("--keep-set-action copy requires --copy-to")
+    if args.keep_set_format == FORMAT_SKOPEO_SYNC and args.keep_set_action == ACTION_COPY:
+        parser.error("--keep-set-action only applies to --keep-set-format script")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        annotation_filters = parse_annotation_filters(args.annotation)
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info("=" * 60)
+        logger.info("   Cleanup Plan Generator")
+        logger.info("=" * 60)
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        if args.policy:
+            source = f"policy {args.policy} on snapshot {args.snapshot}"
+        else:
+            source = "candidate file " + args.input if args.input else "unused images"
+        logger.info(f"Source: {source}")
+        logger.info("=" * 60)
+
+        replicate_to: Dict[str, str] = {}
+        reasons: Dict[str, str] = {}
+        usage: Optional[Dict[str, TagUsage]] = None
+        if args.policy:
+            analyzer, usage, _ = load_snapshot(args.snapshot)
+            if usage is None:
+                logger.warning("⚠️  Snapshot has no usage data: rules on usage delete no images")
+        else:
+            analyzer = ImageAnalyzer(registry_url, repository)
+            success_count = 0
+            for image_type in args.image_types:
+                logger.info(f"Analyzing {image_type} images...")
+                if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+                    success_count += 1
+
+            if success_count == 0:
+                logger.error("No image data found. Check your registry access.")
+                sys.exit(1)
+
+        if args.policy:
+            replicate_to = select_policy_images(analyzer, usage, args.policy)
+            image_ids = list(replicate_to)
+            policy = PolicyProvenance(
+                source="retention_policy",
+                description=f"Images deleted by retention policy {args.policy}",
+                options={"policy": args.policy, "snapshot": args.snapshot},
+            )
+            reason = "deleted by retention policy"
+        elif args.input:
+            image_ids = select_candidate_file_images(analyzer, args.input, args.image_types)
+            policy = PolicyProvenance(
+                source="candidate_file",
+                description=f"Images listed in {args.input}",
+                options={"input": args.input, "image_types": args.image_types},
+            )
+            reason = "listed in candidate file"
+        else:
+            ensure_mongodb_reports(force=args.generate_reports)
+            image_ids = select_unused_images(analyzer, args.unused_since_days)
+            since = f" in the last {args.unused_since_days} days" if args.unused_since_days else ""
+            policy = PolicyProvenance(
+                source="unused_images",
+                description=f"Images not used by any Domino workload{since}",
+                options={
+                    "unused_since_days": args.unused_since_days,
+                    "image_types": args.image_types,
+                    "ignore_expiry": args.ignore_expiry,
+                },
+            )
+            reason = f"not in use{since}"
+            if not args.ignore_expiry:
+                unused = set(image_ids)
+                for image_id, expiry in select_expired_images(analyzer).items():
+                    if image_id not in unused:
+                        image_ids.append(image_id)
+                        reasons[image_id] = f"expired ({expiry['key']}={expiry['value']})"
+                if reasons:
+                    policy.description += " and images past their build-time expiry"
+
+        if annotation_filters:
+            image_ids = filter_by_annotations(analyzer, image_ids, annotation_filters)
+            conditions = ", ".join(f"{key}={pattern}" for key, pattern in annotation_filters.items())
+            policy.description += f" with annotations {conditions}"
+            policy.options["annotations"] = annotation_filters
+            reason += f", annotations {conditions}"
+            reasons = {image_id: f"{r}, annotations {conditions}" for image_id, r in reasons.items()}
+
+        providers = configured_providers(config_manager.get_protection_providers(), usage)
+        if providers:
+            image_ids = drop_protected(image_ids, collect_protections(analyzer, providers))
+            policy.options["protection_providers"] = [provider.name for provider in providers]
+
+        plan = build_plan(analyzer, image_ids, policy, reason=reason, replicate_to=replicate_to, reasons=reasons)
+        apply_owner_quotas(plan, analyzer, args.prioritize_over_quota)
+
+        output_path = args.output or str(Path(config_manager.get_output_dir()) / "cleanup-plan.json")
+        saved_path = save_plan(plan, output_path, timestamp=not args.output)
+        emit_run_metrics(gauges={"tags_planned": len(plan.items), "bytes_reclaimable": plan.expected_freed_bytes})
+
+        logger.info("\n📊 Plan Summary:")
+        logger.info(f"   Plan ID: {plan.plan_id}")
+        logger.info(f"   Images: {len(plan.items)}")
+        attached = sum(1 for item in plan.items if item.subject_digest)
+        if attached:
+            logger.info(f"   Signatures, attestations and SBOMs of planned images: {attached}")
+        logger.info(f"   Expected space freed: {sizeof_fmt(plan.expected_freed_bytes)}")
+        zero_impact = sum(1 for item in plan.items if item.pullable_from)
+        if zero_impact:
+            logger.info(f"   Zero user impact (content still pullable from a surviving image): {zero_impact}")
+        expired = sum(1 for item in plan.items if item.image_id in reasons)
+        if expired:
+            logger.info(f"   Past their build-time expiry (in use by no current configuration): {expired}")
+        replicated = sum(1 for item in plan.items if item.replicate_to)
+        if replicated:
+            logger.info(f"   Copied to an archive registry before deletion: {replicated}")
+        over_quota = over_quota_owners(plan.owner_quotas)
+        if over_quota:
+            logger.info(f"   Owners over quota: {', '.join(over_quota)}")
+        logger.info(f"   Plan file: {saved_path}")
+        if args.keep_set:
+            kept = write_keep_set(
+                analyzer, plan, args.keep_set, args.keep_set_format, args.keep_set_action, args.copy_to
+            )
+            logger.info(f"   Keep-set ({kept} images, {args.keep_set_format}): {args.keep_set}")
+        logger.info("\nReview the plan, then run: apply <plan-file> --apply")
+
+    except Exception as e:
+        logger.error(f"\n❌ Plan generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
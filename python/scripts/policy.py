@@ -0,0 +1,239 @@
+#!/usr/bin/env python3
+"""
+Retention Policy Tool
+
+This script works with retention policy files (see utils/retention_policy.py
+for the format) without touching the registry.
+
+Subcommands:
+  validate  Check a policy for syntax errors, unknown fields, rules that can
+            never match, and rules that overlap or contradict each other (for
+            example, images both protected by a keep rule and expired by a
+            delete rule). Exits non-zero on errors, or on warnings with --strict.
+  test      Evaluate a policy against a saved scan snapshot and show what it
+            would keep and delete. Needs no registry or MongoDB access, so rules
+            can be iterated on offline before a policy is used for real.
+
+Snapshots are saved by a scan with `image_data_analysis --mode snapshot`.
+
+Usage examples:
+  # Check a policy before using it
+  python policy.py validate --policy policy.yaml
+
+  # Save a snapshot of the registry once
+  python image_data_analysis.py --mode snapshot
+
+  # Test a policy against it as often as needed
+  python policy.py test --snapshot reports/scan-snapshot-<timestamp>.json --policy policy.yaml
+"""
+
+import argparse
+import sys
+from collections import Counter
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.retention_policy import (
+    PolicyDecision,
+    PolicyFormatError,
+    PolicyIssue,
+    evaluate_policy,
+    lint_policy,
+    policy_from_dict,
+    read_policy_file,
+)
+from utils.scan_snapshot import load_snapshot
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+
+def validate_policy(policy_path: str) -> List[PolicyIssue]:
+    """Check a policy file for errors and likely mistakes.
+
+    Args:
+        policy_path: Policy YAML file
+
+    Returns:
+        Issues found, errors first
+    """
+    try:
+        data = read_policy_file(policy_path)
+    except PolicyFormatError as e:
+        return [PolicyIssue("error", "policy", str(e))]
+    return lint_policy(data)
+
+
+def print_issues(issues: List[PolicyIssue]) -> None:
+    """Print policy issues, errors first"""
+    for issue in issues:
+        if issue.severity == "error":
+            logger.error(f"   ❌ {issue.location}: {issue.message}")
+        else:
+            logger.warning(f"   ⚠️  {issue.location}: {issue.message}")
+
+
+def run_policy_test(snapshot_path: str, policy_path: str) -> Dict:
+    """Evaluate a policy against a snapshot.
+
+    Args:
+        snapshot_path: Snapshot file saved by image_data_analysis --mode snapshot
+        policy_path: Policy YAML file
+
+    Returns:
+        Dict with summary, per-rule counts and one decision per image
+    """
+    data = read_policy_file(policy_path)
+    warnings = [issue for issue in lint_policy(data) if issue.severity == "warning"]
+    if warnings:
+        logger.warning(f"Policy has {len(warnings)} warning(s) (run policy validate for details):")
+        print_issues(warnings)
+    policy = policy_from_dict(data)
+    analyzer, usage, metadata = load_snapshot(snapshot_path)
+    if usage is None:
+        logger.warning(
+            "⚠️  Snapshot has no usage data: rules on usage keep every image they could apply to and delete none"
+        )
+
+    decisions = evaluate_policy(
+        policy,
+        analyzer,
+        usage,
+        now=datetime.now(timezone.utc),
+        protection_days=config_manager.get_recent_run_protection_days(),
+    )
+    deleted: List[PolicyDecision] = [d for d in decisions if d["action"] == "delete"]
+    freed_bytes = analyzer.freed_space_if_deleted([d["image_id"] for d in deleted])
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "snapshot": snapshot_path,
+            "snapshot_created_at": metadata.get("created_at"),
+            "snapshot_build": metadata.get("build"),
+            "policy": policy_path,
+            "usage_data": usage is not None,
+            "total_images": len(decisions),
+            "keep": len(decisions) - len(deleted),
+            "delete": len(deleted),
+            "expected_freed_bytes": freed_bytes,
+            "expected_freed_gb": round(freed_bytes / (1024**3), 2),
+        },
+        "rules": dict(Counter(d["rule"] for d in decisions)),
+        "decisions": decisions,
+    }
+
+
+def print_test_summary(result: Dict, show_kept: bool) -> None:
+    """Print what a policy would keep and delete"""
+    summary = result["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Policy Test")
+    logger.info("=" * 80)
+    logger.info(f"Policy: {summary['policy']}")
+    taken = format_timestamp(summary["snapshot_created_at"], config_manager.get_display_timezone(), with_age=True)
+    logger.info(f"Snapshot: {summary['snapshot']} (taken {taken})")
+    logger.info(f"Images: {summary['total_images']}")
+    logger.info(f"Keep: {summary['keep']}")
+    logger.info(f"Delete: {summary['delete']}")
+    logger.info(f"Expected space freed: {sizeof_fmt(summary['expected_freed_bytes'])}")
+
+    logger.info("\nDeciding rule:")
+    for rule, count in sorted(result["rules"].items(), key=lambda item: -item[1]):
+        logger.info(f"   {rule}: {count}")
+
+    shown = [d for d in result["decisions"] if show_kept or d["action"] == "delete"]
+    if shown:
+        logger.info("\nImages:")
+        for decision in shown:
+            undetermined = decision["undetermined_rules"]
+            note = f" (undetermined: {', '.join(undetermined)})" if undetermined else ""
+            if decision["replicate_to"]:
+                note += f" (copy to {decision['replicate_to']} first)"
+            logger.info(f"   {decision['action'].upper():<6} {decision['image_id']}  [{decision['rule']}]{note}")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Work with retention policy files offline",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Check a policy for errors and overlapping rules
+  python policy.py validate --policy policy.yaml
+
+  # Treat warnings as failures (e.g. in CI)
+  python policy.py validate --policy policy.yaml --strict
+
+  # Save a snapshot of the registry (once)
+  python image_data_analysis.py --mode snapshot
+
+  # Show what a policy would delete from the snapshot
+  python policy.py test --snapshot reports/scan-snapshot-<timestamp>.json --policy policy.yaml
+
+  # Also list kept images and save every decision
+  python policy.py test --snapshot snapshot.json --policy policy.yaml --show-kept --output decisions.json
+        """,
+    )
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    validate = subparsers.add_parser("validate", help="Check a policy for errors and overlapping rules")
+    validate.add_argument("--policy", required=True, help="Policy YAML file")
+    validate.add_argument("--strict", action="store_true", help="Fail on warnings as well as errors")
+
+    test = subparsers.add_parser("test", help="Evaluate a policy against a saved scan snapshot")
+    test.add_argument("--snapshot", required=True, help="Snapshot file saved by image_data_analysis --mode snapshot")
+    test.add_argument("--policy", required=True, help="Policy YAML file")
+    test.add_argument("--show-kept", action="store_true", help="List kept images as well as deleted ones")
+    test.add_argument("--output", help="Save every decision to this JSON file")
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        if args.command == "validate":
+            issues = validate_policy(args.policy)
+            errors = [issue for issue in issues if issue.severity == "error"]
+            logger.info(f"Policy: {args.policy}")
+            print_issues(issues)
+            if errors or (args.strict and issues):
+                logger.error(f"\n❌ Policy is not valid: {len(errors)} error(s), {len(issues) - len(errors)} warning(s)")
+                sys.exit(1)
+            logger.info(f"\n✅ Policy is valid ({len(issues)} warning(s))")
+
+        elif args.command == "test":
+            result = run_policy_test(args.snapshot, args.policy)
+            print_test_summary(result, args.show_kept)
+            if args.output:
+                saved_path = save_json(args.output, result)
+                logger.info(f"\nDecisions saved to: {saved_path}")
+            logger.info("\nNothing was deleted: policy test only reads the snapshot.")
+
+    except Exception as e:
+        logger.error(f"\n❌ Policy {args.command} failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,225 @@
+#!/usr/bin/env python3
+"""
+Pull Time Report
+
+This script estimates how long a cold pull of each image takes - pulling it
+onto a node that has none of its layers cached, as happens when a workspace or
+model starts on a fresh node - and lists the slowest images to pull.
+
+Estimates use the compressed layer sizes stored in the registry and the link
+speed from analysis.pull_link_speed_mbps in config.yaml (or --link-speed).
+They cover transfer time only, so they are a lower bound on real pull times,
+but they rank images the way startup delays caused by image size do.
+
+Usage examples:
+  # Estimate pull times at the configured link speed
+  python pull_time_report.py
+
+  # Estimate for nodes with a 250 Mbit/s link and show the 50 slowest images
+  python pull_time_report.py --link-speed 250 --top 50
+
+  # Environment images only, from a quick triage scan
+  python pull_time_report.py --image-types environment --fast
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def format_duration(seconds: float) -> str:
+    """Format seconds for display, e.g. 45.2s, 3m 05s or 1h 02m"""
+    if seconds < 60:
+        return f"{seconds:.1f}s"
+    minutes, secs = divmod(int(round(seconds)), 60)
+    if minutes < 60:
+        return f"{minutes}m {secs:02d}s"
+    hours, minutes = divmod(minutes, 60)
+    return f"{hours}h {minutes:02d}m"
+
+
+def generate_pull_time_report(analyzer: ImageAnalyzer, link_speed_mbps: float, image_types: List[str]) -> Dict:
+    """Generate the pull time report.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        link_speed_mbps: Network speed in megabits per second
+        image_types: Image types the analyzer scanned
+
+    Returns:
+        Dict with summary and images sorted slowest first
+    """
+    estimates = analyzer.estimate_pull_times(link_speed_mbps)
+    total_bytes = sum(estimate["pull_bytes"] for estimate in estimates)
+    seconds = sorted(estimate["estimated_seconds"] for estimate in estimates)
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_images": len(estimates),
+            "image_types": image_types,
+            "link_speed_mbps": link_speed_mbps,
+            "total_pull_bytes": total_bytes,
+            "median_pull_seconds": seconds[len(seconds) // 2] if seconds else 0.0,
+            "max_pull_seconds": seconds[-1] if seconds else 0.0,
+            "generated_at": datetime.now().isoformat(),
+        },
+        "images": estimates,
+    }
+
+
+def print_report_summary(report_data: Dict, top: int) -> None:
+    """Print a human-readable summary of the report"""
+    summary = report_data["summary"]
+    images = report_data["images"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info("   Slowest Images to Pull")
+    logger.info("=" * 80)
+    logger.info(f"Images: {summary['total_images']}")
+    logger.info(f"Link speed: {summary['link_speed_mbps']:g} Mbit/s")
+    logger.info(f"Median cold pull: {format_duration(summary['median_pull_seconds'])}")
+    logger.info(f"Slowest cold pull: {format_duration(summary['max_pull_seconds'])}")
+
+    logger.info(f"\nTop {min(top, len(images))} slowest images:")
+    logger.info(f"{'Rank':>5}  {'Pull time':>9}  {'Pull size':>10}  {'Layers':>6}  {'Largest':>10}  Image")
+    for rank, image in enumerate(images[:top], 1):
+        logger.info(
+            f"{rank:>5}  {format_duration(image['estimated_seconds']):>9}  {sizeof_fmt(image['pull_bytes']):>10}  "
+            f"{image['layer_count']:>6}  {sizeof_fmt(image['largest_layer_bytes']):>10}  {image['image_id']}"
+        )
+
+    logger.info("\nNote: estimates cover transfer time only; request latency, decompression")
+    logger.info("      and layers already cached on the node are not taken into account.")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Estimate cold-pull time and bytes per image and list the slowest images to pull",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Estimate pull times at the configured link speed
+  python pull_time_report.py
+
+  # Estimate for nodes with a 250 Mbit/s link and show the 50 slowest images
+  python pull_time_report.py --link-speed 250 --top 50
+
+  # Environment images only, from a quick triage scan
+  python pull_time_report.py --image-types environment --fast
+
+  # Specify output file
+  python pull_time_report.py --output pull-times.json
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: pull-time-report.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--link-speed",
+        type=float,
+        metavar="MBPS",
+        help="Network speed in megabits per second (default: analysis.pull_link_speed_mbps from config)",
+    )
+
+    parser.add_argument("--top", type=int, default=20, metavar="N", help="Number of images to print (default: 20)")
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    parser.add_argument(
+        "--fast",
+        action="store_true",
+        help="Approximate triage scan: fetch only manifests and reuse cached inspections instead of inspecting every tag",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        if args.link_speed is not None and args.link_speed <= 0:
+            raise ValueError(f"--link-speed must be greater than 0, got: {args.link_speed:g}")
+        link_speed = args.link_speed if args.link_speed is not None else config_manager.get_pull_link_speed_mbps()
+
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info("=" * 80)
+        logger.info("   Pull Time Report")
+        logger.info("=" * 80)
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        logger.info(f"Image Types: {', '.join(args.image_types)}")
+        logger.info(f"Link speed: {link_speed:g} Mbit/s")
+        logger.info("=" * 80)
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"\nAnalyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers, fast=args.fast):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        report_data = generate_pull_time_report(analyzer, link_speed, args.image_types)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "pull-time-report.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data, args.top)
+
+        logger.info("\n✅ Pull time report generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
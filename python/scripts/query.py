@@ -0,0 +1,203 @@
+#!/usr/bin/env python3
+"""
+Snapshot Query
+
+This script answers questions about the registry from a saved scan snapshot,
+without touching the registry: after one scan, exploring images and layers is
+instant. By default the newest snapshot saved under reports.snapshot is
+queried.
+
+    images  images filtered by size, age, repository and tag, with their total
+            size and the bytes only they use
+    layers  layers filtered by how many images use them and by size
+
+Results are printed to stdout as a table, or as JSON with --json; logs go to
+stderr.
+
+Usage examples:
+  # Images over 10 GB created more than 180 days ago
+  python query.py images --min-size 10GB --older-than 180d
+
+  # Layers only one image uses, largest first
+  python query.py layers --frequency 1
+
+  # Model images tagged latest-*, as JSON, from a given snapshot
+  python query.py images --repository model --tag 'latest-*' --json --snapshot reports/scan-snapshot-<timestamp>.json
+"""
+
+import argparse
+import json
+import sys
+from pathlib import Path
+from typing import List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.config_manager import config_manager
+from utils.index_query import (
+    IMAGE_SORT_KEYS,
+    LAYER_SORT_KEYS,
+    QueriedImage,
+    QueriedLayer,
+    parse_age,
+    parse_size,
+    query_images,
+    query_layers,
+)
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.scan_snapshot import find_latest_snapshot, read_snapshot
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+
+def format_images(images: List[QueriedImage]) -> List[str]:
+    """Render queried images as table lines"""
+    tz = config_manager.get_display_timezone()
+    lines = [f"{'SIZE':>10}  {'UNIQUE':>10}  {'CREATED':<36}  IMAGE"]
+    for image in images:
+        created = format_timestamp(image["created"], tz, with_age=True) if image["created"] else "unknown"
+        lines.append(
+            f"{sizeof_fmt(image['size_bytes']):>10}  {sizeof_fmt(image['unique_bytes']):>10}  {created:<36}  "
+            f"{image['repository']}:{image['tag']}"
+        )
+    return lines
+
+
+def format_layers(layers: List[QueriedLayer]) -> List[str]:
+    """Render queried layers as table lines"""
+    lines = [f"{'SIZE':>10}  {'IMAGES':>6}  {'LAYER':<19}  USED BY"]
+    for layer in layers:
+        used_by = ", ".join(layer["images"][:3])
+        if layer["frequency"] > 3:
+            used_by += f" (+{layer['frequency'] - 3} more)"
+        lines.append(
+            f"{sizeof_fmt(layer['size_bytes']):>10}  {layer['frequency']:>6}  {layer['layer_id'][:19]:<19}  {used_by}"
+        )
+    return lines
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Query images and layers of a saved scan snapshot without touching the registry",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Images over 10 GB created more than 180 days ago
+  python query.py images --min-size 10GB --older-than 180d
+
+  # Layers only one image uses, largest first
+  python query.py layers --frequency 1
+
+  # Model images tagged latest-*, as JSON, from a given snapshot
+  python query.py images --repository model --tag 'latest-*' --json --snapshot reports/scan-snapshot-<timestamp>.json
+        """,
+    )
+
+    common = argparse.ArgumentParser(add_help=False)
+    common.add_argument("--snapshot", help="Snapshot to query (default: the newest saved under reports.snapshot)")
+    common.add_argument(
+        "--repository", help="Shell-style pattern of the repository, by full name or last segment (e.g. environment)"
+    )
+    common.add_argument("--min-size", type=parse_size, help="Only results of at least this size, e.g. 10GB or 500MiB")
+    common.add_argument(
+        "--limit", type=int, default=50, help="Print at most this many results, 0 for all (default: 50)"
+    )
+    common.add_argument("--json", action="store_true", help="Print the results as JSON instead of a table")
+    common.add_argument("--output", help="Also save every result to this JSON file")
+
+    subparsers = parser.add_subparsers(dest="command", required=True)
+
+    images = subparsers.add_parser("images", parents=[common], help="Find images by size, age, repository and tag")
+    images.add_argument("--max-size", type=parse_size, help="Only images of at most this size")
+    images.add_argument("--older-than", type=parse_age, help="Only images created longer ago, e.g. 180d, 26w or 1y")
+    images.add_argument("--newer-than", type=parse_age, help="Only images created more recently, e.g. 7d")
+    images.add_argument("--tag", help="Shell-style pattern of the tag")
+    images.add_argument(
+        "--sort",
+        choices=IMAGE_SORT_KEYS,
+        default="size",
+        help="size, unique (bytes no other image shares), age (oldest first) or name (default: size)",
+    )
+
+    layers = subparsers.add_parser("layers", parents=[common], help="Find layers by how many images use them")
+    layers.add_argument("--frequency", type=int, help="Only layers used by exactly this many images")
+    layers.add_argument("--max-frequency", type=int, help="Only layers used by at most this many images")
+    layers.add_argument(
+        "--sort", choices=LAYER_SORT_KEYS, default="size", help="size or frequency, largest first (default: size)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        snapshot_path = args.snapshot or find_latest_snapshot()
+        if snapshot_path is None:
+            raise ValueError(
+                "No saved scan snapshot found; save one with image_data_analysis --mode snapshot or pass --snapshot"
+            )
+        snapshot = read_snapshot(snapshot_path)
+        taken = format_timestamp(snapshot.get("created_at"), config_manager.get_display_timezone(), with_age=True)
+        logger.info(f"Querying {snapshot_path} (taken {taken}, {len(snapshot['images'])} images)")
+
+        results: list
+        if args.command == "images":
+            results = query_images(
+                snapshot,
+                min_size=args.min_size,
+                max_size=args.max_size,
+                older_than_days=args.older_than,
+                newer_than_days=args.newer_than,
+                repository=args.repository,
+                tag=args.tag,
+                sort=args.sort,
+            )
+            total_bytes = sum(image["size_bytes"] for image in results)
+            unique_bytes = sum(image["unique_bytes"] for image in results)
+            summary = f"{len(results)} image(s), {sizeof_fmt(total_bytes)} ({sizeof_fmt(unique_bytes)} unique)"
+        else:
+            results = query_layers(
+                snapshot,
+                frequency=args.frequency,
+                max_frequency=args.max_frequency,
+                min_size=args.min_size,
+                repository=args.repository,
+                sort=args.sort,
+            )
+            summary = f"{len(results)} layer(s), {sizeof_fmt(sum(layer['size_bytes'] for layer in results))}"
+
+        shown = results[: args.limit] if args.limit else results
+        if args.json:
+            print(json.dumps(shown, indent=2))
+        else:
+            lines = format_images(shown) if args.command == "images" else format_layers(shown)
+            print("\n".join(lines))
+        if len(shown) < len(results):
+            logger.info(f"Showing {len(shown)} of {len(results)} results (--limit 0 shows all)")
+        logger.info(summary)
+
+        if args.output:
+            report = {"snapshot": snapshot_path, "command": args.command, "results": results}
+            saved_path = save_json(args.output, report)
+            logger.info(f"Results saved to: {saved_path}")
+
+    except Exception as e:
+        logger.error(f"\n❌ Query failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
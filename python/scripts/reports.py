@@ -51,11 +51,12 @@ def load_metadata_files() -> tuple:
     Supports both timestamped and non-timestamped report files.
     If exact file doesn't exist, finds the most recent timestamped version.
     """
-    from utils.report_utils import get_latest_report, get_reports_dir
+    from utils.report_utils import find_report_file, get_latest_report, get_reports_dir, load_json
 
     # Load tag sums
     tag_sums_path = config_manager.get_tag_sums_path()
     tag_sums_file = Path(tag_sums_path)
+    tag_sums_file = find_report_file(tag_sums_file) or tag_sums_file
 
     # If exact file doesn't exist, try to find latest timestamped version
     if not tag_sums_file.exists():
@@ -72,8 +73,7 @@ def load_metadata_files() -> tuple:
         logger.error(f"Tag sums file not found: {tag_sums_path}")
         raise FileNotFoundError(f"Tag sums file not found: {tag_sums_path}")
 
-    with open(tag_sums_file) as f:
-        tag_data = json.load(f)
+    tag_data = load_json(tag_sums_file)
     logger.info(f"Loaded {len(tag_data)} tags from tag sums")
 
     # Load MongoDB usage reports from consolidated file
@@ -0,0 +1,277 @@
+#!/usr/bin/env python3
+"""
+Docker Repository Summary Report Generator
+
+This script generates a per-repository composition and deduplication summary,
+showing for each repository (e.g. environment, model) the number of tags, the
+layers and bytes exclusive to that repository, the bytes shared with other
+repositories, and the largest image. It lets admins compare the health of the
+environment and model repositories at a glance.
+
+It also breaks registry usage down by layer media type (gzip, zstd,
+uncompressed, foreign, provenance blobs), to inform compression and
+artifact-policy decisions, and shows each repository's oldest and newest image
+with its age. Creation times are printed in the display time zone
+(reports.timezone in config.yaml, or --timezone).
+
+Layers shared between environments or models are attributed to the one that
+introduced them - the owner of the earliest created image referencing the
+layer - rather than to every consumer, both per repository (introduced bytes)
+and per environment/model namespace (shared layer origins).
+
+Usage examples:
+  # Generate summary for environment and model repositories
+  python repository_summary_report.py
+
+  # Only summarize the environment repository
+  python repository_summary_report.py --image-types environment
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+# Introducing namespaces printed to the console; the report lists them all
+SHARED_ORIGINS_SHOWN = 10
+
+
+def generate_repository_summary_report(analyzer: ImageAnalyzer) -> Dict:
+    """Generate a per-repository summary report from analyzed images.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+
+    Returns:
+        Dict with 'summary' totals, a 'repositories' list sorted by total bytes,
+        'media_types' and 'media_type_categories' usage, and 'shared_layer_origins'
+        (namespaces by the bytes of shared layers they introduced)
+    """
+    repositories: List[Dict] = []
+    for repository, summary in analyzer.generate_repository_summary().items():
+        image_type = repository.rsplit("/", 1)[-1]
+        largest = summary["largest_image"]
+        repositories.append(
+            {
+                **summary,
+                "image_type": image_type,
+                "total_gb": round(summary["total_bytes"] / (1024**3), 2),
+                "unique_gb": round(summary["unique_bytes"] / (1024**3), 2),
+                "shared_gb": round(summary["shared_bytes"] / (1024**3), 2),
+                "introduced_gb": round(summary["introduced_bytes"] / (1024**3), 2),
+                "largest_image": (
+                    {**largest, "size_gb": round(largest["size_bytes"] / (1024**3), 2)} if largest else None
+                ),
+            }
+        )
+
+    repositories.sort(key=lambda r: r["total_bytes"], reverse=True)
+
+    media_types = analyzer.media_type_breakdown()
+    categories: Dict[str, Dict[str, int]] = {}
+    for entry in media_types:
+        category = categories.setdefault(entry["category"], {"layers": 0, "bytes": 0})
+        category["layers"] += entry["layers"]
+        category["bytes"] += entry["bytes"]
+
+    stats = analyzer.generate_summary_stats()
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_repositories": len(repositories),
+            "total_images": stats["total_images"],
+            "total_layers": stats["total_layers"],
+            "total_size_gb": stats["total_size_gb"],
+            "generated_at": datetime.now().isoformat(),
+        },
+        "repositories": repositories,
+        "media_types": media_types,
+        "media_type_categories": dict(sorted(categories.items(), key=lambda item: item[1]["bytes"], reverse=True)),
+        "shared_layer_origins": analyzer.shared_layer_introducers(),
+    }
+
+
+def print_report_summary(report_data: Dict) -> None:
+    """Print a human-readable summary of the report"""
+    logger.info("\n" + "=" * 80)
+    logger.info("   Repository Summary")
+    logger.info("=" * 80)
+    logger.info(
+        f"{'Repository':<15} {'Tags':<8} {'Layers':<8} {'Total':<12} {'Unique':<12} {'Shared':<12} {'Largest Image':<30}"
+    )
+    logger.info("-" * 100)
+
+    for repo in report_data["repositories"]:
+        largest = repo["largest_image"]
+        largest_display = "-"
+        if largest:
+            tag = largest["tag"][:17] + "..." if len(largest["tag"]) > 20 else largest["tag"]
+            largest_display = f"{tag} ({sizeof_fmt(largest['size_bytes'])})"
+        logger.info(
+            f"{repo['image_type']:<15} {repo['tag_count']:<8} {repo['layer_count']:<8} "
+            f"{sizeof_fmt(repo['total_bytes']):<12} {sizeof_fmt(repo['unique_bytes']):<12} "
+            f"{sizeof_fmt(repo['shared_bytes']):<12} {largest_display:<30}"
+        )
+
+    tz = config_manager.get_display_timezone()
+    logger.info("\nImage age:")
+    for repo in report_data["repositories"]:
+        for label in ("oldest", "newest"):
+            image = repo[f"{label}_image"]
+            if image:
+                created = format_timestamp(image["created"], tz, with_age=True)
+                logger.info(f"{repo['image_type']:<15} {label:<7} {image['tag']}  created {created}")
+
+    total_bytes = sum(category["bytes"] for category in report_data["media_type_categories"].values())
+    logger.info("\nLayer media types:")
+    logger.info(f"{'Category':<15} {'Layers':<8} {'Bytes':<12} {'Share':<8}")
+    logger.info("-" * 45)
+    for name, category in report_data["media_type_categories"].items():
+        share = category["bytes"] / total_bytes * 100 if total_bytes else 0.0
+        logger.info(f"{name:<15} {category['layers']:<8} {sizeof_fmt(category['bytes']):<12} {share:>5.1f}%")
+
+    if report_data["shared_layer_origins"]:
+        logger.info("\nShared layers by the environment or model that introduced them:")
+        logger.info(f"{'Namespace':<50} {'Layers':<8} {'Bytes':<12} {'Consumers':<10}")
+        logger.info("-" * 82)
+        for introducer in report_data["shared_layer_origins"][:SHARED_ORIGINS_SHOWN]:
+            logger.info(
+                f"{introducer['namespace']:<50} {introducer['layers']:<8} {sizeof_fmt(introducer['bytes']):<12} "
+                f"{introducer['consumer_namespaces']:<10}"
+            )
+
+    logger.info("\n" + "=" * 80)
+    logger.info("Note: 'Unique' bytes are only referenced by images in that repository; 'Shared'")
+    logger.info("      bytes are also referenced by at least one other repository.")
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Generate per-repository composition and deduplication summary",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Generate summary for environment and model repositories
+  python repository_summary_report.py
+
+  # Only summarize the environment repository
+  python repository_summary_report.py --image-types environment
+
+  # Specify output file
+  python repository_summary_report.py --output custom-summary.json
+
+  # Quick approximate triage scan
+  python repository_summary_report.py --fast
+
+  # Layer sizes only, for storage accounting
+  python repository_summary_report.py --sizes-only
+        """,
+    )
+
+    parser.add_argument(
+        "--output", help="Output file path for the report (default: repository-summary.json in reports directory)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    parser.add_argument(
+        "--fast",
+        action="store_true",
+        help="Approximate triage scan: fetch only manifests and reuse cached inspections instead of inspecting every tag",
+    )
+
+    parser.add_argument(
+        "--sizes-only",
+        action="store_true",
+        help="Storage accounting scan: one manifest request per tag for its layer sizes, without labels, env or "
+        "creation times (takes precedence over --fast)",
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        logger.info("=" * 80)
+        logger.info("   Docker Repository Summary Report Generator")
+        logger.info("=" * 80)
+
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        logger.info(f"Registry: {registry_url}")
+        logger.info(f"Repository: {repository}")
+        logger.info(f"Image Types: {', '.join(args.image_types)}")
+        logger.info("=" * 80)
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"\nAnalyzing {image_type} images...")
+            if analyzer.analyze_image(
+                image_type, object_ids=None, max_workers=args.max_workers, fast=args.fast, sizes_only=args.sizes_only
+            ):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        report_data = generate_repository_summary_report(analyzer)
+
+        if args.output:
+            output_path = args.output
+        else:
+            reports_dir = Path(config_manager.get_output_dir())
+            output_path = str(reports_dir / "repository-summary.json")
+
+        saved_path = save_json(output_path, report_data, timestamp=True)
+        logger.info(f"\nReport saved to: {saved_path}")
+
+        print_report_summary(report_data)
+
+        logger.info("\n✅ Repository summary generation completed successfully!")
+
+    except Exception as e:
+        logger.error(f"\n❌ Report generation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
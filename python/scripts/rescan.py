@@ -0,0 +1,170 @@
+#!/usr/bin/env python3
+"""
+Selective Rescan
+
+This script brings the reports up to date after a targeted push or deletion
+without a full scan: it starts from the newest saved scan snapshot, refreshes
+only the given repositories - or only the given tags of them - against the
+registry, then regenerates the reports and saves a new snapshot.
+
+With --tags, each tag is inspected again: a re-pushed tag gets its new digest
+and layers, and a tag the registry no longer has is dropped. Without --tags,
+the repositories are scanned again entirely. Digests already in the inspection
+cache are not inspected again.
+
+If a tag cannot be inspected or a repository cannot be listed, nothing is
+saved, so the previous snapshot stays the newest, and the script exits with a
+nonzero status.
+
+Usage examples:
+  # Refresh two re-pushed environment tags
+  python rescan.py --repo environment --tags tagA,tagB
+
+  # Rescan the model repository after deleting model images
+  python rescan.py --repo model
+
+  # Refresh a tag in two repositories, writing only the images report
+  python rescan.py --repo environment,model --tags latest --mode images
+"""
+
+import argparse
+import sys
+from pathlib import Path
+from typing import List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.config_manager import config_manager
+from utils.error_utils import ERROR_NOT_FOUND, error_exit_status
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import sizeof_fmt
+from utils.rescan import rescan, restore_cached_results
+from utils.scan_snapshot import find_latest_snapshot, load_snapshot
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+
+def split_list(values: List[str]) -> List[str]:
+    """Flatten repeated, comma-separated option values, dropping empty and duplicate entries"""
+    items: List[str] = []
+    for value in values:
+        for item in value.split(","):
+            item = item.strip()
+            if item and item not in items:
+                items.append(item)
+    return items
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Refresh selected repositories or tags of the last scan snapshot and regenerate reports",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Refresh two re-pushed environment tags
+  python rescan.py --repo environment --tags tagA,tagB
+
+  # Rescan the model repository after deleting model images
+  python rescan.py --repo model
+
+  # Refresh a tag in two repositories, writing only the images report
+  python rescan.py --repo environment,model --tags latest --mode images
+        """,
+    )
+
+    parser.add_argument(
+        "--repo",
+        action="append",
+        required=True,
+        metavar="IMAGE_TYPE",
+        help="Repository to refresh, e.g. environment; comma-separated or repeated for several",
+    )
+
+    parser.add_argument(
+        "--tags", help="Comma-separated tags to refresh in each repository (default: rescan the repositories entirely)"
+    )
+
+    parser.add_argument("--snapshot", help="Snapshot to start from (default: the newest saved under reports.snapshot)")
+
+    parser.add_argument(
+        "--mode",
+        choices=["all", "layers", "images", "repos", "snapshot", "both"],
+        default="all",
+        help="Reports to write, as for image_data_analysis (default: all); a new snapshot is saved in every mode",
+    )
+
+    parser.add_argument(
+        "--max-workers",
+        type=int,
+        help="Maximum number of parallel workers for a repository rescan (default: from config)",
+    )
+
+    args = parser.parse_args()
+    args.repo = split_list(args.repo)
+    args.tags = split_list([args.tags]) if args.tags else None
+    if not args.repo:
+        parser.error("--repo needs at least one repository")
+    return args
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        snapshot_path = args.snapshot or find_latest_snapshot()
+        if snapshot_path is None:
+            raise ValueError(
+                "No saved scan snapshot found; save one with image_data_analysis --mode snapshot or pass --snapshot"
+            )
+        analyzer, _, metadata = load_snapshot(snapshot_path)
+        taken = format_timestamp(metadata.get("created_at"), config_manager.get_display_timezone(), with_age=True)
+        logger.info(f"Starting from {snapshot_path} (taken {taken}, {len(analyzer.images)} images)")
+        restored = restore_cached_results(analyzer)
+        if restored:
+            logger.info(f"Restored cached inspection results of {restored} images")
+
+        target = ", ".join(args.repo)
+        logger.info(f"Refreshing {', '.join(args.tags)} in {target}" if args.tags else f"Rescanning {target}")
+        result = rescan(analyzer, args.repo, tags=args.tags, max_workers=args.max_workers)
+
+        for image_id in result["added"]:
+            logger.info(f"+ {image_id}")
+        for image_id in result["removed"]:
+            logger.info(f"- {image_id}")
+        for image_id in result["changed"]:
+            logger.info(f"~ {image_id}")
+        logger.info(
+            f"{len(result['added'])} added, {len(result['removed'])} removed, {len(result['changed'])} changed, "
+            f"storage {'+' if result['size_delta_bytes'] >= 0 else '-'}{sizeof_fmt(abs(result['size_delta_bytes']))}"
+        )
+
+        if result["failed"]:
+            codes = [f["code"] for f in analyzer.failures.values() if f["code"] != ERROR_NOT_FOUND]
+            logger.error(
+                f"\n❌ Rescan failed for {', '.join(result['failed'])}; no reports saved, "
+                f"the newest snapshot is still {snapshot_path}"
+            )
+            sys.exit(error_exit_status(codes) or 1)
+
+        analyzer.save_reports(args.mode)
+        if args.mode != "snapshot":
+            analyzer.save_snapshot()
+        logger.info("✅ Rescan complete")
+
+    except Exception as e:
+        logger.error(f"\n❌ Rescan failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,265 @@
+#!/usr/bin/env python3
+"""
+What-if Deletion Simulator
+
+This script simulates deleting a single image and reports exactly which layers
+would become unreferenced, the bytes that would be freed, and the remaining
+references for every layer that survives because it is shared with other images.
+Nothing is deleted and no cleanup plan is built.
+
+With --input, a file of candidate images is simulated as one batch. Layers shared
+between candidates are only freed when all of them are deleted, so the combined
+result is reported next to the naive per-image totals, which either double-count
+shared layers (sum of image sizes) or miss them (sum of individual savings).
+
+Usage examples:
+  # Simulate deleting an environment image
+  python simulate_deletion.py environment:507f1f77bcf86cd799439011-3
+
+  # Tag without a type prefix is looked up in all image types
+  python simulate_deletion.py 507f1f77bcf86cd799439011-3
+
+  # Simulate deleting every candidate listed in a file (one image per line)
+  python simulate_deletion.py --input candidates.txt
+"""
+
+import argparse
+import sys
+from datetime import datetime
+from pathlib import Path
+from typing import Dict, List
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.image_data_analysis import DeletionSimulation, ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.object_id_utils import read_image_references_from_file
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+
+logger = get_logger(__name__)
+
+
+def simulate_candidates(analyzer: ImageAnalyzer, image_ids: List[str]) -> Dict:
+    """Simulate deleting a batch of candidate images together.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        image_ids: Resolved image_ids of the candidates
+
+    Returns:
+        Dict with the combined simulation, per-candidate figures, and naive totals
+    """
+    combined = analyzer.simulate_deletion(image_ids)
+
+    candidates = []
+    for image_id in image_ids:
+        candidates.append(
+            {
+                "image_id": image_id,
+                "total_size_bytes": analyzer.get_image_total_size(image_id),
+                "freed_alone_bytes": analyzer.freed_space_if_deleted([image_id]),
+            }
+        )
+    candidates.sort(key=lambda c: c["freed_alone_bytes"], reverse=True)
+
+    sum_of_sizes = sum(c["total_size_bytes"] for c in candidates)
+    sum_freed_alone = sum(c["freed_alone_bytes"] for c in candidates)
+
+    return {
+        "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "candidates": len(image_ids),
+            "combined_freed_bytes": combined["freed_bytes"],
+            "sum_of_image_sizes_bytes": sum_of_sizes,
+            "sum_of_individual_freed_bytes": sum_freed_alone,
+            "freed_only_together_bytes": combined["freed_bytes"] - sum_freed_alone,
+            "generated_at": datetime.now().isoformat(),
+        },
+        "combined": combined,
+        "candidates": candidates,
+    }
+
+
+def print_batch_summary(result: Dict) -> None:
+    """Print a human-readable summary of a batch simulation"""
+    summary = result["summary"]
+
+    logger.info("\n" + "=" * 80)
+    logger.info(f"   What-if: deleting {summary['candidates']} candidate images together")
+    logger.info("=" * 80)
+    logger.info(f"Combined bytes freed: {sizeof_fmt(summary['combined_freed_bytes'])}")
+    logger.info(f"Sum of image sizes (double-counts shared layers): {sizeof_fmt(summary['sum_of_image_sizes_bytes'])}")
+    logger.info(
+        "Sum of individual savings (misses layers shared between candidates): "
+        f"{sizeof_fmt(summary['sum_of_individual_freed_bytes'])}"
+    )
+    logger.info(f"Freed only when deleted together: {sizeof_fmt(summary['freed_only_together_bytes'])}")
+    logger.info(f"Shared layers kept by other images: {len(result['combined']['retained_layers'])}")
+    if result["combined"]["foreign_layers"]:
+        logger.info(
+            f"Foreign layers (not stored in the registry, not freed): {sizeof_fmt(result['combined']['foreign_bytes'])}"
+        )
+
+    logger.info("\nTop candidates by individual savings:")
+    for candidate in result["candidates"][:20]:
+        logger.info(
+            f"   {candidate['image_id']:<50} size {sizeof_fmt(candidate['total_size_bytes']):<12} "
+            f"freed alone {sizeof_fmt(candidate['freed_alone_bytes'])}"
+        )
+    if len(result["candidates"]) > 20:
+        logger.info(f"   ... and {len(result['candidates']) - 20} more candidates")
+    logger.info("=" * 80)
+
+
+def print_simulation(simulation: DeletionSimulation) -> None:
+    """Print a human-readable summary of a deletion simulation"""
+    logger.info("\n" + "=" * 80)
+    logger.info(f"   What-if: deleting {', '.join(simulation['image_ids'])}")
+    logger.info("=" * 80)
+    logger.info(f"Bytes freed: {sizeof_fmt(simulation['freed_bytes'])} ({len(simulation['freed_layers'])} layers)")
+    logger.info(
+        f"Bytes retained (shared): {sizeof_fmt(simulation['retained_bytes'])} "
+        f"({len(simulation['retained_layers'])} layers)"
+    )
+    if simulation["foreign_layers"]:
+        logger.info(
+            f"Foreign layers (not stored in the registry, not freed): {sizeof_fmt(simulation['foreign_bytes'])} "
+            f"({len(simulation['foreign_layers'])} layers)"
+        )
+
+    if simulation["freed_layers"]:
+        logger.info("\n🗑️  Layers that would become unreferenced:")
+        for layer in simulation["freed_layers"]:
+            logger.info(f"   {layer['layer_id']}  {sizeof_fmt(layer['size_bytes'])}")
+
+    if simulation["retained_layers"]:
+        logger.info("\n🔗 Shared layers that would remain:")
+        for layer in simulation["retained_layers"]:
+            holders = layer["remaining_images"]
+            holders_display = ", ".join(holders[:3]) + (f" (+{len(holders) - 3} more)" if len(holders) > 3 else "")
+            logger.info(
+                f"   {layer['layer_id']}  {sizeof_fmt(layer['size_bytes'])}  "
+                f"{layer['remaining_refs']} remaining ref(s): {holders_display}"
+            )
+
+    logger.info("=" * 80)
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Simulate deleting an image and report freed and shared layers",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Simulate deleting an environment image
+  python simulate_deletion.py environment:507f1f77bcf86cd799439011-3
+
+  # Tag without a type prefix is looked up in all image types
+  python simulate_deletion.py 507f1f77bcf86cd799439011-3
+
+  # Simulate deleting every candidate listed in a file (one image per line)
+  python simulate_deletion.py --input candidates.txt
+
+  # Save the simulation result as JSON
+  python simulate_deletion.py environment:507f1f77bcf86cd799439011-3 --output what-if.json
+        """,
+    )
+
+    target = parser.add_mutually_exclusive_group(required=True)
+    target.add_argument("image", nargs="?", help="Image to simulate deleting, as <type>:<tag> or a bare tag")
+    target.add_argument(
+        "--input", help="File of candidate images (one <type>:<tag> or bare tag per line) to simulate as a batch"
+    )
+
+    parser.add_argument("--output", help="Optional output file path for the simulation result (JSON)")
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to analyze; layers shared with these are counted as remaining references "
+        "(default: the profile's image types)",
+    )
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    return parser.parse_args()
+
+
+def main():
+    """Main function"""
+    setup_logging()
+    args = parse_arguments()
+
+    try:
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+
+        analyzer = ImageAnalyzer(registry_url, repository)
+        success_count = 0
+        for image_type in args.image_types:
+            logger.info(f"Analyzing {image_type} images...")
+            if analyzer.analyze_image(image_type, object_ids=None, max_workers=args.max_workers):
+                success_count += 1
+
+        if success_count == 0:
+            logger.error("No image data found. Check your registry access.")
+            sys.exit(1)
+
+        if args.input:
+            references = read_image_references_from_file(args.input)
+            image_ids: List[str] = []
+            for reference in references:
+                image_id = analyzer.resolve_image_id(reference, args.image_types)
+                if image_id in image_ids:
+                    logger.info(f"Candidate '{reference}' is listed more than once as {image_id}, counting it once")
+                elif image_id:
+                    image_ids.append(image_id)
+                else:
+                    logger.warning(f"⚠️  Candidate '{reference}' not found, skipping")
+
+            if not image_ids:
+                logger.error(f"❌ None of the {len(references)} candidates in '{args.input}' were found")
+                sys.exit(1)
+
+            result = simulate_candidates(analyzer, image_ids)
+            print_batch_summary(result)
+        else:
+            image_id = analyzer.resolve_image_id(args.image, args.image_types)
+            if not image_id:
+                logger.error(f"❌ Image '{args.image}' not found in {', '.join(args.image_types)} images")
+                sys.exit(1)
+
+            result = analyzer.simulate_deletion([image_id])
+            print_simulation(result)
+            result = {
+                **result,
+                "build": get_build_info(),
+                "runStats": get_run_stats(),
+                "generated_at": datetime.now().isoformat(),
+            }
+
+        if args.output:
+            saved_path = save_json(args.output, result)
+            logger.info(f"\nSimulation saved to: {saved_path}")
+
+    except Exception as e:
+        logger.error(f"\n❌ Simulation failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -33,6 +33,7 @@ _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.build_info import get_build_info
 from utils.config_manager import config_manager
 from utils.image_data_analysis import ImageAnalyzer
 from utils.image_metadata import extract_model_tag_from_version_doc, lookup_user_names_and_logins
@@ -40,6 +41,7 @@ from utils.image_usage import ImageUsageService
 from utils.logging_utils import get_logger, setup_logging
 from utils.object_id_utils import normalize_object_id
 from utils.report_utils import ensure_all_reports, save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
 
 logger = get_logger(__name__)
 
@@ -476,7 +478,7 @@ def generate_user_size_report(
         Dict with report data including users sorted by total size
     """
     if image_types is None:
-        image_types = ["environment", "model"]
+        image_types = config_manager.get_image_types()
 
     # Build mapping from tags to owners
     logger.info("Mapping images to their owners...")
@@ -575,6 +577,8 @@ def generate_user_size_report(
 
     report_data = {
         "summary": {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
             "total_users": len(users_list),
             "total_images": sum(u["image_count"] for u in users_list),
             "total_size_bytes": total_size,
@@ -673,8 +677,8 @@ Examples:
     parser.add_argument(
         "--image-types",
         nargs="+",
-        default=["environment", "model"],
-        help="Image types to include in report (default: environment model)",
+        default=config_manager.get_image_types(),
+        help="Image types to include in report (default: the profile's image types)",
     )
 
     parser.add_argument(
@@ -0,0 +1,222 @@
+#!/usr/bin/env python3
+"""
+Registry Watch
+
+This script rescans the registry at a fixed interval and prints only what
+changed since the previous scan: tags pushed (+), tags removed (-), tags that
+now point to a different digest (~), and the change in bytes stored in the
+registry. Iterations without changes print nothing, so the output can be
+tailed during heavy build periods.
+
+Rescans are incremental: with the inspection cache enabled (cache.enabled and
+cache.incremental_scan in config.yaml, the default), tags whose digest was seen
+before are not inspected again, so each iteration costs little more than
+listing tags.
+
+Logs go to stderr and changes to stdout, one line each:
+
+    [2026-01-01 11:00 UTC] + dominodatalab/environment:507f1f77bcf86cd799439011-4 (5.2 GB)
+    [2026-01-01 11:00 UTC] ~ dominodatalab/model:latest sha256:4f2a91c0b1d3 -> sha256:9be07d2c55a1
+    [2026-01-01 11:00 UTC] 1 added, 0 removed, 1 changed, storage +312.4 MB (1.2 TB)
+
+or, with --json, one JSON object per iteration with changes.
+
+Usage examples:
+  # Print changes every hour until interrupted
+  python watch.py --interval 1h
+
+  # Environment images every 15 minutes, as JSON lines
+  python watch.py --interval 15m --image-types environment --json
+
+  # Two scans 10 minutes apart, then exit
+  python watch.py --interval 10m --iterations 2
+"""
+
+import argparse
+import json
+import logging
+import sys
+import time
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import List, Optional
+
+# Add parent directory to path for imports
+_parent_dir = Path(__file__).parent.parent.absolute()
+if str(_parent_dir) not in sys.path:
+    sys.path.insert(0, str(_parent_dir))
+
+from utils.config_manager import config_manager
+from utils.image_data_analysis import ImageAnalyzer
+from utils.logging_utils import get_logger, setup_logging
+from utils.report_utils import sizeof_fmt
+from utils.scan_diff import ScanDiff, ScanState, diff_scans, has_changes, parse_interval, scan_state
+from utils.time_format import format_timestamp
+
+logger = get_logger(__name__)
+
+
+def scan_registry(
+    registry_url: str, repository: str, image_types: List[str], max_workers: Optional[int] = None
+) -> Optional[ScanState]:
+    """Scan the registry once, returning None if no image type could be analyzed"""
+    analyzer = ImageAnalyzer(registry_url, repository)
+    success_count = 0
+    for image_type in image_types:
+        if analyzer.analyze_image(image_type, object_ids=None, max_workers=max_workers):
+            success_count += 1
+    if success_count == 0:
+        return None
+    return scan_state(analyzer)
+
+
+def format_diff(diff: ScanDiff, previous: ScanState, current: ScanState) -> List[str]:
+    """Render the changes between two scans as console lines, without timestamps"""
+    lines = []
+    for image_id in diff["added"]:
+        image = current["images"][image_id]
+        lines.append(f"+ {image['repository']}:{image['tag']} ({sizeof_fmt(image['size_bytes'])})")
+    for image_id in diff["removed"]:
+        image = previous["images"][image_id]
+        lines.append(f"- {image['repository']}:{image['tag']}")
+    for image_id in diff["changed"]:
+        image = current["images"][image_id]
+        old_digest = previous["images"][image_id]["digest"][:19]
+        lines.append(f"~ {image['repository']}:{image['tag']} {old_digest} -> {image['digest'][:19]}")
+    sign = "+" if diff["size_delta_bytes"] >= 0 else "-"
+    lines.append(
+        f"{len(diff['added'])} added, {len(diff['removed'])} removed, {len(diff['changed'])} changed, "
+        f"storage {sign}{sizeof_fmt(abs(diff['size_delta_bytes']))} ({sizeof_fmt(current['total_bytes'])})"
+    )
+    return lines
+
+
+def diff_record(diff: ScanDiff, previous: ScanState, current: ScanState, scanned_at: datetime) -> dict:
+    """The changes between two scans as one JSON object"""
+    return {
+        "scanned_at": scanned_at.isoformat(),
+        "added": [{"image_id": image_id, **current["images"][image_id]} for image_id in diff["added"]],
+        "removed": [{"image_id": image_id, **previous["images"][image_id]} for image_id in diff["removed"]],
+        "changed": [
+            {
+                "image_id": image_id,
+                **current["images"][image_id],
+                "previous_digest": previous["images"][image_id]["digest"],
+            }
+            for image_id in diff["changed"]
+        ],
+        "size_delta_bytes": diff["size_delta_bytes"],
+        "total_bytes": current["total_bytes"],
+    }
+
+
+def parse_arguments():
+    """Parse command line arguments"""
+    parser = argparse.ArgumentParser(
+        description="Rescan the registry at an interval and print only what changed since the last scan",
+        formatter_class=argparse.RawDescriptionHelpFormatter,
+        epilog="""
+Examples:
+  # Print changes every hour until interrupted
+  python watch.py --interval 1h
+
+  # Environment images every 15 minutes, as JSON lines
+  python watch.py --interval 15m --image-types environment --json
+
+  # Two scans 10 minutes apart, then exit
+  python watch.py --interval 10m --iterations 2
+        """,
+    )
+
+    parser.add_argument(
+        "--interval", default="1h", help="Time between scans, e.g. 90s, 15m, 1h or 1d (default: 1h)"
+    )
+
+    parser.add_argument(
+        "--iterations", type=int, default=0, metavar="N", help="Stop after N scans (default: 0, run until interrupted)"
+    )
+
+    parser.add_argument(
+        "--image-types",
+        nargs="+",
+        default=config_manager.get_image_types(),
+        help="Image types to watch (default: the profile's image types)",
+    )
+
+    parser.add_argument("--json", action="store_true", help="Print one JSON object per iteration with changes")
+
+    parser.add_argument("--verbose", action="store_true", help="Also log scan progress to stderr")
+
+    parser.add_argument(
+        "--max-workers", type=int, help="Maximum number of parallel workers for image analysis (default: from config)"
+    )
+
+    args = parser.parse_args()
+    try:
+        args.interval_seconds = parse_interval(args.interval)
+    except ValueError as e:
+        parser.error(str(e))
+    if args.iterations < 0:
+        parser.error(f"--iterations must be 0 or more, got: {args.iterations}")
+    return args
+
+
+def main():
+    """Main function"""
+    args = parse_arguments()
+    setup_logging(logging.INFO if args.verbose else logging.WARNING)
+
+    try:
+        registry_url = config_manager.get_registry_url()
+        repository = config_manager.get_repository()
+        tz = config_manager.get_display_timezone()
+        logger.warning(
+            f"Watching {registry_url}/{repository} ({', '.join(args.image_types)}) every {args.interval}; "
+            "press Ctrl+C to stop"
+        )
+
+        previous: Optional[ScanState] = None
+        iteration = 0
+        while True:
+            started = time.monotonic()
+            scanned_at = datetime.now(timezone.utc)
+            current = scan_registry(registry_url, repository, args.image_types, args.max_workers)
+            iteration += 1
+            stamp = f"[{format_timestamp(scanned_at, tz)}]"
+
+            if current is None:
+                # Keep the last good scan, so a registry outage does not show every tag as removed and re-added
+                logger.error(f"{stamp} Scan failed: no image data found; the next scan is compared with the last one")
+            elif previous is None:
+                logger.warning(
+                    f"{stamp} Baseline: {len(current['images'])} tags, {sizeof_fmt(current['total_bytes'])} stored"
+                )
+                previous = current
+            else:
+                diff = diff_scans(previous, current)
+                if has_changes(diff):
+                    if args.json:
+                        print(json.dumps(diff_record(diff, previous, current, scanned_at)), flush=True)
+                    else:
+                        for line in format_diff(diff, previous, current):
+                            print(f"{stamp} {line}", flush=True)
+                else:
+                    logger.info(f"{stamp} No changes")
+                previous = current
+
+            if args.iterations and iteration >= args.iterations:
+                break
+            time.sleep(max(args.interval_seconds - (time.monotonic() - started), 0))
+
+    except KeyboardInterrupt:
+        logger.warning("Watch stopped")
+    except Exception as e:
+        logger.error(f"\n❌ Watch failed: {e}")
+        from utils.logging_utils import log_exception
+
+        log_exception(logger, "Error in main", exc_info=e)
+        sys.exit(1)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,245 @@
+"""
+On-call alerts for scheduled runs.
+
+The API server checks every scheduled run (see schedule in config.yaml) when it
+finishes and raises an alert when:
+
+- the run failed (alerting.on_failure)
+- more than alerting.max_error_rate of its registry requests failed, from the
+  runStats section of the report the run wrote
+- the latest plan would free more than alerting.max_reclaimable_gb
+- an owner uses more registry storage than its quota, from the owner_quotas
+  section of the latest plan (see quotas); one alert per owner
+
+Alerts go to PagerDuty (Events API v2) and/or Opsgenie, whichever is
+configured. Each condition has a stable key per registry and phase, so repeated
+failures update one open incident instead of opening new ones, and the incident
+is resolved by the next run of the phase that no longer meets the condition.
+Sending is best-effort: a failure to reach the on-call service is logged and
+never fails the run.
+"""
+
+import json
+import urllib.error
+import urllib.parse
+import urllib.request
+from dataclasses import dataclass, field
+from typing import Any, Dict, List, Optional
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+PAGERDUTY_EVENTS_URL = "https://events.pagerduty.com/v2/enqueue"
+SEND_TIMEOUT_SECONDS = 10
+
+# Alert conditions
+RUN_FAILED = "run_failed"
+ERROR_RATE = "error_rate"
+RECLAIMABLE_SPACE = "reclaimable_space"
+OWNER_QUOTA = "owner_quota"
+
+
+@dataclass
+class Alert:
+    """An alert condition met by a scheduled run."""
+
+    key: str  # Stable per registry, phase and condition; used to deduplicate and resolve
+    summary: str
+    severity: str = "error"  # PagerDuty severity: critical, error, warning or info
+    details: Dict[str, Any] = field(default_factory=dict)
+
+
+def alert_key(registry_url: str, phase: str, condition: str) -> str:
+    """Deduplication key of an alert condition"""
+    return f"docker-registry-cleaner/{registry_url}/{phase}/{condition}"
+
+
+def request_error_rate(run_stats: Optional[Dict[str, Dict[str, Any]]]) -> Optional[float]:
+    """Fraction of a run's registry requests that failed.
+
+    Args:
+        run_stats: runStats section of a report (see request_stats)
+
+    Returns:
+        The error rate, or None if the run made no requests
+    """
+    if not run_stats:
+        return None
+    count = sum(int(stats.get("count", 0)) for stats in run_stats.values())
+    errors = sum(int(stats.get("errors", 0)) for stats in run_stats.values())
+    return errors / count if count else None
+
+
+def evaluate_run(
+    registry_url: str,
+    phase: str,
+    returncode: Optional[int],
+    thresholds: Dict[str, Any],
+    run_stats: Optional[Dict[str, Dict[str, Any]]] = None,
+    reclaimable_bytes: Optional[int] = None,
+    log_tail: Optional[List[str]] = None,
+    owner_quotas: Optional[List[Dict[str, Any]]] = None,
+) -> Dict[str, Optional[Alert]]:
+    """Check a finished scheduled run against the alert thresholds.
+
+    Args:
+        registry_url: Registry the run worked on
+        phase: Scheduled phase (scan, plan, apply or mirror)
+        returncode: Exit code of the run (None if it could not be started)
+        thresholds: Alert thresholds (see ConfigManager.get_alert_thresholds)
+        run_stats: runStats of the report the run wrote, if any
+        reclaimable_bytes: Bytes the plan the run wrote would free (plan phase only)
+        log_tail: Last lines of the run's output, included in failure alerts
+        owner_quotas: Quota status of every owner with a quota (see quotas.check_owner_quotas;
+            plan phase only)
+
+    Returns:
+        Dict mapping each checked alert key to its Alert, or to None if the
+        condition was checked and is not met. Conditions that could not be
+        checked (disabled, or no data) are left out.
+    """
+    results: Dict[str, Optional[Alert]] = {}
+
+    if thresholds["on_failure"]:
+        key = alert_key(registry_url, phase, RUN_FAILED)
+        results[key] = None
+        if returncode != 0:
+            results[key] = Alert(
+                key=key,
+                summary=f"Scheduled {phase} of {registry_url} failed (exit code {returncode})",
+                severity="error",
+                details={"phase": phase, "returncode": returncode, "log_tail": log_tail or []},
+            )
+
+    error_rate = request_error_rate(run_stats)
+    if thresholds["max_error_rate"] and error_rate is not None:
+        key = alert_key(registry_url, phase, ERROR_RATE)
+        results[key] = None
+        if error_rate > thresholds["max_error_rate"]:
+            results[key] = Alert(
+                key=key,
+                summary=(
+                    f"Scheduled {phase} of {registry_url}: {error_rate:.1%} of registry requests failed "
+                    f"(threshold {thresholds['max_error_rate']:.1%})"
+                ),
+                severity="warning",
+                details={"phase": phase, "error_rate": round(error_rate, 4), "runStats": run_stats},
+            )
+
+    if thresholds["max_reclaimable_gb"] and reclaimable_bytes is not None:
+        key = alert_key(registry_url, phase, RECLAIMABLE_SPACE)
+        results[key] = None
+        reclaimable_gb = reclaimable_bytes / (1024**3)
+        if reclaimable_gb > thresholds["max_reclaimable_gb"]:
+            results[key] = Alert(
+                key=key,
+                summary=(
+                    f"{registry_url}: {reclaimable_gb:.1f} GB reclaimable "
+                    f"(threshold {thresholds['max_reclaimable_gb']:g} GB)"
+                ),
+                severity="warning",
+                details={"phase": phase, "reclaimable_gb": round(reclaimable_gb, 2)},
+            )
+
+    for status in owner_quotas or []:
+        key = alert_key(registry_url, phase, f"{OWNER_QUOTA}/{status['owner']}")
+        results[key] = None
+        if status["over_bytes"] > 0:
+            used_gb = status["used_bytes"] / (1024**3)
+            quota_gb = status["quota_bytes"] / (1024**3)
+            results[key] = Alert(
+                key=key,
+                summary=(
+                    f"{registry_url}: owner {status['owner']} uses {used_gb:.1f} GB of registry storage "
+                    f"(quota {quota_gb:g} GB)"
+                ),
+                severity="warning",
+                details={
+                    "phase": phase,
+                    "owner": status["owner"],
+                    "used_gb": round(used_gb, 2),
+                    "quota_gb": round(quota_gb, 2),
+                    "share": status["share"],
+                },
+            )
+
+    return results
+
+
+def _post_json(url: str, body: Dict[str, Any], headers: Optional[Dict[str, str]] = None) -> None:
+    """POST a JSON body, raising on HTTP errors"""
+    request = urllib.request.Request(
+        url,
+        data=json.dumps(body).encode(),
+        method="POST",
+        headers={"Content-Type": "application/json", **(headers or {})},
+    )
+    with urllib.request.urlopen(request, timeout=SEND_TIMEOUT_SECONDS):
+        pass
+
+
+def _send_pagerduty(destination: Dict[str, str], alert: Alert, resolve: bool, source: str) -> None:
+    """Trigger or resolve a PagerDuty incident through the Events API v2"""
+    body: Dict[str, Any] = {
+        "routing_key": destination["routing_key"],
+        "event_action": "resolve" if resolve else "trigger",
+        "dedup_key": alert.key,
+    }
+    if not resolve:
+        body["payload"] = {
+            "summary": alert.summary,
+            "source": source,
+            "severity": alert.severity,
+            "component": "docker-registry-cleaner",
+            "custom_details": alert.details,
+        }
+    _post_json(PAGERDUTY_EVENTS_URL, body)
+
+
+def _send_opsgenie(destination: Dict[str, str], alert: Alert, resolve: bool, source: str) -> None:
+    """Create or close an Opsgenie alert, identified by its alias"""
+    headers = {"Authorization": f"GenieKey {destination['api_key']}"}
+    if resolve:
+        alias = urllib.parse.quote(alert.key, safe="")
+        url = f"{destination['api_url']}/v2/alerts/{alias}/close?identifierType=alias"
+        _post_json(url, {"source": source}, headers)
+        return
+    priority = {"critical": "P1", "error": "P2", "warning": "P3"}.get(alert.severity, "P4")
+    # Opsgenie details are a map of strings
+    details = {key: value if isinstance(value, str) else json.dumps(value) for key, value in alert.details.items()}
+    body = {
+        "message": alert.summary[:130],
+        "alias": alert.key,
+        "description": alert.summary,
+        "priority": priority,
+        "source": source,
+        "details": details,
+    }
+    _post_json(f"{destination['api_url']}/v2/alerts", body, headers)
+
+
+_SENDERS = {"pagerduty": _send_pagerduty, "opsgenie": _send_opsgenie}
+
+
+def send_alert(destinations: Dict[str, Dict[str, str]], alert: Alert, source: str, resolve: bool = False) -> bool:
+    """Trigger (or resolve) an alert in every configured on-call service.
+
+    Args:
+        destinations: Configured services (see ConfigManager.get_alert_destinations)
+        alert: Alert to send
+        source: Where the alert comes from (e.g. the registry URL)
+        resolve: Resolve the alert's open incident instead of triggering it
+
+    Returns:
+        True if every service accepted the alert
+    """
+    delivered = True
+    for name, destination in destinations.items():
+        try:
+            _SENDERS[name](destination, alert, resolve, source)
+            logger.info(f"{'Resolved' if resolve else 'Sent'} {name} alert {alert.key}")
+        except (urllib.error.URLError, OSError) as e:
+            logger.error(f"Could not send {name} alert {alert.key}: {e}")
+            delivered = False
+    return delivered
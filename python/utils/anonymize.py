@@ -0,0 +1,207 @@
+"""
+Anonymized copies of reports.
+
+With --anonymize every JSON and CSV report a run writes is also saved to the
+anonymized/ folder of the reports directory, with customer identifiers
+replaced by keyed hashes, so the copy can be shared with Domino support or a
+vendor:
+
+- tags (values of keys such as tag, tags, environment_docker_tag, the tag of
+  image IDs like environment:<tag>, and anything named like a Domino tag,
+  including keys of maps keyed by tag) become tag-<hash>
+- names (values of keys ending in name, such as environment_name or
+  project_name) become name-<hash>
+- registry addresses (values of keys naming a registry or URL, and any other
+  occurrence of a known registry host) become host-<hash>
+
+The same identifier always gets the same hash within a run, and across runs
+when reports.anonymize_salt (or ANONYMIZE_SALT) is set, so anonymized reports
+can still be correlated with each other. Sizes, counts, dates, digests and
+ObjectIDs are kept. The originals are left untouched, since later commands
+such as apply read them back.
+"""
+
+import csv
+import hashlib
+import hmac
+import json
+import re
+import urllib.parse
+from pathlib import Path
+from typing import Any, Iterable, List, Optional
+
+from utils.logging_utils import get_logger
+from utils.report_utils import REPORT_COMPRESSIONS, open_report
+from utils.tag_matching import DOMINO_TAG_PATTERN
+
+logger = get_logger(__name__)
+
+ANONYMIZED_DIR = "anonymized"
+
+# Report formats that can be anonymized; others (HTML) are not copied
+ANONYMIZABLE_SUFFIXES = (".json", ".csv")
+
+_TAG_KEY = re.compile(r"tags?(_[ab])?$", re.IGNORECASE)
+_NAME_KEY = re.compile(r"name$", re.IGNORECASE)
+_ADDRESS_KEY = re.compile(r"registry|url$", re.IGNORECASE)
+_IMAGE_REF_KEYS = ("full_image", "image", "image_ref", "reference")
+_IMAGE_ID = re.compile(r"^(environment|model):(.+)$")
+_DOMINO_TAG = re.compile(DOMINO_TAG_PATTERN)
+# As in Docker, the first path component is a registry host only with a dot or port, or for localhost
+_IMAGE_REF = re.compile(
+    r"^(?:(?P<host>[\w-]+(?:\.[\w-]+)+(?::\d+)?|[\w-]+:\d+|localhost)/)?(?P<path>[\w./-]+):(?P<tag>[\w][\w.-]{0,127})$"
+)
+
+
+class Anonymizer:
+    """Replaces customer identifiers in report data by keyed hashes."""
+
+    def __init__(self, salt: bytes, hosts: Iterable[str] = ()):
+        """
+        Args:
+            salt: Key of the hashes; the same salt gives the same hashes
+            hosts: Registry hosts to replace wherever they occur in text
+        """
+        self.salt = salt
+        self.hosts: set = set()
+        for host in hosts:
+            self._add_host(host)
+
+    def token(self, kind: str, value: str) -> str:
+        """Hash of an identifier, e.g. tag-1a2b3c4d5e6f"""
+        digest = hmac.new(self.salt, f"{kind}:{value}".encode(), hashlib.sha256).hexdigest()
+        return f"{kind}-{digest[:12]}"
+
+    def anonymize(self, data: Any, key: str = "") -> Any:
+        """Anonymize a report, or the value of one of its keys"""
+        if isinstance(data, dict):
+            tag_keys = bool(key) and _TAG_KEY.search(key) is not None
+            return {
+                self._anonymize_key(item_key, tag_keys): self.anonymize(value, str(item_key))
+                for item_key, value in data.items()
+            }
+        if isinstance(data, list):
+            # Dicts in a list of tags are records, not maps keyed by tag
+            return [self.anonymize(item, "" if isinstance(item, dict) else key) for item in data]
+        if isinstance(data, str):
+            return self.anonymize_string(data, key)
+        return data
+
+    def anonymize_string(self, value: str, key: str = "") -> str:
+        """Anonymize a string value found under a key"""
+        if not value:
+            return value
+        match = _IMAGE_ID.match(value)
+        if match:
+            return f"{match.group(1)}:{self.token('tag', match.group(2))}"
+        if _TAG_KEY.search(key) or _DOMINO_TAG.match(value):
+            return self.token("tag", value)
+        if _NAME_KEY.search(key):
+            return self.token("name", value)
+        if _ADDRESS_KEY.search(key):
+            return self.anonymize_address(value)
+        if key in _IMAGE_REF_KEYS:
+            match = _IMAGE_REF.match(value)
+            if match:
+                host = f"{self.anonymize_address(match.group('host'))}/" if match.group("host") else ""
+                return f"{host}{match.group('path')}:{self.token('tag', match.group('tag'))}"
+        return self._replace_hosts(value)
+
+    def anonymize_address(self, value: str) -> str:
+        """Anonymize the host of a registry address or URL"""
+        if "://" in value:
+            host = urllib.parse.urlparse(value).netloc
+        else:
+            host = value.split("/", 1)[0]
+        self._add_host(host)
+        return self._replace_hosts(value)
+
+    def _anonymize_key(self, key: Any, tag_keys: bool) -> Any:
+        """Anonymize a dict key: image IDs and tags, and every key of a dict keyed by tag"""
+        if not isinstance(key, str):
+            return key
+        if tag_keys or _IMAGE_ID.match(key) or _DOMINO_TAG.match(key):
+            return self.anonymize_string(key, "tag")
+        return key
+
+    def _add_host(self, host: str) -> None:
+        """Remember a registry host, with and without port, to replace it in text"""
+        host = host.rsplit("@", 1)[-1]
+        if not host or host.startswith("host-"):
+            return
+        self.hosts.add(host)
+        self.hosts.add(host.split(":", 1)[0])
+
+    def _replace_hosts(self, value: str) -> str:
+        """Replace known registry hosts in text, longest first"""
+        for host in sorted(self.hosts, key=len, reverse=True):
+            if host in value:
+                value = value.replace(host, self.token("host", host.split(":", 1)[0]))
+        return value
+
+
+def report_suffix(path: Path) -> str:
+    """Suffix of a report's format, below any compression suffix (".json" for report.json.gz)"""
+    if path.suffix in REPORT_COMPRESSIONS.values():
+        return Path(path.stem).suffix
+    return path.suffix
+
+
+def anonymize_file(anonymizer: Anonymizer, source: Path, destination: Path) -> None:
+    """Write an anonymized copy of a JSON or CSV report, compressed like the report"""
+    destination.parent.mkdir(parents=True, exist_ok=True)
+    if report_suffix(source) == ".csv":
+        with open_report(source, newline="") as f:
+            reader = csv.DictReader(f)
+            fieldnames = reader.fieldnames or []
+            rows = [{key: anonymizer.anonymize_string(value, key) for key, value in row.items()} for row in reader]
+        with open_report(destination, "w", newline="") as f:
+            writer = csv.DictWriter(f, fieldnames=fieldnames)
+            writer.writeheader()
+            writer.writerows(rows)
+        return
+
+    with open_report(source) as f:
+        data = json.load(f)
+    with open_report(destination, "w") as f:
+        json.dump(anonymizer.anonymize(data), f, indent=2)
+
+
+def anonymize_reports(anonymizer: Anonymizer, reports: List[Path], output_dir: Path) -> List[Path]:
+    """Save anonymized copies of reports, under the same file names.
+
+    Args:
+        anonymizer: Anonymizer to use
+        reports: Report paths
+        output_dir: Folder for the copies
+
+    Returns:
+        Paths of the copies; reports that are not JSON or CSV, or cannot be
+        read, are skipped with a warning
+    """
+    copies: List[Path] = []
+    for report in reports:
+        if report_suffix(report) not in ANONYMIZABLE_SUFFIXES:
+            logger.warning(f"Not anonymizing {report.name}: only JSON and CSV reports can be anonymized")
+            continue
+        destination = output_dir / report.name
+        try:
+            anonymize_file(anonymizer, report, destination)
+        except (OSError, ValueError) as e:
+            logger.warning(f"Could not anonymize {report.name}: {e}")
+            continue
+        copies.append(destination)
+    return copies
+
+
+def salt_or_random(salt: Optional[str]) -> bytes:
+    """Key for an Anonymizer: the configured salt, or a random one for this run"""
+    if salt:
+        return salt.encode()
+    import secrets
+
+    logger.info(
+        "No reports.anonymize_salt configured: hashes are consistent within this run only, "
+        "not with reports anonymized by other runs"
+    )
+    return secrets.token_bytes(32)
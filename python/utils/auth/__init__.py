@@ -5,12 +5,21 @@ This module provides authentication helpers for various registry types:
 - AWS ECR (Elastic Container Registry)
 - Azure ACR (Azure Container Registry)
 - Kubernetes secrets (for in-cluster and external registries)
+- Credential profiles from the config file, per registry host
 """
 
-from utils.auth.providers import authenticate_acr, authenticate_ecr, get_credentials_from_k8s_secret
+from utils.auth.providers import (
+    authenticate_acr,
+    authenticate_ecr,
+    get_credentials_from_k8s_secret,
+    get_ecr_client,
+    get_profile_credentials,
+)
 
 __all__ = [
     "authenticate_ecr",
     "authenticate_acr",
     "get_credentials_from_k8s_secret",
+    "get_ecr_client",
+    "get_profile_credentials",
 ]
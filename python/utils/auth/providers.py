@@ -11,7 +11,28 @@ import os
 import subprocess
 import urllib.parse
 import urllib.request
-from typing import Optional, Tuple
+from typing import Any, Dict, Optional, Tuple
+
+from utils.registry_http import write_auth_file_credentials
+
+
+def _store_login(registry_url: str, auth_file: str, username: str, password: str, native: bool) -> None:
+    """Store registry credentials in the auth file: with skopeo login, or written directly when native.
+
+    Raises:
+        subprocess.CalledProcessError: If skopeo login fails
+    """
+    if native:
+        write_auth_file_credentials(auth_file, registry_url, username, password)
+        return
+    # Run skopeo login with password on stdin (no shell)
+    subprocess.run(
+        ["skopeo", "login", "--authfile", auth_file, "--username", username, "--password-stdin", registry_url],
+        input=password,
+        capture_output=True,
+        text=True,
+        check=True,
+    )
 
 
 def _load_kubernetes_config():
@@ -127,56 +148,87 @@ def get_credentials_from_k8s_secret(
         return None, None
 
 
-def authenticate_ecr(registry_url: str, auth_file: str) -> None:
+def get_ecr_client(
+    registry_url: str,
+    role_arn: Optional[str] = None,
+    external_id: Optional[str] = None,
+    region: Optional[str] = None,
+) -> Any:
+    """Create a boto3 ECR client for a registry.
+
+    Args:
+        registry_url: ECR registry URL (e.g., '123456789.dkr.ecr.us-west-2.amazonaws.com')
+        role_arn: IAM role to assume for the client's credentials (optional)
+        external_id: External ID the role's trust policy requires (optional)
+        region: AWS region (defaults to the region in the registry URL)
+
+    Returns:
+        boto3 ECR client
+    """
+    # Extract region from registry URL unless given
+    # ECR URLs are typically: account.dkr.ecr.region.amazonaws.com
+    parts = registry_url.split(".")
+    if not region and len(parts) >= 4 and parts[-2] == "amazonaws" and parts[-1] == "com":
+        region = parts[-3]  # Extract region from URL
+    elif not region:
+        region = os.environ.get("AWS_DEFAULT_REGION", "us-east-1")
+
+    logging.info(f"Using ECR in region: {region}")
+
+    # boto3 instead of the aws CLI (no CLI or shell needed)
+    import boto3
+
+    client_kwargs = {"region_name": region}
+    if role_arn:
+        logging.info(f"Assuming IAM role {role_arn} for {registry_url}")
+        assume_args = {"RoleArn": role_arn, "RoleSessionName": "docker-registry-cleaner"}
+        if external_id:
+            assume_args["ExternalId"] = external_id
+        role_credentials = boto3.client("sts", region_name=region).assume_role(**assume_args)["Credentials"]
+        client_kwargs.update(
+            aws_access_key_id=role_credentials["AccessKeyId"],
+            aws_secret_access_key=role_credentials["SecretAccessKey"],
+            aws_session_token=role_credentials["SessionToken"],
+        )
+
+    return boto3.client("ecr", **client_kwargs)
+
+
+def authenticate_ecr(
+    registry_url: str,
+    auth_file: str,
+    role_arn: Optional[str] = None,
+    external_id: Optional[str] = None,
+    region: Optional[str] = None,
+    native: bool = False,
+) -> None:
     """Authenticate with AWS ECR using boto3.
 
-    Uses boto3 to get an ECR authorization token and logs in via skopeo.
+    Uses boto3 to get an ECR authorization token and logs in via skopeo. With a
+    role ARN, the token is requested with that role's temporary credentials, so
+    registries in other AWS accounts can be reached from one run.
 
     Args:
         registry_url: ECR registry URL (e.g., '123456789.dkr.ecr.us-west-2.amazonaws.com')
         auth_file: Path to skopeo auth file for storing credentials
+        role_arn: IAM role to assume before requesting the token (optional)
+        external_id: External ID the role's trust policy requires (optional)
+        region: AWS region (defaults to the region in the registry URL)
+        native: Write the token to the auth file instead of running skopeo login (skopeo.client: native)
 
     Raises:
         subprocess.CalledProcessError: If skopeo login fails
         Exception: For other authentication errors
     """
     try:
-        # Extract region from registry URL
-        # ECR URLs are typically: account.dkr.ecr.region.amazonaws.com
-        parts = registry_url.split(".")
-        if len(parts) >= 4 and parts[-2] == "amazonaws" and parts[-1] == "com":
-            region = parts[-3]  # Extract region from URL
-        else:
-            region = os.environ.get("AWS_DEFAULT_REGION", "us-east-1")
-
-        logging.info(f"Authenticating with ECR in region: {region}")
-
-        # Get ECR login password via boto3 (no aws CLI or shell needed)
-        import boto3
-
-        client = boto3.client("ecr", region_name=region)
+        # Get ECR login password via boto3
+        client = get_ecr_client(registry_url, role_arn=role_arn, external_id=external_id, region=region)
         response = client.get_authorization_token()
         token_b64 = response["authorizationData"][0]["authorizationToken"]
         token = base64.b64decode(token_b64).decode("utf-8")
         _, password = token.split(":", 1)
 
-        # Run skopeo login with password on stdin (no shell)
-        subprocess.run(
-            [
-                "skopeo",
-                "login",
-                "--authfile",
-                auth_file,
-                "--username",
-                "AWS",
-                "--password-stdin",
-                registry_url,
-            ],
-            input=password,
-            capture_output=True,
-            text=True,
-            check=True,
-        )
+        _store_login(registry_url, auth_file, "AWS", password, native)
         logging.info("ECR authentication successful")
 
     except subprocess.CalledProcessError as e:
@@ -189,7 +241,9 @@ def authenticate_ecr(registry_url: str, auth_file: str) -> None:
         raise
 
 
-def authenticate_acr(registry_url: str, auth_file: str) -> None:
+def authenticate_acr(
+    registry_url: str, auth_file: str, client_id: Optional[str] = None, native: bool = False
+) -> None:
     """Authenticate with Azure Container Registry using managed identity.
 
     Uses Azure Identity SDK to get an access token and exchanges it for
@@ -198,6 +252,8 @@ def authenticate_acr(registry_url: str, auth_file: str) -> None:
     Args:
         registry_url: ACR registry URL (e.g., 'myregistry.azurecr.io')
         auth_file: Path to skopeo auth file for storing credentials
+        client_id: Client ID of the managed identity (defaults to AZURE_CLIENT_ID)
+        native: Write the token to the auth file instead of running skopeo login (skopeo.client: native)
 
     Raises:
         subprocess.CalledProcessError: If skopeo login fails
@@ -218,7 +274,7 @@ def authenticate_acr(registry_url: str, auth_file: str) -> None:
         # Get Azure AD access token using managed identity
         # If AZURE_CLIENT_ID is set, use ManagedIdentityCredential directly
         # (required when multiple user-assigned identities exist on the AKS cluster)
-        client_id = os.environ.get("AZURE_CLIENT_ID")
+        client_id = client_id or os.environ.get("AZURE_CLIENT_ID")
         if client_id:
             from azure.identity import ManagedIdentityCredential
 
@@ -251,24 +307,9 @@ def authenticate_acr(registry_url: str, auth_file: str) -> None:
             result = json.loads(response.read().decode("utf-8"))
             refresh_token = result["refresh_token"]
 
-        # Run skopeo login with the refresh token as password
+        # Log in with the refresh token as password
         # ACR uses a placeholder GUID as the username when using refresh tokens
-        subprocess.run(
-            [
-                "skopeo",
-                "login",
-                "--authfile",
-                auth_file,
-                "--username",
-                "00000000-0000-0000-0000-000000000000",
-                "--password-stdin",
-                registry_url,
-            ],
-            input=refresh_token,
-            capture_output=True,
-            text=True,
-            check=True,
-        )
+        _store_login(registry_url, auth_file, "00000000-0000-0000-0000-000000000000", refresh_token, native)
         logging.info("ACR authentication successful")
 
     except subprocess.CalledProcessError as e:
@@ -295,3 +336,63 @@ def authenticate_acr(registry_url: str, auth_file: str) -> None:
             logging.error("    - The identity is not assigned to the AKS node pool VMSS")
             logging.error("  Run: az vmss identity show -g <node-rg> -n <vmss-name>")
         raise
+
+
+def get_profile_credentials(
+    profile: Dict[str, str],
+    registry_url: str,
+    namespace: str,
+    auth_file: str,
+    native: bool = False,
+) -> Tuple[Optional[str], Optional[str]]:
+    """Get registry credentials with a credential profile from the config file.
+
+    ECR and ACR profiles log in to the registry themselves and return no
+    password; their credentials end up in the auth file, as with automatic
+    ECR/ACR authentication.
+
+    Args:
+        profile: Credential profile (see ConfigManager.get_credential_profile)
+        registry_url: Registry the credentials are for
+        namespace: Default Kubernetes namespace for secret profiles
+        auth_file: Path to skopeo auth file for storing credentials
+        native: Write ECR/ACR tokens to the auth file instead of running skopeo login
+
+    Returns:
+        Tuple of (username, password) - either or both may be None
+
+    Raises:
+        RuntimeError: If the profile's environment variables or secret do not hold credentials
+    """
+    method = profile["method"]
+    logging.info(f"Using {method} credential profile for {registry_url}")
+    if method == "ecr":
+        authenticate_ecr(
+            registry_url,
+            auth_file,
+            role_arn=profile.get("role_arn"),
+            external_id=profile.get("external_id"),
+            region=profile.get("region"),
+            native=native,
+        )
+        return "AWS", None
+    if method == "acr":
+        authenticate_acr(registry_url, auth_file, client_id=profile.get("client_id"), native=native)
+        return "00000000-0000-0000-0000-000000000000", None
+    if method == "secret":
+        secret_namespace = profile.get("namespace") or namespace
+        username, password = get_credentials_from_k8s_secret(profile["secret"], secret_namespace, registry_url)
+        if not username:
+            raise RuntimeError(
+                f"Secret {secret_namespace}/{profile['secret']} has no credentials for {registry_url}"
+            )
+        return username, password
+    if method == "env":
+        username = os.environ.get(profile["username_env"])
+        password = os.environ.get(profile["password_env"])
+        if not username or not password:
+            raise RuntimeError(
+                f"Set {profile['username_env']} and {profile['password_env']} to the credentials for {registry_url}"
+            )
+        return username, password
+    return None, None
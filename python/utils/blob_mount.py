@@ -0,0 +1,146 @@
+"""
+Copying images between repositories of the same registry by mounting blobs.
+
+An archive copy or migration within one registry host (replicate_to or a
+mirror destination such as "registry.example.com/archive") does not need to
+download and re-upload layers: the registry API can mount a blob another
+repository already holds (POST /v2/<name>/blobs/uploads/?mount=<digest>&from=<repo>),
+which only adds a link to the existing data. Once every blob of an image is in
+the destination repository, its manifest is pushed unchanged, so the copy has
+the same digest.
+
+Blobs the destination repository already has are not mounted again. If the
+registry refuses a mount (for example when the repositories are on different
+storage), the image is copied with skopeo instead, which uploads only the
+blobs that are still missing. Every copy reports the blobs and bytes that were
+mounted, already present, and transferred.
+"""
+
+import json
+from typing import Any, List, Optional, Tuple, TypedDict
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+
+class BlobCopyStats(TypedDict):
+    """Blobs of a copy within one registry, and how each reached the destination repository."""
+
+    mounted_blobs: int
+    mounted_bytes: int
+    existing_blobs: int  # Already in the destination repository
+    existing_bytes: int
+    transferred_blobs: int  # Could not be mounted, uploaded by skopeo
+    transferred_bytes: int
+
+
+def empty_copy_stats() -> BlobCopyStats:
+    """Stats of no copy, to add copies to"""
+    return {
+        "mounted_blobs": 0,
+        "mounted_bytes": 0,
+        "existing_blobs": 0,
+        "existing_bytes": 0,
+        "transferred_blobs": 0,
+        "transferred_bytes": 0,
+    }
+
+
+def add_copy_stats(total: BlobCopyStats, stats: BlobCopyStats) -> None:
+    """Add the stats of one copy to a total"""
+    for key in total:
+        total[key] += stats[key]  # type: ignore[literal-required]
+
+
+def same_registry_prefix(registry_url: str, destination: str) -> Optional[str]:
+    """Get the repository prefix of a destination on the same registry host.
+
+    Args:
+        registry_url: Registry the images are in, e.g. "registry.example.com:5000"
+        destination: Destination registry, optionally with a path, e.g. "registry.example.com:5000/archive"
+
+    Returns:
+        The destination's path ("archive"), or None if it is on another host or has no path
+    """
+
+    def split(url: str) -> Tuple[str, str]:
+        host, _, path = url.split("://", 1)[-1].partition("/")
+        return host.lower(), path.strip("/")
+
+    source_host, _ = split(registry_url)
+    destination_host, prefix = split(destination)
+    if destination_host != source_host or not prefix:
+        return None
+    return prefix
+
+
+def _manifest_blobs(manifest: dict) -> List[Tuple[str, int]]:
+    """(digest, size) of the config and layers of an image manifest, without foreign layers"""
+    descriptors = [manifest["config"]] if isinstance(manifest.get("config"), dict) else []
+    descriptors += [layer for layer in manifest.get("layers") or [] if isinstance(layer, dict)]
+    # Foreign layers are pulled from their urls and are not stored in the registry
+    return [(d["digest"], int(d.get("size") or 0)) for d in descriptors if d.get("digest") and not d.get("urls")]
+
+
+def mount_image(
+    http_client: Any, source_repository: str, digest: str, destination_repository: str, tag: str
+) -> Tuple[BlobCopyStats, bool]:
+    """Copy an image to another repository of the same registry by mounting its blobs.
+
+    Manifest lists are copied with every platform they list. Manifests are only
+    pushed once all their blobs are in the destination repository.
+
+    Args:
+        http_client: RegistryHttpClient of the registry
+        source_repository: Repository the image is in
+        digest: Manifest digest to copy
+        destination_repository: Repository to copy to
+        tag: Tag to give the copy
+
+    Returns:
+        (stats, complete): complete is False if a blob could not be mounted or the
+        manifest is schema1, in which case no tag was pushed and the image must be
+        copied another way (blobs mounted so far stay mounted)
+
+    Raises:
+        ValueError: If a manifest does not exist in the source repository
+        urllib.error.URLError: If the registry could not be reached
+    """
+    stats = empty_copy_stats()
+
+    def copy_manifest(reference: str, push_as: str) -> bool:
+        fetched = http_client.get_manifest(source_repository, reference)
+        if fetched is None:
+            raise ValueError(f"manifest {reference} not found in {source_repository}")
+        content, media_type = fetched
+        manifest = json.loads(content)
+        if manifest.get("schemaVersion") == 1:
+            return False
+        complete = True
+        if "manifests" in manifest:
+            for child in manifest["manifests"]:
+                complete = copy_manifest(child["digest"], child["digest"]) and complete
+        else:
+            for blob_digest, size in _manifest_blobs(manifest):
+                if http_client.head_blob_size(destination_repository, blob_digest) is not None:
+                    stats["existing_blobs"] += 1
+                    stats["existing_bytes"] += size
+                elif http_client.mount_blob(destination_repository, blob_digest, source_repository):
+                    stats["mounted_blobs"] += 1
+                    stats["mounted_bytes"] += size
+                else:
+                    stats["transferred_blobs"] += 1
+                    stats["transferred_bytes"] += size
+                    complete = False
+        if complete:
+            http_client.put_manifest(destination_repository, push_as, content, media_type or manifest.get("mediaType"))
+        return complete
+
+    complete = copy_manifest(digest, tag)
+    if not complete:
+        logger.info(
+            f"{source_repository}@{digest}: {stats['transferred_blobs']} blob(s) could not be mounted into "
+            f"{destination_repository}"
+        )
+    return stats, complete
@@ -0,0 +1,102 @@
+"""
+Version and build metadata.
+
+The package version comes from the installed distribution (or pyproject.toml
+when running from a source checkout). The commit and build date are embedded
+in the container image at build time through the GIT_COMMIT and BUILD_DATE
+environment variables (see the Dockerfile); outside an image the commit is
+read from git when available. The skopeo version is detected from the skopeo
+binary on PATH. Reports record this metadata so that their results, and bug
+reports about them, can be traced to the exact build that produced them.
+"""
+
+import functools
+import os
+import re
+import subprocess
+from pathlib import Path
+from typing import Optional, TypedDict
+
+DISTRIBUTION_NAME = "docker-registry-cleaner"
+
+_REPO_ROOT = Path(__file__).parent.parent.parent
+
+
+class BuildInfo(TypedDict):
+    version: str
+    commit: Optional[str]
+    build_date: Optional[str]
+    skopeo_version: Optional[str]
+
+
+def _package_version() -> str:
+    """Version of the installed distribution, else the source tree's pyproject.toml"""
+    from importlib.metadata import PackageNotFoundError, version
+
+    try:
+        return version(DISTRIBUTION_NAME)
+    except PackageNotFoundError:
+        pass
+
+    import tomllib
+
+    try:
+        with open(_REPO_ROOT / "pyproject.toml", "rb") as f:
+            return tomllib.load(f)["project"]["version"]
+    except (OSError, KeyError, tomllib.TOMLDecodeError):
+        return "unknown"
+
+
+def _run_version_command(cmd: list) -> Optional[str]:
+    """Stdout of a short-lived command, or None if it is missing or fails"""
+    try:
+        result = subprocess.run(cmd, capture_output=True, text=True, timeout=5, check=True)
+    except (OSError, subprocess.SubprocessError):
+        return None
+    return result.stdout.strip() or None
+
+
+def _git_commit() -> Optional[str]:
+    """Commit embedded at build time, else the source checkout's HEAD"""
+    commit = os.environ.get("GIT_COMMIT")
+    if commit:
+        return commit
+    if not (_REPO_ROOT / ".git").exists():
+        return None
+    return _run_version_command(["git", "-C", str(_REPO_ROOT), "rev-parse", "--short=12", "HEAD"])
+
+
+def _skopeo_version() -> Optional[str]:
+    """Version reported by `skopeo --version` (e.g. "skopeo version 1.22.0" -> "1.22.0")"""
+    output = _run_version_command(["skopeo", "--version"])
+    if not output:
+        return None
+    match = re.search(r"version\s+(\S+)", output)
+    return match.group(1) if match else output
+
+
+@functools.lru_cache(maxsize=1)
+def get_build_info() -> BuildInfo:
+    """Get version and build metadata (detected once per process).
+
+    Returns:
+        BuildInfo; commit, build_date and skopeo_version are None when unknown
+    """
+    return {
+        "version": _package_version(),
+        "commit": _git_commit(),
+        "build_date": os.environ.get("BUILD_DATE") or None,
+        "skopeo_version": _skopeo_version(),
+    }
+
+
+def format_build_info(info: Optional[BuildInfo] = None) -> str:
+    """One-line description of a build, e.g. for log headers and bug reports"""
+    info = info or get_build_info()
+    parts = [f"docker-registry-cleaner {info['version']}"]
+    if info["commit"]:
+        parts.append(f"commit {info['commit']}")
+    if info["build_date"]:
+        parts.append(f"built {info['build_date']}")
+    parts.append(f"skopeo {info['skopeo_version'] or 'not found'}")
+    return ", ".join(parts)
@@ -4,8 +4,10 @@ import functools
 import hashlib
 import json
 import logging
+import os
+import threading
 import time
-from typing import Any, Callable, Dict, Optional, Tuple, TypeVar
+from typing import Any, Callable, Dict, List, Optional, Tuple, TypeVar
 
 logger = logging.getLogger(__name__)
 
@@ -88,6 +90,201 @@ class TTLCache:
         return len(expired_keys)
 
 
+class DigestInspectCache:
+    """Persistent cache of image layers keyed by manifest digest.
+
+    A manifest digest identifies immutable content, so entries never expire:
+    a tag that still points to a cached digest has exactly the cached layers.
+    The cache is a JSON file shared between runs; it is loaded on creation and
+    written back by save() when it has changed. It also keeps a snapshot of the
+    digest each tag pointed to, so the next scan can tell which tags changed,
+    the legacy manifest format (e.g. schema1) of digests that have one, the
+    OCI annotations of digests whose annotations were collected, and the image
+    config labels, possible secrets (see utils/secret_scan.py) and build fields
+    (see utils/toolchain.py) of fully inspected digests.
+    """
+
+    FORMAT_VERSION = 1
+
+    def __init__(self, path: Optional[str] = None):
+        """Initialize the cache
+
+        Args:
+            path: JSON file backing the cache (None = in-memory only)
+        """
+        self.path = path
+        self._lock = threading.Lock()
+        self._entries: Dict[str, List[Dict[str, Any]]] = {}
+        self._tags: Dict[str, str] = {}
+        self._created: Dict[str, str] = {}
+        self._legacy_formats: Dict[str, str] = {}
+        self._annotations: Dict[str, Dict[str, str]] = {}
+        self._labels: Dict[str, Dict[str, str]] = {}
+        self._secret_findings: Dict[str, List[Dict[str, str]]] = {}
+        self._toolchain: Dict[str, Dict[str, str]] = {}
+        self._dirty = False
+        if path and os.path.exists(path):
+            self._load()
+
+    def _load(self) -> None:
+        """Load entries from disk, starting empty if the file is unreadable"""
+        try:
+            with open(self.path, "r") as f:
+                data = json.load(f)
+            if data.get("format_version") == self.FORMAT_VERSION:
+                self._entries = data.get("digests", {})
+                self._tags = data.get("tags", {})
+                self._created = data.get("created", {})
+                self._legacy_formats = data.get("legacy_formats", {})
+                self._annotations = data.get("annotations", {})
+                self._labels = data.get("labels", {})
+                self._secret_findings = data.get("secret_findings", {})
+                self._toolchain = data.get("toolchain", {})
+            else:
+                logger.info(f"Ignoring inspect cache {self.path} with unsupported format")
+        except (OSError, ValueError, AttributeError) as e:
+            logger.warning(f"Could not read inspect cache {self.path}, starting empty: {e}")
+
+    def get(self, digest: str) -> Optional[List[Dict[str, Any]]]:
+        """Get cached layers ([{"Digest", "Size", "MediaType"}, ...], foreign layers marked "Foreign") of a digest"""
+        if not digest:
+            return None
+        with self._lock:
+            return self._entries.get(digest)
+
+    def get_created(self, digest: str) -> Optional[str]:
+        """Get the cached creation time of a manifest digest's image"""
+        with self._lock:
+            return self._created.get(digest)
+
+    def get_legacy_format(self, digest: str) -> Optional[str]:
+        """Get the legacy manifest format (e.g. "schema1") of a digest, or None for current formats"""
+        with self._lock:
+            return self._legacy_formats.get(digest)
+
+    def get_annotations(self, digest: str) -> Optional[Dict[str, str]]:
+        """Get the OCI annotations of a digest, or None if they were not collected"""
+        with self._lock:
+            return self._annotations.get(digest)
+
+    def get_labels(self, digest: str) -> Optional[Dict[str, str]]:
+        """Get the image config labels of a digest, or None if it was not fully inspected"""
+        with self._lock:
+            return self._labels.get(digest)
+
+    def get_secret_findings(self, digest: str) -> Optional[List[Dict[str, str]]]:
+        """Get the possible secrets found in a digest's Env and labels, or None if it was not fully inspected"""
+        with self._lock:
+            return self._secret_findings.get(digest)
+
+    def get_toolchain(self, digest: str) -> Optional[Dict[str, str]]:
+        """Get the build fields (DockerVersion, OS, architecture) of a digest, or None if it was not fully inspected"""
+        with self._lock:
+            return self._toolchain.get(digest)
+
+    def set(
+        self,
+        digest: str,
+        layers_data: List[Dict[str, Any]],
+        created: Optional[str] = None,
+        legacy_format: Optional[str] = None,
+        annotations: Optional[Dict[str, str]] = None,
+        labels: Optional[Dict[str, str]] = None,
+        secret_findings: Optional[List[Dict[str, str]]] = None,
+        toolchain: Optional[Dict[str, str]] = None,
+    ) -> None:
+        """Cache the layers (and optionally creation time, format, annotations, labels, secrets and build fields)"""
+        if not digest:
+            return
+        layers = [
+            {
+                "Digest": layer["Digest"],
+                "Size": layer["Size"],
+                **({"MediaType": layer["MediaType"]} if layer.get("MediaType") else {}),
+                **({"Foreign": True} if layer.get("Foreign") else {}),
+            }
+            for layer in layers_data
+        ]
+        with self._lock:
+            if self._entries.get(digest) != layers:
+                self._entries[digest] = layers
+                self._dirty = True
+            if created and self._created.get(digest) != created:
+                self._created[digest] = created
+                self._dirty = True
+            if legacy_format and self._legacy_formats.get(digest) != legacy_format:
+                self._legacy_formats[digest] = legacy_format
+                self._dirty = True
+            if annotations is not None and self._annotations.get(digest) != annotations:
+                self._annotations[digest] = dict(annotations)
+                self._dirty = True
+            if labels is not None and self._labels.get(digest) != labels:
+                self._labels[digest] = dict(labels)
+                self._dirty = True
+            if secret_findings is not None and self._secret_findings.get(digest) != secret_findings:
+                self._secret_findings[digest] = list(secret_findings)
+                self._dirty = True
+            if toolchain is not None and self._toolchain.get(digest) != toolchain:
+                self._toolchain[digest] = dict(toolchain)
+                self._dirty = True
+
+    def record_tag_digest(self, reference: str, digest: str) -> str:
+        """Record the digest a tag points to and compare it with the previous snapshot
+
+        Args:
+            reference: Tag reference ("repository:tag")
+            digest: Manifest digest the tag currently points to
+
+        Returns:
+            "unchanged", "changed" or "new"
+        """
+        with self._lock:
+            previous = self._tags.get(reference)
+            if previous == digest:
+                return "unchanged"
+            if digest:
+                self._tags[reference] = digest
+                self._dirty = True
+            return "new" if previous is None else "changed"
+
+    def tag_references(self) -> List[str]:
+        """Get every tag reference ("repository:tag") recorded by record_tag_digest"""
+        with self._lock:
+            return list(self._tags)
+
+    def save(self) -> None:
+        """Write the cache to disk if it changed since it was loaded"""
+        if not self.path:
+            return
+        with self._lock:
+            if not self._dirty:
+                return
+            os.makedirs(os.path.dirname(self.path) or ".", exist_ok=True)
+            tmp_path = f"{self.path}.tmp"
+            with open(tmp_path, "w") as f:
+                json.dump(
+                    {
+                        "format_version": self.FORMAT_VERSION,
+                        "digests": self._entries,
+                        "tags": self._tags,
+                        "created": self._created,
+                        "legacy_formats": self._legacy_formats,
+                        "annotations": self._annotations,
+                        "labels": self._labels,
+                        "secret_findings": self._secret_findings,
+                        "toolchain": self._toolchain,
+                    },
+                    f,
+                )
+            os.replace(tmp_path, self.path)
+            self._dirty = False
+
+    def size(self) -> int:
+        """Get number of cached digests"""
+        with self._lock:
+            return len(self._entries)
+
+
 # Global caches for different operation types
 _tag_list_cache = TTLCache(ttl_seconds=1800, max_size=100)  # 30 minutes, 100 entries
 _image_inspect_cache = TTLCache(ttl_seconds=3600, max_size=1000)  # 1 hour, 1000 entries
@@ -138,8 +335,11 @@ def cached(cache: TTLCache, key_func: Optional[Callable] = None):
             if key_func:
                 cache_key_str = key_func(*args, **kwargs)
             else:
-                # For methods, exclude 'self' from cache key
-                key_args = args[1:] if args and hasattr(args[0], func.__name__) else args
+                # For methods, exclude 'self' from cache key, but keep the registry a client talks to
+                if args and hasattr(args[0], func.__name__):
+                    key_args = (getattr(args[0], "registry_url", None),) + args[1:]
+                else:
+                    key_args = args
                 cache_key_str = f"{func.__module__}.{func.__name__}:{cache_key(*key_args, **kwargs)}"
 
             # Try to get from cache
@@ -0,0 +1,115 @@
+"""
+Per-owner storage chargeback.
+
+Turns owner usage (ImageAnalyzer.owner_usage) into a storage bill: each owner
+pays for its amortized bytes, which split every layer evenly between the
+images using it and so add up to the registry's total. Exclusive bytes, what
+deleting all of an owner's images would free, are listed alongside.
+
+Growth compares the bill with the owner usage of an earlier scan, normally the
+previous scan snapshot. Owners that no longer have images are kept in the bill
+with zero bytes so that their shrinkage shows. With owner quotas configured
+(see quotas), each line also shows the owner's quota and how far over it is.
+"""
+
+from typing import Dict, List, Optional, TypedDict
+
+from utils.image_data_analysis import OwnerUsage
+from utils.quotas import OwnerQuotas, quota_for
+
+BYTES_PER_GB = 1024**3
+
+# Columns of the CSV export, in order
+CSV_FIELDS = (
+    "owner",
+    "images",
+    "amortized_bytes",
+    "exclusive_bytes",
+    "shared_bytes",
+    "cost",
+    "previous_amortized_bytes",
+    "growth_bytes",
+    "growth_percent",
+    "quota_bytes",
+    "over_quota_bytes",
+)
+
+
+class OwnerCharge(TypedDict):
+    """One owner's line of the storage bill."""
+
+    owner: str
+    images: int
+    amortized_bytes: int  # billed bytes
+    exclusive_bytes: int
+    shared_bytes: int
+    cost: float  # amortized GB x cost per GB-month
+    previous_amortized_bytes: Optional[int]  # None without an earlier scan
+    growth_bytes: Optional[int]
+    growth_percent: Optional[float]  # None without an earlier scan or for new owners
+    quota_bytes: Optional[int]  # None if the owner has no quota
+    over_quota_bytes: Optional[int]
+
+
+def storage_cost(size_bytes: int, cost_per_gb_month: float) -> float:
+    """Monthly cost of storing size_bytes, rounded to cents"""
+    return round(size_bytes / BYTES_PER_GB * cost_per_gb_month, 2)
+
+
+def build_chargeback(
+    usage: List[OwnerUsage],
+    previous_usage: Optional[List[OwnerUsage]],
+    cost_per_gb_month: float,
+    quotas: Optional[OwnerQuotas] = None,
+) -> List[OwnerCharge]:
+    """Bill every owner for its amortized registry storage.
+
+    Args:
+        usage: Current usage per owner
+        previous_usage: Usage per owner of an earlier scan, or None if there is none
+        cost_per_gb_month: Storage price per GB (1024^3 bytes) and month
+        quotas: Owner quotas, if configured
+
+    Returns:
+        One charge per owner, largest amortized_bytes first
+    """
+    previous: Dict[str, int] = {entry["owner"]: entry["amortized_bytes"] for entry in previous_usage or []}
+    current = {entry["owner"]: entry for entry in usage}
+    if previous_usage is not None:
+        for owner in previous.keys() - current.keys():
+            current[owner] = {
+                "owner": owner,
+                "images": 0,
+                "total_bytes": 0,
+                "exclusive_bytes": 0,
+                "shared_bytes": 0,
+                "amortized_bytes": 0,
+            }
+
+    charges: List[OwnerCharge] = []
+    for owner, entry in current.items():
+        charge: OwnerCharge = {
+            "owner": owner,
+            "images": entry["images"],
+            "amortized_bytes": entry["amortized_bytes"],
+            "exclusive_bytes": entry["exclusive_bytes"],
+            "shared_bytes": entry["shared_bytes"],
+            "cost": storage_cost(entry["amortized_bytes"], cost_per_gb_month),
+            "previous_amortized_bytes": None,
+            "growth_bytes": None,
+            "growth_percent": None,
+            "quota_bytes": None,
+            "over_quota_bytes": None,
+        }
+        if previous_usage is not None:
+            before = previous.get(owner, 0)
+            charge["previous_amortized_bytes"] = before
+            charge["growth_bytes"] = entry["amortized_bytes"] - before
+            if before:
+                charge["growth_percent"] = round((entry["amortized_bytes"] - before) * 100 / before, 1)
+        quota_bytes = quota_for(owner, quotas) if quotas else 0
+        if quota_bytes:
+            charge["quota_bytes"] = quota_bytes
+            charge["over_quota_bytes"] = max(entry["amortized_bytes"] - quota_bytes, 0)
+        charges.append(charge)
+    return sorted(charges, key=lambda charge: (-charge["amortized_bytes"], charge["owner"]))
@@ -0,0 +1,177 @@
+"""
+Per-host circuit breaker for registry requests.
+
+Retries with backoff protect a single request, but a scan runs thousands of
+them across several workers: when a registry is overloaded or failing, every
+worker keeps retrying and the registry never gets a chance to recover.
+
+The circuit breaker watches the outcome of every request to a host. After
+skopeo.circuit_breaker.failure_threshold consecutive server errors (5xx),
+rate-limit responses (429) or timeouts, it opens: requests to that host wait
+instead of being sent. After the cooldown, one probe request is let through.
+If it succeeds the breaker closes and all waiting requests resume; if it fails
+the breaker opens again with twice the cooldown, up to max_cooldown.
+
+Requests are paused, never failed, so an open breaker slows a scan down but
+does not change its results.
+"""
+
+import re
+import threading
+import time
+from typing import Callable, Dict, Optional
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+CLOSED = "closed"
+OPEN = "open"
+HALF_OPEN = "half_open"
+
+
+class CircuitBreaker:
+    """Circuit breaker for one registry host."""
+
+    def __init__(
+        self,
+        host: str,
+        failure_threshold: int = 5,
+        cooldown: float = 30.0,
+        max_cooldown: float = 300.0,
+        clock: Callable[[], float] = time.monotonic,
+    ):
+        """Initialize the breaker
+
+        Args:
+            host: Registry host the breaker protects (used in log messages)
+            failure_threshold: Consecutive failures that open the breaker
+            cooldown: Seconds to pause requests the first time the breaker opens
+            max_cooldown: Longest pause after repeated failed probes
+            clock: Monotonic time source
+        """
+        self.host = host
+        self.failure_threshold = failure_threshold
+        self.initial_cooldown = cooldown
+        self.max_cooldown = max_cooldown
+        self._clock = clock
+        self._cond = threading.Condition()
+        self.state = CLOSED
+        self.consecutive_failures = 0
+        self.cooldown = cooldown
+        self.times_opened = 0
+        self._opened_at = 0.0
+        self._probe_started: Optional[float] = None
+
+    def before_request(self) -> None:
+        """Wait until a request may be sent to the host.
+
+        Returns immediately while the breaker is closed. While it is open, waits
+        for the cooldown to end; the first caller after that sends the probe
+        request and the others wait for its outcome.
+        """
+        with self._cond:
+            while True:
+                now = self._clock()
+                if self.state == CLOSED:
+                    return
+                if self.state == OPEN:
+                    remaining = self._opened_at + self.cooldown - now
+                    if remaining > 0:
+                        self._cond.wait(remaining)
+                        continue
+                    self.state = HALF_OPEN
+                    self._probe_started = now
+                    logger.info(f"Circuit breaker for {self.host}: cooldown over, sending a probe request")
+                    return
+                # Half-open: one probe at a time; a probe that never reported back
+                # (e.g. its caller crashed) is replaced after one cooldown
+                if self._probe_started is None or now - self._probe_started >= self.cooldown:
+                    self._probe_started = now
+                    return
+                self._cond.wait(self.cooldown)
+
+    def record_success(self) -> None:
+        """Record a request the registry answered normally (including 4xx other than 429)."""
+        with self._cond:
+            if self.state != CLOSED:
+                logger.info(f"Circuit breaker for {self.host} closed: registry is responding again, resuming requests")
+            self.state = CLOSED
+            self.consecutive_failures = 0
+            self.cooldown = self.initial_cooldown
+            self._probe_started = None
+            self._cond.notify_all()
+
+    def record_failure(self) -> None:
+        """Record a request that failed with a server error, rate limiting or a timeout."""
+        with self._cond:
+            self.consecutive_failures += 1
+            if self.state == HALF_OPEN:
+                self.cooldown = min(self.cooldown * 2, self.max_cooldown)
+                self._open()
+            elif self.state == CLOSED and self.consecutive_failures >= self.failure_threshold:
+                self._open()
+
+    def _open(self) -> None:
+        """Start pausing requests (caller holds the lock)."""
+        self.state = OPEN
+        self.times_opened += 1
+        self._opened_at = self._clock()
+        self._probe_started = None
+        logger.warning(
+            f"Circuit breaker for {self.host} open after {self.consecutive_failures} consecutive failures: "
+            f"pausing requests for {self.cooldown:.0f}s"
+        )
+        self._cond.notify_all()
+
+
+_breakers: Dict[str, CircuitBreaker] = {}
+_breakers_lock = threading.Lock()
+
+
+def host_key(registry_url: str) -> str:
+    """Host[:port] of a registry URL, with or without scheme."""
+    return registry_url.split("://", 1)[-1].split("/", 1)[0].lower()
+
+
+def get_circuit_breaker(registry_url: str) -> Optional[CircuitBreaker]:
+    """Get the shared circuit breaker of a registry host.
+
+    All clients talking to the same host share one breaker, so skopeo commands
+    and native HTTP requests pause together.
+
+    Returns:
+        The host's CircuitBreaker, or None if skopeo.circuit_breaker.enabled is false
+    """
+    from utils.config_manager import config_manager
+
+    if not config_manager.get_circuit_breaker_enabled():
+        return None
+    host = host_key(registry_url)
+    with _breakers_lock:
+        breaker = _breakers.get(host)
+        if breaker is None:
+            breaker = CircuitBreaker(
+                host,
+                failure_threshold=config_manager.get_circuit_breaker_failure_threshold(),
+                cooldown=config_manager.get_circuit_breaker_cooldown(),
+                max_cooldown=config_manager.get_circuit_breaker_max_cooldown(),
+            )
+            _breakers[host] = breaker
+        return breaker
+
+
+def is_overload_status(status: int) -> bool:
+    """Whether an HTTP status means the registry is overloaded or failing (429 or 5xx)."""
+    return status == 429 or 500 <= status < 600
+
+
+_OVERLOAD_PATTERN = re.compile(
+    r"\b(429|5\d\d)\b|too many requests|rate limit|service unavailable|bad gateway|gateway timeout"
+    r"|internal server error"
+)
+
+
+def is_overload_message(message: str) -> bool:
+    """Whether an error message (e.g. skopeo's stderr) reports a 429 or 5xx response."""
+    return bool(_OVERLOAD_PATTERN.search(message.lower()))
@@ -0,0 +1,294 @@
+"""
+Portable cleanup plan files.
+
+A cleanup plan separates deciding what to delete from actually deleting it, so
+that one person can produce a plan, another can review and approve it, and a
+third can apply it later. Plans are versioned JSON documents recording every
+target tag, the digest it pointed to when the plan was made, the expected bytes
+freed, and the provenance of the policy that selected it.
+
+Plans are applied in dependency order rather than item by item: a tag and
+its aliases (other tags of the same manifest in the plan) are deleted
+together, since deleting one deletes the manifest, and signatures,
+attestations and SBOMs attached to a manifest (referrers) are deleted before
+the manifest they describe (their subject), so none is left pointing at a
+deleted image. See apply_order.
+"""
+
+import getpass
+import json
+import uuid
+from dataclasses import asdict, dataclass, field
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional, Tuple
+
+from utils.blob_mount import BlobCopyStats, add_copy_stats, same_registry_prefix
+from utils.build_info import get_build_info
+from utils.logging_utils import get_logger
+from utils.report_utils import save_json
+from utils.request_stats import get_run_stats
+from utils.tag_matching import parse_reference_tag
+
+logger = get_logger(__name__)
+
+PLAN_FORMAT_VERSION = 1
+
+
+class PlanFormatError(ValueError):
+    """Raised when a plan file is malformed or has an unsupported version."""
+
+
+@dataclass
+class PlanItem:
+    """A single image scheduled for deletion by a plan."""
+
+    image_id: str  # "<type>:<tag>", e.g. "environment:507f...-3"
+    repository: str  # Full repository path, e.g. "dominodatalab/environment"
+    tag: str
+    digest: str  # Manifest digest the tag pointed to at planning time
+    size_bytes: int = 0  # Total size of all layers
+    expected_freed_bytes: int = 0  # Bytes freed if only this image were deleted
+    reason: str = ""
+    replicate_to: str = ""  # Archive registry to copy the image to before deleting it
+    subject_digest: str = ""  # For signatures, attestations and SBOMs: digest of the image they describe
+    pullable_from: List[str] = field(default_factory=list)  # Surviving images with the same content (zero user impact)
+
+
+@dataclass
+class PolicyProvenance:
+    """Where the selection in a plan came from."""
+
+    source: str  # e.g. "candidate_file", "unused_images"
+    description: str = ""
+    options: Dict[str, Any] = field(default_factory=dict)
+
+
+@dataclass
+class CleanupPlan:
+    """A reviewable, portable list of deletions."""
+
+    registry_url: str
+    repository: str
+    policy: PolicyProvenance
+    items: List[PlanItem] = field(default_factory=list)
+    expected_freed_bytes: int = 0  # Combined bytes freed, accounting for shared layers
+    owner_quotas: List[Dict[str, Any]] = field(default_factory=list)  # quotas.OwnerQuotaStatus at planning time
+    format_version: int = PLAN_FORMAT_VERSION
+    plan_id: str = field(default_factory=lambda: uuid.uuid4().hex)
+    created_at: str = field(default_factory=lambda: datetime.now(timezone.utc).isoformat())
+    created_by: str = field(default_factory=lambda: _current_user())
+
+    def to_dict(self) -> Dict[str, Any]:
+        """Serialize the plan to a JSON-compatible dict."""
+        data = asdict(self)
+        data["summary"] = {
+            "build": get_build_info(),
+            "runStats": get_run_stats(),
+            "total_items": len(self.items),
+            "expected_freed_bytes": self.expected_freed_bytes,
+            "expected_freed_gb": round(self.expected_freed_bytes / (1024**3), 2),
+        }
+        return data
+
+    @classmethod
+    def from_dict(cls, data: Dict[str, Any]) -> "CleanupPlan":
+        """Build a plan from a dict, validating its version and required fields.
+
+        Raises:
+            PlanFormatError: If the document is not a supported plan
+        """
+        if not isinstance(data, dict):
+            raise PlanFormatError("Plan must be a JSON object")
+
+        version = data.get("format_version")
+        if version != PLAN_FORMAT_VERSION:
+            raise PlanFormatError(
+                f"Unsupported plan format_version {version!r} (this version reads {PLAN_FORMAT_VERSION})"
+            )
+
+        for key in ("registry_url", "repository", "policy", "items"):
+            if key not in data:
+                raise PlanFormatError(f"Plan is missing required field '{key}'")
+
+        policy_data = data["policy"]
+        if not isinstance(policy_data, dict) or "source" not in policy_data:
+            raise PlanFormatError("Plan 'policy' must be an object with a 'source'")
+
+        items: List[PlanItem] = []
+        for index, item_data in enumerate(data["items"]):
+            try:
+                items.append(PlanItem(**item_data))
+            except TypeError as e:
+                raise PlanFormatError(f"Plan item {index} is invalid: {e}") from e
+            if not items[-1].digest:
+                raise PlanFormatError(f"Plan item {index} ({items[-1].image_id}) has no digest")
+
+        return cls(
+            registry_url=data["registry_url"],
+            repository=data["repository"],
+            policy=PolicyProvenance(**policy_data),
+            items=items,
+            expected_freed_bytes=int(data.get("expected_freed_bytes", 0)),
+            owner_quotas=list(data.get("owner_quotas") or []),
+            format_version=version,
+            plan_id=data.get("plan_id", ""),
+            created_at=data.get("created_at", ""),
+            created_by=data.get("created_by", ""),
+        )
+
+
+def _current_user() -> str:
+    """Best-effort name of the user creating a plan."""
+    try:
+        return getpass.getuser()
+    except Exception:
+        return "unknown"
+
+
+@dataclass
+class ApplyUnit:
+    """Plan items that are applied together: a manifest's tags and the artifacts referring to it."""
+
+    subjects: List[PlanItem]  # Tags of one manifest (aliases), deleted together; empty if only referrers
+    referrers: List[PlanItem] = field(default_factory=list)  # Deleted before the subjects
+
+    @property
+    def items(self) -> List[PlanItem]:
+        """Items in the order they are deleted"""
+        return self.referrers + self.subjects
+
+
+def item_subject(item: PlanItem) -> Optional[str]:
+    """Digest of the image a plan item refers to (signature, attestation, SBOM), or None"""
+    if item.subject_digest:
+        return item.subject_digest
+    reference = parse_reference_tag(item.tag)
+    return reference[0] if reference else None
+
+
+def apply_order(items: List[PlanItem]) -> List[ApplyUnit]:
+    """Group plan items into the units they are applied in, in dependency order.
+
+    Items with the same repository and digest are aliases and form one unit;
+    items referring to a digest in the plan join the unit of their subject, as
+    its referrers. Units keep the order of their first item in the plan, so a
+    plan's priorities (e.g. owners over quota first) are kept.
+
+    Args:
+        items: Plan items, in plan order
+
+    Returns:
+        The units to apply, in order
+    """
+    units: Dict[Tuple[str, str], ApplyUnit] = {}
+    order: List[Tuple[str, str]] = []
+    subjects = {(item.repository, item.digest) for item in items if item_subject(item) is None}
+    for item in items:
+        subject = item_subject(item)
+        if subject is not None and (item.repository, subject) in subjects:
+            key = (item.repository, subject)
+        else:
+            key = (item.repository, item.digest)
+        if key not in units:
+            units[key] = ApplyUnit(subjects=[])
+            order.append(key)
+        if subject is not None and key[1] == subject:
+            units[key].referrers.append(item)
+        else:
+            units[key].subjects.append(item)
+    return [units[key] for key in order]
+
+
+def check_item_digest(item: PlanItem, current_digest: Optional[str]) -> Optional[str]:
+    """Check that a plan item's tag still points to the digest recorded in the plan.
+
+    Args:
+        item: Plan item to verify
+        current_digest: Digest the tag points to now, or None if the tag no longer exists
+
+    Returns:
+        A reason the item must not be deleted, or None if the digest still matches
+    """
+    if not current_digest:
+        return "tag no longer exists in registry"
+    if current_digest != item.digest:
+        return f"tag was re-pushed since planning (plan: {item.digest}, registry: {current_digest})"
+    return None
+
+
+def replicate_item(
+    item: PlanItem, skopeo_client: Any, archive_client: Any, copy_stats: Optional[BlobCopyStats] = None
+) -> Optional[str]:
+    """Copy a plan item to its archive registry and verify the copy.
+
+    An archive on the same registry host (e.g. "registry:5000/archive") gets
+    the image by mounting its blobs instead of uploading them again. The copy
+    is verified by reading back the digest its tag points to in the archive,
+    which must equal the digest recorded in the plan.
+
+    Args:
+        item: Plan item with replicate_to set
+        skopeo_client: SkopeoClient of the registry the plan deletes from
+        archive_client: SkopeoClient of item.replicate_to
+        copy_stats: Totals to add the blobs mounted and transferred to, for copies within the registry
+
+    Returns:
+        A reason the item must not be deleted, or None if the archive holds a verified copy
+    """
+    prefix = same_registry_prefix(skopeo_client.registry_url, item.replicate_to)
+    try:
+        if prefix is not None:
+            destination_repository = f"{prefix}/{item.repository}"
+            stats = skopeo_client.copy_image_within_registry(
+                item.repository, item.digest, destination_repository, item.tag
+            )
+            if stats is None:
+                return f"copy to {item.replicate_to} failed"
+            if copy_stats is not None:
+                add_copy_stats(copy_stats, stats)
+        elif not skopeo_client.copy_image(item.repository, item.digest, item.replicate_to, item.tag):
+            return f"copy to {item.replicate_to} failed"
+        archived_digest = archive_client.get_manifest_digest(item.repository, item.tag)
+    except Exception as e:
+        return f"copy to {item.replicate_to} failed: {e}"
+    if archived_digest != item.digest:
+        return f"copy in {item.replicate_to} could not be verified (plan: {item.digest}, archive: {archived_digest})"
+    return None
+
+
+def save_plan(plan: CleanupPlan, path: str, timestamp: bool = False) -> str:
+    """Write a plan to disk as JSON.
+
+    Args:
+        plan: Plan to save
+        path: Destination file path
+        timestamp: If True, add a timestamp to the filename
+
+    Returns:
+        Path the plan was written to
+    """
+    # Plans stay uncompressed, for reviewers to read and edit
+    saved_path = save_json(path, plan.to_dict(), timestamp=timestamp, compress="")
+    logger.info(f"Plan {plan.plan_id} with {len(plan.items)} item(s) saved to {saved_path}")
+    return saved_path
+
+
+def load_plan(path: str) -> CleanupPlan:
+    """Load and validate a plan file.
+
+    Args:
+        path: Plan file path
+
+    Returns:
+        The loaded CleanupPlan
+
+    Raises:
+        PlanFormatError: If the file is not valid JSON or not a supported plan
+    """
+    try:
+        with open(path, "r") as f:
+            data = json.load(f)
+    except json.JSONDecodeError as e:
+        raise PlanFormatError(f"Plan file '{path}' is not valid JSON: {e}") from e
+    return CleanupPlan.from_dict(data)
+
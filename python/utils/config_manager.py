@@ -10,10 +10,41 @@ import base64
 import logging
 import os
 import re
-from typing import Any, Dict, Optional
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional, Pattern
+from zoneinfo import ZoneInfo, ZoneInfoNotFoundError
 
 import yaml
 
+from utils.cron import CronExpression
+from utils.deletion_candidates import DEFAULT_SCORE_WEIGHTS
+from utils.image_expiry import DEFAULT_EXPIRY_KEYS
+from utils.profiles import (
+    BUILTIN_PROFILES,
+    DOMINO_PROFILE,
+    ProfileError,
+    profile_config,
+    profile_drift,
+    render_registry_url,
+    resolve_profile,
+)
+from utils.protection_providers import PROTECTION_PROVIDERS
+from utils.redaction import DEFAULT_REDACT_KEYS
+from utils.tag_matching import DEFAULT_EXCLUDED_TAG_PATTERNS, DEFAULT_FLOATING_TAGS
+from utils.toolchain import DEFAULT_MIN_DOCKER_VERSION, DEFAULT_OUTDATED_BASE_IMAGES, parse_version
+
+# Credential profile methods, and the settings each one accepts (required settings first)
+CREDENTIAL_METHODS = {
+    "ecr": ([], ["role_arn", "external_id", "region"]),
+    "acr": ([], ["client_id"]),
+    "secret": (["secret"], ["namespace"]),
+    "env": (["username_env", "password_env"], []),
+    "anonymous": ([], []),
+}
+
+# Phases the API server can run on a cron schedule, in the order they build on each other
+SCHEDULE_PHASES = ("scan", "plan", "apply", "mirror")
+
 
 class ConfigValidationError(Exception):
     """Raised when configuration validation fails"""
@@ -70,12 +101,48 @@ class ConfigManager:
             self.validate_config()
 
     def _load_config(self) -> Dict[str, Any]:
-        """Load configuration from YAML file with defaults"""
+        """Load configuration from YAML file with defaults, the selected profile's under those of the file"""
         default_config = {
-            "registry": {"url": "docker-registry:5000", "repository": "dominodatalab"},
+            # url, repository and image_types come from the profile
+            "registry": {},
+            "credentials": {},
             "kubernetes": {"domino_platform_namespace": "domino-platform"},
-            "mongo": {"host": "mongodb-replicaset", "port": 27017, "replicaset": "rs0", "db": "domino"},
-            "analysis": {"max_workers": 4, "timeout": 300, "output_dir": "reports"},
+            "mongo": {
+                "host": "mongodb-replicaset",
+                "port": 27017,
+                "replicaset": "rs0",
+                "db": "domino",
+                "reference_scan": {"enabled": False, "fields": []},
+            },
+            "analysis": {
+                "max_workers": 4,
+                "timeout": 300,
+                "output_dir": "reports",
+                "disk_index_threshold": 100000,
+                "exclude_tags": [],
+                "floating_tags": [],
+                "collect_annotations": True,
+                "collect_provenance": False,
+                "retry_failed_tags": True,
+                "secret_patterns": {},
+                "owner_labels": ["owner", "team"],
+                "expiry_keys": list(DEFAULT_EXPIRY_KEYS),
+                "recent_run_protection_days": 0,
+                "pull_link_speed_mbps": 1000,
+                "storage_cost_per_gb_month": 0.023,
+                "candidate_weights": dict(DEFAULT_SCORE_WEIGHTS),
+                "oversized_layers": {"threshold_gb": 5, "fail_run": False},
+                "toolchain": {
+                    "min_docker_version": DEFAULT_MIN_DOCKER_VERSION,
+                    "outdated_base_images": list(DEFAULT_OUTDATED_BASE_IMAGES),
+                },
+                "contents": {
+                    "max_layers": 50,
+                    "max_layer_mb": 512,
+                    "max_entries_per_layer": 200000,
+                    "hash_max_file_mb": 64,
+                },
+            },
             "retry": {
                 "max_retries": 3,
                 "initial_delay": 1.0,
@@ -85,16 +152,27 @@ class ConfigManager:
                 "timeout": 300,
             },
             "s3": {"bucket": "", "region": "us-west-2"},
+            "registry_storage": {"path": "", "s3_bucket": "", "prefix": "docker/registry/v2"},
+            "mirror": {"destination": ""},
             "skopeo": {
+                "client": "skopeo",
                 "rate_limit": {
                     "enabled": True,
                     "requests_per_second": 10.0,
                     "burst_size": 20,
                 },
+                "circuit_breaker": {
+                    "enabled": True,
+                    "failure_threshold": 5,
+                    "cooldown": 30,
+                    "max_cooldown": 300,
+                },
+                "host_limits": {"max_concurrent_requests": 0, "hosts": {}},
             },
             "reports": {
                 "archived_tags": "archived-tags.json",
                 "deletion_analysis": "deletion-analysis.json",
+                "eol_bases": "eol-bases.json",
                 "unused_environments": "unused-environments.json",
                 "old_revisions": "old-revisions.json",
                 "deactivated_user_envs": "deactivated-user-envs.json",
@@ -102,14 +180,56 @@ class ConfigManager:
                 "image_analysis": "final-report.json",
                 "images_report": "images-report",
                 "layers_and_sizes": "layers-and-sizes.json",
+                "layer_contents": "layer-contents.json",
+                "layer_image_map": "layer-image-map.json",
+                "partial_report": "scan-partial.json",
+                "repos_report": "repos-report.json",
+                "secret_findings": "secret-findings.json",
+                "snapshot": "scan-snapshot.json",
+                "snapshot_retention": {"keep_last": 0, "keep_weekly": 0, "keep_monthly": 0},
                 "tags_per_layer": "tags-per-layer.json",
                 "tag_sums": "tag-sums.json",
                 "unused_references": "unused-references.json",
                 "mongodb_usage": "mongodb_usage_report.json",
+                "upload_url": "",
+                "compress": "",
+                "anonymize_salt": "",
+                "timezone": "UTC",
+                "redact_keys": list(DEFAULT_REDACT_KEYS),
+            },
+            "security": {
+                "dry_run_by_default": True,
+                "require_confirmation": True,
+                "content_trust": {"notary_url": "", "on_signed_tag": "refuse"},
+                "deletion_delay_hours": 0,
+                "read_only": False,
+            },
+            "protection": {
+                "providers": [],
+                "reference_file": "",
+                "kubernetes_namespaces": [],
+                "ecr_pull_days": 30,
+            },
+            "schedule": {"scan": "", "plan": "", "apply": ""},
+            "registry_events": {
+                "enabled": False,
+                "image_types": [],
+                "snapshot_interval_minutes": 15,
+            },
+            "alerting": {
+                "pagerduty": {"routing_key": ""},
+                "opsgenie": {"api_key": "", "api_url": "https://api.opsgenie.com"},
+                "on_failure": True,
+                "max_error_rate": 0,
+                "max_reclaimable_gb": 0,
+            },
+            "quotas": {"default_gb": 0, "owners": {}, "prioritize_plans": False},
+            "metrics": {
+                "statsd": {"host": "", "port": 8125, "prefix": "registry_cleaner", "dogstatsd": True, "tags": {}},
             },
-            "security": {"dry_run_by_default": True, "require_confirmation": True},
             "cache": {
                 "enabled": True,
+                "incremental_scan": True,
                 "tag_list_ttl": 1800,
                 "tag_list_max_size": 100,
                 "image_inspect_ttl": 3600,
@@ -121,17 +241,40 @@ class ConfigManager:
             },
         }
 
+        user_config: Dict[str, Any] = {}
         try:
             if os.path.exists(self.config_file):
                 with open(self.config_file, "r") as f:
                     user_config = yaml.safe_load(f) or {}
-                return self._merge_config(default_config, user_config)
             else:
                 logging.warning(f"Config file {self.config_file} not found, using defaults")
-                return default_config
         except Exception as e:
             logging.error(f"Error loading config file: {e}")
-            return default_config
+        default_config = self._merge_config(default_config, profile_config(self._load_profile(user_config)))
+        return self._merge_config(default_config, user_config)
+
+    def _load_profile(self, user_config: Dict[str, Any]) -> Dict[str, Any]:
+        """Resolve the selected profile (CONFIG_PROFILE env var or --profile, then profile in config.yaml)
+
+        An invalid profile falls back to the built-in one and is reported by get_profile.
+        """
+        selected = os.environ.get("CONFIG_PROFILE") or user_config.get("profile")
+        self.profile_name = str(selected or DOMINO_PROFILE)
+        self.profile_error: Optional[str] = None
+        try:
+            self.profile = resolve_profile(self.profile_name, user_config.get("profiles"))
+        except ProfileError as e:
+            self.profile_error = str(e)
+            self.profile = dict(BUILTIN_PROFILES[DOMINO_PROFILE])
+        # Overriding the implicit default profile is how most installs configure the cleaner; only drift
+        # from a profile someone chose is worth a warning
+        self.profile_drift = profile_drift(self.profile_name, self.profile, user_config) if selected else []
+        return self.profile
+
+    def select_profile(self, name: str) -> None:
+        """Reload the configuration with another profile (--profile); scripts inherit it through CONFIG_PROFILE"""
+        os.environ["CONFIG_PROFILE"] = name
+        self.config = self._load_config()
 
     def _merge_config(self, default: Dict[str, Any], user: Dict[str, Any]) -> Dict[str, Any]:
         """Recursively merge user config with defaults"""
@@ -145,17 +288,70 @@ class ConfigManager:
 
     # Registry configuration
     def get_registry_url(self) -> str:
-        """Get registry URL from environment or config"""
-        return os.environ.get("REGISTRY_URL") or self.config["registry"]["url"]
+        """Get registry URL from environment or config ({namespace} is the Domino platform namespace)"""
+        url = os.environ.get("REGISTRY_URL") or self.config["registry"]["url"]
+        return render_registry_url(url, self.get_domino_platform_namespace())
 
     def get_repository(self) -> str:
         """Get canonical repository value."""
         return os.environ.get("REPOSITORY") or self.config["registry"]["repository"]
 
+    def get_image_types(self) -> List[str]:
+        """Get the image types under the repository scripts work on by default (default: the profile's)"""
+        image_types = self.config["registry"].get("image_types")
+        if not isinstance(image_types, list) or not image_types or not all(isinstance(t, str) for t in image_types):
+            raise ConfigValidationError(f"registry.image_types must be a list of image types, got: {image_types}")
+        return list(image_types)
+
+    def get_profile(self) -> str:
+        """Get the name of the defaults profile in use (see utils/profiles.py)"""
+        if self.profile_error:
+            raise ConfigValidationError(self.profile_error)
+        return self.profile_name
+
     def get_registry_auth_secret(self) -> Optional[str]:
         """Get the name of a custom Kubernetes secret for registry authentication."""
         return os.environ.get("REGISTRY_AUTH_SECRET")
 
+    def get_credential_profile(self, registry_url: str) -> Optional[Dict[str, str]]:
+        """Get the credential profile configured for a registry host.
+
+        credentials maps registry hosts to a profile with a "method" (see
+        CREDENTIAL_METHODS) and its settings. A host listed without a port also
+        matches the host on any port.
+
+        Returns:
+            The profile, or None if the host has none and the default credential chain applies
+        """
+        credentials = self.config.get("credentials") or {}
+        if not isinstance(credentials, dict):
+            raise ConfigValidationError(f"credentials must be a mapping of registry host to profile: {credentials}")
+        profiles = {str(host).lower(): profile for host, profile in credentials.items()}
+        host = registry_url.split("://", 1)[-1].split("/", 1)[0].lower()
+        name = next((name for name in (host, host.split(":", 1)[0]) if name in profiles), None)
+        if name is None:
+            return None
+
+        profile = profiles[name]
+        if not isinstance(profile, dict):
+            raise ConfigValidationError(f"credentials.{name} must be a mapping with a method, got: {profile}")
+        method = profile.get("method")
+        if method not in CREDENTIAL_METHODS:
+            raise ConfigValidationError(
+                f"credentials.{name}.method must be one of {', '.join(CREDENTIAL_METHODS)}, got: {method}"
+            )
+        required, optional = CREDENTIAL_METHODS[method]
+        unknown = sorted(set(profile) - {"method", *required, *optional})
+        if unknown:
+            raise ConfigValidationError(f"credentials.{name}: unknown setting(s) {unknown} for method {method}")
+        missing = [key for key in required if not profile.get(key)]
+        if missing:
+            raise ConfigValidationError(f"credentials.{name}: method {method} requires {', '.join(missing)}")
+        role_arn = profile.get("role_arn")
+        if role_arn and not re.match(r"^arn:aws[\w-]*:iam::\d{12}:role/.+$", str(role_arn)):
+            raise ConfigValidationError(f"credentials.{name}.role_arn is not an IAM role ARN: {role_arn}")
+        return {key: str(value) for key, value in profile.items() if value is not None}
+
     # Kubernetes configuration
     def get_domino_platform_namespace(self) -> str:
         """Get Domino Platform namespace from environment or config"""
@@ -180,9 +376,208 @@ class ConfigManager:
         except (ValueError, TypeError):
             raise ConfigValidationError(f"timeout must be an integer, got: {timeout} (type: {type(timeout).__name__})")
 
+    def get_disk_index_threshold(self) -> int:
+        """Get the tag count above which image analysis uses an on-disk index (0 disables it)"""
+        threshold = self.config["analysis"].get("disk_index_threshold", 100000)
+        try:
+            return int(threshold)
+        except (ValueError, TypeError):
+            raise ConfigValidationError(
+                f"disk_index_threshold must be an integer, got: {threshold} (type: {type(threshold).__name__})"
+            )
+
+    def get_excluded_tag_patterns(self) -> List[str]:
+        """Get tag patterns skipped by image analysis: the built-in defaults plus analysis.exclude_tags"""
+        patterns = self.config["analysis"].get("exclude_tags") or []
+        if not isinstance(patterns, list) or not all(isinstance(p, str) for p in patterns):
+            raise ConfigValidationError(f"analysis.exclude_tags must be a list of strings, got: {patterns}")
+        return DEFAULT_EXCLUDED_TAG_PATTERNS + [p for p in patterns if p not in DEFAULT_EXCLUDED_TAG_PATTERNS]
+
+    def get_tag_naming_pattern(self) -> Pattern[str]:
+        """Get the regular expression naming_audit expects tags to match (default: the profile's tag scheme)"""
+        pattern = self.config["analysis"].get("tag_naming_pattern") or self.profile["tag_naming_pattern"]
+        try:
+            return re.compile(str(pattern))
+        except re.error as e:
+            raise ConfigValidationError(f"analysis.tag_naming_pattern is not a valid regular expression: {e}")
+
+    def get_floating_tag_patterns(self) -> List[str]:
+        """Get tag patterns mutable_tags_report treats as floating: the built-in names plus analysis.floating_tags"""
+        patterns = self.config["analysis"].get("floating_tags") or []
+        if not isinstance(patterns, list) or not all(isinstance(p, str) for p in patterns):
+            raise ConfigValidationError(f"analysis.floating_tags must be a list of strings, got: {patterns}")
+        return DEFAULT_FLOATING_TAGS + [p for p in patterns if p not in DEFAULT_FLOATING_TAGS]
+
+    def get_recent_run_protection_days(self) -> int:
+        """Get the number of days within which any run or workspace use protects an image (0 = disabled)"""
+        days = self.config["analysis"].get("recent_run_protection_days", 0)
+        if isinstance(days, bool) or not isinstance(days, int) or days < 0:
+            raise ConfigValidationError(
+                f"analysis.recent_run_protection_days must be a non-negative integer, got: {days}"
+            )
+        return days
+
+    def is_annotation_collection_enabled(self) -> bool:
+        """Get whether image analysis reads the OCI annotations of each tag's manifest"""
+        enabled = self.config["analysis"].get("collect_annotations", True)
+        if not isinstance(enabled, bool):
+            raise ConfigValidationError(f"analysis.collect_annotations must be true or false, got: {enabled}")
+        return enabled
+
+    def is_provenance_collection_enabled(self) -> bool:
+        """Get whether image analysis reads the SLSA provenance attestations of each image"""
+        enabled = self.config["analysis"].get("collect_provenance", False)
+        if not isinstance(enabled, bool):
+            raise ConfigValidationError(f"analysis.collect_provenance must be true or false, got: {enabled}")
+        return enabled
+
+    def is_failed_tag_retry_enabled(self) -> bool:
+        """Get whether image analysis inspects tags that failed transiently again after the main pass"""
+        enabled = self.config["analysis"].get("retry_failed_tags", True)
+        if not isinstance(enabled, bool):
+            raise ConfigValidationError(f"analysis.retry_failed_tags must be true or false, got: {enabled}")
+        return enabled
+
+    def get_secret_patterns(self) -> Dict[str, str]:
+        """Get the extra secret patterns (rule name -> regex) checked besides the built-in ones (see utils.secret_scan)"""
+        patterns = self.config["analysis"].get("secret_patterns") or {}
+        if not isinstance(patterns, dict):
+            raise ConfigValidationError(f"analysis.secret_patterns must be a mapping of name to regex, got: {patterns}")
+        for name, pattern in patterns.items():
+            try:
+                re.compile(pattern)
+            except (re.error, TypeError) as e:
+                raise ConfigValidationError(f"analysis.secret_patterns.{name} is not a valid regex: {e}")
+        return {str(name): pattern for name, pattern in patterns.items()}
+
+    def get_owner_label_keys(self) -> List[str]:
+        """Get the image labels naming an image's owner, in order of preference"""
+        keys = self.config["analysis"].get("owner_labels")
+        if not isinstance(keys, list) or not keys or not all(isinstance(key, str) and key for key in keys):
+            raise ConfigValidationError(f"analysis.owner_labels must be a non-empty list of label keys, got: {keys}")
+        return keys
+
+    def get_expiry_keys(self) -> List[str]:
+        """Get the annotations and labels recording when an image expires, in order of preference ([] = off)"""
+        keys = self.config["analysis"].get("expiry_keys", DEFAULT_EXPIRY_KEYS)
+        if not isinstance(keys, list) or not all(isinstance(key, str) and key for key in keys):
+            raise ConfigValidationError(f"analysis.expiry_keys must be a list of annotation or label keys, got: {keys}")
+        return keys
+
+    def get_pull_link_speed_mbps(self) -> float:
+        """Get the link speed (megabits per second) pull_time_report estimates cold pulls with"""
+        speed = self.config["analysis"].get("pull_link_speed_mbps", 1000)
+        try:
+            mbps = float(speed)
+        except (ValueError, TypeError):
+            raise ConfigValidationError(f"analysis.pull_link_speed_mbps must be a number, got: {speed}")
+        if mbps <= 0:
+            raise ConfigValidationError(f"analysis.pull_link_speed_mbps must be greater than 0, got: {speed}")
+        return mbps
+
+    def get_storage_cost_per_gb_month(self) -> float:
+        """Get the storage price per GB and month chargeback_report bills owners with"""
+        cost = self.config["analysis"].get("storage_cost_per_gb_month", 0.023)
+        try:
+            price = float(cost)
+        except (ValueError, TypeError):
+            raise ConfigValidationError(f"analysis.storage_cost_per_gb_month must be a number, got: {cost}")
+        if price < 0:
+            raise ConfigValidationError(f"analysis.storage_cost_per_gb_month must not be negative, got: {cost}")
+        return price
+
+    def get_oversized_layer_policy(self) -> Optional[Dict[str, Any]]:
+        """Get the per-layer size above which image analysis flags a layer and its images.
+
+        Returns:
+            Dict with threshold_bytes and fail_run, or None if threshold_gb is 0 (off)
+        """
+        policy = self.config["analysis"].get("oversized_layers") or {}
+        if not isinstance(policy, dict):
+            raise ConfigValidationError(f"analysis.oversized_layers must be a mapping, got: {policy}")
+        threshold = policy.get("threshold_gb", 5)
+        if isinstance(threshold, bool) or not isinstance(threshold, (int, float)) or threshold < 0:
+            raise ConfigValidationError(
+                f"analysis.oversized_layers.threshold_gb must be a non-negative number of GB, got: {threshold}"
+            )
+        fail_run = policy.get("fail_run", False)
+        if not isinstance(fail_run, bool):
+            raise ConfigValidationError(f"analysis.oversized_layers.fail_run must be true or false, got: {fail_run}")
+        if not threshold:
+            return None
+        return {"threshold_bytes": int(threshold * 1024**3), "fail_run": fail_run}
+
+    def get_toolchain_policy(self) -> Dict[str, Any]:
+        """Get what counts as an outdated build toolchain (see utils.toolchain).
+
+        Returns:
+            Dict with min_docker_version and outdated_base_images (shell-style patterns)
+        """
+        policy = self.config["analysis"].get("toolchain") or {}
+        if not isinstance(policy, dict):
+            raise ConfigValidationError(f"analysis.toolchain must be a mapping, got: {policy}")
+        min_version = str(policy.get("min_docker_version", DEFAULT_MIN_DOCKER_VERSION))
+        if parse_version(min_version) is None:
+            raise ConfigValidationError(
+                f"analysis.toolchain.min_docker_version must be a version like 18.09, got: {min_version}"
+            )
+        patterns = policy.get("outdated_base_images", DEFAULT_OUTDATED_BASE_IMAGES)
+        if patterns is None:
+            patterns = []
+        if not isinstance(patterns, list) or not all(isinstance(pattern, str) and pattern for pattern in patterns):
+            raise ConfigValidationError(
+                f"analysis.toolchain.outdated_base_images must be a list of image patterns, got: {patterns}"
+            )
+        return {"min_docker_version": min_version, "outdated_base_images": patterns}
+
+    def get_layer_contents_limits(self) -> Dict[str, int]:
+        """Get the safeguards of a layer contents scan (see utils.layer_contents).
+
+        Returns:
+            Dict with max_layers, max_layer_mb, max_entries_per_layer and hash_max_file_mb
+        """
+        defaults = {"max_layers": 50, "max_layer_mb": 512, "max_entries_per_layer": 200000, "hash_max_file_mb": 64}
+        limits = self.config["analysis"].get("contents") or {}
+        if not isinstance(limits, dict):
+            raise ConfigValidationError(f"analysis.contents must be a mapping, got: {limits}")
+        result = {}
+        for key, default in defaults.items():
+            value = limits.get(key, default)
+            if isinstance(value, bool) or not isinstance(value, int) or value < 0:
+                raise ConfigValidationError(f"analysis.contents.{key} must be a non-negative integer, got: {value}")
+            result[key] = value
+        return result
+
+    def get_candidate_score_weights(self) -> Dict[str, float]:
+        """Get the weights candidates_report scores each factor with (analysis.candidate_weights)"""
+        weights = self.config["analysis"].get("candidate_weights") or {}
+        if not isinstance(weights, dict):
+            raise ConfigValidationError(f"analysis.candidate_weights must be a mapping, got: {weights}")
+        unknown = sorted(set(weights) - set(DEFAULT_SCORE_WEIGHTS))
+        if unknown:
+            raise ConfigValidationError(
+                f"Unknown analysis.candidate_weights factor(s): {', '.join(unknown)} "
+                f"(expected: {', '.join(DEFAULT_SCORE_WEIGHTS)})"
+            )
+        result = dict(DEFAULT_SCORE_WEIGHTS)
+        for name, weight in weights.items():
+            try:
+                result[name] = float(weight)
+            except (ValueError, TypeError):
+                raise ConfigValidationError(f"analysis.candidate_weights.{name} must be a number, got: {weight}")
+            if result[name] < 0:
+                raise ConfigValidationError(f"analysis.candidate_weights.{name} must not be negative, got: {weight}")
+        if not any(result.values()):
+            raise ConfigValidationError("analysis.candidate_weights must give at least one factor a positive weight")
+        return result
+
     def get_output_dir(self) -> str:
         """Get output directory from config"""
-        return self.config["analysis"]["output_dir"]
+        return os.environ.get("OUTPUT_DIR") or self.config["analysis"]["output_dir"]
+
+    def get_inspect_cache_path(self) -> str:
+        """Get path of the persistent inspect cache (under the output directory)"""
+        return os.path.join(self.get_output_dir(), ".cache", "inspect-cache.json")
 
     # Retry configuration
     def get_max_retries(self) -> int:
@@ -244,6 +639,10 @@ class ConfigManager:
         """Get cache enabled setting from config"""
         return self.config.get("cache", {}).get("enabled", True)
 
+    def is_incremental_scan_enabled(self) -> bool:
+        """Get whether scans skip full inspection of tags whose digest is already cached"""
+        return self.config.get("cache", {}).get("incremental_scan", True)
+
     def get_cache_tag_list_ttl(self) -> int:
         """Get tag list cache TTL from config, with type coercion"""
         ttl = self.config.get("cache", {}).get("tag_list_ttl", 1800)
@@ -275,6 +674,30 @@ class ConfigManager:
         return os.environ.get("S3_REGION") or self.config.get("s3", {}).get("region", "us-west-2")
 
     # Skopeo rate limiting configuration
+    # Registry storage configuration (read-only access for orphan scans)
+    def get_registry_storage_path(self) -> Optional[str]:
+        """Get the mounted registry storage root directory from config"""
+        return self.config.get("registry_storage", {}).get("path") or None
+
+    def get_registry_storage_bucket(self) -> Optional[str]:
+        """Get the S3 bucket the registry stores its data in from config"""
+        return self.config.get("registry_storage", {}).get("s3_bucket") or None
+
+    def get_registry_storage_prefix(self) -> str:
+        """Get the path of the registry data below the storage root"""
+        return self.config.get("registry_storage", {}).get("prefix") or "docker/registry/v2"
+
+    def get_mirror_destination(self) -> Optional[str]:
+        """Get the secondary registry mirror copies kept images to"""
+        return self.config.get("mirror", {}).get("destination") or None
+
+    def get_registry_client(self) -> str:
+        """Get how registry requests are made: "skopeo" (a skopeo command each) or "native" (HTTP requests)"""
+        client = os.environ.get("REGISTRY_CLIENT") or self.config.get("skopeo", {}).get("client") or "skopeo"
+        if client not in ("skopeo", "native"):
+            raise ConfigValidationError(f"skopeo.client must be skopeo or native, got: {client}")
+        return client
+
     def get_skopeo_rate_limit_enabled(self) -> bool:
         """Get whether rate limiting is enabled for Skopeo operations"""
         return self.config.get("skopeo", {}).get("rate_limit", {}).get("enabled", True)
@@ -287,6 +710,53 @@ class ConfigManager:
         """Get burst size for Skopeo rate limiting"""
         return int(self.config.get("skopeo", {}).get("rate_limit", {}).get("burst_size", 20))
 
+    def get_circuit_breaker_enabled(self) -> bool:
+        """Get whether registry requests pause when a registry keeps failing"""
+        return self.config.get("skopeo", {}).get("circuit_breaker", {}).get("enabled", True)
+
+    def _get_circuit_breaker_number(self, key: str, default: float, minimum: float) -> float:
+        """Get a numeric skopeo.circuit_breaker setting"""
+        value = self.config.get("skopeo", {}).get("circuit_breaker", {}).get(key, default)
+        try:
+            number = float(value)
+        except (ValueError, TypeError):
+            raise ConfigValidationError(f"skopeo.circuit_breaker.{key} must be a number, got: {value}")
+        if number < minimum:
+            raise ConfigValidationError(f"skopeo.circuit_breaker.{key} must be at least {minimum:g}, got: {value}")
+        return number
+
+    def get_circuit_breaker_failure_threshold(self) -> int:
+        """Get the consecutive registry failures (5xx, 429, timeouts) that pause requests"""
+        return int(self._get_circuit_breaker_number("failure_threshold", 5, 1))
+
+    def get_circuit_breaker_cooldown(self) -> float:
+        """Get seconds requests pause the first time the circuit breaker opens"""
+        return self._get_circuit_breaker_number("cooldown", 30, 0)
+
+    def get_circuit_breaker_max_cooldown(self) -> float:
+        """Get the longest pause after repeated failed probe requests"""
+        return max(self._get_circuit_breaker_number("max_cooldown", 300, 0), self.get_circuit_breaker_cooldown())
+
+    def get_host_concurrency_limit(self, host: str) -> int:
+        """Get the most requests in flight to a registry host at once (0 = unlimited).
+
+        Args:
+            host: Registry host[:port]; a limit for the host without its port also applies
+        """
+        limits = self.config.get("skopeo", {}).get("host_limits", {}) or {}
+        hosts = limits.get("hosts") or {}
+        if not isinstance(hosts, dict):
+            raise ConfigValidationError(f"skopeo.host_limits.hosts must be a mapping of host to limit, got: {hosts}")
+        hosts = {str(name).lower(): value for name, value in hosts.items()}
+        name = next((name for name in (host.lower(), host.lower().split(":", 1)[0]) if name in hosts), None)
+        if name is not None:
+            key, value = f"skopeo.host_limits.hosts.{name}", hosts[name]
+        else:
+            key, value = "skopeo.host_limits.max_concurrent_requests", limits.get("max_concurrent_requests", 0)
+        if isinstance(value, bool) or not isinstance(value, int) or value < 0:
+            raise ConfigValidationError(f"{key} must be a non-negative integer, got: {value}")
+        return value
+
     # Report configuration
     def _resolve_report_path(self, path: str) -> str:
         """Resolve report file path under the configured output_dir unless absolute."""
@@ -329,6 +799,51 @@ class ConfigManager:
             return base
         return os.path.join(self.get_output_dir(), base)
 
+    def get_repos_report_path(self) -> str:
+        """Get per-repository summary report path from config"""
+        return self._resolve_report_path(self.config["reports"].get("repos_report", "repos-report.json"))
+
+    def get_layer_image_map_path(self) -> str:
+        """Get path of the combined layers-to-images and images-to-layers report (image_data_analysis --mode both)"""
+        return self._resolve_report_path(self.config["reports"].get("layer_image_map", "layer-image-map.json"))
+
+    def get_layer_contents_report_path(self) -> str:
+        """Get the path the layer contents report (image_data_analysis --contents) is written to"""
+        return self._resolve_report_path(self.config["reports"].get("layer_contents", "layer-contents.json"))
+
+    def get_secret_findings_report_path(self) -> str:
+        """Get the path possible secrets found in image metadata are written to"""
+        return self._resolve_report_path(self.config["reports"].get("secret_findings", "secret-findings.json"))
+
+    def get_eol_bases_report_path(self) -> str:
+        """Get the path the images running on end-of-life OS releases are written to"""
+        return self._resolve_report_path(self.config["reports"].get("eol_bases", "eol-bases.json"))
+
+    def get_partial_report_path(self) -> str:
+        """Get the path partial results of a scan in progress are written to"""
+        return self._resolve_report_path(self.config["reports"].get("partial_report", "scan-partial.json"))
+
+    def get_snapshot_path(self) -> str:
+        """Get saved scan snapshot path from config"""
+        return self._resolve_report_path(self.config["reports"].get("snapshot", "scan-snapshot.json"))
+
+    def get_snapshot_retention(self) -> Dict[str, int]:
+        """Get how many saved scan snapshots to keep.
+
+        Returns:
+            Dict with keep_last, keep_weekly and keep_monthly (all 0 keeps every snapshot)
+        """
+        retention = self.config["reports"].get("snapshot_retention") or {}
+        result = {}
+        for key in ("keep_last", "keep_weekly", "keep_monthly"):
+            value = retention.get(key, 0)
+            if isinstance(value, bool) or not isinstance(value, int) or value < 0:
+                raise ConfigValidationError(
+                    f"reports.snapshot_retention.{key} must be a non-negative integer, got: {value}"
+                )
+            result[key] = value
+        return result
+
     def get_archived_tags_report_path(self) -> str:
         """Get archived tags report path from config"""
         return self._resolve_report_path(self.config["reports"]["archived_tags"])
@@ -353,6 +868,53 @@ class ConfigManager:
         """Get archived model tags report path from config"""
         return self._resolve_report_path(self.config["reports"]["archived_model_tags"])
 
+    def get_report_upload_url(self) -> Optional[str]:
+        """Get the object storage location run reports are uploaded to.
+
+        Returns:
+            s3://, gs:// or Azure Blob URL, or None if uploads are not configured
+        """
+        url = os.environ.get("REPORT_UPLOAD_URL") or self.config["reports"].get("upload_url")
+        if not url:
+            return None
+        from utils.report_upload import ReportUploadError, parse_upload_url
+
+        try:
+            parse_upload_url(str(url))
+        except ReportUploadError as e:
+            raise ConfigValidationError(f"reports.upload_url: {e}") from e
+        return str(url)
+
+    def get_display_timezone(self) -> ZoneInfo:
+        """Get the time zone console tables render timestamps in (see utils.time_format)"""
+        name = os.environ.get("REPORT_TIMEZONE") or self.config["reports"].get("timezone") or "UTC"
+        try:
+            return ZoneInfo(str(name))
+        except (ZoneInfoNotFoundError, ValueError):
+            raise ConfigValidationError(
+                f"reports.timezone must be an IANA time zone such as Europe/Berlin, got: {name}"
+            )
+
+    def get_report_compression(self) -> str:
+        """Get the compression of saved reports: "gzip", "zstd", or "" for none"""
+        compression = os.environ.get("REPORT_COMPRESS") or self.config["reports"].get("compress") or ""
+        if compression not in ("", "gzip", "zstd"):
+            raise ConfigValidationError(f"reports.compress must be gzip, zstd or empty, got: {compression}")
+        return compression
+
+    def get_redact_key_patterns(self) -> List[str]:
+        """Get the shell-style patterns of label and Env keys whose values are redacted (see utils.redaction)"""
+        patterns = self.config["reports"].get("redact_keys")
+        if patterns is None:
+            return list(DEFAULT_REDACT_KEYS)
+        if not isinstance(patterns, list) or not all(isinstance(pattern, str) and pattern for pattern in patterns):
+            raise ConfigValidationError(f"reports.redact_keys must be a list of key patterns, got: {patterns}")
+        return patterns
+
+    def get_anonymize_salt(self) -> Optional[str]:
+        """Get the key --anonymize hashes identifiers with, or None for a random key per run"""
+        return os.environ.get("ANONYMIZE_SALT") or self.config["reports"].get("anonymize_salt") or None
+
     # Security configuration
     def is_dry_run_by_default(self) -> bool:
         """Get dry run default from config"""
@@ -362,6 +924,232 @@ class ConfigManager:
         """Get confirmation requirement from config"""
         return self.config["security"]["require_confirmation"]
 
+    def is_read_only(self) -> bool:
+        """Whether every mutating operation is refused (READ_ONLY env var or --read-only, or security.read_only)"""
+        if os.environ.get("READ_ONLY", "").strip().lower() in ("1", "true", "yes"):
+            return True
+        return self.config["security"].get("read_only") is True
+
+    def get_notary_url(self) -> Optional[str]:
+        """Get the Notary server holding Docker Content Trust data, or None if signatures are not checked"""
+        content_trust = self.config["security"].get("content_trust") or {}
+        return os.environ.get("NOTARY_URL") or content_trust.get("notary_url") or None
+
+    def get_signed_tag_action(self) -> str:
+        """Get what deleting a tag signed with Docker Content Trust does ("refuse" or "warn")"""
+        content_trust = self.config["security"].get("content_trust") or {}
+        action = content_trust.get("on_signed_tag", "refuse")
+        if action not in ("refuse", "warn"):
+            raise ConfigValidationError(
+                f"security.content_trust.on_signed_tag must be 'refuse' or 'warn', got: {action}"
+            )
+        return action
+
+    def get_deletion_delay_hours(self) -> float:
+        """Get how long the API server queues approved deletions before running them (0 = run them at once)"""
+        delay = self.config["security"].get("deletion_delay_hours", 0)
+        try:
+            hours = float(delay)
+        except (ValueError, TypeError):
+            hours = -1.0
+        if hours < 0 or isinstance(delay, bool):
+            raise ConfigValidationError(f"security.deletion_delay_hours must be a non-negative number, got: {delay}")
+        return hours
+
+    # Protection configuration
+    def get_protection_providers(self) -> List[str]:
+        """Get the protection providers candidates_report, plan and apply consult (see utils.protection_providers)"""
+        providers = self.config.get("protection", {}).get("providers") or []
+        if not isinstance(providers, list) or not all(provider in PROTECTION_PROVIDERS for provider in providers):
+            raise ConfigValidationError(
+                f"protection.providers must be a list of {', '.join(PROTECTION_PROVIDERS)}, got: {providers}"
+            )
+        if "reference-file" in providers and not self.get_protection_reference_file():
+            raise ConfigValidationError("protection.reference_file is required by the reference-file provider")
+        return list(dict.fromkeys(providers))
+
+    def get_protection_reference_file(self) -> str:
+        """Get the file listing images the reference-file provider protects ("" = none)"""
+        path = self.config.get("protection", {}).get("reference_file") or ""
+        if not isinstance(path, str):
+            raise ConfigValidationError(f"protection.reference_file must be a path, got: {path}")
+        return path
+
+    def get_protection_kubernetes_namespaces(self) -> List[str]:
+        """Get the namespaces whose pods the kubernetes provider protects ([] = all namespaces)"""
+        namespaces = self.config.get("protection", {}).get("kubernetes_namespaces") or []
+        if not isinstance(namespaces, list) or not all(isinstance(n, str) and n for n in namespaces):
+            raise ConfigValidationError(
+                f"protection.kubernetes_namespaces must be a list of namespaces, got: {namespaces}"
+            )
+        return namespaces
+
+    def get_protection_ecr_pull_days(self) -> int:
+        """Get the number of days within which an ECR-recorded pull protects an image"""
+        days = self.config.get("protection", {}).get("ecr_pull_days", 30)
+        if isinstance(days, bool) or not isinstance(days, int) or days < 1:
+            raise ConfigValidationError(f"protection.ecr_pull_days must be a positive integer, got: {days}")
+        return days
+
+    # Schedule configuration
+    def get_schedules(self) -> Dict[str, Dict[str, Any]]:
+        """Get the cron schedules of the phases the API server runs.
+
+        Each of schedule.scan, schedule.plan and schedule.apply is a cron
+        expression, or a mapping with the expression under "cron" and extra
+        command-line arguments under "args" (scan and plan only). Phases without
+        a schedule are left out.
+
+        Returns:
+            Dict mapping phase -> {"cron": CronExpression, "args": [...]}, in SCHEDULE_PHASES order
+        """
+        schedule = self.config.get("schedule") or {}
+        unknown = [phase for phase in schedule if phase not in SCHEDULE_PHASES]
+        if unknown:
+            raise ConfigValidationError(f"Unknown schedule phase(s) {unknown}, expected: {', '.join(SCHEDULE_PHASES)}")
+
+        schedules: Dict[str, Dict[str, Any]] = {}
+        for phase in SCHEDULE_PHASES:
+            value = schedule.get(phase)
+            spec = value if isinstance(value, dict) else {"cron": value}
+            if not spec.get("cron"):
+                continue
+            args = spec.get("args") or []
+            if not isinstance(args, list) or not all(isinstance(arg, (str, int)) for arg in args):
+                raise ConfigValidationError(f"schedule.{phase}.args must be a list of strings, got: {args}")
+            if args and phase == "apply":
+                raise ConfigValidationError("schedule.apply takes no args; it applies the latest plan")
+            try:
+                cron = CronExpression(str(spec["cron"]))
+                cron.next_after(datetime.now(timezone.utc))
+            except ValueError as e:
+                raise ConfigValidationError(f"schedule.{phase}: {e}")
+            schedules[phase] = {"cron": cron, "args": [str(arg) for arg in args]}
+        return schedules
+
+    # Alerting configuration
+    def get_alert_destinations(self) -> Dict[str, Dict[str, str]]:
+        """Get the on-call services alerts are sent to.
+
+        Returns:
+            Dict with "pagerduty" -> {"routing_key"} and/or "opsgenie" -> {"api_key", "api_url"},
+            for the services that are configured
+        """
+        alerting = self.config.get("alerting") or {}
+        pagerduty = alerting.get("pagerduty") or {}
+        opsgenie = alerting.get("opsgenie") or {}
+        destinations: Dict[str, Dict[str, str]] = {}
+        routing_key = os.environ.get("PAGERDUTY_ROUTING_KEY") or pagerduty.get("routing_key")
+        if routing_key:
+            destinations["pagerduty"] = {"routing_key": str(routing_key)}
+        api_key = os.environ.get("OPSGENIE_API_KEY") or opsgenie.get("api_key")
+        if api_key:
+            api_url = str(opsgenie.get("api_url") or "https://api.opsgenie.com").rstrip("/")
+            if not api_url.startswith("https://"):
+                raise ConfigValidationError(f"alerting.opsgenie.api_url must be an https:// URL, got: {api_url}")
+            destinations["opsgenie"] = {"api_key": str(api_key), "api_url": api_url}
+        return destinations
+
+    def get_alert_thresholds(self) -> Dict[str, Any]:
+        """Get when scheduled runs raise alerts.
+
+        Returns:
+            Dict with on_failure (bool), max_error_rate (fraction of failed registry
+            requests, 0 = no alert) and max_reclaimable_gb (0 = no alert)
+        """
+        alerting = self.config.get("alerting") or {}
+        on_failure = alerting.get("on_failure", True)
+        if not isinstance(on_failure, bool):
+            raise ConfigValidationError(f"alerting.on_failure must be true or false, got: {on_failure}")
+        thresholds: Dict[str, Any] = {"on_failure": on_failure}
+        for key, upper in (("max_error_rate", 1.0), ("max_reclaimable_gb", float("inf"))):
+            value = alerting.get(key, 0)
+            if isinstance(value, bool) or not isinstance(value, (int, float)) or not 0 <= value <= upper:
+                limit = "between 0 and 1" if upper == 1.0 else "non-negative"
+                raise ConfigValidationError(f"alerting.{key} must be a {limit} number, got: {value}")
+            thresholds[key] = float(value)
+        return thresholds
+
+    def get_owner_quotas(self) -> Optional[Dict[str, Any]]:
+        """Get the per-owner storage quotas (see utils.quotas).
+
+        Returns:
+            OwnerQuotas dict with default_bytes, owners (owner -> bytes) and
+            prioritize_plans, or None if no owner has a quota
+        """
+        quotas = self.config.get("quotas") or {}
+        owners = quotas.get("owners") or {}
+        if not isinstance(owners, dict):
+            raise ConfigValidationError(f"quotas.owners must be a mapping of owner to GB, got: {owners}")
+        limits = {"default_gb": quotas.get("default_gb", 0), **{f"owners.{owner}": gb for owner, gb in owners.items()}}
+        for key, gb in limits.items():
+            if isinstance(gb, bool) or not isinstance(gb, (int, float)) or gb < 0:
+                raise ConfigValidationError(f"quotas.{key} must be a non-negative number of GB, got: {gb}")
+        prioritize = quotas.get("prioritize_plans", False)
+        if not isinstance(prioritize, bool):
+            raise ConfigValidationError(f"quotas.prioritize_plans must be true or false, got: {prioritize}")
+        if not any(limits.values()):
+            return None
+        return {
+            "default_bytes": int(limits["default_gb"] * 1024**3),
+            "owners": {str(owner): int(gb * 1024**3) for owner, gb in owners.items()},
+            "prioritize_plans": prioritize,
+        }
+
+    # Metrics configuration
+    def get_statsd_settings(self) -> Optional[Dict[str, Any]]:
+        """Get the StatsD server runs send metrics to.
+
+        Returns:
+            Dict with host, port, prefix, dogstatsd and tags, or None if no host is configured
+        """
+        statsd = (self.config.get("metrics") or {}).get("statsd") or {}
+        host = os.environ.get("STATSD_HOST") or statsd.get("host")
+        if not host:
+            return None
+        port = os.environ.get("STATSD_PORT") or statsd.get("port", 8125)
+        try:
+            port = int(port)
+        except (ValueError, TypeError):
+            port = 0
+        if not 1 <= port <= 65535:
+            raise ConfigValidationError(f"metrics.statsd.port must be a port number, got: {statsd.get('port')}")
+        tags = statsd.get("tags") or {}
+        if not isinstance(tags, dict) or not all(isinstance(value, (str, int, float)) for value in tags.values()):
+            raise ConfigValidationError(f"metrics.statsd.tags must be a mapping of tag names to values, got: {tags}")
+        return {
+            "host": str(host),
+            "port": port,
+            "prefix": str(statsd.get("prefix", "registry_cleaner") or ""),
+            "dogstatsd": bool(statsd.get("dogstatsd", True)),
+            "tags": {str(key): str(value) for key, value in tags.items()},
+        }
+
+    def get_registry_event_settings(self) -> Optional[Dict[str, Any]]:
+        """Get the registry notification listener of the API server (see utils/registry_events.py).
+
+        Returns:
+            Dict with image_types and snapshot_interval_seconds, or None if registry_events.enabled is off
+        """
+        events = self.config.get("registry_events") or {}
+        if not events.get("enabled"):
+            return None
+        image_types = events.get("image_types") or self.get_image_types()
+        if not isinstance(image_types, list) or not image_types or not all(isinstance(t, str) for t in image_types):
+            raise ConfigValidationError(
+                f"registry_events.image_types must be a list of image types, got: {image_types}"
+            )
+        interval = events.get("snapshot_interval_minutes", 15)
+        try:
+            minutes = float(interval)
+        except (ValueError, TypeError):
+            minutes = -1.0
+        if minutes < 0 or isinstance(interval, bool):
+            raise ConfigValidationError(
+                f"registry_events.snapshot_interval_minutes must be a non-negative number, got: {interval}"
+            )
+        return {"image_types": list(image_types), "snapshot_interval_seconds": minutes * 60}
+
     # Mongo configuration
     def get_mongo_host(self) -> str:
         return self.config["mongo"]["host"]
@@ -409,6 +1197,33 @@ class ConfigManager:
                 "Mongo password is not set and failed to load from Kubernetes secret 'mongodb-replicaset-admin'"
             ) from e
 
+    def get_reference_scan_fields(self) -> Optional[List[Dict[str, Any]]]:
+        """Get the MongoDB fields the reference scan reads (built-in and configured), None if it is disabled"""
+        from utils.reference_scan import DEFAULT_REFERENCE_FIELDS, REFERENCE_KINDS
+
+        scan = self.config["mongo"].get("reference_scan") or {}
+        enabled = scan.get("enabled", False)
+        if not isinstance(enabled, bool):
+            raise ConfigValidationError(f"mongo.reference_scan.enabled must be true or false, got: {enabled}")
+        fields = scan.get("fields") or []
+        if not isinstance(fields, list):
+            raise ConfigValidationError(f"mongo.reference_scan.fields must be a list, got: {fields}")
+        for index, field in enumerate(fields):
+            location = f"mongo.reference_scan.fields[{index}]"
+            if not isinstance(field, dict) or not all(
+                isinstance(field.get(key), str) and field.get(key) for key in ("collection", "field")
+            ):
+                raise ConfigValidationError(f"{location} needs a collection and a field, got: {field}")
+            if field.get("kind") not in REFERENCE_KINDS:
+                raise ConfigValidationError(
+                    f"{location}.kind must be one of {', '.join(REFERENCE_KINDS)}, got: {field.get('kind')}"
+                )
+            if not isinstance(field.get("filter") or {}, dict):
+                raise ConfigValidationError(f"{location}.filter must be a mapping, got: {field.get('filter')}")
+        if not enabled:
+            return None
+        return list(DEFAULT_REFERENCE_FIELDS) + fields
+
     def get_mongo_connection_string(self) -> str:
         auth = self.get_mongo_auth()
         host = self.get_mongo_host()
@@ -425,6 +1240,13 @@ class ConfigManager:
         errors = []
         warnings = []
 
+        # Validate the defaults profile, and config.yaml settings that drift from it
+        try:
+            self.get_profile()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+        warnings.extend(self.profile_drift)
+
         # Validate registry configuration
         registry_url = self.get_registry_url()
         if not registry_url or not registry_url.strip():
@@ -432,11 +1254,21 @@ class ConfigManager:
         elif not self._is_valid_registry_url(registry_url):
             warnings.append(f"Registry URL '{registry_url}' may be invalid (expected format: hostname[:port])")
 
+        for host in self.config.get("credentials") or {}:
+            try:
+                self.get_credential_profile(str(host))
+            except ConfigValidationError as e:
+                errors.append(str(e))
+
         repository = self.get_repository()
         if not repository or not repository.strip():
             errors.append("Repository name is required and cannot be empty")
         elif not self._is_valid_repository_name(repository):
             errors.append(f"Repository name '{repository}' contains invalid characters")
+        try:
+            self.get_image_types()
+        except ConfigValidationError as e:
+            errors.append(str(e))
 
         # Validate Kubernetes configuration
         namespace = self.get_domino_platform_namespace()
@@ -526,6 +1358,116 @@ class ConfigManager:
             if not s3_region or not s3_region.strip():
                 errors.append("S3 region is required when S3 bucket is configured")
 
+        # Validate alerting configuration
+        try:
+            self.get_alert_destinations()
+            self.get_alert_thresholds()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        # Validate metrics configuration
+        try:
+            self.get_statsd_settings()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_registry_event_settings()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_report_upload_url()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_redact_key_patterns()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_display_timezone()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_report_compression()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_registry_client()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_snapshot_retention()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_tag_naming_pattern()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_floating_tag_patterns()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_reference_scan_fields()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_recent_run_protection_days()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_protection_providers()
+            self.get_protection_kubernetes_namespaces()
+            self.get_protection_ecr_pull_days()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_storage_cost_per_gb_month()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_owner_quotas()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_layer_contents_limits()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_oversized_layer_policy()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_toolchain_policy()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_expiry_keys()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
+        try:
+            self.get_secret_patterns()
+        except ConfigValidationError as e:
+            errors.append(str(e))
+
         # Log warnings
         for warning in warnings:
             logging.warning(f"Configuration warning: {warning}")
@@ -574,14 +1516,45 @@ class ConfigManager:
     def print_config(self):
         """Print current configuration"""
         print("Current Configuration:")
+        print(f"  Profile: {self.get_profile()}")
         print(f"  Registry URL: {self.get_registry_url()}")
         print(f"  Repository Name: {self.get_repository()}")
+        print(f"  Image Types: {', '.join(self.get_image_types())}")
+        print(f"  Registry Client: {self.get_registry_client()}")
+        credentials = self.config.get("credentials") or {}
+        if credentials:
+            profiles = [f"{host} ({self.get_credential_profile(str(host))['method']})" for host in credentials]
+            print(f"  Credential Profiles: {', '.join(profiles)}")
         print(f"  Domino Platform Namespace: {self.get_domino_platform_namespace()}")
         print(f"  Max Workers: {self.get_max_workers()}")
         print(f"  Timeout: {self.get_timeout()}")
         print(f"  Output Directory: {self.get_output_dir()}")
         print(f"  Dry Run Default: {self.is_dry_run_by_default()}")
         print(f"  Require Confirmation: {self.requires_confirmation()}")
+        print(f"  Read Only: {self.is_read_only()}")
+        print(f"  Protection Providers: {', '.join(self.get_protection_providers()) or 'Domino usage only'}")
+        print(f"  Notary Server: {self.get_notary_url() or 'Not configured'}")
+        print(f"  Deletion Delay: {self.get_deletion_delay_hours():g}h")
+        schedules = [f"{phase} '{schedule['cron']}'" for phase, schedule in self.get_schedules().items()]
+        print(f"  Schedules: {', '.join(schedules) or 'None'}")
+        print(f"  Alerting: {', '.join(self.get_alert_destinations()) or 'Not configured'}")
+        quotas = self.get_owner_quotas()
+        quota_owners = f"{len(quotas['owners'])} owner(s)" if quotas else "Not configured"
+        print(f"  Owner Quotas: {quota_owners}")
+        statsd = self.get_statsd_settings()
+        statsd_address = f"{statsd['host']}:{statsd['port']}" if statsd else "Not configured"
+        print(f"  StatsD: {statsd_address}")
+        print(f"  Report Upload: {self.get_report_upload_url() or 'Not configured'}")
+        print(f"  Display Time Zone: {self.get_display_timezone().key}")
+        print(f"  Redacted Keys: {', '.join(self.get_redact_key_patterns()) or 'None'}")
+        print(f"  Anonymize Salt: {'Configured' if self.get_anonymize_salt() else 'Random per run'}")
+        reference_fields = self.get_reference_scan_fields()
+        print(f"  Reference Scan: {f'{len(reference_fields)} field(s)' if reference_fields else 'Disabled'}")
+        protection_days = self.get_recent_run_protection_days()
+        print(f"  Recent Run Protection: {f'{protection_days} day(s)' if protection_days else 'Disabled'}")
+        retention = self.get_snapshot_retention()
+        kept = ", ".join(f"{key} {value}" for key, value in retention.items() if value)
+        print(f"  Snapshot Retention: {kept or 'Keep all'}")
 
         s3_bucket = self.get_s3_bucket()
         s3_region = self.get_s3_region()
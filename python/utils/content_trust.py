@@ -0,0 +1,111 @@
+"""
+Docker Content Trust (Notary) awareness.
+
+With Docker Content Trust (DCT), image publishers sign tags and store the
+signatures as TUF metadata on a Notary server, separately from the registry.
+Clients that enforce content trust (DOCKER_CONTENT_TRUST=1) look a tag up in
+that metadata before pulling it. Deleting a signed tag from the registry leaves
+its signature behind, so those clients keep resolving the tag to a digest that
+no longer exists and fail to pull, until the tag is also removed from the trust
+data with `notary remove`.
+
+When security.content_trust.notary_url is set, every tag is checked against the
+trust data of its repository before it is deleted. Signed tags are refused
+unless deletion is forced; with security.content_trust.on_signed_tag set to
+"warn" they are deleted with a warning instead.
+
+Trust data is stored per repository under its globally unique name (GUN),
+<registry host>/<repository>. Signed tags are the targets of the top-level
+targets role plus those of its delegations (e.g. targets/releases):
+
+    GET /v2/<gun>/_trust/tuf/targets.json
+    {"signed": {"targets": {"1.0": {...}}, "delegations": {"roles": [{"name": "targets/releases"}]}}}
+"""
+
+import threading
+from typing import Any, Dict, Optional, Set
+
+from utils.host_limits import get_host_limiter
+from utils.logging_utils import get_logger
+from utils.registry_http import RegistryHttpClient
+
+logger = get_logger(__name__)
+
+
+def _signed(document: Any) -> Dict[str, Any]:
+    """The "signed" section of a TUF metadata document"""
+    signed = document.get("signed") if isinstance(document, dict) else None
+    return signed if isinstance(signed, dict) else {}
+
+
+def target_names(document: Any) -> Set[str]:
+    """Tags listed in a TUF targets metadata document"""
+    targets = _signed(document).get("targets")
+    return set(targets) if isinstance(targets, dict) else set()
+
+
+def delegated_roles(document: Any) -> Set[str]:
+    """Names of the roles a TUF targets metadata document delegates to"""
+    roles = (_signed(document).get("delegations") or {}).get("roles")
+    if not isinstance(roles, list):
+        return set()
+    return {role["name"] for role in roles if isinstance(role, dict) and role.get("name")}
+
+
+class ContentTrustChecker:
+    """Looks up which tags are signed in a Notary server's trust data."""
+
+    def __init__(
+        self, notary_url: str, registry_url: str, username: Optional[str] = None, password: Optional[str] = None
+    ):
+        """Initialize the checker
+
+        Args:
+            notary_url: Notary server URL, optionally with an http(s):// scheme
+            registry_url: Registry the trust data belongs to (the host part of every GUN)
+            username: Registry username; Notary uses the registry's token service
+            password: Registry password or token
+        """
+        self.registry_host = registry_url.split("://", 1)[-1].rstrip("/")
+        self._client = RegistryHttpClient(notary_url, username, password, host_limiter=get_host_limiter(notary_url))
+        self._signed_tags: Dict[str, Optional[Set[str]]] = {}
+        self._lock = threading.Lock()
+
+    def _read_role(self, gun: str, role: str) -> Any:
+        """Read the metadata of one targets role"""
+        return self._client.get_json(f"/v2/{gun}/_trust/tuf/{role}.json", f"repository:{gun}:pull")
+
+    def _load_signed_tags(self, repository: str) -> Optional[Set[str]]:
+        """Read the signed tags of a repository from the Notary server"""
+        gun = f"{self.registry_host}/{repository}"
+        try:
+            targets = self._read_role(gun, "targets")
+            if targets is None:
+                # No trust data: the repository has never been signed
+                return set()
+            tags = target_names(targets)
+            for role in sorted(delegated_roles(targets)):
+                tags |= target_names(self._read_role(gun, role))
+        except Exception as e:
+            logger.warning(f"Could not read Docker Content Trust data of {gun}: {e}")
+            return None
+        if tags:
+            logger.info(f"{gun} is signed with Docker Content Trust ({len(tags)} signed tags)")
+        return tags
+
+    def signed_tags(self, repository: str) -> Optional[Set[str]]:
+        """Get the signed tags of a repository.
+
+        Returns:
+            The signed tags (empty if the repository has no trust data), or None
+            if the Notary server could not be read
+        """
+        with self._lock:
+            if repository not in self._signed_tags:
+                self._signed_tags[repository] = self._load_signed_tags(repository)
+            return self._signed_tags[repository]
+
+    def is_signed(self, repository: str, tag: str) -> Optional[bool]:
+        """Whether a tag is signed, or None if the trust data could not be read"""
+        tags = self.signed_tags(repository)
+        return None if tags is None else tag in tags
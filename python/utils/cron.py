@@ -0,0 +1,140 @@
+"""
+Cron expressions.
+
+Schedules in config.yaml use the standard five-field cron syntax, evaluated in
+UTC:
+
+    ┌───────── minute (0-59)
+    │ ┌─────── hour (0-23)
+    │ │ ┌───── day of month (1-31)
+    │ │ │ ┌─── month (1-12 or jan-dec)
+    │ │ │ │ ┌─ day of week (0-6 or sun-sat; 7 is also Sunday)
+    0 2 * * *
+
+Each field is "*", a value, a range ("1-5"), a step ("*/15", "0-30/10") or a
+comma-separated list of those. As in cron, when both day of month and day of
+week are restricted, a day matching either one matches. The shorthands
+@hourly, @daily (@midnight), @weekly, @monthly and @yearly (@annually) are
+accepted too.
+"""
+
+from datetime import datetime, timedelta
+from typing import List, Set, Tuple
+
+_SHORTHANDS = {
+    "@hourly": "0 * * * *",
+    "@daily": "0 0 * * *",
+    "@midnight": "0 0 * * *",
+    "@weekly": "0 0 * * 0",
+    "@monthly": "0 0 1 * *",
+    "@yearly": "0 0 1 1 *",
+    "@annually": "0 0 1 1 *",
+}
+
+_MONTHS = ["jan", "feb", "mar", "apr", "may", "jun", "jul", "aug", "sep", "oct", "nov", "dec"]
+_DAYS = ["sun", "mon", "tue", "wed", "thu", "fri", "sat"]
+
+# (name, lowest value, highest value, value names starting at the lowest value)
+_FIELDS: List[Tuple[str, int, int, List[str]]] = [
+    ("minute", 0, 59, []),
+    ("hour", 0, 23, []),
+    ("day of month", 1, 31, []),
+    ("month", 1, 12, _MONTHS),
+    ("day of week", 0, 7, _DAYS),
+]
+
+# Longest search for the next matching minute; "0 0 29 2 *" matches once in four years
+_MAX_SEARCH = timedelta(days=5 * 366)
+
+
+def _parse_value(value: str, low: int, names: List[str]) -> int:
+    """A field value as a number, accepting month and day names"""
+    if value.lower() in names:
+        return low + names.index(value.lower())
+    return int(value)
+
+
+def _parse_field(field: str, name: str, low: int, high: int, names: List[str]) -> Set[int]:
+    """Values a cron field matches"""
+    values: Set[int] = set()
+    for part in field.split(","):
+        part_range, _, step_text = part.partition("/")
+        step = int(step_text) if step_text else 1
+        if part_range == "*":
+            start, end = low, high
+        elif "-" in part_range:
+            first, _, last = part_range.partition("-")
+            start, end = _parse_value(first, low, names), _parse_value(last, low, names)
+        else:
+            start = _parse_value(part_range, low, names)
+            end = high if step_text else start
+        if step < 1 or not low <= start <= end <= high:
+            raise ValueError(f"invalid {name} field: {field}")
+        values.update(range(start, end + 1, step))
+    return values
+
+
+class CronExpression:
+    """A parsed five-field cron expression."""
+
+    def __init__(self, expression: str):
+        """Parse an expression.
+
+        Raises:
+            ValueError: If the expression is not a valid cron expression
+        """
+        self.expression = expression.strip()
+        fields = _SHORTHANDS.get(self.expression.lower(), self.expression).split()
+        if len(fields) != 5:
+            raise ValueError(f"expected 5 fields (minute hour day month weekday), got: {expression!r}")
+        try:
+            parsed = [_parse_field(field, *spec) for field, spec in zip(fields, _FIELDS)]
+        except ValueError as e:
+            raise ValueError(f"invalid cron expression {expression!r}: {e}") from None
+        self.minutes, self.hours, self.days, self.months, weekdays = parsed
+        # Sunday is 0 or 7 in cron, and 6 in datetime.weekday()
+        self.weekdays = {(day - 1) % 7 for day in weekdays}
+        self._any_day = fields[2] == "*"
+        self._any_weekday = fields[4] == "*"
+
+    def _day_matches(self, moment: datetime) -> bool:
+        """Whether the day of a moment matches the day of month and day of week fields"""
+        day_match = moment.day in self.days
+        weekday_match = moment.weekday() in self.weekdays
+        if self._any_day or self._any_weekday:
+            return day_match and weekday_match
+        return day_match or weekday_match
+
+    def matches(self, moment: datetime) -> bool:
+        """Whether the expression matches the minute of a moment"""
+        return (
+            moment.month in self.months
+            and self._day_matches(moment)
+            and moment.hour in self.hours
+            and moment.minute in self.minutes
+        )
+
+    def next_after(self, moment: datetime) -> datetime:
+        """The first minute after a moment that the expression matches.
+
+        Raises:
+            ValueError: If the expression never matches (e.g. "0 0 31 2 *")
+        """
+        candidate = moment.replace(second=0, microsecond=0) + timedelta(minutes=1)
+        limit = candidate + _MAX_SEARCH
+        while candidate < limit:
+            if candidate.month not in self.months:
+                month_start = candidate.replace(day=1, hour=0, minute=0)
+                candidate = (month_start + timedelta(days=32)).replace(day=1)
+            elif not self._day_matches(candidate):
+                candidate = candidate.replace(hour=0, minute=0) + timedelta(days=1)
+            elif candidate.hour not in self.hours:
+                candidate = candidate.replace(minute=0) + timedelta(hours=1)
+            elif candidate.minute not in self.minutes:
+                candidate += timedelta(minutes=1)
+            else:
+                return candidate
+        raise ValueError(f"cron expression {self.expression!r} never matches")
+
+    def __str__(self) -> str:
+        return self.expression
@@ -17,6 +17,7 @@ from utils.config_manager import SkopeoClient, config_manager
 from utils.health_checks import HealthChecker
 from utils.logging_utils import get_logger
 from utils.report_utils import sizeof_fmt
+from utils.statsd_metrics import emit_run_metrics
 
 
 # NOTE: BaseDeletionScript intentionally does not define abstract methods.
@@ -173,3 +174,17 @@ class BaseDeletionScript(ABC):  # noqa: B024
             self.logger.info(f"   {'Would save' if dry_run else 'Saved'}: {sizeof_fmt(space_freed_bytes)}")
         if "results_file" in summary:
             self.logger.info(f"   Results saved to: {summary['results_file']}")
+
+        self._emit_summary_metrics(summary, dry_run)
+
+    def _emit_summary_metrics(self, summary: Dict[str, Any], dry_run: bool) -> None:
+        """Send the deletion summary to StatsD, if configured"""
+        space: Dict[str, float] = {}
+        if "space_freed_bytes" in summary or "space_freed_gb" in summary:
+            space_bytes = summary.get("space_freed_bytes", summary.get("space_freed_gb", 0) * (1024**3))
+            space["bytes_reclaimable" if dry_run else "bytes_freed"] = int(space_bytes)
+        if dry_run:
+            emit_run_metrics(gauges=space)
+            return
+        counts = {"deletes_performed": summary.get("deleted", 0), "deletes_failed": summary.get("failed", 0)}
+        emit_run_metrics(counts={**counts, **space})
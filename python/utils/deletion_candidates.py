@@ -0,0 +1,333 @@
+"""
+Ranked deletion candidates.
+
+Combines what is known about each analyzed image - how old it is, how many
+bytes only it holds, how often and how recently Domino workloads used it, and
+whether current configuration still references it - into a single score, and
+ranks images by it. Walking down the ranking, cumulative savings account for
+layers shared between candidates, which are only freed once every image that
+holds them is deleted.
+
+Usage data comes from the MongoDB usage reports and is optional; without it,
+images are ranked by age and size alone and none are protected. Vulnerability
+counts from an external scanner can be added as a further factor, and images
+built with an outdated toolchain (see utils.toolchain) are ranked higher.
+Images past the expiry set at build time (see utils.image_expiry) are ranked
+before all others, unless current configuration references them. Images the
+configured protection providers protect (see utils.protection_providers) are
+left out of the ranking too, with the providers that protected them.
+Candidates whose content a protected image still provides - the same digest
+in another repository, or the same layers - are zero user impact deletions
+(see utils.pull_availability).
+"""
+
+from collections import Counter
+from datetime import datetime, timezone
+from typing import TYPE_CHECKING, Dict, List, Optional, Tuple, TypedDict
+
+from utils.protection_providers import DOMINO_PROVIDER, Protections
+from utils.pull_availability import pull_alternatives
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+    from utils.image_expiry import ImageExpiry
+
+# Relative weight of each scoring factor (overridden by analysis.candidate_weights)
+DEFAULT_SCORE_WEIGHTS: Dict[str, float] = {
+    "age": 1.0,
+    "exclusive_size": 1.0,
+    "frequency": 1.0,
+    "recency": 1.0,
+    "vulnerabilities": 1.0,
+    "outdated_toolchain": 1.0,
+}
+
+# Ages and idle times of this many days or more score the maximum
+SATURATION_DAYS = 365
+
+# Usage that reflects current configuration rather than past executions; an image
+# referenced by any of these cannot be deleted without breaking something
+PROTECTING_USAGE = (
+    "workspaces",
+    "models",
+    "scheduler_jobs",
+    "projects",
+    "organizations",
+    "app_versions",
+    "references",
+)
+
+
+class TagUsage(TypedDict):
+    """Usage of one tag, condensed from the MongoDB usage reports."""
+
+    use_count: int  # Runs and workspaces that used the tag
+    last_used: Optional[datetime]
+    protected_by: List[str]  # PROTECTING_USAGE sources referencing the tag
+
+
+class DeletionCandidate(TypedDict):
+    """An image in the ranked list of deletion candidates."""
+
+    rank: int
+    image_id: str
+    repository: str
+    tag: str
+    digest: str
+    score: float  # 0 (keep) to 1 (delete first)
+    factors: Dict[str, Optional[float]]  # Per-factor scores; None when the data is not available
+    age_days: Optional[float]
+    use_count: Optional[int]
+    last_used: Optional[str]
+    vulnerabilities: Optional[int]
+    expires_at: Optional[str]  # Expiry set at build time, if any
+    expired: bool
+    size_bytes: int
+    estimated_savings_bytes: int  # Bytes freed if only this image were deleted
+    cumulative_savings_bytes: int  # Bytes freed by deleting this and every higher-ranked candidate
+    pullable_from: List[str]  # Protected images with the same content; deleting this has zero user impact
+
+
+class ProtectedImage(TypedDict):
+    """An image left out of the ranking because current configuration references it or a provider protects it."""
+
+    image_id: str
+    repository: str
+    tag: str
+    protected_by: List[str]  # PROTECTING_USAGE sources referencing the tag, then the other protecting providers
+    providers: Dict[str, List[str]]  # Protecting provider -> what protects the image, e.g. {"kubernetes": ["pod ..."]}
+    estimated_savings_bytes: int
+
+
+def days_since(timestamp: Optional[datetime], now: datetime) -> Optional[float]:
+    """Days between a timestamp and now, or None without a timestamp"""
+    if timestamp is None:
+        return None
+    if timestamp.tzinfo is None:
+        timestamp = timestamp.replace(tzinfo=timezone.utc)
+    return max((now - timestamp).total_seconds() / 86400, 0.0)
+
+
+def parse_created(created: Optional[str]) -> Optional[datetime]:
+    """Parse an image creation time as reported by skopeo"""
+    if not created:
+        return None
+    try:
+        return datetime.fromisoformat(created.replace("Z", "+00:00"))
+    except ValueError:
+        return None
+
+
+def score_factors(
+    age_days: Optional[float],
+    exclusive_bytes: int,
+    max_exclusive_bytes: int,
+    usage: Optional[TagUsage],
+    idle_days: Optional[float],
+    vulnerabilities: Optional[int] = None,
+    max_vulnerabilities: int = 0,
+    outdated_toolchain: Optional[bool] = None,
+) -> Dict[str, Optional[float]]:
+    """Score each factor from 0 (keep) to 1 (delete).
+
+    Args:
+        age_days: Days since the image was created, if known
+        exclusive_bytes: Bytes freed if only this image were deleted
+        max_exclusive_bytes: Largest exclusive size among all images
+        usage: Usage of the tag, or None if usage data is not available
+        idle_days: Days since the tag was last used, if it was used
+        vulnerabilities: Known vulnerabilities in the image, if it was scanned
+        max_vulnerabilities: Largest vulnerability count among all scanned images
+        outdated_toolchain: Whether the image was built with an outdated toolchain, if its build fields are known
+
+    Returns:
+        Dict of factor name -> score, None where the data is not available
+    """
+    factors: Dict[str, Optional[float]] = {
+        "age": min(age_days / SATURATION_DAYS, 1.0) if age_days is not None else None,
+        "exclusive_size": exclusive_bytes / max_exclusive_bytes if max_exclusive_bytes else 0.0,
+        "frequency": None,
+        "recency": None,
+        "vulnerabilities": None,
+        "outdated_toolchain": None,
+    }
+    if outdated_toolchain is not None:
+        factors["outdated_toolchain"] = 1.0 if outdated_toolchain else 0.0
+    if vulnerabilities is not None:
+        factors["vulnerabilities"] = vulnerabilities / max_vulnerabilities if max_vulnerabilities else 0.0
+    if usage is not None:
+        factors["frequency"] = 1.0 / (1 + usage["use_count"])
+        # Never used scores as idle for the full saturation period
+        factors["recency"] = 1.0 if idle_days is None else min(idle_days / SATURATION_DAYS, 1.0)
+    return factors
+
+
+def weighted_score(factors: Dict[str, Optional[float]], weights: Dict[str, float]) -> float:
+    """Weighted average of the available factor scores.
+
+    Factors without data are left out rather than counted as 0, so an image is
+    not ranked lower just because, for example, its creation time is unknown.
+    """
+    total_weight = 0.0
+    total = 0.0
+    for name, value in factors.items():
+        weight = weights.get(name, 0.0)
+        if value is None or weight <= 0:
+            continue
+        total_weight += weight
+        total += weight * value
+    return total / total_weight if total_weight else 0.0
+
+
+def rank_candidates(
+    analyzer: "ImageAnalyzer",
+    usage: Optional[Dict[str, TagUsage]] = None,
+    weights: Optional[Dict[str, float]] = None,
+    now: Optional[datetime] = None,
+    vulnerabilities: Optional[Dict[str, int]] = None,
+    outdated_toolchain: Optional[Dict[str, bool]] = None,
+    expiries: Optional[Dict[str, "ImageExpiry"]] = None,
+    protections: Optional[Protections] = None,
+) -> Tuple[List[DeletionCandidate], List[ProtectedImage]]:
+    """Rank analyzed images as deletion candidates.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        usage: Usage by tag; tags missing from it were never used. None if usage
+            data is not available, in which case no image is protected.
+        weights: Factor weights (default: DEFAULT_SCORE_WEIGHTS)
+        now: Reference time for ages (default: current time)
+        vulnerabilities: Vulnerability counts by image_id; images missing from it
+            were not scanned and are scored without the factor
+        outdated_toolchain: Whether each image was built with an outdated toolchain, by
+            image_id; images missing from it are scored without the factor
+        expiries: Expiry set at build time, by image_id; expired images rank first
+        protections: Images protection providers protect (collect_protections), protected
+            whether or not usage data is available
+
+    Returns:
+        Tuple of (candidates sorted best first, protected images)
+    """
+    weights = weights or DEFAULT_SCORE_WEIGHTS
+    now = now or datetime.now(timezone.utc)
+
+    layers_by_image: Dict[str, List[str]] = {}
+    for mapping in analyzer.image_layers:
+        layers_by_image.setdefault(mapping["image_id"], []).append(mapping["layer_id"])
+    layers = analyzer.layers
+
+    exclusive: Dict[str, int] = {}
+    for image_id in analyzer.images:
+        own_refs = Counter(layers_by_image.get(image_id, []))
+        exclusive[image_id] = sum(
+            layers[layer_id]["size_bytes"]
+            for layer_id, count in own_refs.items()
+            if layer_id in layers and layers[layer_id]["ref_count"] <= count
+        )
+    max_exclusive = max(exclusive.values(), default=0)
+    vulnerabilities = vulnerabilities or {}
+    max_vulnerabilities = max(vulnerabilities.values(), default=0)
+    outdated_toolchain = outdated_toolchain or {}
+    expiries = expiries or {}
+    protections = protections or {}
+
+    scored: List[DeletionCandidate] = []
+    protected: List[ProtectedImage] = []
+    for image_id, image_data in analyzer.images.items():
+        tag_usage: Optional[TagUsage] = None
+        providers: Dict[str, List[str]] = {}
+        if usage is not None:
+            tag_usage = usage.get(image_data["tag"], {"use_count": 0, "last_used": None, "protected_by": []})
+            if tag_usage["protected_by"]:
+                providers[DOMINO_PROVIDER] = list(tag_usage["protected_by"])
+        for provider, reasons in protections.get(image_id, {}).items():
+            providers.setdefault(provider, list(reasons))
+        if providers:
+            protected.append(
+                {
+                    "image_id": image_id,
+                    "repository": image_data["repository"],
+                    "tag": image_data["tag"],
+                    "protected_by": providers.get(DOMINO_PROVIDER, [])
+                    + [provider for provider in providers if provider != DOMINO_PROVIDER],
+                    "providers": providers,
+                    "estimated_savings_bytes": exclusive[image_id],
+                }
+            )
+            continue
+
+        last_used = tag_usage["last_used"] if tag_usage else None
+        expiry = expiries.get(image_id)
+        age_days = days_since(parse_created(analyzer.created.get(image_id)), now)
+        factors = score_factors(
+            age_days,
+            exclusive[image_id],
+            max_exclusive,
+            tag_usage,
+            days_since(last_used, now),
+            vulnerabilities.get(image_id),
+            max_vulnerabilities,
+            outdated_toolchain.get(image_id),
+        )
+        scored.append(
+            {
+                "rank": 0,
+                "image_id": image_id,
+                "repository": image_data["repository"],
+                "tag": image_data["tag"],
+                "digest": image_data["digest"],
+                "score": round(weighted_score(factors, weights), 4),
+                "factors": {name: round(v, 4) if v is not None else None for name, v in factors.items()},
+                "age_days": round(age_days, 1) if age_days is not None else None,
+                "use_count": tag_usage["use_count"] if tag_usage else None,
+                "last_used": last_used.isoformat() if last_used else None,
+                "vulnerabilities": vulnerabilities.get(image_id),
+                "expires_at": expiry["expires_at"] if expiry else None,
+                "expired": bool(expiry and expiry["expired"]),
+                "size_bytes": sum(
+                    layers[layer_id]["size_bytes"] for layer_id in layers_by_image.get(image_id, []) if layer_id in layers
+                ),
+                "estimated_savings_bytes": exclusive[image_id],
+                "cumulative_savings_bytes": 0,
+                "pullable_from": [],
+            }
+        )
+
+    scored.sort(key=lambda c: (not c["expired"], -c["score"], -c["estimated_savings_bytes"], c["image_id"]))
+
+    # Every candidate may be deleted, so only protected images are sure to keep providing the content
+    alternatives = pull_alternatives(analyzer, [c["image_id"] for c in scored], [p["image_id"] for p in protected])
+    # A layer is freed once the candidates deleted so far hold all of its references
+    deleted_refs: Counter = Counter()
+    cumulative = 0
+    for rank, candidate in enumerate(scored, start=1):
+        for layer_id in layers_by_image.get(candidate["image_id"], []):
+            deleted_refs[layer_id] += 1
+            layer_data = layers.get(layer_id)
+            if layer_data and deleted_refs[layer_id] == layer_data["ref_count"]:
+                cumulative += layer_data["size_bytes"]
+        candidate["rank"] = rank
+        candidate["cumulative_savings_bytes"] = cumulative
+        candidate["pullable_from"] = alternatives.get(candidate["image_id"], [])
+
+    protected.sort(key=lambda p: p["image_id"])
+    return scored, protected
+
+
+def savings_curve(candidates: List[DeletionCandidate], points: int = 20) -> List[Dict[str, int]]:
+    """Sample the cumulative savings curve at evenly spaced ranks.
+
+    Args:
+        candidates: Ranked candidates
+        points: Maximum number of points; the last candidate is always included
+
+    Returns:
+        List of {"candidates": N, "savings_bytes": bytes freed by deleting the top N}
+    """
+    if not candidates:
+        return []
+    step = max(len(candidates) // points, 1)
+    ranks = list(range(step, len(candidates) + 1, step))
+    if ranks[-1] != len(candidates):
+        ranks.append(len(candidates))
+    return [{"candidates": n, "savings_bytes": candidates[n - 1]["cumulative_savings_bytes"]} for n in ranks]
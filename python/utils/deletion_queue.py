@@ -0,0 +1,187 @@
+"""
+Time-delayed deletion queue.
+
+When security.deletion_delay_hours is set, the API server does not run an
+approved deletion (a destructive operation requested with apply) right away.
+It queues it instead, and runs it once the delay has passed. Until then the
+pending queue can be listed and individual items cancelled, which gives a
+window to catch a mistaken selection before anything is deleted.
+
+The queue is stored as JSON next to the reports, so pending deletions survive
+a restart of the API server:
+
+    {"items": [{"item_id": "...", "operation": "delete_image", "params": {"apply": true},
+                "status": "pending", "queued_at": "...", "execute_after": "...", ...}]}
+
+Items move from "pending" to "started" (a job was launched for them, see
+job_id), "cancelled", or "failed" (the job could not be launched, see error).
+An item is marked started before its job is launched, so a crash in between
+never runs a deletion twice. A queue file that cannot be read is set aside and
+replaced by an empty queue: losing queued deletions only means they do not run.
+"""
+
+import copy
+import json
+import threading
+import uuid
+from datetime import datetime, timedelta, timezone
+from pathlib import Path
+from typing import Any, Dict, List, Optional, TypedDict
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+STATUS_PENDING = "pending"
+STATUS_STARTED = "started"
+STATUS_CANCELLED = "cancelled"
+STATUS_FAILED = "failed"
+
+
+class QueueItem(TypedDict):
+    """An approved deletion waiting for (or past) its execution time."""
+
+    item_id: str
+    operation: str
+    params: Dict[str, Any]
+    status: str
+    queued_at: str
+    execute_after: str
+    finished_at: Optional[str]
+    job_id: Optional[str]
+    error: Optional[str]
+
+
+class DeletionQueue:
+    """Approved deletions held back for a delay, persisted to a JSON file."""
+
+    def __init__(self, path: Path, max_finished: int = 50):
+        """Initialize the queue, loading items saved by an earlier run
+
+        Args:
+            path: JSON file the queue is stored in
+            max_finished: Number of started, cancelled and failed items kept for reference
+        """
+        self.path = Path(path)
+        self.max_finished = max_finished
+        self._lock = threading.Lock()
+        self._items: List[QueueItem] = self._load()
+
+    def _load(self) -> List[QueueItem]:
+        """Read the queue file"""
+        if not self.path.exists():
+            return []
+        try:
+            items = json.loads(self.path.read_text())["items"]
+            if not isinstance(items, list):
+                raise ValueError("items is not a list")
+            return items
+        except (OSError, ValueError, KeyError, TypeError) as e:
+            broken = self.path.with_suffix(".broken.json")
+            logger.error(f"Could not read deletion queue {self.path} ({e}); moving it to {broken}")
+            try:
+                self.path.replace(broken)
+            except OSError:
+                pass
+            return []
+
+    def _save(self) -> None:
+        """Write the queue file, keeping only the most recent finished items. Call with _lock held."""
+        finished = [item for item in self._items if item["status"] != STATUS_PENDING]
+        for item in finished[: max(0, len(finished) - self.max_finished)]:
+            self._items.remove(item)
+        self.path.parent.mkdir(parents=True, exist_ok=True)
+        tmp_path = self.path.with_suffix(".tmp")
+        tmp_path.write_text(json.dumps({"items": self._items}, indent=2))
+        tmp_path.replace(self.path)
+
+    def add(
+        self, operation: str, params: Dict[str, Any], delay: timedelta, now: Optional[datetime] = None
+    ) -> QueueItem:
+        """Queue an operation to run after a delay"""
+        now = now or datetime.now(timezone.utc)
+        item: QueueItem = {
+            "item_id": str(uuid.uuid4()),
+            "operation": operation,
+            "params": copy.deepcopy(params),
+            "status": STATUS_PENDING,
+            "queued_at": now.isoformat(),
+            "execute_after": (now + delay).isoformat(),
+            "finished_at": None,
+            "job_id": None,
+            "error": None,
+        }
+        with self._lock:
+            self._items.append(item)
+            self._save()
+        logger.info(f"Queued {operation} ({item['item_id']}) to run after {item['execute_after']}")
+        return copy.deepcopy(item)
+
+    def list_items(self, pending_only: bool = False) -> List[QueueItem]:
+        """Queue items in the order they were queued"""
+        with self._lock:
+            items = [item for item in self._items if not pending_only or item["status"] == STATUS_PENDING]
+            return copy.deepcopy(items)
+
+    def get(self, item_id: str) -> Optional[QueueItem]:
+        """A queue item by ID, or None"""
+        with self._lock:
+            item = self._find(item_id)
+            return copy.deepcopy(item) if item else None
+
+    def _find(self, item_id: str) -> Optional[QueueItem]:
+        """A queue item by ID. Call with _lock held."""
+        return next((item for item in self._items if item["item_id"] == item_id), None)
+
+    def cancel(self, item_id: str, now: Optional[datetime] = None) -> Optional[QueueItem]:
+        """Cancel a pending item.
+
+        Returns:
+            The item, or None if there is no such item
+
+        Raises:
+            ValueError: If the item is no longer pending
+        """
+        with self._lock:
+            item = self._find(item_id)
+            if item is None:
+                return None
+            if item["status"] != STATUS_PENDING:
+                raise ValueError(f"Queue item is already {item['status']}")
+            item["status"] = STATUS_CANCELLED
+            item["finished_at"] = (now or datetime.now(timezone.utc)).isoformat()
+            self._save()
+        logger.info(f"Cancelled queued {item['operation']} ({item_id})")
+        return copy.deepcopy(item)
+
+    def take_due(self, now: Optional[datetime] = None) -> List[QueueItem]:
+        """Mark every pending item whose delay has passed as started, and return them"""
+        now = now or datetime.now(timezone.utc)
+        with self._lock:
+            due = [
+                item
+                for item in self._items
+                if item["status"] == STATUS_PENDING and datetime.fromisoformat(item["execute_after"]) <= now
+            ]
+            for item in due:
+                item["status"] = STATUS_STARTED
+                item["finished_at"] = now.isoformat()
+            if due:
+                self._save()
+            return [copy.deepcopy(item) for item in due]
+
+    def set_job(self, item_id: str, job_id: str) -> None:
+        """Record the job launched for a started item"""
+        self._update(item_id, job_id=job_id)
+
+    def set_failed(self, item_id: str, error: str) -> None:
+        """Record that the job of a started item could not be launched"""
+        self._update(item_id, status=STATUS_FAILED, error=error)
+
+    def _update(self, item_id: str, **fields: Any) -> None:
+        """Update fields of an item"""
+        with self._lock:
+            item = self._find(item_id)
+            if item is not None:
+                item.update(fields)  # type: ignore[typeddict-item]
+                self._save()
@@ -0,0 +1,219 @@
+"""
+Running one command against many Domino installs.
+
+Operators managing many Domino installs keep one config.yaml per install and
+list them in an environments file, one per line:
+
+    # name      config file (relative to this file)
+    prod-us     configs/prod-us.yaml
+    prod-eu     configs/prod-eu.yaml
+    configs/staging.yaml
+
+A line with only a config file is named after the file ("staging"). Blank
+lines and lines starting with # are ignored.
+
+main.py --environments-file runs the command once per install, sequentially
+or in parallel, each with its own config (CONFIG_FILE) and its own reports
+directory (OUTPUT_DIR) under environments/<name>/ in the reports directory, so
+inspection caches and checkpoints are kept per install. The runs share the
+batch's run ID and record the install's name (RUN_ENVIRONMENT) in the run
+metadata of their reports. The JSON reports every
+run wrote are then merged into one consolidated report. The per-install report
+files are removed afterwards unless they are kept (--per-environment-reports).
+"""
+
+import concurrent.futures
+import os
+import re
+import subprocess
+import sys
+import time
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import Any, Dict, List, Optional, TypedDict
+
+from utils.logging_utils import get_logger
+from utils.report_upload import find_run_reports
+from utils.report_utils import REPORT_COMPRESSIONS, load_json
+from utils.run_metadata import RUN_ENVIRONMENT_VARIABLE
+
+logger = get_logger(__name__)
+
+ENVIRONMENTS_DIR = "environments"
+
+_NAME_PATTERN = re.compile(r"^[A-Za-z0-9][A-Za-z0-9._-]*$")
+
+
+class BatchEnvironment(TypedDict):
+    """A Domino install listed in an environments file."""
+
+    name: str
+    config_file: str
+
+
+class EnvironmentRun(TypedDict):
+    """Outcome of running the command against one install."""
+
+    name: str
+    config_file: str
+    exit_code: int
+    duration_seconds: float
+    reports: List[Path]  # Report files the run wrote to its reports directory
+
+
+def load_environments_file(path: str) -> List[BatchEnvironment]:
+    """Read an environments file.
+
+    Raises:
+        ValueError: If a line is malformed, a name is repeated, a config file does not exist, or no install is listed
+    """
+    base_dir = Path(path).resolve().parent
+    environments: List[BatchEnvironment] = []
+    with open(path) as f:
+        for line_number, line in enumerate(f, start=1):
+            fields = line.split()
+            if not fields or fields[0].startswith("#"):
+                continue
+            if len(fields) > 2:
+                raise ValueError(f"{path}:{line_number}: expected '[name] <config file>', got: {line.strip()!r}")
+            config_file = base_dir / fields[-1]
+            name = fields[0] if len(fields) == 2 else config_file.stem
+            if not _NAME_PATTERN.match(name):
+                raise ValueError(f"{path}:{line_number}: invalid environment name {name!r}")
+            if any(environment["name"] == name for environment in environments):
+                raise ValueError(f"{path}:{line_number}: environment {name!r} is listed twice")
+            if not config_file.is_file():
+                raise ValueError(f"{path}:{line_number}: config file {config_file} not found")
+            environments.append({"name": name, "config_file": str(config_file)})
+    if not environments:
+        raise ValueError(f"{path} lists no environments")
+    return environments
+
+
+def _run_one(
+    environment: BatchEnvironment, command: List[str], reports_dir: Path, capture_output: bool
+) -> EnvironmentRun:
+    """Run the command against one install, logging its output"""
+    output_dir = reports_dir / ENVIRONMENTS_DIR / environment["name"]
+    output_dir.mkdir(parents=True, exist_ok=True)
+    env = {
+        **os.environ,
+        "CONFIG_FILE": environment["config_file"],
+        "OUTPUT_DIR": str(output_dir),
+        RUN_ENVIRONMENT_VARIABLE: environment["name"],
+    }
+    started = time.time()
+    result = subprocess.run(
+        [sys.executable, *command],
+        env=env,
+        stdout=subprocess.PIPE if capture_output else None,
+        stderr=subprocess.STDOUT if capture_output else None,
+        text=True,
+    )
+    if capture_output and result.stdout:
+        # Parallel runs log each install's output in one block, so installs do not interleave
+        for line in result.stdout.rstrip().splitlines():
+            logger.info(f"[{environment['name']}] {line}")
+    return {
+        "name": environment["name"],
+        "config_file": environment["config_file"],
+        "exit_code": result.returncode,
+        "duration_seconds": round(time.time() - started, 1),
+        "reports": find_run_reports(output_dir, started),
+    }
+
+
+def run_environments(
+    environments: List[BatchEnvironment], command: List[str], reports_dir: Path, max_parallel: int = 1
+) -> List[EnvironmentRun]:
+    """Run a command against every install.
+
+    Args:
+        environments: Installs to run against (load_environments_file)
+        command: Command line to run with the Python interpreter, e.g. [main.py, "image_size_report"]
+        reports_dir: Reports directory; each install writes to environments/<name>/ in it
+        max_parallel: Installs to run at the same time; with more than one, output is logged per install
+
+    Returns:
+        One run per install, in the order of environments
+    """
+    capture_output = max_parallel > 1
+    if not capture_output:
+        runs = []
+        for position, environment in enumerate(environments, start=1):
+            logger.info("\n" + "=" * 60)
+            logger.info(f"Environment {position}/{len(environments)}: {environment['name']}")
+            logger.info("=" * 60)
+            runs.append(_run_one(environment, command, reports_dir, capture_output))
+        return runs
+    with concurrent.futures.ThreadPoolExecutor(max_workers=max_parallel) as executor:
+        futures = [
+            executor.submit(_run_one, environment, command, reports_dir, capture_output)
+            for environment in environments
+        ]
+        return [future.result() for future in futures]
+
+
+def _report_name(path: Path) -> str:
+    """Name of a report file without its compression suffix"""
+    return path.stem if path.suffix in REPORT_COMPRESSIONS.values() else path.name
+
+
+def consolidate_reports(runs: List[EnvironmentRun], command: str, keep_reports: bool) -> Dict[str, Any]:
+    """Merge the JSON reports of every install's run into one report.
+
+    Args:
+        runs: Runs of the command (run_environments)
+        command: Command that was run, for the summary
+        keep_reports: Keep the per-install report files; otherwise they are removed once merged
+
+    Returns:
+        Dict with "summary" and "environments" (each with its exit code and its JSON reports by file name)
+    """
+    environments = []
+    for run in runs:
+        reports: Dict[str, Any] = {}
+        for path in run["reports"]:
+            if _report_name(path).endswith(".json"):
+                try:
+                    reports[_report_name(path)] = load_json(path)
+                except ValueError as e:
+                    logger.warning(f"Could not read {path}: {e}")
+            if not keep_reports:
+                path.unlink()
+        environments.append(
+            {
+                "name": run["name"],
+                "config_file": run["config_file"],
+                "status": "success" if run["exit_code"] == 0 else "failure",
+                "exit_code": run["exit_code"],
+                "duration_seconds": run["duration_seconds"],
+                "reports": reports,
+                # Report files kept in environments/<name>/, including CSV and HTML reports
+                "files": [path.name for path in run["reports"]] if keep_reports else [],
+            }
+        )
+    return {
+        "summary": {
+            "command": command,
+            "environments": len(runs),
+            "succeeded": sum(1 for run in runs if run["exit_code"] == 0),
+            "failed": [run["name"] for run in runs if run["exit_code"] != 0],
+            "per_environment_reports": keep_reports,
+            "generated_at": datetime.now(timezone.utc).isoformat(),
+        },
+        "environments": environments,
+    }
+
+
+def print_batch_summary(runs: List[EnvironmentRun], consolidated_path: Optional[str]) -> None:
+    """Log the status of every install's run"""
+    logger.info("\n" + "=" * 60)
+    logger.info("   Environments")
+    logger.info("=" * 60)
+    for run in runs:
+        status = "✅" if run["exit_code"] == 0 else f"❌ exit {run['exit_code']}"
+        duration = f"{run['duration_seconds']:.1f}s"
+        logger.info(f"   {run['name']:<24} {status:<12} {duration:>9}  {len(run['reports'])} report(s)")
+    if consolidated_path:
+        logger.info(f"\nConsolidated report: {consolidated_path}")
@@ -2,11 +2,13 @@
 Error message utilities for providing actionable guidance to users.
 
 This module provides functions to create helpful error messages with
-suggested fixes and troubleshooting steps.
+suggested fixes and troubleshooting steps, and the machine-readable error
+codes attached to per-tag failures in reports and to exit statuses.
 """
 
+import re
 from enum import Enum
-from typing import Any, Dict, List, Optional
+from typing import Any, Dict, Iterable, List, Optional, Union
 
 
 class ErrorCategory(Enum):
@@ -22,6 +24,31 @@ class ErrorCategory(Enum):
     UNKNOWN = "unknown"
 
 
+# Machine-readable codes of failures, for automation deciding what to retry
+ERROR_AUTH = "AUTH"  # Credentials missing, rejected or expired
+ERROR_NOT_FOUND = "NOT_FOUND"  # The tag or repository does not exist (e.g. deleted meanwhile)
+ERROR_RATE_LIMITED = "RATE_LIMITED"  # The registry throttled requests (429)
+ERROR_TIMEOUT = "TIMEOUT"  # A request or skopeo command timed out
+ERROR_UNAVAILABLE = "UNAVAILABLE"  # The registry could not be reached or failed (5xx)
+ERROR_PARSE = "PARSE"  # The registry or skopeo answered with output that could not be used
+ERROR_UNKNOWN = "UNKNOWN"
+
+RETRYABLE_ERROR_CODES = (ERROR_RATE_LIMITED, ERROR_TIMEOUT, ERROR_UNAVAILABLE)
+
+# Exit status of a run that failed with each code, in order of precedence: a run
+# whose failures have several codes exits with the first, so it only exits with
+# a retryable status when every failure is retryable
+ERROR_EXIT_STATUSES = {
+    ERROR_AUTH: 10,
+    ERROR_PARSE: 15,
+    ERROR_UNKNOWN: 1,
+    ERROR_NOT_FOUND: 11,
+    ERROR_UNAVAILABLE: 14,
+    ERROR_TIMEOUT: 13,
+    ERROR_RATE_LIMITED: 12,
+}
+
+
 class ActionableError(Exception):
     """Exception with actionable guidance for users"""
 
@@ -256,3 +283,52 @@ def create_rate_limit_error(operation: str, retry_after: Optional[float] = None)
         suggestions=suggestions,
         details={"operation": operation, "retry_after": retry_after},
     )
+
+
+def _has_status(text: str, *statuses: int) -> bool:
+    """Whether text mentions one of these HTTP statuses (as a number on its own, not part of a port)"""
+    return re.search(r"(?<![\w:.])(%s)(?![\w.])" % "|".join(str(status) for status in statuses), text) is not None
+
+
+def classify_error(error: Union[BaseException, str]) -> str:
+    """Machine-readable code (ERROR_*) of an exception or error message"""
+    if isinstance(error, str):
+        error_type, text = "", error
+    else:
+        error_type, text = type(error).__name__, str(error)
+        if isinstance(error, ActionableError):
+            error_type = error.details.get("error_type", "")
+            text = f"{error.message} {error.details.get('error_message', '')}"
+            if error.category == ErrorCategory.AUTHENTICATION:
+                return ERROR_AUTH
+    text = text.lower()
+
+    if error_type in ("TimeoutExpired", "TimeoutError", "timeout") or "timed out" in text or "timeout" in text:
+        return ERROR_TIMEOUT
+    if _has_status(text, 429) or "rate limit" in text or "too many requests" in text:
+        return ERROR_RATE_LIMITED
+    if error_type in ("JSONDecodeError", "PlanFormatError") or "failed to parse" in text:
+        return ERROR_PARSE
+    if _has_status(text, 401, 403) or any(
+        marker in text for marker in ("unauthorized", "forbidden", "authentication required")
+    ):
+        return ERROR_AUTH
+    if (
+        error_type == "ImageNotFoundError"
+        or _has_status(text, 404)
+        or any(marker in text for marker in ("not found", "manifest unknown", "name unknown"))
+    ):
+        return ERROR_NOT_FOUND
+    if _has_status(text, 500, 502, 503, 504) or any(
+        marker in text for marker in ("connect", "refused", "unreachable", "reset by peer", "unavailable")
+    ):
+        return ERROR_UNAVAILABLE
+    return ERROR_UNKNOWN
+
+
+def error_exit_status(codes: Iterable[str]) -> int:
+    """Exit status of a run that failed with these error codes (0 if there are none)"""
+    failed = {code if code in ERROR_EXIT_STATUSES else ERROR_UNKNOWN for code in codes}
+    if not failed:
+        return 0
+    return next(status for code, status in ERROR_EXIT_STATUSES.items() if code in failed)
@@ -830,7 +830,8 @@ def run(target: str) -> None:
 
     Args:
             target: Which aggregation(s) to run ('model', 'workspace', 'runs',
-                    'projects', 'scheduler_jobs', 'organizations', 'app_versions', or 'all')
+                    'projects', 'scheduler_jobs', 'organizations', 'app_versions',
+                    'references', or 'all')
     """
     from utils.image_usage import ImageUsageService  # Local import to avoid circular dependency
 
@@ -845,7 +846,17 @@ def main() -> None:
     parser = argparse.ArgumentParser(description="Extract metadata from MongoDB using PyMongo")
     parser.add_argument(
         "--target",
-        choices=["model", "workspace", "runs", "projects", "scheduler_jobs", "organizations", "app_versions", "all"],
+        choices=[
+            "model",
+            "workspace",
+            "runs",
+            "projects",
+            "scheduler_jobs",
+            "organizations",
+            "app_versions",
+            "references",
+            "all",
+        ],
         default="all",
         help="Which aggregation(s) to run",
     )
@@ -0,0 +1,56 @@
+"""
+Foreign (non-distributable) layers.
+
+Some images reference layers that are not stored in the registry: Windows base
+images, for example, list their base layers with a foreign or non-distributable
+media type and a URL to download them from elsewhere. The registry only holds
+the manifest's reference to such a layer, so deleting the image frees none of
+the layer's size even though the manifest reports it.
+
+Image analysis marks these layers ("Foreign": true in the layer data) so that
+reclaimable-space calculations leave them out and reports can flag the images
+that contain them.
+"""
+
+from typing import Any, Dict, List, Optional
+
+FOREIGN_LAYER_MEDIA_TYPES = (
+    "application/vnd.docker.image.rootfs.foreign.diff.tar.gzip",
+    "application/vnd.oci.image.layer.nondistributable.v1.tar",
+    "application/vnd.oci.image.layer.nondistributable.v1.tar+gzip",
+    "application/vnd.oci.image.layer.nondistributable.v1.tar+zstd",
+)
+
+
+def is_foreign_media_type(media_type: Optional[str]) -> bool:
+    """Whether a layer media type marks a layer stored outside the registry"""
+    if not media_type:
+        return False
+    return media_type in FOREIGN_LAYER_MEDIA_TYPES or ".foreign." in media_type or ".nondistributable." in media_type
+
+
+def mark_foreign_layers(layers: List[Dict[str, Any]], media_type_key: str) -> List[Dict[str, Any]]:
+    """Convert layer descriptors to layer data, marking foreign layers.
+
+    Args:
+        layers: Layer descriptors with "Digest"/"digest" and "Size"/"size" keys
+        media_type_key: Key holding each layer's media type ("MIMEType" in skopeo
+            inspect LayersData, "mediaType" in manifests)
+
+    Returns:
+        [{"Digest", "Size", "MediaType"}, ...] with "Foreign": True on foreign
+        layers ("MediaType" only where the descriptor has one)
+    """
+    layers_data = []
+    for layer in layers:
+        layer_data = {
+            "Digest": layer.get("Digest", layer.get("digest")),
+            "Size": layer.get("Size", layer.get("size", 0)),
+        }
+        media_type = layer.get(media_type_key)
+        if media_type:
+            layer_data["MediaType"] = media_type
+        if is_foreign_media_type(media_type):
+            layer_data["Foreign"] = True
+        layers_data.append(layer_data)
+    return layers_data
@@ -0,0 +1,94 @@
+"""
+Per-host concurrency limits for registry requests.
+
+The worker pool sizes (--max-workers) bound how much work a run does at once,
+but not how much of it lands on one registry host. A run that talks to a fast
+ECR and a fragile on-prem Harbor, or a large worker pool pointed at a small
+registry, can open more connections than that host copes with.
+
+A host limiter caps the requests in flight to one host, whichever worker or
+client sends them. skopeo commands and native HTTP requests to the same host
+share one limiter; each of them holds one slot for as long as its connection
+is open. Requests over the limit wait for a free slot instead of failing.
+
+Limits are set under skopeo.host_limits: max_concurrent_requests applies to
+every host, and hosts overrides it for individual hosts. 0 means unlimited.
+"""
+
+import contextlib
+import threading
+from typing import Dict, Iterator, Optional
+
+from utils.circuit_breaker import host_key
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+
+class HostLimiter:
+    """Concurrency limit for one registry host."""
+
+    def __init__(self, host: str, max_concurrent: int):
+        """Initialize the limiter
+
+        Args:
+            host: Registry host the limit applies to (used in log messages)
+            max_concurrent: Most requests in flight to the host at once
+        """
+        self.host = host
+        self.max_concurrent = max_concurrent
+        self._cond = threading.Condition()
+        self.in_flight = 0
+        self.peak_in_flight = 0
+        self.times_waited = 0
+
+    @contextlib.contextmanager
+    def slot(self) -> Iterator[None]:
+        """Hold one of the host's request slots, waiting for one to become free."""
+        with self._cond:
+            if self.in_flight >= self.max_concurrent:
+                self.times_waited += 1
+                logger.debug(f"{self.host}: {self.in_flight} requests in flight, waiting for a free slot")
+            while self.in_flight >= self.max_concurrent:
+                self._cond.wait()
+            self.in_flight += 1
+            self.peak_in_flight = max(self.peak_in_flight, self.in_flight)
+        try:
+            yield
+        finally:
+            with self._cond:
+                self.in_flight -= 1
+                self._cond.notify()
+
+
+_limiters: Dict[str, HostLimiter] = {}
+_limiters_lock = threading.Lock()
+
+
+def get_host_limiter(registry_url: str) -> Optional[HostLimiter]:
+    """Get the shared concurrency limiter of a registry host.
+
+    All clients talking to the same host share one limiter, so skopeo commands
+    and native HTTP requests count against the same limit.
+
+    Returns:
+        The host's HostLimiter, or None if the host has no limit
+    """
+    from utils.config_manager import config_manager
+
+    host = host_key(registry_url)
+    limit = config_manager.get_host_concurrency_limit(host)
+    if limit == 0:
+        return None
+    with _limiters_lock:
+        limiter = _limiters.get(host)
+        if limiter is None:
+            limiter = HostLimiter(host, limit)
+            _limiters[host] = limiter
+            logger.info(f"Limiting {host} to {limit} concurrent requests")
+        return limiter
+
+
+def host_slot(limiter: Optional[HostLimiter]) -> contextlib.AbstractContextManager:
+    """A request slot of a limiter, or a no-op context without a limit."""
+    return limiter.slot() if limiter is not None else contextlib.nullcontext()
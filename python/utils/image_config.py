@@ -0,0 +1,86 @@
+"""
+Details read from image config blobs.
+
+A default scan reads what skopeo inspect reports about each tag: its layers,
+labels and creation time. A deep scan (--deep) also fetches the config blob of
+every distinct image, once per manifest digest, and keeps the fields a default
+scan cannot see:
+
+    {"diff_ids": ["sha256:..."],
+     "history": [{"created": "2024-05-01T12:00:00Z", "created_by": "RUN pip install ...", "empty_layer": false}],
+     "user": "domino", "entrypoint": ["/opt/entrypoint.sh"], "cmd": ["bash"],
+     "working_dir": "/mnt", "env": ["PATH=/usr/bin", "API_TOKEN=[REDACTED]"]}
+
+This is one extra registry request per distinct image, so it is opt-in. Env
+values of sensitive names are redacted like labels (see utils/redaction.py).
+"""
+
+from typing import Any, List, Optional, TypedDict
+
+from utils.redaction import redact_env
+
+
+class HistoryEntry(TypedDict, total=False):
+    """One build step of an image, from its config's history."""
+
+    created: str
+    created_by: str  # the Dockerfile instruction or command that made the step
+    comment: str
+    empty_layer: bool  # True for metadata-only steps (ENV, LABEL, ...) that add no layer
+
+
+class ImageConfigDetails(TypedDict):
+    """Fields of an image config that a default scan does not read."""
+
+    diff_ids: List[str]  # uncompressed digest of each layer, in layer order
+    history: List[HistoryEntry]
+    user: Optional[str]
+    entrypoint: Optional[List[str]]
+    cmd: Optional[List[str]]
+    working_dir: Optional[str]
+    env: Optional[List[str]]
+
+
+def _string_list(value: Any) -> Optional[List[str]]:
+    """A list of strings from a config field, or None if it is not one"""
+    if not isinstance(value, list):
+        return None
+    return [str(item) for item in value]
+
+
+def config_details(image_config: Any, redact_patterns: List[str]) -> Optional[ImageConfigDetails]:
+    """Extract the deep-scan fields of a parsed image config.
+
+    Args:
+        image_config: Parsed image config blob (skopeo inspect --config)
+        redact_patterns: Env names whose values are redacted (reports.redact_keys)
+
+    Returns:
+        The config's details, or None if it is not an image config
+    """
+    if not isinstance(image_config, dict):
+        return None
+    runtime = image_config.get("config") if isinstance(image_config.get("config"), dict) else {}
+    rootfs = image_config.get("rootfs") if isinstance(image_config.get("rootfs"), dict) else {}
+
+    history: List[HistoryEntry] = []
+    for step in image_config.get("history") or []:
+        if not isinstance(step, dict):
+            continue
+        entry: HistoryEntry = {}
+        for key in ("created", "created_by", "comment"):
+            if isinstance(step.get(key), str):
+                entry[key] = step[key]  # type: ignore[literal-required]
+        entry["empty_layer"] = bool(step.get("empty_layer"))
+        history.append(entry)
+
+    return {
+        "diff_ids": _string_list(rootfs.get("diff_ids")) or [],
+        "history": history,
+        "user": runtime.get("User") or None,
+        "entrypoint": _string_list(runtime.get("Entrypoint")),
+        "cmd": _string_list(runtime.get("Cmd")),
+        "working_dir": runtime.get("WorkingDir") or None,
+        "env": redact_env(_string_list(runtime.get("Env")), redact_patterns),
+    }
+
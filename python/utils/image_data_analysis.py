@@ -9,53 +9,102 @@ Data Model:
 - Layers: dict mapping layer_id -> {size_bytes, ref_count}
 - Images: dict mapping image_id -> {repository, tag, digest}
 - Image-to-Layer Mapping: list of {image_id, layer_id, order_index}
+
+The three structures live in a thread-safe ImageIndex (see utils/image_index.py),
+kept in memory by default and moved to an on-disk SQLite index once the number
+of tags exceeds analysis.disk_index_threshold.
 """
 
 import argparse
 import concurrent.futures
 import logging
 import sys
+import threading
 from collections import Counter
+from dataclasses import dataclass, field
+from datetime import date, datetime, timezone
 from pathlib import Path
-from typing import Any, Dict, List, Optional, TypedDict
+from typing import Any, Callable, Dict, List, Optional, Pattern, Set, Tuple, TypedDict
 
 # Add parent directory to path for imports
 _parent_dir = Path(__file__).parent.parent.absolute()
 if str(_parent_dir) not in sys.path:
     sys.path.insert(0, str(_parent_dir))
 
+from utils.cache_utils import DigestInspectCache
 from utils.config_manager import SkopeoClient, config_manager
+from utils.error_utils import (
+    ERROR_NOT_FOUND,
+    ERROR_UNKNOWN,
+    RETRYABLE_ERROR_CODES,
+    classify_error,
+    error_exit_status,
+)
+from utils.deletion_candidates import parse_created
+from utils.foreign_layers import mark_foreign_layers
+from utils.image_config import HistoryEntry, ImageConfigDetails, config_details
+from utils.image_index import (
+    ImageData,
+    ImageIndex,
+    ImageLayerMapping,
+    InMemoryImageIndex,
+    LayerData,
+    SqliteImageIndex,
+)
+from utils.layer_contents import (
+    LayerListing,
+    find_duplicate_files,
+    find_files,
+    list_layer_entries,
+    select_layers,
+)
+from utils.image_expiry import ImageExpiry, image_expiry
 from utils.logging_utils import get_logger, setup_logging
-from utils.object_id_utils import read_typed_object_ids_from_file
-from utils.report_utils import save_json
+from utils.manifest_schema1 import LEGACY_FORMAT_SCHEMA1, is_schema1, is_schema1_inspection, normalize_schema1
+from utils.media_types import CATEGORY_FOREIGN, is_encrypted_media_type, media_type_category
+from utils.object_id_utils import read_image_object_id_filters
+from utils.oci_annotations import ANNOTATION_CREATED, manifest_annotations
+from utils.os_release import (
+    SOURCE_LAYER,
+    OsRelease,
+    eol_date,
+    infer_release,
+    is_eol,
+    parse_os_release,
+    read_os_release,
+)
+from utils.ownership import image_labels, owner_from_labels
+from utils.partial_results import PartialResultWriter
+from utils.progress_events import PHASE_FINISH_REPOSITORY, PHASE_RETRY, PHASE_SCAN, ProgressEvents
+from utils.provenance import (
+    MAX_ATTESTATION_BYTES,
+    Provenance,
+    attestation_references,
+    is_provenance_layer,
+    parse_provenance,
+)
+from utils.redaction import redact_labels
+from utils.report_utils import save_json, sizeof_fmt
+from utils.request_stats import get_run_stats
+from utils.statsd_metrics import emit_run_metrics
+from utils.scan_snapshot import prune_snapshots, save_snapshot
+from utils.secret_scan import SecretFinding, compile_patterns, scan_history, scan_metadata
+from utils.size_breakdown import SizeBreakdown, break_down_image
+from utils.tag_matching import extract_tag_namespace, is_excluded_tag, parse_reference_tag
+from utils.time_format import age_days
+from utils.toolchain import (
+    REASON_EOL_OS,
+    OutdatedToolchain,
+    ToolchainInfo,
+    base_image,
+    outdated_reasons,
+    toolchain_info,
+)
 
 logger = get_logger(__name__)
 
 
 # TypedDict definitions for structured data
-class LayerData(TypedDict):
-    """Layer information stored in the analyzer."""
-
-    size_bytes: int
-    ref_count: int
-
-
-class ImageData(TypedDict):
-    """Image metadata stored in the analyzer."""
-
-    repository: str
-    tag: str
-    digest: str
-
-
-class ImageLayerMapping(TypedDict):
-    """Mapping between an image and one of its layers."""
-
-    image_id: str
-    layer_id: str
-    order_index: int
-
-
 class InspectionResult(TypedDict, total=False):
     """Result from inspecting a single tag."""
 
@@ -64,6 +113,22 @@ class InspectionResult(TypedDict, total=False):
     tag: str
     digest: str
     layers_data: List[Dict[str, Any]]
+    source: str  # "inspect", "cache", "alias" or "manifest"
+    change: str  # "unchanged", "changed" or "new" since the previous scan (digest-keyed scans only)
+    created: Optional[str]  # image creation time (ISO 8601), if known
+    legacy_format: Optional[str]  # "schema1" for images with a legacy manifest, else None
+    annotations: Optional[Dict[str, str]]  # OCI manifest/index annotations, None if not collected
+    labels: Optional[Dict[str, str]]  # image config labels, None if the image config was not read
+    secret_findings: Optional[List[SecretFinding]]  # possible secrets in Env and labels, None if the config was not read
+    toolchain: Optional[ToolchainInfo]  # DockerVersion, OS and architecture, None if the config was not read
+
+
+class TagFailure(TypedDict):
+    """A tag the scan could not inspect."""
+
+    code: str  # error_utils ERROR_* code, e.g. "TIMEOUT"
+    retryable: bool  # Whether retrying may succeed (code in RETRYABLE_ERROR_CODES)
+    message: str
 
 
 class LegacyLayerData(TypedDict):
@@ -88,21 +153,302 @@ class SummaryStats(TypedDict):
     avg_ref_count: float
 
 
+class RepositorySummary(TypedDict):
+    """Per-repository composition and deduplication statistics."""
+
+    repository: str
+    tag_count: int
+    layer_count: int
+    total_bytes: int
+    unique_layers: int
+    unique_bytes: int
+    shared_layers: int
+    shared_bytes: int
+    introduced_layers: int  # Layers an image of this repository was the first to reference (see layer_origins)
+    introduced_bytes: int
+    largest_image: Optional[Dict[str, Any]]
+    oldest_image: Optional[Dict[str, Any]]  # image_id, tag, created, age_days
+    newest_image: Optional[Dict[str, Any]]
+
+
+class LayerOrigin(TypedDict):
+    """The image that introduced a layer: the earliest created image referencing it."""
+
+    image_id: str
+    repository: str
+    namespace: str  # Repository plus the environment/model ObjectID the tag starts with
+    tag: str
+    created: str
+    consumers: int  # Images referencing the layer, the introducing image included
+    consumer_namespaces: int
+
+
+class SharedLayerIntroducer(TypedDict):
+    """Layers shared between namespaces, attributed to the namespace that introduced them."""
+
+    namespace: str
+    repository: str
+    layers: int
+    bytes: int
+    consumer_namespaces: int  # Other namespaces referencing at least one of the layers
+
+
+class DuplicateImageGroup(TypedDict):
+    """Images sharing one manifest digest across different namespaces."""
+
+    digest: str
+    size_bytes: int
+    namespaces: List[str]
+    image_ids: List[str]
+    duplicated_bytes: int
+    canonical_image_id: str
+    canonicalization_plan: List[Dict[str, str]]
+
+
+class TagAliasGroup(TypedDict):
+    """Tags of one repository that point at the same manifest digest."""
+
+    repository: str
+    digest: str
+    tags: List[str]
+    size_bytes: int
+
+
+class CrossRepositoryDuplicate(TypedDict):
+    """Identical manifest stored in more than one repository."""
+
+    digest: str
+    repositories: List[str]
+    image_ids: List[str]
+    size_bytes: int
+
+
+class DuplicateLayerGroup(TypedDict):
+    """Layers with different blob digests but identical uncompressed content (diffID)."""
+
+    diff_id: str
+    layer_ids: List[str]
+    size_bytes: int
+    duplicated_bytes: int
+
+
+class AttachedArtifact(TypedDict):
+    """Signature, attestation or SBOM stored under a digest-style reference tag."""
+
+    repository: str
+    tag: str
+    kind: str  # tag suffix, e.g. "sig", "att" or "sbom"
+    subject_digest: str
+
+
+class DeletionSimulation(TypedDict):
+    """What-if result of deleting a set of images."""
+
+    image_ids: List[str]
+    missing_image_ids: List[str]
+    freed_bytes: int
+    freed_layers: List[Dict[str, Any]]
+    retained_bytes: int
+    retained_layers: List[Dict[str, Any]]
+    foreign_bytes: int  # size of foreign layers, which are not stored in the registry and never freed
+    foreign_layers: List[Dict[str, Any]]
+
+
+class PullEstimate(TypedDict):
+    """Estimated cold pull of one image."""
+
+    image_id: str
+    repository: str
+    tag: str
+    pull_bytes: int  # compressed bytes of every layer, including foreign layers fetched from elsewhere
+    layer_count: int
+    largest_layer_bytes: int
+    estimated_seconds: float
+
+
+class MediaTypeUsage(TypedDict):
+    """Layers and bytes stored with one layer media type."""
+
+    media_type: Optional[str]  # None for layers whose media type is not known
+    category: str  # see utils.media_types
+    layers: int
+    bytes: int
+
+
+class OversizedLayer(TypedDict):
+    """A layer larger than the oversized-layer threshold (analysis.oversized_layers)."""
+
+    layer_id: str
+    size_bytes: int
+    image_ids: List[str]  # images containing the layer
+
+
+class EolImage(TypedDict):
+    """An image running on an OS release past its end of life."""
+
+    image_id: str
+    distro: str
+    version: str
+    eol_date: str
+    source: str  # where the release was read (utils.os_release SOURCE_*)
+
+
+class ImageAttribution(TypedDict):
+    """Owner and registry bytes of one image."""
+
+    image_id: str
+    repository: str
+    tag: str
+    owner: str
+    size_bytes: int
+    exclusive_bytes: int  # layers no other image uses (freed if only this image were deleted)
+
+
+class OwnerUsage(TypedDict):
+    """Images and registry bytes attributed to one owner."""
+
+    owner: str  # owner label value, or utils.ownership.UNLABELED / UNKNOWN
+    images: int
+    total_bytes: int  # distinct layers used by the owner's images
+    exclusive_bytes: int  # layers used only by the owner's images (freed if all of them were deleted)
+    shared_bytes: int  # layers also used by other owners' images
+    amortized_bytes: int  # each layer's size split evenly between the images using it
+
+
+# Exit status of a scan that found layers over the oversized-layer threshold
+# with analysis.oversized_layers.fail_run set (tag failures take precedence)
+OVERSIZED_LAYERS_EXIT_STATUS = 3
+
+
+@dataclass
+class _RepositoryScan:
+    """Progress of one repository through the analyze_images pipeline."""
+
+    image_type: str
+    tags: List[str]  # tags to inspect
+    reference_tags: Dict[str, Tuple[str, str]]  # reference tag -> parsed (digest, kind)
+    completed: int = 0  # tags recorded, inspected or failed
+    inspected: int = 0
+    # index in tags -> finished inspection waiting for the tags before it to be recorded
+    finished: Dict[int, concurrent.futures.Future] = field(default_factory=dict)
+    sources: Counter = field(default_factory=Counter)  # InspectionResult source -> count
+    changes: Counter = field(default_factory=Counter)  # change since last scan -> count
+
+    @property
+    def total(self) -> int:
+        return len(self.tags)
+
+
 class ImageAnalyzer:
     """Analyzes Docker images and their layers using native Python data structures."""
 
-    def __init__(self, registry_url: str, repository: str) -> None:
+    def __init__(self, registry_url: str, repository: str, skopeo_client: Optional[SkopeoClient] = None) -> None:
         self.registry_url: str = registry_url
         self.repository: str = repository
-        self.skopeo_client: SkopeoClient = SkopeoClient(config_manager)
+        self.skopeo_client: SkopeoClient = skopeo_client or SkopeoClient(config_manager)
+
+        # Thread-safe store for layers, images and image-to-layer mappings
+        self.index: ImageIndex = InMemoryImageIndex()
+
+        # Layers of previously inspected manifests, shared between runs
+        cache_path = None
+        if config_manager.is_cache_enabled():
+            cache_path = config_manager.get_inspect_cache_path()
+        self.inspect_cache: DigestInspectCache = DigestInspectCache(cache_path)
+
+        # Layers of manifests inspected during this run, so alias tags pointing at
+        # the same digest share one inspection even when the persistent cache is off
+        self._run_digests: Dict[str, List[Dict[str, Any]]] = {}
+        self._digest_inflight: Dict[str, threading.Event] = {}
+        self._digest_lock = threading.Lock()
+
+        # image_id -> image creation time (ISO 8601), where known
+        self.created: Dict[str, str] = {}
+
+        # image_id -> legacy manifest format ("schema1"), for images that have one
+        self.legacy_formats: Dict[str, str] = {}
+
+        # image_id -> OCI annotations of the image's manifest or index, for images
+        # whose annotations were collected ({} for manifests without annotations)
+        self.annotations: Dict[str, Dict[str, str]] = {}
+
+        # image_id -> labels of the image's config, for images whose config was read
+        self.labels: Dict[str, Dict[str, str]] = {}
+
+        # Layers stored outside the registry (foreign/non-distributable media types),
+        # whose size deleting an image never frees
+        self.foreign_layers: Set[str] = set()
+
+        # layer_id -> media type declared for the layer, where known
+        self.layer_media_types: Dict[str, str] = {}
+
+        # image_id -> artifacts whose reference tag names the image's digest
+        self.attached_artifacts: Dict[str, List[AttachedArtifact]] = {}
+
+        # image_id -> SLSA provenance of the image, or None if it has none; only
+        # images whose provenance was collected have an entry
+        self.provenance: Dict[str, Optional[Provenance]] = {}
+
+        # image_id -> details read from the image's config blob (diffIDs, history,
+        # user, entrypoint, ...); only images of a deep scan have an entry
+        self.config_details: Dict[str, ImageConfigDetails] = {}
+
+        # image_id -> possible secrets in the image's Env, labels and (deep scans)
+        # build history; only images with findings have an entry
+        self.secret_findings: Dict[str, List[SecretFinding]] = {}
+        self._secret_patterns: Optional[List[Tuple[str, Pattern[str]]]] = None
+
+        # image_id -> build fields (DockerVersion, OS, architecture), for images whose config was read
+        self.toolchain: Dict[str, ToolchainInfo] = {}
 
-        # Initialize data structures
-        self.layers: Dict[str, LayerData] = {}  # layer_id -> {size_bytes, ref_count}
-        self.images: Dict[str, ImageData] = {}  # image_id -> {repository, tag, digest}
-        self.image_layers: List[ImageLayerMapping] = []  # [{image_id, layer_id, order_index}, ...]
+        # image_id -> OS release the image runs on, for images of a deep scan whose release was inferred
+        self.os_releases: Dict[str, OsRelease] = {}
+        self._layer_os_releases: Dict[str, Optional[OsRelease]] = {}
+
+        # layer_id -> tar entries of the layer, for layers read by a contents scan
+        self.layer_contents: Dict[str, LayerListing] = {}
+
+        # image_id -> why the tag could not be inspected, for tags that failed
+        self.failures: Dict[str, TagFailure] = {}
+
+        # Writes the results found so far while the scan runs, if partial results are enabled
+        self.partial_results: Optional[PartialResultWriter] = None
+
+        # Receives structured progress events, for applications embedding the analyzer
+        self.progress: Optional[ProgressEvents] = None
 
         self.logger: logging.Logger = get_logger(__name__)
 
+    @property
+    def layers(self) -> Dict[str, LayerData]:
+        """layer_id -> {size_bytes, ref_count}"""
+        return self.index.layers
+
+    @layers.setter
+    def layers(self, value: Dict[str, LayerData]) -> None:
+        with self.index.lock:
+            self.index.layers = value
+
+    @property
+    def images(self) -> Dict[str, ImageData]:
+        """image_id -> {repository, tag, digest}"""
+        return self.index.images
+
+    @images.setter
+    def images(self, value: Dict[str, ImageData]) -> None:
+        with self.index.lock:
+            self.index.images = value
+
+    @property
+    def image_layers(self) -> List[ImageLayerMapping]:
+        """[{image_id, layer_id, order_index}, ...]"""
+        return self.index.image_layers
+
+    @image_layers.setter
+    def image_layers(self, value: List[ImageLayerMapping]) -> None:
+        with self.index.lock:
+            self.index.image_layers = value
+
     def filter_tags_by_object_ids(self, tags: List[str], object_ids: Optional[List[str]] = None) -> List[str]:
         """Filter tags to only include those that start with one of the provided ObjectIDs.
 
@@ -138,15 +484,45 @@ class ImageAnalyzer:
         """
         try:
             # Inspect image using standardized client
-            image_info = self.skopeo_client.inspect_image(f"{self.repository}/{image_type}", tag)
+            repository = f"{self.repository}/{image_type}"
+            image_info = self.skopeo_client.inspect_image(repository, tag)
             if not image_info:
                 self.logger.error(f"Failed to inspect image {image_type}:{tag}")
+                self._record_failure(image_type, tag, "inspection failed")
                 return None
 
             # Extract image metadata
             digest = image_info.get("Digest", "")
             image_id = f"{image_type}:{tag}"
-            layers_data = image_info.get("LayersData", [])
+            layers_data = mark_foreign_layers(image_info.get("LayersData") or [], "MIMEType")
+            created = image_info.get("Created")
+            legacy_format = None
+            annotations = None
+            raw_labels = image_labels(image_info.get("Labels"))
+            env = image_info.get("Env") if isinstance(image_info.get("Env"), list) else None
+            # Secrets are looked for before the labels are redacted for reports
+            secret_findings = scan_metadata(
+                env, raw_labels, self._get_secret_patterns(), config_manager.get_redact_key_patterns()
+            )
+            labels = redact_labels(raw_labels, config_manager.get_redact_key_patterns())
+            toolchain = toolchain_info(image_info)
+
+            # skopeo inspect reports neither annotations nor schema1 layers, so
+            # those come from the raw manifest (or index, for multi-arch images)
+            schema1 = is_schema1_inspection(image_info)
+            collect_annotations = config_manager.is_annotation_collection_enabled()
+            manifest = None
+            if schema1 or collect_annotations:
+                manifest_result = self.skopeo_client.get_manifest(repository, tag)
+                manifest = manifest_result[1] if manifest_result else None
+            if collect_annotations and manifest is not None:
+                annotations = manifest_annotations(manifest)
+            if schema1:
+                legacy_format = LEGACY_FORMAT_SCHEMA1
+                layers_data, created = self._inspect_schema1(image_type, tag, image_info, manifest)
+            self._remember_digest(
+                digest, layers_data or [], created, legacy_format, annotations, labels, secret_findings, toolchain
+            )
 
             return {
                 "image_id": image_id,
@@ -154,13 +530,593 @@ class ImageAnalyzer:
                 "tag": tag,
                 "digest": digest,
                 "layers_data": layers_data,
+                "source": "inspect",
+                "created": created,
+                "legacy_format": legacy_format,
+                "annotations": annotations,
+                "labels": labels,
+                "secret_findings": secret_findings,
+                "toolchain": toolchain,
             }
         except Exception as e:
             self.logger.error(f"Error inspecting {image_type}:{tag}: {e}")
+            self._record_failure(image_type, tag, str(e), classify_error(e))
+            return None
+
+    def _inspect_schema1(
+        self, image_type: str, tag: str, image_info: Dict[str, Any], manifest: Optional[Dict[str, Any]]
+    ) -> Tuple[List[Dict[str, Any]], Optional[str]]:
+        """Get the layers and creation time of a schema1 (legacy manifest) image.
+
+        skopeo reports no layer sizes for schema1 images and lists the empty
+        layer of every metadata-only step, so the layers are read from the
+        manifest instead (see utils/manifest_schema1.py) and sized with blob
+        HEAD requests. Layers whose blob size cannot be fetched fall back to the
+        uncompressed size recorded in the manifest history, or 0.
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag
+            image_info: skopeo inspect result for the tag
+            manifest: Raw manifest of the tag, or None if it could not be fetched
+
+        Returns:
+            Tuple of (layers_data as [{"Digest", "Size"}, ...], creation time)
+        """
+        repository = f"{self.repository}/{image_type}"
+        created = image_info.get("Created")
+        if manifest and is_schema1(manifest):
+            normalized = normalize_schema1(manifest)
+            layers = [(layer["digest"], layer["size"]) for layer in normalized["layers"]]
+            created = created or normalized["created"]
+        else:
+            self.logger.warning(f"Could not read the schema1 manifest of {image_type}:{tag}; using skopeo's layer list")
+            layers = [(digest, None) for digest in dict.fromkeys(image_info.get("Layers") or [])]
+
+        layers_data = []
+        unsized = 0
+        for digest, history_size in layers:
+            size = self.skopeo_client.get_blob_size(repository, digest)
+            if size is None:
+                unsized += 1
+                size = history_size or 0
+            layers_data.append({"Digest": digest, "Size": size})
+
+        self.logger.info(f"{image_type}:{tag} has a legacy schema1 manifest ({len(layers_data)} layers)")
+        if unsized:
+            self.logger.warning(
+                f"Could not fetch the size of {unsized} layer(s) of {image_type}:{tag}; "
+                "sizes are estimated from the manifest history"
+            )
+        return layers_data, created
+
+    def _remember_digest(
+        self,
+        digest: str,
+        layers_data: List[Dict[str, Any]],
+        created: Optional[str] = None,
+        legacy_format: Optional[str] = None,
+        annotations: Optional[Dict[str, str]] = None,
+        labels: Optional[Dict[str, str]] = None,
+        secret_findings: Optional[List[SecretFinding]] = None,
+        toolchain: Optional[ToolchainInfo] = None,
+    ) -> None:
+        """Record the layers of an inspected manifest for this run and later runs"""
+        if not digest:
+            return
+        with self._digest_lock:
+            self._run_digests[digest] = layers_data
+        self.inspect_cache.set(
+            digest, layers_data, created, legacy_format, annotations, labels, secret_findings, toolchain
+        )
+
+    def _get_secret_patterns(self) -> List[Tuple[str, Pattern[str]]]:
+        """The compiled secret patterns: built-in ones and analysis.secret_patterns"""
+        if self._secret_patterns is None:
+            self._secret_patterns = compile_patterns(config_manager.get_secret_patterns())
+        return self._secret_patterns
+
+    def _inspect_digest_once(self, image_type: str, tag: str, digest: str) -> Optional[InspectionResult]:
+        """Fully inspect a tag unless another tag with the same digest is already being inspected.
+
+        The first tag seen for a digest is inspected; concurrent alias tags wait
+        for it and reuse its layers instead of running their own skopeo inspect.
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag to inspect
+            digest: Manifest digest the tag resolved to
+
+        Returns:
+            Same shape as _inspect_single_tag, or None if inspection fails
+        """
+        with self._digest_lock:
+            layers_data = self._run_digests.get(digest)
+            pending = self._digest_inflight.get(digest)
+            if layers_data is None and pending is None:
+                self._digest_inflight[digest] = threading.Event()
+
+        if layers_data is None and pending is not None:
+            pending.wait()
+            with self._digest_lock:
+                layers_data = self._run_digests.get(digest)
+
+        if layers_data is not None:
+            return {
+                "image_id": f"{image_type}:{tag}",
+                "repository": f"{self.repository}/{image_type}",
+                "tag": tag,
+                "digest": digest,
+                "layers_data": layers_data,
+                "source": "alias",
+                "created": self.inspect_cache.get_created(digest),
+                "legacy_format": self.inspect_cache.get_legacy_format(digest),
+                "annotations": self.inspect_cache.get_annotations(digest),
+                "labels": self.inspect_cache.get_labels(digest),
+                "secret_findings": self.inspect_cache.get_secret_findings(digest),
+                "toolchain": self.inspect_cache.get_toolchain(digest),
+            }
+
+        if pending is not None:
+            # The other tag's inspection failed, so try this one directly
+            return self._inspect_single_tag(image_type, tag)
+
+        try:
+            return self._inspect_single_tag(image_type, tag)
+        finally:
+            with self._digest_lock:
+                self._digest_inflight.pop(digest).set()
+
+    def _inspect_tag_sizes_only(self, image_type: str, tag: str) -> Optional[InspectionResult]:
+        """Size a single tag from its manifest alone, for storage accounting.
+
+        One manifest GET gives both the digest and the layer sizes, where a
+        full inspection also fetches the image config (labels, env, creation
+        time). Manifest lists (multi-arch images) and schema1 manifests list no
+        usable layer sizes, so those tags fall back to a full inspection.
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag to size
+
+        Returns:
+            Same shape as _inspect_single_tag (without created time or labels), or None if it fails
+        """
+        repository = f"{self.repository}/{image_type}"
+        try:
+            manifest_result = self.skopeo_client.get_manifest(repository, tag)
+            if manifest_result and "layers" in manifest_result[1]:
+                digest, manifest = manifest_result
+                annotations = None
+                if config_manager.is_annotation_collection_enabled():
+                    annotations = manifest_annotations(manifest)
+                result: Optional[InspectionResult] = {
+                    "image_id": f"{image_type}:{tag}",
+                    "repository": repository,
+                    "tag": tag,
+                    "digest": digest,
+                    "layers_data": mark_foreign_layers(manifest["layers"], "mediaType"),
+                    "source": "manifest",
+                    "created": None,
+                    "legacy_format": None,
+                    "annotations": annotations,
+                    "labels": None,
+                    "secret_findings": None,
+                    "toolchain": None,
+                }
+            else:
+                result = self._inspect_single_tag(image_type, tag)
+
+            if result:
+                result["change"] = self.inspect_cache.record_tag_digest(f"{repository}:{tag}", result["digest"])
+            return result
+        except Exception as e:
+            self.logger.error(f"Error inspecting {image_type}:{tag}: {e}")
+            self._record_failure(image_type, tag, str(e), classify_error(e))
             return None
 
+    def _tag_inspector(self, fast: bool, sizes_only: bool) -> Callable[[str, str], Optional[InspectionResult]]:
+        """Get the function that inspects one tag in the given scan mode.
+
+        Args:
+            fast: Approximate triage mode (see analyze_images)
+            sizes_only: Size tags from their manifest alone (see analyze_images)
+
+        Returns:
+            Function of (image_type, tag) returning an InspectionResult, or None if inspection fails
+        """
+        if sizes_only:
+            return self._inspect_tag_sizes_only
+        # Incremental scans skip full inspection of tags whose digest was seen in a previous run;
+        # tags sharing a digest within this run are always inspected only once
+        incremental = self.inspect_cache.path is not None and config_manager.is_incremental_scan_enabled()
+        return lambda image_type, tag: self._inspect_single_tag_by_digest(image_type, tag, fast, fast or incremental)
+
+    def _inspect_single_tag_by_digest(
+        self, image_type: str, tag: str, size_from_manifest: bool = False, use_cache: bool = True
+    ) -> Optional[InspectionResult]:
+        """Inspect a single tag, skipping the full inspection when its digest is already known.
+
+        Resolves the tag's digest with a manifest HEAD request first. If the
+        digest was already inspected for another tag in this run, or (with
+        use_cache) is in the inspect cache, those layers are used, since a
+        digest always identifies the same layers, and no skopeo call is made.
+        Otherwise the tag is fully inspected, or with size_from_manifest (fast
+        mode) its layer sizes are taken from the manifest's layer descriptors
+        without fetching the image config. Manifest lists (multi-arch images)
+        and schema1 manifests always fall back to a full inspection.
+
+        The resolved digest is compared with the one recorded for the tag by the
+        previous scan, and the result's "change" is "unchanged", "changed" or "new".
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag to inspect
+            size_from_manifest: Size uncached images from their manifest instead of inspecting them
+            use_cache: Reuse layers cached by previous runs
+
+        Returns:
+            Same shape as _inspect_single_tag, or None if inspection fails
+        """
+        repository = f"{self.repository}/{image_type}"
+        try:
+            digest = self.skopeo_client.get_manifest_digest(repository, tag)
+            if not digest:
+                self.logger.warning(f"Failed to resolve digest for {image_type}:{tag}, inspecting instead")
+                result = self._inspect_single_tag(image_type, tag)
+            else:
+                with self._digest_lock:
+                    layers_data = self._run_digests.get(digest)
+                source = "alias"
+                annotations = self.inspect_cache.get_annotations(digest)
+                if layers_data is None and use_cache:
+                    layers_data = self.inspect_cache.get(digest)
+                    source = "cache"
+                if layers_data is None and size_from_manifest:
+                    manifest_result = self.skopeo_client.get_manifest(repository, tag)
+                    if manifest_result and "layers" in manifest_result[1]:
+                        digest, manifest = manifest_result
+                        layers_data = mark_foreign_layers(manifest["layers"], "mediaType")
+                        source = "manifest"
+                        if config_manager.is_annotation_collection_enabled():
+                            annotations = manifest_annotations(manifest)
+                if layers_data is None:
+                    result = self._inspect_digest_once(image_type, tag, digest)
+                else:
+                    result = {
+                        "image_id": f"{image_type}:{tag}",
+                        "repository": repository,
+                        "tag": tag,
+                        "digest": digest,
+                        "layers_data": layers_data,
+                        "source": source,
+                        "created": self.inspect_cache.get_created(digest),
+                        "legacy_format": self.inspect_cache.get_legacy_format(digest),
+                        "annotations": annotations,
+                        "labels": self.inspect_cache.get_labels(digest),
+                        "secret_findings": self.inspect_cache.get_secret_findings(digest),
+                        "toolchain": self.inspect_cache.get_toolchain(digest),
+                    }
+
+            if result:
+                result["change"] = self.inspect_cache.record_tag_digest(f"{repository}:{tag}", result["digest"])
+            return result
+        except Exception as e:
+            self.logger.error(f"Error inspecting {image_type}:{tag}: {e}")
+            self._record_failure(image_type, tag, str(e), classify_error(e))
+            return None
+
+    def _record_failure(self, image_type: str, tag: str, message: str, code: Optional[str] = None) -> None:
+        """Record a tag that could not be inspected.
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag
+            message: What went wrong
+            code: Error code; defaults to that of the last registry request that failed on this thread
+        """
+        if code is None:
+            code = self.skopeo_client.last_error_code()
+        if not isinstance(code, str):
+            code = ERROR_UNKNOWN
+        self.failures[f"{image_type}:{tag}"] = {
+            "code": code,
+            "retryable": code in RETRYABLE_ERROR_CODES,
+            "message": message,
+        }
+
+    def _report_tag_done(self, image_type: str, tag: str, completed: int, total: int, inspected: bool) -> None:
+        """Emit the progress events of a finished tag: its failure, if any, and tag_done"""
+        if not self.progress:
+            return
+        failure = None if inspected else self.failures.get(f"{image_type}:{tag}")
+        if failure:
+            self.progress.error(failure["message"], image_type, tag, failure["code"])
+        status = "inspected" if inspected else "failed" if failure else "skipped"
+        self.progress.tag_done(image_type, tag, completed, total, status)
+
+    def _ensure_index_capacity(self, incoming_tags: int) -> None:
+        """Switch to the on-disk index if the scan would exceed the configured tag count.
+
+        Args:
+            incoming_tags: Number of tags about to be added to the index
+        """
+        threshold = config_manager.get_disk_index_threshold()
+        if threshold <= 0 or isinstance(self.index, SqliteImageIndex):
+            return
+        expected = len(self.index) + incoming_tags
+        if expected <= threshold:
+            return
+
+        self.logger.info(f"Scan covers {expected} tags (threshold {threshold}) - switching to on-disk image index")
+        disk_index = SqliteImageIndex(directory=config_manager.get_output_dir())
+        disk_index.copy_from(self.index)
+        self.index = disk_index
+
+    def _record_inspection(self, tag_data: InspectionResult) -> None:
+        """Add an inspected image and its layers to the index.
+
+        Args:
+            tag_data: Result from _inspect_single_tag
+        """
+        self.failures.pop(tag_data["image_id"], None)
+        self.index.add_image(
+            tag_data["image_id"],
+            tag_data["repository"],
+            tag_data["tag"],
+            tag_data["digest"],
+            [(layer["Digest"], layer["Size"]) for layer in tag_data["layers_data"]],
+        )
+        annotations = tag_data.get("annotations")
+        created = tag_data.get("created") or (annotations or {}).get(ANNOTATION_CREATED)
+        if created:
+            self.created[tag_data["image_id"]] = created
+        if tag_data.get("legacy_format"):
+            self.legacy_formats[tag_data["image_id"]] = tag_data["legacy_format"]
+        if annotations is not None:
+            self.annotations[tag_data["image_id"]] = annotations
+        if tag_data.get("labels") is not None:
+            # Also redacts labels cached before a key was added to reports.redact_keys
+            self.labels[tag_data["image_id"]] = redact_labels(
+                tag_data["labels"], config_manager.get_redact_key_patterns()
+            )
+        if tag_data.get("secret_findings"):
+            self.secret_findings[tag_data["image_id"]] = list(tag_data["secret_findings"])
+        if tag_data.get("toolchain") is not None:
+            self.toolchain[tag_data["image_id"]] = tag_data["toolchain"]
+        for layer in tag_data["layers_data"]:
+            if layer.get("Foreign"):
+                self.foreign_layers.add(layer["Digest"])
+            if layer.get("MediaType"):
+                self.layer_media_types[layer["Digest"]] = layer["MediaType"]
+
+    def _attach_reference_tags(self, image_type: str, reference_tags: Dict[str, Tuple[str, str]]) -> None:
+        """Attach reference tags to the analyzed images whose digest they name.
+
+        Args:
+            image_type: Type of image the tags were listed for
+            reference_tags: tag -> (subject_digest, kind) from parse_reference_tag
+        """
+        repository = f"{self.repository}/{image_type}"
+        subjects: Dict[str, List[str]] = {}
+        for image_id, image_data in self.images.items():
+            if image_data["repository"] == repository:
+                subjects.setdefault(image_data["digest"], []).append(image_id)
+
+        attached = 0
+        for tag, (subject_digest, kind) in sorted(reference_tags.items()):
+            artifact: AttachedArtifact = {
+                "repository": repository,
+                "tag": tag,
+                "kind": kind,
+                "subject_digest": subject_digest,
+            }
+            if subject_digest not in subjects:
+                continue
+            for image_id in subjects[subject_digest]:
+                self.attached_artifacts.setdefault(image_id, []).append(artifact)
+            attached += 1
+
+        self.logger.info(
+            f"Attached {attached}/{len(reference_tags)} reference tag(s) to their subject images for {image_type}"
+        )
+        if attached < len(reference_tags):
+            self.logger.info(f"  {len(reference_tags) - attached} reference tag(s) name a digest with no analyzed image")
+
+    def _read_provenance(self, repository: str, digest: str, reference_tags: List[str]) -> Optional[Provenance]:
+        """Read the SLSA provenance attested for a manifest digest, or None if there is none"""
+        referrers = self.skopeo_client.get_referrers(repository, digest)
+        for reference in attestation_references(referrers, reference_tags):
+            manifest_result = self.skopeo_client.get_manifest(repository, reference)
+            if not manifest_result:
+                continue
+            for layer in manifest_result[1].get("layers") or []:
+                if not layer.get("digest") or not is_provenance_layer(layer):
+                    continue
+                blob = self.skopeo_client.get_blob(repository, layer["digest"], MAX_ATTESTATION_BYTES)
+                provenance = parse_provenance(blob) if blob else None
+                if provenance:
+                    return provenance
+        return None
+
+    def collect_provenance(self, image_type: str, max_workers: Optional[int] = None) -> None:
+        """Read the provenance attestations of the analyzed images of one type.
+
+        Attestations are found with the registry's referrers API and under
+        "sha256-<digest>.att" reference tags, and read once per distinct
+        manifest digest.
+
+        Args:
+            image_type: Type of image to read provenance for
+            max_workers: Number of parallel workers (default: from config)
+        """
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+        by_digest: Dict[str, List[str]] = {}
+        for image_id, image_data in self.images.items():
+            if image_id.startswith(f"{image_type}:") and image_data.get("digest"):
+                by_digest.setdefault(image_data["digest"], []).append(image_id)
+
+        def fetch(digest: str) -> Optional[Provenance]:
+            image_ids = by_digest[digest]
+            reference_tags = [
+                artifact["tag"]
+                for image_id in image_ids
+                for artifact in self.attached_artifacts.get(image_id, [])
+                if artifact["kind"] == "att"
+            ]
+            return self._read_provenance(self.images[image_ids[0]]["repository"], digest, reference_tags)
+
+        self.logger.info(f"Reading provenance attestations of {len(by_digest)} {image_type} manifest(s)...")
+        found = 0
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_digest = {executor.submit(fetch, digest): digest for digest in by_digest}
+            for future in concurrent.futures.as_completed(future_to_digest):
+                digest = future_to_digest[future]
+                try:
+                    provenance = future.result()
+                except Exception as e:
+                    self.logger.warning(f"Could not read provenance of {digest}: {e}")
+                    continue
+                for image_id in by_digest[digest]:
+                    self.provenance[image_id] = provenance
+                found += provenance is not None
+        self.logger.info(f"{found}/{len(by_digest)} {image_type} manifest(s) have SLSA provenance")
+
+    def collect_config_details(self, image_type: str, max_workers: Optional[int] = None) -> None:
+        """Read the config blobs of the analyzed images of one type (deep scans).
+
+        Each distinct manifest digest's config is fetched once and its details
+        (see utils/image_config.py) are recorded for every tag of the digest.
+
+        Args:
+            image_type: Type of image to read configs for
+            max_workers: Number of parallel workers (default: from config)
+        """
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+        by_digest: Dict[str, List[str]] = {}
+        for image_id, image_data in self.images.items():
+            if image_id.startswith(f"{image_type}:") and image_data.get("digest"):
+                by_digest.setdefault(image_data["digest"], []).append(image_id)
+        redact_patterns = config_manager.get_redact_key_patterns()
+        secret_patterns = self._get_secret_patterns()
+
+        def fetch(digest: str) -> Tuple[Optional[ImageConfigDetails], List[SecretFinding]]:
+            image_data = self.images[by_digest[digest][0]]
+            image_config = self.skopeo_client.get_image_config(image_data["repository"], image_data["tag"])
+            details = config_details(image_config, redact_patterns)
+            commands = [step.get("created_by") for step in details["history"]] if details else []
+            return details, scan_history(commands, secret_patterns)
+
+        self.logger.info(f"Deep scan: reading image configs of {len(by_digest)} {image_type} manifest(s)...")
+        found = 0
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_digest = {executor.submit(fetch, digest): digest for digest in by_digest}
+            for future in concurrent.futures.as_completed(future_to_digest):
+                digest = future_to_digest[future]
+                try:
+                    details, history_findings = future.result()
+                except Exception as e:
+                    self.logger.warning(f"Could not read image config of {digest}: {e}")
+                    continue
+                if details is None:
+                    continue
+                for image_id in by_digest[digest]:
+                    self.config_details[image_id] = details
+                    if history_findings:
+                        findings = self.secret_findings.setdefault(image_id, [])
+                        findings.extend(finding for finding in history_findings if finding not in findings)
+                found += 1
+        self.logger.info(f"Read {found}/{len(by_digest)} {image_type} image config(s)")
+
+    def infer_os_releases(self, image_type: str, max_workers: Optional[int] = None) -> None:
+        """Infer the OS release of the analyzed images of one type (deep scans).
+
+        Labels and the base image are tried first (see utils/os_release.py);
+        for the other images, the os-release file of the bottom layer is read,
+        once per distinct layer and only within analysis.contents.max_layer_mb.
+
+        Args:
+            image_type: Type of image to infer releases for
+            max_workers: Number of parallel layer downloads (default: from config)
+        """
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+        max_layer_bytes = config_manager.get_layer_contents_limits()["max_layer_mb"] * 1024 * 1024
+        unreadable = self.foreign_layers | self.encrypted_layers()
+
+        bottom_layers: Dict[str, str] = {}
+        for mapping in self.image_layers:
+            if mapping["order_index"] == 0:
+                bottom_layers[mapping["image_id"]] = mapping["layer_id"]
+
+        pending: Dict[str, List[str]] = {}  # bottom layer -> images whose release it decides
+        for image_id in self.images:
+            if not image_id.startswith(f"{image_type}:"):
+                continue
+            release = infer_release(self.labels.get(image_id), self.annotations.get(image_id))
+            if release:
+                self.os_releases[image_id] = release
+                continue
+            layer_id = bottom_layers.get(image_id)
+            layer = self.layers.get(layer_id) if layer_id else None
+            if layer_id and layer and layer_id not in unreadable and 0 < layer["size_bytes"] <= max_layer_bytes:
+                pending.setdefault(layer_id, []).append(image_id)
+
+        def fetch(layer_id: str) -> Optional[OsRelease]:
+            repository = self.images[pending[layer_id][0]]["repository"]
+            text = self.skopeo_client.read_blob(repository, layer_id, read_os_release)
+            release = parse_os_release(text) if text else None
+            return {"distro": release[0], "version": release[1], "source": SOURCE_LAYER} if release else None
+
+        to_read = [layer_id for layer_id in pending if layer_id not in self._layer_os_releases]
+        if to_read:
+            self.logger.info(f"Reading os-release from {len(to_read)} {image_type} base layer(s)...")
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_layer = {executor.submit(fetch, layer_id): layer_id for layer_id in to_read}
+            for future in concurrent.futures.as_completed(future_to_layer):
+                layer_id = future_to_layer[future]
+                try:
+                    self._layer_os_releases[layer_id] = future.result()
+                except Exception as e:
+                    self.logger.warning(f"Could not read os-release from layer {layer_id}: {e}")
+                    self._layer_os_releases[layer_id] = None
+        for layer_id, image_ids in pending.items():
+            release = self._layer_os_releases.get(layer_id)
+            if release:
+                for image_id in image_ids:
+                    self.os_releases[image_id] = release
+
+    def eol_images(self, today: Optional[date] = None) -> List[EolImage]:
+        """Get the images whose OS release is past its end of life.
+
+        Args:
+            today: Reference date (default: today)
+
+        Returns:
+            The images on end-of-life releases, by image_id
+        """
+        return [
+            {
+                "image_id": image_id,
+                "distro": release["distro"],
+                "version": release["version"],
+                "eol_date": eol_date(release) or "",
+                "source": release["source"],
+            }
+            for image_id, release in sorted(self.os_releases.items())
+            if image_id in self.images and is_eol(release, today)
+        ]
+
     def analyze_image(
-        self, image_type: str, object_ids: Optional[List[str]] = None, max_workers: Optional[int] = None
+        self,
+        image_type: str,
+        object_ids: Optional[List[str]] = None,
+        max_workers: Optional[int] = None,
+        fast: bool = False,
+        sizes_only: bool = False,
+        deep: bool = False,
     ) -> bool:
         """Analyze a single image type (e.g., 'environment', 'model') with parallel tag inspection.
 
@@ -168,145 +1124,927 @@ class ImageAnalyzer:
             image_type: Type of image to analyze
             object_ids: Optional list of ObjectIDs to filter tags
             max_workers: Number of parallel workers for tag inspection (default: from config, or 4)
+            fast: Approximate mode for triage - fetch only manifests and reuse cached
+                inspections instead of fully inspecting every tag
+            sizes_only: Storage accounting mode - size every tag from its manifest
+                alone, without reading image configs
+            deep: Also read every image's config blob (diffIDs, history, user, entrypoint)
 
         Returns:
             True if successful, False otherwise
         """
+        object_ids_map = {image_type: object_ids} if object_ids else None
+        analyzed = self.analyze_images(
+            [image_type], object_ids_map, max_workers=max_workers, fast=fast, sizes_only=sizes_only, deep=deep
+        )
+        return bool(analyzed)
+
+    def analyze_images(
+        self,
+        image_types: List[str],
+        object_ids_map: Optional[Dict[str, List[str]]] = None,
+        max_workers: Optional[int] = None,
+        fast: bool = False,
+        sizes_only: bool = False,
+        deep: bool = False,
+    ) -> List[str]:
+        """Analyze several image types as one pipeline.
+
+        The tags of every repository are listed concurrently, and each
+        repository's tags go to one shared inspection pool as soon as its
+        listing finishes, so a slow listing does not hold up the inspection of
+        the other repositories. Finished inspections are recorded in the index
+        (and the partial results) in a fixed order - repositories in the order
+        given, tags in listing order - so reports come out the same with any
+        number of workers; an inspection that finishes early waits for the ones
+        before it. A repository is finished (reference tags, provenance,
+        deep-scan configs, summary logs) once its last tag is recorded.
+
+        Args:
+            image_types: Types of image to analyze
+            object_ids_map: Optional image type -> ObjectIDs to filter its tags by
+            max_workers: Number of parallel workers for tag inspection (default: from config, or 4)
+            fast: Approximate mode for triage - fetch only manifests and reuse cached
+                inspections instead of fully inspecting every tag
+            sizes_only: Storage accounting mode - one manifest request per tag for
+                its digest and layer sizes; no image config, so no labels, env or
+                creation times, and no provenance
+            deep: Detailed mode - also fetch the config blob of every distinct image
+                for its diffIDs, history, user, entrypoint and env (one more
+                request per manifest digest; see utils/image_config.py)
+
+        Returns:
+            Image types analyzed successfully, in the order given
+        """
         if max_workers is None:
             max_workers = config_manager.get_max_workers()
+        if not image_types:
+            return []
+
+        inspect_tag = self._tag_inspector(fast, sizes_only)
+        incremental = self.inspect_cache.path is not None and config_manager.is_incremental_scan_enabled()
+        if self.progress:
+            self.progress.phase_changed(PHASE_SCAN)
+
+        # image type -> its scan, or None if its tags could not be listed or none are to be inspected
+        scans: Dict[str, Optional[_RepositoryScan]] = {}
+        succeeded: Set[str] = set()
+        # future -> (image type, index of the tag in the scan); the index is None for a listing
+        pending: Dict[concurrent.futures.Future, Tuple[str, Optional[int]]] = {}
+        # Position in image_types of the repository whose inspections are being recorded
+        recording = 0
+
+        listers = min(len(image_types), max_workers)
+        with concurrent.futures.ThreadPoolExecutor(max_workers=listers) as list_executor, (
+            concurrent.futures.ThreadPoolExecutor(max_workers=max_workers)
+        ) as inspect_executor:
+            for image_type in image_types:
+                future = list_executor.submit(self.skopeo_client.list_tags, f"{self.repository}/{image_type}")
+                pending[future] = (image_type, None)
+
+            while pending:
+                done, _ = concurrent.futures.wait(pending, return_when=concurrent.futures.FIRST_COMPLETED)
+                for future in done:
+                    image_type, index = pending.pop(future)
+                    if index is not None:
+                        scans[image_type].finished[index] = future
+                        continue
+                    scan = self._start_repository_scan(image_type, future, (object_ids_map or {}).get(image_type))
+                    scans[image_type] = scan
+                    if scan is None:
+                        continue
+                    self.logger.info(f"Analyzing {scan.total} tags for {image_type} (using {max_workers} workers)...")
+                    for scan_index, scan_tag in enumerate(scan.tags):
+                        inspect_future = inspect_executor.submit(inspect_tag, image_type, scan_tag)
+                        pending[inspect_future] = (image_type, scan_index)
+
+                # Record what is next in order, finishing each repository once all of its tags are recorded
+                while recording < len(image_types) and image_types[recording] in scans:
+                    scan = scans[image_types[recording]]
+                    if scan is not None:
+                        while scan.completed in scan.finished:
+                            self._complete_tag(scan, scan.tags[scan.completed], scan.finished.pop(scan.completed))
+                        if scan.completed < scan.total:
+                            break
+                        if self._finish_repository_scan(scan, max_workers, fast, sizes_only, deep, incremental):
+                            succeeded.add(scan.image_type)
+                    recording += 1
+
+        return [image_type for image_type in image_types if image_type in succeeded]
+
+    def _start_repository_scan(
+        self, image_type: str, listing: concurrent.futures.Future, object_ids: Optional[List[str]]
+    ) -> Optional["_RepositoryScan"]:
+        """Select the tags to inspect from a finished tag listing.
+
+        Args:
+            image_type: Type of image whose tags were listed
+            listing: Finished list_tags future
+            object_ids: Optional list of ObjectIDs to filter tags
+
+        Returns:
+            The repository's scan state, or None if its tags could not be listed or none match object_ids
+        """
         try:
-            # Get tags using standardized client
-            tags = self.skopeo_client.list_tags(f"{self.repository}/{image_type}")
+            tags = listing.result()
+        except Exception as e:
+            self.logger.error(f"Failed to retrieve information for image: {image_type}")
+            self.logger.error(f"Error: {e}")
+            if self.partial_results:
+                self.partial_results.repository_done(image_type, succeeded=False)
+            if self.progress:
+                self.progress.error(f"Failed to list tags: {e}", image_type, error_code=classify_error(e))
+                self.progress.repository_done(image_type, succeeded=False)
+            return None
 
-            # Skip internal/cache tags
+        # Skip internal/cache tags
+        original_count = len(tags)
+        tags = [t for t in tags if t != "buildcache"]
+        if len(tags) != original_count:
+            self.logger.info(f"Skipping {original_count - len(tags)} 'buildcache' tag(s) for {image_type}")
+
+        # Reference tags (sha256-<digest>.<kind>) are attached to their subject image after the scan
+        reference_tags: Dict[str, Tuple[str, str]] = {}
+        for tag in tags:
+            parsed = parse_reference_tag(tag)
+            if parsed:
+                reference_tags[tag] = parsed
+        tags = [t for t in tags if t not in reference_tags]
+
+        # Skip other signature, attestation and SBOM tags, which are not images of their own
+        excluded_patterns = config_manager.get_excluded_tag_patterns()
+        original_count = len(tags)
+        tags = [t for t in tags if not is_excluded_tag(t, excluded_patterns)]
+        if len(tags) != original_count:
+            self.logger.info(
+                f"Skipping {original_count - len(tags)} tag(s) matching analysis.exclude_tags patterns for {image_type}"
+            )
+
+        # Filter tags by ObjectIDs if provided
+        if object_ids:
             original_count = len(tags)
-            tags = [t for t in tags if t != "buildcache"]
-            if len(tags) != original_count:
-                self.logger.info(f"Skipping {original_count - len(tags)} 'buildcache' tag(s) for {image_type}")
-
-            # Filter tags by ObjectIDs if provided
-            if object_ids:
-                original_count = len(tags)
-                tags = self.filter_tags_by_object_ids(tags, object_ids)
-                filtered_count = len(tags)
+            tags = self.filter_tags_by_object_ids(tags, object_ids)
+            filtered_count = len(tags)
+            self.logger.info(
+                f"Filtered tags for {image_type}: {filtered_count}/{original_count} tags match the provided ObjectIDs"
+            )
+
+            if filtered_count == 0:
+                self.logger.warning(f"No tags found matching the provided ObjectIDs for image: {image_type}")
+                return None
+
+        self._ensure_index_capacity(len(tags))
+        if self.partial_results:
+            self.partial_results.repository_started(image_type, len(tags))
+        if self.progress:
+            self.progress.repository_started(image_type, len(tags))
+        return _RepositoryScan(image_type, tags, reference_tags)
+
+    def _complete_tag(self, scan: "_RepositoryScan", tag: str, future: concurrent.futures.Future) -> None:
+        """Record the result of one finished tag inspection of a repository scan"""
+        image_type = scan.image_type
+        scan.completed += 1
+        try:
+            tag_data = future.result()
+            if tag_data:
+                self._record_inspection(tag_data)
+                scan.inspected += 1
+                scan.sources[tag_data.get("source", "inspect")] += 1
+                if "change" in tag_data:
+                    scan.changes[tag_data["change"]] += 1
+
+                # Log progress every 10 tags or at the end
+                if scan.completed % 10 == 0 or scan.completed == scan.total:
+                    self.logger.info(
+                        f"  {image_type} progress: {scan.completed}/{scan.total} tags processed "
+                        f"({scan.completed/scan.total*100:.1f}%)"
+                    )
+        except Exception as e:
+            self.logger.error(f"  Error processing {tag}: {e}")
+            self._record_failure(image_type, tag, str(e), classify_error(e))
+            tag_data = None
+        if self.partial_results:
+            self.partial_results.tag_completed(image_type, scan.completed, inspected=bool(tag_data))
+        self._report_tag_done(image_type, tag, scan.completed, scan.total, inspected=bool(tag_data))
+
+    def _finish_repository_scan(
+        self,
+        scan: "_RepositoryScan",
+        max_workers: int,
+        fast: bool,
+        sizes_only: bool,
+        deep: bool,
+        incremental: bool,
+    ) -> bool:
+        """Log a repository's results and collect what needs all of its images once its last tag is done.
+
+        Returns:
+            True if successful, False otherwise
+        """
+        image_type = scan.image_type
+        sources = scan.sources
+        changes = scan.changes
+        if self.progress:
+            self.progress.phase_changed(PHASE_FINISH_REPOSITORY, image_type)
+        try:
+            self.logger.info(f"Successfully inspected {scan.inspected}/{scan.total} {image_type} tags")
+            failed = Counter(
+                failure["code"] for image_id, failure in self.failures.items() if image_id.startswith(f"{image_type}:")
+            )
+            if failed:
+                self.logger.warning(
+                    f"{sum(failed.values())} {image_type} tag(s) could not be inspected: "
+                    + ", ".join(f"{count} {code}" for code, count in sorted(failed.items()))
+                )
+            if sizes_only:
                 self.logger.info(
-                    f"Filtered tags for {image_type}: {filtered_count}/{original_count} tags match the provided ObjectIDs"
+                    f"Sizes-only mode: {sources['manifest']} sized from manifests, "
+                    f"{sources['inspect']} fully inspected (manifest lists and schema1 images)"
                 )
+            elif fast:
+                self.logger.info(
+                    f"Fast mode: {sources['cache']} from cache, {sources['manifest']} sized from manifests, "
+                    f"{sources['inspect']} fully inspected"
+                )
+            elif incremental:
+                self.logger.info(
+                    f"Incremental scan: {sources['cache']} unchanged digests reused from cache, "
+                    f"{sources['inspect']} fully inspected"
+                )
+            foreign_images = [
+                image_id for image_id in self.images_with_foreign_layers() if image_id.startswith(f"{image_type}:")
+            ]
+            if foreign_images:
+                self.logger.warning(
+                    f"{len(foreign_images)} {image_type} image(s) contain foreign layers that are not stored in the "
+                    "registry; their size is excluded from reclaimable space"
+                )
+            encrypted_images = [
+                image_id for image_id in self.images_with_encrypted_layers() if image_id.startswith(f"{image_type}:")
+            ]
+            if encrypted_images:
+                self.logger.warning(
+                    f"{len(encrypted_images)} {image_type} image(s) contain encrypted layers; "
+                    "content-based analysis (e.g. duplicate layer detection) skips them"
+                )
+            oversized_policy = config_manager.get_oversized_layer_policy()
+            if oversized_policy:
+                oversized_images = {
+                    image_id
+                    for layer in self.oversized_layers(oversized_policy["threshold_bytes"])
+                    for image_id in layer["image_ids"]
+                    if image_id.startswith(f"{image_type}:")
+                }
+                if oversized_images:
+                    self.logger.warning(
+                        f"{len(oversized_images)} {image_type} image(s) contain layers larger than "
+                        f"{sizeof_fmt(oversized_policy['threshold_bytes'])} (analysis.oversized_layers)"
+                    )
+            if scan.reference_tags:
+                self._attach_reference_tags(image_type, scan.reference_tags)
+            if deep:
+                self.collect_config_details(image_type, max_workers)
+                self.infer_os_releases(image_type, max_workers)
+                eol = [image for image in self.eol_images() if image["image_id"].startswith(f"{image_type}:")]
+                if eol:
+                    releases = Counter(f"{image['distro']} {image['version']}" for image in eol)
+                    self.logger.warning(
+                        f"{len(eol)} {image_type} image(s) run on an end-of-life OS release: "
+                        + ", ".join(f"{count} {release}" for release, count in sorted(releases.items()))
+                    )
+            outdated = [
+                image for image in self.outdated_toolchains() if image["image_id"].startswith(f"{image_type}:")
+            ]
+            if outdated:
+                reasons = Counter(reason for image in outdated for reason in image["reasons"])
+                self.logger.warning(
+                    f"{len(outdated)} {image_type} image(s) were built with an outdated toolchain ("
+                    + ", ".join(f"{count} {reason}" for reason, count in sorted(reasons.items()))
+                    + "); consider rebuilding or deleting them"
+                )
+            expired = [
+                image_id
+                for image_id, expiry in self.image_expiries().items()
+                if expiry["expired"] and image_id.startswith(f"{image_type}:")
+            ]
+            if expired:
+                self.logger.info(
+                    f"{len(expired)} {image_type} image(s) are past the expiry set at build time "
+                    "(analysis.expiry_keys); plan --unused selects them unless they are in use"
+                )
+            leaking = [image_id for image_id in self.secret_findings if image_id.startswith(f"{image_type}:")]
+            if leaking:
+                self.logger.warning(
+                    f"{len(leaking)} {image_type} image(s) may contain secrets in their Env, labels or build history "
+                    "(see the secret findings report)"
+                )
+            if config_manager.is_provenance_collection_enabled() and not (fast or sizes_only):
+                self.collect_provenance(image_type, max_workers)
+            if sources["alias"]:
+                self.logger.info(f"{sources['alias']} alias tags reused the inspection of a tag with the same digest")
+            if changes:
+                self.logger.info(
+                    f"Tags since last scan: {changes['unchanged']} unchanged, {changes['changed']} changed, "
+                    f"{changes['new']} new"
+                )
+            self.inspect_cache.save()
+            if self.partial_results:
+                self.partial_results.repository_done(image_type)
+            if self.progress:
+                self.progress.repository_done(image_type)
+            return True
+
+        except Exception as e:
+            self.logger.error(f"Failed to retrieve information for image: {image_type}")
+            self.logger.error(f"Error: {e}")
+            if self.partial_results:
+                self.partial_results.repository_done(image_type, succeeded=False)
+            if self.progress:
+                self.progress.error(str(e), image_type, error_code=classify_error(e))
+                self.progress.repository_done(image_type, succeeded=False)
+            return False
+
+    def retry_failed_tags(self, max_workers: Optional[int] = None, fast: bool = False, sizes_only: bool = False) -> int:
+        """Inspect the tags that failed with a retryable error again, after the main pass.
+
+        Registry load is lower once every repository has been scanned, so tags
+        that timed out, were rate limited or hit an unavailable registry often
+        succeed then. Tags that fail again keep their failure, with the code of
+        the second attempt; the others are recorded like any inspected tag.
+        Reference tags, provenance and deep-scan configs of recovered images are
+        not collected.
+
+        Args:
+            max_workers: Number of parallel workers (default: from config)
+            fast: Inspect like analyze_image(fast=True)
+            sizes_only: Inspect like analyze_image(sizes_only=True)
+
+        Returns:
+            Number of tags that succeeded on the second pass
+        """
+        retry = sorted(image_id for image_id, failure in self.failures.items() if failure["retryable"])
+        if not retry:
+            return 0
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+        inspect_tag = self._tag_inspector(fast, sizes_only)
+        self.logger.info(f"Retrying {len(retry)} tag(s) that failed transiently...")
+        if self.progress:
+            self.progress.phase_changed(PHASE_RETRY)
+
+        recovered = 0
+        completed = 0
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_image = {}
+            for image_id in retry:
+                image_type, tag = image_id.split(":", 1)
+                future = executor.submit(inspect_tag, image_type, tag)
+                future_to_image[future] = (image_type, tag)
+
+            # Recorded in the order submitted, like the first pass
+            for future, (image_type, tag) in future_to_image.items():
+                completed += 1
+                try:
+                    tag_data = future.result()
+                except Exception as e:
+                    self.logger.error(f"  Error processing {tag}: {e}")
+                    self._record_failure(image_type, tag, str(e), classify_error(e))
+                    tag_data = None
+                if tag_data:
+                    self._record_inspection(tag_data)
+                    recovered += 1
+                self._report_tag_done(image_type, tag, completed, len(retry), inspected=bool(tag_data))
+
+        self.inspect_cache.save()
+        self.logger.info(
+            f"Second pass: {recovered}/{len(retry)} tag(s) inspected, {len(retry) - recovered} still failing"
+        )
+        return recovered
+
+    def is_scanned_tag(self, tag: str) -> bool:
+        """Whether a scan inspects a tag as an image (not buildcache, reference or excluded tags)"""
+        if tag == "buildcache" or parse_reference_tag(tag):
+            return False
+        return not is_excluded_tag(tag, config_manager.get_excluded_tag_patterns())
+
+    def refresh_tag(self, image_type: str, tag: str) -> bool:
+        """Inspect one tag again and update the index, e.g. after the registry reported a push.
+
+        Results recorded for the tag's previous digest are dropped first, so a
+        re-pushed tag carries only what its new image has. Reference tags,
+        provenance and deep-scan configs are not collected.
+
+        Args:
+            image_type: Type of image (e.g., 'environment', 'model')
+            tag: Docker image tag
+
+        Returns:
+            True if the tag was inspected and recorded, False if inspection failed
+        """
+        tag_data = self._tag_inspector(fast=False, sizes_only=False)(image_type, tag)
+        if not tag_data:
+            return False
+        self.forget_images([tag_data["image_id"]])
+        self._record_inspection(tag_data)
+        self.inspect_cache.save()
+        return True
+
+    def forget_images(self, image_ids: List[str]) -> int:
+        """Remove images from the index and from every per-image result, e.g. after the registry reported a delete.
+
+        Layers no other image references are dropped with them.
+
+        Returns:
+            Number of images that were indexed
+        """
+        per_image: List[Dict[str, Any]] = [
+            self.created,
+            self.legacy_formats,
+            self.annotations,
+            self.labels,
+            self.attached_artifacts,
+            self.provenance,
+            self.config_details,
+            self.secret_findings,
+            self.toolchain,
+            self.os_releases,
+            self.failures,
+        ]
+        removed = 0
+        for image_id in image_ids:
+            if self.index.remove_image(image_id):
+                removed += 1
+            for results in per_image:
+                results.pop(image_id, None)
+        return removed
+
+    def resolve_image_id(self, image: str, image_types: Optional[List[str]] = None) -> Optional[str]:
+        """Resolve an image reference to an analyzed image_id.
+
+        Args:
+            image: Image reference, either "<type>:<tag>" or a bare tag
+            image_types: Image types to search when no type prefix is given (default: the profile's)
+
+        Returns:
+            Matching image_id, or None if the image was not analyzed
+        """
+        if image in self.images:
+            return image
+        for image_type in image_types or config_manager.get_image_types():
+            candidate = f"{image_type}:{image}"
+            if candidate in self.images:
+                return candidate
+        return None
+
+    def get_image_total_size(self, image_id: str) -> int:
+        """Calculate total size of an image (sum of all its layers).
+
+        Args:
+            image_id: Image ID to calculate size for
+
+        Returns:
+            Total bytes for all layers in the image
+        """
+        total_size: int = 0
+        for mapping in self.image_layers:
+            if mapping["image_id"] == image_id:
+                layer_id = mapping["layer_id"]
+                layer_data = self.layers.get(layer_id)
+                if layer_data:
+                    total_size += layer_data["size_bytes"]
+        return total_size
+
+    def freed_space_if_deleted(self, image_ids: List[str]) -> int:
+        """Calculate space that would be freed by deleting one or more images.
+
+        Foreign layers are not stored in the registry and never count as freed.
+
+        Args:
+            image_ids: List of image_ids to simulate deletion
+
+        Returns:
+            Total bytes that would be freed
+        """
+        # Count how many of the to-be-deleted images use each layer
+        layer_ids_to_delete = [mapping["layer_id"] for mapping in self.image_layers if mapping["image_id"] in image_ids]
+        layers_to_delete = Counter(layer_ids_to_delete)
+
+        total_freed = 0
+        for layer_id, delete_count in layers_to_delete.items():
+            # Get current ref_count for this layer
+            layer_data = self.layers.get(layer_id)
+            if not layer_data or layer_id in self.foreign_layers:
+                continue
+
+            current_ref = layer_data["ref_count"]
+
+            # If this layer would have 0 references after deletion, it will be freed
+            if current_ref == delete_count:
+                layer_size = layer_data["size_bytes"]
+                total_freed += layer_size
+
+        return int(total_freed)
+
+    def simulate_deletion(self, image_ids: List[str]) -> DeletionSimulation:
+        """Simulate deleting one or more images without modifying any state.
+
+        Unlike freed_space_if_deleted, this reports the individual layers that
+        would become unreferenced, and for every layer that survives because it
+        is shared, how many references remain and which images hold them.
+        Foreign layers are listed separately: they are not stored in the
+        registry, so their size is neither freed nor retained.
+
+        Args:
+            image_ids: List of image_ids to simulate deletion
+
+        Returns:
+            DeletionSimulation describing freed and retained layers
+        """
+        targets = set(image_ids)
+        missing = sorted(image_id for image_id in targets if image_id not in self.images)
+
+        deleted_refs: Counter = Counter()
+        remaining_images: Dict[str, set] = {}
+        for mapping in self.image_layers:
+            layer_id = mapping["layer_id"]
+            if mapping["image_id"] in targets:
+                deleted_refs[layer_id] += 1
+            else:
+                remaining_images.setdefault(layer_id, set()).add(mapping["image_id"])
+
+        freed_layers: List[Dict[str, Any]] = []
+        retained_layers: List[Dict[str, Any]] = []
+        foreign_layers: List[Dict[str, Any]] = []
+        for layer_id, delete_count in deleted_refs.items():
+            layer_data = self.layers.get(layer_id)
+            if not layer_data:
+                continue
+            if layer_id in self.foreign_layers:
+                foreign_layers.append({"layer_id": layer_id, "size_bytes": layer_data["size_bytes"]})
+                continue
+            remaining_refs = layer_data["ref_count"] - delete_count
+            if remaining_refs <= 0:
+                freed_layers.append({"layer_id": layer_id, "size_bytes": layer_data["size_bytes"]})
+            else:
+                retained_layers.append(
+                    {
+                        "layer_id": layer_id,
+                        "size_bytes": layer_data["size_bytes"],
+                        "remaining_refs": remaining_refs,
+                        "remaining_images": sorted(remaining_images.get(layer_id, set())),
+                    }
+                )
+
+        freed_layers.sort(key=lambda layer: layer["size_bytes"], reverse=True)
+        retained_layers.sort(key=lambda layer: layer["size_bytes"], reverse=True)
+        foreign_layers.sort(key=lambda layer: layer["size_bytes"], reverse=True)
+
+        return {
+            "image_ids": sorted(targets - set(missing)),
+            "missing_image_ids": missing,
+            "freed_bytes": sum(layer["size_bytes"] for layer in freed_layers),
+            "freed_layers": freed_layers,
+            "retained_bytes": sum(layer["size_bytes"] for layer in retained_layers),
+            "retained_layers": retained_layers,
+            "foreign_bytes": sum(layer["size_bytes"] for layer in foreign_layers),
+            "foreign_layers": foreign_layers,
+        }
+
+    def size_breakdown(self, image_id: str, history: Optional[List[HistoryEntry]] = None) -> SizeBreakdown:
+        """Break an analyzed image down layer by layer (see utils/size_breakdown.py).
+
+        A layer counts as shared when an image with a different digest uses it;
+        other tags of the same digest are the same image.
+
+        Args:
+            image_id: Analyzed image to break down
+            history: Build steps from the image's config (deep mode), to show the step behind each layer
+
+        Returns:
+            The image's layers with size, share of the image and sharing, and suggestions
+        """
+        image_data = self.images[image_id]
+        own_layers: List[Tuple[int, str]] = []
+        layer_images: Dict[str, List[str]] = {}
+        for mapping in self.image_layers:
+            if mapping["image_id"] == image_id:
+                own_layers.append((mapping["order_index"], mapping["layer_id"]))
+            elif self.images[mapping["image_id"]]["digest"] != image_data["digest"]:
+                layer_images.setdefault(mapping["layer_id"], []).append(mapping["image_id"])
+        layers = [(layer_id, self.layers[layer_id]["size_bytes"]) for _, layer_id in sorted(own_layers)]
+        return break_down_image(
+            image_id,
+            image_data["repository"],
+            image_data["tag"],
+            image_data["digest"],
+            layers,
+            history,
+            {layer_id: layer_images.get(layer_id, []) for layer_id, _ in layers},
+        )
+
+    def images_with_foreign_layers(self) -> Dict[str, List[str]]:
+        """Get the images that contain foreign layers.
+
+        Deleting such an image frees less than its reported size, since its
+        foreign layers are not stored in the registry.
+
+        Returns:
+            image_id -> foreign layer_ids of the image
+        """
+        result: Dict[str, List[str]] = {}
+        if not self.foreign_layers:
+            return result
+        for mapping in self.image_layers:
+            if mapping["layer_id"] in self.foreign_layers:
+                result.setdefault(mapping["image_id"], []).append(mapping["layer_id"])
+        return result
+
+    def encrypted_layers(self) -> Set[str]:
+        """Get the layers encrypted with OCI crypt, whose content cannot be read"""
+        return {
+            layer_id for layer_id, media_type in self.layer_media_types.items() if is_encrypted_media_type(media_type)
+        }
+
+    def images_with_encrypted_layers(self) -> Dict[str, List[str]]:
+        """Get the images that contain encrypted layers.
+
+        Returns:
+            image_id -> encrypted layer_ids of the image
+        """
+        result: Dict[str, List[str]] = {}
+        encrypted = self.encrypted_layers()
+        if not encrypted:
+            return result
+        for mapping in self.image_layers:
+            if mapping["layer_id"] in encrypted:
+                result.setdefault(mapping["image_id"], []).append(mapping["layer_id"])
+        return result
+
+    def oversized_layers(self, threshold_bytes: int) -> List[OversizedLayer]:
+        """Get the layers larger than a threshold, usually data or models baked into an image by mistake.
+
+        Foreign layers are left out, since they are not stored in the registry.
+
+        Args:
+            threshold_bytes: Flag layers larger than this (compressed size)
+
+        Returns:
+            The oversized layers with the images containing them, largest first
+        """
+        oversized = {
+            layer_id: layer_data["size_bytes"]
+            for layer_id, layer_data in self.layers.items()
+            if layer_data["size_bytes"] > threshold_bytes and layer_id not in self.foreign_layers
+        }
+        if not oversized:
+            return []
+        images: Dict[str, Set[str]] = {layer_id: set() for layer_id in oversized}
+        for mapping in self.image_layers:
+            if mapping["layer_id"] in images:
+                images[mapping["layer_id"]].add(mapping["image_id"])
+        result: List[OversizedLayer] = [
+            {"layer_id": layer_id, "size_bytes": int(size), "image_ids": sorted(images[layer_id])}
+            for layer_id, size in oversized.items()
+        ]
+        result.sort(key=lambda layer: (-layer["size_bytes"], layer["layer_id"]))
+        return result
+
+    def outdated_toolchains(self, policy: Optional[Dict[str, Any]] = None) -> List[OutdatedToolchain]:
+        """Get the images built with an old Docker engine or on an outdated base image (see utils.toolchain).
+
+        Images of a deep scan whose OS release is past end of life (see
+        infer_os_releases) are outdated too.
+
+        Args:
+            policy: min_docker_version and outdated_base_images (default: analysis.toolchain)
+
+        Returns:
+            The outdated images, by image_id
+        """
+        policy = policy or config_manager.get_toolchain_policy()
+        result: List[OutdatedToolchain] = []
+        for image_id in sorted(self.images):
+            info = self.toolchain.get(image_id)
+            base = base_image(self.labels.get(image_id), self.annotations.get(image_id))
+            reasons = outdated_reasons(info, base, policy["min_docker_version"], policy["outdated_base_images"])
+            if image_id in self.os_releases and is_eol(self.os_releases[image_id]):
+                reasons.append(REASON_EOL_OS)
+            if reasons:
+                result.append(
+                    {
+                        "image_id": image_id,
+                        "reasons": reasons,
+                        "docker_version": (info or {}).get("docker_version"),
+                        "os": (info or {}).get("os"),
+                        "base_image": base,
+                    }
+                )
+        return result
+
+    def toolchain_status(self, policy: Optional[Dict[str, Any]] = None) -> Dict[str, bool]:
+        """Whether each image whose build fields or base image are known was built with an outdated toolchain"""
+        outdated = {image["image_id"] for image in self.outdated_toolchains(policy)}
+        return {
+            image_id: image_id in outdated
+            for image_id in self.images
+            if image_id in self.toolchain
+            or image_id in self.os_releases
+            or base_image(self.labels.get(image_id), self.annotations.get(image_id))
+        }
+
+    def estimate_pull_times(self, link_speed_mbps: float) -> List[PullEstimate]:
+        """Estimate how long pulling each image takes on a node that has none of its layers.
+
+        Layer sizes are the compressed sizes stored in the registry, which is
+        what a pull downloads. The estimate is transfer time only: pull_bytes
+        at link_speed_mbps, ignoring request latency and decompression.
+
+        Args:
+            link_speed_mbps: Network speed in megabits per second
+
+        Returns:
+            Estimates sorted by estimated_seconds, slowest first
+        """
+        bytes_per_second = link_speed_mbps * 1_000_000 / 8
+        layer_sizes: Dict[str, List[int]] = {}
+        for mapping in self.image_layers:
+            layer_data = self.layers.get(mapping["layer_id"])
+            if layer_data:
+                layer_sizes.setdefault(mapping["image_id"], []).append(layer_data["size_bytes"])
 
-                if filtered_count == 0:
-                    self.logger.warning(f"No tags found matching the provided ObjectIDs for image: {image_type}")
-                    return False
-
-            self.logger.info(f"Analyzing {len(tags)} tags for {image_type} (using {max_workers} workers)...")
-
-            # Process tags in parallel
-            tag_data_list = []
-            with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
-                # Submit all tag inspection tasks
-                future_to_tag = {executor.submit(self._inspect_single_tag, image_type, tag): tag for tag in tags}
-
-                # Process completed tasks with progress tracking
-                completed = 0
-                total = len(tags)
-                for future in concurrent.futures.as_completed(future_to_tag):
-                    tag = future_to_tag[future]
-                    completed += 1
-
-                    try:
-                        tag_data = future.result()
-                        if tag_data:
-                            tag_data_list.append(tag_data)
-
-                            # Log progress every 10 tags or at the end
-                            if completed % 10 == 0 or completed == total:
-                                self.logger.info(
-                                    f"  Progress: {completed}/{total} tags processed ({completed/total*100:.1f}%)"
-                                )
-                    except Exception as e:
-                        self.logger.error(f"  Error processing {tag}: {e}")
-
-            self.logger.info(f"Successfully inspected {len(tag_data_list)}/{len(tags)} tags")
-
-            # Now process the collected data (must be sequential to maintain data integrity)
-            for tag_data in tag_data_list:
-                image_id = tag_data["image_id"]
-
-                # Add image to images dict
-                self.images[image_id] = {
-                    "repository": tag_data["repository"],
-                    "tag": tag_data["tag"],
-                    "digest": tag_data["digest"],
+        estimates: List[PullEstimate] = []
+        for image_id, image_data in self.images.items():
+            sizes = layer_sizes.get(image_id, [])
+            pull_bytes = sum(sizes)
+            estimates.append(
+                {
+                    "image_id": image_id,
+                    "repository": image_data["repository"],
+                    "tag": image_data["tag"],
+                    "pull_bytes": pull_bytes,
+                    "layer_count": len(sizes),
+                    "largest_layer_bytes": max(sizes, default=0),
+                    "estimated_seconds": round(pull_bytes / bytes_per_second, 1),
                 }
+            )
+        estimates.sort(key=lambda estimate: (-estimate["pull_bytes"], estimate["image_id"]))
+        return estimates
 
-                # Extract layers
-                for order_index, layer in enumerate(tag_data["layers_data"]):
-                    layer_id = layer["Digest"]
-                    layer_size = layer["Size"]
-
-                    # Add or update layer in layers dict
-                    if layer_id in self.layers:
-                        # Layer exists, increment ref_count
-                        self.layers[layer_id]["ref_count"] += 1
-                    else:
-                        # New layer
-                        self.layers[layer_id] = {"size_bytes": layer_size, "ref_count": 1}
+    def media_type_breakdown(self) -> List[MediaTypeUsage]:
+        """Get the layers and bytes of each layer media type across the registry.
 
-                    # Add image-to-layer mapping
-                    self.image_layers.append({"image_id": image_id, "layer_id": layer_id, "order_index": order_index})
+        Every layer is counted once, however many images share it.
 
-            return True
+        Returns:
+            Usage per media type, largest first
+        """
+        usage: Dict[Optional[str], MediaTypeUsage] = {}
+        for layer_id, layer_data in self.layers.items():
+            media_type = self.layer_media_types.get(layer_id)
+            entry = usage.get(media_type)
+            if entry is None:
+                category = media_type_category(media_type)
+                if media_type is None and layer_id in self.foreign_layers:
+                    category = CATEGORY_FOREIGN
+                entry = usage[media_type] = {"media_type": media_type, "category": category, "layers": 0, "bytes": 0}
+            entry["layers"] += 1
+            entry["bytes"] += layer_data["size_bytes"]
+        return sorted(usage.values(), key=lambda entry: (-entry["bytes"], entry["media_type"] or ""))
+
+    def image_ages(self) -> Dict[str, Dict[str, Any]]:
+        """Get the creation time and age of every image whose creation time is known
 
-        except Exception as e:
-            self.logger.error(f"Failed to retrieve information for image: {image_type}")
-            self.logger.error(f"Error: {e}")
-            return False
+        Returns:
+            image_id -> {"created": ISO 8601 time, "ageDays": whole days since then}
+        """
+        return {
+            image_id: {"created": created, "ageDays": age_days(created)}
+            for image_id, created in sorted(self.created.items())
+            if image_id in self.images
+        }
 
-    def get_image_total_size(self, image_id: str) -> int:
-        """Calculate total size of an image (sum of all its layers).
+    def image_expiries(
+        self, keys: Optional[List[str]] = None, now: Optional[datetime] = None
+    ) -> Dict[str, ImageExpiry]:
+        """Get the expiry of every image with an expiry annotation or label (see utils.image_expiry)
 
         Args:
-            image_id: Image ID to calculate size for
+            keys: Expiry keys, in order of preference (default: analysis.expiry_keys)
+            now: Reference time (default: current time)
+        """
+        keys = config_manager.get_expiry_keys() if keys is None else keys
+        expiries: Dict[str, ImageExpiry] = {}
+        for image_id in sorted(self.images):
+            expiry = image_expiry(
+                self.annotations.get(image_id), self.labels.get(image_id), self.created.get(image_id), keys, now
+            )
+            if expiry:
+                expiries[image_id] = expiry
+        return expiries
 
-        Returns:
-            Total bytes for all layers in the image
+    def image_owners(self, label_keys: List[str]) -> Dict[str, str]:
+        """Get the owner of every image from its labels (see utils.ownership)
+
+        Args:
+            label_keys: Owner label keys, in order of preference
         """
-        total_size: int = 0
-        for mapping in self.image_layers:
-            if mapping["image_id"] == image_id:
-                layer_id = mapping["layer_id"]
-                layer_data = self.layers.get(layer_id)
-                if layer_data:
-                    total_size += layer_data["size_bytes"]
-        return total_size
+        return {image_id: owner_from_labels(self.labels.get(image_id), label_keys) for image_id in self.images}
 
-    def freed_space_if_deleted(self, image_ids: List[str]) -> int:
-        """Calculate space that would be freed by deleting one or more images.
+    def image_attribution(self, label_keys: List[str]) -> List[ImageAttribution]:
+        """Get the owner, size and exclusive bytes of every image.
+
+        Foreign layers count toward size_bytes but, as they are not stored in
+        the registry, never toward exclusive_bytes.
 
         Args:
-            image_ids: List of image_ids to simulate deletion
+            label_keys: Owner label keys, in order of preference
 
         Returns:
-            Total bytes that would be freed
+            Images sorted by owner, then largest exclusive_bytes first
         """
-        # Count how many of the to-be-deleted images use each layer
-        layer_ids_to_delete = [mapping["layer_id"] for mapping in self.image_layers if mapping["image_id"] in image_ids]
-        layers_to_delete = Counter(layer_ids_to_delete)
+        owners = self.image_owners(label_keys)
+        layers = self.layers
+        sizes: Counter = Counter()
+        exclusive: Counter = Counter()
+        own_refs: Dict[str, Counter] = {}
+        for mapping in self.image_layers:
+            own_refs.setdefault(mapping["image_id"], Counter())[mapping["layer_id"]] += 1
+        for image_id, refs in own_refs.items():
+            for layer_id, count in refs.items():
+                layer_data = layers.get(layer_id)
+                if not layer_data:
+                    continue
+                sizes[image_id] += layer_data["size_bytes"] * count
+                if layer_data["ref_count"] <= count and layer_id not in self.foreign_layers:
+                    exclusive[image_id] += layer_data["size_bytes"]
+
+        attribution: List[ImageAttribution] = [
+            {
+                "image_id": image_id,
+                "repository": image_data["repository"],
+                "tag": image_data["tag"],
+                "owner": owners[image_id],
+                "size_bytes": sizes[image_id],
+                "exclusive_bytes": exclusive[image_id],
+            }
+            for image_id, image_data in self.images.items()
+        ]
+        attribution.sort(key=lambda entry: (entry["owner"], -entry["exclusive_bytes"], entry["image_id"]))
+        return attribution
 
-        total_freed = 0
-        for layer_id, delete_count in layers_to_delete.items():
-            # Get current ref_count for this layer
-            layer_data = self.layers.get(layer_id)
-            if not layer_data:
-                continue
+    def owner_usage(self, label_keys: List[str]) -> List[OwnerUsage]:
+        """Attribute every image and the registry bytes it uses to an owner.
 
-            current_ref = layer_data["ref_count"]
+        exclusive_bytes are what deleting all of an owner's images would free;
+        amortized_bytes split every layer evenly between the images using it,
+        so they add up to the registry's total across owners and suit
+        chargeback. Foreign layers are not stored in the registry and count
+        toward no owner.
 
-            # If this layer would have 0 references after deletion, it will be freed
-            if current_ref == delete_count:
-                layer_size = layer_data["size_bytes"]
-                total_freed += layer_size
+        Args:
+            label_keys: Owner label keys, in order of preference
 
-        return int(total_freed)
+        Returns:
+            Usage per owner, largest amortized_bytes first
+        """
+        owners = self.image_owners(label_keys)
+        layers = self.layers
+        layer_images: Dict[str, Set[str]] = {}
+        for mapping in self.image_layers:
+            if mapping["image_id"] in owners and mapping["layer_id"] not in self.foreign_layers:
+                layer_images.setdefault(mapping["layer_id"], set()).add(mapping["image_id"])
+
+        usage: Dict[str, OwnerUsage] = {}
+        for owner in owners.values():
+            if owner not in usage:
+                usage[owner] = {
+                    "owner": owner,
+                    "images": 0,
+                    "total_bytes": 0,
+                    "exclusive_bytes": 0,
+                    "shared_bytes": 0,
+                    "amortized_bytes": 0,
+                }
+            usage[owner]["images"] += 1
+
+        amortized: Counter = Counter()
+        for layer_id, image_ids in layer_images.items():
+            size = layers[layer_id]["size_bytes"] if layer_id in layers else 0
+            layer_owners = Counter(owners[image_id] for image_id in image_ids)
+            for owner, count in layer_owners.items():
+                usage[owner]["total_bytes"] += size
+                usage[owner]["exclusive_bytes" if len(layer_owners) == 1 else "shared_bytes"] += size
+                amortized[owner] += size * count / len(image_ids)
+        for owner, value in amortized.items():
+            usage[owner]["amortized_bytes"] = int(round(value))
+        return sorted(usage.values(), key=lambda entry: (-entry["amortized_bytes"], entry["owner"]))
+
+    def _image_info(self, image_id: str, image_data: ImageData) -> Dict[str, Any]:
+        """Image dict with image_id and, if any, its attachedArtifacts"""
+        info: Dict[str, Any] = {"image_id": image_id, **image_data}
+        if image_id in self.attached_artifacts:
+            info["attachedArtifacts"] = list(self.attached_artifacts[image_id])
+        return info
 
     def get_unused_images(self, used_tags: List[str]) -> List[Dict[str, Any]]:
         """Get images that are not in the used_tags list.
@@ -321,7 +2059,7 @@ class ImageAnalyzer:
         unused_images: List[Dict[str, Any]] = []
         for image_id, image_data in self.images.items():
             if image_data["tag"] not in used_tags_set:
-                unused_images.append({"image_id": image_id, **image_data})
+                unused_images.append(self._image_info(image_id, image_data))
         return unused_images
 
     def generate_summary_stats(self) -> SummaryStats:
@@ -355,6 +2093,480 @@ class ImageAnalyzer:
             "avg_ref_count": round(avg_ref_count, 2),
         }
 
+    def generate_repository_summary(self) -> Dict[str, RepositorySummary]:
+        """Generate per-repository composition and deduplication statistics.
+
+        A layer counts as unique to a repository when no image in any other
+        repository references it; otherwise its bytes are reported as shared.
+        A layer counts as introduced by the repository of its origin (see
+        layer_origins), so each layer with a known origin is introduced once.
+        Layer bytes are counted once per repository regardless of how many tags
+        within that repository reference the layer. The oldest and newest images
+        are taken from image creation times, which skopeo reports on inspection.
+
+        Returns:
+            Dict mapping repository -> RepositorySummary
+        """
+        repo_layers: Dict[str, set] = {}
+        layer_repos: Dict[str, set] = {}
+        image_sizes: Dict[str, int] = {}
+
+        for mapping in self.image_layers:
+            image_data = self.images.get(mapping["image_id"])
+            if not image_data:
+                continue
+            repository = image_data["repository"]
+            layer_id = mapping["layer_id"]
+            repo_layers.setdefault(repository, set()).add(layer_id)
+            layer_repos.setdefault(layer_id, set()).add(repository)
+            layer_data = self.layers.get(layer_id)
+            if layer_data:
+                image_sizes[mapping["image_id"]] = image_sizes.get(mapping["image_id"], 0) + layer_data["size_bytes"]
+
+        summaries: Dict[str, RepositorySummary] = {}
+        for image_id, image_data in self.images.items():
+            repository = image_data["repository"]
+            summary = summaries.setdefault(
+                repository,
+                {
+                    "repository": repository,
+                    "tag_count": 0,
+                    "layer_count": 0,
+                    "total_bytes": 0,
+                    "unique_layers": 0,
+                    "unique_bytes": 0,
+                    "shared_layers": 0,
+                    "shared_bytes": 0,
+                    "introduced_layers": 0,
+                    "introduced_bytes": 0,
+                    "largest_image": None,
+                    "oldest_image": None,
+                    "newest_image": None,
+                },
+            )
+            summary["tag_count"] += 1
+
+            size = image_sizes.get(image_id, 0)
+            largest = summary["largest_image"]
+            if largest is None or size > largest["size_bytes"]:
+                summary["largest_image"] = {"image_id": image_id, "tag": image_data["tag"], "size_bytes": size}
+
+            created = self.created.get(image_id)
+            if created:
+                dated = {
+                    "image_id": image_id,
+                    "tag": image_data["tag"],
+                    "created": created,
+                    "age_days": age_days(created),
+                }
+                if summary["oldest_image"] is None or created < summary["oldest_image"]["created"]:
+                    summary["oldest_image"] = dated
+                if summary["newest_image"] is None or created > summary["newest_image"]["created"]:
+                    summary["newest_image"] = dated
+
+        for repository, layer_ids in repo_layers.items():
+            summary = summaries[repository]
+            for layer_id in layer_ids:
+                size = self.layers.get(layer_id, {}).get("size_bytes", 0)
+                summary["layer_count"] += 1
+                summary["total_bytes"] += size
+                if len(layer_repos[layer_id]) == 1:
+                    summary["unique_layers"] += 1
+                    summary["unique_bytes"] += size
+                else:
+                    summary["shared_layers"] += 1
+                    summary["shared_bytes"] += size
+
+        for layer_id, origin in self.layer_origins().items():
+            summary = summaries[origin["repository"]]
+            summary["introduced_layers"] += 1
+            summary["introduced_bytes"] += self.layers.get(layer_id, {}).get("size_bytes", 0)
+
+        return summaries
+
+    def layer_origins(self) -> Dict[str, LayerOrigin]:
+        """Find the image that introduced each layer.
+
+        Shared base layers are counted against every image that references them,
+        which hides where they came from. The origin of a layer is the earliest
+        created image referencing it (ties broken by image_id), so a shared blob
+        can be attributed to the environment or model that introduced it.
+        Layers whose images all lack a creation time have no known origin and
+        are left out.
+
+        Returns:
+            Dict mapping layer_id -> LayerOrigin
+        """
+        layer_images: Dict[str, Set[str]] = {}
+        for mapping in self.image_layers:
+            if mapping["image_id"] in self.images:
+                layer_images.setdefault(mapping["layer_id"], set()).add(mapping["image_id"])
+
+        created_at: Dict[str, datetime] = {}
+        for image_id, created in self.created.items():
+            parsed = parse_created(created)
+            if parsed is not None:
+                created_at[image_id] = parsed if parsed.tzinfo else parsed.replace(tzinfo=timezone.utc)
+
+        origins: Dict[str, LayerOrigin] = {}
+        for layer_id, image_ids in layer_images.items():
+            dated = [image_id for image_id in image_ids if image_id in created_at]
+            if not dated:
+                continue
+            image_id = min(dated, key=lambda i: (created_at[i], i))
+            image_data = self.images[image_id]
+            namespaces = {
+                f"{self.images[i]['repository']}/{extract_tag_namespace(self.images[i]['tag'])}" for i in image_ids
+            }
+            origins[layer_id] = {
+                "image_id": image_id,
+                "repository": image_data["repository"],
+                "namespace": f"{image_data['repository']}/{extract_tag_namespace(image_data['tag'])}",
+                "tag": image_data["tag"],
+                "created": self.created[image_id],
+                "consumers": len(image_ids),
+                "consumer_namespaces": len(namespaces),
+            }
+        return origins
+
+    def shared_layer_introducers(self, origins: Optional[Dict[str, LayerOrigin]] = None) -> List[SharedLayerIntroducer]:
+        """Attribute the layers shared between namespaces to the namespaces that introduced them.
+
+        Args:
+            origins: Result of layer_origins (computed if omitted)
+
+        Returns:
+            List of SharedLayerIntroducer sorted by bytes (largest first)
+        """
+        origins = self.layer_origins() if origins is None else origins
+        layer_namespaces: Dict[str, Set[str]] = {}
+        for mapping in self.image_layers:
+            image_data = self.images.get(mapping["image_id"])
+            if image_data and mapping["layer_id"] in origins:
+                namespace = f"{image_data['repository']}/{extract_tag_namespace(image_data['tag'])}"
+                layer_namespaces.setdefault(mapping["layer_id"], set()).add(namespace)
+
+        introducers: Dict[str, SharedLayerIntroducer] = {}
+        consumers: Dict[str, Set[str]] = {}
+        for layer_id, origin in origins.items():
+            if origin["consumer_namespaces"] < 2:
+                continue
+            introducer = introducers.setdefault(
+                origin["namespace"],
+                {
+                    "namespace": origin["namespace"],
+                    "repository": origin["repository"],
+                    "layers": 0,
+                    "bytes": 0,
+                    "consumer_namespaces": 0,
+                },
+            )
+            introducer["layers"] += 1
+            introducer["bytes"] += self.layers.get(layer_id, {}).get("size_bytes", 0)
+            consumers.setdefault(origin["namespace"], set()).update(layer_namespaces[layer_id] - {origin["namespace"]})
+        for namespace, introducer in introducers.items():
+            introducer["consumer_namespaces"] = len(consumers[namespace])
+        return sorted(introducers.values(), key=lambda i: (-i["bytes"], i["namespace"]))
+
+    def find_duplicate_images(self) -> List[DuplicateImageGroup]:
+        """Find identical images (same manifest digest) pushed under different namespaces.
+
+        A namespace is the repository plus the environment/model ObjectID the tag
+        starts with. Images sharing a digest are stored only once by the registry,
+        but each extra namespace holding them is a duplicated logical copy that
+        complicates retention decisions. For each group, the namespace with the
+        most tags (ties broken by name) is proposed as canonical, and every other
+        image is planned to be replaced by a reference to the canonical one.
+
+        Returns:
+            List of DuplicateImageGroup sorted by duplicated bytes (largest first)
+        """
+        by_digest: Dict[str, List[str]] = {}
+        for image_id, image_data in self.images.items():
+            digest = image_data.get("digest")
+            if digest:
+                by_digest.setdefault(digest, []).append(image_id)
+
+        groups: List[DuplicateImageGroup] = []
+        for digest, image_ids in by_digest.items():
+            namespace_images: Dict[str, List[str]] = {}
+            for image_id in sorted(image_ids):
+                image_data = self.images[image_id]
+                namespace = f"{image_data['repository']}/{extract_tag_namespace(image_data['tag'])}"
+                namespace_images.setdefault(namespace, []).append(image_id)
+
+            if len(namespace_images) < 2:
+                continue
+
+            canonical_namespace = min(namespace_images, key=lambda ns: (-len(namespace_images[ns]), ns))
+            canonical_image_id = namespace_images[canonical_namespace][0]
+            size = self.get_image_total_size(canonical_image_id)
+
+            plan = [
+                {"image_id": image_id, "namespace": namespace, "replace_with": canonical_image_id}
+                for namespace, ns_image_ids in sorted(namespace_images.items())
+                if namespace != canonical_namespace
+                for image_id in ns_image_ids
+            ]
+
+            groups.append(
+                {
+                    "digest": digest,
+                    "size_bytes": size,
+                    "namespaces": sorted(namespace_images),
+                    "image_ids": sorted(image_ids),
+                    "duplicated_bytes": size * (len(namespace_images) - 1),
+                    "canonical_image_id": canonical_image_id,
+                    "canonicalization_plan": plan,
+                }
+            )
+
+        groups.sort(key=lambda g: g["duplicated_bytes"], reverse=True)
+        return groups
+
+    def find_tag_aliases(self) -> List[TagAliasGroup]:
+        """Find tags within a repository that point at the same manifest digest.
+
+        Returns:
+            List of TagAliasGroup sorted by number of tags (most first)
+        """
+        by_digest: Dict[Tuple[str, str], List[str]] = {}
+        for image_id, image_data in self.images.items():
+            if image_data.get("digest"):
+                by_digest.setdefault((image_data["repository"], image_data["digest"]), []).append(image_id)
+
+        groups: List[TagAliasGroup] = []
+        for (repository, digest), image_ids in by_digest.items():
+            if len(image_ids) < 2:
+                continue
+            groups.append(
+                {
+                    "repository": repository,
+                    "digest": digest,
+                    "tags": sorted(self.images[image_id]["tag"] for image_id in image_ids),
+                    "size_bytes": self.get_image_total_size(image_ids[0]),
+                }
+            )
+
+        groups.sort(key=lambda g: (-len(g["tags"]), g["repository"], g["digest"]))
+        return groups
+
+    def find_cross_repository_duplicates(self) -> List[CrossRepositoryDuplicate]:
+        """Find identical manifests (same digest) stored in more than one repository.
+
+        Returns:
+            List of CrossRepositoryDuplicate sorted by size (largest first)
+        """
+        by_digest: Dict[str, List[str]] = {}
+        for image_id, image_data in self.images.items():
+            if image_data.get("digest"):
+                by_digest.setdefault(image_data["digest"], []).append(image_id)
+
+        duplicates: List[CrossRepositoryDuplicate] = []
+        for digest, image_ids in by_digest.items():
+            repositories = sorted({self.images[image_id]["repository"] for image_id in image_ids})
+            if len(repositories) < 2:
+                continue
+            duplicates.append(
+                {
+                    "digest": digest,
+                    "repositories": repositories,
+                    "image_ids": sorted(image_ids),
+                    "size_bytes": self.get_image_total_size(sorted(image_ids)[0]),
+                }
+            )
+
+        duplicates.sort(key=lambda d: d["size_bytes"], reverse=True)
+        return duplicates
+
+    def collect_layer_diff_ids(self, max_workers: Optional[int] = None) -> Dict[str, str]:
+        """Map each layer (compressed blob digest) to its diffID (uncompressed content digest).
+
+        Diff IDs are read from the image config, once per distinct manifest
+        digest, and matched to the image's layers by position. Images with
+        encrypted layers are skipped: their diffIDs describe content that
+        cannot be read without the decryption key.
+
+        Args:
+            max_workers: Number of parallel config requests (default: from config)
+
+        Returns:
+            Dict mapping layer_id -> diffID for every layer whose image config could be read
+        """
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+
+        encrypted_images = self.images_with_encrypted_layers()
+        if encrypted_images:
+            self.logger.info(f"Skipping {len(encrypted_images)} image(s) with encrypted layers")
+        representatives: Dict[str, str] = {}
+        for image_id, image_data in self.images.items():
+            if image_data.get("digest") and image_id not in encrypted_images:
+                representatives.setdefault(image_data["digest"], image_id)
+
+        ordered_layers: Dict[str, List[str]] = {}
+        for mapping in sorted(self.image_layers, key=lambda m: (m["image_id"], m["order_index"])):
+            ordered_layers.setdefault(mapping["image_id"], []).append(mapping["layer_id"])
+
+        def fetch(image_id: str) -> Optional[Dict]:
+            if image_id in self.config_details:
+                # Already read by a deep scan
+                return {"rootfs": {"diff_ids": self.config_details[image_id]["diff_ids"]}}
+            image_data = self.images[image_id]
+            return self.skopeo_client.get_image_config(image_data["repository"], image_data["tag"])
+
+        diff_ids: Dict[str, str] = {}
+        self.logger.info(f"Reading image configs for {len(representatives)} distinct manifests...")
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_image = {executor.submit(fetch, image_id): image_id for image_id in representatives.values()}
+            for future in concurrent.futures.as_completed(future_to_image):
+                image_id = future_to_image[future]
+                try:
+                    image_config = future.result()
+                except Exception as e:
+                    self.logger.warning(f"Could not read image config of {image_id}: {e}")
+                    continue
+                image_diff_ids = ((image_config or {}).get("rootfs") or {}).get("diff_ids") or []
+                layers = ordered_layers.get(image_id, [])
+                if len(image_diff_ids) != len(layers):
+                    # Manifest lists and configs with empty layers cannot be matched by position
+                    continue
+                diff_ids.update(zip(layers, image_diff_ids))
+        return diff_ids
+
+    def find_duplicate_layers(self, diff_ids: Dict[str, str]) -> List[DuplicateLayerGroup]:
+        """Find layers stored as different blobs although their uncompressed content is identical.
+
+        This happens when the same layer is compressed differently (e.g. by
+        different build tools or compression levels); the registry cannot
+        deduplicate such blobs, so every copy after the smallest is wasted space.
+
+        Args:
+            diff_ids: layer_id -> diffID, from collect_layer_diff_ids
+
+        Returns:
+            List of DuplicateLayerGroup sorted by duplicated bytes (largest first)
+        """
+        by_diff_id: Dict[str, List[str]] = {}
+        for layer_id, diff_id in diff_ids.items():
+            if layer_id in self.layers:
+                by_diff_id.setdefault(diff_id, []).append(layer_id)
+
+        groups: List[DuplicateLayerGroup] = []
+        for diff_id, layer_ids in by_diff_id.items():
+            if len(layer_ids) < 2:
+                continue
+            sizes = [self.layers[layer_id]["size_bytes"] for layer_id in layer_ids]
+            groups.append(
+                {
+                    "diff_id": diff_id,
+                    "layer_ids": sorted(layer_ids),
+                    "size_bytes": sum(sizes),
+                    "duplicated_bytes": sum(sizes) - min(sizes),
+                }
+            )
+
+        groups.sort(key=lambda g: g["duplicated_bytes"], reverse=True)
+        return groups
+
+    def collect_layer_contents(self, max_workers: Optional[int] = None) -> Dict[str, LayerListing]:
+        """Download selected layers and list their tar entries (contents scans).
+
+        The largest layers within analysis.contents limits are read, each once,
+        from a repository of an image that uses it (see utils/layer_contents.py).
+
+        Args:
+            max_workers: Number of parallel downloads (default: from config)
+
+        Returns:
+            layer_id -> listing, for every layer that could be read
+        """
+        if max_workers is None:
+            max_workers = config_manager.get_max_workers()
+        limits = config_manager.get_layer_contents_limits()
+        layer_ids = select_layers(
+            {layer_id: layer["size_bytes"] for layer_id, layer in self.layers.items()},
+            self.foreign_layers | self.encrypted_layers(),
+            limits["max_layers"],
+            limits["max_layer_mb"] * 1024 * 1024,
+        )
+        repositories: Dict[str, str] = {}
+        for mapping in self.image_layers:
+            image = self.images.get(mapping["image_id"])
+            if image and mapping["layer_id"] not in repositories:
+                repositories[mapping["layer_id"]] = image["repository"]
+
+        def reader(stream: Any) -> LayerListing:
+            return list_layer_entries(
+                stream, limits["max_entries_per_layer"], limits["hash_max_file_mb"] * 1024 * 1024
+            )
+
+        def fetch(layer_id: str) -> Optional[LayerListing]:
+            return self.skopeo_client.read_blob(repositories[layer_id], layer_id, reader)
+
+        skipped = len(self.layers) - len(layer_ids)
+        self.logger.info(f"Listing the contents of {len(layer_ids)} layer(s) ({skipped} skipped by size limits)...")
+        with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+            future_to_layer = {
+                executor.submit(fetch, layer_id): layer_id for layer_id in layer_ids if layer_id in repositories
+            }
+            for future in concurrent.futures.as_completed(future_to_layer):
+                layer_id = future_to_layer[future]
+                try:
+                    listing = future.result()
+                except Exception as e:
+                    self.logger.warning(f"Could not list the contents of layer {layer_id}: {e}")
+                    continue
+                if listing is not None:
+                    self.layer_contents[layer_id] = listing
+        truncated = sum(1 for listing in self.layer_contents.values() if listing["truncated"])
+        self.logger.info(
+            f"Listed {len(self.layer_contents)}/{len(layer_ids)} layer(s)"
+            + (f", {truncated} truncated at {limits['max_entries_per_layer']} entries" if truncated else "")
+        )
+        return self.layer_contents
+
+    def save_layer_contents_report(self, find_patterns: Optional[List[str]] = None) -> str:
+        """Save the entries of the layers read by collect_layer_contents.
+
+        Args:
+            find_patterns: Shell-style file patterns to list the images containing
+
+        Returns:
+            Path of the saved report
+        """
+        layer_images: Dict[str, List[str]] = {}
+        for mapping in self.image_layers:
+            if mapping["layer_id"] in self.layer_contents:
+                layer_images.setdefault(mapping["layer_id"], []).append(mapping["image_id"])
+        duplicates = find_duplicate_files(self.layer_contents)
+        matches = find_files(self.layer_contents, layer_images, find_patterns) if find_patterns else []
+        report = {
+            "summary": {
+                "layers_listed": len(self.layer_contents),
+                "entries": sum(len(listing["entries"]) for listing in self.layer_contents.values()),
+                "truncated_layers": sum(1 for listing in self.layer_contents.values() if listing["truncated"]),
+                "duplicate_files": len(duplicates),
+                "duplicate_file_bytes": sum(group["wasted_bytes"] for group in duplicates),
+                "find_patterns": find_patterns or [],
+                "matches": len(matches),
+            },
+            "matches": matches,
+            "duplicate_files": duplicates,
+            "layers": {
+                layer_id: {"image_ids": sorted(layer_images.get(layer_id, [])), **listing}
+                for layer_id, listing in sorted(self.layer_contents.items())
+            },
+        }
+        saved_path = save_json(config_manager.get_layer_contents_report_path(), report, timestamp=True)
+        self.logger.info(f"Layer contents saved to: {saved_path}")
+        if find_patterns:
+            self.logger.info(
+                f"{len(matches)} file(s) matching {', '.join(find_patterns)} in "
+                f"{len({image_id for match in matches for image_id in match['image_ids']})} image(s)"
+            )
+        return saved_path
+
     def get_images_by_tag_prefix(self, prefix: str) -> List[Dict[str, Any]]:
         """Get all images whose tags start with the given prefix (e.g., ObjectID).
 
@@ -367,7 +2579,7 @@ class ImageAnalyzer:
         matching_images: List[Dict[str, Any]] = []
         for image_id, image_data in self.images.items():
             if image_data["tag"].startswith(prefix):
-                matching_images.append({"image_id": image_id, **image_data})
+                matching_images.append(self._image_info(image_id, image_data))
         return matching_images
 
     def export_to_legacy_format(self) -> Dict[str, LegacyLayerData]:
@@ -391,8 +2603,7 @@ class ImageAnalyzer:
                     tag = self.images[image_id]["tag"]
                     tag_set.add(tag)
                     # Extract environment ID (first part before '-')
-                    env_id = tag.split("-")[0] if "-" in tag else tag
-                    env_set.add(env_id)
+                    env_set.add(extract_tag_namespace(tag))
 
             legacy_data[layer_id] = {
                 "size": int(layer_data["size_bytes"]),
@@ -402,8 +2613,134 @@ class ImageAnalyzer:
 
         return legacy_data
 
-    def save_reports(self) -> None:
-        """Save analysis reports to files."""
+    def save_repos_report(self) -> str:
+        """Save the per-repository summary report (one record per repository).
+
+        Returns:
+            Path of the saved report
+        """
+        repositories = sorted(self.generate_repository_summary().values(), key=lambda r: r["total_bytes"], reverse=True)
+        stats = self.generate_summary_stats()
+        repos_report = {
+            "summary": {
+                "total_repositories": len(repositories),
+                "total_images": stats["total_images"],
+                "total_size_gb": stats["total_size_gb"],
+            },
+            "repositories": repositories,
+        }
+        saved_path = save_json(config_manager.get_repos_report_path(), repos_report, timestamp=True)
+        self.logger.info(f"Repository summary saved to: {saved_path}")
+        return saved_path
+
+    def build_layer_image_map(self) -> Dict[str, Any]:
+        """Map layers to the images using them and images to their layers, in one document.
+
+        Returns:
+            Dict with "summary", "layers_to_images" (layer_id -> size_bytes, ref_count,
+            sorted image_ids and origin, see layer_origins) and "images_to_layers"
+            (image_id -> repository, tag, digest, size_bytes and layer_ids in manifest order)
+        """
+        image_layers: Dict[str, List[Tuple[int, str]]] = {}
+        layer_images: Dict[str, List[str]] = {}
+        for mapping in self.image_layers:
+            image_layers.setdefault(mapping["image_id"], []).append((mapping["order_index"], mapping["layer_id"]))
+            layer_images.setdefault(mapping["layer_id"], []).append(mapping["image_id"])
+
+        origins = self.layer_origins()
+        layers_to_images = {
+            layer_id: {
+                "size_bytes": int(layer_data["size_bytes"]),
+                "ref_count": layer_data["ref_count"],
+                "images": sorted(set(layer_images.get(layer_id, []))),
+                "origin": origins.get(layer_id),
+            }
+            for layer_id, layer_data in sorted(self.layers.items())
+        }
+        images_to_layers = {}
+        for image_id, image_data in sorted(self.images.items()):
+            layer_ids = [layer_id for _, layer_id in sorted(image_layers.get(image_id, []))]
+            images_to_layers[image_id] = {
+                "repository": image_data["repository"],
+                "tag": image_data["tag"],
+                "digest": image_data["digest"],
+                "size_bytes": sum(layers_to_images[layer_id]["size_bytes"] for layer_id in set(layer_ids)),
+                "layers": layer_ids,
+            }
+        return {
+            "summary": {
+                "total_images": len(images_to_layers),
+                "total_layers": len(layers_to_images),
+                "total_size_bytes": sum(layer["size_bytes"] for layer in layers_to_images.values()),
+                "runStats": get_run_stats(),
+            },
+            "layers_to_images": layers_to_images,
+            "images_to_layers": images_to_layers,
+        }
+
+    def save_layer_image_map(self) -> str:
+        """Save the layers-to-images and images-to-layers mappings of this scan as one report.
+
+        Returns:
+            Path of the saved report
+        """
+        saved_path = save_json(config_manager.get_layer_image_map_path(), self.build_layer_image_map(), timestamp=True)
+        self.logger.info(f"Layer and image mappings saved to: {saved_path}")
+        return saved_path
+
+    def save_snapshot(self) -> str:
+        """Save a snapshot of this scan for offline use, such as testing policies.
+
+        Tag usage is included when a MongoDB usage report has been saved; MongoDB
+        itself is not queried. Older snapshots outside the snapshot retention are
+        deleted afterwards.
+
+        Returns:
+            Path of the saved snapshot
+        """
+        from utils.image_usage import ImageUsageService
+
+        service = ImageUsageService()
+        reports = service.load_usage_reports()
+        usage = None
+        if any(reports.values()):
+            usage = service.summarize_tag_usage([image_data["tag"] for image_data in self.images.values()], reports)
+        else:
+            self.logger.warning("No MongoDB usage report found; snapshot saved without usage data")
+        saved_path = save_snapshot(self, config_manager.get_snapshot_path(), usage, timestamp=True)
+        prune_snapshots(config_manager.get_snapshot_path(), config_manager.get_snapshot_retention())
+        return saved_path
+
+    def save_reports(self, mode: str = "all") -> None:
+        """Save analysis reports to files.
+
+        Args:
+            mode: "layers" for the per-layer reports, "images" for the images report,
+                "repos" for the per-repository summary, "snapshot" for a saved scan
+                (see utils/scan_snapshot.py), "both" for layers-to-images and
+                images-to-layers mappings in one report, or "all" for every report
+                but the snapshot and the combined mappings
+        """
+        emit_run_metrics(
+            gauges={
+                "tags_scanned": len(self.images),
+                "bytes_total": sum(layer["size_bytes"] for layer in self.layers.values()),
+            }
+        )
+
+        if mode == "snapshot":
+            self.save_snapshot()
+            return
+
+        if mode == "both":
+            self.save_layer_image_map()
+            return
+
+        if mode in ("repos", "all"):
+            self.save_repos_report()
+        if mode == "repos":
+            return
+
         # Get output paths from config
         final_output_file = config_manager.get_image_analysis_path()
         tags_per_layer_output_file = config_manager.get_tags_per_layer_path()
@@ -412,44 +2749,157 @@ class ImageAnalyzer:
         tag_sums_output_file = config_manager.get_tag_sums_path()
         images_report_output_file = config_manager.get_images_report_path()
 
-        # Export to legacy format (for backward compatibility)
-        # Use timestamp=True for auto-generated reports
+        # Export to legacy format (for backward compatibility); the files of layers mode
+        # map layer IDs and tags to values, so they get no run metadata
         legacy_data = self.export_to_legacy_format()
-        saved_path = save_json(final_output_file, legacy_data, timestamp=True)
-        self.logger.info(f"Image analysis saved to: {saved_path}")
 
-        # Tags per layer
-        tags_per_layer = {layer_id: layer_data["ref_count"] for layer_id, layer_data in self.layers.items()}
-        saved_path = save_json(tags_per_layer_output_file, tags_per_layer, timestamp=True)
-        self.logger.info(f"Tags per layer count saved to: {saved_path}")
+        if mode in ("layers", "all"):
+            # Use timestamp=True for auto-generated reports
+            saved_path = save_json(final_output_file, legacy_data, timestamp=True, run_metadata=False)
+            self.logger.info(f"Image analysis saved to: {saved_path}")
 
-        # Layers and sizes
-        layers_and_sizes = {layer_id: int(layer_data["size_bytes"]) for layer_id, layer_data in self.layers.items()}
-        saved_path = save_json(layers_and_sizes_output_file, layers_and_sizes, timestamp=True)
-        self.logger.info(f"Layers and sizes saved to: {saved_path}")
+            # Tags per layer
+            tags_per_layer = {layer_id: layer_data["ref_count"] for layer_id, layer_data in self.layers.items()}
+            saved_path = save_json(tags_per_layer_output_file, tags_per_layer, timestamp=True, run_metadata=False)
+            self.logger.info(f"Tags per layer count saved to: {saved_path}")
 
-        # Filtered layers (ref_count == 1)
-        filtered_legacy = {}
-        for layer_id, layer_data in self.layers.items():
-            if layer_data["ref_count"] == 1 and layer_id in legacy_data:
-                filtered_legacy[layer_id] = legacy_data[layer_id]
-        saved_path = save_json(filtered_layers_output_file, filtered_legacy, timestamp=True)
-        self.logger.info(f"Filtered layers saved to: {saved_path}")
-
-        # Tag sums (sum of single-use layer sizes per tag)
-        tag_sums = {}
-        for _layer_id, data in filtered_legacy.items():
-            for tag in data["tags"]:
-                if tag not in tag_sums:
-                    tag_sums[tag] = {"size": 0, "environments": data["environments"]}
-                tag_sums[tag]["size"] += data["size"]
-        saved_path = save_json(tag_sums_output_file, tag_sums, timestamp=True)
-        self.logger.info(f"Tag sums saved to: {saved_path}")
-
-        # Images report (comprehensive)
-        images_report = {"summary": self.generate_summary_stats(), "layers": legacy_data}
-        saved_path = save_json(f"{images_report_output_file}.json", images_report, timestamp=True)
-        self.logger.info(f"Images report saved to: {saved_path}")
+            # Layers and sizes
+            layers_and_sizes = {
+                layer_id: int(layer_data["size_bytes"]) for layer_id, layer_data in self.layers.items()
+            }
+            saved_path = save_json(layers_and_sizes_output_file, layers_and_sizes, timestamp=True, run_metadata=False)
+            self.logger.info(f"Layers and sizes saved to: {saved_path}")
+
+            # Filtered layers (ref_count == 1)
+            filtered_legacy = {}
+            for layer_id, layer_data in self.layers.items():
+                if layer_data["ref_count"] == 1 and layer_id in legacy_data:
+                    filtered_legacy[layer_id] = legacy_data[layer_id]
+            saved_path = save_json(filtered_layers_output_file, filtered_legacy, timestamp=True, run_metadata=False)
+            self.logger.info(f"Filtered layers saved to: {saved_path}")
+
+            # Tag sums (sum of single-use layer sizes per tag)
+            tag_sums = {}
+            for _layer_id, data in filtered_legacy.items():
+                for tag in data["tags"]:
+                    if tag not in tag_sums:
+                        tag_sums[tag] = {"size": 0, "environments": data["environments"]}
+                    tag_sums[tag]["size"] += data["size"]
+            saved_path = save_json(tag_sums_output_file, tag_sums, timestamp=True, run_metadata=False)
+            self.logger.info(f"Tag sums saved to: {saved_path}")
+
+        if mode in ("images", "all"):
+            # Images report (comprehensive)
+            images_report = {
+                "summary": self.generate_summary_stats(),
+                "layers": legacy_data,
+                "attachedArtifacts": self.attached_artifacts,
+                "legacyFormat": self.legacy_formats,
+                "annotations": self.annotations,
+                "created": self.image_ages(),
+                "expiry": self.image_expiries(),
+                "foreignLayers": self.images_with_foreign_layers(),
+                "encryptedLayers": self.images_with_encrypted_layers(),
+                "oversizedLayers": self.oversized_layers_report(),
+                "provenance": dict(sorted(self.provenance.items())),
+                "config": dict(sorted(self.config_details.items())),
+                "secretFindings": dict(sorted(self.secret_findings.items())),
+                "toolchain": dict(sorted(self.toolchain.items())),
+                "outdatedToolchains": self.outdated_toolchains(),
+                "osReleases": dict(sorted(self.os_releases.items())),
+                "failures": dict(sorted(self.failures.items())),
+                "runStats": get_run_stats(),
+            }
+            saved_path = save_json(f"{images_report_output_file}.json", images_report, timestamp=True)
+            self.logger.info(f"Images report saved to: {saved_path}")
+            self.save_secret_findings_report()
+            self.save_eol_bases_report()
+
+    def oversized_layers_report(self) -> Dict[str, Any]:
+        """The oversized-layer policy and the layers it flags, for the images report"""
+        policy = config_manager.get_oversized_layer_policy()
+        if not policy:
+            return {"threshold_bytes": None, "layers": []}
+        return {
+            "threshold_bytes": policy["threshold_bytes"],
+            "layers": self.oversized_layers(policy["threshold_bytes"]),
+        }
+
+    def save_eol_bases_report(self, today: Optional[date] = None) -> str:
+        """Save the OS releases of the analyzed images and those past end of life, with counts per environment.
+
+        Images are grouped by the environment (or model) their tag belongs to
+        (see extract_tag_namespace). Releases are only known after a deep scan.
+
+        Args:
+            today: Reference date for end of life (default: today)
+
+        Returns:
+            Path of the saved report
+        """
+        eol = self.eol_images(today)
+        eol_ids = {image["image_id"] for image in eol}
+        per_environment: Dict[str, Dict[str, Any]] = {}
+        for image_id, image_data in sorted(self.images.items()):
+            if image_id not in self.os_releases:
+                continue
+            release = self.os_releases[image_id]
+            image_type = image_id.split(":", 1)[0]
+            owner = f"{image_type}:{extract_tag_namespace(image_data['tag'])}"
+            entry = per_environment.setdefault(owner, {"images": 0, "eol_images": 0, "releases": Counter()})
+            entry["images"] += 1
+            if image_id in eol_ids:
+                entry["eol_images"] += 1
+            entry["releases"][f"{release['distro']} {release['version']}"] += 1
+        by_release = Counter(f"{image['distro']} {image['version']}" for image in eol)
+        report = {
+            "summary": {
+                "images_scanned": len(self.images),
+                "images_with_release": sum(1 for image_id in self.os_releases if image_id in self.images),
+                "eol_images": len(eol),
+                "eol_by_release": dict(sorted(by_release.items())),
+                "environments_with_eol_images": sum(1 for entry in per_environment.values() if entry["eol_images"]),
+                "deep_scan": bool(self.config_details),
+            },
+            "environments": {
+                owner: {**entry, "releases": dict(sorted(entry["releases"].items()))}
+                for owner, entry in sorted(per_environment.items(), key=lambda item: (-item[1]["eol_images"], item[0]))
+            },
+            "eol_images": eol,
+        }
+        saved_path = save_json(config_manager.get_eol_bases_report_path(), report, timestamp=True)
+        self.logger.info(f"End-of-life OS report saved to: {saved_path}")
+        return saved_path
+
+    def save_secret_findings_report(self) -> str:
+        """Save the possible secrets found in image metadata, by image and by rule.
+
+        Returns:
+            Path of the saved report
+        """
+        by_rule: Counter = Counter(
+            finding["rule"] for findings in self.secret_findings.values() for finding in findings
+        )
+        report = {
+            "summary": {
+                "images_scanned": len(self.images),
+                "images_with_findings": len(self.secret_findings),
+                "findings": sum(by_rule.values()),
+                "by_rule": dict(sorted(by_rule.items())),
+                "history_scanned": bool(self.config_details),
+            },
+            "images": {
+                image_id: {
+                    "repository": self.images[image_id]["repository"] if image_id in self.images else None,
+                    "digest": self.images[image_id]["digest"] if image_id in self.images else None,
+                    "findings": findings,
+                }
+                for image_id, findings in sorted(self.secret_findings.items())
+            },
+        }
+        saved_path = save_json(config_manager.get_secret_findings_report_path(), report, timestamp=True)
+        self.logger.info(f"Secret findings saved to: {saved_path}")
+        return saved_path
 
 
 def main() -> None:
@@ -466,6 +2916,30 @@ Examples:
 
   # Filter by ObjectIDs from file
   python image_data_analysis.py --file environments environment model
+
+  # Quick approximate triage scan
+  python image_data_analysis.py --fast
+
+  # Layer sizes only, for storage accounting
+  python image_data_analysis.py --sizes-only
+
+  # Detailed scan including image config history, user and entrypoint
+  python image_data_analysis.py --deep
+
+  # List layer files and find the images containing private keys
+  python image_data_analysis.py --contents --find id_rsa --find '*.pem'
+
+  # Only write the per-repository summary (no per-layer detail)
+  python image_data_analysis.py --mode repos
+
+  # Save a snapshot of the scan for offline policy testing
+  python image_data_analysis.py --mode snapshot
+
+  # Layers-to-images and images-to-layers mappings from one scan, in one report
+  python image_data_analysis.py --mode both
+
+  # Write the results so far every 500 images and every 5 minutes
+  python image_data_analysis.py --flush-every 500 --flush-interval 300
         """,
     )
 
@@ -473,10 +2947,76 @@ Examples:
         "--file",
         help="File containing ObjectIDs (first column) to filter images (requires prefixes: environment:, environmentRevision:, model:, or modelVersion:)",
     )
-    parser.add_argument("--max-workers", type=int, help="Maximum number of parallel workers (default: from config)")
+    parser.add_argument(
+        "--max-workers", "--workers", type=int, help="Maximum number of parallel workers (default: from config)"
+    )
+    parser.add_argument(
+        "--fast",
+        action="store_true",
+        help="Approximate triage scan: fetch only manifests and reuse cached inspections instead of inspecting every tag",
+    )
+    parser.add_argument(
+        "--sizes-only",
+        action="store_true",
+        help="Storage accounting scan: one manifest request per tag for its layer sizes, without labels, env or "
+        "creation times",
+    )
+    parser.add_argument(
+        "--deep",
+        action="store_true",
+        help="Detailed scan: also fetch each image's config blob for its diffIDs, history, user and entrypoint "
+        "(one more request per distinct image)",
+    )
+    parser.add_argument(
+        "--contents",
+        action="store_true",
+        help="Download the largest layers (within analysis.contents limits) and list their files, "
+        "with duplicate files across layers",
+    )
+    parser.add_argument(
+        "--find",
+        action="append",
+        metavar="PATTERN",
+        help="With --contents: list the images containing files matching PATTERN (repeatable), "
+        "e.g. 'id_rsa' or '/root/.aws/*'",
+    )
+    parser.add_argument(
+        "--mode",
+        choices=["all", "layers", "images", "repos", "snapshot", "both"],
+        default="all",
+        help="Reports to write: per-layer reports, the images report, one record per repository, "
+        "a snapshot of the scan for offline use, layers-to-images and images-to-layers mappings in one "
+        "report (both), or the per-layer, images and repository reports (default: all)",
+    )
+    parser.add_argument(
+        "--flush-every",
+        type=int,
+        metavar="N",
+        help="Write the results found so far to the partial results file every N inspected images",
+    )
+    parser.add_argument(
+        "--flush-interval",
+        type=float,
+        metavar="SECONDS",
+        help="Write the results found so far to the partial results file at most every SECONDS seconds",
+    )
+    parser.add_argument(
+        "--partial-output",
+        help="Partial results file for --flush-every/--flush-interval (default: reports/scan-partial.json)",
+    )
     parser.add_argument("images", nargs="*", help="Images to analyze (default: environment, model)")
 
     args = parser.parse_args()
+    if (args.flush_every is not None and args.flush_every < 1) or (
+        args.flush_interval is not None and args.flush_interval <= 0
+    ):
+        parser.error("--flush-every and --flush-interval must be positive")
+    if args.fast and args.sizes_only:
+        parser.error("--fast and --sizes-only cannot be combined")
+    if args.deep and (args.fast or args.sizes_only):
+        parser.error("--deep cannot be combined with --fast or --sizes-only")
+    if args.find and not args.contents:
+        parser.error("--find requires --contents")
 
     # Use config_manager for registry and repository
     registry_url = config_manager.get_registry_url()
@@ -485,16 +3025,7 @@ Examples:
     # Parse ObjectIDs (typed) from file if provided
     object_ids_map = None
     if args.file:
-        object_ids_map = read_typed_object_ids_from_file(args.file)
-        env_ids = set(object_ids_map.get("environment", [])) if object_ids_map else set()
-        env_ids.update(object_ids_map.get("environment_revision", []))
-        model_ids = set(object_ids_map.get("model", [])) if object_ids_map else set()
-        model_ids.update(object_ids_map.get("model_version", []))
-        # Store back into a map keyed by image name
-        object_ids_map = {
-            "environment": sorted(env_ids),
-            "model": sorted(model_ids),
-        }
+        object_ids_map = read_image_object_id_filters(args.file)
         if not any(object_ids_map.values()):
             logger.error(
                 f"No valid ObjectIDs found in file '{args.file}' (prefixes required: environment:, environmentRevision:, model:, modelVersion:)"
@@ -506,8 +3037,8 @@ Examples:
     if args.images:
         images = args.images
     else:
-        logger.info("No images provided for registry scanning, scanning default Domino images...")
-        images = ["environment", "model"]
+        logger.info("No images provided for registry scanning, scanning the profile's image types...")
+        images = config_manager.get_image_types()
 
     logger.info("=" * 60)
     logger.info("   Container Registry Scanning")
@@ -521,30 +3052,47 @@ Examples:
 
     # Create analyzer
     analyzer = ImageAnalyzer(registry_url, repository)
+    if args.flush_every or args.flush_interval:
+        partial_path = args.partial_output or config_manager.get_partial_report_path()
+        analyzer.partial_results = PartialResultWriter(
+            partial_path, analyzer, every_images=args.flush_every, interval_seconds=args.flush_interval
+        )
+        logger.info(f"Partial results: {partial_path}")
+
+    # List and inspect all image types as one pipeline
+    analyzed = analyzer.analyze_images(
+        images,
+        object_ids_map,
+        max_workers=args.max_workers,
+        fast=args.fast,
+        sizes_only=args.sizes_only,
+        deep=args.deep,
+    )
+    success_count = len(analyzed)
+    logger.info("")
+
+    if config_manager.is_failed_tag_retry_enabled():
+        analyzer.retry_failed_tags(max_workers=args.max_workers, fast=args.fast, sizes_only=args.sizes_only)
 
-    # Analyze each image type
-    success_count = 0
-    for image in images:
-        # Pick typed IDs if provided
-        per_image_oids = None
-        if object_ids_map is not None:
-            per_image_oids = object_ids_map.get(image, [])
+    if analyzer.partial_results:
+        logger.info(f"Partial results complete: {analyzer.partial_results.finish()}")
 
-        logger.info(f"\nAnalyzing image type: {image}")
-        if analyzer.analyze_image(image, per_image_oids, max_workers=args.max_workers):
-            success_count += 1
-        logger.info("")
+    # Tags deleted while the scan ran are reported but do not fail it
+    failure_codes = [f["code"] for f in analyzer.failures.values() if f["code"] != ERROR_NOT_FOUND]
 
     if success_count == 0:
         logger.error("No image data found. Check your ObjectID filters or registry access.")
-        sys.exit(1)
+        sys.exit(error_exit_status(failure_codes) or 1)
 
     # Generate and save reports
     logger.info("\n" + "=" * 60)
     logger.info("   Generating Reports")
     logger.info("=" * 60)
 
-    analyzer.save_reports()
+    analyzer.save_reports(args.mode)
+    if args.contents:
+        analyzer.collect_layer_contents(max_workers=args.max_workers)
+        analyzer.save_layer_contents_report(args.find)
 
     # Print summary
     summary = analyzer.generate_summary_stats()
@@ -558,8 +3106,28 @@ Examples:
     logger.info(f"Shared Layers: {summary['shared_layers']} ({summary['shared_size_gb']} GB)")
     logger.info(f"Average Layers per Image: {summary['avg_layers_per_image']}")
     logger.info(f"Average Reference Count: {summary['avg_ref_count']}")
+    if analyzer.failures:
+        codes = Counter(failure["code"] for failure in analyzer.failures.values())
+        counts = ", ".join(f"{count} {code}" for code, count in sorted(codes.items()))
+        logger.info(f"Failed Tags: {len(analyzer.failures)} ({counts})")
     logger.info("=" * 60)
 
+    if failure_codes:
+        status = error_exit_status(failure_codes)
+        logger.error(f"\n❌ Analysis finished with {len(failure_codes)} failed tag(s) (exit status {status})")
+        sys.exit(status)
+
+    oversized_policy = config_manager.get_oversized_layer_policy()
+    if oversized_policy and oversized_policy["fail_run"]:
+        oversized = analyzer.oversized_layers(oversized_policy["threshold_bytes"])
+        if oversized:
+            logger.error(
+                f"\n❌ {len(oversized)} layer(s) larger than {sizeof_fmt(oversized_policy['threshold_bytes'])} "
+                f"(largest: {oversized[0]['layer_id']}, {sizeof_fmt(oversized[0]['size_bytes'])}, "
+                f"in {', '.join(oversized[0]['image_ids'][:3])}) (exit status {OVERSIZED_LAYERS_EXIT_STATUS})"
+            )
+            sys.exit(OVERSIZED_LAYERS_EXIT_STATUS)
+
     logger.info("\n✅ Analysis complete!")
 
 
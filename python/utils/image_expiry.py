@@ -0,0 +1,102 @@
+"""
+Image expiry from build-time annotations and labels.
+
+Builds can mark an image as short-lived by recording when it expires, in an
+OCI annotation of its manifest or a label of its config, for example:
+
+    LABEL quay.expires-after=2w
+    --annotation com.dominodatalab.expires-after=2025-06-30
+
+The value is either a duration after the image's creation time - a number
+followed by s, m, h, d or w, as Quay reads quay.expires-after - or an ISO 8601
+date or time. The keys come from analysis.expiry_keys, first match wins;
+annotations are read before labels. Relative expiries of images whose
+creation time is unknown, and values that cannot be parsed, have no expiry
+time and never count as expired.
+
+Expired images are ranked first by candidates_report and selected by
+plan --unused unless current configuration references them or a run used them
+within analysis.recent_run_protection_days.
+"""
+
+import re
+from datetime import datetime, timedelta, timezone
+from typing import Dict, List, Optional, TypedDict
+
+from utils.deletion_candidates import parse_created
+
+# Expiry keys read when analysis.expiry_keys is not set
+DEFAULT_EXPIRY_KEYS = ["quay.expires-after", "com.dominodatalab.expires-after"]
+
+_DURATION = re.compile(r"^(\d+)\s*([smhdw])$")
+_UNIT_SECONDS = {"s": 1, "m": 60, "h": 3600, "d": 86400, "w": 7 * 86400}
+
+
+class ImageExpiry(TypedDict):
+    """Expiry of one image, as recorded at build time."""
+
+    key: str  # Annotation or label the expiry was read from
+    value: str
+    source: str  # "annotation" or "label"
+    expires_at: Optional[str]  # ISO 8601, None if the value or the creation time is unknown
+    expired: bool
+
+
+def parse_expiry(value: str, created: Optional[str]) -> Optional[datetime]:
+    """Expiry time of an image from an expiry value.
+
+    Args:
+        value: Duration such as "2w" or "36h", or an ISO 8601 date or time
+        created: Creation time of the image (ISO 8601), for durations
+
+    Returns:
+        The expiry time (UTC if the value has no zone), or None if it cannot be determined
+    """
+    value = value.strip()
+    duration = _DURATION.match(value.lower())
+    if duration:
+        created_at = parse_created(created)
+        if created_at is None:
+            return None
+        if created_at.tzinfo is None:
+            created_at = created_at.replace(tzinfo=timezone.utc)
+        return created_at + timedelta(seconds=int(duration.group(1)) * _UNIT_SECONDS[duration.group(2)])
+    expires_at = parse_created(value)
+    if expires_at is not None and expires_at.tzinfo is None:
+        expires_at = expires_at.replace(tzinfo=timezone.utc)
+    return expires_at
+
+
+def image_expiry(
+    annotations: Optional[Dict[str, str]],
+    labels: Optional[Dict[str, str]],
+    created: Optional[str],
+    keys: List[str],
+    now: Optional[datetime] = None,
+) -> Optional[ImageExpiry]:
+    """Expiry of an image from its annotations and labels.
+
+    Args:
+        annotations: OCI annotations of the image, or None if not collected
+        labels: Labels of the image config, or None if not read
+        created: Creation time of the image (ISO 8601)
+        keys: Expiry keys, in order of preference
+        now: Reference time (default: current time)
+
+    Returns:
+        The image's expiry, or None if it has none of the keys
+    """
+    for key in keys:
+        for source, values in (("annotation", annotations), ("label", labels)):
+            value = (values or {}).get(key, "").strip()
+            if not value:
+                continue
+            expires_at = parse_expiry(value, created)
+            return {
+                "key": key,
+                "value": value,
+                "source": source,
+                "expires_at": expires_at.isoformat() if expires_at else None,
+                "expired": expires_at is not None and expires_at <= (now or datetime.now(timezone.utc)),
+            }
+    return None
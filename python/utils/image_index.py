@@ -0,0 +1,360 @@
+"""
+Thread-safe index of registry images and their layers.
+
+An index owns the three structures produced by image analysis and keeps them
+consistent with each other:
+
+- layers: mapping of layer_id -> {size_bytes, ref_count}
+- images: mapping of image_id -> {repository, tag, digest}
+- image_layers: sequence of {image_id, layer_id, order_index}
+
+All writes go through add_image/remove_image/clear, which hold a lock for the
+whole update, so images can be recorded from worker threads while a scan is in
+progress. Readers that iterate the live structures while writers may still be
+active should hold `index.lock` or work on a snapshot().
+
+Two implementations share this interface:
+
+- InMemoryImageIndex: plain dicts and lists, the default
+- SqliteImageIndex: a temporary SQLite database, for registries too large to
+  index in memory. Its layers/images/image_layers are read-only views.
+"""
+
+import atexit
+import os
+import sqlite3
+import tempfile
+import threading
+from abc import ABC, abstractmethod
+from collections.abc import Mapping
+from typing import Any, Dict, Iterable, Iterator, List, Optional, Tuple, TypedDict
+
+
+class LayerData(TypedDict):
+    """Layer information stored in the index."""
+
+    size_bytes: int
+    ref_count: int
+
+
+class ImageData(TypedDict):
+    """Image metadata stored in the index."""
+
+    repository: str
+    tag: str
+    digest: str
+
+
+class ImageLayerMapping(TypedDict):
+    """Mapping between an image and one of its layers."""
+
+    image_id: str
+    layer_id: str
+    order_index: int
+
+
+class ImageIndex(ABC):
+    """Interface shared by image/layer index backends."""
+
+    lock: threading.RLock
+    layers: Mapping
+    images: Mapping
+    image_layers: Iterable[ImageLayerMapping]
+
+    @abstractmethod
+    def add_image(
+        self, image_id: str, repository: str, tag: str, digest: str, layers: Iterable[Tuple[str, int]]
+    ) -> None:
+        """Record an image and its layers, replacing any existing entry for image_id."""
+
+    @abstractmethod
+    def remove_image(self, image_id: str) -> bool:
+        """Remove an image, returning False if it was not indexed."""
+
+    @abstractmethod
+    def clear(self) -> None:
+        """Remove all images and layers."""
+
+    @abstractmethod
+    def __len__(self) -> int:
+        """Number of indexed images."""
+
+    def snapshot(self) -> Tuple[Dict[str, LayerData], Dict[str, ImageData], List[ImageLayerMapping]]:
+        """Return consistent in-memory copies of (layers, images, image_layers)."""
+        with self.lock:
+            layers = {layer_id: dict(data) for layer_id, data in self.layers.items()}
+            images = {image_id: dict(data) for image_id, data in self.images.items()}
+            image_layers = [dict(mapping) for mapping in self.image_layers]
+        return layers, images, image_layers  # type: ignore[return-value]
+
+    def copy_from(self, other: "ImageIndex") -> None:
+        """Add every image of another index to this one, preserving layer order."""
+        layers_by_image: Dict[str, List[Tuple[int, str]]] = {}
+        with other.lock:
+            for mapping in other.image_layers:
+                layers_by_image.setdefault(mapping["image_id"], []).append(
+                    (mapping["order_index"], mapping["layer_id"])
+                )
+            for image_id, image_data in other.images.items():
+                ordered = sorted(layers_by_image.get(image_id, []))
+                self.add_image(
+                    image_id,
+                    image_data["repository"],
+                    image_data["tag"],
+                    image_data["digest"],
+                    [(layer_id, other.layers[layer_id]["size_bytes"]) for _, layer_id in ordered],
+                )
+
+
+class InMemoryImageIndex(ImageIndex):
+    """In-memory image/layer index guarded by a re-entrant lock."""
+
+    def __init__(self) -> None:
+        self.lock = threading.RLock()
+        self.layers: Dict[str, LayerData] = {}
+        self.images: Dict[str, ImageData] = {}
+        self.image_layers: List[ImageLayerMapping] = []
+
+    def add_image(
+        self, image_id: str, repository: str, tag: str, digest: str, layers: Iterable[Tuple[str, int]]
+    ) -> None:
+        """Record an image and its layers, updating layer reference counts.
+
+        Re-adding an image that is already indexed replaces it, so its layers
+        are never counted twice.
+
+        Args:
+            image_id: Image ID ("<type>:<tag>")
+            repository: Full repository path, e.g. "dominodatalab/environment"
+            tag: Image tag
+            digest: Manifest digest
+            layers: (layer_id, size_bytes) pairs in manifest order
+        """
+        with self.lock:
+            if image_id in self.images:
+                self.remove_image(image_id)
+
+            self.images[image_id] = {"repository": repository, "tag": tag, "digest": digest}
+            for order_index, (layer_id, size_bytes) in enumerate(layers):
+                if layer_id in self.layers:
+                    self.layers[layer_id]["ref_count"] += 1
+                else:
+                    self.layers[layer_id] = {"size_bytes": size_bytes, "ref_count": 1}
+                self.image_layers.append({"image_id": image_id, "layer_id": layer_id, "order_index": order_index})
+
+    def remove_image(self, image_id: str) -> bool:
+        """Remove an image, dropping layers that are no longer referenced.
+
+        Returns:
+            True if the image was indexed, False otherwise
+        """
+        with self.lock:
+            if self.images.pop(image_id, None) is None:
+                return False
+
+            remaining: List[ImageLayerMapping] = []
+            for mapping in self.image_layers:
+                if mapping["image_id"] != image_id:
+                    remaining.append(mapping)
+                    continue
+                layer_data = self.layers.get(mapping["layer_id"])
+                if layer_data:
+                    layer_data["ref_count"] -= 1
+                    if layer_data["ref_count"] <= 0:
+                        del self.layers[mapping["layer_id"]]
+            self.image_layers = remaining
+            return True
+
+    def clear(self) -> None:
+        """Remove all images and layers."""
+        with self.lock:
+            self.layers = {}
+            self.images = {}
+            self.image_layers = []
+
+    def __len__(self) -> int:
+        """Number of indexed images."""
+        return len(self.images)
+
+
+class _SqliteMapping(Mapping):
+    """Read-only dict-like view over one SQLite table keyed by its first column."""
+
+    def __init__(self, index: "SqliteImageIndex", table: str, key: str, columns: Tuple[str, ...]) -> None:
+        self._index = index
+        self._table = table
+        self._key = key
+        self._columns = columns
+
+    def _row_to_dict(self, row: Tuple[Any, ...]) -> Dict[str, Any]:
+        return dict(zip(self._columns, row))
+
+    def __getitem__(self, key: str) -> Dict[str, Any]:
+        row = self._index._query_one(
+            f"SELECT {', '.join(self._columns)} FROM {self._table} WHERE {self._key} = ?", (key,)
+        )
+        if row is None:
+            raise KeyError(key)
+        return self._row_to_dict(row)
+
+    def __contains__(self, key: object) -> bool:
+        return self._index._query_one(f"SELECT 1 FROM {self._table} WHERE {self._key} = ?", (key,)) is not None
+
+    def __iter__(self) -> Iterator[str]:
+        for (key,) in self._index._query_iter(f"SELECT {self._key} FROM {self._table} ORDER BY rowid"):
+            yield key
+
+    def __len__(self) -> int:
+        return self._index._query_one(f"SELECT COUNT(*) FROM {self._table}")[0]
+
+    def items(self):  # type: ignore[override]
+        """Iterate (key, value) pairs with a single query."""
+        columns = ", ".join((self._key,) + self._columns)
+        for row in self._index._query_iter(f"SELECT {columns} FROM {self._table} ORDER BY rowid"):
+            yield row[0], self._row_to_dict(row[1:])
+
+    def values(self):  # type: ignore[override]
+        """Iterate values with a single query."""
+        for _, value in self.items():
+            yield value
+
+
+class _SqliteImageLayers:
+    """Read-only list-like view over the image_layers table, in insertion order."""
+
+    def __init__(self, index: "SqliteImageIndex") -> None:
+        self._index = index
+
+    def __iter__(self) -> Iterator[ImageLayerMapping]:
+        for image_id, layer_id, order_index in self._index._query_iter(
+            "SELECT image_id, layer_id, order_index FROM image_layers ORDER BY rowid"
+        ):
+            yield {"image_id": image_id, "layer_id": layer_id, "order_index": order_index}
+
+    def __len__(self) -> int:
+        return self._index._query_one("SELECT COUNT(*) FROM image_layers")[0]
+
+
+class SqliteImageIndex(ImageIndex):
+    """Image/layer index stored in a SQLite database on disk.
+
+    Used for registries with millions of layers, where the in-memory maps no
+    longer fit comfortably in memory. Unless an explicit path is given, the
+    database is a temporary file removed by close() or at interpreter exit.
+    """
+
+    _SCHEMA = """
+        CREATE TABLE IF NOT EXISTS images (
+            image_id TEXT PRIMARY KEY, repository TEXT NOT NULL, tag TEXT NOT NULL, digest TEXT NOT NULL
+        );
+        CREATE TABLE IF NOT EXISTS layers (
+            layer_id TEXT PRIMARY KEY, size_bytes INTEGER NOT NULL, ref_count INTEGER NOT NULL
+        );
+        CREATE TABLE IF NOT EXISTS image_layers (
+            image_id TEXT NOT NULL, layer_id TEXT NOT NULL, order_index INTEGER NOT NULL
+        );
+        CREATE INDEX IF NOT EXISTS idx_image_layers_image ON image_layers (image_id);
+        CREATE INDEX IF NOT EXISTS idx_image_layers_layer ON image_layers (layer_id);
+    """
+
+    def __init__(self, path: Optional[str] = None, directory: Optional[str] = None) -> None:
+        """Open (or create) an on-disk index.
+
+        Args:
+            path: Database file to use; kept after close()
+            directory: Directory for a temporary database when no path is given
+        """
+        self.lock = threading.RLock()
+        self._temporary = path is None
+        if path is None:
+            fd, path = tempfile.mkstemp(prefix="image-index-", suffix=".db", dir=directory)
+            os.close(fd)
+            atexit.register(self.close)
+        self.path = path
+        self._conn: Optional[sqlite3.Connection] = sqlite3.connect(path, check_same_thread=False)
+        with self.lock:
+            self._conn.executescript(self._SCHEMA)
+
+        self.layers = _SqliteMapping(self, "layers", "layer_id", ("size_bytes", "ref_count"))
+        self.images = _SqliteMapping(self, "images", "image_id", ("repository", "tag", "digest"))
+        self.image_layers = _SqliteImageLayers(self)
+
+    def _query_one(self, sql: str, params: Tuple[Any, ...] = ()) -> Optional[Tuple[Any, ...]]:
+        with self.lock:
+            return self._conn.execute(sql, params).fetchone()
+
+    def _query_iter(self, sql: str, params: Tuple[Any, ...] = ()) -> Iterator[Tuple[Any, ...]]:
+        with self.lock:
+            cursor = self._conn.execute(sql, params)
+        while True:
+            with self.lock:
+                rows = cursor.fetchmany(1000)
+            if not rows:
+                return
+            yield from rows
+
+    def add_image(
+        self, image_id: str, repository: str, tag: str, digest: str, layers: Iterable[Tuple[str, int]]
+    ) -> None:
+        """Record an image and its layers, updating layer reference counts.
+
+        Re-adding an image that is already indexed replaces it, so its layers
+        are never counted twice.
+        """
+        with self.lock, self._conn:
+            self._remove_image(image_id)
+            self._conn.execute(
+                "INSERT INTO images (image_id, repository, tag, digest) VALUES (?, ?, ?, ?)",
+                (image_id, repository, tag, digest),
+            )
+            for order_index, (layer_id, size_bytes) in enumerate(layers):
+                self._conn.execute(
+                    "INSERT INTO layers (layer_id, size_bytes, ref_count) VALUES (?, ?, 1) "
+                    "ON CONFLICT(layer_id) DO UPDATE SET ref_count = ref_count + 1",
+                    (layer_id, size_bytes),
+                )
+                self._conn.execute(
+                    "INSERT INTO image_layers (image_id, layer_id, order_index) VALUES (?, ?, ?)",
+                    (image_id, layer_id, order_index),
+                )
+
+    def _remove_image(self, image_id: str) -> bool:
+        """Remove an image; the caller holds the lock and the transaction."""
+        if self._conn.execute("DELETE FROM images WHERE image_id = ?", (image_id,)).rowcount == 0:
+            return False
+        rows = self._conn.execute("SELECT layer_id FROM image_layers WHERE image_id = ?", (image_id,)).fetchall()
+        self._conn.execute("DELETE FROM image_layers WHERE image_id = ?", (image_id,))
+        for (layer_id,) in rows:
+            self._conn.execute("UPDATE layers SET ref_count = ref_count - 1 WHERE layer_id = ?", (layer_id,))
+            self._conn.execute("DELETE FROM layers WHERE layer_id = ? AND ref_count <= 0", (layer_id,))
+        return True
+
+    def remove_image(self, image_id: str) -> bool:
+        """Remove an image, dropping layers that are no longer referenced.
+
+        Returns:
+            True if the image was indexed, False otherwise
+        """
+        with self.lock, self._conn:
+            return self._remove_image(image_id)
+
+    def clear(self) -> None:
+        """Remove all images and layers."""
+        with self.lock, self._conn:
+            self._conn.execute("DELETE FROM image_layers")
+            self._conn.execute("DELETE FROM images")
+            self._conn.execute("DELETE FROM layers")
+
+    def close(self) -> None:
+        """Close the database, deleting it if it was temporary."""
+        with self.lock:
+            if self._conn is None:
+                return
+            self._conn.close()
+            self._conn = None
+            if self._temporary and os.path.exists(self.path):
+                os.remove(self.path)
+
+    def __len__(self) -> int:
+        """Number of indexed images."""
+        return len(self.images)
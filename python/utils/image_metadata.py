@@ -6,10 +6,51 @@ Provides functions for querying MongoDB to get image names and owner information
 for Docker images (models and environments).
 """
 
-from typing import Dict, Optional, Set, Tuple
+from typing import Any, Dict, Iterable, Optional, Set, Tuple, TypedDict
 
 from utils.object_id_utils import normalize_object_id
-from utils.tag_matching import model_tags_match
+from utils.tag_matching import build_model_tag_query, model_tags_match, parse_environment_tag
+
+# Environment revision statuses: whether users can still select the revision in Domino
+REVISION_SELECTABLE = "selectable"
+REVISION_NOT_BUILT = "not_built"  # The revision's build failed or never finished
+REVISION_ENVIRONMENT_ARCHIVED = "environment_archived"
+REVISION_ENVIRONMENT_MISSING = "environment_missing"  # The environment record was deleted
+REVISION_NO_RECORD = "no_record"  # The tag follows Domino's scheme but has no revision record
+
+# Model version deployment statuses, from the version's latest completed deployment saga
+DEPLOYMENT_RUNNING = "running"
+DEPLOYMENT_STOPPED = "stopped"
+DEPLOYMENT_NOT_DEPLOYED = "not_deployed"
+
+# Sagas that start (or stop) a model API serving a version's image
+_START_SAGAS = ("ModelVersionDeployment", "StartModelVersion")
+_STOP_SAGAS = ("StopModelVersion",)
+_SUCCEEDED_SAGA_STATES = ("slug-build-succeeded", "succeeded")
+
+
+class EnvironmentRevision(TypedDict):
+    """The Domino environment revision an environment image was built for."""
+
+    environment_id: str
+    environment_name: str
+    revision: Optional[int]  # Revision number
+    revision_id: str  # "" without a revision record
+    status: str
+    selectable: bool
+    active: bool  # The environment's active revision
+
+
+class ModelVersion(TypedDict):
+    """The Domino model version a model image was built for."""
+
+    model_id: str
+    model_name: str
+    model_archived: bool
+    version_id: str
+    version: Optional[int]  # Version number
+    deployment_status: str
+    pinned: bool  # A model API is running on the image
 
 
 def extract_model_tag_from_version_doc(version_doc: dict) -> Optional[str]:
@@ -305,3 +346,228 @@ def build_environment_tag_to_metadata_mapping(
         logger.debug(traceback.format_exc())
 
     return tag_to_metadata
+
+
+def map_environment_revisions(
+    environment_tags: Iterable[str], revisions: Iterable[Dict[str, Any]], environments: Iterable[Dict[str, Any]]
+) -> Dict[str, EnvironmentRevision]:
+    """Map environment tags to their revisions.
+
+    Args:
+        environment_tags: Environment image tags
+        revisions: environment_revisions documents (_id, environmentId, metadata.number,
+            metadata.isBuilt, metadata.dockerImageName.tag)
+        environments: environments_v2 documents (_id, name, isArchived, activeRevisionId)
+
+    Returns:
+        Dict mapping tag -> revision; tags with neither a revision record nor
+        Domino's <environmentId>-<revision> form are left out
+    """
+    revision_by_tag = {}
+    for rev_doc in revisions:
+        tag = (rev_doc.get("metadata") or {}).get("dockerImageName", {}).get("tag")
+        if tag:
+            revision_by_tag[tag] = rev_doc
+    environment_by_id = {normalize_object_id(env_doc["_id"]): env_doc for env_doc in environments}
+
+    mapping: Dict[str, EnvironmentRevision] = {}
+    for tag in environment_tags:
+        rev_doc = revision_by_tag.get(tag)
+        parsed = parse_environment_tag(tag)
+        if rev_doc is not None:
+            environment_id = normalize_object_id(rev_doc.get("environmentId"))
+            number = (rev_doc.get("metadata") or {}).get("number")
+            revision = number if isinstance(number, int) else (parsed[1] if parsed else None)
+            revision_id = normalize_object_id(rev_doc["_id"])
+        elif parsed:
+            environment_id, revision = parsed
+            revision_id = ""
+        else:
+            continue
+
+        env_doc = environment_by_id.get(environment_id)
+        if rev_doc is None:
+            status = REVISION_NO_RECORD
+        elif env_doc is None:
+            status = REVISION_ENVIRONMENT_MISSING
+        elif env_doc.get("isArchived"):
+            status = REVISION_ENVIRONMENT_ARCHIVED
+        elif rev_doc.get("metadata", {}).get("isBuilt") is False:
+            status = REVISION_NOT_BUILT
+        else:
+            status = REVISION_SELECTABLE
+
+        active_revision_id = normalize_object_id(env_doc.get("activeRevisionId")) if env_doc else ""
+        mapping[tag] = {
+            "environment_id": environment_id,
+            "environment_name": env_doc.get("name", "") if env_doc else "",
+            "revision": revision,
+            "revision_id": revision_id,
+            "status": status,
+            "selectable": status == REVISION_SELECTABLE,
+            "active": bool(revision_id) and revision_id == active_revision_id,
+        }
+    return mapping
+
+
+def build_environment_revision_mapping(environment_tags: Set[str]) -> Dict[str, EnvironmentRevision]:
+    """Look up the Domino environment revision of each environment tag in MongoDB.
+
+    Unlike the metadata mappings above, MongoDB errors are raised rather than
+    logged, so a report never mistakes an unreachable database for missing records.
+
+    Args:
+        environment_tags: Set of environment Docker tags
+
+    Returns:
+        Dict mapping tag -> revision (see map_environment_revisions)
+    """
+    from bson import ObjectId
+
+    from utils.config_manager import config_manager
+    from utils.mongo_utils import get_mongo_client
+
+    if not environment_tags:
+        return {}
+
+    mongo_client = get_mongo_client()
+    try:
+        db = mongo_client[config_manager.get_mongo_db()]
+        revisions = list(
+            db.environment_revisions.find(
+                {"metadata.dockerImageName.tag": {"$in": list(environment_tags)}},
+                {
+                    "_id": 1,
+                    "environmentId": 1,
+                    "metadata.number": 1,
+                    "metadata.isBuilt": 1,
+                    "metadata.dockerImageName.tag": 1,
+                },
+            )
+        )
+
+        environment_ids = {rev_doc["environmentId"] for rev_doc in revisions if rev_doc.get("environmentId")}
+        for tag in environment_tags:
+            parsed = parse_environment_tag(tag)
+            if parsed:
+                environment_ids.add(ObjectId(parsed[0]))
+        environments = db.environments_v2.find(
+            {"_id": {"$in": list(environment_ids)}}, {"_id": 1, "name": 1, "isArchived": 1, "activeRevisionId": 1}
+        )
+        return map_environment_revisions(environment_tags, revisions, environments)
+    finally:
+        mongo_client.close()
+
+
+def map_model_versions(
+    model_tags: Iterable[str],
+    versions: Iterable[Dict[str, Any]],
+    models: Iterable[Dict[str, Any]],
+    sagas: Iterable[Dict[str, Any]],
+) -> Dict[str, ModelVersion]:
+    """Map model tags to their model versions and deployment status.
+
+    Args:
+        model_tags: Model image tags
+        versions: model_versions documents (_id, modelId.value, metadata.number, metadata.builds)
+        models: models documents (_id, name, isArchived)
+        sagas: Completed, succeeded deployment sagas (parameters.modelVersionId, sagaName),
+            newest first
+
+    Returns:
+        Dict mapping tag -> model version; tags matching no version are left out
+    """
+    model_by_id = {normalize_object_id(model_doc["_id"]): model_doc for model_doc in models}
+
+    latest_saga: Dict[str, Dict[str, Any]] = {}
+    for saga in sagas:
+        version_id = normalize_object_id((saga.get("parameters") or {}).get("modelVersionId"))
+        if not version_id:
+            continue
+        latest_saga.setdefault(version_id, saga)
+
+    stored_tags = []
+    for version_doc in versions:
+        stored_tag = extract_model_tag_from_version_doc(version_doc)
+        if stored_tag:
+            stored_tags.append((stored_tag, version_doc))
+
+    mapping: Dict[str, ModelVersion] = {}
+    for tag in model_tags:
+        version_doc = next((doc for stored, doc in stored_tags if model_tags_match(tag, stored)), None)
+        if version_doc is None:
+            continue
+        version_id = normalize_object_id(version_doc["_id"])
+        model_id_obj = version_doc.get("modelId")
+        model_id = normalize_object_id(model_id_obj.get("value") if isinstance(model_id_obj, dict) else model_id_obj)
+        model_doc = model_by_id.get(model_id, {})
+        number = (version_doc.get("metadata") or {}).get("number")
+
+        saga_name = latest_saga.get(version_id, {}).get("sagaName")
+        if saga_name in _START_SAGAS:
+            status = DEPLOYMENT_RUNNING
+        elif saga_name in _STOP_SAGAS:
+            status = DEPLOYMENT_STOPPED
+        else:
+            status = DEPLOYMENT_NOT_DEPLOYED
+
+        mapping[tag] = {
+            "model_id": model_id,
+            "model_name": model_doc.get("name", ""),
+            "model_archived": bool(model_doc.get("isArchived")),
+            "version_id": version_id,
+            "version": number if isinstance(number, int) else None,
+            "deployment_status": status,
+            "pinned": status == DEPLOYMENT_RUNNING,
+        }
+    return mapping
+
+
+def build_model_version_mapping(model_tags: Set[str]) -> Dict[str, ModelVersion]:
+    """Look up the Domino model version and deployment status of each model tag in MongoDB.
+
+    MongoDB errors are raised, as in build_environment_revision_mapping.
+
+    Args:
+        model_tags: Set of model Docker tags
+
+    Returns:
+        Dict mapping tag -> model version (see map_model_versions)
+    """
+    from utils.config_manager import config_manager
+    from utils.mongo_utils import get_mongo_client
+
+    if not model_tags:
+        return {}
+
+    mongo_client = get_mongo_client()
+    try:
+        db = mongo_client[config_manager.get_mongo_db()]
+        versions = list(
+            db.model_versions.find(
+                build_model_tag_query(sorted(model_tags)),
+                {"_id": 1, "modelId.value": 1, "metadata.number": 1, "metadata.builds": 1},
+            )
+        )
+        version_ids = [version_doc["_id"] for version_doc in versions]
+        model_ids = list(
+            {
+                version_doc["modelId"]["value"]
+                for version_doc in versions
+                if isinstance(version_doc.get("modelId"), dict) and version_doc["modelId"].get("value")
+            }
+        )
+        models = db.models.find({"_id": {"$in": model_ids}}, {"_id": 1, "name": 1, "isArchived": 1})
+        sagas = db.sagas.find(
+            {
+                "parameters.modelVersionId": {"$in": version_ids},
+                "sagaName": {"$in": list(_START_SAGAS + _STOP_SAGAS)},
+                "state": {"$in": list(_SUCCEEDED_SAGA_STATES)},
+                "isCompleted": True,
+            },
+            {"parameters.modelVersionId": 1, "sagaName": 1, "started": 1},
+            sort=[("started", -1)],
+        )
+        return map_model_versions(model_tags, versions, list(models), list(sagas))
+    finally:
+        mongo_client.close()
@@ -0,0 +1,234 @@
+"""
+Set algebra over saved image selections.
+
+Every step of a cleanup writes down a set of images - a scan snapshot, a
+candidates report, a plan, the protected images ConfigMap, a hand-written
+candidate file. The set command combines them without external scripting,
+e.g. "what policy A would delete, minus what the cluster scan protects":
+
+    set subtract cleanup-plan.json protected-images.yaml --by digest
+
+Operands are read by their content:
+
+    scan snapshot          every image of the scan
+    cleanup plan           the planned images (items)
+    candidates report      the ranked candidates; FILE#protected for the protected images
+    selection              the output of an earlier set command
+    protection ConfigMap   images.json of the protected images ConfigMap
+    text file              one reference per line: <type>:<tag> or
+                           [<registry>/]<repository>:<tag>, optionally @sha256:...
+
+Images are compared by tag (<type>:<tag>, the image ID every report uses;
+the default) or by manifest digest. Comparing by digest also matches the
+other tags of a manifest, which is what deleting it affects. An operand
+that does not know the digest of some of its images cannot be compared by
+digest, and the command fails rather than guessing.
+"""
+
+import json
+import re
+from pathlib import Path
+from typing import Any, Dict, List, Optional, Tuple, TypedDict
+
+import yaml
+
+from utils.report_utils import open_report
+
+BY_TAG = "tag"
+BY_DIGEST = "digest"
+SET_KEYS = (BY_TAG, BY_DIGEST)
+
+OPERATIONS = ("union", "intersect", "subtract")
+
+_DIGEST = re.compile(r"@?(sha256:[0-9a-f]{64})$")
+
+
+class SetMember(TypedDict):
+    """One image of a selection."""
+
+    image_id: str  # <type>:<tag>
+    repository: str  # As recorded by the operand; may be just the image type
+    tag: str
+    digest: str  # Empty when the operand does not record it
+
+
+class SelectionFormatError(ValueError):
+    """Raised when a file is not a selection the set command can read."""
+
+
+def _member(repository: str, tag: str, digest: Optional[str] = "") -> SetMember:
+    """A selection member from a repository and tag"""
+    return {
+        "image_id": f"{repository.rsplit('/', 1)[-1]}:{tag}",
+        "repository": repository,
+        "tag": tag,
+        "digest": digest or "",
+    }
+
+
+def parse_reference(reference: str) -> SetMember:
+    """A selection member from an image reference.
+
+    Args:
+        reference: <type>:<tag> or [<registry>/]<repository>:<tag>, optionally followed by @sha256:...
+
+    Raises:
+        SelectionFormatError: If the reference has no tag
+    """
+    digest = ""
+    match = _DIGEST.search(reference)
+    if match:
+        digest = match.group(1)
+        reference = reference[: match.start()]
+    name, _, tag = reference.rpartition(":")
+    if not name or not tag or "/" in tag:
+        raise SelectionFormatError(f"'{reference}' is not an image reference with a tag (<type>:<tag>)")
+    return _member(name, tag, digest)
+
+
+def _from_documents(documents: List[Dict[str, Any]], origin: str) -> List[SetMember]:
+    """Members from report entries holding repository and tag (or image_id) and digest"""
+    members: List[SetMember] = []
+    for entry in documents:
+        if not isinstance(entry, dict):
+            raise SelectionFormatError(f"{origin} lists an image that is not an object: {entry!r}")
+        if entry.get("repository") and entry.get("tag"):
+            members.append(_member(entry["repository"], entry["tag"], entry.get("digest")))
+        elif entry.get("image_id"):
+            member = parse_reference(entry["image_id"])
+            member["digest"] = entry.get("digest") or ""
+            members.append(member)
+        else:
+            raise SelectionFormatError(f"{origin} lists an image without repository and tag: {entry!r}")
+    return members
+
+
+def _from_json(data: Any, section: str, origin: str) -> List[SetMember]:
+    """Members of a JSON report"""
+    if not isinstance(data, dict):
+        raise SelectionFormatError(f"{origin} is not a JSON object")
+    if section:
+        if not isinstance(data.get(section), list):
+            raise SelectionFormatError(f"{origin} has no list of images named '{section}'")
+        return _from_documents(data[section], f"{origin}#{section}")
+    if "format_version" in data and isinstance(data.get("images"), dict):
+        # Scan snapshot
+        return [
+            {**_member(image["repository"], image["tag"], image.get("digest")), "image_id": image_id}
+            for image_id, image in data["images"].items()
+        ]
+    for key in ("items", "candidates", "images"):
+        if isinstance(data.get(key), list):
+            return _from_documents(data[key], origin)
+    raise SelectionFormatError(
+        f"{origin} is not a scan snapshot, cleanup plan, candidates report or selection; "
+        "name the list of images with FILE#KEY"
+    )
+
+
+def _from_configmap(data: Dict[str, Any], origin: str) -> List[SetMember]:
+    """Members of the protected images ConfigMap (see utils.protection_manifest)"""
+    listed = (data.get("data") or {}).get("images.json")
+    if listed is None:
+        raise SelectionFormatError(f"{origin} is a ConfigMap without images.json")
+    return [
+        {**parse_reference(reference["image"]), "digest": reference.get("digest") or ""}
+        for reference in json.loads(listed)
+    ]
+
+
+def load_selection(path: str) -> List[SetMember]:
+    """Read the images of a saved selection.
+
+    Args:
+        path: File to read; FILE#KEY reads the list of images under KEY of a JSON report
+
+    Returns:
+        The images, in file order
+
+    Raises:
+        SelectionFormatError: If the file is not a selection the set command can read
+    """
+    file_path, _, section = path.partition("#")
+    with open_report(Path(file_path)) as f:
+        text = f.read()
+
+    stripped = text.lstrip()
+    if stripped.startswith("{"):
+        try:
+            data = json.loads(text)
+        except json.JSONDecodeError as e:
+            raise SelectionFormatError(f"{file_path} is not valid JSON: {e}") from e
+        return _from_json(data, section, file_path)
+    if section:
+        raise SelectionFormatError(f"{file_path} is not a JSON report, so #{section} cannot be read from it")
+    if re.search(r"^kind:\s*ConfigMap\s*$", text, re.MULTILINE):
+        return _from_configmap(yaml.safe_load(text), file_path)
+
+    members: List[SetMember] = []
+    for raw in text.splitlines():
+        line = raw.strip()
+        if line and not line.startswith("#"):
+            members.append(parse_reference(line.split()[0]))
+    return members
+
+
+def _keys(members: List[SetMember], by: str, origin: str) -> List[Tuple[str, SetMember]]:
+    """(key, member) pairs of a selection
+
+    Raises:
+        SelectionFormatError: If images are compared by digest and some have none recorded
+    """
+    if by == BY_TAG:
+        return [(member["image_id"], member) for member in members]
+    missing = [member["image_id"] for member in members if not member["digest"]]
+    if missing:
+        raise SelectionFormatError(
+            f"{origin} does not record the digest of {len(missing)} image(s) (e.g. {missing[0]}); "
+            "compare by tag instead"
+        )
+    return [(member["digest"], member) for member in members]
+
+
+def combine(operation: str, operands: List[Tuple[str, List[SetMember]]], by: str = BY_TAG) -> List[SetMember]:
+    """Combine selections.
+
+    union keeps the images of any operand, intersect those of every operand,
+    and subtract those of the first operand that no other operand has. A
+    result image keeps the record of the first operand holding it; compared
+    by digest, every tag of a matching manifest is kept.
+
+    Args:
+        operation: union, intersect or subtract
+        operands: (name, images) of each operand, at least two
+        by: Compare images by "tag" (<type>:<tag>) or "digest"
+
+    Returns:
+        The resulting images, in the order the operands list them
+    """
+    if operation not in OPERATIONS:
+        raise ValueError(f"Unknown set operation '{operation}' (expected one of: {', '.join(OPERATIONS)})")
+    if by not in SET_KEYS:
+        raise ValueError(f"Unknown set key '{by}' (expected one of: {', '.join(SET_KEYS)})")
+    if len(operands) < 2:
+        raise ValueError(f"{operation} needs at least two operands")
+
+    keyed = [_keys(members, by, name) for name, members in operands]
+    if operation == "union":
+        candidates = [pair for pairs in keyed for pair in pairs]
+    else:
+        candidates = keyed[0]
+    others = [{key for key, _ in pairs} for pairs in keyed[1:]]
+
+    result: List[SetMember] = []
+    seen = set()
+    for key, member in candidates:
+        if member["image_id"] in seen:
+            continue
+        if operation == "intersect" and not all(key in keys for keys in others):
+            continue
+        if operation == "subtract" and any(key in keys for keys in others):
+            continue
+        seen.add(member["image_id"])
+        result.append(member)
+    return result
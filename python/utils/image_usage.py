@@ -11,20 +11,22 @@ are used across Domino:
   - scheduler_jobs.jobDataPlain.overrideEnvironmentId
   - organizations.defaultV2EnvironmentId
   - app_versions.environmentId
+- Direct references read from other MongoDB fields (optional, see utils.reference_scan)
 
 It can:
 - Run aggregations directly against MongoDB and return usage data
 - Save / load usage reports via config_manager paths
 """
 
-import json
 import logging
 from datetime import datetime, timedelta, timezone
 from pathlib import Path
 from typing import Any, Dict, List, Optional, Set, Tuple, TypedDict, Union
 
 from utils.config_manager import config_manager
+from utils.deletion_candidates import PROTECTING_USAGE, TagUsage
 from utils.mongo_utils import get_mongo_client
+from utils.reference_scan import scan_references
 from utils.report_utils import save_json
 
 logger = logging.getLogger(__name__)
@@ -96,6 +98,14 @@ class AppVersionInfo(TypedDict, total=False):
     versionNumber: int
 
 
+class ReferenceInfo(TypedDict, total=False):
+    """A MongoDB document field referencing a Docker image directly (reference scan)."""
+
+    collection: str
+    field: str
+    document_id: str
+
+
 class UsageInfo(TypedDict):
     """Usage information for a Docker image tag."""
 
@@ -106,6 +116,7 @@ class UsageInfo(TypedDict):
     projects: List[ProjectInfo]
     organizations: List[OrganizationInfo]
     app_versions: List[AppVersionInfo]
+    references: List[ReferenceInfo]
 
 
 class MongoDBReports(TypedDict, total=False):
@@ -118,6 +129,7 @@ class MongoDBReports(TypedDict, total=False):
     scheduler_jobs: List[Dict[str, Any]]
     organizations: List[Dict[str, Any]]
     app_versions: List[Dict[str, Any]]
+    references: List[Dict[str, Any]]
 
 
 class EnvironmentUsageInfo(TypedDict):
@@ -149,6 +161,30 @@ from utils.extract_metadata import (
 )
 
 
+def usage_window_days(recent_days: Optional[int]) -> Optional[int]:
+    """Widen a usage window to analysis.recent_run_protection_days.
+
+    Runs and workspaces within the protection window always count as usage, so
+    users on pinned environment revisions are not broken by a short --days.
+
+    Args:
+        recent_days: Requested window in days; None or 0 counts all usage
+
+    Returns:
+        The window to filter usage with
+    """
+    if recent_days is None or recent_days <= 0:
+        return recent_days
+    protection_days = config_manager.get_recent_run_protection_days()
+    if protection_days > recent_days:
+        logger.info(
+            f"Counting usage within the last {protection_days} days (analysis.recent_run_protection_days), "
+            f"not {recent_days}"
+        )
+        return protection_days
+    return recent_days
+
+
 class ImageUsageService:
     """Service for collecting, loading, and analyzing image usage information."""
 
@@ -222,6 +258,15 @@ class ImageUsageService:
         finally:
             client.close()
 
+    def collect_direct_references(self) -> List[Dict[str, Any]]:
+        """Read the reference scan fields (mongo.reference_scan) and resolve them to Docker tags."""
+        fields = config_manager.get_reference_scan_fields() or []
+        client = get_mongo_client()
+        try:
+            return list(scan_references(client[self.mongo_db], fields))
+        finally:
+            client.close()
+
     def collect_model_version_slugs(self, model_version_ids: List[str]) -> Dict[str, Optional[str]]:
         """Look up slug image tags for model versions directly from model_versions collection.
 
@@ -318,10 +363,12 @@ class ImageUsageService:
         """Run usage aggregations against MongoDB.
 
         Args:
-            target: 'model', 'workspace', 'runs', 'projects', 'scheduler_jobs', 'organizations', 'app_versions', or 'all'
+            target: 'model', 'workspace', 'runs', 'projects', 'scheduler_jobs', 'organizations', 'app_versions',
+                'references' (only when mongo.reference_scan is enabled), or 'all'
 
         Returns:
-            Dict with keys subset of {'models', 'workspaces', 'runs', 'projects', 'scheduler_jobs', 'organizations', 'app_versions'}.
+            Dict with keys subset of {'models', 'workspaces', 'runs', 'projects', 'scheduler_jobs', 'organizations',
+            'app_versions', 'references'}.
         """
         results: Dict[str, List[Dict[str, Any]]] = {}
 
@@ -339,6 +386,8 @@ class ImageUsageService:
             results["organizations"] = self.collect_organizations_usage()
         if target in ("app_versions", "all"):
             results["app_versions"] = self.collect_app_versions_usage()
+        if target in ("references", "all") and config_manager.get_reference_scan_fields() is not None:
+            results["references"] = self.collect_direct_references()
 
         return results
 
@@ -350,7 +399,16 @@ class ImageUsageService:
         results = self.run_aggregations(target)
 
         consolidated = {}
-        for key in ["runs", "workspaces", "models", "projects", "scheduler_jobs", "organizations", "app_versions"]:
+        for key in [
+            "runs",
+            "workspaces",
+            "models",
+            "projects",
+            "scheduler_jobs",
+            "organizations",
+            "app_versions",
+            "references",
+        ]:
             consolidated[key] = results.get(key, [])
 
         # Save to consolidated file with timestamp
@@ -374,9 +432,10 @@ class ImageUsageService:
             Dict with keys: 'runs', 'workspaces', 'models', 'projects',
             'scheduler_jobs', 'organizations', 'app_versions'
         """
-        from utils.report_utils import get_latest_report, get_reports_dir
+        from utils.report_utils import find_report_file, get_latest_report, get_reports_dir, load_json
 
         consolidated_path = Path(config_manager.get_mongodb_usage_path())
+        consolidated_path = find_report_file(consolidated_path) or consolidated_path
 
         # If exact file doesn't exist, try to find latest timestamped version
         if not consolidated_path.exists():
@@ -398,21 +457,22 @@ class ImageUsageService:
                 "scheduler_jobs": [],
                 "organizations": [],
                 "app_versions": [],
+                "references": [],
             }
 
         try:
-            with open(consolidated_path, "r") as f:
-                data = json.load(f)
-                # Ensure all keys are present
-                return {
-                    "runs": data.get("runs", []),
-                    "workspaces": data.get("workspaces", []),
-                    "models": data.get("models", []),
-                    "projects": data.get("projects", []),
-                    "scheduler_jobs": data.get("scheduler_jobs", []),
-                    "organizations": data.get("organizations", []),
-                    "app_versions": data.get("app_versions", []),
-                }
+            data = load_json(consolidated_path)
+            # Ensure all keys are present
+            return {
+                "runs": data.get("runs", []),
+                "workspaces": data.get("workspaces", []),
+                "models": data.get("models", []),
+                "projects": data.get("projects", []),
+                "scheduler_jobs": data.get("scheduler_jobs", []),
+                "organizations": data.get("organizations", []),
+                "app_versions": data.get("app_versions", []),
+                "references": data.get("references", []),
+            }
         except Exception:
             # If file is corrupted, return empty dict
             return {
@@ -423,6 +483,7 @@ class ImageUsageService:
                 "scheduler_jobs": [],
                 "organizations": [],
                 "app_versions": [],
+                "references": [],
             }
 
     # ------------------------------------------------------------------
@@ -452,6 +513,7 @@ class ImageUsageService:
                 "scheduler_jobs": reports.get("scheduler_jobs", []),
                 "organizations": reports.get("organizations", []),
                 "app_versions": reports.get("app_versions", []),
+                "references": reports.get("references", []),
             }
 
         return reports
@@ -485,6 +547,7 @@ class ImageUsageService:
                         "projects": [],
                         "organizations": [],
                         "app_versions": [],
+                        "references": [],
                     }
                 run_info = {
                     "run_id": record.get("run_id") or record.get("_id", "unknown"),
@@ -525,6 +588,7 @@ class ImageUsageService:
                             "projects": [],
                             "organizations": [],
                             "app_versions": [],
+                            "references": [],
                         }
                     # Skip if this workspace is already recorded for this tag (same image
                     # can appear in multiple tag fields, e.g. environment + project_default)
@@ -564,6 +628,7 @@ class ImageUsageService:
                             "projects": [],
                             "organizations": [],
                             "app_versions": [],
+                            "references": [],
                         }
                     model_info = {
                         "model_id": model_id,
@@ -587,6 +652,7 @@ class ImageUsageService:
                         "projects": [],
                         "organizations": [],
                         "app_versions": [],
+                        "references": [],
                     }
                 project_info = {
                     "_id": str(record.get("project_id", "")),
@@ -610,6 +676,7 @@ class ImageUsageService:
                         "projects": [],
                         "organizations": [],
                         "app_versions": [],
+                        "references": [],
                     }
                 job_info = {
                     "_id": str(record.get("job_id", "")),
@@ -632,6 +699,7 @@ class ImageUsageService:
                         "projects": [],
                         "organizations": [],
                         "app_versions": [],
+                        "references": [],
                     }
                 org_info = {
                     "_id": str(record.get("organization_id", "")),
@@ -653,6 +721,7 @@ class ImageUsageService:
                         "projects": [],
                         "organizations": [],
                         "app_versions": [],
+                        "references": [],
                     }
                 app_version_info = {
                     "_id": str(record.get("app_version_id", "")),
@@ -661,6 +730,30 @@ class ImageUsageService:
                 }
                 usage_info[tag]["app_versions"].append(app_version_info)
 
+        # Extract tags from direct references (reference scan)
+        for record in mongodb_reports.get("references", []):
+            tag = record.get("environment_docker_tag")
+            if not tag:
+                continue
+            tags.add(tag)
+            if tag not in usage_info:
+                usage_info[tag] = {
+                    "runs": [],
+                    "workspaces": [],
+                    "models": [],
+                    "scheduler_jobs": [],
+                    "projects": [],
+                    "organizations": [],
+                    "app_versions": [],
+                    "references": [],
+                }
+            reference_info = {
+                "collection": record.get("collection", "unknown"),
+                "field": record.get("field", "unknown"),
+                "document_id": record.get("document_id", ""),
+            }
+            usage_info[tag]["references"].append(reference_info)
+
         return tags, usage_info
 
     def get_usage_for_tag(self, tag: str, mongodb_reports: Optional[MongoDBReports] = None) -> UsageInfo:
@@ -687,6 +780,7 @@ class ImageUsageService:
                 "projects": [],
                 "organizations": [],
                 "app_versions": [],
+                "references": [],
             },
         )
 
@@ -733,6 +827,11 @@ class ImageUsageService:
             app_version_count = len(app_versions)
             reasons.append(f"{app_version_count} app version{'s' if app_version_count > 1 else ''}")
 
+        references = usage.get("references", [])
+        if references:
+            reference_count = len(references)
+            reasons.append(f"{reference_count} direct MongoDB reference{'s' if reference_count > 1 else ''}")
+
         if not reasons:
             return "Referenced in system (source unknown)"
 
@@ -811,6 +910,7 @@ class ImageUsageService:
             tags: List of Docker image tags to check
             mongodb_reports: Optional MongoDB usage reports
             recent_days: Optional number of days - if provided, only consider tags as "in-use" if they were used within the last N days
+                (never fewer than analysis.recent_run_protection_days)
 
         Returns:
             Tuple of (set of tags that are in use, dict mapping tag -> usage info)
@@ -835,12 +935,14 @@ class ImageUsageService:
                     "projects": [],
                     "organizations": [],
                     "app_versions": [],
+                    "references": [],
                 },
             )
             for tag in in_use_tags
         }
 
         # Filter by age if recent_days is specified
+        recent_days = usage_window_days(recent_days)
         if recent_days is not None and recent_days > 0:
             threshold = datetime.now(timezone.utc) - timedelta(days=recent_days)
             filtered_in_use_tags = set()
@@ -856,6 +958,7 @@ class ImageUsageService:
                     or len(tag_usage.get("projects", [])) > 0
                     or len(tag_usage.get("organizations", [])) > 0
                     or len(tag_usage.get("app_versions", [])) > 0
+                    or len(tag_usage.get("references", [])) > 0
                 )
 
                 if has_config_usage:
@@ -874,6 +977,28 @@ class ImageUsageService:
 
         return in_use_tags, usage_info
 
+    def summarize_tag_usage(
+        self, tags: List[str], mongodb_reports: Optional[MongoDBReports] = None
+    ) -> Dict[str, TagUsage]:
+        """Condense usage of the given tags into use counts, last use and protecting references.
+
+        Args:
+            tags: List of Docker image tags to check
+            mongodb_reports: Optional MongoDB usage reports
+
+        Returns:
+            Dict mapping tag -> TagUsage for tags with any usage; unused tags are omitted
+        """
+        _, usage_info = self.check_tags_in_use(tags, mongodb_reports)
+        return {
+            tag: {
+                "use_count": len(usage.get("runs", [])) + len(usage.get("workspaces", [])),
+                "last_used": self._get_most_recent_usage_date(usage),
+                "protected_by": [source for source in PROTECTING_USAGE if usage.get(source)],
+            }
+            for tag, usage in usage_info.items()
+        }
+
     def find_usage_for_environment_ids(
         self, environment_ids: Set[str], mongodb_reports: Optional[MongoDBReports] = None
     ) -> Dict[str, EnvironmentUsageInfo]:
@@ -0,0 +1,224 @@
+"""
+Queries over a saved scan snapshot.
+
+A snapshot (see scan_snapshot.py) holds every image and layer of a scan, so
+questions such as "which images over 10 GB are older than six months" or "which
+layers belong to a single image" can be answered from it instantly, without
+touching the registry. The query command (scripts/query.py) runs these queries
+against the newest saved snapshot.
+
+Sizes are given as bytes or with a unit: KB, MB, GB and TB are powers of 1000,
+KiB, MiB, GiB and TiB powers of 1024 ("10GB", "500MiB", "1.5T"). Ages are given
+in days, weeks or years ("180d", "26w", "1y"); a bare number is days.
+"""
+
+import fnmatch
+import re
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional, TypedDict
+
+from utils.deletion_candidates import days_since, parse_created
+
+_SIZE_UNITS = {
+    "": 1,
+    "b": 1,
+    "k": 1000,
+    "kb": 1000,
+    "m": 1000**2,
+    "mb": 1000**2,
+    "g": 1000**3,
+    "gb": 1000**3,
+    "t": 1000**4,
+    "tb": 1000**4,
+    "kib": 1024,
+    "mib": 1024**2,
+    "gib": 1024**3,
+    "tib": 1024**4,
+}
+
+_AGE_UNITS = {"": 1, "d": 1, "w": 7, "y": 365}
+
+IMAGE_SORT_KEYS = ("size", "unique", "age", "name")
+LAYER_SORT_KEYS = ("size", "frequency")
+
+
+class QueriedImage(TypedDict):
+    """An image matching an image query."""
+
+    image_id: str
+    repository: str
+    tag: str
+    digest: str
+    created: Optional[str]
+    age_days: Optional[int]
+    size_bytes: int  # All layers of the image
+    unique_bytes: int  # Layers no other image in the snapshot shares
+    layers: int
+
+
+class QueriedLayer(TypedDict):
+    """A layer matching a layer query."""
+
+    layer_id: str
+    size_bytes: int
+    frequency: int  # Number of images using the layer
+    images: List[str]  # image_ids using the layer, sorted
+
+
+def parse_size(value: str) -> int:
+    """Parse a size such as "10GB", "500MiB" or "1048576" into bytes.
+
+    Raises:
+        ValueError: If the size is malformed
+    """
+    match = re.fullmatch(r"\s*(\d+(?:\.\d+)?)\s*([a-z]*)\s*", value.lower())
+    if not match or match.group(2) not in _SIZE_UNITS:
+        raise ValueError(f"Invalid size {value!r} (expected e.g. 10GB, 500MiB or a number of bytes)")
+    return int(float(match.group(1)) * _SIZE_UNITS[match.group(2)])
+
+
+def parse_age(value: str) -> int:
+    """Parse an age such as "180d", "26w" or "1y" into days.
+
+    Raises:
+        ValueError: If the age is malformed
+    """
+    match = re.fullmatch(r"\s*(\d+)\s*([dwy]?)\s*", value.lower())
+    if not match:
+        raise ValueError(f"Invalid age {value!r} (expected e.g. 180d, 26w or 1y)")
+    return int(match.group(1)) * _AGE_UNITS[match.group(2)]
+
+
+def _layer_images(snapshot: Dict[str, Any]) -> Dict[str, List[str]]:
+    """image_ids using each layer of a snapshot"""
+    layer_images: Dict[str, List[str]] = {}
+    for image_id, image in snapshot["images"].items():
+        for layer_id in set(image["layers"]):
+            layer_images.setdefault(layer_id, []).append(image_id)
+    return layer_images
+
+
+def _matches_repository(repository: str, pattern: Optional[str]) -> bool:
+    """Whether a repository matches a shell-style pattern, by full name or last path segment"""
+    if pattern is None:
+        return True
+    return fnmatch.fnmatch(repository, pattern) or fnmatch.fnmatch(repository.rsplit("/", 1)[-1], pattern)
+
+
+def query_images(
+    snapshot: Dict[str, Any],
+    min_size: Optional[int] = None,
+    max_size: Optional[int] = None,
+    older_than_days: Optional[int] = None,
+    newer_than_days: Optional[int] = None,
+    repository: Optional[str] = None,
+    tag: Optional[str] = None,
+    sort: str = "size",
+    now: Optional[datetime] = None,
+) -> List[QueriedImage]:
+    """Find the images of a snapshot matching every given filter.
+
+    Args:
+        snapshot: Snapshot document (read_snapshot)
+        min_size: Only images of at least this many bytes
+        max_size: Only images of at most this many bytes
+        older_than_days: Only images created more than this many days ago (images of unknown age never match)
+        newer_than_days: Only images created less than this many days ago (images of unknown age never match)
+        repository: Shell-style pattern of the repository, e.g. "environment" or "dominodatalab/*"
+        tag: Shell-style pattern of the tag
+        sort: One of IMAGE_SORT_KEYS; sizes and ages sort largest/oldest first
+        now: Time ages are measured from (default: now)
+
+    Returns:
+        Matching images
+    """
+    now = now or datetime.now(timezone.utc)
+    layer_sizes = snapshot["layers"]
+    layer_images = _layer_images(snapshot)
+    results: List[QueriedImage] = []
+    for image_id, image in snapshot["images"].items():
+        if not _matches_repository(image["repository"], repository):
+            continue
+        if tag is not None and not fnmatch.fnmatchcase(image["tag"], tag):
+            continue
+        layers = set(image["layers"])
+        size_bytes = sum(layer_sizes.get(layer_id, 0) for layer_id in layers)
+        if min_size is not None and size_bytes < min_size:
+            continue
+        if max_size is not None and size_bytes > max_size:
+            continue
+        age = days_since(parse_created(image.get("created")), now)
+        if older_than_days is not None and (age is None or age <= older_than_days):
+            continue
+        if newer_than_days is not None and (age is None or age >= newer_than_days):
+            continue
+        results.append(
+            {
+                "image_id": image_id,
+                "repository": image["repository"],
+                "tag": image["tag"],
+                "digest": image["digest"],
+                "created": image.get("created"),
+                "age_days": None if age is None else int(age),
+                "size_bytes": size_bytes,
+                "unique_bytes": sum(
+                    layer_sizes.get(layer_id, 0) for layer_id in layers if len(layer_images[layer_id]) == 1
+                ),
+                "layers": len(layers),
+            }
+        )
+
+    if sort == "name":
+        results.sort(key=lambda image: (image["repository"], image["tag"]))
+    elif sort == "age":
+        results.sort(key=lambda image: (image["age_days"] is None, -(image["age_days"] or 0), image["image_id"]))
+    else:
+        size_key = "unique_bytes" if sort == "unique" else "size_bytes"
+        results.sort(key=lambda image: (-image[size_key], image["image_id"]))  # type: ignore[literal-required]
+    return results
+
+
+def query_layers(
+    snapshot: Dict[str, Any],
+    frequency: Optional[int] = None,
+    max_frequency: Optional[int] = None,
+    min_size: Optional[int] = None,
+    repository: Optional[str] = None,
+    sort: str = "size",
+) -> List[QueriedLayer]:
+    """Find the layers of a snapshot matching every given filter.
+
+    Args:
+        snapshot: Snapshot document (read_snapshot)
+        frequency: Only layers used by exactly this many images
+        max_frequency: Only layers used by at most this many images
+        min_size: Only layers of at least this many bytes
+        repository: Only layers used by an image of a repository matching this shell-style pattern
+        sort: One of LAYER_SORT_KEYS; largest or most used first
+
+    Returns:
+        Matching layers
+    """
+    images = snapshot["images"]
+    results: List[QueriedLayer] = []
+    for layer_id, image_ids in _layer_images(snapshot).items():
+        size_bytes = int(snapshot["layers"].get(layer_id, 0))
+        if frequency is not None and len(image_ids) != frequency:
+            continue
+        if max_frequency is not None and len(image_ids) > max_frequency:
+            continue
+        if min_size is not None and size_bytes < min_size:
+            continue
+        if repository is not None and not any(
+            _matches_repository(images[image_id]["repository"], repository) for image_id in image_ids
+        ):
+            continue
+        results.append(
+            {"layer_id": layer_id, "size_bytes": size_bytes, "frequency": len(image_ids), "images": sorted(image_ids)}
+        )
+
+    if sort == "frequency":
+        results.sort(key=lambda layer: (-layer["frequency"], -layer["size_bytes"], layer["layer_id"]))
+    else:
+        results.sort(key=lambda layer: (-layer["size_bytes"], layer["layer_id"]))
+    return results
@@ -0,0 +1,128 @@
+"""
+Keep-set export.
+
+The keep-set of a cleanup plan is every analyzed image the plan does not
+delete. Exported as a runnable script or a skopeo sync file, it is the list of
+images a disaster-recovery rehearsal should be able to pull, or a registry
+migration should copy, once the plan is applied:
+
+    # pull: docker pull <registry>/<repository>@<digest> per image
+    # copy: skopeo copy --all docker://<registry>/<repository>@<digest> docker://<destination>/<repository>:<tag>
+    # skopeo-sync: skopeo sync --src yaml --dest docker keep-set.yaml <destination>
+
+    docker-registry:5000:
+      images:
+        domino/environment:
+        - 62798b9bee0eb12322fc97e8-3
+
+Scripts pin every image to the digest its tag pointed to when the plan was
+made, so a tag pushed again afterwards fails loudly instead of silently copying
+other content. skopeo sync copies tags as they are when it runs.
+"""
+
+import shlex
+from typing import TYPE_CHECKING, Dict, Iterable, List, Optional, TypedDict
+
+import yaml
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+FORMAT_SCRIPT = "script"
+FORMAT_SKOPEO_SYNC = "skopeo-sync"
+KEEP_SET_FORMATS = (FORMAT_SCRIPT, FORMAT_SKOPEO_SYNC)
+
+ACTION_PULL = "pull"
+ACTION_COPY = "copy"
+KEEP_SET_ACTIONS = (ACTION_PULL, ACTION_COPY)
+
+
+class KeepSetImage(TypedDict):
+    """An image kept by a cleanup plan."""
+
+    image_id: str
+    repository: str
+    tag: str
+    digest: str
+
+
+def keep_set(analyzer: "ImageAnalyzer", deleted_image_ids: Iterable[str]) -> List[KeepSetImage]:
+    """The analyzed images that are not deleted, by repository and tag"""
+    deleted = set(deleted_image_ids)
+    images: List[KeepSetImage] = [
+        {
+            "image_id": image_id,
+            "repository": image_data["repository"],
+            "tag": image_data["tag"],
+            "digest": image_data.get("digest", ""),
+        }
+        for image_id, image_data in analyzer.images.items()
+        if image_id not in deleted
+    ]
+    images.sort(key=lambda image: (image["repository"], image["tag"]))
+    return images
+
+
+def _reference(registry_url: str, image: KeepSetImage) -> str:
+    """Source reference of an image, pinned to its digest when known"""
+    if image["digest"]:
+        return f"{registry_url}/{image['repository']}@{image['digest']}"
+    return f"{registry_url}/{image['repository']}:{image['tag']}"
+
+
+def render_script(
+    images: List[KeepSetImage], registry_url: str, action: str = ACTION_PULL, destination: Optional[str] = None
+) -> str:
+    """Render the keep-set as a bash script with one pull or copy command per image.
+
+    Args:
+        images: Kept images (keep_set)
+        registry_url: Registry the images are in
+        action: ACTION_PULL (docker pull) or ACTION_COPY (skopeo copy to destination)
+        destination: Registry to copy to (ACTION_COPY only)
+
+    Returns:
+        Script text
+    """
+    if action not in KEEP_SET_ACTIONS:
+        raise ValueError(f"Unknown keep-set action {action!r} (expected: {', '.join(KEEP_SET_ACTIONS)})")
+    if action == ACTION_COPY and not destination:
+        raise ValueError("Copying the keep-set needs a destination registry")
+    lines = [
+        "#!/usr/bin/env bash",
+        f"# Keep-set of {registry_url}: {len(images)} image(s)",
+        "set -euo pipefail",
+        "",
+    ]
+    for image in images:
+        source = _reference(registry_url, image)
+        lines.append(f"# {image['image_id']}")
+        if action == ACTION_PULL:
+            lines.append(f"docker pull {shlex.quote(source)}")
+        else:
+            target = f"{destination}/{image['repository']}:{image['tag']}"
+            lines.append(f"skopeo copy --all {shlex.quote('docker://' + source)} {shlex.quote('docker://' + target)}")
+    return "\n".join(lines) + "\n"
+
+
+def render_skopeo_sync(images: List[KeepSetImage], registry_url: str) -> str:
+    """Render the keep-set as a skopeo sync YAML source file (skopeo sync --src yaml)"""
+    repositories: Dict[str, List[str]] = {}
+    for image in images:
+        repositories.setdefault(image["repository"], []).append(image["tag"])
+    return yaml.safe_dump({registry_url: {"images": repositories}}, default_flow_style=False, sort_keys=True)
+
+
+def render_keep_set(
+    images: List[KeepSetImage],
+    registry_url: str,
+    output_format: str = FORMAT_SCRIPT,
+    action: str = ACTION_PULL,
+    destination: Optional[str] = None,
+) -> str:
+    """Render the keep-set in one of KEEP_SET_FORMATS"""
+    if output_format == FORMAT_SKOPEO_SYNC:
+        return render_skopeo_sync(images, registry_url)
+    if output_format == FORMAT_SCRIPT:
+        return render_script(images, registry_url, action, destination)
+    raise ValueError(f"Unknown keep-set format {output_format!r} (expected: {', '.join(KEEP_SET_FORMATS)})")
@@ -0,0 +1,226 @@
+"""
+Layer content listing.
+
+Layer blobs are tar archives (usually gzip-compressed). A contents scan
+(image_data_analysis --contents) downloads selected layers, streams each one
+through tarfile without writing it to disk, and records its entries:
+
+    {"path": "/opt/conda/lib/libmkl.so", "size": 104857600, "type": "file", "sha256": "..."}
+
+From the entries, reports answer "which images contain file X" (shell-style
+patterns matched against the full path) and find files stored more than once
+across layers (same content digest in different layers).
+
+Downloading layers is expensive, so it is opt-in and bounded by
+analysis.contents in config.yaml: layers larger than max_layer_mb are skipped,
+at most max_layers layers (largest first) are read, a layer's listing stops
+after max_entries_per_layer entries, and only files up to hash_max_file_mb are
+hashed for duplicate detection. Foreign and encrypted layers are never read.
+"""
+
+import hashlib
+import tarfile
+from fnmatch import fnmatchcase
+from typing import IO, Dict, Iterable, List, Optional, Tuple, TypedDict
+
+# Whiteout files mark paths deleted by a layer (see the OCI image spec)
+WHITEOUT_PREFIX = ".wh."
+
+ENTRY_FILE = "file"
+ENTRY_DIR = "dir"
+ENTRY_SYMLINK = "symlink"
+ENTRY_HARDLINK = "hardlink"
+ENTRY_WHITEOUT = "whiteout"
+ENTRY_OTHER = "other"
+
+_HASH_CHUNK_BYTES = 1024 * 1024
+
+
+class LayerEntry(TypedDict):
+    """One entry of a layer's tar archive."""
+
+    path: str  # absolute path in the image filesystem
+    size: int
+    type: str  # ENTRY_* constant
+    sha256: Optional[str]  # content digest of regular files up to the hash limit, else None
+
+
+class LayerListing(TypedDict):
+    """The entries of one layer."""
+
+    entries: List[LayerEntry]
+    truncated: bool  # True if the listing stopped at max_entries_per_layer
+
+
+class FileMatch(TypedDict):
+    """A file matching a search pattern, and the images containing it."""
+
+    path: str
+    size: int
+    layer_id: str
+    image_ids: List[str]
+
+
+class DuplicateFileGroup(TypedDict):
+    """Identical file content stored in more than one layer."""
+
+    sha256: str
+    size: int
+    occurrences: List[Dict[str, str]]  # [{"layer_id", "path"}, ...]
+    wasted_bytes: int  # size of every copy but one
+
+
+def _entry_type(member: tarfile.TarInfo) -> str:
+    """Kind of a tar member, with whiteouts told apart from ordinary files"""
+    if member.name.rsplit("/", 1)[-1].startswith(WHITEOUT_PREFIX):
+        return ENTRY_WHITEOUT
+    if member.isreg():
+        return ENTRY_FILE
+    if member.isdir():
+        return ENTRY_DIR
+    if member.issym():
+        return ENTRY_SYMLINK
+    if member.islnk():
+        return ENTRY_HARDLINK
+    return ENTRY_OTHER
+
+
+def _normalize_path(name: str) -> str:
+    """Absolute path of a tar member name ("./usr/bin/" -> "/usr/bin")"""
+    path = name.lstrip(".").strip("/")
+    return f"/{path}"
+
+
+def list_layer_entries(stream: IO[bytes], max_entries: int, hash_max_bytes: int) -> LayerListing:
+    """List the entries of a layer tar stream, compressed or not.
+
+    The stream is read once, front to back; nothing is written to disk.
+
+    Args:
+        stream: The layer blob
+        max_entries: Stop after this many entries
+        hash_max_bytes: Hash regular files up to this size (0 = hash nothing)
+
+    Returns:
+        The layer's entries, in archive order
+
+    Raises:
+        tarfile.TarError: If the blob is not a (gzip, bzip2 or xz compressed) tar archive
+    """
+    entries: List[LayerEntry] = []
+    with tarfile.open(fileobj=stream, mode="r|*") as archive:
+        for member in archive:
+            if len(entries) >= max_entries:
+                return {"entries": entries, "truncated": True}
+            entry_type = _entry_type(member)
+            digest = None
+            if entry_type == ENTRY_FILE and member.size <= hash_max_bytes:
+                content = archive.extractfile(member)
+                if content is not None:
+                    sha256 = hashlib.sha256()
+                    for chunk in iter(lambda: content.read(_HASH_CHUNK_BYTES), b""):
+                        sha256.update(chunk)
+                    digest = sha256.hexdigest()
+            entries.append(
+                {"path": _normalize_path(member.name), "size": member.size, "type": entry_type, "sha256": digest}
+            )
+    return {"entries": entries, "truncated": False}
+
+
+def select_layers(
+    layer_sizes: Dict[str, int], excluded: Iterable[str], max_layers: int, max_layer_bytes: int
+) -> List[str]:
+    """Pick the layers a contents scan reads: the largest that fit the size limit.
+
+    Args:
+        layer_sizes: layer_id -> compressed size in bytes
+        excluded: Layers never read (foreign and encrypted layers)
+        max_layers: Most layers to read
+        max_layer_bytes: Largest layer to read
+
+    Returns:
+        Layer ids, largest first
+    """
+    excluded = set(excluded)
+    eligible = [
+        (size, layer_id)
+        for layer_id, size in layer_sizes.items()
+        if layer_id not in excluded and 0 < size <= max_layer_bytes
+    ]
+    eligible.sort(key=lambda item: (-item[0], item[1]))
+    return [layer_id for _, layer_id in eligible[:max_layers]]
+
+
+def find_files(
+    contents: Dict[str, LayerListing], layer_images: Dict[str, List[str]], patterns: List[str]
+) -> List[FileMatch]:
+    """Find the files whose path matches any of the shell-style patterns.
+
+    Patterns without a "/" match the file name anywhere (e.g. "id_rsa");
+    others match the full path (e.g. "/root/.aws/*").
+
+    Args:
+        contents: layer_id -> listing
+        layer_images: layer_id -> image_ids using the layer
+        patterns: Shell-style patterns
+
+    Returns:
+        Matching files with the images containing them, by path
+    """
+    matches: List[FileMatch] = []
+    for layer_id, listing in contents.items():
+        for entry in listing["entries"]:
+            if entry["type"] in (ENTRY_DIR, ENTRY_WHITEOUT):
+                continue
+            name = entry["path"].rsplit("/", 1)[-1]
+            if any(fnmatchcase(entry["path"] if "/" in pattern else name, pattern) for pattern in patterns):
+                matches.append(
+                    {
+                        "path": entry["path"],
+                        "size": entry["size"],
+                        "layer_id": layer_id,
+                        "image_ids": sorted(layer_images.get(layer_id, [])),
+                    }
+                )
+    matches.sort(key=lambda match: (match["path"], match["layer_id"]))
+    return matches
+
+
+def find_duplicate_files(contents: Dict[str, LayerListing], min_size: int = 1) -> List[DuplicateFileGroup]:
+    """Find file content stored in more than one layer.
+
+    Copies within a single layer (hard links, vendored duplicates) do not
+    count: only content that a second layer stores again wastes registry space.
+
+    Args:
+        contents: layer_id -> listing
+        min_size: Ignore files smaller than this many bytes
+
+    Returns:
+        Groups of identical files, most wasted bytes first
+    """
+    by_digest: Dict[str, List[Tuple[str, LayerEntry]]] = {}
+    for layer_id, listing in contents.items():
+        for entry in listing["entries"]:
+            if entry["sha256"] and entry["size"] >= min_size:
+                by_digest.setdefault(entry["sha256"], []).append((layer_id, entry))
+
+    groups: List[DuplicateFileGroup] = []
+    for sha256, occurrences in by_digest.items():
+        layers = {layer_id for layer_id, _ in occurrences}
+        if len(layers) < 2:
+            continue
+        size = occurrences[0][1]["size"]
+        groups.append(
+            {
+                "sha256": sha256,
+                "size": size,
+                "occurrences": sorted(
+                    ({"layer_id": layer_id, "path": entry["path"]} for layer_id, entry in occurrences),
+                    key=lambda occurrence: (occurrence["layer_id"], occurrence["path"]),
+                ),
+                "wasted_bytes": size * (len(layers) - 1),
+            }
+        )
+    groups.sort(key=lambda group: (-group["wasted_bytes"], group["sha256"]))
+    return groups
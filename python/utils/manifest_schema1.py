@@ -0,0 +1,117 @@
+"""
+Docker image manifest v2 schema 1 (legacy) support.
+
+Very old images were pushed with schema1 manifests, which differ from schema2
+and OCI manifests in ways that break the usual inspection:
+
+- layers are listed as fsLayers (blobSum only, no sizes), newest layer first
+- every history entry has a layer, so metadata-only steps (ENV, LABEL, ...)
+  appear as "throwaway" copies of the same empty tar blob
+- the manifest may be signed (JWS), and its digest is computed over the
+  payload without the signatures, not over the raw bytes
+
+This module detects schema1 manifests and normalizes them into the layer list
+the analyzer works with (base layer first, each blob once, empty layers
+dropped). Layer sizes are not in the manifest and are looked up separately.
+"""
+
+import base64
+import hashlib
+import json
+from typing import Any, Dict, List, Optional, TypedDict
+
+SCHEMA1_MEDIA_TYPES = (
+    "application/vnd.docker.distribution.manifest.v1+json",
+    "application/vnd.docker.distribution.manifest.v1+prettyjws",
+)
+
+# The gzipped empty tar that schema1 uses as the layer of metadata-only history entries
+EMPTY_LAYER_DIGEST = "sha256:a3ed95caeb02ffe68cdd9fd84406680ae93d633cb16422d00e8a7c22955b46d4"
+
+LEGACY_FORMAT_SCHEMA1 = "schema1"
+
+
+class Schema1Layer(TypedDict):
+    digest: str
+    size: Optional[int]  # uncompressed size from v1Compatibility, if recorded
+
+
+class Schema1Image(TypedDict):
+    layers: List[Schema1Layer]  # base layer first
+    created: Optional[str]
+
+
+def is_schema1(manifest: Dict[str, Any]) -> bool:
+    """Whether a parsed manifest is a schema1 manifest"""
+    return manifest.get("schemaVersion") == 1 or manifest.get("mediaType") in SCHEMA1_MEDIA_TYPES
+
+
+def is_schema1_inspection(image_info: Dict[str, Any]) -> bool:
+    """Whether a skopeo inspect result looks like a schema1 image.
+
+    skopeo cannot report layer sizes for schema1 images (LayersData sizes are
+    -1), and lists the empty layer of every metadata-only step.
+    """
+    layers_data = image_info.get("LayersData") or []
+    if any(layer.get("Size", 0) < 0 for layer in layers_data):
+        return True
+    return not layers_data and EMPTY_LAYER_DIGEST in (image_info.get("Layers") or [])
+
+
+def _decode_base64url(value: str) -> bytes:
+    return base64.urlsafe_b64decode(value + "=" * (-len(value) % 4))
+
+
+def manifest_digest(raw: str) -> str:
+    """Digest of a raw manifest as the registry computes it.
+
+    For signed schema1 manifests this is the digest of the signed payload
+    (the manifest without its "signatures"), rebuilt from the formatLength and
+    formatTail of the first signature's protected header. Any other manifest's
+    digest is the sha256 of its raw bytes.
+    """
+    data = raw.encode("utf-8")
+    try:
+        manifest = json.loads(raw)
+        signatures = manifest.get("signatures") if isinstance(manifest, dict) else None
+        if signatures:
+            protected = json.loads(_decode_base64url(signatures[0]["protected"]))
+            data = data[: protected["formatLength"]] + _decode_base64url(protected["formatTail"])
+    except (ValueError, KeyError, TypeError, IndexError):
+        pass
+    return "sha256:" + hashlib.sha256(data).hexdigest()
+
+
+def normalize_schema1(manifest: Dict[str, Any]) -> Schema1Image:
+    """Convert a schema1 manifest into base-first layers and a creation time.
+
+    Args:
+        manifest: Parsed schema1 manifest
+
+    Returns:
+        Schema1Image with each non-empty layer blob once, base layer first
+    """
+    fs_layers = manifest.get("fsLayers") or []
+    history = manifest.get("history") or []
+
+    compatibility: List[Dict[str, Any]] = []
+    for entry in history:
+        try:
+            compatibility.append(json.loads(entry.get("v1Compatibility") or "{}"))
+        except (ValueError, AttributeError):
+            compatibility.append({})
+
+    layers: List[Schema1Layer] = []
+    seen = set()
+    # fsLayers[i] belongs to history[i]; both are ordered newest first
+    for index in reversed(range(len(fs_layers))):
+        digest = fs_layers[index].get("blobSum")
+        v1 = compatibility[index] if index < len(compatibility) else {}
+        if not digest or digest == EMPTY_LAYER_DIGEST or v1.get("throwaway") or digest in seen:
+            continue
+        seen.add(digest)
+        size = v1.get("Size")
+        layers.append({"digest": digest, "size": size if isinstance(size, int) and size >= 0 else None})
+
+    created = compatibility[0].get("created") if compatibility else None
+    return {"layers": layers, "created": created}
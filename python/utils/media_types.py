@@ -0,0 +1,68 @@
+"""
+Layer media types.
+
+Every layer in a manifest declares a media type, which tells how its blob is
+stored: a gzip- or zstd-compressed tarball, an uncompressed tarball, a foreign
+layer stored outside the registry, or a non-filesystem blob such as an in-toto
+provenance or SBOM attestation. Image analysis records the media type of each
+layer so that reports can break registry usage down by media type, e.g. to see
+how much storage zstd recompression would affect or how much space attestation
+blobs take.
+
+Encrypted layers (OCI crypt, media types ending in "+encrypted") can only be
+read with the decryption key. Their size and digest are known like those of any
+other layer, but operations that need their content, such as matching layers by
+uncompressed content, leave them out.
+
+Media types are grouped into categories:
+
+    gzip          application/vnd.oci.image.layer.v1.tar+gzip, Docker rootfs diffs
+    zstd          application/vnd.oci.image.layer.v1.tar+zstd
+    uncompressed  application/vnd.oci.image.layer.v1.tar
+    foreign       foreign and non-distributable layers (see foreign_layers)
+    encrypted     layers encrypted with OCI crypt (...+encrypted)
+    provenance    in-toto attestations, SBOMs and signatures
+    other         any other media type
+    unknown       layers whose media type was not recorded (e.g. schema1 images)
+"""
+
+from typing import Optional
+
+from utils.foreign_layers import is_foreign_media_type
+
+CATEGORY_GZIP = "gzip"
+CATEGORY_ZSTD = "zstd"
+CATEGORY_UNCOMPRESSED = "uncompressed"
+CATEGORY_FOREIGN = "foreign"
+CATEGORY_ENCRYPTED = "encrypted"
+CATEGORY_PROVENANCE = "provenance"
+CATEGORY_OTHER = "other"
+CATEGORY_UNKNOWN = "unknown"
+
+# Substrings of attestation, SBOM and signature media types
+_PROVENANCE_MARKERS = ("in-toto", "attestation", "spdx", "cyclonedx", "dev.cosign", "dsse")
+
+
+def is_encrypted_media_type(media_type: Optional[str]) -> bool:
+    """Whether a layer media type marks an OCI crypt encrypted layer"""
+    return bool(media_type) and "+encrypted" in media_type.lower()
+
+
+def media_type_category(media_type: Optional[str]) -> str:
+    """Category of a layer media type (one of the CATEGORY_* constants)"""
+    if not media_type:
+        return CATEGORY_UNKNOWN
+    if is_foreign_media_type(media_type):
+        return CATEGORY_FOREIGN
+    if is_encrypted_media_type(media_type):
+        return CATEGORY_ENCRYPTED
+    lowered = media_type.lower()
+    if any(marker in lowered for marker in _PROVENANCE_MARKERS):
+        return CATEGORY_PROVENANCE
+    if lowered.endswith(("+zstd", ".zstd")):
+        return CATEGORY_ZSTD
+    if lowered.endswith(("+gzip", ".gzip")):
+        return CATEGORY_GZIP
+    if lowered.endswith(".tar"):
+        return CATEGORY_UNCOMPRESSED
+    return CATEGORY_OTHER
@@ -0,0 +1,173 @@
+"""
+Mirroring the keep-set to a secondary registry.
+
+mirror copies every image a cleanup plan keeps (see keep_set.py) to a
+secondary registry, so the secondary holds what the primary will still hold
+once the plan is applied, and nothing the plan deletes. Run on a schedule
+(schedule.mirror in config.yaml), it keeps a disaster-recovery or migration
+target in step with retention.
+
+Two methods are supported:
+
+    copy         one skopeo copy per image, by the digest the tag points to, skipping
+                 images the destination already has at that digest and verifying each copy
+    skopeo-sync  a single skopeo sync of a generated YAML source file, copying tags as
+                 they are when it runs; nothing is skipped or verified
+
+A destination on the source registry's host, with a repository prefix
+(e.g. "registry:5000/migrated"), is copied to with the copy method by mounting
+blobs instead of uploading them again (see blob_mount.py).
+
+The destination is only ever added to: images removed from the keep-set are
+not deleted from it.
+"""
+
+import concurrent.futures
+import os
+import tempfile
+from threading import Lock
+from typing import Any, List, Optional, TypedDict
+
+from utils.blob_mount import BlobCopyStats, add_copy_stats, empty_copy_stats, same_registry_prefix
+from utils.keep_set import KeepSetImage, render_skopeo_sync
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+METHOD_COPY = "copy"
+METHOD_SKOPEO_SYNC = "skopeo-sync"
+MIRROR_METHODS = (METHOD_COPY, METHOD_SKOPEO_SYNC)
+
+
+class MirrorFailure(TypedDict):
+    """An image that could not be mirrored."""
+
+    image_id: str
+    reason: str
+
+
+class MirrorResult(TypedDict):
+    """Outcome of mirroring a keep-set."""
+
+    copied: List[str]  # image_ids copied (or, in a dry run, that would be)
+    up_to_date: List[str]  # image_ids the destination already had at the same digest
+    failed: List[MirrorFailure]
+    copy_blobs: BlobCopyStats  # Blobs mounted and transferred, for a destination on the source registry's host
+
+
+def _mirror_one(
+    image: KeepSetImage,
+    source_client: Any,
+    destination_client: Any,
+    dry_run: bool,
+    prefix: Optional[str] = None,
+    copy_stats: Optional[BlobCopyStats] = None,
+    stats_lock: Optional[Lock] = None,
+) -> str:
+    """Mirror one image, returning "copied" or "up_to_date".
+
+    With prefix (a destination on the source registry's host), the image is
+    copied to <prefix>/<repository> by mounting its blobs, adding them to copy_stats.
+
+    Raises:
+        RuntimeError: If the image cannot be copied or the copy cannot be verified
+    """
+    if not image["digest"]:
+        raise RuntimeError("digest unknown; rescan to mirror it by digest")
+    if destination_client.get_manifest_digest(image["repository"], image["tag"]) == image["digest"]:
+        return "up_to_date"
+    if dry_run:
+        return "copied"
+    destination = destination_client.registry_url
+    if prefix is not None:
+        destination_repository = f"{prefix}/{image['repository']}"
+        stats = source_client.copy_image_within_registry(
+            image["repository"], image["digest"], destination_repository, image["tag"]
+        )
+        if stats is None:
+            raise RuntimeError(f"copy to {destination} failed")
+        if copy_stats is not None and stats_lock is not None:
+            with stats_lock:
+                add_copy_stats(copy_stats, stats)
+    elif not source_client.copy_image(image["repository"], image["digest"], destination, image["tag"]):
+        raise RuntimeError(f"copy to {destination} failed")
+    mirrored_digest = destination_client.get_manifest_digest(image["repository"], image["tag"])
+    if mirrored_digest != image["digest"]:
+        raise RuntimeError(f"copy could not be verified (source: {image['digest']}, mirror: {mirrored_digest})")
+    return "copied"
+
+
+def mirror_images(
+    images: List[KeepSetImage], source_client: Any, destination_client: Any, max_workers: int, dry_run: bool = False
+) -> MirrorResult:
+    """Copy images to the destination registry one at a time (METHOD_COPY).
+
+    Args:
+        images: Kept images (keep_set)
+        source_client: SkopeoClient of the registry the images are in
+        destination_client: SkopeoClient of the destination registry
+        max_workers: Parallel copies
+        dry_run: Only check which images the destination is missing
+
+    Returns:
+        The images copied, already up to date and failed, each sorted
+    """
+    result: MirrorResult = {"copied": [], "up_to_date": [], "failed": [], "copy_blobs": empty_copy_stats()}
+    prefix = same_registry_prefix(source_client.registry_url, destination_client.registry_url)
+    stats_lock = Lock()
+    with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+        future_to_image = {
+            executor.submit(
+                _mirror_one,
+                image,
+                source_client,
+                destination_client,
+                dry_run,
+                prefix,
+                result["copy_blobs"],
+                stats_lock,
+            ): image
+            for image in images
+        }
+        for future in concurrent.futures.as_completed(future_to_image):
+            image = future_to_image[future]
+            try:
+                result[future.result()].append(image["image_id"])  # type: ignore[literal-required]
+            except Exception as e:
+                logger.error(f"Could not mirror {image['repository']}:{image['tag']}: {e}")
+                result["failed"].append({"image_id": image["image_id"], "reason": str(e)})
+    result["copied"].sort()
+    result["up_to_date"].sort()
+    result["failed"].sort(key=lambda failure: failure["image_id"])
+    return result
+
+
+def sync_images(
+    images: List[KeepSetImage], source_client: Any, destination: str, dry_run: bool = False
+) -> MirrorResult:
+    """Copy images to the destination registry with one skopeo sync (METHOD_SKOPEO_SYNC).
+
+    Args:
+        images: Kept images (keep_set)
+        source_client: SkopeoClient of the registry the images are in
+        destination: Destination registry
+        dry_run: Only report the images that would be synced
+
+    Returns:
+        Every image as copied if the sync succeeded, or every image as failed
+    """
+    image_ids = sorted(image["image_id"] for image in images)
+    if dry_run or not images:
+        return {"copied": image_ids, "up_to_date": [], "failed": [], "copy_blobs": empty_copy_stats()}
+    fd, source_file = tempfile.mkstemp(prefix="mirror-", suffix=".yaml")
+    try:
+        with os.fdopen(fd, "w") as f:
+            f.write(render_skopeo_sync(images, source_client.registry_url))
+        synced = source_client.sync_images(source_file, destination)
+    finally:
+        os.remove(source_file)
+    if synced:
+        return {"copied": image_ids, "up_to_date": [], "failed": [], "copy_blobs": empty_copy_stats()}
+    reason = f"skopeo sync to {destination} failed"
+    failed: List[MirrorFailure] = [{"image_id": image_id, "reason": reason} for image_id in image_ids]
+    return {"copied": [], "up_to_date": [], "failed": failed, "copy_blobs": empty_copy_stats()}
@@ -9,12 +9,17 @@ from bson import json_util
 from pymongo import MongoClient
 
 from utils.config_manager import config_manager
+from utils.read_only import read_only_mongo_client
 
 
 def get_mongo_client() -> MongoClient:
-    """Return a MongoClient using the centralized connection string."""
+    """Return a MongoClient using the centralized connection string.
+
+    In read-only mode the client refuses writes (see utils.read_only).
+    """
     connection_string = config_manager.get_mongo_connection_string()
-    return MongoClient(connection_string)
+    client = MongoClient(connection_string)
+    return read_only_mongo_client(client) if config_manager.is_read_only() else client
 
 
 def get_db(client: Optional[MongoClient] = None):
@@ -0,0 +1,131 @@
+"""
+Mutable tag risk.
+
+Age-based retention judges an image by when it was created, through the tag
+that names it. Floating tags such as latest, stable or prod are moved to a new
+image on every release, so a rule that keeps or deletes images through them
+acts on whatever the tag points to at the time, not on a fixed image.
+
+A tag is reported as risky when:
+
+- digest_changed: it pointed to different digests in successive scan snapshots
+- floating_name: its name matches a floating tag pattern (analysis.floating_tags)
+- alias: it points to the same image as other tags of its repository and does
+  not follow the tag naming convention (analysis.tag_naming_pattern); aliases
+  between conventionally named tags are duplicate pushes, which
+  duplicate_images_report covers
+
+Retention policy rules whose tag patterns select risky tags are flagged, those
+with age conditions first, since their decisions are the least reliable.
+"""
+
+from datetime import datetime, timezone
+from fnmatch import fnmatchcase
+from typing import Any, Dict, Iterable, List, Pattern, Tuple, TypedDict
+
+from utils.retention_policy import RetentionPolicy, rule_matches
+
+DIGEST_CHANGED = "digest_changed"
+FLOATING_NAME = "floating_name"
+ALIAS = "alias"
+
+
+class MutableTag(TypedDict):
+    """A tag whose image is not fixed."""
+
+    image_id: str
+    repository: str
+    tag: str
+    reasons: List[str]
+    digests: List[str]  # Digests across snapshots, oldest first, consecutive repeats removed
+    aliases: List[str]  # Other tags of the repository pointing to the same image
+
+
+class DependentRule(TypedDict):
+    """A retention policy rule whose tag patterns select mutable tags."""
+
+    rule: str
+    action: str
+    age_based: bool
+    tags: List[str]  # "<repository>:<tag>" of the mutable tags it selects
+
+
+def find_mutable_tags(
+    history: Dict[str, Dict[str, List[str]]],
+    latest_images: Dict[str, Dict[str, Any]],
+    floating_patterns: Iterable[str],
+    naming_pattern: Pattern[str],
+) -> List[MutableTag]:
+    """Find the current tags whose image is not fixed.
+
+    Args:
+        history: Repository -> tag -> digests across snapshots (see tag_immutability.tag_digest_history)
+        latest_images: Images of the newest snapshot (image_id -> image)
+        floating_patterns: Shell-style patterns of floating tag names
+        naming_pattern: Tag naming convention, matched in full
+
+    Returns:
+        Risky tags, sorted by repository and tag
+    """
+    patterns = list(floating_patterns)
+    tags_by_digest: Dict[Tuple[str, str], List[str]] = {}
+    for image in latest_images.values():
+        if image.get("digest"):
+            tags_by_digest.setdefault((image["repository"], image["digest"]), []).append(image["tag"])
+
+    mutable: List[MutableTag] = []
+    for image_id, image in latest_images.items():
+        repository, tag = image["repository"], image["tag"]
+        digests = history.get(repository, {}).get(tag, [])
+        aliases = sorted(
+            other for other in tags_by_digest.get((repository, image.get("digest")), []) if other != tag
+        )
+        reasons = []
+        if len(digests) > 1:
+            reasons.append(DIGEST_CHANGED)
+        if any(fnmatchcase(tag, pattern) for pattern in patterns):
+            reasons.append(FLOATING_NAME)
+        if aliases and not naming_pattern.fullmatch(tag):
+            reasons.append(ALIAS)
+        if reasons:
+            mutable.append(
+                {
+                    "image_id": image_id,
+                    "repository": repository,
+                    "tag": tag,
+                    "reasons": reasons,
+                    "digests": digests,
+                    "aliases": aliases,
+                }
+            )
+    return sorted(mutable, key=lambda entry: (entry["repository"], entry["tag"]))
+
+
+def rules_depending_on(policy: RetentionPolicy, mutable_tags: List[MutableTag]) -> List[DependentRule]:
+    """Find the policy rules whose tag patterns select mutable tags.
+
+    Rules without tag patterns apply to every tag alike and are not reported.
+
+    Args:
+        policy: Retention policy
+        mutable_tags: Risky tags (see find_mutable_tags)
+
+    Returns:
+        Dependent rules, age-based rules first, in policy order otherwise
+    """
+    now = datetime.now(timezone.utc)
+    dependent: List[DependentRule] = []
+    for rule in policy.rules:
+        if not rule.tags:
+            continue
+        # Without age, usage or annotation data, a rule only fails on its repository and tag patterns
+        selected = [
+            f"{entry['repository']}:{entry['tag']}"
+            for entry in mutable_tags
+            if rule_matches(rule, entry["image_id"], entry["repository"], entry["tag"], None, None, False, now)
+            is not False
+        ]
+        if selected:
+            age_based = rule.older_than_days is not None or rule.newer_than_days is not None
+            dependent.append({"rule": rule.name, "action": rule.action, "age_based": age_based, "tags": selected})
+    return sorted(dependent, key=lambda rule: not rule["age_based"])
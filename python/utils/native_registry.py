@@ -0,0 +1,193 @@
+"""
+Registry requests without skopeo.
+
+By default every tag listing, image inspection, manifest read and deletion
+runs a skopeo command, which costs a process per request and needs skopeo
+installed on every host. With skopeo.client: native (or REGISTRY_CLIENT=native),
+SkopeoClient makes those requests over HTTP with the Docker Registry v2 / OCI
+Distribution API instead (see utils/registry_http.py): no process is started
+per request, Bearer tokens are reused across requests, and scans and
+deletions run on hosts without skopeo. ECR and ACR logins write the auth file
+directly rather than with skopeo login.
+
+The functions here return what the corresponding skopeo commands print, so
+the rest of the cleaner reads their results the same way:
+
+    inspect_image     skopeo inspect (Digest, Created, Labels, Env, Layers, LayersData, ...)
+    get_manifest      skopeo inspect --raw, with the manifest digest
+    get_image_config  skopeo inspect --config
+
+As with skopeo, a multi-arch image is inspected through the linux/amd64 entry
+of its index (or its first image, without one) but reports the digest of the
+index, and schema1 images report layer sizes of -1. Copies to another
+registry (replicate_to, mirror) still run skopeo.
+"""
+
+import json
+from typing import Any, Dict, List, Optional, Tuple
+
+from utils.manifest_schema1 import is_schema1, manifest_digest
+from utils.registry_http import RegistryHttpClient
+
+# Largest image config read; configs are a few kilobytes, even with a long build history
+CONFIG_MAX_BYTES = 16 * 1024 * 1024
+
+# Platform of an index inspected, as skopeo picks on a linux/amd64 host
+DEFAULT_PLATFORM = ("linux", "amd64")
+
+SCHEMA1_LAYER_MEDIA_TYPE = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+
+
+class ManifestNotFoundError(LookupError):
+    """The tag or digest does not exist in the repository."""
+
+
+def get_manifest(client: RegistryHttpClient, repository: str, reference: str) -> Tuple[str, Dict[str, Any]]:
+    """Fetch the raw manifest of a tag or "sha256:..." digest.
+
+    Returns:
+        (digest, manifest), the digest computed as the registry does (see manifest_schema1.manifest_digest)
+
+    Raises:
+        ManifestNotFoundError: If the manifest does not exist
+        ValueError: If the manifest is not a JSON object
+    """
+    result = client.get_manifest(repository, reference)
+    if result is None:
+        raise ManifestNotFoundError(f"Manifest {repository}:{reference} not found")
+    raw = result[0].decode("utf-8")
+    manifest = json.loads(raw)
+    if not isinstance(manifest, dict):
+        raise ValueError(f"Manifest of {repository}:{reference} is not a JSON object")
+    return manifest_digest(raw), manifest
+
+
+def select_platform(index: Dict[str, Any]) -> Optional[str]:
+    """Digest of the linux/amd64 image of an index, or of its first image; None if it lists none"""
+    entries = [entry for entry in index.get("manifests") or [] if isinstance(entry, dict) and entry.get("digest")]
+    # Attestation manifests of buildx images are listed with platform unknown/unknown
+    images = [entry for entry in entries if (entry.get("platform") or {}).get("os") != "unknown"]
+    for entry in images:
+        platform = entry.get("platform") or {}
+        if (platform.get("os"), platform.get("architecture")) == DEFAULT_PLATFORM:
+            return entry["digest"]
+    return images[0]["digest"] if images else None
+
+
+def _image_manifest(client: RegistryHttpClient, repository: str, reference: str) -> Tuple[str, Dict[str, Any]]:
+    """The digest of a tag's manifest, and its image manifest (the selected platform's, for an index)"""
+    digest, manifest = get_manifest(client, repository, reference)
+    if "manifests" in manifest:
+        platform_digest = select_platform(manifest)
+        if platform_digest is None:
+            raise ValueError(f"Index {repository}:{reference} lists no images")
+        _, manifest = get_manifest(client, repository, platform_digest)
+    return digest, manifest
+
+
+def _schema1_config(manifest: Dict[str, Any]) -> Dict[str, Any]:
+    """The image config recorded in the newest history entry of a schema1 manifest"""
+    history = manifest.get("history") or []
+    try:
+        config = json.loads(history[0].get("v1Compatibility") or "{}") if history else {}
+    except (ValueError, AttributeError):
+        config = {}
+    return config if isinstance(config, dict) else {}
+
+
+def _read_config(client: RegistryHttpClient, repository: str, manifest: Dict[str, Any]) -> Dict[str, Any]:
+    """The image config a schema2 or OCI manifest refers to; empty for artifacts without a JSON config"""
+    config_digest = (manifest.get("config") or {}).get("digest")
+    if not config_digest:
+        return {}
+    content = client.get_blob(repository, config_digest, CONFIG_MAX_BYTES)
+    if content is None:
+        raise ManifestNotFoundError(f"Config blob {config_digest} of {repository} not found")
+    try:
+        config = json.loads(content.decode("utf-8"))
+    except (ValueError, UnicodeDecodeError):
+        return {}
+    return config if isinstance(config, dict) else {}
+
+
+def get_image_config(client: RegistryHttpClient, repository: str, reference: str) -> Dict[str, Any]:
+    """The image config of a tag, as skopeo inspect --config prints it (including rootfs.diff_ids)
+
+    Raises:
+        ManifestNotFoundError: If the tag or its config does not exist
+    """
+    _, manifest = _image_manifest(client, repository, reference)
+    if is_schema1(manifest):
+        return _schema1_config(manifest)
+    return _read_config(client, repository, manifest)
+
+
+def inspect_image(client: RegistryHttpClient, repository: str, reference: str) -> Dict[str, Any]:
+    """Inspect a tag, returning what skopeo inspect prints for it.
+
+    Raises:
+        ManifestNotFoundError: If the tag or its config does not exist
+    """
+    digest, manifest = _image_manifest(client, repository, reference)
+    if is_schema1(manifest):
+        config = _schema1_config(manifest)
+        # fsLayers are listed newest first
+        blobs = [layer.get("blobSum") for layer in reversed(manifest.get("fsLayers") or [])]
+        layers: List[Dict[str, Any]] = [
+            {"MIMEType": SCHEMA1_LAYER_MEDIA_TYPE, "Digest": blob, "Size": -1, "Annotations": None}
+            for blob in blobs
+            if blob
+        ]
+    else:
+        config = _read_config(client, repository, manifest)
+        layers = [
+            {
+                "MIMEType": layer.get("mediaType", ""),
+                "Digest": layer["digest"],
+                "Size": layer.get("size", 0),
+                "Annotations": layer.get("annotations"),
+            }
+            for layer in manifest.get("layers") or []
+            if isinstance(layer, dict) and layer.get("digest")
+        ]
+    image_config = config.get("config") or {}
+    return {
+        "Name": f"{client.host}/{repository}",
+        "Digest": digest,
+        "Created": config.get("created"),
+        "DockerVersion": config.get("docker_version", ""),
+        "Labels": image_config.get("Labels"),
+        "Architecture": config.get("architecture", ""),
+        "Os": config.get("os", ""),
+        "Layers": [layer["Digest"] for layer in layers],
+        "LayersData": layers,
+        "Env": image_config.get("Env"),
+    }
+
+
+def delete_manifest(client: RegistryHttpClient, repository: str, digest: str) -> bool:
+    """Delete a manifest by digest, removing every tag that points to it.
+
+    Returns:
+        True
+
+    Raises:
+        ManifestNotFoundError: If the manifest does not exist (e.g. it was already deleted)
+    """
+    if not client.delete_manifest(repository, digest):
+        raise ManifestNotFoundError(f"Manifest {repository}@{digest} not found")
+    return True
+
+
+def delete_tag(client: RegistryHttpClient, repository: str, tag: str) -> bool:
+    """Delete the manifest a tag points to, as skopeo delete does for a tag.
+
+    Returns:
+        True
+
+    Raises:
+        ManifestNotFoundError: If the tag does not exist
+    """
+    # Registries that send no Docker-Content-Digest header get the digest computed from the manifest
+    digest = client.head_manifest_digest(repository, tag) or get_manifest(client, repository, tag)[0]
+    return delete_manifest(client, repository, digest)
@@ -97,6 +97,48 @@ def read_typed_object_ids_from_file(file_path: str) -> Dict[str, List[str]]:
         return {}
 
 
+def read_image_object_id_filters(file_path: str) -> Dict[str, List[str]]:
+    """Read typed ObjectIDs from file and group them by image type.
+
+    Environment and environment revision IDs filter the 'environment' image;
+    model and model version IDs filter the 'model' image.
+
+    Returns a dict like { 'environment': [...], 'model': [...] } with sorted IDs.
+    """
+    object_ids_map = read_typed_object_ids_from_file(file_path)
+    env_ids = set(object_ids_map.get("environment", []))
+    env_ids.update(object_ids_map.get("environment_revision", []))
+    model_ids = set(object_ids_map.get("model", []))
+    model_ids.update(object_ids_map.get("model_version", []))
+    return {
+        "environment": sorted(env_ids),
+        "model": sorted(model_ids),
+    }
+
+
+def read_image_references_from_file(file_path: str) -> List[str]:
+    """Read image references from a file.
+
+    Each non-empty line that does not start with '#' holds one image reference,
+    either "<type>:<tag>" or a bare tag. Anything after the first whitespace is
+    ignored, so the first column of existing reports can be used directly.
+
+    Returns the references in file order, without duplicates.
+    """
+    references: List[str] = []
+    seen = set()
+    with open(file_path, "r") as f:
+        for raw in f:
+            line = raw.strip()
+            if not line or line.startswith("#"):
+                continue
+            reference = line.split()[0]
+            if reference not in seen:
+                seen.add(reference)
+                references.append(reference)
+    return references
+
+
 def filter_values_by_object_ids(values: List[str], object_ids: List[str]) -> List[str]:
     """Return values that start with any of the provided ObjectIDs."""
     if not object_ids:
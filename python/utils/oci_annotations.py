@@ -0,0 +1,75 @@
+"""
+OCI manifest and index annotations.
+
+OCI image manifests and image indexes can carry an "annotations" map of
+string keys and values. The pre-defined keys record where an image came from,
+for example:
+
+    org.opencontainers.image.created   2024-05-01T12:00:00Z
+    org.opencontainers.image.source    https://github.com/example/app
+    org.opencontainers.image.revision  4f1c2a9
+
+Image analysis reads the annotations of each tag's manifest (or index, for
+multi-arch images) so that reports can show them and policy rules and filters
+can match on them. Filters are written as key=pattern, with the shell-style
+wildcards used by tag patterns; the short names created, source and revision
+stand for the org.opencontainers.image.* keys.
+
+Docker schema2 and schema1 manifests have no annotations, so images using them
+have an empty annotation map. Images whose annotations were not collected have
+no map at all: filters on them cannot be evaluated and match nothing.
+"""
+
+from fnmatch import fnmatchcase
+from typing import Any, Dict, List, Optional
+
+ANNOTATION_CREATED = "org.opencontainers.image.created"
+ANNOTATION_SOURCE = "org.opencontainers.image.source"
+ANNOTATION_REVISION = "org.opencontainers.image.revision"
+
+ANNOTATION_ALIASES = {
+    "created": ANNOTATION_CREATED,
+    "source": ANNOTATION_SOURCE,
+    "revision": ANNOTATION_REVISION,
+}
+
+
+def resolve_annotation_key(key: str) -> str:
+    """Full annotation key for a short name (e.g. "source"), or the key unchanged"""
+    return ANNOTATION_ALIASES.get(key, key)
+
+
+def manifest_annotations(manifest: Dict[str, Any]) -> Dict[str, str]:
+    """Top-level annotations of a parsed manifest or index ({} if it has none)"""
+    annotations = manifest.get("annotations") if isinstance(manifest, dict) else None
+    if not isinstance(annotations, dict):
+        return {}
+    return {str(key): str(value) for key, value in annotations.items()}
+
+
+def parse_annotation_filters(values: List[str]) -> Dict[str, str]:
+    """Parse key=pattern filters into {full key: pattern}.
+
+    Raises:
+        ValueError: If a filter is not of the form key=pattern
+    """
+    filters: Dict[str, str] = {}
+    for value in values:
+        key, sep, pattern = value.partition("=")
+        if not sep or not key.strip() or not pattern:
+            raise ValueError(f"Annotation filter must be KEY=PATTERN, got: {value!r}")
+        filters[resolve_annotation_key(key.strip())] = pattern
+    return filters
+
+
+def annotations_match(annotations: Optional[Dict[str, str]], filters: Dict[str, str]) -> Optional[bool]:
+    """Whether annotations satisfy every key=pattern filter.
+
+    Returns:
+        True or False, or None if the image's annotations are unknown
+    """
+    if not filters:
+        return True
+    if annotations is None:
+        return None
+    return all(key in annotations and fnmatchcase(annotations[key], pattern) for key, pattern in filters.items())
@@ -0,0 +1,176 @@
+"""
+Operating system releases of images, and their end of life.
+
+Images keep running on the distribution release they were built on long after
+it stops getting security updates. A deep scan (image_data_analysis --deep)
+infers each image's release, first match wins:
+
+1. Labels: org.opencontainers.image.ref.name and org.opencontainers.image.version,
+   as set by the official Ubuntu images ("ubuntu", "22.04")
+2. Base image: the org.opencontainers.image.base.name annotation or label
+   ("docker.io/library/debian:bullseye-slim")
+3. Layers: /etc/os-release (or /usr/lib/os-release) in the image's bottom layer,
+   read once per distinct base layer; layers larger than
+   analysis.contents.max_layer_mb, foreign and encrypted layers are not read
+
+and compares it with the small end-of-life table below (end of standard
+security support; Debian dates include LTS). Releases missing from the table
+are reported without an end-of-life date.
+"""
+
+import re
+import tarfile
+from datetime import date
+from typing import IO, Dict, Optional, Tuple, TypedDict
+
+from utils.toolchain import ANNOTATION_BASE_NAME
+
+LABEL_REF_NAME = "org.opencontainers.image.ref.name"
+LABEL_VERSION = "org.opencontainers.image.version"
+
+SOURCE_LABELS = "labels"
+SOURCE_BASE_IMAGE = "base_image"
+SOURCE_LAYER = "os-release"
+
+# distro -> release -> end of security support (ISO date)
+OS_EOL_DATES: Dict[str, Dict[str, str]] = {
+    "ubuntu": {
+        "14.04": "2019-04-30",
+        "16.04": "2021-04-30",
+        "18.04": "2023-05-31",
+        "20.04": "2025-05-31",
+        "22.04": "2027-06-01",
+        "24.04": "2029-05-31",
+    },
+    "debian": {
+        "8": "2020-06-30",
+        "9": "2022-06-30",
+        "10": "2024-06-30",
+        "11": "2026-08-31",
+        "12": "2028-06-30",
+    },
+    "centos": {
+        "6": "2020-11-30",
+        "7": "2024-06-30",
+        "8": "2021-12-31",
+    },
+}
+
+CODENAMES: Dict[str, Tuple[str, str]] = {
+    "trusty": ("ubuntu", "14.04"),
+    "xenial": ("ubuntu", "16.04"),
+    "bionic": ("ubuntu", "18.04"),
+    "focal": ("ubuntu", "20.04"),
+    "jammy": ("ubuntu", "22.04"),
+    "noble": ("ubuntu", "24.04"),
+    "jessie": ("debian", "8"),
+    "stretch": ("debian", "9"),
+    "buster": ("debian", "10"),
+    "bullseye": ("debian", "11"),
+    "bookworm": ("debian", "12"),
+}
+
+# os-release paths in a layer, as tar member names without the leading "./" or "/"
+_OS_RELEASE_PATHS = ("etc/os-release", "usr/lib/os-release")
+_MAX_OS_RELEASE_BYTES = 64 * 1024
+
+
+class OsRelease(TypedDict):
+    """The distribution release an image runs on."""
+
+    distro: str  # "ubuntu", "debian", "centos", ...
+    version: str  # release as in OS_EOL_DATES ("22.04", "11", "7")
+    source: str  # SOURCE_* constant: where the release was read
+
+
+def normalize_release(distro: str, version: str) -> Optional[Tuple[str, str]]:
+    """Normalize a distro and release ("Ubuntu", "18.04.6" -> "ubuntu", "18.04"; "debian", "stretch" -> "9")"""
+    distro = distro.strip().lower()
+    version = version.strip().lower()
+    if not distro or not version:
+        return None
+    for word in re.split(r"[-_.\s]", version):
+        if word in CODENAMES and CODENAMES[word][0] == distro:
+            return CODENAMES[word]
+    match = re.match(r"(\d+)(?:\.(\d+))?", version)
+    if not match:
+        return None
+    major, minor = match.group(1), match.group(2)
+    if distro == "ubuntu":
+        return (distro, f"{major}.{minor}") if minor else None
+    return distro, major
+
+
+def release_from_labels(labels: Optional[Dict[str, str]]) -> Optional[Tuple[str, str]]:
+    """The release named by OCI ref.name and version labels, as the official Ubuntu images set them"""
+    labels = labels or {}
+    if labels.get(LABEL_REF_NAME) and labels.get(LABEL_VERSION):
+        return normalize_release(labels[LABEL_REF_NAME], labels[LABEL_VERSION])
+    return None
+
+
+def release_from_base_image(reference: Optional[str]) -> Optional[Tuple[str, str]]:
+    """The release of a distribution base image ("docker.io/library/ubuntu:20.04", "debian:bullseye-slim")"""
+    if not reference or ":" not in reference.rsplit("/", 1)[-1]:
+        return None
+    name, tag = reference.rsplit("/", 1)[-1].split(":", 1)
+    tag = tag.split("@", 1)[0]
+    if name.lower() not in OS_EOL_DATES:
+        return None
+    return normalize_release(name, tag)
+
+
+def parse_os_release(text: str) -> Optional[Tuple[str, str]]:
+    """The release described by an os-release file (ID and VERSION_ID, or VERSION_CODENAME)"""
+    fields: Dict[str, str] = {}
+    for line in text.splitlines():
+        key, separator, value = line.partition("=")
+        if separator:
+            fields[key.strip()] = value.strip().strip("\"'")
+    distro = fields.get("ID", "")
+    return normalize_release(distro, fields.get("VERSION_ID") or fields.get("VERSION_CODENAME") or "")
+
+
+def read_os_release(stream: IO[bytes]) -> Optional[str]:
+    """Read the os-release file of a layer tar stream, or None if the layer has none.
+
+    Raises:
+        tarfile.TarError: If the blob is not a (compressed) tar archive
+    """
+    found: Dict[str, str] = {}
+    with tarfile.open(fileobj=stream, mode="r|*") as archive:
+        for member in archive:
+            path = member.name.lstrip(".").strip("/")
+            if path not in _OS_RELEASE_PATHS or not member.isreg() or member.size > _MAX_OS_RELEASE_BYTES:
+                continue
+            content = archive.extractfile(member)
+            if content is not None:
+                found[path] = content.read().decode("utf-8", errors="replace")
+            if _OS_RELEASE_PATHS[0] in found:
+                break
+    return next((found[path] for path in _OS_RELEASE_PATHS if path in found), None)
+
+
+def infer_release(
+    labels: Optional[Dict[str, str]], annotations: Optional[Dict[str, str]]
+) -> Optional[OsRelease]:
+    """Infer an image's release from its labels or base image, without reading layers"""
+    release = release_from_labels(labels)
+    if release:
+        return {"distro": release[0], "version": release[1], "source": SOURCE_LABELS}
+    for source in (annotations, labels):
+        release = release_from_base_image((source or {}).get(ANNOTATION_BASE_NAME))
+        if release:
+            return {"distro": release[0], "version": release[1], "source": SOURCE_BASE_IMAGE}
+    return None
+
+
+def eol_date(release: OsRelease) -> Optional[str]:
+    """End of security support of a release (ISO date), or None if it is not in the table"""
+    return OS_EOL_DATES.get(release["distro"], {}).get(release["version"])
+
+
+def is_eol(release: OsRelease, today: Optional[date] = None) -> bool:
+    """Whether a release is past its end of security support"""
+    end = eol_date(release)
+    return end is not None and date.fromisoformat(end) < (today or date.today())
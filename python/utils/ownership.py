@@ -0,0 +1,41 @@
+"""
+Image ownership from image labels.
+
+Teams often record who owns an image in a label of its config, set at build
+time with LABEL or --label:
+
+    LABEL owner="data-science" team="forecasting"
+
+Image analysis records the labels of every inspected image. The owner of an
+image is the value of the first label from analysis.owner_labels that the
+image has, so a registry-wide convention ("owner") can fall back to older ones
+("team", "maintainer"). Images without any of the labels are attributed to
+UNLABELED; images whose labels were not read (fast scans of uncached digests)
+to UNKNOWN.
+"""
+
+from typing import Dict, List, Optional
+
+# Owner of images that have none of the owner labels
+UNLABELED = "(unlabeled)"
+
+# Owner of images whose labels were not read
+UNKNOWN = "(unknown)"
+
+
+def image_labels(labels: Optional[Dict[str, object]]) -> Dict[str, str]:
+    """Labels from skopeo inspect output as strings ({} for images without labels)"""
+    if not isinstance(labels, dict):
+        return {}
+    return {str(key): str(value) for key, value in labels.items() if value is not None}
+
+
+def owner_from_labels(labels: Optional[Dict[str, str]], label_keys: List[str]) -> str:
+    """Owner of an image: the first non-empty owner label, UNLABELED, or UNKNOWN if labels are unknown"""
+    if labels is None:
+        return UNKNOWN
+    for key in label_keys:
+        value = (labels.get(key) or "").strip()
+        if value:
+            return value
+    return UNLABELED
@@ -0,0 +1,149 @@
+"""
+Partial results of a scan still in progress.
+
+A scan of a large registry can take hours, and its reports are only written
+at the end. With partial results enabled, the scan rewrites one file every N
+inspected images and every S seconds, so monitoring systems can consume the
+inventory found so far. The file has one section per repository (image
+type): sections of repositories already scanned are complete and final, the
+sections of the repositories being scanned hold what has been inspected so
+far, and a repository whose tags could not be listed is marked "failed". The
+file is replaced atomically, so a reader never sees a half-written document,
+and its status becomes "complete" once the scan has finished.
+
+Flushes happen as tags complete: a scan waiting on a slow registry writes
+the file again when the next tag finishes, not while it waits.
+"""
+
+import os
+import time
+from datetime import datetime
+from typing import TYPE_CHECKING, Any, Dict, List, Optional
+
+from utils.logging_utils import get_logger
+from utils.report_utils import save_json
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+logger = get_logger(__name__)
+
+STATUS_IN_PROGRESS = "in_progress"
+STATUS_COMPLETE = "complete"
+STATUS_FAILED = "failed"
+
+
+class PartialResultWriter:
+    """Periodically writes the inventory of a scan in progress to a file."""
+
+    def __init__(
+        self,
+        path: str,
+        analyzer: "ImageAnalyzer",
+        every_images: Optional[int] = None,
+        interval_seconds: Optional[float] = None,
+    ):
+        """
+        Args:
+            path: File to write (replaced on every flush)
+            analyzer: Analyzer whose results are written
+            every_images: Flush after this many images were inspected since the last flush (None: never)
+            interval_seconds: Flush when this many seconds passed since the last flush (None: never)
+        """
+        self.path = path
+        self.analyzer = analyzer
+        self.every_images = every_images
+        self.interval_seconds = interval_seconds
+        self.started_at = datetime.now().isoformat()
+        self.flushes = 0
+        # image type -> "in_progress", "complete" or "failed", in scan order
+        self._repositories: Dict[str, str] = {}
+        # image type -> tags processed and total, of the repositories being scanned
+        self._progress: Dict[str, Dict[str, int]] = {}
+        self._images_since_flush = 0
+        self._last_flush = time.monotonic()
+
+    def repository_started(self, image_type: str, tags_total: int) -> None:
+        """Mark a repository as being scanned"""
+        self._repositories[image_type] = STATUS_IN_PROGRESS
+        self._progress[image_type] = {"tags_processed": 0, "tags_total": tags_total}
+
+    def tag_completed(self, image_type: str, tags_processed: int, inspected: bool) -> None:
+        """Note that a tag finished (inspected or failed), flushing if one of the thresholds is reached"""
+        if image_type in self._progress:
+            self._progress[image_type]["tags_processed"] = tags_processed
+        if inspected:
+            self._images_since_flush += 1
+        due_by_count = self.every_images is not None and self._images_since_flush >= self.every_images
+        due_by_time = (
+            self.interval_seconds is not None and time.monotonic() - self._last_flush >= self.interval_seconds
+        )
+        if due_by_count or due_by_time:
+            self.flush()
+
+    def repository_done(self, image_type: str, succeeded: bool = True) -> None:
+        """Mark a repository as scanned (or its scan as failed) and write its final section"""
+        self._repositories[image_type] = STATUS_COMPLETE if succeeded else STATUS_FAILED
+        self._progress.pop(image_type, None)
+        self.flush()
+
+    def finish(self) -> str:
+        """Write the file a last time, with status "complete"; returns its path"""
+        return self.flush(status=STATUS_COMPLETE)
+
+    def flush(self, status: str = STATUS_IN_PROGRESS) -> str:
+        """Replace the file with the current results; returns its path"""
+        tmp_path = save_json(f"{self.path}.tmp", self.document(status), timestamp=False)
+        os.replace(tmp_path, self.path)
+        self.flushes += 1
+        self._images_since_flush = 0
+        self._last_flush = time.monotonic()
+        logger.debug(f"Partial results flushed to {self.path} ({len(self.analyzer.images)} images)")
+        return self.path
+
+    def document(self, status: str = STATUS_IN_PROGRESS) -> Dict[str, Any]:
+        """The partial results: scan progress and one section per repository"""
+        analyzer = self.analyzer
+        sizes: Dict[str, int] = {}
+        for mapping in analyzer.image_layers:
+            layer = analyzer.layers.get(mapping["layer_id"])
+            if layer:
+                sizes[mapping["image_id"]] = sizes.get(mapping["image_id"], 0) + layer["size_bytes"]
+
+        summaries = analyzer.generate_repository_summary()
+        sections: Dict[str, Dict[str, Any]] = {}
+        for image_type, repository_status in self._repositories.items():
+            prefix = f"{image_type}:"
+            images = {
+                image_id: {"tag": image["tag"], "digest": image["digest"], "size_bytes": sizes.get(image_id, 0)}
+                for image_id, image in sorted(analyzer.images.items())
+                if image_id.startswith(prefix)
+            }
+            sections[image_type] = {
+                "status": repository_status,
+                "summary": summaries.get(f"{analyzer.repository}/{image_type}"),
+                "images": images,
+                "failures": {
+                    image_id: failure
+                    for image_id, failure in sorted(analyzer.failures.items())
+                    if image_id.startswith(prefix)
+                },
+            }
+
+        in_progress: List[str] = [t for t, s in self._repositories.items() if s == STATUS_IN_PROGRESS]
+        return {
+            "status": status,
+            "started_at": self.started_at,
+            "updated_at": datetime.now().isoformat(),
+            "registry_url": analyzer.registry_url,
+            "repository": analyzer.repository,
+            "progress": {
+                "images_inspected": len(analyzer.images),
+                "failed_tags": len(analyzer.failures),
+                "repositories_complete": [t for t, s in self._repositories.items() if s == STATUS_COMPLETE],
+                "current_repository": in_progress[0] if in_progress else None,
+                "repositories_in_progress": {t: self._progress.get(t) for t in in_progress},
+                **(self._progress.get(in_progress[0], {}) if in_progress else {}),
+            },
+            "repositories": sections,
+        }
@@ -0,0 +1,147 @@
+"""
+Defaults profiles.
+
+The registry layout the cleaner expects - where the registry is, the base
+repository, the image types under it and how their tags are named - is
+Domino's by default. A profile bundles those settings so an install with a
+different layout selects them once instead of overriding every flag:
+
+    registry_url        registry.url; {namespace} is replaced by
+                        kubernetes.domino_platform_namespace
+    repository          registry.repository
+    image_types         registry.image_types, the repositories under the
+                        base repository (<repository>/<image type>)
+    tag_naming_pattern  analysis.tag_naming_pattern
+
+The built-in "domino" profile is the default. Custom profiles live under
+profiles in config.yaml and extend another profile ("domino" unless extends
+names one), so they only list what differs:
+
+    profiles:
+      ml-platform:
+        registry_url: registry.{namespace}.svc.cluster.local:5000
+        repository: ml
+        image_types: [notebook, serving]
+
+The profile is selected with --profile, the CONFIG_PROFILE environment
+variable or profile in config.yaml. Settings config.yaml sets itself still
+override the profile; when a profile was selected, each such override is
+reported as drift so the profile and the config do not silently disagree.
+"""
+
+from typing import Any, Dict, List, Optional, Tuple
+
+from utils.tag_matching import DOMINO_TAG_PATTERN
+
+DOMINO_PROFILE = "domino"
+
+BUILTIN_PROFILES: Dict[str, Dict[str, Any]] = {
+    DOMINO_PROFILE: {
+        "registry_url": "docker-registry:5000",
+        "repository": "dominodatalab",
+        "image_types": ["environment", "model"],
+        "tag_naming_pattern": DOMINO_TAG_PATTERN,
+    },
+}
+
+# Profile setting -> (config section, key) it provides the default of
+PROFILE_SETTINGS: Dict[str, Tuple[str, str]] = {
+    "registry_url": ("registry", "url"),
+    "repository": ("registry", "repository"),
+    "image_types": ("registry", "image_types"),
+    "tag_naming_pattern": ("analysis", "tag_naming_pattern"),
+}
+
+
+class ProfileError(ValueError):
+    """Raised when a profile does not exist or is not valid."""
+
+
+def resolve_profile(name: str, custom: Optional[Dict[str, Any]] = None) -> Dict[str, Any]:
+    """The settings of a profile, with those of the profiles it extends.
+
+    Args:
+        name: Profile name
+        custom: Custom profiles (profiles in config.yaml); they may shadow built-in ones
+
+    Returns:
+        Every setting of PROFILE_SETTINGS
+
+    Raises:
+        ProfileError: If the profile, or one it extends, is unknown or invalid
+    """
+    custom = custom or {}
+    if not isinstance(custom, dict):
+        raise ProfileError(f"profiles must map profile names to settings, got: {custom}")
+
+    chain: List[str] = []
+    settings: Dict[str, Any] = {}
+    current: Optional[str] = name
+    while current is not None:
+        if current in chain:
+            raise ProfileError(f"Profile '{name}' extends itself ({' -> '.join(chain + [current])})")
+        chain.append(current)
+        if current in custom:
+            profile = custom[current]
+            if not isinstance(profile, dict):
+                raise ProfileError(f"profiles.{current} must be a mapping of settings, got: {profile}")
+            unknown = sorted(set(profile) - set(PROFILE_SETTINGS) - {"extends"})
+            if unknown:
+                raise ProfileError(
+                    f"profiles.{current} has unknown settings: {', '.join(unknown)} "
+                    f"(expected: {', '.join(PROFILE_SETTINGS)}, extends)"
+                )
+            parent = profile.get("extends", DOMINO_PROFILE if current != DOMINO_PROFILE else None)
+        elif current in BUILTIN_PROFILES:
+            profile = BUILTIN_PROFILES[current]
+            parent = None
+        else:
+            known = sorted(set(BUILTIN_PROFILES) | set(custom))
+            raise ProfileError(f"Unknown profile '{current}' (known profiles: {', '.join(known)})")
+        # Settings of the profile itself win over those it extends
+        for key, value in profile.items():
+            if key != "extends":
+                settings.setdefault(key, value)
+        current = parent
+    # A custom "domino" profile extends nothing; fill what it leaves out from the built-in one
+    for key, value in BUILTIN_PROFILES[DOMINO_PROFILE].items():
+        settings.setdefault(key, value)
+
+    image_types = settings["image_types"]
+    if not isinstance(image_types, list) or not image_types or not all(isinstance(t, str) for t in image_types):
+        raise ProfileError(f"Profile '{name}': image_types must be a non-empty list of strings, got: {image_types}")
+    for key in ("registry_url", "repository", "tag_naming_pattern"):
+        if not isinstance(settings[key], str) or not settings[key]:
+            raise ProfileError(f"Profile '{name}': {key} must be a non-empty string, got: {settings[key]}")
+    return settings
+
+
+def profile_config(profile: Dict[str, Any]) -> Dict[str, Dict[str, Any]]:
+    """Config sections holding a resolved profile's settings, to merge under config.yaml"""
+    sections: Dict[str, Dict[str, Any]] = {}
+    for setting, (section, key) in PROFILE_SETTINGS.items():
+        value = profile[setting]
+        sections.setdefault(section, {})[key] = list(value) if isinstance(value, list) else value
+    return sections
+
+
+def profile_drift(name: str, profile: Dict[str, Any], user_config: Dict[str, Any]) -> List[str]:
+    """Settings config.yaml sets to something other than the profile's value
+
+    Returns:
+        One message per overridden setting
+    """
+    drift = []
+    for setting, (section, key) in PROFILE_SETTINGS.items():
+        configured = (user_config.get(section) or {}).get(key)
+        if configured is not None and configured != profile[setting]:
+            drift.append(
+                f"{section}.{key} in config.yaml ({configured}) overrides {setting} of profile '{name}' "
+                f"({profile[setting]})"
+            )
+    return drift
+
+
+def render_registry_url(url: str, namespace: str) -> str:
+    """A registry URL with its {namespace} placeholder filled in"""
+    return url.replace("{namespace}", namespace)
@@ -0,0 +1,169 @@
+"""
+Structured progress events for embedding applications.
+
+Scripts report progress in their logs. An application that drives the
+analyzer or the plan applier as a library - an admin UI, a job runner - can
+instead attach a ProgressEvents channel and render progress itself, without
+parsing log lines:
+
+    events = ProgressEvents()
+    events.subscribe(lambda event: ui.update(event.to_dict()))
+    analyzer.progress = events
+    analyzer.analyze_images(["environment", "model"])
+
+Every event has a kind:
+
+    phase_changed       A stage of the run started (phase; repository, for the stages of one repository)
+    repository_started  The tags of a repository were listed (repository, total)
+    tag_done            A tag finished (repository, tag, completed, total, status)
+    repository_done     A repository finished (repository, status "complete" or "failed")
+    error               A tag or repository failed (repository, tag, message, error_code)
+
+Scans inspect tags on worker threads, so events can be emitted from several
+threads. Emission is serialized: events get increasing sequence numbers and
+each subscriber receives them one at a time, in sequence order. Subscribers
+run on the emitting thread and should return quickly; one that needs to do
+slow work (or lives on another thread, like a UI event loop) should take
+events from a queue instead (see ProgressEvents.queue). An exception raised
+by a subscriber is logged and does not interrupt the run.
+"""
+
+import threading
+import time
+from dataclasses import asdict, dataclass
+from queue import Full, Queue
+from typing import Any, Callable, Dict, List, Optional
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+PHASE_CHANGED = "phase_changed"
+REPOSITORY_STARTED = "repository_started"
+TAG_DONE = "tag_done"
+REPOSITORY_DONE = "repository_done"
+ERROR = "error"
+EVENT_KINDS = (PHASE_CHANGED, REPOSITORY_STARTED, TAG_DONE, REPOSITORY_DONE, ERROR)
+
+# Phases of a scan (ImageAnalyzer) and of applying a plan (PlanApplier)
+PHASE_SCAN = "scan"
+PHASE_FINISH_REPOSITORY = "finish_repository"  # Reference tags, provenance and deep-scan configs of one repository
+PHASE_RETRY = "retry"
+PHASE_USAGE_CHECK = "usage_check"
+PHASE_DELETE = "delete"
+
+
+@dataclass(frozen=True)
+class ProgressEvent:
+    """One progress event; fields that do not apply to its kind are None."""
+
+    kind: str
+    sequence: int  # 1 for the first event of a channel, increasing by one
+    timestamp: float  # Seconds since the epoch
+    phase: Optional[str] = None
+    repository: Optional[str] = None  # Image type, e.g. "environment"
+    tag: Optional[str] = None
+    completed: Optional[int] = None  # Tags (or plan items) finished so far
+    total: Optional[int] = None
+    status: Optional[str] = None  # tag_done: e.g. "inspected", "failed", "deleted"; repository_done: "complete"
+    message: Optional[str] = None
+    error_code: Optional[str] = None  # See utils.error_utils
+
+    def to_dict(self) -> Dict[str, Any]:
+        """The event as a JSON-compatible dict, without the fields that do not apply"""
+        return {key: value for key, value in asdict(self).items() if value is not None}
+
+
+class ProgressEvents:
+    """A thread-safe channel delivering progress events to subscribers."""
+
+    def __init__(self):
+        self._lock = threading.Lock()
+        self._subscribers: List[Callable[[ProgressEvent], None]] = []
+        self._sequence = 0
+
+    def subscribe(self, callback: Callable[[ProgressEvent], None]) -> Callable[[], None]:
+        """Call callback with every event emitted from now on.
+
+        Returns:
+            A function that unsubscribes the callback
+        """
+        with self._lock:
+            self._subscribers.append(callback)
+
+        def unsubscribe() -> None:
+            with self._lock:
+                if callback in self._subscribers:
+                    self._subscribers.remove(callback)
+
+        return unsubscribe
+
+    def queue(self, maxsize: int = 0) -> "Queue[ProgressEvent]":
+        """A queue receiving every event emitted from now on, for consumers on another thread.
+
+        Args:
+            maxsize: Bound of the queue (0: unbounded); when it is full, new events are dropped
+                rather than blocking the run, and the consumer sees a gap in the sequence numbers
+        """
+        events: "Queue[ProgressEvent]" = Queue(maxsize)
+
+        def put(event: ProgressEvent) -> None:
+            try:
+                events.put_nowait(event)
+            except Full:
+                pass
+
+        self.subscribe(put)
+        return events
+
+    def emit(self, kind: str, **fields: Any) -> ProgressEvent:
+        """Deliver an event to every subscriber.
+
+        Args:
+            kind: One of EVENT_KINDS
+            fields: The other ProgressEvent fields
+
+        Returns:
+            The event delivered
+        """
+        if kind not in EVENT_KINDS:
+            raise ValueError(f"Unknown progress event kind '{kind}' (expected one of: {', '.join(EVENT_KINDS)})")
+        with self._lock:
+            self._sequence += 1
+            event = ProgressEvent(kind=kind, sequence=self._sequence, timestamp=time.time(), **fields)
+            for callback in list(self._subscribers):
+                try:
+                    callback(event)
+                except Exception as e:
+                    logger.warning(f"Progress event subscriber failed on {kind} event: {e}")
+        return event
+
+    def phase_changed(self, phase: str, repository: Optional[str] = None) -> ProgressEvent:
+        """Emit a phase_changed event"""
+        return self.emit(PHASE_CHANGED, phase=phase, repository=repository)
+
+    def repository_started(self, repository: str, total: int) -> ProgressEvent:
+        """Emit a repository_started event"""
+        return self.emit(REPOSITORY_STARTED, repository=repository, completed=0, total=total)
+
+    def tag_done(
+        self, repository: str, tag: str, completed: int, total: int, status: str, message: Optional[str] = None
+    ) -> ProgressEvent:
+        """Emit a tag_done event"""
+        return self.emit(
+            TAG_DONE, repository=repository, tag=tag, completed=completed, total=total, status=status, message=message
+        )
+
+    def repository_done(self, repository: str, succeeded: bool = True) -> ProgressEvent:
+        """Emit a repository_done event"""
+        return self.emit(REPOSITORY_DONE, repository=repository, status="complete" if succeeded else "failed")
+
+    def error(
+        self,
+        message: str,
+        repository: Optional[str] = None,
+        tag: Optional[str] = None,
+        error_code: Optional[str] = None,
+    ) -> ProgressEvent:
+        """Emit an error event"""
+        return self.emit(ERROR, repository=repository, tag=tag, message=message, error_code=error_code)
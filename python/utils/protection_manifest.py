@@ -0,0 +1,114 @@
+"""
+Kubernetes manifest of protected images.
+
+candidates_report computes which images current configuration protects
+(workspaces, models, scheduler jobs, project and organization defaults, app
+versions). Written as a ConfigMap, that set can be applied to the cluster and
+read by admission controllers or other tooling, for example to reject deleting
+or retagging an image that is still in use:
+
+    apiVersion: v1
+    kind: ConfigMap
+    metadata:
+      name: docker-registry-cleaner-protected-images
+      namespace: domino-platform
+      labels: {app.kubernetes.io/managed-by: docker-registry-cleaner}
+      annotations: {docker-registry-cleaner/registry: ..., docker-registry-cleaner/image-count: "2"}
+    data:
+      images.txt: |
+        registry:5000/dominodatalab/environment:62798b9bee0eb12322fc97e8-3
+      images.json: '[{"image": "...", "digest": "sha256:...", "protected_by": ["workspaces"]}]'
+
+images.txt lists one reference per line for simple matching; images.json also
+carries each image's digest and the references protecting it.
+"""
+
+import json
+from datetime import datetime, timezone
+from typing import Any, Dict, List, Optional, TypedDict
+
+import yaml
+
+DEFAULT_CONFIGMAP_NAME = "docker-registry-cleaner-protected-images"
+MANAGED_BY = "docker-registry-cleaner"
+
+ANNOTATION_REGISTRY = "docker-registry-cleaner/registry"
+ANNOTATION_IMAGE_COUNT = "docker-registry-cleaner/image-count"
+ANNOTATION_GENERATED_AT = "docker-registry-cleaner/generated-at"
+
+
+class ProtectedReference(TypedDict):
+    """A protected image as listed in images.json."""
+
+    image: str  # <registry>/<repository>:<tag>
+    digest: str  # Empty when the digest is not known
+    protected_by: List[str]
+
+
+def protected_references(
+    protected: List[Dict[str, Any]], registry_url: str, digests: Optional[Dict[str, str]] = None
+) -> List[ProtectedReference]:
+    """Full references of protected images, sorted.
+
+    Args:
+        protected: Protected images (rank_candidates)
+        registry_url: Registry the images are in
+        digests: Manifest digests by image_id, where known
+    """
+    references: List[ProtectedReference] = [
+        {
+            "image": f"{registry_url}/{image['repository']}:{image['tag']}",
+            "digest": (digests or {}).get(image["image_id"], ""),
+            "protected_by": list(image["protected_by"]),
+        }
+        for image in protected
+    ]
+    references.sort(key=lambda reference: reference["image"])
+    return references
+
+
+def protection_configmap(
+    references: List[ProtectedReference],
+    registry_url: str,
+    namespace: str,
+    name: str = DEFAULT_CONFIGMAP_NAME,
+    generated_at: Optional[datetime] = None,
+) -> Dict[str, Any]:
+    """Build the ConfigMap listing protected images.
+
+    Args:
+        references: Protected images (protected_references)
+        registry_url: Registry the images are in
+        namespace: Namespace of the ConfigMap
+        name: Name of the ConfigMap
+        generated_at: When the protection set was computed (default: now)
+
+    Returns:
+        ConfigMap manifest as a dict
+    """
+    generated_at = generated_at or datetime.now(timezone.utc)
+    return {
+        "apiVersion": "v1",
+        "kind": "ConfigMap",
+        "metadata": {
+            "name": name,
+            "namespace": namespace,
+            "labels": {"app.kubernetes.io/managed-by": MANAGED_BY},
+            "annotations": {
+                ANNOTATION_REGISTRY: registry_url,
+                ANNOTATION_IMAGE_COUNT: str(len(references)),
+                ANNOTATION_GENERATED_AT: generated_at.isoformat(),
+            },
+        },
+        "data": {
+            "images.txt": "".join(f"{reference['image']}\n" for reference in references),
+            "images.json": json.dumps(references, sort_keys=True),
+        },
+    }
+
+
+def save_manifest(manifest: Dict[str, Any], output_path: str) -> str:
+    """Write a manifest as YAML, ready for kubectl apply -f"""
+    with open(output_path, "w") as f:
+        yaml.safe_dump(manifest, f, default_flow_style=False, sort_keys=False)
+    return output_path
@@ -0,0 +1,301 @@
+"""
+Pluggable sources of image protection.
+
+An image is protected when something still needs it, and protected images
+are never ranked as deletion candidates or planned for deletion. Each source
+of that knowledge is a ProtectionProvider; several can be enabled at once
+(protection.providers in config.yaml), and reports record which providers
+protected each image and why:
+
+    domino          Domino's current configuration - workspaces, models,
+                    scheduled jobs, project and organization defaults, app
+                    versions - from the MongoDB usage reports
+    kubernetes      Images of the pods running in the cluster (all namespaces,
+                    or protection.kubernetes_namespaces)
+    reference-file  Images listed in protection.reference_file, one per line:
+                    <type>:<tag>, a bare tag, <repository>:<tag>,
+                    <registry>/<repository>:<tag>, or a reference by digest
+    ecr-pull-time   Images ECR recorded a pull of within the last
+                    protection.ecr_pull_days days (ECR registries only)
+
+candidates_report always protects what Domino's configuration references
+when usage data is loaded, and adds the configured providers. plan drops
+images the configured providers protect, whatever selected them, and apply
+asks them again, skipping plan items protected since the plan was made.
+
+A provider that fails raises ProtectionProviderError: deleting images because
+the cluster or ECR could not be asked whether they are needed would defeat
+the point, so reports and plans fail instead.
+"""
+
+import re
+from abc import ABC, abstractmethod
+from datetime import datetime, timedelta, timezone
+from typing import TYPE_CHECKING, Any, Dict, Iterable, List, Optional, Tuple
+
+from utils.logging_utils import get_logger
+
+if TYPE_CHECKING:
+    from utils.deletion_candidates import TagUsage
+    from utils.image_data_analysis import ImageAnalyzer
+
+logger = get_logger(__name__)
+
+DOMINO_PROVIDER = "domino"
+KUBERNETES_PROVIDER = "kubernetes"
+REFERENCE_FILE_PROVIDER = "reference-file"
+ECR_PULL_TIME_PROVIDER = "ecr-pull-time"
+PROTECTION_PROVIDERS = (DOMINO_PROVIDER, KUBERNETES_PROVIDER, REFERENCE_FILE_PROVIDER, ECR_PULL_TIME_PROVIDER)
+
+# image_id -> provider name -> what protects the image, e.g. {"kubernetes": ["pod default/web-1"]}
+Protections = Dict[str, Dict[str, List[str]]]
+
+_DIGEST = re.compile(r"sha256:[0-9a-f]{64}")
+
+
+class ProtectionProviderError(Exception):
+    """A protection provider could not tell which images it protects."""
+
+
+class ProtectionProvider(ABC):
+    """Source of images that must not be deleted."""
+
+    name: str
+
+    @abstractmethod
+    def protected_images(self, analyzer: "ImageAnalyzer") -> Dict[str, List[str]]:
+        """Analyzed images this provider protects.
+
+        Returns:
+            image_id -> what protects the image, e.g. ["pod default/web-1"]
+        """
+
+
+class ImageMatcher:
+    """Matches image references from outside the registry scan to analyzed images."""
+
+    def __init__(self, analyzer: "ImageAnalyzer"):
+        self.analyzer = analyzer
+        self.registry = re.sub(r"^https?://", "", analyzer.registry_url or "").rstrip("/")
+        self.by_tag: Dict[Tuple[str, str], List[str]] = {}
+        self.by_digest: Dict[str, List[str]] = {}
+        for image_id, image_data in analyzer.images.items():
+            self.by_tag.setdefault((image_data["repository"], image_data["tag"]), []).append(image_id)
+            if image_data.get("digest"):
+                self.by_digest.setdefault(image_data["digest"], []).append(image_id)
+
+    def _repository(self, name: str) -> Optional[str]:
+        """Repository of a reference's name, or None if the name is on another registry"""
+        if self.registry and name.startswith(self.registry + "/"):
+            return name[len(self.registry) + 1 :]
+        host = name.split("/", 1)[0]
+        if "/" in name and ("." in host or ":" in host or host == "localhost"):
+            return None
+        return name
+
+    def match(self, reference: str) -> List[str]:
+        """Analyzed images a reference names; empty if it names none"""
+        # Container runtimes report what a container runs as e.g. docker-pullable://<name>@sha256:...
+        reference = re.sub(r"^[a-z-]+://", "", reference.strip())
+        digest = _DIGEST.search(reference)
+        if digest:
+            name = reference[: digest.start()].rstrip("@")
+            repository = self._repository(name) if name else ""
+            if repository is None:
+                return []
+            return [
+                image_id
+                for image_id in self.by_digest.get(digest.group(0), [])
+                if not repository or self.analyzer.images[image_id]["repository"] == repository
+            ]
+        if "/" in reference:
+            name, _, tag = reference.rpartition(":")
+            repository = self._repository(name) if "/" not in tag else None
+            return list(self.by_tag.get((repository, tag), [])) if repository else []
+        image_id = self.analyzer.resolve_image_id(reference)
+        return [image_id] if image_id else []
+
+
+class DominoUsageProvider(ProtectionProvider):
+    """Images Domino's current configuration references, from the MongoDB usage reports."""
+
+    name = DOMINO_PROVIDER
+
+    def __init__(self, usage: Optional[Dict[str, "TagUsage"]] = None):
+        """Initialize the provider
+
+        Args:
+            usage: Usage by tag (ImageUsageService.summarize_tag_usage); read when needed if omitted
+        """
+        self.usage = usage
+
+    def protected_images(self, analyzer: "ImageAnalyzer") -> Dict[str, List[str]]:
+        usage = self.usage
+        if usage is None:
+            from utils.image_usage import ImageUsageService
+
+            usage = ImageUsageService().summarize_tag_usage([data["tag"] for data in analyzer.images.values()])
+        protected: Dict[str, List[str]] = {}
+        for image_id, image_data in analyzer.images.items():
+            tag_usage = usage.get(image_data["tag"])
+            if tag_usage and tag_usage["protected_by"]:
+                protected[image_id] = list(tag_usage["protected_by"])
+        return protected
+
+
+class KubernetesProvider(ProtectionProvider):
+    """Images of the pods running in the cluster."""
+
+    name = KUBERNETES_PROVIDER
+
+    def __init__(self, namespaces: Optional[List[str]] = None, core_v1: Any = None):
+        """Initialize the provider
+
+        Args:
+            namespaces: Namespaces to read pods from (default: all)
+            core_v1: Kubernetes CoreV1Api (created from the cluster config if omitted)
+        """
+        self.namespaces = namespaces or []
+        self.core_v1 = core_v1
+
+    def _pods(self) -> Iterable[Any]:
+        core_v1 = self.core_v1
+        if core_v1 is None:
+            from utils.config_manager import _get_kubernetes_clients
+
+            core_v1, _ = _get_kubernetes_clients()
+        if not self.namespaces:
+            return core_v1.list_pod_for_all_namespaces(watch=False).items
+        return [pod for namespace in self.namespaces for pod in core_v1.list_namespaced_pod(namespace).items]
+
+    def protected_images(self, analyzer: "ImageAnalyzer") -> Dict[str, List[str]]:
+        matcher = ImageMatcher(analyzer)
+        protected: Dict[str, List[str]] = {}
+        for pod in self._pods():
+            spec = pod.spec
+            references = [c.image for c in (spec.containers or []) + (spec.init_containers or []) if c.image]
+            # Tags may have moved since the pod started; the digest it runs is what it needs
+            statuses = (pod.status.container_statuses or []) + (pod.status.init_container_statuses or [])
+            references += [s.image_id for s in statuses if s.image_id]
+            where = f"pod {pod.metadata.namespace}/{pod.metadata.name}"
+            for reference in references:
+                for image_id in matcher.match(reference):
+                    if where not in protected.setdefault(image_id, []):
+                        protected[image_id].append(where)
+        return protected
+
+
+class ReferenceFileProvider(ProtectionProvider):
+    """Images listed in a file, one reference per line."""
+
+    name = REFERENCE_FILE_PROVIDER
+
+    def __init__(self, path: str):
+        self.path = path
+
+    def protected_images(self, analyzer: "ImageAnalyzer") -> Dict[str, List[str]]:
+        matcher = ImageMatcher(analyzer)
+        protected: Dict[str, List[str]] = {}
+        with open(self.path, "r") as f:
+            for line_number, raw in enumerate(f, start=1):
+                line = raw.strip()
+                if not line or line.startswith("#"):
+                    continue
+                image_ids = matcher.match(line.split()[0])
+                if not image_ids:
+                    logger.debug(f"{self.path}:{line_number}: '{line}' does not match an analyzed image")
+                for image_id in image_ids:
+                    protected.setdefault(image_id, []).append(f"{self.path}:{line_number}")
+        return protected
+
+
+class EcrPullTimeProvider(ProtectionProvider):
+    """Images ECR recorded a recent pull of."""
+
+    name = ECR_PULL_TIME_PROVIDER
+
+    def __init__(self, ecr_client: Any, days: int, now: Optional[datetime] = None):
+        """Initialize the provider
+
+        Args:
+            ecr_client: boto3 ECR client of the registry
+            days: Images pulled within this many days are protected
+            now: Reference time (default: current time)
+        """
+        self.ecr_client = ecr_client
+        self.days = days
+        self.now = now
+
+    def protected_images(self, analyzer: "ImageAnalyzer") -> Dict[str, List[str]]:
+        threshold = (self.now or datetime.now(timezone.utc)) - timedelta(days=self.days)
+        matcher = ImageMatcher(analyzer)
+        protected: Dict[str, List[str]] = {}
+        for repository in sorted({image_data["repository"] for image_data in analyzer.images.values()}):
+            paginator = self.ecr_client.get_paginator("describe_images")
+            for page in paginator.paginate(repositoryName=repository):
+                for detail in page.get("imageDetails", []):
+                    pulled = detail.get("lastRecordedPullTime")
+                    if pulled is None:
+                        continue
+                    if pulled.tzinfo is None:
+                        pulled = pulled.replace(tzinfo=timezone.utc)
+                    if pulled < threshold:
+                        continue
+                    for image_id in matcher.match(f"{repository}@{detail['imageDigest']}"):
+                        protected.setdefault(image_id, []).append(f"pulled {pulled.date().isoformat()}")
+        return protected
+
+
+def configured_providers(names: List[str], usage: Optional[Dict[str, "TagUsage"]] = None) -> List[ProtectionProvider]:
+    """Create the providers named in protection.providers.
+
+    Args:
+        names: Provider names (config_manager.get_protection_providers)
+        usage: Usage by tag for the domino provider, if already loaded
+    """
+    from utils.config_manager import config_manager
+
+    providers: List[ProtectionProvider] = []
+    for name in names:
+        if name == DOMINO_PROVIDER:
+            providers.append(DominoUsageProvider(usage))
+        elif name == KUBERNETES_PROVIDER:
+            providers.append(KubernetesProvider(config_manager.get_protection_kubernetes_namespaces()))
+        elif name == REFERENCE_FILE_PROVIDER:
+            providers.append(ReferenceFileProvider(config_manager.get_protection_reference_file()))
+        elif name == ECR_PULL_TIME_PROVIDER:
+            from utils.auth import get_ecr_client
+
+            registry_url = config_manager.get_registry_url()
+            profile = config_manager.get_credential_profile(registry_url) or {}
+            ecr_client = get_ecr_client(
+                registry_url,
+                role_arn=profile.get("role_arn"),
+                external_id=profile.get("external_id"),
+                region=profile.get("region"),
+            )
+            providers.append(EcrPullTimeProvider(ecr_client, config_manager.get_protection_ecr_pull_days()))
+    return providers
+
+
+def collect_protections(analyzer: "ImageAnalyzer", providers: List[ProtectionProvider]) -> Protections:
+    """Ask every provider which analyzed images it protects.
+
+    Raises:
+        ProtectionProviderError: If a provider fails
+    """
+    protections: Protections = {}
+    for provider in providers:
+        try:
+            protected = provider.protected_images(analyzer)
+        except Exception as e:
+            raise ProtectionProviderError(f"Protection provider '{provider.name}' failed: {e}") from e
+        logger.info(f"Protection provider '{provider.name}': {len(protected)} image(s) protected")
+        for image_id, reasons in protected.items():
+            protections.setdefault(image_id, {})[provider.name] = reasons
+    return protections
+
+
+def describe_protection(providers: Dict[str, List[str]]) -> str:
+    """One line naming what protects an image, e.g. "kubernetes (pod default/web-1)" """
+    return "; ".join(f"{name} ({', '.join(reasons)})" if reasons else name for name, reasons in providers.items())
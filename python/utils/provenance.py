@@ -0,0 +1,155 @@
+"""
+In-toto / SLSA build provenance.
+
+Build systems can attach a signed provenance attestation to an image: an
+in-toto statement whose SLSA provenance predicate records which builder
+produced the image and from which source. Attestations are stored as separate
+artifacts that refer to the image, found either through the registry's OCI
+referrers API or under a "sha256-<digest>.att" reference tag (cosign). Each
+attestation layer is an in-toto statement, usually wrapped in a DSSE envelope:
+
+    {"payloadType": "application/vnd.in-toto+json", "payload": "<base64 statement>", "signatures": [...]}
+
+Image analysis reads the provenance of each image when
+analysis.collect_provenance is enabled and records its builder and source:
+
+    {"predicate_type": "https://slsa.dev/provenance/v1",
+     "builder_id": "https://github.com/actions/runner",
+     "build_type": "https://actions.github.io/buildtypes/workflow/v1",
+     "source": "git+https://github.com/example/app@refs/heads/main",
+     "revision": "4f1c2a9..."}
+
+Both SLSA v0.2 (builder.id, invocation.configSource, materials) and v1
+(runDetails.builder.id, buildDefinition.resolvedDependencies) predicates are
+understood. Other attestations, such as SBOMs or vulnerability scans, are
+ignored.
+"""
+
+import base64
+import json
+from typing import Any, Dict, List, Optional, TypedDict
+
+SLSA_PREDICATE_PREFIX = "https://slsa.dev/provenance/"
+
+# Largest attestation layer read; provenance of large builds can run to a few MB
+MAX_ATTESTATION_BYTES = 16 * 1024 * 1024
+
+# Substrings of media and artifact types that hold in-toto statements
+_ATTESTATION_MARKERS = ("in-toto", "dsse")
+
+# Layer annotations naming the predicate type of an attestation layer
+_PREDICATE_TYPE_ANNOTATIONS = ("predicateType", "in-toto.io/predicate-type")
+
+
+class Provenance(TypedDict):
+    """Builder and source of an image, from its SLSA provenance."""
+
+    predicate_type: str
+    builder_id: Optional[str]
+    build_type: Optional[str]
+    source: Optional[str]
+    revision: Optional[str]
+
+
+def is_provenance_predicate(predicate_type: Optional[str]) -> bool:
+    """Whether an in-toto predicate type is SLSA provenance"""
+    return bool(predicate_type) and predicate_type.startswith(SLSA_PREDICATE_PREFIX)
+
+
+def is_attestation_descriptor(descriptor: Dict[str, Any]) -> bool:
+    """Whether a referrer descriptor is an in-toto attestation (including DSSE-wrapped ones)"""
+    kinds = f"{descriptor.get('artifactType') or ''} {descriptor.get('mediaType') or ''}".lower()
+    if any(marker in kinds for marker in _ATTESTATION_MARKERS):
+        return True
+    annotations = descriptor.get("annotations") or {}
+    return any(is_provenance_predicate(annotations.get(key)) for key in _PREDICATE_TYPE_ANNOTATIONS)
+
+
+def is_provenance_layer(layer: Dict[str, Any]) -> bool:
+    """Whether an attestation manifest layer may hold SLSA provenance.
+
+    Layers that name their predicate type in an annotation are only read if it
+    is provenance; other in-toto layers have to be read to find out.
+    """
+    if not any(marker in (layer.get("mediaType") or "").lower() for marker in _ATTESTATION_MARKERS):
+        return False
+    annotations = layer.get("annotations") or {}
+    for key in _PREDICATE_TYPE_ANNOTATIONS:
+        if annotations.get(key):
+            return is_provenance_predicate(annotations[key])
+    return True
+
+
+def _statement(blob: bytes) -> Optional[Dict[str, Any]]:
+    """The in-toto statement in an attestation layer, unwrapping a DSSE envelope"""
+    try:
+        document = json.loads(blob)
+        if isinstance(document, dict) and "payload" in document and "payloadType" in document:
+            document = json.loads(base64.b64decode(document["payload"]))
+    except (ValueError, TypeError):
+        return None
+    return document if isinstance(document, dict) else None
+
+
+def _first_dependency(dependencies: Any) -> Dict[str, Any]:
+    """First material / resolved dependency that names a URI"""
+    for dependency in dependencies if isinstance(dependencies, list) else []:
+        if isinstance(dependency, dict) and dependency.get("uri"):
+            return dependency
+    return {}
+
+
+def _digest_value(digest: Any) -> Optional[str]:
+    """Commit or content digest from an in-toto digest set, preferring git commits"""
+    if not isinstance(digest, dict) or not digest:
+        return None
+    for algorithm in ("gitCommit", "sha1"):
+        if digest.get(algorithm):
+            return str(digest[algorithm])
+    return str(next(iter(digest.values())))
+
+
+def parse_provenance(blob: bytes) -> Optional[Provenance]:
+    """Extract builder and source from an attestation layer.
+
+    Returns:
+        The provenance, or None if the layer is not an in-toto statement with a
+        SLSA provenance predicate
+    """
+    statement = _statement(blob)
+    if not statement or not is_provenance_predicate(statement.get("predicateType")):
+        return None
+    predicate = statement.get("predicate")
+    if not isinstance(predicate, dict):
+        return None
+
+    if "buildDefinition" in predicate or "runDetails" in predicate:
+        # SLSA v1
+        definition = predicate.get("buildDefinition") or {}
+        builder = (predicate.get("runDetails") or {}).get("builder") or {}
+        parameters = definition.get("externalParameters") or {}
+        workflow = parameters.get("workflow") if isinstance(parameters.get("workflow"), dict) else {}
+        dependency = _first_dependency(definition.get("resolvedDependencies"))
+        source = workflow.get("repository") or parameters.get("source") or dependency.get("uri")
+        build_type = definition.get("buildType")
+    else:
+        # SLSA v0.1 / v0.2
+        builder = predicate.get("builder") or {}
+        config_source = (predicate.get("invocation") or {}).get("configSource") or {}
+        dependency = config_source if config_source.get("uri") else _first_dependency(predicate.get("materials"))
+        source = dependency.get("uri")
+        build_type = predicate.get("buildType")
+
+    return {
+        "predicate_type": statement["predicateType"],
+        "builder_id": builder.get("id") if isinstance(builder, dict) else None,
+        "build_type": build_type,
+        "source": str(source) if source else None,
+        "revision": _digest_value(dependency.get("digest")),
+    }
+
+
+def attestation_references(referrers: Optional[List[Dict[str, Any]]], reference_tags: List[str]) -> List[str]:
+    """Manifest references (digests, then tags) of the attestations attached to an image"""
+    references = [d["digest"] for d in referrers or [] if d.get("digest") and is_attestation_descriptor(d)]
+    return references + [tag for tag in reference_tags if tag not in references]
@@ -0,0 +1,74 @@
+"""
+Pull availability of deleted images.
+
+Deleting a tag only affects users if its content can no longer be pulled.
+When a surviving image in another repository has the same manifest digest,
+or a surviving image has exactly the same layers in the same order, the
+content stays pullable under that image's name, and the deletion has zero
+user impact: anyone who still needs it can switch references without a
+rebuild or a push.
+
+Tags with the same digest as any deleted image in its repository are not
+counted: they name the same manifest, and deleting a tag deletes its
+manifest, so those tags are deleted along with it.
+"""
+
+from typing import TYPE_CHECKING, Dict, Iterable, List, Optional, Tuple
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+
+def _ordered_layers(analyzer: "ImageAnalyzer") -> Dict[str, Tuple[str, ...]]:
+    """Layers of every analyzed image, in manifest order"""
+    layers: Dict[str, List[Tuple[int, str]]] = {}
+    for mapping in analyzer.image_layers:
+        layers.setdefault(mapping["image_id"], []).append((mapping.get("order_index", 0), mapping["layer_id"]))
+    return {image_id: tuple(layer_id for _, layer_id in sorted(entries)) for image_id, entries in layers.items()}
+
+
+def pull_alternatives(
+    analyzer: "ImageAnalyzer", deleted: Iterable[str], survivors: Optional[Iterable[str]] = None
+) -> Dict[str, List[str]]:
+    """Surviving images that still provide the content of deleted images.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        deleted: image_ids to be deleted; ids that were not analyzed are ignored
+        survivors: image_ids that are kept (default: every analyzed image not in deleted)
+
+    Returns:
+        "<repository>:<tag>" references of the surviving alternatives, sorted, by image_id,
+        for the deleted images that have any (the zero user impact deletions)
+    """
+    deleted_ids = [image_id for image_id in deleted if image_id in analyzer.images]
+    if survivors is None:
+        excluded = set(deleted_ids)
+        survivors = [image_id for image_id in analyzer.images if image_id not in excluded]
+    # Tags naming a deleted manifest are deleted along with it
+    deleted_manifests = set()
+    for image_id in deleted_ids:
+        image_data = analyzer.images[image_id]
+        if image_data.get("digest"):
+            deleted_manifests.add((image_data["repository"], image_data["digest"]))
+    layers = _ordered_layers(analyzer)
+
+    by_digest: Dict[str, List[str]] = {}
+    by_layers: Dict[Tuple[str, ...], List[str]] = {}
+    for image_id in survivors:
+        image_data = analyzer.images.get(image_id)
+        if not image_data or (image_data["repository"], image_data.get("digest")) in deleted_manifests:
+            continue
+        if image_data.get("digest"):
+            by_digest.setdefault(image_data["digest"], []).append(image_id)
+        if layers.get(image_id):
+            by_layers.setdefault(layers[image_id], []).append(image_id)
+
+    alternatives: Dict[str, List[str]] = {}
+    for image_id in deleted_ids:
+        image_data = analyzer.images[image_id]
+        matches = by_digest.get(image_data.get("digest") or "", []) + by_layers.get(layers.get(image_id, ()), [])
+        references = {f"{analyzer.images[match]['repository']}:{analyzer.images[match]['tag']}" for match in matches}
+        if references:
+            alternatives[image_id] = sorted(references)
+    return alternatives
@@ -0,0 +1,115 @@
+"""
+Per-owner storage quotas.
+
+Owners (see utils.ownership) can be given a quota on the registry storage
+their images use, in the quotas section of config.yaml:
+
+    quotas:
+      default_gb: 200          # every owner without a quota of its own (0 = none)
+      owners:
+        data-science: 500
+        "(unlabeled)": 50
+      prioritize_plans: true   # plan lists images of owners over quota first
+
+Usage is measured in amortized bytes, as chargeback_report bills them, so the
+usage of all owners adds up to the registry's total. Owners over quota are
+reported by chargeback_report and plan, raise an on-call alert when a scheduled
+plan finds them (see alerting), and, with prioritize_plans, have their images
+put first in cleanup plans.
+"""
+
+from typing import Dict, List, TypedDict
+
+from utils.cleanup_plan import PlanItem
+from utils.image_data_analysis import OwnerUsage
+
+BYTES_PER_GB = 1024**3
+
+
+class OwnerQuotas(TypedDict):
+    """Quota settings from config.yaml."""
+
+    default_bytes: int  # 0 = owners without a quota of their own have none
+    owners: Dict[str, int]  # owner -> quota in bytes
+    prioritize_plans: bool
+
+
+class OwnerQuotaStatus(TypedDict):
+    """An owner's storage measured against its quota."""
+
+    owner: str
+    used_bytes: int  # amortized bytes
+    quota_bytes: int
+    over_bytes: int  # 0 when within quota
+    share: float  # fraction of the registry's storage the owner uses
+
+
+def quota_for(owner: str, quotas: OwnerQuotas) -> int:
+    """Quota of an owner in bytes (0 = none)"""
+    return quotas["owners"].get(owner, quotas["default_bytes"])
+
+
+def check_owner_quotas(usage: List[OwnerUsage], quotas: OwnerQuotas) -> List[OwnerQuotaStatus]:
+    """Measure every owner that has a quota against it.
+
+    Owners with a quota of their own but no images are included with zero
+    usage, so alerts raised for them earlier can be resolved.
+
+    Args:
+        usage: Usage per owner (ImageAnalyzer.owner_usage)
+        quotas: Quota settings
+
+    Returns:
+        One status per owner with a quota, furthest over quota first
+    """
+    used = {entry["owner"]: entry["amortized_bytes"] for entry in usage}
+    total = sum(used.values())
+    for owner in quotas["owners"]:
+        used.setdefault(owner, 0)
+
+    statuses: List[OwnerQuotaStatus] = []
+    for owner, used_bytes in used.items():
+        quota_bytes = quota_for(owner, quotas)
+        if not quota_bytes:
+            continue
+        statuses.append(
+            {
+                "owner": owner,
+                "used_bytes": used_bytes,
+                "quota_bytes": quota_bytes,
+                "over_bytes": max(used_bytes - quota_bytes, 0),
+                "share": round(used_bytes / total, 4) if total else 0.0,
+            }
+        )
+    return sorted(statuses, key=lambda status: (-status["over_bytes"], -status["used_bytes"], status["owner"]))
+
+
+def over_quota_owners(statuses: List[OwnerQuotaStatus]) -> List[str]:
+    """Owners using more than their quota, furthest over first"""
+    return [status["owner"] for status in statuses if status["over_bytes"] > 0]
+
+
+def prioritize_over_quota(
+    items: List[PlanItem], image_owners: Dict[str, str], over_owners: List[str]
+) -> List[PlanItem]:
+    """Put the plan items of owners over quota first.
+
+    Items of the owner furthest over quota come first; the order within each
+    owner, and of the remaining items, is kept. Prioritized items have the
+    owner noted in their reason.
+
+    Args:
+        items: Plan items, in plan order
+        image_owners: image_id -> owner
+        over_owners: Owners over quota, furthest over first
+
+    Returns:
+        The reordered items
+    """
+    rank = {owner: index for index, owner in enumerate(over_owners)}
+    for item in items:
+        owner = image_owners.get(item.image_id)
+        if owner in rank:
+            note = f"owner {owner} over quota"
+            item.reason = f"{item.reason}, {note}" if item.reason else note
+    return sorted(items, key=lambda item: rank.get(image_owners.get(item.image_id, ""), len(rank)))
@@ -0,0 +1,129 @@
+"""
+Read-only mode.
+
+With --read-only (READ_ONLY=true, or security.read_only in config.yaml) no
+command can change the registry, the cluster or MongoDB, so security teams
+can give the scanning role broad credentials without trusting every code
+path to honour --apply. Config cannot turn read-only mode off once the flag
+or the environment variable turned it on.
+
+The mode is enforced twice:
+
+- main.py refuses commands that would change something (anything with
+  --apply, mirroring without --dry-run, restoring or deleting backups,
+  registry garbage collection) before they start.
+- The clients refuse every mutating request themselves, raising
+  ReadOnlyError: skopeo delete and copies or syncs to a registry, HTTP
+  requests other than GET and HEAD, StatefulSet patches and pod execs, ECR
+  deletions and MongoDB writes. A script that reaches one of them, however it
+  was started, fails instead of changing anything.
+"""
+
+from typing import Any, Dict, List, Optional
+
+# Commands that change something unless run with --dry-run
+_DRY_RUN_COMMANDS = ("mirror",)
+
+# Commands that always change something
+_MUTATING_COMMANDS = ("run_registry_gc",)
+
+# MongoDB methods that write
+MONGO_WRITE_METHODS = frozenset(
+    {
+        "insert_one",
+        "insert_many",
+        "update_one",
+        "update_many",
+        "replace_one",
+        "delete_one",
+        "delete_many",
+        "find_one_and_delete",
+        "find_one_and_replace",
+        "find_one_and_update",
+        "bulk_write",
+        "create_index",
+        "create_indexes",
+        "drop",
+        "drop_index",
+        "drop_indexes",
+        "rename",
+        "create_collection",
+        "drop_collection",
+        "drop_database",
+    }
+)
+
+
+class ReadOnlyError(PermissionError):
+    """A mutating operation was attempted in read-only mode."""
+
+
+def ensure_writable(operation: str) -> None:
+    """Raise ReadOnlyError if read-only mode is on.
+
+    Args:
+        operation: What was about to change, for the error message, e.g. "skopeo delete"
+    """
+    # Imported here: the registry clients the config manager imports use this module
+    from utils.config_manager import config_manager
+
+    if config_manager.is_read_only():
+        raise ReadOnlyError(f"Refusing {operation}: read-only mode is on")
+
+
+def command_refusal(command: str, args: List[str]) -> Optional[str]:
+    """Why a command may not run in read-only mode, or None if it only reads"""
+    if "--apply" in args:
+        return f"{command} --apply deletes or changes data"
+    if command in _MUTATING_COMMANDS:
+        return f"{command} changes the registry"
+    if command in _DRY_RUN_COMMANDS and "--dry-run" not in args:
+        return f"{command} copies images to another registry; add --dry-run to see what it would copy"
+    if command == "backup_restore" and ("restore" in args or "delete" in args or "--delete" in args):
+        if "--dry-run" not in args:
+            return "backup_restore restore and delete change the registry; add --dry-run to simulate them"
+    return None
+
+
+class _ReadOnlyProxy:
+    """Wraps a MongoDB client, database or collection, refusing its write methods"""
+
+    def __init__(self, target: Any, name: str):
+        self._target = target
+        self._name = name
+
+    def __getattr__(self, attribute: str) -> Any:
+        if attribute in MONGO_WRITE_METHODS:
+            ensure_writable(f"MongoDB {attribute} on {self._name}")
+        value = getattr(self._target, attribute)
+        if attribute in ("get_database", "get_default_database", "get_collection"):
+            return lambda *args, **kwargs: _read_only(value(*args, **kwargs))
+        if attribute == "aggregate":
+            return self._aggregate
+        return _read_only(value)
+
+    def __getitem__(self, key: str) -> Any:
+        return _read_only(self._target[key])
+
+    def _aggregate(self, pipeline: List[Dict[str, Any]], *args: Any, **kwargs: Any) -> Any:
+        """Run an aggregation, unless a stage writes its results to a collection"""
+        if any("$out" in stage or "$merge" in stage for stage in pipeline):
+            ensure_writable(f"MongoDB aggregation writing to a collection from {self._name}")
+        return self._target.aggregate(pipeline, *args, **kwargs)
+
+
+def _read_only(value: Any) -> Any:
+    """A database or collection wrapped to refuse writes; other values as they are"""
+    from pymongo.collection import Collection
+    from pymongo.database import Database
+
+    if isinstance(value, Collection):
+        return _ReadOnlyProxy(value, value.full_name)
+    if isinstance(value, Database):
+        return _ReadOnlyProxy(value, value.name)
+    return value
+
+
+def read_only_mongo_client(client: Any) -> Any:
+    """A MongoClient whose databases and collections refuse writes"""
+    return _ReadOnlyProxy(client, "MongoDB")
@@ -0,0 +1,54 @@
+"""
+Redaction of sensitive image config fields.
+
+Image config labels and Env values often hold credentials or internal URLs
+(API_TOKEN=..., com.example.db-url=...). Every label or variable whose key
+matches one of the shell-style patterns in reports.redact_keys (matched without
+regard to case) has its value replaced by REDACTED before it is stored with
+the scan, so it never reaches reports, snapshots or exports:
+
+    reports:
+      redact_keys: ["*password*", "*secret*", "*token*", "*_url"]
+"""
+
+from fnmatch import fnmatchcase
+from typing import Dict, Iterable, List, Optional
+
+REDACTED = "[REDACTED]"
+
+# Keys redacted unless reports.redact_keys says otherwise
+DEFAULT_REDACT_KEYS = [
+    "*password*",
+    "*passwd*",
+    "*secret*",
+    "*token*",
+    "*api_key*",
+    "*apikey*",
+    "*access_key*",
+    "*private_key*",
+    "*credential*",
+]
+
+
+def is_sensitive_key(key: str, patterns: Iterable[str]) -> bool:
+    """Whether a label or variable name matches any redaction pattern (case-insensitive)"""
+    key = key.lower()
+    return any(fnmatchcase(key, pattern.lower()) for pattern in patterns)
+
+
+def redact_labels(labels: Optional[Dict[str, str]], patterns: List[str]) -> Optional[Dict[str, str]]:
+    """Labels with the values of sensitive keys replaced by REDACTED (None stays None)"""
+    if labels is None:
+        return None
+    return {key: REDACTED if is_sensitive_key(key, patterns) else value for key, value in labels.items()}
+
+
+def redact_env(env: Optional[List[str]], patterns: List[str]) -> Optional[List[str]]:
+    """Image config Env entries (NAME=value) with the values of sensitive names replaced by REDACTED"""
+    if env is None:
+        return None
+    redacted = []
+    for entry in env:
+        name, separator, _ = entry.partition("=")
+        redacted.append(f"{name}={REDACTED}" if separator and is_sensitive_key(name, patterns) else entry)
+    return redacted
@@ -0,0 +1,170 @@
+"""
+Direct MongoDB reference scan.
+
+The usage pipelines in extract_metadata cover the places Domino workloads take
+their environment from: runs, workspaces, models, projects, scheduler jobs,
+organizations and app versions. Domino keeps other references to environment
+images that no workload currently shows, such as the active revision of every
+environment, which users launching with the environment's default get.
+
+For admins with database access, this module reads such fields directly and
+resolves them to Docker tags, so they can be merged into the protection set
+(mongo.reference_scan in config.yaml). Each field is read from one collection
+and holds one of:
+
+    revision_id         An environment_revisions _id
+    environment_id      An environments_v2 _id; the environment's active revision is referenced
+    tag                 A Docker image tag
+"""
+
+from typing import Any, Dict, Iterable, List, Optional, Tuple, TypedDict
+
+from utils.logging_utils import get_logger
+from utils.object_id_utils import normalize_object_id
+
+logger = get_logger(__name__)
+
+# Reference kinds
+REVISION_ID = "revision_id"
+ENVIRONMENT_ID = "environment_id"
+TAG = "tag"
+REFERENCE_KINDS = (REVISION_ID, ENVIRONMENT_ID, TAG)
+
+
+class ReferenceField(TypedDict, total=False):
+    """A MongoDB field that references environment images."""
+
+    collection: str
+    field: str  # Dotted path; lists along the path are searched element by element
+    kind: str  # One of REFERENCE_KINDS
+    filter: Dict[str, Any]  # Documents to read (default: all)
+
+
+class DirectReference(TypedDict):
+    """A document field referencing a Docker tag."""
+
+    collection: str
+    field: str
+    document_id: str
+    environment_docker_tag: str
+
+
+# Fields scanned whenever the reference scan is enabled
+DEFAULT_REFERENCE_FIELDS: List[ReferenceField] = [
+    # The revision users get when they pick an environment without choosing a revision
+    {
+        "collection": "environments_v2",
+        "field": "activeRevisionId",
+        "kind": REVISION_ID,
+        "filter": {"isArchived": False},
+    },
+    # The environment new versions of a model are built on
+    {"collection": "models", "field": "environmentId", "kind": ENVIRONMENT_ID, "filter": {"isArchived": False}},
+]
+
+
+def field_values(document: Dict[str, Any], path: str) -> List[Any]:
+    """Values at a dotted path of a document, searching lists along the path.
+
+    Args:
+        document: MongoDB document
+        path: Dotted field path (e.g. "jobDataPlain.overrideEnvironmentId")
+
+    Returns:
+        Non-empty values found, lists flattened
+    """
+    values: List[Any] = [document]
+    for key in path.split("."):
+        next_values: List[Any] = []
+        for value in values:
+            items = value if isinstance(value, list) else [value]
+            next_values.extend(item[key] for item in items if isinstance(item, dict) and key in item)
+        values = next_values
+    flattened: List[Any] = []
+    for value in values:
+        flattened.extend(value if isinstance(value, list) else [value])
+    return [value for value in flattened if value not in (None, "")]
+
+
+def resolve_references(
+    raw_references: Iterable[Tuple[ReferenceField, str, Any]],
+    revision_tags: Dict[str, str],
+    active_revisions: Dict[str, str],
+) -> List[DirectReference]:
+    """Resolve raw field values to the Docker tags they reference.
+
+    Args:
+        raw_references: (field, document_id, value) read from MongoDB
+        revision_tags: environment_revisions _id -> Docker tag
+        active_revisions: environments_v2 _id -> active revision _id
+
+    Returns:
+        References to Docker tags; values that resolve to no tag are left out
+    """
+    references: List[DirectReference] = []
+    for field, document_id, value in raw_references:
+        kind = field["kind"]
+        if kind == TAG:
+            tag: Optional[str] = str(value)
+        elif kind == REVISION_ID:
+            tag = revision_tags.get(normalize_object_id(value))
+        else:
+            tag = revision_tags.get(active_revisions.get(normalize_object_id(value), ""))
+        if tag:
+            references.append(
+                {
+                    "collection": field["collection"],
+                    "field": field["field"],
+                    "document_id": document_id,
+                    "environment_docker_tag": tag,
+                }
+            )
+    return references
+
+
+def scan_references(db: Any, fields: List[ReferenceField]) -> List[DirectReference]:
+    """Read reference fields from MongoDB and resolve them to Docker tags.
+
+    Args:
+        db: pymongo database
+        fields: Fields to read (see DEFAULT_REFERENCE_FIELDS)
+
+    Returns:
+        References to Docker tags
+    """
+    existing_collections = set(db.list_collection_names())
+    raw_references: List[Tuple[ReferenceField, str, Any]] = []
+    for field in fields:
+        if field["collection"] not in existing_collections:
+            logger.info(f"Skipping reference field {field['collection']}.{field['field']} (no such collection)")
+            continue
+        query = dict(field.get("filter") or {})
+        query[field["field"]] = {"$exists": True, "$ne": None}
+        for document in db[field["collection"]].find(query, {field["field"]: 1}):
+            for value in field_values(document, field["field"]):
+                raw_references.append((field, normalize_object_id(document.get("_id")), value))
+
+    environment_ids = [value for field, _, value in raw_references if field["kind"] == ENVIRONMENT_ID]
+    revision_ids = [value for field, _, value in raw_references if field["kind"] == REVISION_ID]
+    active_revisions: Dict[str, str] = {}
+    if environment_ids:
+        for env_doc in db.environments_v2.find(
+            {"_id": {"$in": environment_ids}, "activeRevisionId": {"$exists": True, "$ne": None}},
+            {"_id": 1, "activeRevisionId": 1},
+        ):
+            active_revisions[normalize_object_id(env_doc["_id"])] = normalize_object_id(env_doc["activeRevisionId"])
+            revision_ids.append(env_doc["activeRevisionId"])
+
+    revision_tags: Dict[str, str] = {}
+    if revision_ids:
+        for rev_doc in db.environment_revisions.find(
+            {"_id": {"$in": revision_ids}}, {"_id": 1, "metadata.dockerImageName.tag": 1}
+        ):
+            tag = (rev_doc.get("metadata") or {}).get("dockerImageName", {}).get("tag")
+            if tag:
+                revision_tags[normalize_object_id(rev_doc["_id"])] = tag
+
+    references = resolve_references(raw_references, revision_tags, active_revisions)
+    tags = {reference["environment_docker_tag"] for reference in references}
+    logger.info(f"Reference scan found {len(references)} reference(s) to {len(tags)} tag(s)")
+    return references
@@ -0,0 +1,195 @@
+"""
+Comparison of two registries.
+
+Compares the tags of the same repositories in two registries, such as a primary
+registry and its pull-through mirror, or a registry and the copy it was migrated
+to. For every repository it reports the tags only one registry has, and the tags
+both have but that point to different manifest digests. A tag whose digest could
+not be read in one of the registries is reported as unreadable rather than as a
+mismatch.
+
+A pull-through mirror only holds what has been pulled through it, so for a mirror
+the tags missing from it are expected; mismatched digests are what matter, since
+they mean the mirror serves stale content.
+
+Before a cleanup, compare_plan checks a backup registry against a cleanup plan
+instead: every image the plan deletes must be in the backup at the digest the
+plan recorded, or it is reported as missing or mismatched.
+"""
+
+import concurrent.futures
+from typing import Any, Dict, Iterable, List, Optional, TypedDict
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+# Repository statuses
+MATCH = "match"
+DIFFERS = "differs"
+ONLY_IN_A = "only_in_a"
+ONLY_IN_B = "only_in_b"
+EMPTY = "empty"
+
+
+class DigestMismatch(TypedDict):
+    """A tag that points to different manifests in the two registries."""
+
+    tag: str
+    digest_a: str
+    digest_b: str
+
+
+class RepositoryComparison(TypedDict):
+    """Differences between one repository in two registries."""
+
+    repository: str
+    status: str
+    tags_a: int
+    tags_b: int
+    tags_only_in_a: List[str]
+    tags_only_in_b: List[str]
+    digest_mismatches: List[DigestMismatch]
+    unreadable_tags: List[str]
+
+
+def compare_tags(
+    repository: str,
+    tags_a: Iterable[str],
+    tags_b: Iterable[str],
+    digests_a: Optional[Dict[str, Optional[str]]] = None,
+    digests_b: Optional[Dict[str, Optional[str]]] = None,
+) -> RepositoryComparison:
+    """Compare the tags of one repository in two registries.
+
+    Args:
+        repository: Repository name
+        tags_a: Tags in registry A
+        tags_b: Tags in registry B
+        digests_a: Digest of each common tag in registry A (None: digests not compared)
+        digests_b: Digest of each common tag in registry B
+
+    Returns:
+        The comparison; its status is "match" only if both registries have the
+        same tags and no common tag differs or is unreadable
+    """
+    set_a, set_b = set(tags_a), set(tags_b)
+    common = sorted(set_a & set_b)
+    mismatches: List[DigestMismatch] = []
+    unreadable: List[str] = []
+    if digests_a is not None and digests_b is not None:
+        for tag in common:
+            digest_a, digest_b = digests_a.get(tag), digests_b.get(tag)
+            if not digest_a or not digest_b:
+                unreadable.append(tag)
+            elif digest_a != digest_b:
+                mismatches.append({"tag": tag, "digest_a": digest_a, "digest_b": digest_b})
+
+    if not set_a and not set_b:
+        status = EMPTY
+    elif not set_b:
+        status = ONLY_IN_A
+    elif not set_a:
+        status = ONLY_IN_B
+    elif set_a != set_b or mismatches or unreadable:
+        status = DIFFERS
+    else:
+        status = MATCH
+    return {
+        "repository": repository,
+        "status": status,
+        "tags_a": len(set_a),
+        "tags_b": len(set_b),
+        "tags_only_in_a": sorted(set_a - set_b),
+        "tags_only_in_b": sorted(set_b - set_a),
+        "digest_mismatches": mismatches,
+        "unreadable_tags": unreadable,
+    }
+
+
+def _tag_digests(client: Any, repository: str, tags: List[str], max_workers: int) -> Dict[str, Optional[str]]:
+    """Current manifest digest of each tag, None where it could not be read"""
+    digests: Dict[str, Optional[str]] = {}
+    with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+        future_to_tag = {executor.submit(client.get_manifest_digest, repository, tag): tag for tag in tags}
+        for future in concurrent.futures.as_completed(future_to_tag):
+            tag = future_to_tag[future]
+            try:
+                digests[tag] = future.result()
+            except Exception as e:
+                logger.debug(f"Manifest lookup for {repository}:{tag} failed: {e}")
+                digests[tag] = None
+    return digests
+
+
+def compare_repository(
+    client_a: Any, client_b: Any, repository: str, max_workers: int, compare_digests: bool = True
+) -> RepositoryComparison:
+    """Compare one repository in two registries.
+
+    Args:
+        client_a: SkopeoClient of registry A
+        client_b: SkopeoClient of registry B
+        repository: Repository name (e.g. "dominodatalab/environment")
+        max_workers: Parallel manifest requests per registry
+        compare_digests: Also compare the digests of the tags both registries have
+    """
+    tags_a = client_a.list_tags(repository)
+    tags_b = client_b.list_tags(repository)
+    if not compare_digests:
+        return compare_tags(repository, tags_a, tags_b)
+    common = sorted(set(tags_a) & set(tags_b))
+    return compare_tags(
+        repository,
+        tags_a,
+        tags_b,
+        _tag_digests(client_a, repository, common, max_workers),
+        _tag_digests(client_b, repository, common, max_workers),
+    )
+
+
+def compare_plan(
+    client_b: Any, items: Iterable[Any], max_workers: int, compare_digests: bool = True
+) -> List[RepositoryComparison]:
+    """Compare the images of a cleanup plan (A) with a backup registry (B).
+
+    Only the planned tags are compared, so the tags B has beyond them are not
+    reported. The plan's digests stand in for registry A, which is not queried.
+
+    Args:
+        client_b: SkopeoClient of the backup registry
+        items: PlanItems of the plan
+        max_workers: Parallel manifest requests
+        compare_digests: Also compare the digests of the tags the backup has
+
+    Returns:
+        One comparison per planned repository, in repository order
+    """
+    planned: Dict[str, Dict[str, str]] = {}
+    for item in items:
+        planned.setdefault(item.repository, {})[item.tag] = item.digest
+
+    comparisons = []
+    for repository, digests_a in sorted(planned.items()):
+        tags_b = [tag for tag in client_b.list_tags(repository) if tag in digests_a]
+        if compare_digests:
+            digests_b = _tag_digests(client_b, repository, sorted(tags_b), max_workers)
+            comparisons.append(compare_tags(repository, digests_a, tags_b, dict(digests_a), digests_b))
+        else:
+            comparisons.append(compare_tags(repository, digests_a, tags_b))
+    return comparisons
+
+
+def summarize(comparisons: List[RepositoryComparison]) -> Dict[str, Any]:
+    """Totals over the compared repositories"""
+    return {
+        "repositories": len(comparisons),
+        "repositories_matching": sum(1 for c in comparisons if c["status"] in (MATCH, EMPTY)),
+        "repositories_only_in_a": sum(1 for c in comparisons if c["status"] == ONLY_IN_A),
+        "repositories_only_in_b": sum(1 for c in comparisons if c["status"] == ONLY_IN_B),
+        "tags_only_in_a": sum(len(c["tags_only_in_a"]) for c in comparisons),
+        "tags_only_in_b": sum(len(c["tags_only_in_b"]) for c in comparisons),
+        "digest_mismatches": sum(len(c["digest_mismatches"]) for c in comparisons),
+        "unreadable_tags": sum(len(c["unreadable_tags"]) for c in comparisons),
+        "identical": all(c["status"] in (MATCH, EMPTY) for c in comparisons),
+    }
@@ -0,0 +1,236 @@
+"""
+Registry notification listener.
+
+A self-hosted Docker distribution registry can notify an HTTP endpoint of every
+push and delete (notifications.endpoints in the registry's config.yml). The
+backend API server accepts those notifications on POST /api/registry-events and,
+with registry_events.enabled in config.yaml, keeps an image index up to date
+from them instead of rescanning the registry:
+
+    registry_events:
+      enabled: true
+      image_types: ["environment", "model"]  # default: the profile's image types
+      snapshot_interval_minutes: 15
+
+On startup, the listener scans the configured image types once. After that,
+each manifest push with a tag inspects that one tag, and each manifest delete
+drops the deleted tag, or every tag pointing to the deleted digest, from the
+index. Events received while the initial scan runs are applied once it ends.
+Blob events, pulls, cross-repository mounts and events for other repositories
+are ignored.
+
+Whenever the index changed, a scan snapshot (see utils/scan_snapshot.py) is
+saved at most every snapshot_interval_minutes, so policies and plans can run
+against a current snapshot without a full rescan:
+
+    plan --policy policy.yaml --snapshot reports/scan-snapshot-<timestamp>.json
+"""
+
+import queue
+import threading
+import time
+from datetime import datetime, timezone
+from typing import TYPE_CHECKING, Any, Callable, List, Optional, TypedDict
+
+from utils.logging_utils import get_logger
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+logger = get_logger(__name__)
+
+# Content type of Docker distribution notification envelopes
+EVENTS_MEDIA_TYPE = "application/vnd.docker.distribution.events.v1+json"
+
+ACTION_PUSH = "push"
+ACTION_DELETE = "delete"
+
+# Manifest media types; pushes of other targets (layers, configs) are blob uploads
+MANIFEST_MEDIA_TYPES = {
+    "application/vnd.docker.distribution.manifest.v1+json",
+    "application/vnd.docker.distribution.manifest.v1+prettyjws",
+    "application/vnd.docker.distribution.manifest.v2+json",
+    "application/vnd.docker.distribution.manifest.list.v2+json",
+    "application/vnd.oci.image.manifest.v1+json",
+    "application/vnd.oci.image.index.v1+json",
+}
+
+
+class RegistryEvent(TypedDict):
+    """A manifest push or delete reported by the registry."""
+
+    action: str  # ACTION_PUSH or ACTION_DELETE
+    repository: str
+    tag: Optional[str]  # None for pushes and deletes by digest
+    digest: str
+
+
+class ListenerStatus(TypedDict):
+    """State of the listener, as GET /api/registry-events reports it."""
+
+    state: str  # "scanning", "ready" or "failed"
+    images: int
+    events_received: int
+    events_applied: int
+    events_failed: int
+    last_event_at: Optional[str]
+    last_snapshot: Optional[str]
+
+
+def parse_events(envelope: Any) -> List[RegistryEvent]:
+    """Extract manifest pushes and deletes from a notification envelope ({"events": [...]})"""
+    events: List[RegistryEvent] = []
+    if not isinstance(envelope, dict) or not isinstance(envelope.get("events"), list):
+        raise ValueError('Notification envelope must be a JSON object with an "events" list')
+    for event in envelope["events"]:
+        target = (event or {}).get("target") or {}
+        action = event.get("action")
+        repository, digest = target.get("repository"), target.get("digest")
+        if action not in (ACTION_PUSH, ACTION_DELETE) or not repository or not digest:
+            continue
+        # Delete events carry no media type; push events of blobs are not manifests
+        if action == ACTION_PUSH and target.get("mediaType") not in MANIFEST_MEDIA_TYPES:
+            continue
+        events.append({"action": action, "repository": repository, "tag": target.get("tag") or None, "digest": digest})
+    return events
+
+
+def image_type_of(repository: str, base_repository: str) -> Optional[str]:
+    """The image type of a repository under the configured one ("dominodatalab/environment" -> "environment")"""
+    prefix = f"{base_repository}/"
+    if not repository.startswith(prefix) or "/" in repository[len(prefix) :]:
+        return None
+    return repository[len(prefix) :]
+
+
+class RegistryEventListener:
+    """Keeps an analyzer's index up to date from registry notifications.
+
+    Events are queued by submit() and applied one at a time by a background
+    thread, so a notification request returns as soon as its events are queued.
+    """
+
+    def __init__(
+        self,
+        analyzer: "ImageAnalyzer",
+        image_types: List[str],
+        snapshot_interval_seconds: float = 900,
+        save_snapshot: Optional[Callable[["ImageAnalyzer"], str]] = None,
+    ) -> None:
+        self.analyzer = analyzer
+        self.image_types = list(image_types)
+        self.snapshot_interval_seconds = snapshot_interval_seconds
+        self._save_snapshot = save_snapshot
+        self._queue: "queue.Queue[RegistryEvent]" = queue.Queue()
+        self._lock = threading.Lock()
+        self._dirty = False
+        self._last_snapshot_at = 0.0
+        self._status: ListenerStatus = {
+            "state": "scanning",
+            "images": 0,
+            "events_received": 0,
+            "events_applied": 0,
+            "events_failed": 0,
+            "last_event_at": None,
+            "last_snapshot": None,
+        }
+
+    def submit(self, envelope: Any) -> int:
+        """Queue the relevant events of a notification envelope, returning how many were queued.
+
+        Raises:
+            ValueError: If the envelope is malformed
+        """
+        events = [
+            event
+            for event in parse_events(envelope)
+            if image_type_of(event["repository"], self.analyzer.repository) in self.image_types
+        ]
+        with self._lock:
+            self._status["events_received"] += len(events)
+        for event in events:
+            self._queue.put(event)
+        return len(events)
+
+    def status(self) -> ListenerStatus:
+        """Current state and counters"""
+        with self._lock:
+            status = dict(self._status)
+        status["images"] = len(self.analyzer.images)
+        return status  # type: ignore[return-value]
+
+    def apply(self, event: RegistryEvent) -> bool:
+        """Apply one event to the index.
+
+        Returns:
+            True if the index changed
+        """
+        image_type = image_type_of(event["repository"], self.analyzer.repository)
+        if image_type is None:
+            return False
+        if event["action"] == ACTION_PUSH:
+            # Pushes by digest alone add no tag, so there is nothing to index
+            if not event["tag"] or not self.analyzer.is_scanned_tag(event["tag"]):
+                return False
+            if not self.analyzer.refresh_tag(image_type, event["tag"]):
+                raise RuntimeError(f"Could not inspect pushed tag {event['repository']}:{event['tag']}")
+            logger.info(f"Indexed push of {event['repository']}:{event['tag']} ({event['digest']})")
+            return True
+
+        if event["tag"]:
+            image_ids = [f"{image_type}:{event['tag']}"]
+        else:
+            image_ids = [
+                image_id
+                for image_id, image_data in self.analyzer.images.items()
+                if image_data["repository"] == event["repository"] and image_data["digest"] == event["digest"]
+            ]
+        removed = self.analyzer.forget_images(image_ids)
+        if removed:
+            logger.info(f"Removed {removed} deleted tag(s) of {event['repository']}@{event['digest']} from the index")
+        return removed > 0
+
+    def maybe_save_snapshot(self, force: bool = False) -> Optional[str]:
+        """Save a snapshot if the index changed and the snapshot interval has passed"""
+        if self._save_snapshot is None or not self._dirty:
+            return None
+        if not force and time.monotonic() - self._last_snapshot_at < self.snapshot_interval_seconds:
+            return None
+        path = self._save_snapshot(self.analyzer)
+        self._dirty = False
+        self._last_snapshot_at = time.monotonic()
+        with self._lock:
+            self._status["last_snapshot"] = path
+        return path
+
+    def run(self, poll_seconds: float = 5) -> None:
+        """Scan once, then apply queued events for the lifetime of the process"""
+        try:
+            self.analyzer.analyze_images(self.image_types)
+        except Exception as e:
+            logger.error(f"Initial scan for registry events failed, events are not applied: {e}")
+            with self._lock:
+                self._status["state"] = "failed"
+            return
+        self._dirty = True
+        self.maybe_save_snapshot(force=True)
+        with self._lock:
+            self._status["state"] = "ready"
+        logger.info(f"Registry event listener ready: {len(self.analyzer.images)} images indexed")
+
+        while True:
+            try:
+                event = self._queue.get(timeout=poll_seconds)
+            except queue.Empty:
+                self.maybe_save_snapshot()
+                continue
+            try:
+                self._dirty = self.apply(event) or self._dirty
+                outcome = "events_applied"
+            except Exception as e:
+                logger.error(f"Could not apply registry {event['action']} event for {event['repository']}: {e}")
+                outcome = "events_failed"
+            with self._lock:
+                self._status[outcome] += 1  # type: ignore[literal-required]
+                self._status["last_event_at"] = datetime.now(timezone.utc).isoformat()
+            self.maybe_save_snapshot()
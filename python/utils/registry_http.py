@@ -0,0 +1,529 @@
+"""
+Minimal HTTP client for the Docker Registry v2 API.
+
+Skopeo is the default client for registry work; with skopeo.client: native,
+tags are listed, manifests and configs read and manifests deleted with this
+client instead (see utils/native_registry.py). This module also covers
+requests that skopeo cannot make cheaply or at all, such as a manifest HEAD
+request that returns a tag's current digest in the Docker-Content-Digest header
+without downloading anything or spawning a process, a referrers listing, the
+repository catalog, reading a small blob such as an attestation, streaming a
+layer blob for a contents scan, reading Docker Content Trust data from a
+Notary server, or copying an image between repositories of the same registry
+by mounting its blobs (see utils/blob_mount.py).
+
+Authentication follows the registry's WWW-Authenticate challenge: Basic auth is
+sent directly, and Bearer challenges are answered by fetching a token from the
+advertised realm using the same credentials.
+"""
+
+import base64
+import contextlib
+import json
+import logging
+import os
+import re
+import ssl
+import threading
+import urllib.error
+import urllib.parse
+import urllib.request
+from typing import IO, Any, Callable, Dict, Iterator, List, Optional, Tuple, TypeVar
+
+from utils.circuit_breaker import CircuitBreaker, is_overload_status
+from utils.host_limits import HostLimiter, host_slot
+from utils.read_only import ensure_writable
+
+T = TypeVar("T")
+
+MANIFEST_ACCEPT = ", ".join(
+    [
+        "application/vnd.docker.distribution.manifest.v2+json",
+        "application/vnd.docker.distribution.manifest.list.v2+json",
+        "application/vnd.oci.image.manifest.v1+json",
+        "application/vnd.oci.image.index.v1+json",
+    ]
+)
+
+
+def read_auth_file_credentials(auth_file: str, registry: str) -> Optional[Tuple[str, str]]:
+    """Read credentials for a registry from a containers auth.json file.
+
+    This is the file skopeo login (and the ECR/ACR helpers) write to.
+
+    Returns:
+        (username, password), or None if the file has no entry for the registry
+    """
+    try:
+        with open(auth_file, "r") as f:
+            auths = json.load(f).get("auths", {})
+    except (OSError, ValueError, AttributeError):
+        return None
+
+    entry = auths.get(registry) or auths.get(registry.split("/")[0])
+    if not entry or not entry.get("auth"):
+        return None
+    try:
+        username, _, password = base64.b64decode(entry["auth"]).decode("utf-8").partition(":")
+    except (ValueError, UnicodeDecodeError):
+        return None
+    return username, password
+
+
+def write_auth_file_credentials(auth_file: str, registry: str, username: str, password: str) -> None:
+    """Store credentials for a registry in a containers auth.json file, as skopeo login does.
+
+    Entries for other registries are kept. The file is only readable by its owner.
+    """
+    try:
+        with open(auth_file, "r") as f:
+            document = json.load(f)
+    except (OSError, ValueError):
+        document = {}
+    if not isinstance(document, dict) or not isinstance(document.get("auths"), dict):
+        document = {"auths": {}}
+    token = base64.b64encode(f"{username}:{password}".encode("utf-8")).decode("ascii")
+    document["auths"][registry] = {"auth": token}
+    descriptor = os.open(auth_file, os.O_WRONLY | os.O_CREAT | os.O_TRUNC, 0o600)
+    with os.fdopen(descriptor, "w") as f:
+        json.dump(document, f, indent=2)
+
+
+def _parse_challenge(header: str) -> Tuple[str, Dict[str, str]]:
+    """Parse a WWW-Authenticate header into (scheme, params)."""
+    scheme, _, rest = header.partition(" ")
+    params = dict(re.findall(r'(\w+)="([^"]*)"', rest))
+    return scheme.lower(), params
+
+
+class RegistryHttpClient:
+    """Registry v2 API client for lightweight metadata requests."""
+
+    def __init__(
+        self,
+        registry_url: str,
+        username: Optional[str] = None,
+        password: Optional[str] = None,
+        verify_tls: bool = False,
+        timeout: int = 30,
+        circuit_breaker: Optional[CircuitBreaker] = None,
+        host_limiter: Optional[HostLimiter] = None,
+    ):
+        """Initialize the client
+
+        Args:
+            registry_url: Registry host[:port], optionally with an http(s):// scheme
+            username: Registry username (optional for anonymous registries)
+            password: Registry password or token
+            verify_tls: Verify TLS certificates (skopeo is run with --tls-verify=false)
+            timeout: Request timeout in seconds
+            circuit_breaker: Breaker that pauses requests while the registry keeps failing
+            host_limiter: Limit on requests in flight to the registry host
+        """
+        if "://" in registry_url:
+            scheme, _, host = registry_url.partition("://")
+            self._schemes = [scheme]
+        else:
+            host = registry_url
+            self._schemes = ["https", "http"]
+        self.host = host.rstrip("/")
+        self.username = username
+        self.password = password
+        self.timeout = timeout
+        self._ssl_context = None if verify_tls else ssl._create_unverified_context()
+        self._tokens: Dict[str, str] = {}
+        self._lock = threading.Lock()
+        self._circuit_breaker = circuit_breaker
+        self._host_limiter = host_limiter
+
+    def _basic_auth(self) -> Optional[str]:
+        """Basic Authorization header value, or None without credentials."""
+        if not self.username:
+            return None
+        credentials = f"{self.username}:{self.password or ''}".encode("utf-8")
+        return "Basic " + base64.b64encode(credentials).decode("ascii")
+
+    def _fetch_token(self, params: Dict[str, str], scope: str) -> Optional[str]:
+        """Fetch a Bearer token for a scope from the challenge's realm."""
+        realm = params.get("realm")
+        if not realm:
+            return None
+        # Several space-separated scopes (e.g. for mounting a blob) are requested as repeated scope parameters
+        query = [("scope", item) for item in (params.get("scope") or scope).split()]
+        if params.get("service"):
+            query.append(("service", params["service"]))
+        request = urllib.request.Request(f"{realm}?{urllib.parse.urlencode(query)}")
+        basic = self._basic_auth()
+        if basic:
+            request.add_header("Authorization", basic)
+        with urllib.request.urlopen(request, timeout=self.timeout, context=self._ssl_context) as response:
+            body = json.loads(response.read().decode("utf-8"))
+        return body.get("token") or body.get("access_token")
+
+    @contextlib.contextmanager
+    def _request(
+        self, method: str, path: str, scope: str, headers: Dict[str, str], data: Optional[bytes] = None
+    ) -> Iterator[Any]:
+        """Send a request and hold its response open, within the host's concurrency limit.
+
+        Yields:
+            The HTTP response; raises urllib.error.HTTPError for error statuses,
+            and ReadOnlyError for requests other than GET and HEAD in read-only mode
+        """
+        if method not in ("GET", "HEAD"):
+            ensure_writable(f"{method} {path}")
+        with host_slot(self._host_limiter):
+            with self._open(method, path, scope, headers, data) as response:
+                yield response
+
+    def _open(self, method: str, path: str, scope: str, headers: Dict[str, str], data: Optional[bytes] = None):
+        """Send a request, trying https then http unless the scheme is known.
+
+        Returns:
+            The HTTP response; raises urllib.error.HTTPError for error statuses
+        """
+        last_error: Optional[Exception] = None
+        for scheme in list(self._schemes):
+            try:
+                response = self._request_authenticated(
+                    method, f"{scheme}://{self.host}{path}", scope, headers, data
+                )
+            except urllib.error.HTTPError:
+                # The registry answered, so this is the right scheme
+                self._schemes = [scheme]
+                raise
+            except (urllib.error.URLError, ssl.SSLError, ConnectionError) as e:
+                last_error = e
+                continue
+            self._schemes = [scheme]
+            return response
+        raise last_error or urllib.error.URLError(f"Could not reach {self.host}")
+
+    def _request_authenticated(
+        self, method: str, url: str, scope: str, headers: Dict[str, str], data: Optional[bytes] = None
+    ):
+        """Send a request, answering one authentication challenge if needed."""
+        with self._lock:
+            authorization = self._tokens.get(scope)
+        try:
+            return self._send(method, url, headers, authorization, data)
+        except urllib.error.HTTPError as e:
+            if e.code != 401:
+                raise
+            auth_scheme, params = _parse_challenge(e.headers.get("WWW-Authenticate", ""))
+            if auth_scheme == "bearer":
+                token = self._fetch_token(params, scope)
+                authorization = f"Bearer {token}" if token else None
+            else:
+                authorization = self._basic_auth()
+            if not authorization:
+                raise
+            with self._lock:
+                self._tokens[scope] = authorization
+            return self._send(method, url, headers, authorization, data)
+
+    def _send(
+        self,
+        method: str,
+        url: str,
+        headers: Dict[str, str],
+        authorization: Optional[str],
+        data: Optional[bytes] = None,
+    ):
+        """Send a single request, reporting its outcome to the circuit breaker."""
+        request = urllib.request.Request(url, data=data, method=method, headers=dict(headers))
+        if authorization:
+            request.add_header("Authorization", authorization)
+        context = self._ssl_context if url.startswith("https://") else None
+        breaker = self._circuit_breaker
+        if breaker is None:
+            return urllib.request.urlopen(request, timeout=self.timeout, context=context)
+
+        breaker.before_request()
+        try:
+            response = urllib.request.urlopen(request, timeout=self.timeout, context=context)
+        except urllib.error.HTTPError as e:
+            if is_overload_status(e.code):
+                breaker.record_failure()
+            else:
+                breaker.record_success()
+            raise
+        except TimeoutError:
+            breaker.record_failure()
+            raise
+        except urllib.error.URLError as e:
+            if isinstance(e.reason, TimeoutError):
+                breaker.record_failure()
+            raise
+        breaker.record_success()
+        return response
+
+    def head_manifest_digest(self, repository: str, tag: str) -> Optional[str]:
+        """Get the digest a tag points to with a manifest HEAD request.
+
+        Returns:
+            The Docker-Content-Digest header value, or None if the tag does not
+            exist or the registry did not return the header
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/manifests/{urllib.parse.quote(tag)}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("HEAD", path, scope, {"Accept": MANIFEST_ACCEPT}) as response:
+                return response.headers.get("Docker-Content-Digest")
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            logging.debug(f"Manifest HEAD for {repository}:{tag} failed with HTTP {e.code}")
+            raise
+
+    def head_blob_size(self, repository: str, digest: str) -> Optional[int]:
+        """Get the size of a blob with a blob HEAD request.
+
+        Returns:
+            The Content-Length of the blob, or None if it does not exist or the
+            registry did not return the header
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/blobs/{digest}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("HEAD", path, scope, {}) as response:
+                length = response.headers.get("Content-Length")
+                return int(length) if length is not None else None
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            logging.debug(f"Blob HEAD for {repository}@{digest} failed with HTTP {e.code}")
+            raise
+
+    def get_referrers(self, repository: str, digest: str) -> Optional[List[Dict[str, Any]]]:
+        """List the artifacts that refer to a manifest with the OCI referrers API.
+
+        Returns:
+            The descriptors of the referrers index, or None if the registry does
+            not support the referrers API
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/referrers/{digest}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("GET", path, scope, {"Accept": "application/vnd.oci.image.index.v1+json"}) as response:
+                index = json.loads(response.read().decode("utf-8"))
+        except urllib.error.HTTPError as e:
+            if e.code in (400, 404, 405):
+                return None
+            logging.debug(f"Referrers request for {repository}@{digest} failed with HTTP {e.code}")
+            raise
+        except ValueError:
+            return None
+        manifests = index.get("manifests") if isinstance(index, dict) else None
+        return manifests if isinstance(manifests, list) else []
+
+    def get_blob(self, repository: str, digest: str, max_bytes: int) -> Optional[bytes]:
+        """Download a small blob.
+
+        Returns:
+            The blob content, or None if it does not exist or is larger than max_bytes
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/blobs/{digest}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("GET", path, scope, {}) as response:
+                content = response.read(max_bytes + 1)
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            logging.debug(f"Blob GET for {repository}@{digest} failed with HTTP {e.code}")
+            raise
+        if len(content) > max_bytes:
+            logging.warning(f"Blob {digest} in {repository} is larger than {max_bytes} bytes, not reading it")
+            return None
+        return content
+
+    def read_blob(self, repository: str, digest: str, reader: Callable[[IO[bytes]], T]) -> Optional[T]:
+        """Stream a blob through reader, without holding it in memory.
+
+        Returns:
+            What reader returned, or None if the blob does not exist
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/blobs/{digest}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("GET", path, scope, {}) as response:
+                return reader(response)
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            logging.debug(f"Blob GET for {repository}@{digest} failed with HTTP {e.code}")
+            raise
+
+    def get_manifest(self, repository: str, reference: str) -> Optional[Tuple[bytes, str]]:
+        """Download a manifest exactly as stored, so it can be pushed elsewhere with the same digest.
+
+        Returns:
+            (manifest bytes, media type), or None if the manifest does not exist
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/manifests/{urllib.parse.quote(reference)}"
+        scope = f"repository:{repository}:pull"
+        try:
+            with self._request("GET", path, scope, {"Accept": MANIFEST_ACCEPT}) as response:
+                return response.read(), response.headers.get("Content-Type", "")
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            logging.debug(f"Manifest GET for {repository}@{reference} failed with HTTP {e.code}")
+            raise
+
+    def put_manifest(self, repository: str, reference: str, content: bytes, media_type: str) -> Optional[str]:
+        """Push a manifest under a tag or digest. Every blob it references must already be in the repository.
+
+        Returns:
+            The Docker-Content-Digest of the pushed manifest, if the registry returned it
+
+        Raises:
+            urllib.error.URLError: If the manifest could not be pushed
+        """
+        path = f"/v2/{repository}/manifests/{urllib.parse.quote(reference)}"
+        scope = f"repository:{repository}:pull,push"
+        with self._request("PUT", path, scope, {"Content-Type": media_type}, content) as response:
+            return response.headers.get("Docker-Content-Digest")
+
+    def mount_blob(self, repository: str, digest: str, from_repository: str) -> bool:
+        """Mount a blob of another repository of this registry into a repository, without uploading it.
+
+        Registries that cannot mount the blob (a different storage backend, or no
+        pull access to from_repository) start an upload instead, which is cancelled.
+
+        Returns:
+            True if the blob was mounted
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        query = urllib.parse.urlencode({"mount": digest, "from": from_repository})
+        path = f"/v2/{repository}/blobs/uploads/?{query}"
+        scope = f"repository:{repository}:pull,push repository:{from_repository}:pull"
+        with self._request("POST", path, scope, {"Content-Length": "0"}, b"") as response:
+            if response.status == 201:
+                return True
+            location = response.headers.get("Location")
+        if location:
+            upload = urllib.parse.urlsplit(location)
+            upload_path = f"{upload.path}?{upload.query}" if upload.query else upload.path
+            try:
+                with self._request("DELETE", upload_path, scope, {}):
+                    pass
+            except urllib.error.HTTPError as e:
+                logging.debug(f"Could not cancel the upload to {repository} started by a refused mount: HTTP {e.code}")
+        return False
+
+    def list_tags(self, repository: str, page_size: int = 1000) -> List[str]:
+        """List the tags of a repository, following pagination.
+
+        Raises:
+            urllib.error.HTTPError: If the repository does not exist (404) or the registry refused the request
+            urllib.error.URLError: If the registry could not be reached
+        """
+        tags: List[str] = []
+        path: Optional[str] = f"/v2/{repository}/tags/list?n={page_size}"
+        scope = f"repository:{repository}:pull"
+        while path:
+            with self._request("GET", path, scope, {"Accept": "application/json"}) as response:
+                page = json.loads(response.read().decode("utf-8"))
+                link = response.headers.get("Link") or ""
+            names = page.get("tags") if isinstance(page, dict) else None
+            tags.extend(name for name in names or [] if isinstance(name, str))
+            # Link: </v2/<repository>/tags/list?last=tag&n=1000>; rel="next"
+            match = re.search(r'<([^>]+)>\s*;\s*rel="?next"?', link)
+            next_url = urllib.parse.urlsplit(match.group(1)) if match and names else None
+            path = f"{next_url.path}?{next_url.query}" if next_url else None
+        return tags
+
+    def delete_manifest(self, repository: str, digest: str) -> bool:
+        """Delete a manifest by digest, removing every tag that points to it.
+
+        Returns:
+            True if the manifest was deleted, False if it does not exist
+
+        Raises:
+            urllib.error.HTTPError: If the registry refused the deletion (e.g. 405 when deletion is disabled)
+            urllib.error.URLError: If the registry could not be reached
+        """
+        path = f"/v2/{repository}/manifests/{digest}"
+        scope = f"repository:{repository}:pull,push,delete"
+        try:
+            with self._request("DELETE", path, scope, {}):
+                return True
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return False
+            raise
+
+    def list_repositories(self, page_size: int = 1000) -> Optional[List[str]]:
+        """List the repositories of the registry with the catalog API, following pagination.
+
+        Returns:
+            Repository names, or None if the registry does not offer the catalog
+            API or does not allow these credentials to use it
+
+        Raises:
+            urllib.error.URLError: If the registry could not be reached
+        """
+        repositories: List[str] = []
+        path: Optional[str] = f"/v2/_catalog?n={page_size}"
+        while path:
+            try:
+                with self._request("GET", path, "registry:catalog:*", {"Accept": "application/json"}) as response:
+                    page = json.loads(response.read().decode("utf-8"))
+                    link = response.headers.get("Link") or ""
+            except urllib.error.HTTPError as e:
+                if e.code in (400, 401, 403, 404, 405):
+                    logging.debug(f"Catalog request to {self.host} failed with HTTP {e.code}")
+                    return None
+                raise
+            except ValueError:
+                return None
+            names = page.get("repositories") if isinstance(page, dict) else None
+            repositories.extend(name for name in names or [] if isinstance(name, str))
+            # Link: </v2/_catalog?last=name&n=1000>; rel="next"
+            match = re.search(r'<([^>]+)>\s*;\s*rel="?next"?', link)
+            next_url = urllib.parse.urlsplit(match.group(1)) if match and names else None
+            path = f"{next_url.path}?{next_url.query}" if next_url else None
+        return repositories
+
+    def get_json(self, path: str, scope: str) -> Optional[Any]:
+        """GET a JSON document.
+
+        Also used for Notary servers, which authenticate with the same token
+        service as the registry.
+
+        Returns:
+            The parsed document, or None if it does not exist
+
+        Raises:
+            urllib.error.URLError: If the server could not be reached or answered with an error
+            ValueError: If the response is not JSON
+        """
+        try:
+            with self._request("GET", path, scope, {"Accept": "application/json"}) as response:
+                return json.loads(response.read().decode("utf-8"))
+        except urllib.error.HTTPError as e:
+            if e.code == 404:
+                return None
+            raise
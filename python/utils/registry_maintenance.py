@@ -11,6 +11,7 @@ from typing import Optional
 from utils.config_manager import _get_kubernetes_clients, config_manager, is_registry_in_cluster
 from utils.error_utils import create_kubernetes_error
 from utils.logging_utils import get_logger
+from utils.read_only import ensure_writable
 
 logger = get_logger(__name__)
 
@@ -38,7 +39,11 @@ def run_registry_garbage_collection(
 
     Returns:
         True if the garbage collection command completed successfully, False otherwise.
+
+    Raises:
+        ReadOnlyError: In read-only mode
     """
+    ensure_writable("registry garbage collection")
     workload_name = registry_statefulset or "docker-registry"
     ns = namespace or config_manager.get_domino_platform_namespace()
     registry_url = config_manager.get_registry_url() or ""
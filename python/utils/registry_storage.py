@@ -0,0 +1,268 @@
+"""
+Read-only access to the storage backend of a Docker Distribution registry.
+
+The registry API can only list tags, so untagged manifests and blobs no
+manifest references are invisible through it. When the storage the registry
+writes to is reachable - a mounted filesystem or the S3 bucket it uses - this
+module reads the registry's layout directly:
+
+    <root>/repositories/<name>/_manifests/tags/<tag>/current/link
+    <root>/repositories/<name>/_manifests/revisions/sha256/<hex>/link
+    <root>/blobs/sha256/<hex[:2]>/<hex>/data
+
+Nothing is ever written; removing orphaned content is left to the registry's
+own garbage collection (see registry_maintenance.py).
+"""
+
+import json
+import os
+from abc import ABC, abstractmethod
+from typing import Any, Dict, Iterator, List, Optional, Set, Tuple, TypedDict
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+# Path of the registry's data below the storage root (the registry's default layout)
+DEFAULT_STORAGE_PREFIX = "docker/registry/v2"
+
+
+class UntaggedManifest(TypedDict):
+    """Manifest revision that no tag (or tagged manifest list) points to."""
+
+    repository: str
+    digest: str
+    size_bytes: int
+
+
+class BrokenManifest(TypedDict):
+    """Manifest or tag whose content is missing or unreadable."""
+
+    repository: str
+    digest: str
+    tags: List[str]
+    reason: str
+
+
+class UnreferencedBlob(TypedDict):
+    """Blob that no manifest revision in any repository references."""
+
+    digest: str
+    size_bytes: int
+
+
+class RegistryStorage(ABC):
+    """Read-only view of a registry storage backend."""
+
+    @abstractmethod
+    def list_files(self, prefix: str) -> Iterator[Tuple[str, int]]:
+        """Yield (path, size_bytes) for every file below prefix, relative to the registry root"""
+
+    @abstractmethod
+    def read(self, path: str) -> Optional[bytes]:
+        """Read a file relative to the registry root, or None if it does not exist"""
+
+    def read_link(self, path: str) -> Optional[str]:
+        """Read a link file (a digest such as "sha256:<hex>")"""
+        data = self.read(path)
+        return data.decode("utf-8").strip() if data else None
+
+
+class FilesystemRegistryStorage(RegistryStorage):
+    """Registry storage on a mounted filesystem (the filesystem storage driver)."""
+
+    def __init__(self, path: str, prefix: str = DEFAULT_STORAGE_PREFIX):
+        """Initialize the storage
+
+        Args:
+            path: Root directory of the registry storage (rootdirectory in the registry config)
+            prefix: Path of the registry data below the root
+        """
+        self.root = os.path.join(path, prefix)
+
+    def list_files(self, prefix: str) -> Iterator[Tuple[str, int]]:
+        base = os.path.join(self.root, prefix)
+        for dirpath, _dirnames, filenames in os.walk(base):
+            for filename in filenames:
+                full_path = os.path.join(dirpath, filename)
+                yield os.path.relpath(full_path, self.root).replace(os.sep, "/"), os.path.getsize(full_path)
+
+    def read(self, path: str) -> Optional[bytes]:
+        try:
+            with open(os.path.join(self.root, path), "rb") as f:
+                return f.read()
+        except FileNotFoundError:
+            return None
+
+
+class S3RegistryStorage(RegistryStorage):
+    """Registry storage in an S3 bucket (the s3 storage driver)."""
+
+    def __init__(self, bucket: str, prefix: str = DEFAULT_STORAGE_PREFIX, s3_client: Any = None):
+        """Initialize the storage
+
+        Args:
+            bucket: Bucket the registry stores its data in
+            prefix: Path of the registry data in the bucket (rootdirectory plus docker/registry/v2)
+            s3_client: boto3 S3 client (created from the default credential chain if omitted)
+        """
+        if s3_client is None:
+            import boto3
+
+            s3_client = boto3.client("s3")
+        self.bucket = bucket
+        self.prefix = prefix.strip("/")
+        self.s3_client = s3_client
+
+    def list_files(self, prefix: str) -> Iterator[Tuple[str, int]]:
+        paginator = self.s3_client.get_paginator("list_objects_v2")
+        for page in paginator.paginate(Bucket=self.bucket, Prefix=f"{self.prefix}/{prefix}"):
+            for obj in page.get("Contents", []):
+                yield obj["Key"][len(self.prefix) + 1 :], obj["Size"]
+
+    def read(self, path: str) -> Optional[bytes]:
+        try:
+            response = self.s3_client.get_object(Bucket=self.bucket, Key=f"{self.prefix}/{path}")
+        except self.s3_client.exceptions.NoSuchKey:
+            return None
+        return response["Body"].read()
+
+
+def _blob_path(digest: str) -> str:
+    """Path of a blob's data file relative to the registry root"""
+    algorithm, _, hex_digest = digest.partition(":")
+    return f"blobs/{algorithm}/{hex_digest[:2]}/{hex_digest}/data"
+
+
+def _manifest_references(manifest: Dict[str, Any]) -> Tuple[List[str], List[str]]:
+    """Get the (blob digests, child manifest digests) a manifest references"""
+    blobs = [layer["digest"] for layer in manifest.get("layers", []) if layer.get("digest")]
+    # Schema 1 manifests list layers as fsLayers
+    blobs += [layer["blobSum"] for layer in manifest.get("fsLayers", []) if layer.get("blobSum")]
+    config_digest = (manifest.get("config") or {}).get("digest")
+    if config_digest:
+        blobs.append(config_digest)
+    children = [child["digest"] for child in manifest.get("manifests", []) if child.get("digest")]
+    return blobs, children
+
+
+def find_orphans(storage: RegistryStorage) -> Dict[str, Any]:
+    """Find untagged manifests, broken manifests and unreferenced blobs in registry storage.
+
+    Every repository is scanned, since blobs are shared between repositories and a
+    blob is only unreferenced if no repository uses it. A manifest is untagged when
+    no tag points to it, directly or through a tagged manifest list. Unreferenced
+    blobs exclude blobs used by untagged manifests; those are freed by garbage
+    collection once the untagged manifests are removed.
+
+    Args:
+        storage: Registry storage to read
+
+    Returns:
+        Dict with "untagged_manifests", "broken_manifests", "unreferenced_blobs" and a "summary"
+    """
+    revisions: Dict[str, Set[str]] = {}
+    tag_links: List[Tuple[str, str, str]] = []
+    for path, _size in storage.list_files("repositories/"):
+        repository, sep, rest = path[len("repositories/") :].partition("/_manifests/")
+        if not sep:
+            continue
+        parts = rest.split("/")
+        if len(parts) == 4 and parts[0] == "revisions" and parts[3] == "link":
+            revisions.setdefault(repository, set()).add(f"{parts[1]}:{parts[2]}")
+        elif len(parts) == 4 and parts[0] == "tags" and parts[2:] == ["current", "link"]:
+            tag_links.append((repository, parts[1], path))
+
+    blob_sizes: Dict[str, int] = {}
+    for path, size in storage.list_files("blobs/"):
+        parts = path.split("/")
+        if len(parts) == 5 and parts[4] == "data":
+            blob_sizes[f"{parts[1]}:{parts[3]}"] = size
+
+    logger.info(
+        f"Scanning {sum(len(d) for d in revisions.values())} manifests and {len(blob_sizes)} blobs "
+        f"in {len(revisions)} repositories"
+    )
+
+    tags: Dict[Tuple[str, str], List[str]] = {}
+    for repository, tag, path in tag_links:
+        digest = storage.read_link(path)
+        if digest:
+            tags.setdefault((repository, digest), []).append(tag)
+
+    broken: List[BrokenManifest] = []
+    referenced: Set[str] = set()
+    children: Dict[Tuple[str, str], List[str]] = {}
+    for repository, digests in sorted(revisions.items()):
+        for digest in sorted(digests):
+            referenced.add(digest)
+            entry_tags = sorted(tags.get((repository, digest), []))
+            data = storage.read(_blob_path(digest)) if digest in blob_sizes else None
+            if data is None:
+                broken.append(
+                    {"repository": repository, "digest": digest, "tags": entry_tags, "reason": "manifest blob is missing"}
+                )
+                continue
+            try:
+                blobs, child_digests = _manifest_references(json.loads(data))
+            except (ValueError, AttributeError, TypeError):
+                broken.append(
+                    {"repository": repository, "digest": digest, "tags": entry_tags, "reason": "manifest is not valid JSON"}
+                )
+                continue
+            referenced.update(blobs)
+            referenced.update(child_digests)
+            children[(repository, digest)] = child_digests
+            missing = [blob for blob in blobs if blob not in blob_sizes]
+            if missing:
+                broken.append(
+                    {
+                        "repository": repository,
+                        "digest": digest,
+                        "tags": entry_tags,
+                        "reason": f"references {len(missing)} missing blob(s): {', '.join(missing[:3])}",
+                    }
+                )
+
+    for (repository, digest), entry_tags in sorted(tags.items()):
+        if digest not in revisions.get(repository, set()):
+            broken.append(
+                {
+                    "repository": repository,
+                    "digest": digest,
+                    "tags": sorted(entry_tags),
+                    "reason": "tag points to a manifest that is not in the repository",
+                }
+            )
+
+    untagged: List[UntaggedManifest] = []
+    for repository, digests in sorted(revisions.items()):
+        reachable: Set[str] = set()
+        pending = [digest for (repo, digest) in tags if repo == repository]
+        while pending:
+            digest = pending.pop()
+            if digest not in reachable:
+                reachable.add(digest)
+                pending.extend(children.get((repository, digest), []))
+        for digest in sorted(digests - reachable):
+            untagged.append({"repository": repository, "digest": digest, "size_bytes": blob_sizes.get(digest, 0)})
+
+    unreferenced: List[UnreferencedBlob] = [
+        {"digest": digest, "size_bytes": size} for digest, size in sorted(blob_sizes.items()) if digest not in referenced
+    ]
+    unreferenced.sort(key=lambda blob: blob["size_bytes"], reverse=True)
+
+    return {
+        "summary": {
+            "repositories": len(revisions),
+            "manifests": sum(len(digests) for digests in revisions.values()),
+            "blobs": len(blob_sizes),
+            "untagged_manifests": len(untagged),
+            "broken_manifests": len(broken),
+            "unreferenced_blobs": len(unreferenced),
+            "unreferenced_bytes": sum(blob["size_bytes"] for blob in unreferenced),
+        },
+        "untagged_manifests": untagged,
+        "broken_manifests": broken,
+        "unreferenced_blobs": unreferenced,
+    }
@@ -0,0 +1,153 @@
+"""
+Repository churn from scan snapshot history.
+
+Retention policies have the most impact where images come and go fastest.
+This module compares successive scan snapshots repository by repository -
+the tags that appeared and disappeared between each pair, and the bytes of
+layers a repository had not held before - and turns the totals into weekly
+rates over the period the snapshots cover:
+
+    tags_added_per_week    Tags present in a snapshot but not in the one before
+    tags_removed_per_week  Tags present in a snapshot but not in the one after
+    bytes_added_per_week   Layer bytes new to the repository in a snapshot
+
+A tag re-pushed between two snapshots counts as neither added nor removed;
+see utils/tag_immutability.py for digest drift. Layers are counted once per
+repository when they first appear, so rebuilding a tag on the same base
+image adds only the layers that changed.
+"""
+
+from datetime import datetime, timezone
+from typing import Any, Dict, Iterable, List, Optional, Set, TypedDict
+
+SECONDS_PER_WEEK = 7 * 24 * 3600
+
+# Sort keys of rank_repositories
+SORT_BY_BYTES = "bytes"
+SORT_BY_TAGS = "tags"
+SORT_KEYS = (SORT_BY_BYTES, SORT_BY_TAGS)
+
+
+class RepositoryChurn(TypedDict):
+    """Change of one repository over the snapshot history."""
+
+    repository: str
+    tags: int  # In the newest snapshot
+    bytes: int  # Layer bytes of the newest snapshot's images, each layer once
+    tags_added: int
+    tags_removed: int
+    bytes_added: int
+    tags_added_per_week: float
+    tags_removed_per_week: float
+    bytes_added_per_week: float
+
+
+def _snapshot_time(snapshot: Dict[str, Any]) -> datetime:
+    """When a snapshot was taken, timezone-aware"""
+    created = datetime.fromisoformat(snapshot["created_at"])
+    return created if created.tzinfo else created.replace(tzinfo=timezone.utc)
+
+
+def _repositories(snapshot: Dict[str, Any]) -> Dict[str, Dict[str, List[str]]]:
+    """Repository -> tag -> layers of a snapshot"""
+    repositories: Dict[str, Dict[str, List[str]]] = {}
+    for image in (snapshot.get("images") or {}).values():
+        repositories.setdefault(image["repository"], {})[image["tag"]] = image.get("layers") or []
+    return repositories
+
+
+def repository_churn(snapshots: Iterable[Dict[str, Any]]) -> Dict[str, Any]:
+    """Churn of every repository across snapshots.
+
+    Args:
+        snapshots: Snapshot documents (see scan_snapshot.build_snapshot), oldest first; read one at a time
+
+    Returns:
+        Dict with snapshots (count), start and end (ISO times of the oldest and newest snapshot),
+        weeks (the period between them) and repositories (RepositoryChurn by repository name)
+
+    Raises:
+        ValueError: If fewer than two snapshots are given, or they were all taken at the same time
+    """
+    count = 0
+    start: Optional[datetime] = None
+    end: Optional[datetime] = None
+    previous: Dict[str, Dict[str, List[str]]] = {}
+    seen_layers: Dict[str, Set[str]] = {}
+    totals: Dict[str, Dict[str, int]] = {}
+    layer_sizes: Dict[str, int] = {}
+
+    for snapshot in snapshots:
+        taken = _snapshot_time(snapshot)
+        start = start or taken
+        end = taken
+        layer_sizes = {layer_id: int(size) for layer_id, size in (snapshot.get("layers") or {}).items()}
+        current = _repositories(snapshot)
+        for repository in set(previous) | set(current):
+            tags = current.get(repository, {})
+            before = previous.get(repository, {})
+            total = totals.setdefault(repository, {"tags_added": 0, "tags_removed": 0, "bytes_added": 0})
+            layers = seen_layers.setdefault(repository, set())
+            new_layers = {layer for tag_layers in tags.values() for layer in tag_layers} - layers
+            if count:
+                # The first snapshot is the baseline; what it holds was not added during the period
+                total["tags_added"] += len(set(tags) - set(before))
+                total["tags_removed"] += len(set(before) - set(tags))
+                total["bytes_added"] += sum(layer_sizes.get(layer, 0) for layer in new_layers)
+            layers |= new_layers
+        previous = current
+        count += 1
+
+    if count < 2 or start is None or end is None or end <= start:
+        raise ValueError("Churn needs at least two snapshots taken at different times")
+    weeks = (end - start).total_seconds() / SECONDS_PER_WEEK
+
+    repositories: Dict[str, RepositoryChurn] = {}
+    for repository, total in sorted(totals.items()):
+        tags = previous.get(repository, {})
+        layers = {layer for tag_layers in tags.values() for layer in tag_layers}
+        repositories[repository] = {
+            "repository": repository,
+            "tags": len(tags),
+            "bytes": sum(layer_sizes.get(layer, 0) for layer in layers),
+            "tags_added": total["tags_added"],
+            "tags_removed": total["tags_removed"],
+            "bytes_added": total["bytes_added"],
+            "tags_added_per_week": round(total["tags_added"] / weeks, 2),
+            "tags_removed_per_week": round(total["tags_removed"] / weeks, 2),
+            "bytes_added_per_week": round(total["bytes_added"] / weeks, 2),
+        }
+    return {
+        "snapshots": count,
+        "start": start.isoformat(),
+        "end": end.isoformat(),
+        "weeks": round(weeks, 2),
+        "repositories": repositories,
+    }
+
+
+def rank_repositories(churn: Dict[str, RepositoryChurn], sort_by: str = SORT_BY_BYTES) -> List[RepositoryChurn]:
+    """Repositories hottest first.
+
+    Args:
+        churn: RepositoryChurn by repository name (see repository_churn)
+        sort_by: "bytes" (bytes added per week first) or "tags" (tags added plus removed per week first)
+
+    Returns:
+        Repositories that changed during the period, hottest first
+    """
+    if sort_by not in SORT_KEYS:
+        raise ValueError(f"Unknown sort key '{sort_by}' (expected one of: {', '.join(SORT_KEYS)})")
+
+    def tag_rate(entry: RepositoryChurn) -> float:
+        return entry["tags_added_per_week"] + entry["tags_removed_per_week"]
+
+    def key(entry: RepositoryChurn) -> tuple:
+        if sort_by == SORT_BY_BYTES:
+            return (-entry["bytes_added_per_week"], -tag_rate(entry), entry["repository"])
+        return (-tag_rate(entry), -entry["bytes_added_per_week"], entry["repository"])
+
+    changed = [
+        entry for entry in churn.values() if entry["tags_added"] or entry["tags_removed"] or entry["bytes_added"]
+    ]
+    return sorted(changed, key=key)
@@ -0,0 +1,179 @@
+"""
+Report upload to object storage.
+
+With --report-upload (or reports.upload_url in config.yaml) every report a run
+writes to the reports directory is copied to object storage once the run
+finishes, building a durable history of runs without a database. Each run gets
+its own folder, so reports saved without a timestamp in their name are not
+overwritten by the next run:
+
+    <prefix>/<command>/<run start, UTC>/<report file>
+    s3://bucket/registry-cleaner/plan/2026-01-01T02-00-00Z/cleanup-plan-2026-01-01-02-00-07.json
+
+Supported destinations:
+
+    s3://bucket/prefix                                   boto3 default credential chain
+    gs://bucket/prefix                                   google-cloud-storage, application default credentials
+    az://account/container/prefix                        azure-storage-blob, DefaultAzureCredential
+    https://account.blob.core.windows.net/container/prefix  (same as az://)
+
+The GCS and Azure Blob clients are optional dependencies, imported only when
+such a destination is used.
+"""
+
+import urllib.parse
+from dataclasses import dataclass
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import List
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+# Compressed reports (reports.compress) end in .gz or .zst
+REPORT_SUFFIXES = (".json", ".html", ".csv", ".gz", ".zst")
+
+_CONTENT_TYPES = {
+    ".json": "application/json",
+    ".html": "text/html",
+    ".csv": "text/csv",
+    ".gz": "application/gzip",
+    ".zst": "application/zstd",
+}
+
+
+class ReportUploadError(Exception):
+    """Raised when reports cannot be uploaded, or the destination is invalid."""
+
+
+@dataclass
+class UploadTarget:
+    """An object storage location reports are uploaded to."""
+
+    scheme: str  # "s3", "gs" or "az"
+    bucket: str  # Bucket, or container for Azure Blob
+    prefix: str  # Key prefix without leading or trailing slash
+    account: str = ""  # Azure storage account
+
+    def url(self, key: str) -> str:
+        """URL of an uploaded object, for logs"""
+        if self.scheme == "az":
+            return f"https://{self.account}.blob.core.windows.net/{self.bucket}/{key}"
+        return f"{self.scheme}://{self.bucket}/{key}"
+
+
+def parse_upload_url(url: str) -> UploadTarget:
+    """Parse a report upload destination.
+
+    Raises:
+        ReportUploadError: If the URL is not a supported destination
+    """
+    parsed = urllib.parse.urlparse(url)
+    path = parsed.path.strip("/")
+    if parsed.scheme in ("s3", "gs") and parsed.netloc:
+        return UploadTarget(parsed.scheme, parsed.netloc, path)
+    if parsed.scheme == "az" and parsed.netloc and path:
+        container, _, prefix = path.partition("/")
+        return UploadTarget("az", container, prefix, account=parsed.netloc)
+    if parsed.scheme == "https" and parsed.netloc.endswith(".blob.core.windows.net") and path:
+        container, _, prefix = path.partition("/")
+        return UploadTarget("az", container, prefix, account=parsed.netloc.split(".", 1)[0])
+    raise ReportUploadError(
+        f"Unsupported report upload destination '{url}' "
+        "(expected s3://bucket/prefix, gs://bucket/prefix or az://account/container/prefix)"
+    )
+
+
+def find_run_reports(reports_dir: Path, since: float) -> List[Path]:
+    """Reports written to the reports directory since a run started.
+
+    Only report files directly in the directory are included, not checkpoints
+    or other subdirectories.
+
+    Args:
+        reports_dir: Reports directory
+        since: Start of the run (Unix time)
+
+    Returns:
+        Report paths, sorted by name
+    """
+    if not reports_dir.is_dir():
+        return []
+    return sorted(
+        path
+        for path in reports_dir.iterdir()
+        if path.is_file()
+        and path.suffix in REPORT_SUFFIXES
+        and not path.name.startswith(".")
+        and path.stat().st_mtime >= since
+    )
+
+
+def report_key(target: UploadTarget, command: str, run_started: datetime, path: Path) -> str:
+    """Object key of a report: <prefix>/<command>/<run start>/<file name>"""
+    run_folder = run_started.astimezone(timezone.utc).strftime("%Y-%m-%dT%H-%M-%SZ")
+    return "/".join(part for part in (target.prefix, command, run_folder, path.name) if part)
+
+
+def _upload_file(target: UploadTarget, path: Path, key: str) -> None:
+    """Upload one file to the target"""
+    content_type = _CONTENT_TYPES.get(path.suffix, "application/octet-stream")
+    if target.scheme == "s3":
+        import boto3
+
+        boto3.client("s3").upload_file(str(path), target.bucket, key, ExtraArgs={"ContentType": content_type})
+    elif target.scheme == "gs":
+        try:
+            from google.cloud import storage
+        except ImportError as e:
+            raise ReportUploadError("Uploading to gs:// needs the google-cloud-storage package") from e
+        storage.Client().bucket(target.bucket).blob(key).upload_from_filename(str(path), content_type=content_type)
+    else:
+        try:
+            from azure.identity import DefaultAzureCredential
+            from azure.storage.blob import BlobServiceClient, ContentSettings
+        except ImportError as e:
+            raise ReportUploadError("Uploading to Azure Blob Storage needs the azure-storage-blob package") from e
+        service = BlobServiceClient(
+            f"https://{target.account}.blob.core.windows.net", credential=DefaultAzureCredential()
+        )
+        with open(path, "rb") as f:
+            service.get_blob_client(target.bucket, key).upload_blob(
+                f, overwrite=True, content_settings=ContentSettings(content_type=content_type)
+            )
+
+
+def upload_reports(url: str, paths: List[Path], command: str, run_started: datetime) -> List[str]:
+    """Upload a run's reports to object storage.
+
+    Args:
+        url: Destination (see parse_upload_url)
+        paths: Report files to upload
+        command: Command that wrote the reports
+        run_started: Start of the run, naming its folder
+
+    Returns:
+        URLs of the uploaded reports
+
+    Raises:
+        ReportUploadError: If the destination is invalid or any upload failed
+    """
+    target = parse_upload_url(url)
+    uploaded: List[str] = []
+    failed: List[str] = []
+    for path in paths:
+        key = report_key(target, command, run_started, path)
+        try:
+            _upload_file(target, path, key)
+        except ReportUploadError:
+            raise
+        except Exception as e:
+            logger.error(f"Could not upload {path.name} to {target.url(key)}: {e}")
+            failed.append(path.name)
+            continue
+        logger.info(f"Uploaded {path.name} to {target.url(key)}")
+        uploaded.append(target.url(key))
+    if failed:
+        raise ReportUploadError(f"{len(failed)} of {len(paths)} report(s) could not be uploaded: {', '.join(failed)}")
+    return uploaded
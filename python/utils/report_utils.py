@@ -2,22 +2,29 @@
 Utility functions for report generation, saving, and freshness checking.
 
 This module provides functions to:
-- Save reports in various formats (JSON, table+JSON)
+- Save reports in various formats (JSON, table+JSON, CSV), optionally compressed
 - Check if reports are fresh
 - Automatically generate reports when needed
 - Generate timestamped report filenames
 """
 
+import csv
+import gzip
 import json
+from collections.abc import Iterator
 from datetime import datetime, timedelta
 from pathlib import Path
-from typing import Any, Dict, Optional
+from typing import IO, Any, Callable, Dict, Iterable, Optional, Sequence
 
 from utils.config_manager import config_manager
 from utils.logging_utils import get_logger
+from utils.run_metadata import RUN_METADATA_KEY, get_run_metadata
 
 logger = get_logger(__name__)
 
+# Compression of saved reports (reports.compress or --compress) -> suffix added to the file name
+REPORT_COMPRESSIONS = {"gzip": ".gz", "zstd": ".zst"}
+
 # ============================================================================
 # Formatting Utilities
 # ============================================================================
@@ -90,7 +97,10 @@ def get_latest_report(report_pattern: str, reports_dir: Optional[Path] = None) -
     if reports_dir is None:
         reports_dir = get_reports_dir()
 
+    # Compressed reports match with their compression suffix
     reports = list(reports_dir.glob(report_pattern))
+    for suffix in REPORT_COMPRESSIONS.values():
+        reports.extend(reports_dir.glob(report_pattern + suffix))
     if not reports:
         return None
 
@@ -98,6 +108,55 @@ def get_latest_report(report_pattern: str, reports_dir: Optional[Path] = None) -
     return max(reports, key=lambda p: p.stat().st_mtime)
 
 
+def find_report_file(path: Path) -> Optional[Path]:
+    """Return a report file as saved, or its compressed version, or None if neither exists"""
+    if path.exists():
+        return path
+    for suffix in REPORT_COMPRESSIONS.values():
+        compressed = path.with_name(path.name + suffix)
+        if compressed.exists():
+            return compressed
+    return None
+
+
+def open_report(path: Path, mode: str = "r", newline: Optional[str] = None) -> IO[str]:
+    """Open a report as text, compressing or decompressing it by its suffix (.gz, .zst)
+
+    Args:
+        path: Report file
+        mode: "r" or "w"
+        newline: As for open()
+
+    Raises:
+        ValueError: If the report is zstd-compressed and the zstandard package is not installed
+    """
+    if path.suffix == REPORT_COMPRESSIONS["gzip"]:
+        return gzip.open(path, mode + "t", encoding="utf-8", newline=newline)
+    if path.suffix == REPORT_COMPRESSIONS["zstd"]:
+        try:
+            import zstandard
+        except ImportError:
+            raise ValueError("zstd-compressed reports need the zstandard package (pip install zstandard)") from None
+        return zstandard.open(path, mode + "t", encoding="utf-8", newline=newline)
+    return open(path, mode, newline=newline)
+
+
+def load_json(path: Path) -> Any:
+    """Read a JSON report, compressed or not"""
+    with open_report(path) as f:
+        return json.load(f)
+
+
+def _output_path(path: str, timestamp: bool, compress: Optional[str]) -> Path:
+    """Path a report is written to, with its timestamp and compression suffix"""
+    p = Path(add_timestamp_to_path(path) if timestamp else path)
+    compression = config_manager.get_report_compression() if compress is None else compress
+    if compression:
+        p = p.with_name(p.name + REPORT_COMPRESSIONS[compression])
+    p.parent.mkdir(parents=True, exist_ok=True)
+    return p
+
+
 # ============================================================================
 # Report Saving Functions
 # ============================================================================
@@ -134,14 +193,57 @@ def save_table_and_json(base_path: str, table_str: str, json_obj: Dict[str, Any]
     # Write JSON using save_json to handle ObjectId serialization
     json_path = save_json(f"{base}.json", json_obj, timestamp=False)
 
-    logger.info(f"Saved reports to {base}.txt and {base}.json")
+    logger.info(f"Saved reports to {base}.txt and {json_path}")
     return json_path
 
 
-def save_json(path: str, data: Any, timestamp: bool = False) -> str:
+def _write_json_stream(f: IO[str], value: Any, normalize: Callable[[Any], Any], level: int = 0) -> None:
+    """Write a value as indented JSON, one container element at a time.
+
+    Produces the same text as json.dump(value, f, indent=2), but never holds a
+    fully normalized copy of the data or its encoded form in memory. Iterators
+    and generators are written as arrays, so callers can stream results that
+    were never materialized as a list.
+    """
+    indent = "  " * (level + 1)
+    if isinstance(value, dict):
+        if not value:
+            f.write("{}")
+            return
+        f.write("{")
+        for index, (key, item) in enumerate(value.items()):
+            key = key if isinstance(key, str) else json.dumps(key)
+            f.write(("," if index else "") + "\n" + indent + json.dumps(key) + ": ")
+            _write_json_stream(f, item, normalize, level + 1)
+        f.write("\n" + "  " * level + "}")
+    elif isinstance(value, (list, tuple, Iterator)):
+        wrote_any = False
+        for item in value:
+            f.write(("," if wrote_any else "[") + "\n" + indent)
+            _write_json_stream(f, item, normalize, level + 1)
+            wrote_any = True
+        f.write("\n" + "  " * level + "]" if wrote_any else "[]")
+    else:
+        normalized = normalize(value)
+        if isinstance(normalized, (dict, list, tuple)):
+            _write_json_stream(f, normalized, normalize, level)
+        else:
+            f.write(json.dumps(normalized))
+
+
+def save_json(
+    path: str, data: Any, timestamp: bool = False, compress: Optional[str] = None, run_metadata: bool = True
+) -> str:
     """
     Write JSON data to a file with indentation.
 
+    A JSON object gets a "runMetadata" block first (see utils.run_metadata),
+    unless it already has one or run_metadata is False.
+
+    The file is written incrementally, element by element, so very large
+    results do not need to be encoded in memory first. Any iterator or
+    generator in the data (at any depth) is written as a JSON array.
+
     Handles MongoDB BSON types and Python types that aren't JSON serializable:
     - ObjectId: normalized to strings
     - datetime/date: converted to ISO format strings
@@ -155,6 +257,10 @@ def save_json(path: str, data: Any, timestamp: bool = False) -> str:
         path: Path to save the JSON file
         data: Data to save
         timestamp: If True, add timestamp to filename (default: True)
+        compress: "gzip" or "zstd" to compress the file, adding .gz or .zst to its
+            name; "" not to; None (default) as configured (reports.compress)
+        run_metadata: If False, write the data as it is, e.g. for files that map
+            layer IDs to values and are read back key by key
 
     Returns:
         Path to the saved file
@@ -223,20 +329,46 @@ def save_json(path: str, data: Any, timestamp: bool = False) -> str:
         else:
             return data
 
-    p = Path(path)
+    if run_metadata and isinstance(data, dict) and RUN_METADATA_KEY not in data:
+        data = {RUN_METADATA_KEY: get_run_metadata(), **data}
 
-    # Add timestamp if requested
-    if timestamp:
-        p = Path(add_timestamp_to_path(str(p)))
+    p = _output_path(path, timestamp, compress)
 
-    p.parent.mkdir(parents=True, exist_ok=True)
+    # Normalize ObjectIds and other BSON types as each value is written
+    with open_report(p, "w") as f:
+        _write_json_stream(f, data, normalize_object_ids_in_data)
+    logger.info(f"Saved JSON to {p}")
+    return str(p)
 
-    # Normalize ObjectIds in the data before serialization
-    normalized_data = normalize_object_ids_in_data(data)
 
-    with open(p, "w") as f:
-        json.dump(normalized_data, f, indent=2)
-    logger.info(f"Saved JSON to {p}")
+def save_csv(
+    path: str,
+    rows: Iterable[Dict[str, Any]],
+    fieldnames: Sequence[str],
+    timestamp: bool = False,
+    compress: Optional[str] = None,
+) -> str:
+    """
+    Write rows to a CSV file with a header line.
+
+    Args:
+        path: Path to save the CSV file
+        rows: Rows to save; keys not in fieldnames are ignored, missing ones left empty
+        fieldnames: Columns, in order
+        timestamp: If True, add timestamp to filename
+        compress: As for save_json
+
+    Returns:
+        Path to the saved file
+    """
+    p = _output_path(path, timestamp, compress)
+
+    with open_report(p, "w", newline="") as f:
+        writer = csv.DictWriter(f, fieldnames=list(fieldnames), extrasaction="ignore")
+        writer.writeheader()
+        for row in rows:
+            writer.writerow({key: "" if value is None else value for key, value in row.items()})
+    logger.info(f"Saved CSV to {p}")
     return str(p)
 
 
@@ -268,8 +400,8 @@ def is_report_fresh(report_name: str, max_age_hours: int = 24) -> bool:
     reports_dir = get_reports_dir()
 
     # Try exact match first
-    report_path = reports_dir / report_name
-    if report_path.exists():
+    report_path = find_report_file(reports_dir / report_name)
+    if report_path:
         mtime = datetime.fromtimestamp(report_path.stat().st_mtime)
         age = datetime.now() - mtime
         return age < timedelta(hours=max_age_hours)
@@ -342,8 +474,8 @@ def ensure_image_analysis_reports(max_age_hours: int = 24, force: bool = False)
         repository = config_manager.get_repository()
         analyzer = ImageAnalyzer(registry_url, repository)
 
-        # Analyze both environment and model images (max_workers from config via analyzer default)
-        for image_type in ["environment", "model"]:
+        # Analyze every image type of the profile (max_workers from config via analyzer default)
+        for image_type in config_manager.get_image_types():
             logger.info(f"Analyzing {image_type} images...")
             success = analyzer.analyze_image(image_type)
             if not success:
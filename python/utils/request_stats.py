@@ -0,0 +1,114 @@
+"""
+Registry request telemetry.
+
+Every registry request made during a run is recorded by operation type -
+skopeo subcommands (list-tags, inspect, delete, ...) and the native manifest
+and blob HEAD requests - with its latency and whether it failed. Reports
+include the totals in a "runStats" section so that slow or failing runs can
+be traced to the operations responsible:
+
+    "runStats": {
+      "inspect": {"count": 1200, "errors": 3, "p50_ms": 410.2, "p90_ms": 980.5,
+                  "p99_ms": 2410.0, "max_ms": 5120.7, "total_seconds": 612.4},
+      ...
+    }
+
+Latencies of skopeo commands include retries but not time spent waiting for
+the rate limiter.
+"""
+
+import threading
+import time
+from collections import Counter
+from contextlib import contextmanager
+from typing import Dict, Iterator, List, TypedDict
+
+
+class OperationStats(TypedDict):
+    """Request totals and latency percentiles of one operation type."""
+
+    count: int
+    errors: int
+    p50_ms: float
+    p90_ms: float
+    p99_ms: float
+    max_ms: float
+    total_seconds: float
+
+
+def _percentile(sorted_values: List[float], percent: float) -> float:
+    """Nearest-rank percentile of an ascending list"""
+    if not sorted_values:
+        return 0.0
+    rank = max(1, -(-len(sorted_values) * percent // 100))
+    return sorted_values[int(rank) - 1]
+
+
+class RequestStats:
+    """Thread-safe recorder of request latencies and errors by operation."""
+
+    def __init__(self) -> None:
+        self._lock = threading.Lock()
+        self._latencies: Dict[str, List[float]] = {}
+        self._errors: Counter = Counter()
+
+    def record(self, operation: str, seconds: float, error: bool = False) -> None:
+        """Record one request"""
+        with self._lock:
+            self._latencies.setdefault(operation, []).append(seconds)
+            if error:
+                self._errors[operation] += 1
+
+    @contextmanager
+    def track(self, operation: str) -> Iterator[Dict[str, bool]]:
+        """Time a request; it counts as an error if it raises or the caller sets outcome["error"].
+
+        Example:
+            with request_stats.track("inspect") as outcome:
+                output = run()
+                outcome["error"] = output is None
+        """
+        outcome = {"error": False}
+        start = time.monotonic()
+        try:
+            yield outcome
+        except BaseException:
+            outcome["error"] = True
+            raise
+        finally:
+            self.record(operation, time.monotonic() - start, outcome["error"])
+
+    def summary(self) -> Dict[str, OperationStats]:
+        """Totals and latency percentiles per operation, sorted by operation name"""
+        with self._lock:
+            latencies = {operation: sorted(values) for operation, values in self._latencies.items()}
+            errors = dict(self._errors)
+
+        result: Dict[str, OperationStats] = {}
+        for operation in sorted(latencies):
+            values = latencies[operation]
+            result[operation] = {
+                "count": len(values),
+                "errors": errors.get(operation, 0),
+                "p50_ms": round(_percentile(values, 50) * 1000, 1),
+                "p90_ms": round(_percentile(values, 90) * 1000, 1),
+                "p99_ms": round(_percentile(values, 99) * 1000, 1),
+                "max_ms": round(values[-1] * 1000, 1),
+                "total_seconds": round(sum(values), 3),
+            }
+        return result
+
+    def reset(self) -> None:
+        """Forget all recorded requests"""
+        with self._lock:
+            self._latencies.clear()
+            self._errors.clear()
+
+
+# Requests made by every client in this process
+request_stats = RequestStats()
+
+
+def get_run_stats() -> Dict[str, OperationStats]:
+    """Get the request telemetry of this run, for the "runStats" section of reports."""
+    return request_stats.summary()
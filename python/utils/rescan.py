@@ -0,0 +1,124 @@
+"""
+Selective rescans.
+
+After a targeted push or deletion, a full scan only to bring reports up to
+date re-lists every repository. A selective rescan starts from the last saved
+scan snapshot instead and refreshes only what changed:
+
+- With tags, each named tag of each repository is inspected again: a re-pushed
+  tag is recorded with its new digest and layers, and a tag the registry no
+  longer has is dropped.
+- Without tags, each repository is scanned again like in a full scan (listing
+  its tags, inspecting new digests, attaching reference tags), replacing what
+  the snapshot held for it.
+
+Everything else comes from the snapshot, plus the per-digest results the
+inspection cache keeps (legacy formats, possible secrets, build fields), so the
+reports saved afterwards cover the whole registry. Attached reference tags are
+not kept in snapshots, so they are only reported for repositories rescanned
+without tags.
+"""
+
+from typing import TYPE_CHECKING, List, Optional, TypedDict
+
+from utils.error_utils import ERROR_NOT_FOUND
+from utils.logging_utils import get_logger
+from utils.scan_diff import diff_scans, scan_state
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+logger = get_logger(__name__)
+
+
+class RescanResult(TypedDict):
+    """What a selective rescan changed in the index."""
+
+    added: List[str]  # image_ids, sorted
+    removed: List[str]
+    changed: List[str]  # image_ids whose tag now points to a different digest
+    failed: List[str]  # image_ids that could not be inspected (or image types that could not be listed)
+    size_delta_bytes: int
+
+
+def restore_cached_results(analyzer: "ImageAnalyzer") -> int:
+    """Add the per-digest results of the inspection cache to images loaded from a snapshot.
+
+    Returns:
+        Number of images that got any
+    """
+    cache = analyzer.inspect_cache
+    restored = 0
+    for image_id, image_data in analyzer.images.items():
+        digest = image_data.get("digest")
+        found = False
+        legacy_format = cache.get_legacy_format(digest)
+        if legacy_format:
+            analyzer.legacy_formats[image_id] = legacy_format
+            found = True
+        secret_findings = cache.get_secret_findings(digest)
+        if secret_findings:
+            analyzer.secret_findings[image_id] = list(secret_findings)
+            found = True
+        toolchain = cache.get_toolchain(digest)
+        if toolchain is not None:
+            analyzer.toolchain[image_id] = toolchain
+            found = True
+        restored += found
+    return restored
+
+
+def rescan(
+    analyzer: "ImageAnalyzer",
+    image_types: List[str],
+    tags: Optional[List[str]] = None,
+    max_workers: Optional[int] = None,
+) -> RescanResult:
+    """Refresh the given repositories, or only the given tags of them, in an analyzer loaded from a snapshot.
+
+    Args:
+        analyzer: ImageAnalyzer holding the previous scan (see scan_snapshot.load_snapshot)
+        image_types: Repositories to refresh, e.g. ["environment"]
+        tags: Tags to refresh in each repository (default: rescan the repositories entirely)
+        max_workers: Number of parallel workers for a repository rescan (default: from config)
+
+    Returns:
+        The images added, removed, changed and failed
+    """
+    previous = scan_state(analyzer)
+    failed: List[str] = []
+
+    if tags:
+        for image_type in image_types:
+            for tag in tags:
+                image_id = f"{image_type}:{tag}"
+                if not analyzer.is_scanned_tag(tag):
+                    logger.warning(f"Skipping {image_id}: buildcache, reference and excluded tags are not scanned")
+                    continue
+                if analyzer.refresh_tag(image_type, tag):
+                    continue
+                failure = analyzer.failures.get(image_id) or {}
+                if failure.get("code") != ERROR_NOT_FOUND:
+                    failed.append(image_id)
+                elif not analyzer.forget_images([image_id]):
+                    analyzer.failures.pop(image_id, None)
+                    logger.warning(f"{image_type}:{tag} is neither in the registry nor in the snapshot")
+    else:
+        for image_type in image_types:
+            analyzer.forget_images([image_id for image_id in analyzer.images if image_id.startswith(f"{image_type}:")])
+        analyzed = analyzer.analyze_images(image_types, max_workers=max_workers)
+        failed.extend(image_type for image_type in image_types if image_type not in analyzed)
+        failed.extend(
+            image_id
+            for image_id, failure in sorted(analyzer.failures.items())
+            if image_id.split(":", 1)[0] in image_types and failure["code"] != ERROR_NOT_FOUND
+        )
+
+    diff = diff_scans(previous, scan_state(analyzer))
+    return {
+        "added": diff["added"],
+        "removed": diff["removed"],
+        "changed": diff["changed"],
+        "failed": failed,
+        "size_delta_bytes": diff["size_delta_bytes"],
+    }
@@ -0,0 +1,641 @@
+"""
+Retention policy files.
+
+A retention policy is a YAML file of rules deciding which images to keep and
+which to delete:
+
+    version: 1
+    default: keep            # action for images no rule matches
+    rules:
+      - name: keep-in-use
+        action: keep
+        in_use: true
+      - name: expire-old-environments
+        action: delete
+        repositories: ["environment"]
+        older_than_days: 180
+      - name: keep-release-builds
+        action: keep
+        annotations:
+          source: "https://github.com/example/*"
+          revision: "release-*"
+      - name: expire-unattested-builds
+        action: delete
+        has_provenance: false
+        older_than_days: 30
+        replicate_to: archive.example.com
+
+Every condition given in a rule must hold for the rule to match. An image is
+deleted when a delete rule matches it and no keep rule does; keep rules always
+win, so a policy errs on the side of keeping images.
+
+Conditions on usage (in_use, unused_for_days), age, OCI annotations and
+provenance need data that is not always available, such as a scan saved without
+the MongoDB usage report. A
+condition that cannot be evaluated makes keep rules match and delete rules not
+match, so missing data never causes a deletion.
+
+Images a run or workspace used within analysis.recent_run_protection_days are
+kept whatever the rules say (rule "recent-run-protection"), so users on old,
+pinned environment revisions are not broken.
+
+A delete rule may name an archive registry in replicate_to; images it deletes
+are copied there first, and kept if the copy cannot be verified (see apply).
+"""
+
+import difflib
+from dataclasses import dataclass, field
+from datetime import datetime, timezone
+from fnmatch import fnmatchcase
+from typing import TYPE_CHECKING, Any, Dict, List, Optional, Tuple, TypedDict
+
+import yaml
+
+from utils.deletion_candidates import TagUsage, days_since, parse_created
+from utils.oci_annotations import annotations_match, resolve_annotation_key
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+POLICY_FORMAT_VERSION = 1
+POLICY_ACTIONS = ("keep", "delete")
+# Decision rule of images kept because they were used within the protection window
+RECENT_RUN_PROTECTION_RULE = "recent-run-protection"
+
+
+class PolicyFormatError(ValueError):
+    """Raised when a policy file is malformed or has an unsupported version."""
+
+
+@dataclass
+class PolicyRule:
+    """A single keep or delete rule."""
+
+    name: str
+    action: str  # "keep" or "delete"
+    repositories: List[str] = field(default_factory=list)  # Image type or repository patterns
+    tags: List[str] = field(default_factory=list)  # Tag patterns
+    older_than_days: Optional[int] = None
+    newer_than_days: Optional[int] = None
+    unused_for_days: Optional[int] = None  # Not used by any workload for this many days
+    in_use: Optional[bool] = None
+    annotations: Dict[str, str] = field(default_factory=dict)  # Annotation key (or created/source/revision) -> pattern
+    has_provenance: Optional[bool] = None  # Image has (true) or lacks (false) SLSA provenance
+    replicate_to: str = ""  # Delete rules: archive registry to copy images to before deleting them
+
+
+@dataclass
+class RetentionPolicy:
+    """A set of rules and the action for images no rule matches."""
+
+    rules: List[PolicyRule] = field(default_factory=list)
+    default: str = "keep"
+    format_version: int = POLICY_FORMAT_VERSION
+
+
+class PolicyDecision(TypedDict):
+    """The outcome of a policy for one image."""
+
+    image_id: str
+    repository: str
+    tag: str
+    action: str
+    rule: str  # Name of the deciding rule, or "default"
+    matched_rules: List[str]
+    undetermined_rules: List[str]  # Rules whose conditions could not be evaluated
+    replicate_to: str  # Archive registry to copy the image to before deleting it, or ""
+
+
+@dataclass
+class PolicyIssue:
+    """A problem found in a policy file."""
+
+    severity: str  # "error" (the policy cannot be used) or "warning"
+    location: str  # "policy" or "rule <N> (<name>)"
+    message: str
+
+    def __str__(self) -> str:
+        return f"{self.severity.upper()}: {self.location}: {self.message}"
+
+
+POLICY_FIELDS = ("version", "default", "rules")
+RULE_FIELDS = tuple(PolicyRule.__dataclass_fields__)
+_PATTERN_FIELDS = ("repositories", "tags")
+_DAYS_FIELDS = ("older_than_days", "newer_than_days", "unused_for_days")
+
+
+def _unknown_field_message(name: str, known: Tuple[str, ...]) -> str:
+    """Describe an unknown field, suggesting the closest known one"""
+    suggestion = difflib.get_close_matches(str(name), known, n=1)
+    hint = f"did you mean '{suggestion[0]}'?" if suggestion else f"expected one of: {', '.join(known)}"
+    return f"unknown field '{name}' ({hint})"
+
+
+def _check_rule_fields(rule_data: Dict[str, Any], location: str) -> List[PolicyIssue]:
+    """Check the fields of one rule"""
+    issues: List[PolicyIssue] = []
+
+    def error(message: str) -> None:
+        issues.append(PolicyIssue("error", location, message))
+
+    for name in rule_data:
+        if name not in RULE_FIELDS:
+            error(_unknown_field_message(name, RULE_FIELDS))
+
+    action = rule_data.get("action")
+    if action is None:
+        error(f"'action' is required ({' or '.join(POLICY_ACTIONS)})")
+    elif action not in POLICY_ACTIONS:
+        error(f"action must be one of {', '.join(POLICY_ACTIONS)}, got: {action!r}")
+
+    if not isinstance(rule_data.get("name", ""), str):
+        error(f"'name' must be a string, got: {rule_data['name']!r}")
+
+    for name in _PATTERN_FIELDS:
+        value = rule_data.get(name)
+        if value is None:
+            continue
+        if isinstance(value, str):
+            error(f"'{name}' must be a list of patterns; write [\"{value}\"] for a single pattern")
+        elif not isinstance(value, list) or not all(isinstance(p, str) and p for p in value):
+            error(f"'{name}' must be a list of non-empty patterns, got: {value!r}")
+
+    for name in _DAYS_FIELDS:
+        value = rule_data.get(name)
+        if value is None:
+            continue
+        if isinstance(value, bool) or not isinstance(value, int) or value < 0:
+            error(f"'{name}' must be a whole number of days (0 or more), got: {value!r}")
+
+    for name in ("in_use", "has_provenance"):
+        value = rule_data.get(name)
+        if value is not None and not isinstance(value, bool):
+            error(f"'{name}' must be true or false, got: {value!r}")
+
+    replicate_to = rule_data.get("replicate_to")
+    if replicate_to is not None:
+        if not isinstance(replicate_to, str) or not replicate_to.strip():
+            error(f"'replicate_to' must be a registry host[:port], got: {replicate_to!r}")
+        elif action != "delete":
+            error("'replicate_to' only applies to delete rules")
+
+    annotations = rule_data.get("annotations")
+    if annotations is not None:
+        if not isinstance(annotations, dict) or not all(
+            isinstance(k, str) and k and isinstance(p, str) and p for k, p in annotations.items()
+        ):
+            error(f"'annotations' must be a mapping of annotation keys to non-empty patterns, got: {annotations!r}")
+        else:
+            resolved: Dict[str, str] = {}
+            for key in annotations:
+                full_key = resolve_annotation_key(key)
+                if full_key in resolved:
+                    error(f"annotation '{key}' repeats '{resolved[full_key]}' (both mean {full_key})")
+                resolved.setdefault(full_key, key)
+
+    return issues
+
+
+def _rule_location(index: int, name: str) -> str:
+    """Location of a rule in issue messages"""
+    return f"rule {index + 1} ({name})"
+
+
+def _patterns_overlap(first: List[str], second: List[str]) -> bool:
+    """Whether two pattern lists can match a common value (empty matches everything)"""
+    if not first or not second:
+        return True
+    return any(_pattern_pair_overlaps(a, b) for a in first for b in second)
+
+
+def _literal_prefix(pattern: str) -> str:
+    """Part of a pattern before its first wildcard"""
+    for index, char in enumerate(pattern):
+        if char in "*?[":
+            return pattern[:index]
+    return pattern
+
+
+def _pattern_pair_overlaps(a: str, b: str) -> bool:
+    """Whether two patterns can match a common value.
+
+    Exact for literals; for two wildcard patterns, only their literal prefixes are
+    compared, so the answer may be a false positive but never a false negative.
+    """
+    a_literal = _literal_prefix(a) == a
+    b_literal = _literal_prefix(b) == b
+    if a_literal and b_literal:
+        return a == b
+    if a_literal:
+        return fnmatchcase(a, b)
+    if b_literal:
+        return fnmatchcase(b, a)
+    prefix_a, prefix_b = _literal_prefix(a), _literal_prefix(b)
+    return prefix_a.startswith(prefix_b) or prefix_b.startswith(prefix_a)
+
+
+def _patterns_cover(outer: List[str], inner: List[str]) -> bool:
+    """Whether every value matched by inner patterns is matched by outer patterns (approximately)"""
+    if not outer:
+        return True
+    if not inner:
+        return False
+    return all(any(fnmatchcase(p, q) for q in outer) for p in inner)
+
+
+def _rule_annotations(rule: PolicyRule) -> Dict[str, str]:
+    """A rule's annotation patterns keyed by full annotation key"""
+    return {resolve_annotation_key(key): pattern for key, pattern in rule.annotations.items()}
+
+
+def _age_range(rule: PolicyRule) -> Tuple[float, float]:
+    """Ages in days a rule can match, as [low, high)"""
+    low = float(rule.older_than_days) if rule.older_than_days is not None else 0.0
+    high = float(rule.newer_than_days) if rule.newer_than_days is not None else float("inf")
+    return low, high
+
+
+def rules_overlap(first: PolicyRule, second: PolicyRule) -> bool:
+    """Whether two rules can match the same image."""
+    if not _patterns_overlap(first.repositories, second.repositories):
+        return False
+    if not _patterns_overlap(first.tags, second.tags):
+        return False
+    second_annotations = _rule_annotations(second)
+    for key, pattern in _rule_annotations(first).items():
+        if key in second_annotations and not _pattern_pair_overlaps(pattern, second_annotations[key]):
+            return False
+    first_low, first_high = _age_range(first)
+    second_low, second_high = _age_range(second)
+    if max(first_low, second_low) >= min(first_high, second_high):
+        return False
+    if first.in_use is not None and second.in_use is not None and first.in_use != second.in_use:
+        return False
+    if (
+        first.has_provenance is not None
+        and second.has_provenance is not None
+        and first.has_provenance != second.has_provenance
+    ):
+        return False
+    # unused_for_days implies the image is not referenced by configuration, but it
+    # may still have old runs, so it does not exclude in_use: true
+    return True
+
+
+def rule_covers(outer: PolicyRule, inner: PolicyRule) -> bool:
+    """Whether outer matches every image inner matches."""
+    if not _patterns_cover(outer.repositories, inner.repositories):
+        return False
+    if not _patterns_cover(outer.tags, inner.tags):
+        return False
+    inner_annotations = _rule_annotations(inner)
+    for key, pattern in _rule_annotations(outer).items():
+        if key not in inner_annotations or not fnmatchcase(inner_annotations[key], pattern):
+            return False
+    outer_low, outer_high = _age_range(outer)
+    inner_low, inner_high = _age_range(inner)
+    if inner_low < outer_low or inner_high > outer_high:
+        return False
+    if outer.in_use is not None and outer.in_use != inner.in_use:
+        return False
+    if outer.has_provenance is not None and outer.has_provenance != inner.has_provenance:
+        return False
+    if outer.unused_for_days is not None and (
+        inner.unused_for_days is None or inner.unused_for_days < outer.unused_for_days
+    ):
+        return False
+    return True
+
+
+def _describe_conditions(rule: PolicyRule) -> str:
+    """Short description of a rule's conditions"""
+    parts = []
+    if rule.repositories:
+        parts.append(f"repositories {', '.join(rule.repositories)}")
+    if rule.tags:
+        parts.append(f"tags {', '.join(rule.tags)}")
+    if rule.older_than_days is not None:
+        parts.append(f"older than {rule.older_than_days} days")
+    if rule.newer_than_days is not None:
+        parts.append(f"newer than {rule.newer_than_days} days")
+    if rule.unused_for_days is not None:
+        parts.append(f"unused for {rule.unused_for_days} days")
+    if rule.in_use is not None:
+        parts.append("in use" if rule.in_use else "not in use")
+    for key, pattern in rule.annotations.items():
+        parts.append(f"annotation {key}={pattern}")
+    if rule.has_provenance is not None:
+        parts.append("with provenance" if rule.has_provenance else "without provenance")
+    return "; ".join(parts) or "every image"
+
+
+def _check_rules(rules: List[PolicyRule], default: str) -> List[PolicyIssue]:
+    """Check rules for contradictions, overlaps and rules that can never take effect"""
+    issues: List[PolicyIssue] = []
+    for index, rule in enumerate(rules):
+        location = _rule_location(index, rule.name)
+        low, high = _age_range(rule)
+        if low >= high:
+            issues.append(
+                PolicyIssue(
+                    "error",
+                    location,
+                    f"older_than_days ({rule.older_than_days}) must be less than newer_than_days "
+                    f"({rule.newer_than_days}); as written the rule never matches",
+                )
+            )
+        if rule.action == "delete" and _describe_conditions(rule) == "every image":
+            issues.append(
+                PolicyIssue(
+                    "warning",
+                    location,
+                    "delete rule has no conditions and deletes every image no keep rule matches; "
+                    "add conditions or use 'default: delete'",
+                )
+            )
+        if rule.action == default and _describe_conditions(rule) == "every image":
+            issues.append(
+                PolicyIssue("warning", location, f"rule has no conditions and repeats 'default: {default}'")
+            )
+
+    for delete_index, delete_rule in enumerate(rules):
+        if delete_rule.action != "delete":
+            continue
+        for keep_index, keep_rule in enumerate(rules):
+            if keep_rule.action != "keep" or not rules_overlap(keep_rule, delete_rule):
+                continue
+            if rule_covers(keep_rule, delete_rule):
+                message = (
+                    f"never deletes anything: every image it matches is also matched by keep rule "
+                    f"'{keep_rule.name}' ({_describe_conditions(keep_rule)}), and keep rules win"
+                )
+            else:
+                message = (
+                    f"overlaps keep rule '{keep_rule.name}': images matching both "
+                    f"({_describe_conditions(delete_rule)} / {_describe_conditions(keep_rule)}) are kept, "
+                    f"since keep rules win; narrow one of the rules if that is not intended"
+                )
+            issues.append(PolicyIssue("warning", _rule_location(delete_index, delete_rule.name), message))
+    return issues
+
+
+def lint_policy(data: Any) -> List[PolicyIssue]:
+    """Check a parsed policy document for errors and likely mistakes.
+
+    Errors make the policy unusable: bad syntax, unknown fields, wrong value types,
+    rules that can never match. Warnings flag rules that overlap or contradict each
+    other, such as an image both protected by a keep rule and expired by a delete
+    rule, which the policy resolves by keeping the image.
+
+    Args:
+        data: Parsed YAML document
+
+    Returns:
+        Issues found, errors first
+    """
+    if not isinstance(data, dict):
+        return [PolicyIssue("error", "policy", f"policy must be a YAML mapping, got: {type(data).__name__}")]
+
+    issues: List[PolicyIssue] = []
+    for name in data:
+        if name not in POLICY_FIELDS:
+            issues.append(PolicyIssue("error", "policy", _unknown_field_message(name, POLICY_FIELDS)))
+
+    version = data.get("version", POLICY_FORMAT_VERSION)
+    if version != POLICY_FORMAT_VERSION:
+        issues.append(
+            PolicyIssue(
+                "error",
+                "policy",
+                f"unsupported policy version {version!r} (this version reads {POLICY_FORMAT_VERSION})",
+            )
+        )
+
+    default = data.get("default", "keep")
+    if default not in POLICY_ACTIONS:
+        issues.append(
+            PolicyIssue("error", "policy", f"'default' must be one of {', '.join(POLICY_ACTIONS)}, got: {default!r}")
+        )
+
+    rules_data = data.get("rules") or []
+    if not isinstance(rules_data, list):
+        issues.append(PolicyIssue("error", "policy", "'rules' must be a list of rules"))
+        rules_data = []
+
+    names: Dict[str, int] = {}
+    for index, rule_data in enumerate(rules_data):
+        if not isinstance(rule_data, dict):
+            issues.append(PolicyIssue("error", f"rule {index + 1}", "rule must be a mapping of fields"))
+            continue
+        name = rule_data.get("name", f"rule-{index + 1}")
+        location = _rule_location(index, name)
+        issues.extend(_check_rule_fields(rule_data, location))
+        if name in names:
+            issues.append(
+                PolicyIssue("error", location, f"duplicate rule name (also rule {names[name] + 1}); names must be unique")
+            )
+        names.setdefault(name, index)
+
+    if not any(issue.severity == "error" for issue in issues):
+        rules = [PolicyRule(**{"name": f"rule-{i + 1}", **r}) for i, r in enumerate(rules_data)]
+        issues.extend(_check_rules(rules, default))
+        if not rules:
+            issues.append(PolicyIssue("warning", "policy", f"policy has no rules; every image gets '{default}'"))
+
+    issues.sort(key=lambda issue: issue.severity != "error")
+    return issues
+
+
+def policy_from_dict(data: Any) -> RetentionPolicy:
+    """Build a policy from a parsed YAML document.
+
+    Raises:
+        PolicyFormatError: If the document has errors (see lint_policy)
+    """
+    errors = [issue for issue in lint_policy(data) if issue.severity == "error"]
+    if errors:
+        raise PolicyFormatError(f"{errors[0].location}: {errors[0].message}")
+
+    rules = [PolicyRule(**{"name": f"rule-{i + 1}", **r}) for i, r in enumerate(data.get("rules") or [])]
+    return RetentionPolicy(rules=rules, default=data.get("default", "keep"), format_version=POLICY_FORMAT_VERSION)
+
+
+def read_policy_file(path: str) -> Any:
+    """Read a policy file as YAML.
+
+    Raises:
+        PolicyFormatError: If the file is not valid YAML
+    """
+    try:
+        with open(path, "r") as f:
+            return yaml.safe_load(f)
+    except yaml.YAMLError as e:
+        raise PolicyFormatError(f"Policy file '{path}' is not valid YAML: {e}") from e
+
+
+def load_policy(path: str) -> RetentionPolicy:
+    """Load a policy file.
+
+    Args:
+        path: Policy file path
+
+    Returns:
+        The loaded RetentionPolicy
+
+    Raises:
+        PolicyFormatError: If the file is not valid YAML or not a valid policy
+    """
+    return policy_from_dict(read_policy_file(path))
+
+
+def _matches_repository(patterns: List[str], repository: str, image_type: str) -> bool:
+    """Whether a repository patterns list matches an image's type or full repository"""
+    return any(fnmatchcase(image_type, p) or fnmatchcase(repository, p) for p in patterns)
+
+
+def rule_matches(
+    rule: PolicyRule,
+    image_id: str,
+    repository: str,
+    tag: str,
+    age_days: Optional[float],
+    usage: Optional[TagUsage],
+    usage_known: bool,
+    now: datetime,
+    annotations: Optional[Dict[str, str]] = None,
+    has_provenance: Optional[bool] = None,
+) -> Optional[bool]:
+    """Evaluate a rule against one image.
+
+    annotations is None when the image's OCI annotations were not collected, and
+    has_provenance is None when its provenance was not collected.
+
+    Returns:
+        True if every condition holds, False if any does not, None if no condition
+        fails but at least one could not be evaluated for lack of data
+    """
+    image_type = image_id.split(":", 1)[0]
+    if rule.repositories and not _matches_repository(rule.repositories, repository, image_type):
+        return False
+    if rule.tags and not any(fnmatchcase(tag, p) for p in rule.tags):
+        return False
+
+    undetermined = False
+    if rule.annotations:
+        matched = annotations_match(annotations, _rule_annotations(rule))
+        if matched is False:
+            return False
+        if matched is None:
+            undetermined = True
+
+    if rule.has_provenance is not None:
+        if has_provenance is None:
+            undetermined = True
+        elif has_provenance != rule.has_provenance:
+            return False
+
+    if rule.older_than_days is not None or rule.newer_than_days is not None:
+        if age_days is None:
+            undetermined = True
+        else:
+            if rule.older_than_days is not None and age_days < rule.older_than_days:
+                return False
+            if rule.newer_than_days is not None and age_days >= rule.newer_than_days:
+                return False
+
+    if rule.in_use is not None or rule.unused_for_days is not None:
+        if not usage_known:
+            undetermined = True
+        else:
+            in_use = bool(usage and (usage["use_count"] or usage["protected_by"]))
+            if rule.in_use is not None and in_use != rule.in_use:
+                return False
+            if rule.unused_for_days is not None and usage:
+                if usage["protected_by"]:
+                    return False
+                idle_days = days_since(usage["last_used"], now)
+                if idle_days is None:
+                    # Used, but by records without timestamps
+                    undetermined = True
+                elif idle_days < rule.unused_for_days:
+                    return False
+
+    return None if undetermined else True
+
+
+def evaluate_policy(
+    policy: RetentionPolicy,
+    analyzer: "ImageAnalyzer",
+    usage: Optional[Dict[str, TagUsage]] = None,
+    now: Optional[datetime] = None,
+    protection_days: int = 0,
+) -> List[PolicyDecision]:
+    """Decide for every analyzed image whether the policy keeps or deletes it.
+
+    Args:
+        policy: Policy to evaluate
+        analyzer: ImageAnalyzer instance with analyzed images (e.g. from a snapshot)
+        usage: Usage by tag; tags missing from it were never used. None if usage
+            data is not available.
+        now: Reference time for ages (default: current time)
+        protection_days: Images used within this many days are kept (0 = disabled)
+
+    Returns:
+        One decision per image, sorted by image_id
+    """
+    now = now or datetime.now(timezone.utc)
+    decisions: List[PolicyDecision] = []
+    for image_id, image_data in sorted(analyzer.images.items()):
+        age_days = days_since(parse_created(analyzer.created.get(image_id)), now)
+        tag_usage = usage.get(image_data["tag"]) if usage is not None else None
+        has_provenance = analyzer.provenance[image_id] is not None if image_id in analyzer.provenance else None
+
+        matched: List[PolicyRule] = []
+        undetermined: List[PolicyRule] = []
+        for rule in policy.rules:
+            result = rule_matches(
+                rule,
+                image_id,
+                image_data["repository"],
+                image_data["tag"],
+                age_days,
+                tag_usage,
+                usage is not None,
+                now,
+                analyzer.annotations.get(image_id),
+                has_provenance,
+            )
+            if result is True:
+                matched.append(rule)
+            elif result is None:
+                undetermined.append(rule)
+
+        # Undetermined keep rules keep the image; undetermined delete rules are ignored
+        keep_rules = [r for r in matched + undetermined if r.action == "keep"]
+        delete_rules = [r for r in matched if r.action == "delete"]
+        replicate_to = ""
+        if keep_rules:
+            action, rule_name = "keep", keep_rules[0].name
+        elif delete_rules:
+            action, rule_name = "delete", delete_rules[0].name
+            # Any matching delete rule that asks for a copy gets one
+            replicate_to = next((r.replicate_to for r in delete_rules if r.replicate_to), "")
+            idle_days = days_since(tag_usage["last_used"], now) if tag_usage else None
+            if protection_days and idle_days is not None and idle_days < protection_days:
+                action, rule_name, replicate_to = "keep", RECENT_RUN_PROTECTION_RULE, ""
+        else:
+            action, rule_name = policy.default, "default"
+
+        decisions.append(
+            {
+                "image_id": image_id,
+                "repository": image_data["repository"],
+                "tag": image_data["tag"],
+                "action": action,
+                "rule": rule_name,
+                "matched_rules": [r.name for r in matched],
+                "undetermined_rules": [r.name for r in undetermined],
+                "replicate_to": replicate_to,
+            }
+        )
+    return decisions
@@ -0,0 +1,146 @@
+"""
+Run identity and metadata for output documents.
+
+Every JSON document a run writes - reports, plans, snapshots, exports - starts
+with a "runMetadata" block, so files written by the same run can be matched
+up later, e.g. a plan with the snapshot it was built from and the apply
+outcome, or an uploaded report with the log lines of its run:
+
+    "runMetadata": {
+      "runId": "4f1c0b6e-...",
+      "startedAt": "2025-06-01T02:00:00+00:00",
+      "duration": 812.4,
+      "registry": "registry.example.com:5000",
+      "environment": "prod-us",
+      "toolVersion": "1.8.0",
+      "options": ["--apply", "--days", "30"]
+    }
+
+main.py gives each invocation a run ID and start time, and passes them to the
+scripts it starts through the RUN_ID and RUN_STARTED_AT environment variables,
+so the documents of every script of a combined command, and of every install
+of an environments batch, share them. A script run on its own gets a run ID of
+its own. environment is the install's name in an environments batch
+(RUN_ENVIRONMENT), or whatever RUN_ENVIRONMENT is set to, and null otherwise.
+duration is the number of seconds from the start of the run to the time the
+document was written. options are the script's command line arguments, with
+the values of credential options replaced by "<redacted>".
+"""
+
+import os
+import sys
+import uuid
+from datetime import datetime, timezone
+from typing import List, Optional, TypedDict
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+
+# Key of the metadata block in output documents
+RUN_METADATA_KEY = "runMetadata"
+
+RUN_ID_VARIABLE = "RUN_ID"
+RUN_STARTED_AT_VARIABLE = "RUN_STARTED_AT"
+RUN_ENVIRONMENT_VARIABLE = "RUN_ENVIRONMENT"
+
+# Command line options whose values are credentials
+SECRET_OPTIONS = ("--password", "--creds")
+REDACTED = "<redacted>"
+
+
+class RunMetadata(TypedDict):
+    """Metadata block of an output document."""
+
+    runId: str
+    startedAt: str  # ISO 8601, UTC
+    duration: float  # Seconds from the start of the run to the time of writing
+    registry: Optional[str]
+    environment: Optional[str]  # Install name in an environments batch
+    toolVersion: str
+    options: List[str]
+
+
+_run_id: Optional[str] = None
+_started_at: Optional[datetime] = None
+
+
+def start_run() -> str:
+    """Start the run of this process, unless a parent process already started it.
+
+    Sets RUN_ID and RUN_STARTED_AT in the environment, so the scripts this
+    process starts share them.
+
+    Returns:
+        The run ID
+    """
+    os.environ[RUN_ID_VARIABLE] = get_run_id()
+    os.environ[RUN_STARTED_AT_VARIABLE] = get_run_started_at().isoformat()
+    return os.environ[RUN_ID_VARIABLE]
+
+
+def get_run_id() -> str:
+    """ID of this run: RUN_ID if set, else an ID generated once per process"""
+    global _run_id
+    if _run_id is None:
+        _run_id = os.environ.get(RUN_ID_VARIABLE) or str(uuid.uuid4())
+    return _run_id
+
+
+def get_run_started_at() -> datetime:
+    """Start time of this run: RUN_STARTED_AT if set and valid, else the first call in this process"""
+    global _started_at
+    if _started_at is None:
+        try:
+            _started_at = datetime.fromisoformat(os.environ.get(RUN_STARTED_AT_VARIABLE, ""))
+        except ValueError:
+            _started_at = datetime.now(timezone.utc)
+        if _started_at.tzinfo is None:
+            _started_at = _started_at.replace(tzinfo=timezone.utc)
+    return _started_at
+
+
+def redact_options(args: List[str]) -> List[str]:
+    """Command line arguments with the values of credential options redacted"""
+    redacted = []
+    redact_next = False
+    for arg in args:
+        if redact_next:
+            redacted.append(REDACTED)
+            redact_next = False
+        elif arg in SECRET_OPTIONS:
+            redacted.append(arg)
+            redact_next = True
+        elif "=" in arg and arg.split("=", 1)[0] in SECRET_OPTIONS:
+            redacted.append(f"{arg.split('=', 1)[0]}={REDACTED}")
+        else:
+            redacted.append(arg)
+    return redacted
+
+
+def _registry_url() -> Optional[str]:
+    """Registry URL of the current configuration, or None if it has none"""
+    try:
+        return config_manager.get_registry_url() or None
+    except (KeyError, TypeError):
+        return None
+
+
+def get_run_metadata(now: Optional[datetime] = None) -> RunMetadata:
+    """Metadata block for a document written now (default: current time)"""
+    started_at = get_run_started_at()
+    return {
+        "runId": get_run_id(),
+        "startedAt": started_at.isoformat(),
+        "duration": round(max(((now or datetime.now(timezone.utc)) - started_at).total_seconds(), 0.0), 1),
+        "registry": _registry_url(),
+        "environment": os.environ.get(RUN_ENVIRONMENT_VARIABLE) or None,
+        "toolVersion": get_build_info()["version"],
+        "options": redact_options(sys.argv[1:]),
+    }
+
+
+def reset_run() -> None:
+    """Forget the run ID and start time of this process (for tests)"""
+    global _run_id, _started_at
+    _run_id = None
+    _started_at = None
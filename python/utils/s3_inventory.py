@@ -0,0 +1,258 @@
+"""
+Reconciliation of an S3-backed registry against an S3 Inventory report.
+
+An S3 Inventory report lists every object in a bucket, with its size and,
+when configured, its version and replication status. For the bucket a registry
+stores its data in, it is the authoritative count of stored (and billed) bytes,
+which is usually more than the bytes the registry's manifests reference. This
+module attributes the gap:
+
+    orphaned_blobs      blob data no manifest in any repository references
+    upload_debris       partial uploads left under repositories/<name>/_uploads/
+    replication_copies  noncurrent object versions kept by bucket versioning (which
+                        S3 replication requires), and objects replication wrote into
+                        the bucket outside the registry's data
+    registry_metadata   link files and other small files of the registry layout
+    other               any other objects in the bucket
+
+Only CSV inventories are read. The report is located by its manifest.json,
+either in S3 or downloaded with its data directory:
+
+    s3://inventory-bucket/registry-bucket/daily/2026-01-01T01-00Z/manifest.json
+    ./inventory/2026-01-01T01-00Z/manifest.json   (data files in ./inventory/data/)
+
+The inventory replaces listing the bucket; only manifests are read from it.
+"""
+
+import csv
+import gzip
+import io
+import json
+import os
+from typing import Any, Dict, Iterable, Iterator, List, Optional, Tuple, TypedDict
+from urllib.parse import unquote
+
+from utils.logging_utils import get_logger
+from utils.registry_storage import RegistryStorage
+
+logger = get_logger(__name__)
+
+REPLICATION_STATUS_REPLICA = "REPLICA"
+
+CATEGORIES = ("referenced", "orphaned_blobs", "upload_debris", "replication_copies", "registry_metadata", "other")
+
+
+class InventoryObject(TypedDict):
+    """An object version listed by an S3 Inventory report."""
+
+    key: str
+    size_bytes: int
+    is_latest: bool  # False for noncurrent versions
+    is_delete_marker: bool
+    replication_status: str  # Empty when the inventory does not include it
+
+
+def _split_s3_url(url: str) -> Tuple[str, str]:
+    """Split "s3://bucket/key" into (bucket, key)"""
+    bucket, _, key = url[len("s3://") :].partition("/")
+    if not bucket or not key:
+        raise ValueError(f"Invalid S3 URL {url!r} (expected s3://<bucket>/<key>)")
+    return bucket, key
+
+
+def _parse_bool(value: str, default: bool) -> bool:
+    """Parse an inventory boolean field ("true"/"false"), which is empty when not applicable"""
+    return value.strip().lower() == "true" if value.strip() else default
+
+
+def parse_inventory_csv(lines: Iterable[str], file_schema: str) -> Iterator[InventoryObject]:
+    """Parse the rows of one CSV inventory data file.
+
+    Args:
+        lines: Lines of the (decompressed) data file
+        file_schema: The manifest's fileSchema, e.g. "Bucket, Key, Size, IsLatest"
+
+    Raises:
+        ValueError: If the schema has no Key or Size field
+    """
+    fields = [field.strip() for field in file_schema.split(",")]
+    if "Key" not in fields or "Size" not in fields:
+        raise ValueError(f"Inventory schema must include Key and Size, got: {file_schema!r}")
+    for row in csv.reader(lines):
+        if not row:
+            continue
+        values = dict(zip(fields, row))
+        yield {
+            # Keys are URL-encoded in inventory reports
+            "key": unquote(values["Key"]),
+            "size_bytes": int(values.get("Size") or 0),
+            "is_latest": _parse_bool(values.get("IsLatest", ""), True),
+            "is_delete_marker": _parse_bool(values.get("IsDeleteMarker", ""), False),
+            "replication_status": values.get("ReplicationStatus", "").strip(),
+        }
+
+
+def _local_data_file(manifest_path: str, key: str) -> str:
+    """Find a data file of a downloaded inventory next to its manifest or in the data/ directory beside it"""
+    manifest_dir = os.path.dirname(os.path.abspath(manifest_path))
+    name = os.path.basename(key)
+    candidates = [
+        os.path.join(manifest_dir, name),
+        os.path.join(manifest_dir, "data", name),
+        os.path.join(os.path.dirname(manifest_dir), "data", name),
+    ]
+    for candidate in candidates:
+        if os.path.exists(candidate):
+            return candidate
+    raise FileNotFoundError(f"Inventory data file {name} not found next to {manifest_path} or in a data/ directory")
+
+
+def load_inventory(location: str, s3_client: Any = None) -> Tuple[Dict[str, Any], List[InventoryObject]]:
+    """Load an S3 Inventory report from its manifest.json.
+
+    Args:
+        location: s3://<bucket>/<key> or local path of the report's manifest.json
+        s3_client: boto3 S3 client (created from the default credential chain if needed and omitted)
+
+    Returns:
+        (manifest, objects)
+
+    Raises:
+        ValueError: If the inventory is not in CSV format
+    """
+
+    def get_s3_client() -> Any:
+        nonlocal s3_client
+        if s3_client is None:
+            import boto3
+
+            s3_client = boto3.client("s3")
+        return s3_client
+
+    if location.startswith("s3://"):
+        bucket, key = _split_s3_url(location)
+        manifest = json.loads(get_s3_client().get_object(Bucket=bucket, Key=key)["Body"].read())
+    else:
+        with open(location) as f:
+            manifest = json.load(f)
+
+    file_format = manifest.get("fileFormat", "CSV")
+    if file_format.upper() != "CSV":
+        raise ValueError(f"Only CSV inventories are supported, this one is {file_format}")
+
+    # Data files are in the destination bucket, given as an ARN (arn:aws:s3:::<bucket>)
+    destination = manifest.get("destinationBucket", "").rsplit(":", 1)[-1]
+
+    def read_file(key: str) -> bytes:
+        if location.startswith("s3://"):
+            return get_s3_client().get_object(Bucket=destination, Key=key)["Body"].read()
+        with open(_local_data_file(location, key), "rb") as f:
+            return f.read()
+
+    objects: List[InventoryObject] = []
+    for data_file in manifest.get("files", []):
+        data = read_file(data_file["key"])
+        if data_file["key"].endswith(".gz"):
+            data = gzip.decompress(data)
+        objects.extend(parse_inventory_csv(io.StringIO(data.decode("utf-8")), manifest.get("fileSchema", "")))
+    logger.info(f"Loaded {len(objects)} objects from the inventory of {manifest.get('sourceBucket', 'the bucket')}")
+    return manifest, objects
+
+
+class InventoryRegistryStorage(RegistryStorage):
+    """Registry storage listed from an inventory report, with files read from the bucket itself."""
+
+    def __init__(self, objects: List[InventoryObject], reader: RegistryStorage, prefix: str):
+        """Initialize the storage
+
+        Args:
+            objects: Objects of the inventory
+            reader: Storage to read files from (normally S3RegistryStorage of the same bucket)
+            prefix: Path of the registry data in the bucket
+        """
+        self.prefix = prefix.strip("/")
+        self.reader = reader
+        self.files = [
+            (obj["key"][len(self.prefix) + 1 :], obj["size_bytes"])
+            for obj in objects
+            if obj["is_latest"] and not obj["is_delete_marker"] and obj["key"].startswith(f"{self.prefix}/")
+        ]
+
+    def list_files(self, prefix: str) -> Iterator[Tuple[str, int]]:
+        for path, size in self.files:
+            if path.startswith(prefix):
+                yield path, size
+
+    def read(self, path: str) -> Optional[bytes]:
+        return self.reader.read(path)
+
+
+def _classify(obj: InventoryObject, prefix: str, unreferenced: Dict[str, int]) -> Tuple[str, Optional[str]]:
+    """Get the category of an inventory object and, for upload debris, its repository"""
+    if not obj["is_latest"] or obj["is_delete_marker"]:
+        return "replication_copies", None
+    if not obj["key"].startswith(f"{prefix}/"):
+        if obj["replication_status"] == REPLICATION_STATUS_REPLICA:
+            return "replication_copies", None
+        return "other", None
+    path = obj["key"][len(prefix) + 1 :]
+    parts = path.split("/")
+    if parts[0] == "blobs" and len(parts) == 5 and parts[4] == "data":
+        return ("orphaned_blobs" if f"{parts[1]}:{parts[3]}" in unreferenced else "referenced"), None
+    if parts[0] == "repositories":
+        repository, sep, _rest = path[len("repositories/") :].partition("/_uploads/")
+        if sep:
+            return "upload_debris", repository
+    return "registry_metadata", None
+
+
+def reconcile(objects: List[InventoryObject], prefix: str, orphans: Dict[str, Any]) -> Dict[str, Any]:
+    """Attribute the bytes stored in the bucket to what the registry references and to the gap.
+
+    Args:
+        objects: Objects of the inventory
+        prefix: Path of the registry data in the bucket
+        orphans: find_orphans result for the same storage
+
+    Returns:
+        Dict with "summary" (stored, referenced and gap bytes), "categories" (objects and bytes
+        per category) and "upload_debris" (bytes and uploads per repository)
+    """
+    prefix = prefix.strip("/")
+    unreferenced = {blob["digest"]: blob["size_bytes"] for blob in orphans["unreferenced_blobs"]}
+    categories: Dict[str, Dict[str, int]] = {name: {"objects": 0, "bytes": 0} for name in CATEGORIES}
+    noncurrent = {"objects": 0, "bytes": 0}
+    uploads: Dict[str, Dict[str, Any]] = {}
+    for obj in objects:
+        category, repository = _classify(obj, prefix, unreferenced)
+        categories[category]["objects"] += 1
+        categories[category]["bytes"] += obj["size_bytes"]
+        if category == "replication_copies" and not obj["is_latest"]:
+            noncurrent["objects"] += 1
+            noncurrent["bytes"] += obj["size_bytes"]
+        if repository is not None:
+            upload_id = obj["key"].split("/_uploads/", 1)[1].split("/", 1)[0]
+            entry = uploads.setdefault(repository, {"repository": repository, "uploads": set(), "bytes": 0})
+            entry["uploads"].add(upload_id)
+            entry["bytes"] += obj["size_bytes"]
+
+    upload_debris = [
+        {"repository": entry["repository"], "uploads": len(entry["uploads"]), "bytes": entry["bytes"]}
+        for entry in uploads.values()
+    ]
+    upload_debris.sort(key=lambda entry: entry["bytes"], reverse=True)
+
+    stored_bytes = sum(category["bytes"] for category in categories.values())
+    referenced_bytes = categories["referenced"]["bytes"]
+    return {
+        "summary": {
+            "objects": len(objects),
+            "stored_bytes": stored_bytes,
+            "referenced_bytes": referenced_bytes,
+            "gap_bytes": stored_bytes - referenced_bytes,
+            "noncurrent_versions": noncurrent["objects"],
+            "noncurrent_bytes": noncurrent["bytes"],
+        },
+        "categories": categories,
+        "upload_debris": upload_debris,
+    }
@@ -0,0 +1,97 @@
+"""
+Differences between two scans of the registry.
+
+watch rescans the registry at a fixed interval and reports only what changed
+since the previous scan: tags pushed, tags removed, tags that now point to a
+different digest, and how much the registry's stored bytes grew or shrank.
+Each scan is condensed to a ScanState first, so only the previous state, not
+the previous analyzer, is kept between iterations:
+
+    {"images": {"environment:abc-1": {"repository": ..., "tag": ..., "digest": ..., "size_bytes": ...}},
+     "total_bytes": 123456789}
+
+Intervals are given as a number with an optional unit: "90s", "15m", "1h", "1d"
+(seconds without a unit).
+"""
+
+import re
+from typing import TYPE_CHECKING, Dict, List, TypedDict
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+_INTERVAL_UNITS = {"": 1, "s": 1, "m": 60, "h": 3600, "d": 86400}
+
+
+class ScannedImage(TypedDict):
+    """An image as seen by one scan."""
+
+    repository: str
+    tag: str
+    digest: str
+    size_bytes: int
+
+
+class ScanState(TypedDict):
+    """The images of one scan and the bytes their layers take up in the registry."""
+
+    images: Dict[str, ScannedImage]
+    total_bytes: int
+
+
+class ScanDiff(TypedDict):
+    """Changes between two scans."""
+
+    added: List[str]  # image_ids, sorted
+    removed: List[str]
+    changed: List[str]  # image_ids whose tag now points to a different digest
+    size_delta_bytes: int  # change in bytes stored, counting shared layers once
+
+
+def parse_interval(value: str) -> int:
+    """Parse an interval such as "90s", "15m", "1h" or "1d" into seconds.
+
+    Raises:
+        ValueError: If the interval is malformed or not positive
+    """
+    match = re.fullmatch(r"\s*(\d+(?:\.\d+)?)\s*([smhd]?)\s*", value.lower())
+    if not match:
+        raise ValueError(f"Invalid interval {value!r} (expected e.g. 90s, 15m, 1h or 1d)")
+    seconds = int(float(match.group(1)) * _INTERVAL_UNITS[match.group(2)])
+    if seconds <= 0:
+        raise ValueError(f"Interval must be positive, got: {value!r}")
+    return seconds
+
+
+def scan_state(analyzer: "ImageAnalyzer") -> ScanState:
+    """Condense an analyzer's images into a ScanState"""
+    images: Dict[str, ScannedImage] = {
+        image_id: {
+            "repository": image_data["repository"],
+            "tag": image_data["tag"],
+            "digest": image_data.get("digest", ""),
+            "size_bytes": analyzer.get_image_total_size(image_id),
+        }
+        for image_id, image_data in analyzer.images.items()
+    }
+    return {"images": images, "total_bytes": sum(layer["size_bytes"] for layer in analyzer.layers.values())}
+
+
+def diff_scans(previous: ScanState, current: ScanState) -> ScanDiff:
+    """Compare two scans"""
+    before, after = previous["images"], current["images"]
+    return {
+        "added": sorted(after.keys() - before.keys()),
+        "removed": sorted(before.keys() - after.keys()),
+        "changed": sorted(
+            image_id
+            for image_id in after.keys() & before.keys()
+            if after[image_id]["digest"] != before[image_id]["digest"]
+        ),
+        "size_delta_bytes": current["total_bytes"] - previous["total_bytes"],
+    }
+
+
+def has_changes(diff: ScanDiff) -> bool:
+    """Whether anything changed between two scans"""
+    return bool(diff["added"] or diff["removed"] or diff["changed"] or diff["size_delta_bytes"])
@@ -0,0 +1,290 @@
+"""
+Saved registry scans.
+
+A snapshot records the result of one image analysis - every image with its
+digest, creation time, OCI annotations, labels, provenance and layers, and,
+when the MongoDB usage report was available, how each tag is used - so that it
+can be examined later without registry or MongoDB access. Policies are tested
+against snapshots (see scripts/policy.py) to iterate on retention rules offline.
+
+Snapshots are saved with a timestamp in their name, so repeated scans build a
+history. Snapshot retention (reports.snapshot_retention) prunes that history
+after each save: the newest keep_last snapshots are kept, plus the newest
+snapshot of each of the last keep_weekly weeks and keep_monthly months that
+have one. Everything else matching the snapshot file name is deleted.
+"""
+
+import json
+import re
+from datetime import datetime, timezone
+from pathlib import Path
+from typing import TYPE_CHECKING, Any, Dict, List, Optional, Tuple
+
+from utils.build_info import get_build_info
+from utils.config_manager import config_manager
+from utils.deletion_candidates import TagUsage
+from utils.logging_utils import get_logger
+from utils.redaction import redact_labels
+from utils.report_utils import save_json
+
+if TYPE_CHECKING:
+    from utils.image_data_analysis import ImageAnalyzer
+
+logger = get_logger(__name__)
+
+SNAPSHOT_FORMAT_VERSION = 1
+
+_TIMESTAMP_FORMAT = "%Y-%m-%d-%H-%M-%S"
+
+
+class SnapshotFormatError(ValueError):
+    """Raised when a snapshot file is malformed or has an unsupported version."""
+
+
+def build_snapshot(analyzer: "ImageAnalyzer", usage: Optional[Dict[str, TagUsage]] = None) -> Dict[str, Any]:
+    """Build a snapshot document from an analyzer.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        usage: Usage by tag, or None if usage data is not available
+
+    Returns:
+        JSON-compatible snapshot dict
+    """
+    image_layers: Dict[str, list] = {}
+    for mapping in analyzer.image_layers:
+        image_layers.setdefault(mapping["image_id"], []).append((mapping["order_index"], mapping["layer_id"]))
+
+    images = {}
+    for image_id, image_data in analyzer.images.items():
+        images[image_id] = {
+            "repository": image_data["repository"],
+            "tag": image_data["tag"],
+            "digest": image_data["digest"],
+            "created": analyzer.created.get(image_id),
+            "annotations": analyzer.annotations.get(image_id),
+            "labels": analyzer.labels.get(image_id),
+            "layers": [layer_id for _, layer_id in sorted(image_layers.get(image_id, []))],
+        }
+        if image_id in analyzer.provenance:
+            images[image_id]["provenance"] = analyzer.provenance[image_id]
+
+    snapshot: Dict[str, Any] = {
+        "format_version": SNAPSHOT_FORMAT_VERSION,
+        "registry_url": analyzer.registry_url,
+        "repository": analyzer.repository,
+        "created_at": datetime.now(timezone.utc).isoformat(),
+        "build": get_build_info(),
+        "images": images,
+        "layers": {layer_id: int(layer_data["size_bytes"]) for layer_id, layer_data in analyzer.layers.items()},
+        "foreign_layers": sorted(analyzer.foreign_layers),
+        "layer_media_types": dict(sorted(analyzer.layer_media_types.items())),
+        "usage": None,
+    }
+    if usage is not None:
+        snapshot["usage"] = {
+            tag: {
+                "use_count": tag_usage["use_count"],
+                "last_used": tag_usage["last_used"].isoformat() if tag_usage["last_used"] else None,
+                "protected_by": list(tag_usage["protected_by"]),
+            }
+            for tag, tag_usage in usage.items()
+        }
+    return snapshot
+
+
+def save_snapshot(
+    analyzer: "ImageAnalyzer", path: str, usage: Optional[Dict[str, TagUsage]] = None, timestamp: bool = False
+) -> str:
+    """Write a snapshot of an analyzer to disk.
+
+    Args:
+        analyzer: ImageAnalyzer instance with analyzed images
+        path: Destination file path
+        usage: Usage by tag, or None if usage data is not available
+        timestamp: If True, add a timestamp to the filename
+
+    Returns:
+        Path the snapshot was written to
+    """
+    # Snapshots stay uncompressed: later commands find and read them by file name
+    saved_path = save_json(path, build_snapshot(analyzer, usage), timestamp=timestamp, compress="")
+    logger.info(f"Snapshot of {len(analyzer.images)} image(s) saved to {saved_path}")
+    return saved_path
+
+
+def find_snapshots(path: str) -> List[Tuple[Path, datetime]]:
+    """Find the timestamped snapshots saved for a snapshot path.
+
+    Args:
+        path: Configured snapshot path (e.g. reports/scan-snapshot.json)
+
+    Returns:
+        (path, time saved) of each snapshot, newest first
+    """
+    base = Path(path)
+    if not base.parent.is_dir():
+        return []
+    name_pattern = re.compile(rf"^{re.escape(base.stem)}-(\d{{4}}(?:-\d{{2}}){{5}}){re.escape(base.suffix)}$")
+    snapshots = []
+    for candidate in base.parent.iterdir():
+        match = name_pattern.match(candidate.name)
+        if match and candidate.is_file():
+            snapshots.append((candidate, datetime.strptime(match.group(1), _TIMESTAMP_FORMAT)))
+    return sorted(snapshots, key=lambda snapshot: snapshot[1], reverse=True)
+
+
+def find_latest_snapshot() -> Optional[str]:
+    """Newest saved scan snapshot, or the untimestamped snapshot file if there is no timestamped one"""
+    snapshot_path = config_manager.get_snapshot_path()
+    snapshots = find_snapshots(snapshot_path)
+    if snapshots:
+        return str(snapshots[0][0])
+    return snapshot_path if Path(snapshot_path).is_file() else None
+
+
+def select_snapshots_to_prune(
+    snapshots: List[Tuple[Path, datetime]], keep_last: int = 0, keep_weekly: int = 0, keep_monthly: int = 0
+) -> List[Path]:
+    """Select the snapshots a retention setting no longer keeps.
+
+    Args:
+        snapshots: (path, time saved) of each snapshot, newest first
+        keep_last: Number of newest snapshots to keep
+        keep_weekly: Number of most recent weeks to keep the newest snapshot of
+        keep_monthly: Number of most recent months to keep the newest snapshot of
+
+    Returns:
+        Paths to delete, or an empty list if retention is off (all limits 0)
+    """
+    if not (keep_last or keep_weekly or keep_monthly):
+        return []
+
+    kept = {path for path, _ in snapshots[:keep_last]}
+    for period_count, period_of in (
+        (keep_weekly, lambda saved: saved.isocalendar()[:2]),
+        (keep_monthly, lambda saved: (saved.year, saved.month)),
+    ):
+        periods: List[Any] = []
+        for path, saved in snapshots:
+            period = period_of(saved)
+            if period in periods:
+                continue
+            if len(periods) == period_count:
+                break
+            periods.append(period)
+            kept.add(path)
+
+    # The newest snapshot is always kept
+    if snapshots:
+        kept.add(snapshots[0][0])
+    return [path for path, _ in snapshots if path not in kept]
+
+
+def prune_snapshots(path: str, retention: Dict[str, int]) -> List[str]:
+    """Delete the snapshots a retention setting no longer keeps.
+
+    Args:
+        path: Configured snapshot path (e.g. reports/scan-snapshot.json)
+        retention: keep_last, keep_weekly and keep_monthly (see ConfigManager.get_snapshot_retention)
+
+    Returns:
+        Paths of the deleted snapshots
+    """
+    deleted = []
+    for snapshot_path in select_snapshots_to_prune(find_snapshots(path), **retention):
+        try:
+            snapshot_path.unlink()
+        except OSError as e:
+            logger.warning(f"Could not delete old snapshot {snapshot_path}: {e}")
+            continue
+        deleted.append(str(snapshot_path))
+    if deleted:
+        logger.info(f"Deleted {len(deleted)} snapshot(s) outside the snapshot retention")
+    return deleted
+
+
+def read_snapshot(path: str) -> Dict[str, Any]:
+    """Read a snapshot document without loading it into an analyzer.
+
+    Args:
+        path: Snapshot file path
+
+    Returns:
+        The snapshot document (see build_snapshot)
+
+    Raises:
+        SnapshotFormatError: If the file is not valid JSON or not a supported snapshot
+    """
+    try:
+        with open(path, "r") as f:
+            data = json.load(f)
+    except json.JSONDecodeError as e:
+        raise SnapshotFormatError(f"Snapshot file '{path}' is not valid JSON: {e}") from e
+
+    if not isinstance(data, dict):
+        raise SnapshotFormatError("Snapshot must be a JSON object")
+    version = data.get("format_version")
+    if version != SNAPSHOT_FORMAT_VERSION:
+        raise SnapshotFormatError(
+            f"Unsupported snapshot format_version {version!r} (this version reads {SNAPSHOT_FORMAT_VERSION})"
+        )
+    for key in ("registry_url", "repository", "images", "layers"):
+        if key not in data:
+            raise SnapshotFormatError(f"Snapshot is missing required field '{key}'")
+    return data
+
+
+def load_snapshot(path: str) -> Tuple["ImageAnalyzer", Optional[Dict[str, TagUsage]], Dict[str, Any]]:
+    """Load a snapshot into a new analyzer.
+
+    Args:
+        path: Snapshot file path
+
+    Returns:
+        Tuple of (analyzer holding the snapshot's images, usage by tag or None,
+        snapshot metadata: registry_url, repository, created_at, build)
+
+    Raises:
+        SnapshotFormatError: If the file is not valid JSON or not a supported snapshot
+    """
+    from utils.image_data_analysis import ImageAnalyzer
+
+    data = read_snapshot(path)
+    analyzer = ImageAnalyzer(data["registry_url"], data["repository"])
+    layer_sizes = data["layers"]
+    # Snapshots saved before a key was added to reports.redact_keys may still hold its values
+    redact_patterns = config_manager.get_redact_key_patterns()
+    for image_id, image_data in data["images"].items():
+        try:
+            layers = [(layer_id, layer_sizes[layer_id]) for layer_id in image_data["layers"]]
+            analyzer.index.add_image(
+                image_id, image_data["repository"], image_data["tag"], image_data["digest"], layers
+            )
+        except (KeyError, TypeError) as e:
+            raise SnapshotFormatError(f"Snapshot image '{image_id}' is invalid: {e}") from e
+        if image_data.get("created"):
+            analyzer.created[image_id] = image_data["created"]
+        if isinstance(image_data.get("annotations"), dict):
+            analyzer.annotations[image_id] = image_data["annotations"]
+        if isinstance(image_data.get("labels"), dict):
+            analyzer.labels[image_id] = redact_labels(image_data["labels"], redact_patterns)
+        if "provenance" in image_data:
+            analyzer.provenance[image_id] = image_data["provenance"]
+
+    analyzer.foreign_layers = set(data.get("foreign_layers") or [])
+    analyzer.layer_media_types = dict(data.get("layer_media_types") or {})
+
+    usage: Optional[Dict[str, TagUsage]] = None
+    if data.get("usage") is not None:
+        usage = {}
+        for tag, tag_usage in data["usage"].items():
+            last_used = tag_usage.get("last_used")
+            usage[tag] = {
+                "use_count": int(tag_usage.get("use_count", 0)),
+                "last_used": datetime.fromisoformat(last_used) if last_used else None,
+                "protected_by": list(tag_usage.get("protected_by", [])),
+            }
+
+    metadata = {key: data.get(key) for key in ("registry_url", "repository", "created_at", "build")}
+    return analyzer, usage, metadata
@@ -0,0 +1,131 @@
+"""
+Secret detection in image metadata.
+
+Environment images often leak credentials through their metadata: an
+`ENV AWS_SECRET_ACCESS_KEY=...` in a Dockerfile, a token baked into a label,
+or a `RUN curl -H "Authorization: token ..."` step recorded in the history.
+Image analysis checks the Env values and labels of every fully inspected image
+(before they are redacted for reports), and on deep scans the created_by
+command of every build step, against the patterns below and any extra ones in
+analysis.secret_patterns:
+
+    analysis:
+      secret_patterns:
+        internal_api_key: "ik_[0-9a-f]{32}"
+
+Findings never contain the secret itself, only a short masked preview:
+
+    {"source": "env", "key": "AWS_SECRET_ACCESS_KEY", "rule": "aws_secret_access_key", "preview": "wJal...(40 chars)"}
+"""
+
+import re
+from typing import Dict, Iterable, List, Optional, Pattern, Tuple, TypedDict
+
+from utils.redaction import is_sensitive_key
+
+SOURCE_ENV = "env"
+SOURCE_LABEL = "label"
+SOURCE_HISTORY = "history"
+
+# Rule name -> pattern whose match is the secret
+BUILTIN_SECRET_PATTERNS: Dict[str, str] = {
+    "aws_access_key_id": r"\b(?:AKIA|ASIA)[0-9A-Z]{16}\b",
+    "aws_secret_access_key": r"(?i)aws.{0,20}?secret.{0,20}?[=:\s\"']([A-Za-z0-9/+]{40})\b",
+    "github_token": r"\bgh[pousr]_[A-Za-z0-9]{36,255}\b",
+    "gitlab_token": r"\bglpat-[A-Za-z0-9_\-]{20,}\b",
+    "slack_token": r"\bxox[abposr]-[A-Za-z0-9\-]{10,}\b",
+    "google_api_key": r"\bAIza[0-9A-Za-z_\-]{35}\b",
+    "private_key": r"-----BEGIN (?:[A-Z]+ )?PRIVATE KEY-----",
+    "jwt": r"\beyJ[A-Za-z0-9_\-]{10,}\.eyJ[A-Za-z0-9_\-]{10,}\.[A-Za-z0-9_\-]{10,}",
+    "url_credentials": r"[a-z][a-z0-9+.\-]*://[^/\s:@]+:([^/\s@]{3,})@",
+}
+
+# Values of sensitive names (see utils.redaction) this short, or referring to
+# another variable, are placeholders rather than secrets
+_MIN_GENERIC_SECRET_LENGTH = 8
+_PLACEHOLDER = re.compile(r"^(\$\{?\w+\}?|<[^>]*>|changeme|none|null|true|false|x+|\*+)$", re.IGNORECASE)
+
+
+class SecretFinding(TypedDict):
+    """A possible secret in an image's metadata."""
+
+    source: str  # SOURCE_ENV, SOURCE_LABEL or SOURCE_HISTORY
+    key: str  # variable name, label key, or "step <n>" of the history
+    rule: str  # pattern that matched, or "sensitive_name" for a secret-looking name with a value
+    preview: str  # masked value: its first characters and length
+
+
+def compile_patterns(extra: Optional[Dict[str, str]] = None) -> List[Tuple[str, Pattern[str]]]:
+    """Compile the built-in secret patterns and any extra ones (rule name -> regex)"""
+    patterns = dict(BUILTIN_SECRET_PATTERNS)
+    patterns.update(extra or {})
+    return [(rule, re.compile(pattern)) for rule, pattern in patterns.items()]
+
+
+def mask(value: str) -> str:
+    """A preview of a secret that does not reveal it"""
+    return f"{value[:4]}...({len(value)} chars)"
+
+
+def scan_value(text: str, patterns: List[Tuple[str, Pattern[str]]]) -> List[Tuple[str, str]]:
+    """Find secrets in one value.
+
+    Returns:
+        (rule, matched secret) for every pattern that matches
+    """
+    found = []
+    for rule, pattern in patterns:
+        match = pattern.search(text)
+        if match:
+            found.append((rule, match.group(match.lastindex or 0)))
+    return found
+
+
+def _findings(
+    source: str, items: Iterable[Tuple[str, str]], patterns: List[Tuple[str, Pattern[str]]], sensitive: List[str]
+) -> List[SecretFinding]:
+    """Scan (key, value) pairs, flagging pattern matches and sensitive names with real-looking values"""
+    findings: List[SecretFinding] = []
+    for key, value in items:
+        matches = scan_value(value, patterns)
+        for rule, secret in matches:
+            findings.append({"source": source, "key": key, "rule": rule, "preview": mask(secret)})
+        if (
+            not matches
+            and sensitive
+            and is_sensitive_key(key, sensitive)
+            and len(value) >= _MIN_GENERIC_SECRET_LENGTH
+            and not _PLACEHOLDER.match(value.strip())
+        ):
+            findings.append({"source": source, "key": key, "rule": "sensitive_name", "preview": mask(value)})
+    return findings
+
+
+def scan_metadata(
+    env: Optional[List[str]],
+    labels: Optional[Dict[str, str]],
+    patterns: List[Tuple[str, Pattern[str]]],
+    sensitive_keys: List[str],
+) -> List[SecretFinding]:
+    """Find secrets in an image's Env entries (NAME=value) and labels.
+
+    Args:
+        env: Env entries of the image config, unredacted
+        labels: Labels of the image config, unredacted
+        patterns: Compiled secret patterns (compile_patterns)
+        sensitive_keys: Names whose values count as secrets on their own (reports.redact_keys)
+    """
+    env_items = []
+    for entry in env or []:
+        name, separator, value = entry.partition("=")
+        if separator:
+            env_items.append((name, value))
+    return _findings(SOURCE_ENV, env_items, patterns, sensitive_keys) + _findings(
+        SOURCE_LABEL, sorted((labels or {}).items()), patterns, sensitive_keys
+    )
+
+
+def scan_history(commands: List[Optional[str]], patterns: List[Tuple[str, Pattern[str]]]) -> List[SecretFinding]:
+    """Find secrets in the created_by commands of an image's build steps (deep scans)"""
+    items = [(f"step {index}", command) for index, command in enumerate(commands) if command]
+    return _findings(SOURCE_HISTORY, items, patterns, [])
@@ -0,0 +1,395 @@
+"""
+Shell completion scripts for the docker-registry-cleaner command.
+
+Completion data is read statically from each script's argparse calls (see
+script_options), so generating a completion script imports none of the
+scripts and needs no registry, MongoDB or Kubernetes access. Repository names
+and tags are completed at completion time by calling back into
+`docker-registry-cleaner completion --list ...`, which reads the inspect cache
+left by earlier scans; without a cache those completions are simply empty.
+"""
+
+import ast
+import re
+from dataclasses import dataclass, field
+from pathlib import Path
+from typing import Dict, List, Optional
+
+from utils.cache_utils import DigestInspectCache
+
+PROG = "docker-registry-cleaner"
+SHELLS = ("bash", "zsh", "fish")
+
+# What `completion --list` can print, completed from the inspect cache
+LIST_KINDS = ("repositories", "images", "references")
+
+# Options whose values are image types, completed with `--list repositories`
+REPOSITORY_OPTIONS = ("--image-types",)
+
+# Scripts whose positional argument is an image, and the form they expect it in:
+# "references" for repository/type:tag, "images" for type:tag
+IMAGE_POSITIONALS = {
+    "delete_image": "references",
+    "simulate_deletion": "images",
+    "inspect-size": "images",
+}
+
+_NO_VALUE_ACTIONS = ("store_true", "store_false", "store_const", "count", "help", "version")
+
+
+@dataclass
+class ScriptOption:
+    """One command line option of a script"""
+
+    flags: List[str]
+    help: str = ""
+    takes_value: bool = True
+
+
+@dataclass
+class ScriptCompletion:
+    """Everything completion knows about one script keyword"""
+
+    name: str
+    description: str
+    options: List[ScriptOption] = field(default_factory=list)
+    subcommands: List[str] = field(default_factory=list)
+    images: Optional[str] = None
+
+    @property
+    def flags(self) -> List[str]:
+        return [flag for option in self.options for flag in option.flags]
+
+
+def _short_help(text: str) -> str:
+    """First sentence of a help string, without format placeholders"""
+    text = " ".join(text.split())
+    text = re.sub(r"\s*\([^()]*%\([^)]*\)[^()]*\)", "", text).split("%(")[0].rstrip()
+    text = text.split(". ")[0].rstrip(".")
+    if len(text) > 80:
+        text = text[:77].rsplit(" ", 1)[0] + "..."
+    return text
+
+
+def script_options(script_path: str) -> ScriptCompletion:
+    """Read the options and subcommands a script defines with argparse.
+
+    Only literal arguments are understood: option strings, and help texts and
+    actions given as plain strings. Options are collected from every parser
+    in the file, so a script's subcommands share one option list.
+
+    Args:
+        script_path: Path to the script source
+
+    Returns:
+        ScriptCompletion with options and subcommands (name and description empty)
+    """
+    tree = ast.parse(Path(script_path).read_text(), filename=script_path)
+    completion = ScriptCompletion(name="", description="")
+    seen = set()
+    for node in ast.walk(tree):
+        if not (isinstance(node, ast.Call) and isinstance(node.func, ast.Attribute)):
+            continue
+        literals = [arg.value for arg in node.args if isinstance(arg, ast.Constant) and isinstance(arg.value, str)]
+        keywords = {
+            kw.arg: kw.value.value for kw in node.keywords if kw.arg and isinstance(kw.value, ast.Constant)
+        }
+        if node.func.attr == "add_parser" and literals:
+            completion.subcommands.append(literals[0])
+        elif node.func.attr == "add_argument":
+            flags = [literal for literal in literals if literal.startswith("-") and literal not in seen]
+            if not flags:
+                continue
+            seen.update(flags)
+            completion.options.append(
+                ScriptOption(
+                    flags=flags,
+                    help=_short_help(str(keywords.get("help") or "")),
+                    takes_value=keywords.get("action") not in _NO_VALUE_ACTIONS,
+                )
+            )
+    return completion
+
+
+def collect_scripts(
+    script_paths: Dict[str, Optional[str]], descriptions: Dict[str, str], python_dir: str
+) -> List[ScriptCompletion]:
+    """Build completion data for every script keyword of main.py.
+
+    Args:
+        script_paths: Script keyword -> script path relative to python_dir (None for built-ins)
+        descriptions: Script keyword -> description
+        python_dir: Directory holding main.py
+
+    Returns:
+        One ScriptCompletion per keyword, in keyword order
+    """
+    # Built-in keywords without a script of their own
+    composed = {
+        "delete_all_unused_environments": [
+            "scripts/delete_unused_environments.py",
+            "scripts/delete_unused_private_environments.py",
+        ],
+    }
+
+    scripts = []
+    for name, relative_path in sorted(script_paths.items()):
+        completion = ScriptCompletion(name=name, description=descriptions.get(name, ""))
+        sources = [relative_path] if relative_path else composed.get(name, [])
+        for source in sources:
+            parsed = script_options(str(Path(python_dir) / source))
+            known = set(completion.flags)
+            completion.options.extend(o for o in parsed.options if not set(o.flags) & known)
+            completion.subcommands.extend(parsed.subcommands)
+        if name == "completion":
+            completion.subcommands = list(SHELLS)
+        elif name == "version":
+            completion.options = [ScriptOption(["--json"], "Print the metadata as JSON", takes_value=False)]
+        completion.images = IMAGE_POSITIONALS.get(name)
+        scripts.append(completion)
+    return scripts
+
+
+def list_cached(kind: str, cache_path: str) -> List[str]:
+    """List repositories or images recorded in the inspect cache.
+
+    Args:
+        kind: "repositories" (image types, e.g. environment), "images"
+            (type:tag) or "references" (repository/type:tag)
+        cache_path: Inspect cache file
+
+    Returns:
+        Sorted unique values; empty if there is no cache
+    """
+    if kind not in LIST_KINDS:
+        raise ValueError(f"Unknown list kind '{kind}' (expected one of: {', '.join(LIST_KINDS)})")
+    if not Path(cache_path).exists():
+        return []
+
+    values = set()
+    for reference in DigestInspectCache(cache_path).tag_references():
+        repository, _, tag = reference.rpartition(":")
+        image_type = repository.rsplit("/", 1)[-1]
+        if kind == "repositories":
+            values.add(image_type)
+        elif kind == "images":
+            values.add(f"{image_type}:{tag}")
+        else:
+            values.add(reference)
+    return sorted(values)
+
+
+def _list_command(kind: str) -> str:
+    return f"{PROG} completion --list {kind} 2>/dev/null"
+
+
+def _bash(scripts: List[ScriptCompletion], main_flags: List[str]) -> str:
+    func = "_" + PROG.replace("-", "_")
+    cases = []
+    for script in scripts:
+        lines = [f'            flags="{" ".join(script.flags)}"']
+        if script.subcommands:
+            lines.append(f'            subcommands="{" ".join(script.subcommands)}"')
+        if script.images:
+            lines.append(f"            images={script.images}")
+        cases.append(f"        {script.name})\n" + "\n".join(lines) + "\n            ;;")
+    repository_options = "|".join(REPOSITORY_OPTIONS)
+
+    return f"""# bash completion for {PROG}
+# Load with: source <({PROG} completion bash)
+
+{func}() {{
+    local cur prev words cword
+    if declare -F _get_comp_words_by_ref >/dev/null; then
+        _get_comp_words_by_ref -n : cur prev words cword
+    else
+        words=("${{COMP_WORDS[@]}}")
+        cword=$COMP_CWORD
+        cur="${{COMP_WORDS[COMP_CWORD]}}"
+        prev="${{COMP_WORDS[COMP_CWORD-1]}}"
+    fi
+
+    local script="" script_index=0 i
+    for ((i = 1; i < cword; i++)); do
+        if [[ "${{words[i]}}" != -* ]]; then
+            script="${{words[i]}}"
+            script_index=$i
+            break
+        fi
+    done
+
+    if [[ -z "$script" ]]; then
+        if [[ "$cur" == -* ]]; then
+            COMPREPLY=($(compgen -W "{" ".join(main_flags)}" -- "$cur"))
+        else
+            COMPREPLY=($(compgen -W "{" ".join(s.name for s in scripts)}" -- "$cur"))
+        fi
+        return
+    fi
+
+    case "$prev" in
+        {repository_options})
+            COMPREPLY=($(compgen -W "$({_list_command("repositories")})" -- "$cur"))
+            return
+            ;;
+    esac
+
+    local flags="" subcommands="" images=""
+    case "$script" in
+{chr(10).join(cases)}
+    esac
+
+    if [[ "$cur" == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags" -- "$cur"))
+    elif [[ -n "$subcommands" && $cword -eq $((script_index + 1)) ]]; then
+        COMPREPLY=($(compgen -W "$subcommands" -- "$cur"))
+    elif [[ -n "$images" ]]; then
+        COMPREPLY=($(compgen -W "$({PROG} completion --list "$images" 2>/dev/null)" -- "$cur"))
+        if declare -F __ltrim_colon_completions >/dev/null; then
+            __ltrim_colon_completions "$cur"
+        fi
+    fi
+}}
+
+complete -o default -F {func} {PROG}
+"""
+
+
+def _zsh_quote(text: str) -> str:
+    return "'" + text.replace("'", "'\\''") + "'"
+
+
+def _zsh(scripts: List[ScriptCompletion], main_flags: List[str]) -> str:
+    func = "_" + PROG.replace("-", "_")
+    described = "\n".join(
+        f"                {_zsh_quote(s.name + ':' + s.description.replace(':', chr(92) + ':'))}" for s in scripts
+    )
+    cases = []
+    for script in scripts:
+        options = " ".join(
+            _zsh_quote(f"{flag}:{option.help.replace(':', chr(92) + ':')}")
+            for option in script.options
+            for flag in option.flags
+        )
+        lines = [f"            options=({options})"]
+        if script.subcommands:
+            lines.append(f"            subcommands=({' '.join(script.subcommands)})")
+        if script.images:
+            lines.append(f"            images={script.images}")
+        cases.append(f"        {script.name})\n" + "\n".join(lines) + "\n            ;;")
+    repository_options = "|".join(REPOSITORY_OPTIONS)
+
+    return f"""#compdef {PROG}
+# zsh completion for {PROG}
+# Load with: source <({PROG} completion zsh), or save as _{PROG} in a directory on $fpath
+
+{func}() {{
+    local script="" script_index=0 i
+    for ((i = 2; i < CURRENT; i++)); do
+        if [[ "${{words[i]}}" != -* ]]; then
+            script="${{words[i]}}"
+            script_index=$i
+            break
+        fi
+    done
+
+    if [[ -z "$script" ]]; then
+        if [[ "${{words[CURRENT]}}" == -* ]]; then
+            compadd -- {" ".join(main_flags)}
+        else
+            local -a scripts
+            scripts=(
+{described}
+            )
+            _describe 'script' scripts
+        fi
+        return
+    fi
+
+    case "${{words[CURRENT-1]}}" in
+        {repository_options})
+            compadd -- ${{(f)"$({_list_command("repositories")})"}}
+            return
+            ;;
+    esac
+
+    local -a options subcommands
+    local images=""
+    case "$script" in
+{chr(10).join(cases)}
+    esac
+
+    if [[ "${{words[CURRENT]}}" == -* ]]; then
+        _describe 'option' options
+    elif (( ${{#subcommands}} && CURRENT == script_index + 1 )); then
+        compadd -- $subcommands
+    elif [[ -n "$images" ]]; then
+        compadd -- ${{(f)"$({PROG} completion --list $images 2>/dev/null)"}}
+    else
+        _files
+    fi
+}}
+
+if [[ "${{zsh_eval_context[-1]}}" == loadautofunc ]]; then
+    {func} "$@"
+else
+    compdef {func} {PROG}
+fi
+"""
+
+
+def _fish_quote(text: str) -> str:
+    return "'" + text.replace("\\", "\\\\").replace("'", "\\'") + "'"
+
+
+def _fish(scripts: List[ScriptCompletion], main_flags: List[str]) -> str:
+    lines = [
+        f"# fish completion for {PROG}",
+        f"# Load with: {PROG} completion fish | source",
+        "",
+        f"complete -c {PROG} -f",
+    ]
+    for flag in main_flags:
+        lines.append(f"complete -c {PROG} -n __fish_use_subcommand -l {flag.lstrip('-')}")
+    for script in scripts:
+        lines.append(f"complete -c {PROG} -n __fish_use_subcommand -a {script.name} -d {_fish_quote(script.description)}")
+
+    for script in scripts:
+        seen = f"__fish_seen_subcommand_from {script.name}"
+        lines.append("")
+        if script.subcommands:
+            subcommands = " ".join(script.subcommands)
+            lines.append(
+                f"complete -c {PROG} -n '{seen}; and not __fish_seen_subcommand_from {subcommands}' -a '{subcommands}'"
+            )
+        if script.images:
+            lines.append(f'complete -c {PROG} -n "{seen}" -a "({_list_command(script.images)})"')
+        for option in script.options:
+            parts = [f"complete -c {PROG} -n '{seen}'"]
+            for flag in option.flags:
+                parts.append(f"-l {flag[2:]}" if flag.startswith("--") else f"-s {flag[1:]}")
+            if any(flag in REPOSITORY_OPTIONS for flag in option.flags):
+                parts.append(f'-x -a "({_list_command("repositories")})"')
+            elif option.takes_value:
+                parts.append("-r -F")
+            if option.help:
+                parts.append(f"-d {_fish_quote(option.help)}")
+            lines.append(" ".join(parts))
+    return "\n".join(lines) + "\n"
+
+
+def generate_completion(shell: str, scripts: List[ScriptCompletion], main_flags: List[str]) -> str:
+    """Generate the completion script for a shell.
+
+    Args:
+        shell: "bash", "zsh" or "fish"
+        scripts: Completion data from collect_scripts
+        main_flags: Options main.py accepts before the script keyword
+
+    Returns:
+        Completion script source
+    """
+    generators = {"bash": _bash, "zsh": _zsh, "fish": _fish}
+    if shell not in generators:
+        raise ValueError(f"Unsupported shell '{shell}' (expected one of: {', '.join(SHELLS)})")
+    return generators[shell](scripts, main_flags)
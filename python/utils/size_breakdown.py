@@ -0,0 +1,187 @@
+"""
+Layer-by-layer size breakdown of one image.
+
+inspect-size explains why an image is big, for the authors of environments:
+each layer with its size, its share of the image, whether other images
+share it (a layer shared with the base image or other environments costs the
+registry nothing extra), and, with --deep, the build step that created it.
+
+Build steps come from the history of the image config. Metadata-only steps
+(ENV, LABEL, ...) add no layer and are skipped, so the remaining steps line up
+with the layers in order. Images built by tools that do not record history, or
+record it partially, get no commands.
+
+Suggestions are simple rules over the build steps of large layers, e.g. a
+package install that leaves its cache behind:
+
+    apt-get install ... without rm -rf /var/lib/apt/lists/*
+    pip install ... without --no-cache-dir
+    conda install ... without conda clean
+"""
+
+import re
+from typing import Dict, List, Optional, Tuple, TypedDict
+
+from utils.image_config import HistoryEntry
+from utils.report_utils import sizeof_fmt
+
+# Layers smaller than this get no suggestions, so the report points at what matters
+SUGGESTION_MIN_BYTES = 10 * 1000**2
+
+# A layer this large a share of the image is called out when nothing else shares it
+LARGE_LAYER_PERCENT = 25.0
+
+# (pattern the build step must match, pattern that shows the cache is already cleaned, suggestion)
+_CACHE_RULES: List[Tuple[str, str, str]] = [
+    (
+        r"apt-get\s+(?:-\S+\s+)*install",
+        r"rm\s+-(?:rf|fr)\s+/var/lib/apt/lists",
+        "remove the package lists in the same RUN: && rm -rf /var/lib/apt/lists/*",
+    ),
+    (r"\bpip3?\s+install", r"--no-cache-dir|PIP_NO_CACHE_DIR", "add --no-cache-dir to pip install"),
+    (
+        r"\b(?:conda|mamba|micromamba)\s+(?:env\s+)?(?:install|create|update)",
+        r"\b(?:conda|mamba|micromamba)\s+clean",
+        "clean the package cache in the same RUN: && conda clean -afy",
+    ),
+    (r"\b(?:yum|dnf)\s+(?:-\S+\s+)*install", r"\b(?:yum|dnf)\s+clean\s+all", "add && yum clean all in the same RUN"),
+    (r"\bapk\s+add", r"--no-cache", "add --no-cache to apk add"),
+    (r"\bnpm\s+(?:install|ci)\b", r"npm\s+cache\s+clean", "add && npm cache clean --force in the same RUN"),
+]
+
+
+class LayerBreakdown(TypedDict):
+    """One layer of an image in a size breakdown."""
+
+    index: int  # Position in the image, from the base up
+    digest: str
+    size_bytes: int
+    percent: float  # Share of the image's total size
+    shared_with: Optional[int]  # Other images using the layer; None if sharing is unknown
+    command: Optional[str]  # Build step that created the layer (deep mode)
+    created: Optional[str]
+
+
+class SizeBreakdown(TypedDict):
+    """Size breakdown of an image."""
+
+    image_id: str
+    repository: str
+    tag: str
+    digest: str
+    total_bytes: int
+    unique_bytes: Optional[int]  # Bytes of layers no other image uses; None if sharing is unknown
+    shared_bytes: Optional[int]
+    history_matched: bool  # Whether build steps could be matched to layers
+    layers: List[LayerBreakdown]
+    suggestions: List[str]
+
+
+def layer_commands(history: List[HistoryEntry], layer_count: int) -> Optional[List[HistoryEntry]]:
+    """Match the build steps of an image's history to its layers.
+
+    Returns:
+        The step that created each layer, in layer order, or None if the history
+        does not have one step per layer
+    """
+    steps = [step for step in history if not step.get("empty_layer")]
+    return steps if len(steps) == layer_count else None
+
+
+def _clean_command(command: str) -> str:
+    """A build step without the shell prefix docker records for RUN steps"""
+    command = re.sub(r"^/bin/sh -c #\(nop\)\s*", "", command.strip())
+    command = re.sub(r"^/bin/sh -c\s+", "RUN ", command)
+    return re.sub(r"\s+", " ", command)
+
+
+def break_down_image(
+    image_id: str,
+    repository: str,
+    tag: str,
+    digest: str,
+    layers: List[Tuple[str, int]],
+    history: Optional[List[HistoryEntry]] = None,
+    layer_images: Optional[Dict[str, List[str]]] = None,
+) -> SizeBreakdown:
+    """Break an image down layer by layer.
+
+    Args:
+        image_id: Image to break down, e.g. "environment:abc-1"
+        repository: Repository of the image
+        tag: Tag of the image
+        digest: Manifest digest of the image
+        layers: (digest, size) of each layer, from the base up
+        history: Build steps from the image config (deep mode), or None
+        layer_images: Images using each layer, e.g. from a scan snapshot, without the
+            image itself or other tags of its digest; None if sharing is unknown
+
+    Returns:
+        The breakdown, with suggestions for reducing the image's size
+    """
+    total = sum(size for _, size in layers)
+    steps = layer_commands(history, len(layers)) if history else None
+    breakdown: List[LayerBreakdown] = []
+    for index, (layer_digest, size) in enumerate(layers):
+        step = steps[index] if steps else {}
+        command = step.get("created_by")
+        breakdown.append(
+            {
+                "index": index,
+                "digest": layer_digest,
+                "size_bytes": size,
+                "percent": round(100.0 * size / total, 1) if total else 0.0,
+                "shared_with": len(layer_images.get(layer_digest, [])) if layer_images is not None else None,
+                "command": _clean_command(command) if command else None,
+                "created": step.get("created"),
+            }
+        )
+
+    unique_bytes = None
+    if layer_images is not None:
+        unique_bytes = sum(layer["size_bytes"] for layer in breakdown if not layer["shared_with"])
+    result: SizeBreakdown = {
+        "image_id": image_id,
+        "repository": repository,
+        "tag": tag,
+        "digest": digest,
+        "total_bytes": total,
+        "unique_bytes": unique_bytes,
+        "shared_bytes": total - unique_bytes if unique_bytes is not None else None,
+        "history_matched": steps is not None,
+        "layers": breakdown,
+        "suggestions": [],
+    }
+    result["suggestions"] = suggest_reductions(result)
+    return result
+
+
+def suggest_reductions(breakdown: SizeBreakdown) -> List[str]:
+    """Suggestions for making an image smaller, largest layers first"""
+    suggestions = []
+    for layer in sorted(breakdown["layers"], key=lambda layer: layer["size_bytes"], reverse=True):
+        if layer["size_bytes"] < SUGGESTION_MIN_BYTES:
+            break
+        where = f"Layer {layer['index']} ({sizeof_fmt(layer['size_bytes'])}, {layer['percent']:.0f}%)"
+        command = layer["command"] or ""
+        for step_pattern, cleaned_pattern, suggestion in _CACHE_RULES:
+            if re.search(step_pattern, command) and not re.search(cleaned_pattern, command):
+                suggestions.append(f"{where}: {suggestion}")
+        if command.startswith(("COPY", "ADD")) and layer["percent"] >= LARGE_LAYER_PERCENT:
+            suggestions.append(
+                f"{where}: copies a lot into the image; check .dockerignore and copy only what is needed"
+            )
+        if layer["shared_with"] == 0 and layer["percent"] >= LARGE_LAYER_PERCENT:
+            suggestions.append(
+                f"{where}: no other image shares it; if other environments install the same packages, "
+                "move them to a shared base image"
+            )
+
+    # Deleting files in a later step hides them but keeps them in the earlier layer
+    for layer in breakdown["layers"]:
+        if layer["index"] > 0 and re.match(r"RUN rm\s", layer["command"] or ""):
+            suggestions.append(
+                f"Layer {layer['index']}: removes files created by an earlier step, which does not make the image "
+                "smaller; remove them in the step that creates them"
+            )
+    return suggestions
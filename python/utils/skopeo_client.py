@@ -2,21 +2,43 @@
 Skopeo client for Docker registry operations.
 
 This module provides a standardized client for interacting with Docker registries
-using skopeo, with support for rate limiting, retries, and various authentication
-methods.
+using skopeo, with support for rate limiting, retries, a per-host circuit breaker
+and concurrency limit, and various authentication methods. With skopeo.client:
+native, requests other than copies to another registry are made over HTTP
+instead of running skopeo (see utils/native_registry.py).
 """
 
-import json
 import logging
 import os
 import subprocess
 import time
-from threading import Lock
-from typing import Any, Dict, List, Optional, Tuple
-
-from utils.auth import authenticate_acr, authenticate_ecr, get_credentials_from_k8s_secret
+import urllib.error
+from threading import Lock, local
+from typing import IO, Any, Callable, Dict, List, Optional, Sequence, Tuple, TypeVar
+
+from utils import native_registry
+from utils.auth import (
+    authenticate_acr,
+    authenticate_ecr,
+    get_credentials_from_k8s_secret,
+    get_ecr_client,
+    get_profile_credentials,
+)
+from utils.blob_mount import BlobCopyStats, empty_copy_stats, mount_image
 from utils.cache_utils import cached_image_inspect, cached_tag_list
+from utils.circuit_breaker import get_circuit_breaker, is_overload_message
+from utils.content_trust import ContentTrustChecker
+from utils.error_utils import ERROR_NOT_FOUND, ERROR_PARSE, classify_error
+from utils.host_limits import get_host_limiter, host_slot
+from utils.manifest_schema1 import manifest_digest
+from utils.read_only import ensure_writable
+from utils.registry_http import RegistryHttpClient, read_auth_file_credentials
+from utils.request_stats import request_stats
 from utils.retry_utils import is_retryable_error, retry_with_backoff
+from utils.skopeo_output import json_document, normalize_inspect, parse_skopeo_json
+from utils.tag_immutability import ecr_tag_immutability, harbor_tag_immutability
+
+T = TypeVar("T")
 
 
 class _AuthExpiredError(Exception):
@@ -112,6 +134,28 @@ def is_registry_in_cluster(registry_url: str, namespace: str) -> bool:
         return False
 
 
+def _operation_name(subcommand: str, args: List[str]) -> str:
+    """Operation type of a skopeo command for request telemetry (e.g. "inspect", "inspect-raw")"""
+    if subcommand == "inspect":
+        if "--raw" in args:
+            return "inspect-raw"
+        if "--config" in args:
+            return "inspect-config"
+    return subcommand
+
+
+def _writes_to_registry(subcommand: str, args: List[str]) -> bool:
+    """Whether a skopeo command changes a registry: a delete, or a copy or sync to a registry"""
+    if subcommand == "delete":
+        return True
+    if subcommand == "copy":
+        return bool(args) and args[-1].startswith("docker://")
+    if subcommand == "sync" and "--dest" in args:
+        position = args.index("--dest") + 1
+        return position < len(args) and args[position] == "docker"
+    return False
+
+
 class SkopeoClient:
     """Standardized Skopeo client for registry operations."""
 
@@ -121,6 +165,7 @@ class SkopeoClient:
         namespace: str = None,
         enable_docker_deletion: bool = False,
         registry_statefulset: str = None,
+        registry_url: Optional[str] = None,
     ):
         """Initialize SkopeoClient.
 
@@ -129,13 +174,17 @@ class SkopeoClient:
             namespace: Kubernetes namespace (defaults to platform namespace from config)
             enable_docker_deletion: If True, enable registry deletion by treating registry as in-cluster
             registry_statefulset: Name of the registry StatefulSet to modify for deletion.
+            registry_url: Registry to connect to (defaults to the configured registry)
         """
         self.config_manager = config_manager
         self.namespace = namespace or config_manager.get_domino_platform_namespace()
-        self.registry_url = config_manager.get_registry_url()
+        self.registry_url = registry_url or config_manager.get_registry_url()
         self.repository = config_manager.get_repository()
         self._logged_in = False
 
+        # Make requests over HTTP rather than with skopeo commands (skopeo.client: native)
+        self.native = config_manager.get_registry_client() == "native"
+
         # Registry deletion override settings
         self.enable_docker_deletion = enable_docker_deletion
         self.registry_statefulset = registry_statefulset or "docker-registry"
@@ -147,6 +196,26 @@ class SkopeoClient:
         self._rate_limiter = None
         self._rate_limiter_lock = Lock()
 
+        # Pauses requests while the registry keeps failing (shared per registry host)
+        self._circuit_breaker = get_circuit_breaker(self.registry_url)
+
+        # Caps requests in flight to the registry host (shared per registry host)
+        self._host_limiter = get_host_limiter(self.registry_url)
+
+        # Native HTTP client for manifest HEAD requests, and every request when native (created on first use)
+        self._http_client: Optional[RegistryHttpClient] = None
+        self._http_client_disabled = False
+        self._http_client_lock = Lock()
+
+        # stderr and error code (see error_utils.classify_error) of the last skopeo
+        # command each thread ran, for diagnosing and classifying failures
+        self._last_stderr = local()
+        self._last_error = local()
+
+        # Docker Content Trust checks before deletion (checker created on first use)
+        self.allow_signed_deletion = False
+        self._content_trust: Optional[ContentTrustChecker] = None
+
         # Set up auth file — use the path config_manager already resolved (one level
         # above output_dir so credentials don't appear alongside report files).
         self.auth_file = config_manager.auth_file
@@ -160,14 +229,20 @@ class SkopeoClient:
             pass
 
         # Get credentials
-        self.username = self._get_registry_username()
-        self.password = self._get_registry_password()
+        self.username, self.password = self._get_registry_credentials()
 
         if self.rate_limit_enabled:
             self._init_rate_limiter()
 
         self._ensure_logged_in()
 
+    def _get_registry_credentials(self) -> Tuple[Optional[str], Optional[str]]:
+        """Get registry credentials from the host's credential profile, or the default chain."""
+        profile = self.config_manager.get_credential_profile(self.registry_url)
+        if profile is not None:
+            return get_profile_credentials(profile, self.registry_url, self.namespace, self.auth_file, self.native)
+        return self._get_registry_username(), self._get_registry_password()
+
     def _get_registry_username(self) -> Optional[str]:
         """Get registry username from environment or Kubernetes secret."""
         # Check explicit username from environment
@@ -218,12 +293,12 @@ class SkopeoClient:
 
         # For ECR, authenticate and return None (auth handled via auth file)
         if "amazonaws.com" in self.registry_url:
-            authenticate_ecr(self.registry_url, self.auth_file)
+            authenticate_ecr(self.registry_url, self.auth_file, native=self.native)
             return None
 
         # For ACR, authenticate and return None (auth handled via auth file)
         if "azurecr.io" in self.registry_url:
-            authenticate_acr(self.registry_url, self.auth_file)
+            authenticate_acr(self.registry_url, self.auth_file, native=self.native)
             return None
 
         return None
@@ -269,7 +344,13 @@ class SkopeoClient:
         try:
             # For ECR/ACR, authentication is handled by _get_registry_password()
             # For other registries, we'll try to login if we have credentials
-            if self.password and "amazonaws.com" not in self.registry_url and "azurecr.io" not in self.registry_url:
+            # Native requests send the credentials themselves (see _http_credentials)
+            if (
+                self.password
+                and not self.native
+                and "amazonaws.com" not in self.registry_url
+                and "azurecr.io" not in self.registry_url
+            ):
                 logging.info(f"Logging in to registry: {self.registry_url}")
                 self._login_to_registry()
 
@@ -286,16 +367,18 @@ class SkopeoClient:
     def refresh_auth(self) -> None:
         """Re-authenticate with the registry, fetching fresh credentials.
 
-        Re-reads credentials from the configured source (environment variable,
-        Kubernetes secret, or cloud provider) and runs skopeo login again.
+        Re-reads credentials from the configured source (credential profile,
+        environment variable, Kubernetes secret, or cloud provider) and runs
+        skopeo login again.
         Useful for long-running operations where tokens (ACR ~3h, ECR 12h)
         may expire mid-run.
         """
         logging.info("Refreshing registry authentication...")
         self._logged_in = False
-        self.username = self._get_registry_username()
-        self.password = self._get_registry_password()
+        self.username, self.password = self._get_registry_credentials()
         self._ensure_logged_in()
+        with self._http_client_lock:
+            self._http_client = None
         logging.info("Registry authentication refreshed")
 
     def _login_to_registry(self):
@@ -350,11 +433,35 @@ class SkopeoClient:
 
         return redacted
 
+    def _record_registry_outcome(self, failed: bool) -> None:
+        """Report whether the registry failed a request (5xx, 429, timeout) to the circuit breaker."""
+        if self._circuit_breaker is None:
+            return
+        if failed:
+            self._circuit_breaker.record_failure()
+        else:
+            self._circuit_breaker.record_success()
+
     def run_skopeo_command(self, subcommand: str, args: List[str]) -> Optional[str]:
-        """Run a Skopeo command with standardized configuration."""
+        """Run a Skopeo command with standardized configuration.
+
+        Raises:
+            ReadOnlyError: If the command would change a registry in read-only mode
+        """
+        if _writes_to_registry(subcommand, args):
+            ensure_writable(f"skopeo {subcommand}")
         self._ensure_logged_in()
         self._acquire_rate_limit_token()
+        self._last_stderr.value = ""
+        self._last_error.code = None
+
+        with request_stats.track(_operation_name(subcommand, args)) as outcome:
+            output = self._execute_skopeo_command(subcommand, args)
+            outcome["error"] = output is None
+        return output
 
+    def _execute_skopeo_command(self, subcommand: str, args: List[str]) -> Optional[str]:
+        """Run a Skopeo command with retries and re-authentication; None if it failed."""
         timeout = self.config_manager.get_retry_timeout()
         cmd = self._build_skopeo_command(subcommand, args)
         log_cmd = " ".join(self._redact_command_for_logging(cmd))
@@ -367,22 +474,29 @@ class SkopeoClient:
             jitter=self.config_manager.get_retry_jitter(),
         )
         def _execute():
+            if self._circuit_breaker:
+                self._circuit_breaker.before_request()
             try:
-                result = subprocess.run(
-                    cmd,
-                    capture_output=True,
-                    text=True,
-                    check=True,
-                    timeout=timeout,
-                )
+                with host_slot(self._host_limiter):
+                    result = subprocess.run(
+                        cmd,
+                        capture_output=True,
+                        text=True,
+                        check=True,
+                        timeout=timeout,
+                    )
+                self._record_registry_outcome(failed=False)
+                self._last_stderr.value = result.stderr if isinstance(result.stderr, str) else ""
                 return result.stdout
             except subprocess.TimeoutExpired as e:
+                self._record_registry_outcome(failed=True)
                 logging.error(f"Skopeo command timed out after {timeout}s: {log_cmd}")
                 from utils.error_utils import create_registry_connection_error
 
                 raise create_registry_connection_error(self.registry_url, e)
             except subprocess.CalledProcessError as e:
                 error_str = (e.stderr or "").lower()
+                self._record_registry_outcome(failed=is_overload_message(error_str))
                 if "429" in error_str or "rate limit" in error_str or "too many requests" in error_str:
                     from utils.error_utils import create_rate_limit_error
 
@@ -418,55 +532,518 @@ class SkopeoClient:
                 return _execute()
             except Exception as retry_e:
                 logging.error(f"Skopeo command failed after re-authentication: {retry_e}")
+                self._last_error.code = classify_error(retry_e)
                 return None
         except ImageNotFoundError as e:
             logging.warning(str(e))
+            self._last_error.code = ERROR_NOT_FOUND
             return None
         except Exception as e:
             is_retryable, error_type = is_retryable_error(e)
             if not is_retryable:
                 logging.error(f"Non-retryable error in Skopeo command: {e}")
+            self._last_error.code = classify_error(e)
             return None
 
+    def _run_native(self, operation: str, request: Callable[[RegistryHttpClient], T]) -> Optional[T]:
+        """Make a registry request over HTTP instead of running skopeo (skopeo.client: native).
+
+        Rate limiting, retries, re-authentication, request statistics and error
+        codes work as for skopeo commands.
+
+        Args:
+            operation: Name the request is counted under, that of the skopeo command it replaces
+            request: Makes the request with the HTTP client
+
+        Returns:
+            The request's result, or None if it failed
+        """
+        self._ensure_logged_in()
+        self._acquire_rate_limit_token()
+        self._last_error.code = None
+
+        @retry_with_backoff(
+            max_retries=self.config_manager.get_max_retries(),
+            initial_delay=self.config_manager.get_retry_initial_delay(),
+            max_delay=self.config_manager.get_retry_max_delay(),
+            exponential_base=self.config_manager.get_retry_exponential_base(),
+            jitter=self.config_manager.get_retry_jitter(),
+        )
+        def _execute():
+            return request(self._get_http_client())
+
+        with request_stats.track(operation) as outcome:
+            try:
+                try:
+                    result = _execute()
+                except urllib.error.HTTPError as e:
+                    if e.code != 401:
+                        raise
+                    logging.warning(f"Registry credentials expired, refreshing and retrying: {e}")
+                    self.refresh_auth()
+                    result = _execute()
+            except Exception as e:
+                self._last_error.code = classify_error(e)
+                if self._last_error.code == ERROR_NOT_FOUND:
+                    logging.warning(f"Image not found in registry (may have already been deleted): {e}")
+                else:
+                    logging.error(f"Registry {operation} request failed: {e}")
+                result = None
+            outcome["error"] = result is None
+        return result
+
+    def last_error_code(self) -> Optional[str]:
+        """Error code of the last failed request this thread made (see error_utils.classify_error), or None"""
+        return getattr(self._last_error, "code", None)
+
     @cached_tag_list(ttl_seconds=1800)
     def list_tags(self, repository: Optional[str] = None) -> List[str]:
         """List all tags for a repository."""
         repo_path = repository or self.repository
+        if self.native:
+            return self._run_native("list-tags", lambda client: client.list_tags(repo_path)) or []
         args = [f"docker://{self.registry_url}/{repo_path}"]
 
         output = self.run_skopeo_command("list-tags", args)
         if output:
-            try:
-                tags_data = json.loads(output)
-                return tags_data.get("Tags", [])
-            except json.JSONDecodeError:
-                logging.error(f"Failed to parse tags for {self.repository}")
+            tags_data = self._parse_output(output, f"tags of {repo_path}")
+            if tags_data is None:
+                return []
+            tags = tags_data.get("Tags") or []
+            if not isinstance(tags, list):
+                logging.error(f"Unexpected skopeo output for tags of {repo_path}: Tags is not a list")
                 return []
+            return [tag for tag in tags if isinstance(tag, str)]
         return []
 
     @cached_image_inspect(ttl_seconds=3600)
     def inspect_image(self, repository: Optional[str], tag: str) -> Optional[Dict]:
         """Inspect a specific image tag."""
         repo_path = repository or self.repository
+        if self.native:
+            image_info = self._run_native(
+                "inspect", lambda client: native_registry.inspect_image(client, repo_path, tag)
+            )
+            return normalize_inspect(image_info, f"image inspection of {repo_path}:{tag}") if image_info else None
         args = [f"docker://{self.registry_url}/{repo_path}:{tag}"]
 
         output = self.run_skopeo_command("inspect", args)
         if output:
-            try:
-                return json.loads(output)
-            except json.JSONDecodeError:
-                logging.error(f"Failed to parse image inspection for {self.repository}:{tag}")
+            what = f"image inspection of {repo_path}:{tag}"
+            image_info = self._parse_output(output, what)
+            return normalize_inspect(image_info, what) if image_info is not None else None
+        return None
+
+    def get_image_digest(self, repository: Optional[str], tag: str) -> Optional[str]:
+        """Get the manifest digest a tag currently points to.
+
+        Unlike inspect_image, this always queries the registry so that a re-pushed
+        tag is never reported with a stale cached digest.
+
+        Returns:
+            The digest (e.g. "sha256:..."), or None if the tag does not exist or inspection failed
+        """
+        repo_path = repository or self.repository
+        if self.native:
+            # The digest of the manifest itself, which is what skopeo inspect reports
+            result = self.get_manifest(repo_path, tag)
+            return result[0] if result else None
+        args = [f"docker://{self.registry_url}/{repo_path}:{tag}"]
+
+        output = self.run_skopeo_command("inspect", args)
+        if output:
+            image_info = self._parse_output(output, f"image inspection of {repo_path}:{tag}")
+            digest = image_info.get("Digest") if image_info is not None else None
+            return digest if isinstance(digest, str) and digest else None
+        return None
+
+    def get_manifest(self, repository: Optional[str], tag: str) -> Optional[Tuple[str, Dict]]:
+        """Fetch the raw manifest of a tag (or of a "sha256:..." digest) without a full inspection.
+
+        This is a single registry request: unlike inspect_image it does not fetch
+        the image config or list the repository's tags.
+
+        Returns:
+            (digest, manifest) where digest is the manifest digest as the registry
+            computes it (see manifest_schema1.manifest_digest), or None on failure
+        """
+        repo_path = repository or self.repository
+        if self.native:
+            return self._run_native("inspect-raw", lambda client: native_registry.get_manifest(client, repo_path, tag))
+        reference = f"@{tag}" if tag.startswith("sha256:") else f":{tag}"
+        args = ["--raw", f"docker://{self.registry_url}/{repo_path}{reference}"]
+
+        output = self.run_skopeo_command("inspect", args)
+        if output:
+            manifest = self._parse_output(output, f"manifest of {repo_path}:{tag}")
+            if manifest is None:
                 return None
+            # The digest is computed over the manifest exactly as served, without any surrounding noise
+            document, noise = json_document(output)
+            return manifest_digest(document if noise else output), manifest
         return None
 
+    def get_image_config(self, repository: Optional[str], tag: str) -> Optional[Dict]:
+        """Fetch the image config of a tag (including rootfs.diff_ids).
+
+        Returns:
+            The parsed image config, or None on failure
+        """
+        repo_path = repository or self.repository
+        if self.native:
+            return self._run_native(
+                "inspect-config", lambda client: native_registry.get_image_config(client, repo_path, tag)
+            )
+        args = ["--config", f"docker://{self.registry_url}/{repo_path}:{tag}"]
+
+        output = self.run_skopeo_command("inspect", args)
+        if output:
+            return self._parse_output(output, f"image config of {repo_path}:{tag}")
+        return None
+
+    def _parse_output(self, output: str, what: str) -> Optional[Dict[str, Any]]:
+        """Decode the JSON object a skopeo command printed, or None (reported with its stderr) if unusable."""
+        data = parse_skopeo_json(output, what, dict, getattr(self._last_stderr, "value", ""))
+        if data is None:
+            self._last_error.code = ERROR_PARSE
+        return data
+
+    def _http_credentials(self) -> Tuple[Optional[str], Optional[str]]:
+        """Registry username and password for native HTTP requests."""
+        if self.password:
+            return self.username, self.password
+        # ECR/ACR credentials only exist in the auth file written at login
+        credentials = read_auth_file_credentials(self.auth_file, self.registry_url)
+        return credentials or (self.username, self.password)
+
+    def _get_http_client(self) -> Optional[RegistryHttpClient]:
+        """Get the native registry HTTP client, or None if HEAD requests are unavailable."""
+        with self._http_client_lock:
+            if self._http_client_disabled:
+                return None
+            if self._http_client is None:
+                username, password = self._http_credentials()
+                self._http_client = RegistryHttpClient(
+                    self.registry_url,
+                    username,
+                    password,
+                    circuit_breaker=self._circuit_breaker,
+                    host_limiter=self._host_limiter,
+                )
+            return self._http_client
+
+    def get_manifest_digest(self, repository: Optional[str], tag: str) -> Optional[str]:
+        """Get the digest a tag currently points to, as cheaply as possible.
+
+        Sends a manifest HEAD request and reads the Docker-Content-Digest header,
+        which downloads nothing and avoids running skopeo. If the registry cannot
+        be reached that way, HEAD requests are disabled for this client and the
+        raw manifest is fetched with skopeo instead.
+
+        Returns:
+            The digest, or None if the tag does not exist or could not be resolved
+        """
+        repo_path = repository or self.repository
+        http_client = self._get_http_client()
+        if http_client is not None:
+            self._acquire_rate_limit_token()
+            try:
+                with request_stats.track("manifest-head"):
+                    digest = http_client.head_manifest_digest(repo_path, tag)
+                if digest:
+                    return digest
+            except Exception as e:
+                if self.native:
+                    logging.info(f"Manifest HEAD request for {repo_path}:{tag} failed ({e}), fetching the manifest")
+                else:
+                    logging.info(f"Manifest HEAD requests unavailable ({e}), falling back to skopeo")
+                    with self._http_client_lock:
+                        self._http_client_disabled = True
+
+        result = self.get_manifest(repo_path, tag)
+        return result[0] if result else None
+
+    def get_blob_size(self, repository: Optional[str], digest: str) -> Optional[int]:
+        """Get the size of a blob, or None if it cannot be determined.
+
+        Used for schema1 images, whose manifests do not record layer sizes.
+        """
+        repo_path = repository or self.repository
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("blob-head"):
+                return http_client.head_blob_size(repo_path, digest)
+        except Exception as e:
+            logging.warning(f"Could not get size of blob {digest} in {repo_path}: {e}")
+            return None
+
+    def get_referrers(self, repository: Optional[str], digest: str) -> Optional[List[Dict[str, Any]]]:
+        """List the artifacts (signatures, attestations, SBOMs) referring to a manifest digest.
+
+        Returns:
+            Referrer descriptors, or None if the registry has no referrers API or
+            cannot be queried natively
+        """
+        repo_path = repository or self.repository
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("referrers"):
+                return http_client.get_referrers(repo_path, digest)
+        except Exception as e:
+            logging.warning(f"Could not list referrers of {repo_path}@{digest}: {e}")
+            return None
+
+    def list_repositories(self) -> Optional[List[str]]:
+        """List the registry's repositories, or None if the registry has no usable catalog API."""
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("catalog"):
+                return http_client.list_repositories()
+        except Exception as e:
+            logging.warning(f"Could not list the repositories of {self.registry_url}: {e}")
+            return None
+
+    def get_api_json(self, path: str) -> Optional[Any]:
+        """GET a JSON document from the registry's own API (e.g. Harbor's /api/v2.0), or None if unavailable."""
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("api-get"):
+                return http_client.get_json(path, "registry:api")
+        except Exception as e:
+            logging.debug(f"Could not read {path} from {self.registry_url}: {e}")
+            return None
+
+    def get_tag_immutability(self, repositories: List[str]) -> Dict[str, str]:
+        """Read the tag immutability setting of repositories, for ECR and Harbor registries.
+
+        Returns:
+            Repository -> immutability (see utils/tag_immutability.py); empty if the
+            registry is neither ECR nor Harbor, repositories that could not be read are left out
+        """
+        if "amazonaws.com" in self.registry_url:
+            profile = self.config_manager.get_credential_profile(self.registry_url) or {}
+            ecr_client = get_ecr_client(
+                self.registry_url,
+                role_arn=profile.get("role_arn"),
+                external_id=profile.get("external_id"),
+                region=profile.get("region"),
+            )
+            return ecr_tag_immutability(ecr_client, repositories)
+        if self.get_api_json("/api/v2.0/systeminfo") is not None:
+            return harbor_tag_immutability(self.get_api_json, repositories)
+        logging.warning(f"{self.registry_url} is neither ECR nor Harbor; tag immutability cannot be read")
+        return {}
+
+    def get_blob(self, repository: Optional[str], digest: str, max_bytes: int) -> Optional[bytes]:
+        """Download a small blob such as an attestation, or None if it cannot be read."""
+        repo_path = repository or self.repository
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("blob-get"):
+                return http_client.get_blob(repo_path, digest, max_bytes)
+        except Exception as e:
+            logging.warning(f"Could not read blob {digest} in {repo_path}: {e}")
+            return None
+
+    def read_blob(self, repository: Optional[str], digest: str, reader: Callable[[IO[bytes]], T]) -> Optional[T]:
+        """Stream a blob such as a layer through reader, or None if it cannot be read."""
+        repo_path = repository or self.repository
+        http_client = self._get_http_client()
+        if http_client is None:
+            return None
+        self._acquire_rate_limit_token()
+        try:
+            with request_stats.track("blob-get"):
+                return http_client.read_blob(repo_path, digest, reader)
+        except Exception as e:
+            logging.warning(f"Could not read blob {digest} in {repo_path}: {e}")
+            return None
+
+    def _signed_tag_problem(self, repository: str, tag: str) -> Optional[str]:
+        """Describe why deleting a tag affects Docker Content Trust, or None if it does not."""
+        notary_url = self.config_manager.get_notary_url()
+        if not notary_url:
+            return None
+        with self._http_client_lock:
+            if self._content_trust is None:
+                self._content_trust = ContentTrustChecker(notary_url, self.registry_url, *self._http_credentials())
+        signed = self._content_trust.is_signed(repository, tag)
+        if signed is None:
+            return "its Docker Content Trust signature could not be checked"
+        return "it is signed with Docker Content Trust" if signed else None
+
+    def signed_tag_refusal(self, repository: Optional[str], tag: str) -> Optional[str]:
+        """Get the reason deleting a tag is refused because of Docker Content Trust.
+
+        Signed tags (and tags whose trust data could not be read) are refused
+        unless allow_signed_deletion is set or security.content_trust.on_signed_tag
+        is "warn".
+
+        Returns:
+            The reason, or None if the tag may be deleted
+        """
+        if self.allow_signed_deletion or self.config_manager.get_signed_tag_action() == "warn":
+            return None
+        return self._signed_tag_problem(repository or self.repository, tag)
+
+    def _check_signed_tag(self, repository: str, tag: str) -> bool:
+        """Log a signed tag about to be deleted; returns False if its deletion is refused."""
+        problem = self._signed_tag_problem(repository, tag)
+        if not problem:
+            return True
+        if self.signed_tag_refusal(repository, tag):
+            logging.error(
+                f"Refusing to delete {repository}:{tag}: {problem}; deleting it would break trust verification "
+                "for consumers (force with --allow-signed or security.content_trust.on_signed_tag: warn)"
+            )
+            return False
+        logging.warning(
+            f"Deleting {repository}:{tag} although {problem}; remove it from the trust data with "
+            f"`notary remove {self.registry_url}/{repository} {tag}` or trusted pulls of it will fail"
+        )
+        return True
+
     def delete_image(self, repository: Optional[str], tag: str) -> bool:
-        """Delete a specific image tag."""
+        """Delete a specific image tag.
+
+        Tags signed with Docker Content Trust are refused (returning False) or
+        deleted with a warning, see signed_tag_refusal.
+        """
         repo_path = repository or self.repository
+        if not self._check_signed_tag(repo_path, tag):
+            return False
+        if self.native:
+            ensure_writable(f"delete of {repo_path}:{tag}")
+            return bool(self._run_native("delete", lambda client: native_registry.delete_tag(client, repo_path, tag)))
         args = [f"docker://{self.registry_url}/{repo_path}:{tag}"]
 
         output = self.run_skopeo_command("delete", args)
         return output is not None
 
+    def delete_manifest(self, repository: Optional[str], digest: str, tags: Sequence[str] = ()) -> bool:
+        """Delete a manifest by digest, removing every tag that points to it.
+
+        Registries delete manifests, not tags, so this is also how untagged
+        manifests are removed. The tags pointing to the manifest, if given, are
+        checked for Docker Content Trust signatures like in delete_image; if
+        any of them is refused, nothing is deleted.
+        """
+        repo_path = repository or self.repository
+        if not all([self._check_signed_tag(repo_path, tag) for tag in tags]):
+            return False
+        if self.native:
+            ensure_writable(f"delete of {repo_path}@{digest}")
+            return bool(
+                self._run_native("delete", lambda client: native_registry.delete_manifest(client, repo_path, digest))
+            )
+        args = [f"docker://{self.registry_url}/{repo_path}@{digest}"]
+
+        output = self.run_skopeo_command("delete", args)
+        return output is not None
+
+    def copy_image(self, repository: Optional[str], digest: str, registry_url: str, tag: str) -> bool:
+        """Copy a manifest, with every platform it lists, to another registry.
+
+        The source is read by digest, so a tag re-pushed in the meantime is not
+        copied by mistake, and digests are preserved, so the copy can be verified
+        by comparing them. The destination registry's credentials must already be
+        in the auth file (creating a SkopeoClient for it logs in).
+
+        Args:
+            repository: Repository in this registry (and the same in the destination)
+            digest: Manifest digest to copy
+            registry_url: Destination registry
+            tag: Tag to give the copy
+
+        Returns:
+            True if skopeo copied the image
+        """
+        repo_path = repository or self.repository
+        args = [
+            "--all",
+            "--preserve-digests",
+            f"docker://{self.registry_url}/{repo_path}@{digest}",
+            f"docker://{registry_url}/{repo_path}:{tag}",
+        ]
+
+        output = self.run_skopeo_command("copy", args)
+        return output is not None
+
+    def copy_image_within_registry(
+        self, repository: Optional[str], digest: str, destination_repository: str, tag: str
+    ) -> Optional[BlobCopyStats]:
+        """Copy a manifest, with every platform it lists, to another repository of this registry.
+
+        Blobs are mounted from the source repository rather than downloaded and
+        uploaded again (see utils/blob_mount.py). If the registry cannot mount
+        every blob, or cannot be reached natively, the image is copied with skopeo.
+
+        Args:
+            repository: Repository the image is in
+            digest: Manifest digest to copy
+            destination_repository: Repository of this registry to copy to
+            tag: Tag to give the copy
+
+        Returns:
+            Blobs mounted, already present and transferred, or None if the copy failed.
+            Blob counts are zero when skopeo copied the image without mounting anything.
+        """
+        repo_path = repository or self.repository
+        stats = empty_copy_stats()
+        http_client = self._get_http_client()
+        if http_client is not None:
+            self._acquire_rate_limit_token()
+            try:
+                with request_stats.track("blob-mount"):
+                    stats, complete = mount_image(http_client, repo_path, digest, destination_repository, tag)
+                if complete:
+                    return stats
+            except Exception as e:
+                logging.info(f"Could not copy {repo_path}@{digest} by mounting blobs ({e}), copying with skopeo")
+
+        args = [
+            "--all",
+            "--preserve-digests",
+            f"docker://{self.registry_url}/{repo_path}@{digest}",
+            f"docker://{self.registry_url}/{destination_repository}:{tag}",
+        ]
+        output = self.run_skopeo_command("copy", args)
+        return stats if output is not None else None
+
+    def sync_images(self, source_file: str, registry_url: str) -> bool:
+        """Copy the images a skopeo sync YAML source file lists to another registry.
+
+        Images keep their repository and tag in the destination. As with
+        copy_image, the destination registry's credentials must already be in
+        the auth file.
+
+        Args:
+            source_file: YAML source file (skopeo sync --src yaml)
+            registry_url: Destination registry
+
+        Returns:
+            True if skopeo synced every image
+        """
+        args = ["--all", "--preserve-digests", "--src", "yaml", "--dest", "docker", source_file, registry_url]
+
+        output = self.run_skopeo_command("sync", args)
+        return output is not None
+
     def is_registry_in_cluster(self) -> bool:
         """Check if the registry service exists in the Kubernetes cluster."""
         if self.enable_docker_deletion:
@@ -529,6 +1106,7 @@ class SkopeoClient:
 
     def enable_registry_deletion(self, namespace: str = None) -> bool:
         """Enable deletion of Docker images in the registry."""
+        ensure_writable("patching the registry StatefulSet")
         service_name, parsed_ns = self._parse_registry_name()
         ns = namespace or parsed_ns
 
@@ -571,6 +1149,7 @@ class SkopeoClient:
 
     def disable_registry_deletion(self, namespace: str = None) -> bool:
         """Disable deletion of Docker images in the registry."""
+        ensure_writable("patching the registry StatefulSet")
         service_name, parsed_ns = self._parse_registry_name()
         ns = namespace or parsed_ns
 
@@ -0,0 +1,145 @@
+"""
+Tolerant decoding of skopeo's JSON output.
+
+skopeo prints one JSON document per command, but in practice its stdout is
+not always clean: wrappers and some versions print warnings (e.g. time="..."
+level=warning msg="...") before the document, registries return null where
+a list is expected, and older or newer skopeo versions add or drop fields.
+Output that cannot be used is reported with what was asked for, where
+decoding failed, an excerpt of the raw output and skopeo's stderr, and the
+caller gets None instead of an exception, so one bad image does not end a
+scan.
+"""
+
+import json
+import logging
+from typing import Any, Dict, List, Optional, Tuple, Type
+
+# Raw output quoted in error messages, in characters
+EXCERPT_CHARS = 300
+
+_decoder = json.JSONDecoder()
+
+
+def _excerpt(text: str, position: int = 0) -> str:
+    """Up to EXCERPT_CHARS of text around a position, on one line"""
+    start = max(0, position - EXCERPT_CHARS // 2)
+    excerpt = text[start : start + EXCERPT_CHARS]
+    prefix = "..." if start else ""
+    suffix = "..." if start + EXCERPT_CHARS < len(text) else ""
+    return f"{prefix}{excerpt}{suffix}".replace("\n", "\\n")
+
+
+def json_document(output: str) -> Tuple[str, str]:
+    """Split skopeo output into its JSON document and whatever surrounds it.
+
+    The document is the first JSON object or array in the output; lines of
+    noise before it and text after it are returned separately.
+
+    Returns:
+        (document, noise); document is the stripped output when no JSON is found
+
+    Raises:
+        json.JSONDecodeError: If no JSON document can be decoded
+    """
+    stripped = output.strip().lstrip("\ufeff")
+    try:
+        _decoder.decode(stripped)
+        return stripped, ""
+    except json.JSONDecodeError as e:
+        first_error = e
+
+    # Try every line that starts like a document, so noise containing braces is skipped
+    offset = 0
+    for line in stripped.splitlines(keepends=True):
+        if line.lstrip()[:1] in ("{", "["):
+            start = offset + len(line) - len(line.lstrip())
+            try:
+                _, end = _decoder.raw_decode(stripped, start)
+            except json.JSONDecodeError:
+                offset += len(line)
+                continue
+            return stripped[start:end], (stripped[:start] + stripped[end:]).strip()
+        offset += len(line)
+    raise first_error
+
+
+def parse_skopeo_json(
+    output: Optional[str], what: str, expected_type: Type = dict, stderr: str = ""
+) -> Optional[Any]:
+    """Decode skopeo output, reporting output that cannot be used.
+
+    Args:
+        output: skopeo's stdout
+        what: What was asked for, for messages (e.g. "image inspection for environment:abc-1")
+        expected_type: Type the document must have (dict or list)
+        stderr: skopeo's stderr, included in messages
+
+    Returns:
+        The decoded document, or None if the output is empty, not JSON, or not of expected_type
+    """
+    if not output or not output.strip():
+        logging.error(f"skopeo returned no output for {what}{_stderr_note(stderr)}")
+        return None
+    try:
+        document, noise = json_document(output)
+        data = json.loads(document)
+    except json.JSONDecodeError as e:
+        logging.error(
+            f"Failed to parse {what}: {e.msg} at line {e.lineno} column {e.colno}; "
+            f"output: {_excerpt(e.doc, e.pos)}{_stderr_note(stderr)}"
+        )
+        logging.debug(f"Raw skopeo output for {what}:\n{output}")
+        return None
+
+    if noise:
+        logging.warning(f"Ignored unexpected text in skopeo output for {what}: {_excerpt(noise)}")
+    if not isinstance(data, expected_type):
+        logging.error(
+            f"Unexpected skopeo output for {what}: expected a JSON {_type_name(expected_type)}, "
+            f"got {_type_name(type(data))}; output: {_excerpt(document)}{_stderr_note(stderr)}"
+        )
+        return None
+    return data
+
+
+def normalize_inspect(data: Dict[str, Any], what: str) -> Dict[str, Any]:
+    """Make skopeo inspect output safe to read whatever the skopeo version.
+
+    Digest, Labels and LayersData of the wrong type (e.g. null) are dropped,
+    so readers fall back to their defaults, layer entries that are not objects
+    are dropped, and layer sizes reported as strings or null become integers
+    (0 if unreadable; schema1 images keep their -1). Fields the cleaner does
+    not know are kept as they are, and well-formed output is returned as is.
+    """
+    data = dict(data)
+    for key, kind in (("Digest", str), ("Labels", dict), ("LayersData", list)):
+        if key in data and not isinstance(data[key], kind):
+            if data[key] is not None:
+                logging.warning(f"Ignoring {key} of unexpected type {_type_name(type(data[key]))} in {what}")
+            del data[key]
+
+    if "LayersData" in data:
+        layers: List[Dict[str, Any]] = []
+        for layer in data["LayersData"]:
+            if not isinstance(layer, dict):
+                continue
+            if not isinstance(layer.get("Size"), int):
+                layer = dict(layer)
+                try:
+                    layer["Size"] = int(layer.get("Size") or 0)
+                except (TypeError, ValueError):
+                    logging.warning(f"Unreadable layer size {layer.get('Size')!r} in {what}, counting it as 0")
+                    layer["Size"] = 0
+            layers.append(layer)
+        data["LayersData"] = layers
+    return data
+
+
+def _type_name(kind: type) -> str:
+    return {dict: "object", list: "array", str: "string", type(None): "null"}.get(kind, kind.__name__)
+
+
+def _stderr_note(stderr: str) -> str:
+    stderr = (stderr or "").strip()
+    return f"; skopeo stderr: {_excerpt(stderr)}" if stderr else ""
@@ -0,0 +1,149 @@
+"""
+StatsD / DogStatsD metrics.
+
+For sites standardized on Datadog (or another StatsD collector) rather than
+Prometheus scraping, each run sends its results to a StatsD endpoint over UDP:
+
+    registry_cleaner.run.duration           timing, every command
+    registry_cleaner.run.completed          count, tagged status:success|failure
+    registry_cleaner.tags_scanned           gauge, registry scans
+    registry_cleaner.bytes_total            gauge, registry scans
+    registry_cleaner.bytes_reclaimable      gauge, plans and dry-run deletions
+    registry_cleaner.deletes_performed      count, deletions
+    registry_cleaner.deletes_failed         count, deletions
+    registry_cleaner.bytes_freed            count, deletions
+    registry_cleaner.tags_planned           gauge, plans
+
+Every metric is tagged with the command and the registry, plus the tags
+configured under metrics.statsd.tags. Tags use the DogStatsD "|#key:value"
+extension unless metrics.statsd.dogstatsd is false, in which case they are
+left out for plain StatsD servers.
+
+Metrics are only sent when metrics.statsd.host (or STATSD_HOST) is set.
+Sending is fire-and-forget: UDP errors are logged at debug level and never
+fail a run.
+"""
+
+import socket
+import sys
+import threading
+from pathlib import Path
+from typing import Dict, Optional
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+
+class StatsdClient:
+    """Minimal UDP StatsD client."""
+
+    def __init__(
+        self,
+        host: str,
+        port: int = 8125,
+        prefix: str = "registry_cleaner",
+        tags: Optional[Dict[str, str]] = None,
+        dogstatsd: bool = True,
+    ):
+        """Initialize the client
+
+        Args:
+            host: StatsD server host
+            port: StatsD server UDP port
+            prefix: Prefix of every metric name
+            tags: Tags added to every metric
+            dogstatsd: Send tags with the DogStatsD extension (plain StatsD has no tags)
+        """
+        self.address = (host, port)
+        self.prefix = prefix
+        self.tags = dict(tags or {})
+        self.dogstatsd = dogstatsd
+        self._socket = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+
+    def format(self, name: str, value: float, metric_type: str, tags: Optional[Dict[str, str]] = None) -> str:
+        """Format one metric as a StatsD line"""
+        name = f"{self.prefix}.{name}" if self.prefix else name
+        # Whole numbers are sent as integers: byte counts exceed the precision of %g
+        value_text = str(int(value)) if float(value).is_integer() else f"{value:.3f}"
+        line = f"{name}:{value_text}|{metric_type}"
+        all_tags = {**self.tags, **(tags or {})}
+        if self.dogstatsd and all_tags:
+            line += "|#" + ",".join(f"{key}:{tag_value}" for key, tag_value in sorted(all_tags.items()))
+        return line
+
+    def _send(self, line: str) -> None:
+        """Send one line, ignoring network errors"""
+        try:
+            self._socket.sendto(line.encode(), self.address)
+        except OSError as e:
+            logger.debug(f"Could not send metric to StatsD at {self.address[0]}:{self.address[1]}: {e}")
+
+    def gauge(self, name: str, value: float, tags: Optional[Dict[str, str]] = None) -> None:
+        """Send a gauge"""
+        self._send(self.format(name, value, "g", tags))
+
+    def count(self, name: str, value: float = 1, tags: Optional[Dict[str, str]] = None) -> None:
+        """Send a counter increment"""
+        self._send(self.format(name, value, "c", tags))
+
+    def timing(self, name: str, milliseconds: float, tags: Optional[Dict[str, str]] = None) -> None:
+        """Send a timing in milliseconds"""
+        self._send(self.format(name, round(milliseconds, 3), "ms", tags))
+
+
+_client: Optional[StatsdClient] = None
+_client_loaded = False
+_client_lock = threading.Lock()
+
+
+def get_statsd() -> Optional[StatsdClient]:
+    """Get the shared StatsD client.
+
+    Returns:
+        The client, or None if no StatsD server is configured
+    """
+    global _client, _client_loaded
+    with _client_lock:
+        if not _client_loaded:
+            from utils.config_manager import ConfigValidationError, config_manager
+
+            _client_loaded = True
+            try:
+                settings = config_manager.get_statsd_settings()
+            except ConfigValidationError as e:
+                logger.warning(f"Invalid StatsD configuration, no metrics are sent: {e}")
+                settings = None
+            if settings:
+                tags = {"registry": config_manager.get_registry_url(), **settings["tags"]}
+                _client = StatsdClient(
+                    settings["host"], settings["port"], settings["prefix"], tags, settings["dogstatsd"]
+                )
+        return _client
+
+
+def current_command() -> str:
+    """Name of the running command, from its script name"""
+    return Path(sys.argv[0]).stem or "unknown"
+
+
+def emit_run_metrics(
+    gauges: Optional[Dict[str, float]] = None,
+    counts: Optional[Dict[str, float]] = None,
+    command: Optional[str] = None,
+) -> None:
+    """Send the results of a run, tagged with the command, if StatsD is configured.
+
+    Args:
+        gauges: Metric name -> value, sent as gauges (e.g. bytes_reclaimable)
+        counts: Metric name -> value, sent as counter increments (e.g. deletes_performed)
+        command: Command tag (default: the running script's name)
+    """
+    client = get_statsd()
+    if client is None:
+        return
+    tags = {"command": command or current_command()}
+    for name, value in (gauges or {}).items():
+        client.gauge(name, value, tags)
+    for name, value in (counts or {}).items():
+        client.count(name, value, tags)
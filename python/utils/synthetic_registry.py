@@ -0,0 +1,394 @@
+"""
+Synthetic registry datasets for benchmarking.
+
+generate_dataset() builds a reproducible registry of N repositories with M
+tags each, laid out like a Domino registry so the scan, index and plan code
+paths see realistic work:
+
+- every repository is built on one of a few shared base images (the large
+  layers most images share)
+- the tags of a repository are revisions: they share the repository's
+  dependency layers, which are rebuilt now and then, and add a few small
+  layers of their own
+- some tags are aliases of the previous revision (same manifest digest)
+- tags are <ObjectID>-<revision>, created over the past two years
+
+The same arguments, seed and reference time always give the same dataset.
+It can be served in memory by SyntheticSkopeoClient, which answers the
+SkopeoClient calls the analyzer makes without a registry, or pushed to a
+local registry with push_dataset() and scanned like any other.
+
+Pushed layers are small placeholder blobs, so a scan of a pushed dataset sees
+the same layer sharing but not the synthetic layer sizes.
+"""
+
+import concurrent.futures
+import hashlib
+import json
+import random
+import time
+import urllib.error
+import urllib.parse
+import urllib.request
+from dataclasses import dataclass, field
+from datetime import datetime, timedelta, timezone
+from functools import cached_property
+from typing import Any, Dict, Iterator, List, Optional, Tuple
+
+from utils.logging_utils import get_logger
+from utils.read_only import ensure_writable
+
+logger = get_logger(__name__)
+
+MANIFEST_MEDIA_TYPE = "application/vnd.docker.distribution.manifest.v2+json"
+CONFIG_MEDIA_TYPE = "application/vnd.docker.container.image.v1+json"
+LAYER_MEDIA_TYPE = "application/vnd.docker.image.rootfs.diff.tar.gzip"
+
+MB = 1024 * 1024
+
+# Tags per Domino environment (ObjectID) before a repository starts a new one
+REVISIONS_PER_OBJECT_ID = 10
+
+
+@dataclass(frozen=True)
+class SyntheticLayer:
+    """A layer: the size it stands for, and the placeholder blob pushed in its place."""
+
+    digest: str
+    size: int
+    content: bytes
+
+
+@dataclass
+class SyntheticImage:
+    """One tag of a synthetic repository."""
+
+    repository: str  # Repository name below the configured repository, e.g. "repo-003"
+    tag: str
+    layers: List[SyntheticLayer]
+    created: str  # ISO 8601
+    labels: Dict[str, str] = field(default_factory=dict)
+
+    def config(self) -> Dict[str, Any]:
+        """Image config document"""
+        return {
+            "architecture": "amd64",
+            "os": "linux",
+            "created": self.created,
+            "config": {"Labels": self.labels},
+            "rootfs": {"type": "layers", "diff_ids": [layer.digest for layer in self.layers]},
+        }
+
+    def config_blob(self) -> bytes:
+        """Serialized image config"""
+        return json.dumps(self.config(), sort_keys=True).encode()
+
+    def manifest(self, placeholder_sizes: bool = False) -> Dict[str, Any]:
+        """Schema 2 manifest of the image.
+
+        Args:
+            placeholder_sizes: Describe layers by their placeholder blobs (as pushed) instead of their sizes
+        """
+        config_blob = self.config_blob()
+        return {
+            "schemaVersion": 2,
+            "mediaType": MANIFEST_MEDIA_TYPE,
+            "config": {"mediaType": CONFIG_MEDIA_TYPE, "size": len(config_blob), "digest": sha256_digest(config_blob)},
+            "layers": [
+                {
+                    "mediaType": LAYER_MEDIA_TYPE,
+                    "size": len(layer.content) if placeholder_sizes else layer.size,
+                    "digest": layer.digest,
+                }
+                for layer in self.layers
+            ],
+        }
+
+    def manifest_blob(self, placeholder_sizes: bool = False) -> bytes:
+        """Serialized manifest"""
+        return json.dumps(self.manifest(placeholder_sizes), sort_keys=True).encode()
+
+    @cached_property
+    def digest(self) -> str:
+        """Manifest digest"""
+        return sha256_digest(self.manifest_blob())
+
+
+@dataclass
+class SyntheticDataset:
+    """A generated registry: repository name -> its images, in revision order."""
+
+    repositories: Dict[str, List[SyntheticImage]]
+    seed: int
+
+    def images(self) -> Iterator[SyntheticImage]:
+        """All images, repository by repository"""
+        for images in self.repositories.values():
+            yield from images
+
+    def stats(self) -> Dict[str, int]:
+        """Size of the dataset: tags, distinct manifests and layers, and logical vs stored bytes"""
+        layers: Dict[str, int] = {}
+        manifests = set()
+        logical_bytes = 0
+        tags = 0
+        for image in self.images():
+            tags += 1
+            manifests.add(image.digest)
+            for layer in image.layers:
+                layers[layer.digest] = layer.size
+                logical_bytes += layer.size
+        return {
+            "repositories": len(self.repositories),
+            "tags": tags,
+            "manifests": len(manifests),
+            "layers": len(layers),
+            "logical_bytes": logical_bytes,
+            "stored_bytes": sum(layers.values()),
+        }
+
+
+def sha256_digest(data: bytes) -> str:
+    """Content digest of a blob"""
+    return f"sha256:{hashlib.sha256(data).hexdigest()}"
+
+
+class _LayerFactory:
+    """Creates distinct layers of random size from a seeded generator."""
+
+    def __init__(self, rng: random.Random, seed: int):
+        self.rng = rng
+        self.seed = seed
+        self.count = 0
+
+    def layer(self, min_bytes: int, max_bytes: int) -> SyntheticLayer:
+        self.count += 1
+        content = f"synthetic layer {self.seed}/{self.count}\n".encode()
+        return SyntheticLayer(sha256_digest(content), self.rng.randint(min_bytes, max_bytes), content)
+
+    def layers(self, count: int, min_bytes: int, max_bytes: int) -> List[SyntheticLayer]:
+        return [self.layer(min_bytes, max_bytes) for _ in range(count)]
+
+
+def generate_dataset(
+    repositories: int,
+    tags_per_repository: int,
+    seed: int = 0,
+    base_images: int = 4,
+    alias_ratio: float = 0.05,
+    rebuild_ratio: float = 0.2,
+    now: Optional[datetime] = None,
+) -> SyntheticDataset:
+    """Generate a synthetic registry.
+
+    Args:
+        repositories: Number of repositories
+        tags_per_repository: Number of tags in each repository
+        seed: Seed of the generator; the same seed gives the same dataset
+        base_images: Number of base images the repositories share
+        alias_ratio: Share of tags that alias the previous tag's image
+        rebuild_ratio: Share of revisions that rebuild the repository's dependency layers
+        now: Reference time for creation dates (default: current time)
+
+    Returns:
+        The dataset
+    """
+    rng = random.Random(seed)
+    factory = _LayerFactory(rng, seed)
+    now = now or datetime.now(timezone.utc)
+
+    bases = [factory.layers(rng.randint(3, 5), 5 * MB, 150 * MB) for _ in range(max(1, base_images))]
+    dataset: Dict[str, List[SyntheticImage]] = {}
+    for index in range(repositories):
+        name = f"repo-{index:03d}"
+        base = rng.choice(bases)
+        team = f"team-{rng.randint(1, 8)}"
+        dependencies = factory.layers(rng.randint(1, 3), 10 * MB, 400 * MB)
+
+        # Revisions are created in order over the past two years
+        ages = sorted((rng.uniform(0, 730) for _ in range(tags_per_repository)), reverse=True)
+        images: List[SyntheticImage] = []
+        object_id = ""
+        for revision in range(tags_per_repository):
+            if revision % REVISIONS_PER_OBJECT_ID == 0:
+                object_id = "%024x" % rng.getrandbits(96)
+            tag = f"{object_id}-{revision % REVISIONS_PER_OBJECT_ID + 1}"
+            created = (now - timedelta(days=ages[revision])).isoformat()
+
+            if images and rng.random() < alias_ratio:
+                previous = images[-1]
+                images.append(SyntheticImage(name, tag, previous.layers, previous.created, dict(previous.labels)))
+                continue
+            if revision and rng.random() < rebuild_ratio:
+                dependencies = factory.layers(len(dependencies), 10 * MB, 400 * MB)
+            own_layers = factory.layers(rng.randint(1, 3), 4096, 40 * MB)
+            labels = {"team": team, "revision": str(revision + 1)}
+            images.append(SyntheticImage(name, tag, base + dependencies + own_layers, created, labels))
+        dataset[name] = images
+
+    return SyntheticDataset(dataset, seed)
+
+
+class SyntheticSkopeoClient:
+    """Serves a synthetic dataset through the SkopeoClient methods the analyzer uses.
+
+    Every call optionally sleeps for a fixed latency, to stand in for the round
+    trip to a registry and make the analyzer's parallelism matter.
+    """
+
+    def __init__(self, dataset: SyntheticDataset, repository: str, latency: float = 0.0):
+        """
+        Args:
+            dataset: Dataset to serve
+            repository: Repository the dataset's repositories are served under
+            latency: Seconds each call takes
+        """
+        self.repository = repository
+        self.latency = latency
+        self._tags: Dict[str, List[str]] = {}
+        self._images: Dict[Tuple[str, str], SyntheticImage] = {}
+        self._blob_sizes: Dict[str, int] = {}
+        for name, images in dataset.repositories.items():
+            path = f"{repository}/{name}"
+            self._tags[path] = [image.tag for image in images]
+            for image in images:
+                self._images[(path, image.tag)] = image
+                for layer in image.layers:
+                    self._blob_sizes[layer.digest] = layer.size
+
+    def _wait(self) -> None:
+        if self.latency:
+            time.sleep(self.latency)
+
+    def _image(self, repository: Optional[str], tag: str) -> Optional[SyntheticImage]:
+        self._wait()
+        return self._images.get((repository or self.repository, tag))
+
+    def list_tags(self, repository: Optional[str] = None) -> List[str]:
+        self._wait()
+        return list(self._tags.get(repository or self.repository, []))
+
+    def inspect_image(self, repository: Optional[str], tag: str) -> Optional[Dict]:
+        image = self._image(repository, tag)
+        if image is None:
+            return None
+        return {
+            "Name": repository or self.repository,
+            "Tag": tag,
+            "Digest": image.digest,
+            "Created": image.created,
+            "Labels": image.labels,
+            "Architecture": "amd64",
+            "Os": "linux",
+            "Layers": [layer.digest for layer in image.layers],
+            "LayersData": [
+                {"MIMEType": LAYER_MEDIA_TYPE, "Digest": layer.digest, "Size": layer.size} for layer in image.layers
+            ],
+        }
+
+    def get_manifest_digest(self, repository: Optional[str], tag: str) -> Optional[str]:
+        image = self._image(repository, tag)
+        return image.digest if image else None
+
+    def get_image_digest(self, repository: Optional[str], tag: str) -> Optional[str]:
+        return self.get_manifest_digest(repository, tag)
+
+    def get_manifest(self, repository: Optional[str], tag: str) -> Optional[Tuple[str, Dict]]:
+        image = self._image(repository, tag)
+        return (image.digest, image.manifest()) if image else None
+
+    def get_image_config(self, repository: Optional[str], tag: str) -> Optional[Dict]:
+        image = self._image(repository, tag)
+        return image.config() if image else None
+
+    def get_blob_size(self, repository: Optional[str], digest: str) -> Optional[int]:
+        self._wait()
+        return self._blob_sizes.get(digest)
+
+    def get_referrers(self, repository: Optional[str], digest: str) -> Optional[List[Dict[str, Any]]]:
+        self._wait()
+        return []
+
+    def get_blob(self, repository: Optional[str], digest: str, max_bytes: int) -> Optional[bytes]:
+        self._wait()
+        return None
+
+    def last_error_code(self) -> Optional[str]:
+        return None
+
+
+def _registry_base_url(registry_url: str) -> str:
+    """Base URL of a registry; registries given without a scheme are reached over plain HTTP"""
+    return registry_url.rstrip("/") if "://" in registry_url else f"http://{registry_url.rstrip('/')}"
+
+
+def _send(method: str, url: str, data: Optional[bytes] = None, headers: Optional[Dict[str, str]] = None):
+    if method not in ("GET", "HEAD"):
+        ensure_writable(f"pushing synthetic images ({method} {url})")
+    request = urllib.request.Request(url, data=data, method=method, headers=headers or {})
+    return urllib.request.urlopen(request, timeout=60)
+
+
+def _push_blob(base_url: str, repository: str, digest: str, content: bytes) -> None:
+    """Upload a blob to a repository in one request, unless it is already there"""
+    try:
+        with _send("HEAD", f"{base_url}/v2/{repository}/blobs/{digest}"):
+            return
+    except urllib.error.HTTPError as e:
+        if e.code != 404:
+            raise
+    with _send("POST", f"{base_url}/v2/{repository}/blobs/uploads/") as response:
+        location = urllib.parse.urljoin(f"{base_url}/", response.headers["Location"])
+    separator = "&" if "?" in location else "?"
+    with _send(
+        "PUT",
+        f"{location}{separator}digest={urllib.parse.quote(digest)}",
+        content,
+        {"Content-Type": "application/octet-stream"},
+    ):
+        pass
+
+
+def _push_repository(base_url: str, repository: str, images: List[SyntheticImage]) -> int:
+    """Push the images of one repository; returns the number of tags pushed"""
+    for image in images:
+        config_blob = image.config_blob()
+        _push_blob(base_url, repository, sha256_digest(config_blob), config_blob)
+        for layer in image.layers:
+            _push_blob(base_url, repository, layer.digest, layer.content)
+        with _send(
+            "PUT",
+            f"{base_url}/v2/{repository}/manifests/{image.tag}",
+            image.manifest_blob(placeholder_sizes=True),
+            {"Content-Type": MANIFEST_MEDIA_TYPE},
+        ):
+            pass
+    return len(images)
+
+
+def push_dataset(dataset: SyntheticDataset, registry_url: str, repository: str, max_workers: int = 4) -> int:
+    """Push a dataset to a registry that accepts anonymous pushes, such as a local registry:2 container.
+
+    Args:
+        dataset: Dataset to push
+        registry_url: Registry host[:port], optionally with an http(s):// scheme (default: http)
+        repository: Repository to push the dataset's repositories under
+        max_workers: Repositories pushed in parallel
+
+    Returns:
+        Number of tags pushed
+
+    Raises:
+        urllib.error.URLError: If the registry could not be reached or refused a push
+    """
+    base_url = _registry_base_url(registry_url)
+    pushed = 0
+    with concurrent.futures.ThreadPoolExecutor(max_workers=max_workers) as executor:
+        futures = [
+            executor.submit(_push_repository, base_url, f"{repository}/{name}", images)
+            for name, images in dataset.repositories.items()
+        ]
+        for future in concurrent.futures.as_completed(futures):
+            pushed += future.result()
+            logger.info(f"  Pushed {pushed} tags")
+    return pushed
@@ -0,0 +1,222 @@
+"""
+Tag immutability audit.
+
+Age- and usage-based retention assumes a tag keeps pointing to the image it was
+first pushed as. Registries can enforce that: ECR repositories have an
+imageTagMutability setting, and Harbor projects have tag immutability rules.
+Where tags are mutable, a tag can be re-pushed with a new image, and retention
+decisions made against the old digest become ambiguous.
+
+This module reads each repository's immutability setting and the digests its
+tags had in successive scan snapshots, and classifies repositories:
+
+    mutable_drift       Mutable tags that were re-pushed: retention is ambiguous
+    immutable_drift     Tags re-pushed although the registry now reports them
+                        immutable (the setting changed, or tags were deleted and
+                        pushed again)
+    mutable             Mutable, but no tag has changed in the snapshots
+    partial             Only some tags are immutable, and no tag has changed
+    immutable           Immutable, and no tag has changed
+    unknown             The setting could not be read, and no tag has changed
+"""
+
+import fnmatch
+import urllib.parse
+from typing import Any, Callable, Dict, Iterable, List, Optional, TypedDict
+
+from utils.logging_utils import get_logger
+
+logger = get_logger(__name__)
+
+# Immutability settings
+IMMUTABLE = "immutable"
+MUTABLE = "mutable"
+PARTIAL = "partial"  # Only some tags are immutable (Harbor rules with tag patterns, ECR exclusions)
+UNKNOWN = "unknown"
+
+# Findings
+MUTABLE_DRIFT = "mutable_drift"
+IMMUTABLE_DRIFT = "immutable_drift"
+
+
+class DigestChange(TypedDict):
+    """A tag that pointed to more than one digest across snapshots."""
+
+    tag: str
+    digests: List[str]  # Digests in snapshot order, without repeats
+
+
+class RepositoryAudit(TypedDict):
+    """Immutability setting and observed digest drift of one repository."""
+
+    repository: str
+    immutability: str
+    finding: str
+    tags: int
+    changed_tags: List[DigestChange]
+
+
+def ecr_tag_immutability(ecr_client: Any, repositories: Iterable[str]) -> Dict[str, str]:
+    """Read the imageTagMutability setting of ECR repositories.
+
+    Args:
+        ecr_client: boto3 ECR client of the registry
+        repositories: Repository names
+
+    Returns:
+        Repository -> IMMUTABLE, MUTABLE or PARTIAL (immutable with exclusions);
+        repositories that could not be described are left out
+    """
+    settings: Dict[str, str] = {}
+    for repository in repositories:
+        try:
+            response = ecr_client.describe_repositories(repositoryNames=[repository])
+        except Exception as e:
+            logger.warning(f"Could not read the tag mutability of ECR repository {repository}: {e}")
+            continue
+        for described in response.get("repositories", []):
+            mutability = described.get("imageTagMutability", "")
+            if mutability == "IMMUTABLE":
+                settings[described["repositoryName"]] = IMMUTABLE
+            elif mutability.startswith("IMMUTABLE"):
+                settings[described["repositoryName"]] = PARTIAL
+            elif mutability:
+                settings[described["repositoryName"]] = MUTABLE
+    return settings
+
+
+def _selector_matches(selectors: List[Dict[str, Any]], name: str, include: str, exclude: str) -> bool:
+    """Whether Harbor selectors (doublestar patterns) select a name"""
+    for selector in selectors or []:
+        pattern = str(selector.get("pattern", ""))
+        matches = any(fnmatch.fnmatchcase(name, part.strip()) for part in pattern.strip("{}").split(","))
+        decoration = selector.get("decoration")
+        if (decoration == include and not matches) or (decoration == exclude and matches):
+            return False
+    return True
+
+
+def harbor_rule_coverage(rules: List[Dict[str, Any]], repository: str) -> str:
+    """How Harbor tag immutability rules of a project cover a repository.
+
+    Args:
+        rules: Immutability rules of the project (GET /api/v2.0/projects/{project}/immutabletagrules)
+        repository: Repository name within the project
+
+    Returns:
+        IMMUTABLE if an enabled rule makes every tag immutable, PARTIAL if rules
+        only cover some tags, MUTABLE if no rule applies
+    """
+    coverage = MUTABLE
+    for rule in rules:
+        if rule.get("disabled"):
+            continue
+        scope = (rule.get("scope_selectors") or {}).get("repository") or []
+        if not _selector_matches(scope, repository, "repoMatches", "repoExcludes"):
+            continue
+        tag_selectors = rule.get("tag_selectors") or []
+        all_tags = all(
+            selector.get("decoration") == "matches" and str(selector.get("pattern")) in ("**", "*")
+            for selector in tag_selectors
+        )
+        if all_tags:
+            return IMMUTABLE
+        coverage = PARTIAL
+    return coverage
+
+
+def harbor_tag_immutability(get_json: Callable[[str], Any], repositories: Iterable[str]) -> Dict[str, str]:
+    """Read the tag immutability rules that apply to Harbor repositories.
+
+    Args:
+        get_json: Reads a Harbor API path (e.g. SkopeoClient.get_api_json), None if unavailable
+        repositories: Repository names ("<project>/<repository>")
+
+    Returns:
+        Repository -> IMMUTABLE, PARTIAL or MUTABLE; repositories whose project
+        rules could not be read are left out
+    """
+    rules_by_project: Dict[str, Optional[List[Dict[str, Any]]]] = {}
+    settings: Dict[str, str] = {}
+    for repository in repositories:
+        project, _, name = repository.partition("/")
+        if project not in rules_by_project:
+            rules = get_json(f"/api/v2.0/projects/{urllib.parse.quote(project, safe='')}/immutabletagrules")
+            rules_by_project[project] = rules if isinstance(rules, list) else None
+            if rules_by_project[project] is None:
+                logger.warning(f"Could not read the tag immutability rules of Harbor project {project}")
+        rules = rules_by_project[project]
+        if rules is not None:
+            settings[repository] = harbor_rule_coverage(rules, name)
+    return settings
+
+
+def tag_digest_history(snapshots: Iterable[Dict[str, Any]]) -> Dict[str, Dict[str, List[str]]]:
+    """Digests each tag had across snapshots.
+
+    Args:
+        snapshots: Snapshot documents (see scan_snapshot.build_snapshot), oldest first
+
+    Returns:
+        Repository -> tag -> digests in snapshot order, consecutive repeats removed
+    """
+    history: Dict[str, Dict[str, List[str]]] = {}
+    for snapshot in snapshots:
+        for image in (snapshot.get("images") or {}).values():
+            digest = image.get("digest")
+            if not digest:
+                continue
+            digests = history.setdefault(image["repository"], {}).setdefault(image["tag"], [])
+            if not digests or digests[-1] != digest:
+                digests.append(digest)
+    return history
+
+
+def audit_repositories(
+    history: Dict[str, Dict[str, List[str]]], immutability: Dict[str, str]
+) -> List[RepositoryAudit]:
+    """Cross-check repositories' immutability settings with their tags' digest history.
+
+    Args:
+        history: Repository -> tag -> digests (see tag_digest_history)
+        immutability: Repository -> setting; missing repositories are UNKNOWN
+
+    Returns:
+        One audit per repository, repositories with drift on mutable tags first
+    """
+    audits: List[RepositoryAudit] = []
+    for repository in sorted(set(history) | set(immutability)):
+        tags = history.get(repository, {})
+        changed: List[DigestChange] = [
+            {"tag": tag, "digests": digests} for tag, digests in sorted(tags.items()) if len(digests) > 1
+        ]
+        setting = immutability.get(repository, UNKNOWN)
+        if changed:
+            finding = IMMUTABLE_DRIFT if setting == IMMUTABLE else MUTABLE_DRIFT
+        else:
+            finding = setting
+        audits.append(
+            {
+                "repository": repository,
+                "immutability": setting,
+                "finding": finding,
+                "tags": len(tags),
+                "changed_tags": changed,
+            }
+        )
+    order = {MUTABLE_DRIFT: 0, IMMUTABLE_DRIFT: 1}
+    audits.sort(key=lambda audit: (order.get(audit["finding"], 2), audit["repository"]))
+    return audits
+
+
+def summarize_audits(audits: List[RepositoryAudit]) -> Dict[str, Any]:
+    """Totals over the audited repositories"""
+    counts: Dict[str, int] = {}
+    for audit in audits:
+        counts[audit["finding"]] = counts.get(audit["finding"], 0) + 1
+    return {
+        "repositories": len(audits),
+        "findings": dict(sorted(counts.items())),
+        "changed_tags": sum(len(audit["changed_tags"]) for audit in audits),
+        "ambiguous_repositories": counts.get(MUTABLE_DRIFT, 0) + counts.get(IMMUTABLE_DRIFT, 0),
+    }
@@ -6,6 +6,38 @@ Provides functions for matching model tags that may have extended formats
 like <modelId>-<version>-<timestamp>_<uniqueId>.
 """
 
+import re
+from fnmatch import fnmatchcase
+from typing import Iterable, List, Optional, Pattern, Tuple
+
+# Tags pushed next to images by signing and supply-chain tools (cosign
+# signatures, attestations and SBOMs); they are not images of their own
+DEFAULT_EXCLUDED_TAG_PATTERNS = ["sha256-*.sig", "*.att", "*.sbom"]
+
+# Tag names that usually float from image to image (mutable_tags_report)
+DEFAULT_FLOATING_TAGS = [
+    "latest",
+    "stable",
+    "prod",
+    "production",
+    "staging",
+    "dev",
+    "main",
+    "master",
+    "edge",
+    "nightly",
+]
+
+# Tags Domino pushes: <environment or model ObjectID>-<revision or version>, optionally
+# followed by build details (e.g. "507f1f77bcf86cd799439011-3", "507f...-v2-1234567890_abc123")
+DOMINO_TAG_PATTERN = r"^[0-9a-f]{24}-v?[0-9]+(-[A-Za-z0-9_.]+)*$"
+
+# Environment tags Domino pushes: <environment ObjectID>-<revision number>
+_ENVIRONMENT_TAG_RE = re.compile(r"^([0-9a-f]{24})-([0-9]+)$")
+
+# Reference tags name the manifest they belong to: sha256-<hex digest>[.<kind>]
+_REFERENCE_TAG_RE = re.compile(r"^sha256-([0-9a-f]{64})(?:\.(.+))?$")
+
 
 def extract_model_tag_prefix(tag: str) -> str:
     """Extract the prefix from a model tag for matching purposes.
@@ -31,6 +63,38 @@ def extract_model_tag_prefix(tag: str) -> str:
     return tag
 
 
+def extract_tag_namespace(tag: str) -> str:
+    """Extract the owning environment/model namespace from a tag.
+
+    Domino tags start with the ObjectID of the environment or model that
+    pushed them (e.g. `<environmentId>-<revision>`), so the first part
+    before '-' identifies the namespace.
+
+    Args:
+        tag: Docker image tag (e.g., "507f1f77bcf86cd799439011-3")
+
+    Returns:
+        Namespace string (e.g., "507f1f77bcf86cd799439011")
+    """
+    return tag.split("-")[0] if "-" in tag else tag
+
+
+def parse_environment_tag(tag: str) -> Optional[Tuple[str, int]]:
+    """Parse an environment tag of Domino's tag scheme.
+
+    Args:
+        tag: Environment image tag (e.g., "507f1f77bcf86cd799439011-3")
+
+    Returns:
+        (environment_id, revision_number), e.g. ("507f1f77bcf86cd799439011", 3);
+        None if the tag does not follow the scheme
+    """
+    match = _ENVIRONMENT_TAG_RE.match(tag)
+    if not match:
+        return None
+    return match.group(1), int(match.group(2))
+
+
 def model_tags_match(registry_tag: str, stored_tag: str) -> bool:
     """Check if a registry tag matches a stored tag from MongoDB.
 
@@ -99,3 +163,53 @@ def build_model_tag_query(tags: list) -> dict:
         return all_conditions[0]
 
     return {"$or": all_conditions}
+
+
+def is_excluded_tag(tag: str, patterns: Iterable[str]) -> bool:
+    """Check whether a tag matches any of the given glob patterns.
+
+    Args:
+        tag: Registry tag
+        patterns: Shell-style patterns (e.g. "sha256-*.sig"), matched case-sensitively
+
+    Returns:
+        True if the tag should be excluded from analysis
+    """
+    return any(fnmatchcase(tag, pattern) for pattern in patterns)
+
+
+def parse_reference_tag(tag: str) -> Optional[Tuple[str, str]]:
+    """Parse a digest-style reference tag such as "sha256-<digest>.sig".
+
+    Signing and supply-chain tools (cosign, ORAS) push signatures, attestations
+    and SBOMs under tags derived from the digest of the image they describe.
+
+    Args:
+        tag: Registry tag
+
+    Returns:
+        (subject_digest, kind), e.g. ("sha256:<digest>", "sig"); kind is "" when
+        the tag has no suffix. None if the tag is not a reference tag.
+    """
+    match = _REFERENCE_TAG_RE.match(tag)
+    if not match:
+        return None
+    return f"sha256:{match.group(1)}", match.group(2) or ""
+
+
+def find_nonconforming_tags(
+    tags: Iterable[str], pattern: Pattern[str], excluded_patterns: Iterable[str] = ()
+) -> List[str]:
+    """Find tags that do not follow a naming convention.
+
+    Args:
+        tags: Registry tags
+        pattern: Compiled regular expression a conforming tag matches in full
+        excluded_patterns: Shell-style patterns of tags that are not checked, such as
+            signature tags (see is_excluded_tag)
+
+    Returns:
+        Non-conforming tags, sorted
+    """
+    excluded = list(excluded_patterns)
+    return sorted(tag for tag in tags if not is_excluded_tag(tag, excluded) and not pattern.fullmatch(tag))
@@ -0,0 +1,56 @@
+"""
+Rendering of timestamps in console output.
+
+Reports store timestamps as ISO 8601 (skopeo reports image creation times in
+UTC), which is what other tools should read. Tables printed to the console
+render them in the display time zone instead - reports.timezone in
+config.yaml, REPORT_TIMEZONE, or --timezone - together with their age, so
+stale images stand out without converting times by hand:
+
+    2025-03-02 14:05 CET (412 days ago)
+"""
+
+from datetime import datetime, timezone, tzinfo
+from typing import Optional, Union
+
+from utils.deletion_candidates import days_since, parse_created
+
+
+def age_days(created: Union[str, datetime, None], now: Optional[datetime] = None) -> Optional[int]:
+    """Whole days since a timestamp (ISO 8601 string or datetime), or None if unknown"""
+    timestamp = parse_created(created) if isinstance(created, str) else created
+    days = days_since(timestamp, now or datetime.now(timezone.utc))
+    return None if days is None else int(days)
+
+
+def format_timestamp(value: Union[str, datetime, None], tz: tzinfo, with_age: bool = False) -> str:
+    """Render a timestamp in a time zone, e.g. "2025-03-02 14:05 CET", or "unknown".
+
+    Timestamps without a zone are taken to be UTC. Strings that are not
+    ISO 8601 are returned unchanged.
+
+    Args:
+        value: ISO 8601 string or datetime
+        tz: Display time zone
+        with_age: Append the age, e.g. " (412 days ago)"
+    """
+    if not value:
+        return "unknown"
+    timestamp = parse_created(value) if isinstance(value, str) else value
+    if timestamp is None:
+        return str(value)
+    if timestamp.tzinfo is None:
+        timestamp = timestamp.replace(tzinfo=timezone.utc)
+    rendered = timestamp.astimezone(tz).strftime("%Y-%m-%d %H:%M %Z")
+    if with_age:
+        rendered += f" ({format_age(age_days(timestamp))})"
+    return rendered
+
+
+def format_age(days: Optional[int]) -> str:
+    """Render an age in days, e.g. "412 days ago", "today", or "age unknown" """
+    if days is None:
+        return "age unknown"
+    if days == 0:
+        return "today"
+    return f"{days} day{'s' if days != 1 else ''} ago"
@@ -0,0 +1,136 @@
+"""
+Outdated build toolchain detection.
+
+Images built years ago with an old Docker engine, or on a base image that has
+reached end of life, are the ones most worth rebuilding or deleting. Every full
+inspection records the build fields skopeo reports for an image:
+
+    {"docker_version": "1.13.1", "os": "linux", "architecture": "amd64"}
+
+and the image's base image is read from the org.opencontainers.image.base.name
+annotation or label, where the build recorded it. An image is outdated when its
+DockerVersion is older than min_docker_version, or when its base image matches
+one of the shell-style outdated_base_images patterns (case-insensitive, matched
+against both the full reference and the short Docker Hub name, e.g.
+"ubuntu:16.04" for "docker.io/library/ubuntu:16.04"):
+
+    analysis:
+      toolchain:
+        min_docker_version: "18.09"
+        outdated_base_images: ["ubuntu:16.04*", "centos:7*", "*/legacy-base:*"]
+
+Images built with BuildKit or other OCI builders report no DockerVersion and are
+never flagged for it. candidates_report ranks outdated images higher through the
+outdated_toolchain factor of analysis.candidate_weights.
+"""
+
+import re
+from fnmatch import fnmatchcase
+from typing import Any, Dict, List, Optional, Tuple, TypedDict
+
+ANNOTATION_BASE_NAME = "org.opencontainers.image.base.name"
+
+REASON_DOCKER_VERSION = "docker_version"
+REASON_BASE_IMAGE = "base_image"
+REASON_EOL_OS = "eol_os"  # the image's OS release is past end of life (see utils.os_release, deep scans)
+
+DEFAULT_MIN_DOCKER_VERSION = "18.09"
+
+# Base images whose distribution release has reached end of life
+DEFAULT_OUTDATED_BASE_IMAGES = [
+    "ubuntu:14.04*",
+    "ubuntu:16.04*",
+    "ubuntu:18.04*",
+    "ubuntu:trusty*",
+    "ubuntu:xenial*",
+    "ubuntu:bionic*",
+    "debian:8*",
+    "debian:9*",
+    "debian:jessie*",
+    "debian:stretch*",
+    "centos:6*",
+    "centos:7*",
+    "centos:8*",
+]
+
+_DOCKER_HUB_PREFIXES = ("docker.io/library/", "index.docker.io/library/", "library/", "docker.io/", "index.docker.io/")
+
+
+class ToolchainInfo(TypedDict, total=False):
+    """Build fields of an image, as skopeo inspect reports them."""
+
+    docker_version: str
+    os: str
+    architecture: str
+
+
+class OutdatedToolchain(TypedDict):
+    """An image built with an outdated toolchain."""
+
+    image_id: str
+    reasons: List[str]  # REASON_* constants
+    docker_version: Optional[str]
+    os: Optional[str]
+    base_image: Optional[str]
+
+
+def toolchain_info(image_info: Dict[str, Any]) -> ToolchainInfo:
+    """Extract the build fields of a skopeo inspect result (empty fields are left out)"""
+    info: ToolchainInfo = {}
+    for key, field in (("docker_version", "DockerVersion"), ("os", "Os"), ("architecture", "Architecture")):
+        value = image_info.get(field)
+        if isinstance(value, str) and value:
+            info[key] = value  # type: ignore[literal-required]
+    return info
+
+
+def base_image(labels: Optional[Dict[str, str]], annotations: Optional[Dict[str, str]]) -> Optional[str]:
+    """The base image an image was built from, where the build recorded it"""
+    for source in (annotations, labels):
+        name = (source or {}).get(ANNOTATION_BASE_NAME)
+        if name:
+            return name
+    return None
+
+
+def parse_version(version: str) -> Optional[Tuple[int, ...]]:
+    """Numeric parts of a Docker version ("18.09.7", "20.10.21+dfsg1", "1.13.1-rc1"), or None if it has none"""
+    match = re.match(r"v?(\d+(?:\.\d+)*)", version.strip())
+    if not match:
+        return None
+    return tuple(int(part) for part in match.group(1).split("."))
+
+
+def _short_name(reference: str) -> str:
+    """A Docker Hub reference without its registry and library prefix"""
+    lowered = reference.lower()
+    for prefix in _DOCKER_HUB_PREFIXES:
+        if lowered.startswith(prefix):
+            return lowered[len(prefix) :]
+    return lowered
+
+
+def outdated_reasons(
+    info: Optional[ToolchainInfo], base: Optional[str], min_docker_version: str, outdated_base_images: List[str]
+) -> List[str]:
+    """Why an image's toolchain is outdated.
+
+    Args:
+        info: Build fields of the image, if it was fully inspected
+        base: Base image of the image, if known
+        min_docker_version: Docker versions older than this are outdated
+        outdated_base_images: Shell-style patterns of outdated base images
+
+    Returns:
+        REASON_* constants, empty if nothing is known to be outdated
+    """
+    reasons = []
+    version = parse_version((info or {}).get("docker_version", ""))
+    minimum = parse_version(min_docker_version)
+    if version is not None and minimum is not None and version < minimum:
+        reasons.append(REASON_DOCKER_VERSION)
+    if base:
+        names = {base.lower(), _short_name(base)}
+        if any(fnmatchcase(name, pattern.lower()) for name in names for pattern in outdated_base_images):
+            reasons.append(REASON_BASE_IMAGE)
+    return reasons
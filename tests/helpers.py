@@ -0,0 +1,34 @@
+"""Helpers for building analyzers in tests."""
+
+import os
+import sys
+from typing import List, Optional, Tuple
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_data_analysis import ImageAnalyzer
+
+
+def make_analyzer(registry_url: str = "http://test-registry", repository: str = "test-repo") -> ImageAnalyzer:
+    """Create an ImageAnalyzer holding no images"""
+    return ImageAnalyzer(registry_url, repository)
+
+
+def add_image(
+    analyzer: ImageAnalyzer,
+    image_id: str,
+    layers: List[Tuple[str, int]],
+    digest: Optional[str] = None,
+    created: Optional[str] = None,
+) -> str:
+    """Index an image with the given (layer_id, size_bytes) layers, in the repository of its type
+
+    Returns:
+        The image_id
+    """
+    image_type, tag = image_id.split(":", 1)
+    repository = f"{analyzer.repository}/{image_type}"
+    analyzer.index.add_image(image_id, repository, tag, digest or f"sha256:{image_id}", layers)
+    if created:
+        analyzer.created[image_id] = created
+    return image_id
@@ -134,6 +134,23 @@ class TestAgeFiltering:
         # old-tag should NOT be in use (older than 30 days)
         assert "old-tag" not in in_use_tags
 
+    def test_check_tags_in_use_widens_to_protection_window(self, monkeypatch):
+        """Test that analysis.recent_run_protection_days widens a shorter usage window"""
+        from utils.config_manager import config_manager
+
+        service = ImageUsageService()
+
+        now = datetime.now(timezone.utc)
+        used_date = (now - timedelta(days=20)).isoformat().replace("+00:00", "Z")
+        mongodb_reports = {"runs": [{"environment_docker_tag": "pinned-revision", "last_used": used_date}]}
+
+        in_use_tags, _ = service.check_tags_in_use(["pinned-revision"], mongodb_reports, recent_days=7)
+        assert "pinned-revision" not in in_use_tags
+
+        monkeypatch.setitem(config_manager.config["analysis"], "recent_run_protection_days", 30)
+        in_use_tags, _ = service.check_tags_in_use(["pinned-revision"], mongodb_reports, recent_days=7)
+        assert "pinned-revision" in in_use_tags
+
     def test_check_tags_in_use_keeps_config_usage(self):
         """Test that tags with config usage (projects, scheduler_jobs) are kept even if old"""
         service = ImageUsageService()
@@ -157,6 +174,31 @@ class TestAgeFiltering:
         # Should be kept because it's in projects (current config)
         assert "old-but-in-project" in in_use_tags
 
+    def test_check_tags_in_use_keeps_direct_references(self):
+        """Test that tags found by the reference scan are kept even if their runs are old"""
+        service = ImageUsageService()
+
+        now = datetime.now(timezone.utc)
+        old_date = (now - timedelta(days=100)).isoformat().replace("+00:00", "Z")
+
+        mongodb_reports = {
+            "runs": [{"environment_docker_tag": "active-revision", "last_used": old_date}],
+            "references": [
+                {
+                    "collection": "environments_v2",
+                    "field": "activeRevisionId",
+                    "document_id": "env1",
+                    "environment_docker_tag": "active-revision",
+                }
+            ],
+        }
+
+        in_use_tags, usage_info = service.check_tags_in_use(["active-revision"], mongodb_reports, recent_days=30)
+
+        assert "active-revision" in in_use_tags
+        assert usage_info["active-revision"]["references"][0]["collection"] == "environments_v2"
+        assert "1 direct MongoDB reference" in service.generate_usage_summary(usage_info["active-revision"])
+
     def test_check_tags_in_use_no_age_filtering(self):
         """Test that without recent_days, all usage is considered"""
         service = ImageUsageService()
@@ -0,0 +1,99 @@
+"""Unit tests for alerting.py"""
+
+import json
+import os
+import sys
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.alerting import Alert, evaluate_run, request_error_rate, send_alert
+
+REGISTRY = "registry.example.com"
+THRESHOLDS = {"on_failure": True, "max_error_rate": 0.05, "max_reclaimable_gb": 100.0}
+
+
+class TestEvaluateRun:
+    """Tests for checking finished scheduled runs against alert thresholds"""
+
+    def test_failed_run(self):
+        """Test that a failed run raises an alert carrying the end of its output"""
+        results = evaluate_run(REGISTRY, "plan", 1, THRESHOLDS, log_tail=["❌ Plan generation failed"])
+
+        alert = results["docker-registry-cleaner/registry.example.com/plan/run_failed"]
+        assert alert is not None
+        assert "failed (exit code 1)" in alert.summary
+        assert alert.details["log_tail"] == ["❌ Plan generation failed"]
+
+    def test_thresholds(self):
+        """Test error rate and reclaimable space thresholds, and that met conditions are reported as None"""
+        run_stats = {"inspect": {"count": 90, "errors": 9}, "delete": {"count": 10, "errors": 1}}
+
+        results = evaluate_run(REGISTRY, "plan", 0, THRESHOLDS, run_stats, reclaimable_bytes=150 * 1024**3)
+
+        assert request_error_rate(run_stats) == 0.1
+        assert results["docker-registry-cleaner/registry.example.com/plan/run_failed"] is None
+        assert "10.0% of registry requests failed" in results[
+            "docker-registry-cleaner/registry.example.com/plan/error_rate"
+        ].summary
+        assert "150.0 GB reclaimable" in results[
+            "docker-registry-cleaner/registry.example.com/plan/reclaimable_space"
+        ].summary
+
+        quiet = evaluate_run(REGISTRY, "plan", 0, THRESHOLDS, {"inspect": {"count": 100, "errors": 1}}, 1024**3)
+        assert set(quiet.values()) == {None}
+
+    def test_unchecked_conditions_are_left_out(self):
+        """Test that disabled thresholds and missing data check nothing, so no alert is resolved by mistake"""
+        thresholds = {"on_failure": False, "max_error_rate": 0.05, "max_reclaimable_gb": 0.0}
+
+        assert evaluate_run(REGISTRY, "apply", 1, thresholds, run_stats={}, reclaimable_bytes=10**15) == {}
+
+
+    def test_owner_quotas(self):
+        """Test one alert per owner with a quota, raised only for owners over it"""
+        gb = 1024**3
+        statuses = [
+            {"owner": "data-science", "used_bytes": 60 * gb, "quota_bytes": 50 * gb, "over_bytes": 10 * gb, "share": 1},
+            {"owner": "forecasting", "used_bytes": 0, "quota_bytes": 50 * gb, "over_bytes": 0, "share": 0},
+        ]
+
+        results = evaluate_run(REGISTRY, "plan", 0, THRESHOLDS, owner_quotas=statuses)
+
+        alert = results["docker-registry-cleaner/registry.example.com/plan/owner_quota/data-science"]
+        assert "owner data-science uses 60.0 GB of registry storage (quota 50 GB)" in alert.summary
+        assert results["docker-registry-cleaner/registry.example.com/plan/owner_quota/forecasting"] is None
+
+class TestSendAlert:
+    """Tests for delivering alerts to PagerDuty and Opsgenie"""
+
+    def test_trigger_and_resolve(self):
+        """Test the PagerDuty and Opsgenie requests for triggering and resolving an alert"""
+        destinations = {
+            "pagerduty": {"routing_key": "pd-key"},
+            "opsgenie": {"api_key": "genie", "api_url": "https://api.opsgenie.com"},
+        }
+        alert = Alert(key="docker-registry-cleaner/r/plan/run_failed", summary="Scheduled plan failed")
+
+        with patch("utils.alerting.urllib.request.urlopen") as urlopen:
+            assert send_alert(destinations, alert, REGISTRY)
+            assert send_alert(destinations, alert, REGISTRY, resolve=True)
+
+        requests = [call.args[0] for call in urlopen.call_args_list]
+        pagerduty = json.loads(requests[0].data)
+        assert pagerduty["event_action"] == "trigger"
+        assert pagerduty["dedup_key"] == alert.key
+        assert pagerduty["payload"]["source"] == REGISTRY
+        assert requests[1].full_url == "https://api.opsgenie.com/v2/alerts"
+        assert requests[1].get_header("Authorization") == "GenieKey genie"
+        assert json.loads(requests[2].data)["event_action"] == "resolve"
+        assert requests[3].full_url.endswith(
+            "/v2/alerts/docker-registry-cleaner%2Fr%2Fplan%2Frun_failed/close?identifierType=alias"
+        )
+
+    def test_delivery_failure(self):
+        """Test that an unreachable service is reported without raising"""
+        alert = Alert(key="k", summary="s")
+
+        with patch("utils.alerting.urllib.request.urlopen", side_effect=OSError("connection refused")):
+            assert send_alert({"pagerduty": {"routing_key": "pd-key"}}, alert, REGISTRY) is False
@@ -0,0 +1,92 @@
+"""Unit tests for anonymize.py"""
+
+import csv
+import json
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.anonymize import Anonymizer, anonymize_reports
+
+TAG = "507f1f77bcf86cd799439011-3"
+
+
+class TestAnonymizer:
+    """Tests for replacing customer identifiers by keyed hashes"""
+
+    def test_identifiers_are_hashed_consistently(self):
+        """Test that tags, names and registry hosts get the same hash wherever they appear"""
+        anonymizer = Anonymizer(b"secret", ["registry.acme.com:5000"])
+        report = {
+            "summary": {"registry_url": "registry.acme.com:5000", "total_size": 1024},
+            TAG: {"size": 1024, "environments": ["507f1f77bcf86cd799439011"]},
+            "items": [
+                {
+                    "image_id": f"environment:{TAG}",
+                    "tag": TAG,
+                    "environment_name": "Acme Spark",
+                    "full_image": f"registry.acme.com:5000/dominodatalab/environment:{TAG}",
+                    "digest": "sha256:abc",
+                    "message": "Pulled from registry.acme.com",
+                }
+            ],
+        }
+
+        result = anonymizer.anonymize(report)
+
+        tag = anonymizer.token("tag", TAG)
+        host = anonymizer.token("host", "registry.acme.com")
+        item = result["items"][0]
+        assert result["summary"] == {"registry_url": host, "total_size": 1024}
+        assert result[tag] == {"size": 1024, "environments": ["507f1f77bcf86cd799439011"]}
+        assert item["image_id"] == f"environment:{tag}"
+        assert item["tag"] == tag
+        assert item["environment_name"] == anonymizer.token("name", "Acme Spark")
+        assert item["full_image"] == f"{host}/dominodatalab/environment:{tag}"
+        assert item["digest"] == "sha256:abc"
+        assert item["message"] == f"Pulled from {host}"
+        assert "acme" not in json.dumps(result).lower()
+
+    def test_hashes_depend_on_salt(self):
+        """Test that hashes are stable for a salt and differ between salts"""
+        assert Anonymizer(b"one").token("tag", "latest") == Anonymizer(b"one").token("tag", "latest")
+        assert Anonymizer(b"one").token("tag", "latest") != Anonymizer(b"two").token("tag", "latest")
+
+    def test_records_in_tag_lists_keep_their_keys(self):
+        """Test that dicts in a list of tags are anonymized as records, and repositories without a host are kept"""
+        anonymizer = Anonymizer(b"secret")
+
+        result = anonymizer.anonymize(
+            {"changed_tags": [{"tag": "latest", "digest": "sha256:abc"}], "image": "dominodatalab/environment:latest"}
+        )
+
+        tag = anonymizer.token("tag", "latest")
+        assert result["changed_tags"] == [{"tag": tag, "digest": "sha256:abc"}]
+        assert result["image"] == f"dominodatalab/environment:{tag}"
+
+
+class TestAnonymizeReports:
+    """Tests for saving anonymized copies of reports"""
+
+    def test_json_and_csv_copies(self, tmp_path):
+        """Test that JSON and CSV reports are copied anonymized, other reports skipped, and originals kept"""
+        (tmp_path / "plan.json").write_text(json.dumps({"items": [{"tag": TAG}]}))
+        (tmp_path / "bill.csv").write_text("owner,environment_name,cost\ndata-science,Acme Spark,1.5\n")
+        (tmp_path / "report.html").write_text("<html>Acme Spark</html>")
+        anonymizer = Anonymizer(b"secret")
+
+        copies = anonymize_reports(
+            anonymizer,
+            [tmp_path / "plan.json", tmp_path / "bill.csv", tmp_path / "report.html"],
+            tmp_path / "anonymized",
+        )
+
+        assert [copy.name for copy in copies] == ["plan.json", "bill.csv"]
+        assert json.loads(copies[0].read_text()) == {"items": [{"tag": anonymizer.token("tag", TAG)}]}
+        with open(copies[1], newline="") as f:
+            rows = list(csv.DictReader(f))
+        assert rows == [
+            {"owner": "data-science", "environment_name": anonymizer.token("name", "Acme Spark"), "cost": "1.5"}
+        ]
+        assert json.loads((tmp_path / "plan.json").read_text()) == {"items": [{"tag": TAG}]}
@@ -0,0 +1,101 @@
+"""Unit tests for scripts/apply.py"""
+
+import os
+import sys
+from typing import List, Optional
+from unittest.mock import MagicMock, patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from scripts.apply import PlanApplier
+from utils.cleanup_plan import CleanupPlan, PlanItem, PolicyProvenance
+from utils.config_manager import config_manager
+
+REGISTRY = "registry.example.com"
+REPOSITORY = "dominodatalab/environment"
+
+
+def _item(tag: str, digest: str) -> PlanItem:
+    """Create a plan item for an environment tag"""
+    return PlanItem(image_id=f"environment:{tag}", repository=REPOSITORY, tag=tag, digest=digest)
+
+
+def _plan(*items: PlanItem) -> CleanupPlan:
+    """Create a plan holding the given items"""
+    return CleanupPlan(
+        registry_url=REGISTRY,
+        repository="dominodatalab",
+        policy=PolicyProvenance(source="test"),
+        items=list(items),
+    )
+
+
+class TestApplyPlan:
+    """Tests for the checks apply repeats immediately before deleting"""
+
+    def setup_method(self):
+        """Set up an applier whose registry holds v1 and v2, and usage, deployments and signing that keep nothing"""
+        with patch("utils.deletion_base.SkopeoClient"), patch("utils.deletion_base.HealthChecker"), patch(
+            "utils.deletion_base.CheckpointManager"
+        ):
+            self.applier = PlanApplier(registry_url=REGISTRY, repository="dominodatalab")
+        self.digests = {"v1": "sha256:1", "v2": "sha256:2"}
+        client = self.applier.skopeo_client
+        client.get_image_digest.side_effect = lambda repository, tag: self.digests.get(tag)
+        client.get_manifest_digest.side_effect = lambda repository, tag: self.digests.get(tag)
+        client.list_tags.side_effect = lambda repository: list(self.digests)
+        client.signed_tag_refusal.return_value = None
+        client.is_registry_in_cluster.return_value = False
+        client.delete_image.return_value = True
+        client.delete_manifest.return_value = True
+        self.in_use: List[str] = []
+
+    def _apply(self, plan: CleanupPlan, providers: Optional[List[str]] = None, reference_file: str = ""):
+        """Apply a plan for real, with the given protection providers configured"""
+        service = MagicMock()
+        service.check_tags_in_use.side_effect = lambda tags: ({tag for tag in tags if tag in self.in_use}, {})
+        service.generate_usage_summary.return_value = "workspace ws-1"
+        with patch("utils.image_usage.ImageUsageService", return_value=service), patch(
+            "scripts.apply.build_model_version_mapping", return_value={}
+        ), patch.object(config_manager, "get_protection_providers", return_value=providers or []), patch.object(
+            config_manager, "get_protection_reference_file", return_value=reference_file
+        ):
+            return self.applier.apply_plan(plan, dry_run=False)
+
+    def test_item_protected_since_planning_skipped(self, tmp_path):
+        """Test that an item a protection provider protects now is skipped, and the others are deleted"""
+        reference_file = tmp_path / "keep.txt"
+        reference_file.write_text(f"{REGISTRY}/{REPOSITORY}:v1\n")
+        plan = _plan(_item("v1", "sha256:1"), _item("v2", "sha256:2"))
+
+        result = self._apply(plan, ["reference-file"], str(reference_file))
+
+        statuses = {r["image_id"]: (r["status"], r.get("reason", "")) for r in result["results"]}
+        assert statuses["environment:v1"] == ("skipped", f"protected by reference-file ({reference_file}:1)")
+        assert statuses["environment:v2"] == ("deleted", "")
+        self.applier.skopeo_client.delete_image.assert_called_once_with(REPOSITORY, "v2")
+
+    def test_in_use_alias_not_in_plan_blocks_deletion(self):
+        """Test that a tag is kept when deleting its manifest would also remove an in-use alias outside the plan"""
+        self.digests["v1-stable"] = "sha256:1"
+        self.in_use.append("v1-stable")
+
+        result = self._apply(_plan(_item("v1", "sha256:1"), _item("v2", "sha256:2")))
+
+        statuses = {r["image_id"]: (r["status"], r.get("reason", "")) for r in result["results"]}
+        assert statuses["environment:v1"] == (
+            "skipped",
+            "deleting the manifest would also remove v1-stable, not in the plan",
+        )
+        assert statuses["environment:v2"] == ("deleted", "")
+        self.applier.skopeo_client.delete_image.assert_called_once_with(REPOSITORY, "v2")
+
+    def test_planned_aliases_deleted_together(self):
+        """Test that a manifest is deleted once when every tag pointing to it is in the plan"""
+        self.digests["v1-stable"] = "sha256:1"
+
+        result = self._apply(_plan(_item("v1", "sha256:1"), _item("v1-stable", "sha256:1")))
+
+        assert [r["status"] for r in result["results"]] == ["deleted", "deleted"]
+        self.applier.skopeo_client.delete_manifest.assert_called_once_with(REPOSITORY, "sha256:1", ["v1", "v1-stable"])
+        self.applier.skopeo_client.delete_image.assert_not_called()
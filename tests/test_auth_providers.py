@@ -20,6 +20,7 @@ from utils.auth.providers import (
     authenticate_acr,
     authenticate_ecr,
     get_credentials_from_k8s_secret,
+    get_profile_credentials,
 )
 
 
@@ -255,6 +256,37 @@ class TestAuthenticateECR:
                 with pytest.raises(subprocess.CalledProcessError):
                     authenticate_ecr("123456789.dkr.ecr.us-west-2.amazonaws.com", "/tmp/auth.json")
 
+    def test_assumes_role_for_token(self):
+        """Test that with a role ARN the ECR token is requested with the role's credentials"""
+        mock_sts_client = MagicMock()
+        mock_sts_client.assume_role.return_value = {
+            "Credentials": {"AccessKeyId": "AKID", "SecretAccessKey": "SECRET", "SessionToken": "SESSION"}
+        }
+        mock_ecr_client = MagicMock()
+        mock_ecr_client.get_authorization_token.return_value = {
+            "authorizationData": [{"authorizationToken": base64.b64encode(b"AWS:mytoken").decode()}]
+        }
+        role_arn = "arn:aws:iam::210987654321:role/registry-cleaner"
+
+        with patch("boto3.client", side_effect=[mock_sts_client, mock_ecr_client]) as mock_boto3:
+            with patch("subprocess.run"):
+                authenticate_ecr(
+                    "210987654321.dkr.ecr.eu-central-1.amazonaws.com", "/tmp/auth.json", role_arn, "ext-1"
+                )
+
+        mock_sts_client.assume_role.assert_called_once_with(
+            RoleArn=role_arn, RoleSessionName="docker-registry-cleaner", ExternalId="ext-1"
+        )
+        assert mock_boto3.call_args_list[1] == (
+            ("ecr",),
+            {
+                "region_name": "eu-central-1",
+                "aws_access_key_id": "AKID",
+                "aws_secret_access_key": "SECRET",
+                "aws_session_token": "SESSION",
+            },
+        )
+
 
 class TestAuthenticateACR:
     """Tests for authenticate_acr function"""
@@ -378,3 +410,45 @@ class TestAuthenticateACR:
                         mock_subprocess.side_effect = subprocess.CalledProcessError(1, "skopeo", stderr="Login failed")
                         with pytest.raises(subprocess.CalledProcessError):
                             authenticate_acr("myregistry.azurecr.io", "/tmp/auth.json")
+
+
+class TestGetProfileCredentials:
+    """Tests for get_profile_credentials function"""
+
+    def test_ecr_profile_logs_in_with_role(self):
+        """Test that an ECR profile logs in with its role and returns no password"""
+        profile = {"method": "ecr", "role_arn": "arn:aws:iam::210987654321:role/cleaner", "region": "us-east-2"}
+
+        with patch("utils.auth.providers.authenticate_ecr") as mock_ecr:
+            credentials = get_profile_credentials(profile, "ecr.example.com", "domino-platform", "/tmp/auth.json")
+
+        assert credentials == ("AWS", None)
+        mock_ecr.assert_called_once_with(
+            "ecr.example.com",
+            "/tmp/auth.json",
+            role_arn="arn:aws:iam::210987654321:role/cleaner",
+            external_id=None,
+            region="us-east-2",
+            native=False,
+        )
+
+    def test_secret_and_env_profiles(self):
+        """Test that secret and env profiles read their credentials, and fail without them"""
+        profile = {"method": "secret", "secret": "harbor-pull", "namespace": "registries"}
+        with patch("utils.auth.providers.get_credentials_from_k8s_secret", return_value=("robot", "pw")) as mock_secret:
+            credentials = get_profile_credentials(profile, "harbor.example.com", "domino-platform", "/tmp/a")
+        assert credentials == ("robot", "pw")
+        mock_secret.assert_called_once_with("harbor-pull", "registries", "harbor.example.com")
+
+        with patch("utils.auth.providers.get_credentials_from_k8s_secret", return_value=(None, None)):
+            with pytest.raises(RuntimeError, match="harbor-pull"):
+                get_profile_credentials(profile, "harbor.example.com", "domino-platform", "/tmp/a")
+
+        profile = {"method": "env", "username_env": "QUAY_USER", "password_env": "QUAY_TOKEN"}
+        with patch.dict(os.environ, {"QUAY_USER": "bot", "QUAY_TOKEN": "tok"}):
+            assert get_profile_credentials(profile, "quay.io", "domino-platform", "/tmp/a") == ("bot", "tok")
+        with patch.dict(os.environ, {"QUAY_USER": "bot"}, clear=True):
+            with pytest.raises(RuntimeError, match="QUAY_TOKEN"):
+                get_profile_credentials(profile, "quay.io", "domino-platform", "/tmp/a")
+
+        assert get_profile_credentials({"method": "anonymous"}, "public.example.com", "ns", "/tmp/a") == (None, None)
@@ -0,0 +1,96 @@
+"""Unit tests for blob_mount.py"""
+
+import json
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.blob_mount import mount_image, same_registry_prefix
+
+IMAGE_MANIFEST = {
+    "schemaVersion": 2,
+    "mediaType": "application/vnd.docker.distribution.manifest.v2+json",
+    "config": {"digest": "sha256:config", "size": 100},
+    "layers": [
+        {"digest": "sha256:base", "size": 1000},
+        {"digest": "sha256:top", "size": 500},
+        {"digest": "sha256:foreign", "size": 9000, "urls": ["https://example.com/layer"]},
+    ],
+}
+
+
+class TestSameRegistryPrefix:
+    """Tests for recognising destinations on the registry's own host"""
+
+    def test_prefix(self):
+        """Test that only a destination with a path on the same host gets a prefix"""
+        assert same_registry_prefix("registry:5000", "registry:5000/archive") == "archive"
+        assert same_registry_prefix("https://Registry:5000", "registry:5000/archive/old/") == "archive/old"
+        assert same_registry_prefix("registry:5000", "registry:5000") is None
+        assert same_registry_prefix("registry:5000", "archive.example.com/archive") is None
+
+
+class TestMountImage:
+    """Tests for copying images by mounting their blobs"""
+
+    def setup_method(self):
+        """Set up a registry client holding one image manifest"""
+        self.http_client = MagicMock()
+        content = json.dumps(IMAGE_MANIFEST).encode("utf-8")
+        self.http_client.get_manifest.return_value = (content, IMAGE_MANIFEST["mediaType"])
+        # The destination already has the base layer
+        sizes = {"sha256:base": 1000}
+        self.http_client.head_blob_size.side_effect = lambda repository, digest: sizes.get(digest)
+        self.http_client.mount_blob.return_value = True
+
+    def test_mounts_missing_blobs_and_pushes_manifest(self):
+        """Test that blobs the destination lacks are mounted, foreign layers skipped, and the manifest pushed"""
+        stats, complete = mount_image(self.http_client, "env", "sha256:image", "archive/env", "v1")
+
+        assert complete
+        assert stats["mounted_blobs"] == 2
+        assert stats["mounted_bytes"] == 600
+        assert stats["existing_bytes"] == 1000
+        assert stats["transferred_bytes"] == 0
+        mounted = [call.args[1] for call in self.http_client.mount_blob.call_args_list]
+        assert mounted == ["sha256:config", "sha256:top"]
+        self.http_client.put_manifest.assert_called_once_with(
+            "archive/env", "v1", self.http_client.get_manifest.return_value[0], IMAGE_MANIFEST["mediaType"]
+        )
+
+    def test_refused_mount_is_incomplete(self):
+        """Test that a blob the registry will not mount leaves the copy to skopeo, pushing nothing"""
+        self.http_client.mount_blob.side_effect = lambda repository, digest, source: digest != "sha256:top"
+
+        stats, complete = mount_image(self.http_client, "env", "sha256:image", "archive/env", "v1")
+
+        assert not complete
+        assert stats["transferred_blobs"] == 1
+        assert stats["transferred_bytes"] == 500
+        self.http_client.put_manifest.assert_not_called()
+
+    def test_index_children_pushed_by_digest(self):
+        """Test that each platform of a manifest list is pushed by digest before the list is tagged"""
+        index = {"schemaVersion": 2, "manifests": [{"digest": "sha256:amd64"}, {"digest": "sha256:arm64"}]}
+        manifests = {"sha256:index": json.dumps(index).encode("utf-8")}
+        image = self.http_client.get_manifest.return_value
+        self.http_client.get_manifest.side_effect = lambda repository, reference: (
+            (manifests[reference], "application/vnd.oci.image.index.v1+json") if reference in manifests else image
+        )
+
+        _, complete = mount_image(self.http_client, "env", "sha256:index", "archive/env", "v1")
+
+        assert complete
+        pushed = [call.args[1] for call in self.http_client.put_manifest.call_args_list]
+        assert pushed == ["sha256:amd64", "sha256:arm64", "v1"]
+
+    def test_missing_manifest(self):
+        """Test that a manifest missing from the source repository is an error"""
+        self.http_client.get_manifest.return_value = None
+
+        with pytest.raises(ValueError):
+            mount_image(self.http_client, "env", "sha256:gone", "archive/env", "v1")
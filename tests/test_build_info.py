@@ -0,0 +1,84 @@
+"""Unit tests for utils/build_info.py"""
+
+import os
+import subprocess
+import sys
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils import build_info
+from utils.build_info import format_build_info, get_build_info
+
+
+@pytest.fixture(autouse=True)
+def clear_cache():
+    """Detect build metadata afresh in every test"""
+    get_build_info.cache_clear()
+    yield
+    get_build_info.cache_clear()
+
+
+class TestGetBuildInfo:
+    """Tests for detecting version and build metadata"""
+
+    def test_embedded_metadata_and_skopeo_version(self, monkeypatch):
+        """Test that build-time environment variables win and the skopeo version is parsed"""
+        monkeypatch.setenv("GIT_COMMIT", "0123456789ab")
+        monkeypatch.setenv("BUILD_DATE", "2026-01-15T10:00:00Z")
+        result = MagicMock(stdout="skopeo version 1.22.0\n")
+
+        with patch("utils.build_info.subprocess.run", return_value=result) as run:
+            info = get_build_info()
+
+        assert info["commit"] == "0123456789ab"
+        assert info["build_date"] == "2026-01-15T10:00:00Z"
+        assert info["skopeo_version"] == "1.22.0"
+        assert info["version"]
+        run.assert_called_once()
+
+    def test_missing_skopeo(self, monkeypatch):
+        """Test that a missing skopeo binary is reported as None rather than an error"""
+        monkeypatch.setenv("GIT_COMMIT", "0123456789ab")
+        monkeypatch.delenv("BUILD_DATE", raising=False)
+
+        with patch("utils.build_info.subprocess.run", side_effect=FileNotFoundError("skopeo")):
+            info = get_build_info()
+
+        assert info["skopeo_version"] is None
+        assert info["build_date"] is None
+
+    def test_detected_once(self, monkeypatch):
+        """Test that skopeo is only run once per process"""
+        monkeypatch.setenv("GIT_COMMIT", "0123456789ab")
+
+        with patch("utils.build_info.subprocess.run", side_effect=subprocess.TimeoutExpired("skopeo", 5)) as run:
+            get_build_info()
+            get_build_info()
+
+        assert run.call_count == 1
+
+    def test_version_from_pyproject_without_installed_package(self):
+        """Test that a source checkout falls back to pyproject.toml"""
+        from importlib.metadata import PackageNotFoundError
+
+        with patch("importlib.metadata.version", side_effect=PackageNotFoundError(build_info.DISTRIBUTION_NAME)):
+            assert build_info._package_version() != "unknown"
+
+
+class TestFormatBuildInfo:
+    """Tests for the one-line build description"""
+
+    def test_unknown_fields_are_omitted(self):
+        """Test that unknown commit and build date are left out and a missing skopeo is named"""
+        info = {"version": "1.2.3", "commit": None, "build_date": None, "skopeo_version": None}
+
+        assert format_build_info(info) == "docker-registry-cleaner 1.2.3, skopeo not found"
+
+    def test_all_fields(self):
+        """Test the full description"""
+        info = {"version": "1.2.3", "commit": "abc", "build_date": "2026-01-15", "skopeo_version": "1.22.0"}
+
+        assert format_build_info(info) == "docker-registry-cleaner 1.2.3, commit abc, built 2026-01-15, skopeo 1.22.0"
@@ -0,0 +1,66 @@
+"""Unit tests for utils/chargeback.py"""
+
+import csv
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.chargeback import BYTES_PER_GB, CSV_FIELDS, build_chargeback
+from utils.report_utils import save_csv
+
+
+def usage(owner, amortized_bytes, images=1, exclusive_bytes=0):
+    """Owner usage entry"""
+    return {
+        "owner": owner,
+        "images": images,
+        "total_bytes": amortized_bytes,
+        "exclusive_bytes": exclusive_bytes,
+        "shared_bytes": amortized_bytes - exclusive_bytes,
+        "amortized_bytes": amortized_bytes,
+    }
+
+
+class TestBuildChargeback:
+    """Tests for billing owners for their storage"""
+
+    def test_cost_without_previous_scan(self):
+        """Test that owners are billed for amortized bytes, largest first, with growth left empty"""
+        charges = build_chargeback(
+            [
+                usage("data-science", BYTES_PER_GB),
+                usage("forecasting", 10 * BYTES_PER_GB, exclusive_bytes=BYTES_PER_GB),
+            ],
+            None,
+            0.5,
+        )
+
+        assert [charge["owner"] for charge in charges] == ["forecasting", "data-science"]
+        assert charges[0]["cost"] == 5.0
+        assert charges[0]["exclusive_bytes"] == BYTES_PER_GB
+        assert charges[1]["cost"] == 0.5
+        assert all(charge["growth_bytes"] is None and charge["previous_amortized_bytes"] is None for charge in charges)
+
+    def test_growth_and_csv_export(self, tmp_path):
+        """Test growth per owner, including new owners and owners whose images are gone, and the CSV rows"""
+        charges = build_chargeback(
+            [usage("data-science", 300), usage("new-team", 50)],
+            [usage("data-science", 200), usage("retired-team", 100)],
+            0.023,
+        )
+        by_owner = {charge["owner"]: charge for charge in charges}
+
+        assert by_owner["data-science"]["growth_bytes"] == 100
+        assert by_owner["data-science"]["growth_percent"] == 50.0
+        assert by_owner["new-team"]["previous_amortized_bytes"] == 0
+        assert by_owner["new-team"]["growth_percent"] is None
+        assert by_owner["retired-team"]["amortized_bytes"] == 0
+        assert by_owner["retired-team"]["growth_bytes"] == -100
+
+        path = save_csv(str(tmp_path / "chargeback.csv"), charges, CSV_FIELDS)
+        with open(path, newline="") as f:
+            rows = list(csv.DictReader(f))
+        assert list(rows[0]) == list(CSV_FIELDS)
+        assert rows[0]["owner"] == "data-science" and rows[0]["growth_bytes"] == "100"
+        assert next(row for row in rows if row["owner"] == "new-team")["growth_percent"] == ""
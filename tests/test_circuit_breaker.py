@@ -0,0 +1,98 @@
+"""Unit tests for utils/circuit_breaker.py"""
+
+import os
+import sys
+import threading
+import time
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.circuit_breaker import CLOSED, HALF_OPEN, OPEN, CircuitBreaker, is_overload_message
+
+
+class FakeClock:
+    """Monotonic clock advanced by hand"""
+
+    def __init__(self):
+        self.now = 1000.0
+
+    def __call__(self) -> float:
+        return self.now
+
+
+class TestCircuitBreaker:
+    """Tests for opening, probing and closing the breaker"""
+
+    def setup_method(self):
+        """Create a breaker with a hand-driven clock"""
+        self.clock = FakeClock()
+        self.breaker = CircuitBreaker("registry:5000", failure_threshold=3, cooldown=10, max_cooldown=25, clock=self.clock)
+
+    def test_opens_after_consecutive_failures(self):
+        """Test that only an unbroken run of failures opens the breaker"""
+        self.breaker.record_failure()
+        self.breaker.record_failure()
+        self.breaker.record_success()
+        self.breaker.record_failure()
+        self.breaker.record_failure()
+        assert self.breaker.state == CLOSED
+
+        self.breaker.record_failure()
+
+        assert self.breaker.state == OPEN
+        assert self.breaker.times_opened == 1
+
+    def test_failed_probes_back_off_and_success_closes(self):
+        """Test that each failed probe doubles the cooldown up to the maximum, and a good probe resets it"""
+        for _ in range(3):
+            self.breaker.record_failure()
+
+        self.clock.now += 10
+        self.breaker.before_request()
+        assert self.breaker.state == HALF_OPEN
+        self.breaker.record_failure()
+        assert (self.breaker.state, self.breaker.cooldown) == (OPEN, 20)
+
+        self.clock.now += 20
+        self.breaker.before_request()
+        self.breaker.record_failure()
+        assert self.breaker.cooldown == 25
+
+        self.clock.now += 25
+        self.breaker.before_request()
+        self.breaker.record_success()
+        assert (self.breaker.state, self.breaker.cooldown, self.breaker.consecutive_failures) == (CLOSED, 10, 0)
+
+    def test_requests_pause_while_open_and_resume_together(self):
+        """Test that waiting requests are held until the probe succeeds"""
+        breaker = CircuitBreaker("registry:5000", failure_threshold=1, cooldown=0.05)
+        breaker.record_failure()
+        released = []
+
+        def request():
+            breaker.before_request()
+            released.append(time.monotonic())
+            if breaker.state == HALF_OPEN:
+                breaker.record_success()
+
+        started = time.monotonic()
+        threads = [threading.Thread(target=request) for _ in range(4)]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join(timeout=5)
+
+        assert len(released) == 4
+        assert min(released) - started >= 0.04
+        assert breaker.state == CLOSED
+
+
+class TestIsOverloadMessage:
+    """Tests for recognizing overload errors in skopeo output"""
+
+    def test_messages(self):
+        """Test 5xx and 429 errors against other failures"""
+        assert is_overload_message("reading manifest v1: received unexpected HTTP status: 503 Service Unavailable")
+        assert is_overload_message("toomanyrequests: Too Many Requests")
+        assert not is_overload_message("manifest unknown: manifest unknown")
+        assert not is_overload_message("dial tcp docker-registry:5000: connect: connection refused")
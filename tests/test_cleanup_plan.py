@@ -0,0 +1,249 @@
+"""Unit tests for cleanup_plan.py"""
+
+import json
+import os
+import sys
+import tempfile
+from unittest.mock import MagicMock
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cleanup_plan import (
+    PLAN_FORMAT_VERSION,
+    CleanupPlan,
+    PlanFormatError,
+    PlanItem,
+    PolicyProvenance,
+    apply_order,
+    check_item_digest,
+    load_plan,
+    replicate_item,
+    save_plan,
+)
+from utils.blob_mount import empty_copy_stats
+
+
+def _make_plan() -> CleanupPlan:
+    """Create a plan with a single item"""
+    return CleanupPlan(
+        registry_url="registry.example.com",
+        repository="dominodatalab",
+        policy=PolicyProvenance(source="unused_images", description="unused", options={"unused_since_days": 30}),
+        items=[
+            PlanItem(
+                image_id="environment:abc-1",
+                repository="dominodatalab/environment",
+                tag="abc-1",
+                digest="sha256:aaa",
+                size_bytes=6000,
+                expected_freed_bytes=1000,
+                reason="not in use",
+            )
+        ],
+        expected_freed_bytes=1000,
+    )
+
+
+class TestCleanupPlanRoundTrip:
+    """Tests for saving and loading plan files"""
+
+    def test_round_trip(self):
+        """Test that a saved plan loads back unchanged"""
+        plan = _make_plan()
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = save_plan(plan, os.path.join(tmpdir, "plan.json"))
+            loaded = load_plan(path)
+
+        assert loaded == plan
+
+    def test_saved_plan_has_version_and_summary(self):
+        """Test that the saved document carries the format version and a summary"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = save_plan(_make_plan(), os.path.join(tmpdir, "plan.json"))
+            with open(path) as f:
+                data = json.load(f)
+
+        assert data["format_version"] == PLAN_FORMAT_VERSION
+        assert data["summary"]["total_items"] == 1
+        assert data["summary"]["expected_freed_bytes"] == 1000
+        assert data["policy"]["source"] == "unused_images"
+
+    def test_timestamped_save(self):
+        """Test that timestamp=True adds a timestamp to the filename"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = save_plan(_make_plan(), os.path.join(tmpdir, "plan.json"), timestamp=True)
+
+            assert os.path.basename(path) != "plan.json"
+            assert os.path.exists(path)
+
+
+class TestCleanupPlanValidation:
+    """Tests for rejecting malformed plan files"""
+
+    def test_unsupported_version(self):
+        """Test that plans with an unknown format_version are rejected"""
+        data = _make_plan().to_dict()
+        data["format_version"] = PLAN_FORMAT_VERSION + 1
+
+        with pytest.raises(PlanFormatError, match="format_version"):
+            CleanupPlan.from_dict(data)
+
+    def test_missing_field(self):
+        """Test that plans missing required fields are rejected"""
+        data = _make_plan().to_dict()
+        del data["items"]
+
+        with pytest.raises(PlanFormatError, match="items"):
+            CleanupPlan.from_dict(data)
+
+    def test_item_without_digest(self):
+        """Test that items without a recorded digest are rejected"""
+        data = _make_plan().to_dict()
+        data["items"][0]["digest"] = ""
+
+        with pytest.raises(PlanFormatError, match="no digest"):
+            CleanupPlan.from_dict(data)
+
+    def test_unknown_item_field(self):
+        """Test that items with unknown fields are rejected"""
+        data = _make_plan().to_dict()
+        data["items"][0]["unexpected"] = True
+
+        with pytest.raises(PlanFormatError, match="item 0"):
+            CleanupPlan.from_dict(data)
+
+    def test_invalid_json(self):
+        """Test that non-JSON files are rejected"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = os.path.join(tmpdir, "plan.json")
+            with open(path, "w") as f:
+                f.write("not json")
+
+            with pytest.raises(PlanFormatError, match="not valid JSON"):
+                load_plan(path)
+
+
+class TestCheckItemDigest:
+    """Tests for verifying plan items against the registry at apply time"""
+
+    def test_matching_digest(self):
+        """Test that an unchanged tag passes verification"""
+        item = _make_plan().items[0]
+
+        assert check_item_digest(item, "sha256:aaa") is None
+
+    def test_repushed_tag(self):
+        """Test that a tag pointing to a different digest is rejected"""
+        item = _make_plan().items[0]
+
+        reason = check_item_digest(item, "sha256:bbb")
+
+        assert reason is not None
+        assert "re-pushed" in reason
+        assert "sha256:bbb" in reason
+
+    def test_missing_tag(self):
+        """Test that a tag that no longer exists is rejected"""
+        item = _make_plan().items[0]
+
+        assert check_item_digest(item, None) == "tag no longer exists in registry"
+
+
+class TestApplyOrder:
+    """Tests for grouping plan items into dependency-ordered units"""
+
+    @staticmethod
+    def _item(tag: str, digest: str, subject_digest: str = "") -> PlanItem:
+        """Plan item of an environment tag"""
+        return PlanItem(
+            image_id=f"environment:{tag}",
+            repository="dominodatalab/environment",
+            tag=tag,
+            digest=digest,
+            subject_digest=subject_digest,
+        )
+
+    def test_aliases_grouped_with_referrers_first(self):
+        """Test that aliases form one unit in plan order, after the artifacts referring to them"""
+        signature_tag = f"sha256-{'a' * 64}.sig"
+        items = [
+            self._item("b", "sha256:bbb"),
+            self._item("a", f"sha256:{'a' * 64}"),
+            self._item("b-alias", "sha256:bbb"),
+            self._item(signature_tag, "sha256:sig"),
+            self._item("attestation", "sha256:att", subject_digest="sha256:bbb"),
+        ]
+
+        units = apply_order(items)
+
+        assert [[item.tag for item in unit.items] for unit in units] == [
+            ["attestation", "b", "b-alias"],
+            [signature_tag, "a"],
+        ]
+        assert [item.tag for item in units[0].subjects] == ["b", "b-alias"]
+
+    def test_referrer_without_planned_subject(self):
+        """Test that an artifact whose image is not in the plan is applied on its own"""
+        attestation_tag = f"sha256-{'f' * 64}.att"
+        units = apply_order([self._item(attestation_tag, "sha256:att"), self._item("a", "sha256:aaa")])
+
+        assert [[item.tag for item in unit.subjects] for unit in units] == [[attestation_tag], ["a"]]
+        assert all(not unit.referrers for unit in units)
+
+
+class TestReplicateItem:
+    """Tests for copying plan items to an archive registry before deletion"""
+
+    def setup_method(self):
+        """Set up an item marked for replication and clients of both registries"""
+        self.item = _make_plan().items[0]
+        self.item.replicate_to = "archive.example.com"
+        self.source = MagicMock()
+        self.source.registry_url = "registry.example.com"
+        self.archive = MagicMock()
+
+    def test_verified_copy(self):
+        """Test that a copy whose digest matches the plan allows deletion"""
+        self.source.copy_image.return_value = True
+        self.archive.get_manifest_digest.return_value = "sha256:aaa"
+
+        assert replicate_item(self.item, self.source, self.archive) is None
+        self.source.copy_image.assert_called_once_with(
+            "dominodatalab/environment", "sha256:aaa", "archive.example.com", "abc-1"
+        )
+
+    def test_failed_or_unverified_copy(self):
+        """Test that a failed copy, or one with a different or unreadable digest, prevents deletion"""
+        self.source.copy_image.return_value = False
+        assert replicate_item(self.item, self.source, self.archive) == "copy to archive.example.com failed"
+
+        self.source.copy_image.return_value = True
+        for archived_digest in ("sha256:bbb", None):
+            self.archive.get_manifest_digest.return_value = archived_digest
+            assert "could not be verified" in replicate_item(self.item, self.source, self.archive)
+
+        self.archive.get_manifest_digest.side_effect = RuntimeError("connection refused")
+        assert "connection refused" in replicate_item(self.item, self.source, self.archive)
+
+    def test_copy_within_registry(self):
+        """Test that an archive on the same host is copied to by mounting blobs, adding up the bytes mounted"""
+        self.item.replicate_to = "registry.example.com/archive"
+        self.source.copy_image_within_registry.return_value = {
+            "mounted_blobs": 2,
+            "mounted_bytes": 600,
+            "existing_blobs": 1,
+            "existing_bytes": 1000,
+            "transferred_blobs": 0,
+            "transferred_bytes": 0,
+        }
+        self.archive.get_manifest_digest.return_value = "sha256:aaa"
+        copy_stats = empty_copy_stats()
+
+        assert replicate_item(self.item, self.source, self.archive, copy_stats) is None
+        self.source.copy_image_within_registry.assert_called_once_with(
+            "dominodatalab/environment", "sha256:aaa", "archive/dominodatalab/environment", "abc-1"
+        )
+        self.source.copy_image.assert_not_called()
+        assert copy_stats["mounted_bytes"] == 600
@@ -128,6 +128,395 @@ class TestConfigManagerGetters:
         assert config_manager.get_timeout() == 300
         assert isinstance(config_manager.get_timeout(), int)
 
+    def test_get_excluded_tag_patterns_defaults(self, config_manager):
+        """Test that cosign/ORAS tag patterns are excluded by default"""
+        assert config_manager.get_excluded_tag_patterns() == ["sha256-*.sig", "*.att", "*.sbom"]
+
+    def test_get_excluded_tag_patterns_extends_defaults(self, config_manager):
+        """Test that user patterns are added to the built-in defaults"""
+        config_manager.config["analysis"]["exclude_tags"] = ["*.cache", "*.sbom"]
+
+        assert config_manager.get_excluded_tag_patterns() == ["sha256-*.sig", "*.att", "*.sbom", "*.cache"]
+
+    def test_get_candidate_score_weights_overrides_defaults(self, config_manager):
+        """Test that configured weights override the defaults factor by factor"""
+        config_manager.config["analysis"]["candidate_weights"] = {"age": 3, "recency": "0.5"}
+
+        weights = config_manager.get_candidate_score_weights()
+
+        assert weights["age"] == 3.0
+        assert weights["recency"] == 0.5
+        assert weights["exclusive_size"] == 1.0
+
+    def test_get_candidate_score_weights_rejects_unknown_and_negative(self, config_manager):
+        """Test that unknown factors and negative weights are configuration errors"""
+        from utils.config_manager import ConfigValidationError
+
+        config_manager.config["analysis"]["candidate_weights"] = {"cves": 1.0}
+        with pytest.raises(ConfigValidationError, match="Unknown"):
+            config_manager.get_candidate_score_weights()
+
+        config_manager.config["analysis"]["candidate_weights"] = {"age": -1}
+        with pytest.raises(ConfigValidationError, match="must not be negative"):
+            config_manager.get_candidate_score_weights()
+
+    def test_get_pull_link_speed_mbps(self, config_manager):
+        """Test the pull link speed default and that it must be a positive number"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_pull_link_speed_mbps() == 1000.0
+
+        config_manager.config["analysis"]["pull_link_speed_mbps"] = 0
+        with pytest.raises(ConfigValidationError, match="greater than 0"):
+            config_manager.get_pull_link_speed_mbps()
+
+    def test_get_owner_label_keys(self, config_manager):
+        """Test the owner label default and that it must be a non-empty list of keys"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_owner_label_keys() == ["owner", "team"]
+
+        config_manager.config["analysis"]["owner_labels"] = "owner"
+        with pytest.raises(ConfigValidationError, match="owner_labels"):
+            config_manager.get_owner_label_keys()
+
+    def test_content_trust_settings(self, config_manager):
+        """Test that signatures are not checked by default and on_signed_tag is validated"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_notary_url() is None
+        assert config_manager.get_signed_tag_action() == "refuse"
+
+        config_manager.config["security"]["content_trust"]["on_signed_tag"] = "ignore"
+        with pytest.raises(ConfigValidationError, match="on_signed_tag"):
+            config_manager.get_signed_tag_action()
+
+    def test_get_deletion_delay_hours(self, config_manager):
+        """Test that deletions are not delayed by default and the delay is validated"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_deletion_delay_hours() == 0
+        config_manager.config["security"]["deletion_delay_hours"] = "24"
+        assert config_manager.get_deletion_delay_hours() == 24.0
+
+        config_manager.config["security"]["deletion_delay_hours"] = -1
+        with pytest.raises(ConfigValidationError, match="deletion_delay_hours"):
+            config_manager.get_deletion_delay_hours()
+
+    def test_get_credential_profile(self, config_manager):
+        """Test that profiles are matched by registry host and validated"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_credential_profile("registry.example.com") is None
+        config_manager.config["credentials"] = {
+            "210987654321.dkr.ecr.us-west-2.amazonaws.com": {
+                "method": "ecr",
+                "role_arn": "arn:aws:iam::210987654321:role/registry-cleaner",
+            },
+            "harbor.example.com": {"method": "secret", "secret": "harbor-pull"},
+        }
+        profile = config_manager.get_credential_profile("https://210987654321.dkr.ecr.us-west-2.amazonaws.com/")
+        assert profile == {"method": "ecr", "role_arn": "arn:aws:iam::210987654321:role/registry-cleaner"}
+        assert config_manager.get_credential_profile("harbor.example.com:8443")["secret"] == "harbor-pull"
+
+        config_manager.config["credentials"]["harbor.example.com"] = {"method": "env", "username_env": "USER"}
+        with pytest.raises(ConfigValidationError, match="password_env"):
+            config_manager.get_credential_profile("harbor.example.com")
+        config_manager.config["credentials"]["harbor.example.com"] = {"method": "ecr", "role_arn": "cleaner"}
+        with pytest.raises(ConfigValidationError, match="role_arn"):
+            config_manager.get_credential_profile("harbor.example.com")
+        config_manager.config["credentials"]["harbor.example.com"] = {"method": "basic", "password": "x"}
+        with pytest.raises(ConfigValidationError, match="method"):
+            config_manager.get_credential_profile("harbor.example.com")
+
+    def test_get_host_concurrency_limit(self, config_manager):
+        """Test that hosts are unlimited by default and per-host limits override the default"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_host_concurrency_limit("registry.example.com") == 0
+        config_manager.config["skopeo"]["host_limits"] = {
+            "max_concurrent_requests": 16,
+            "hosts": {"Harbor.example.com": 4, "registry.example.com:5000": 2},
+        }
+        assert config_manager.get_host_concurrency_limit("harbor.example.com:443") == 4
+        assert config_manager.get_host_concurrency_limit("registry.example.com:5000") == 2
+        assert config_manager.get_host_concurrency_limit("registry.example.com") == 16
+
+        config_manager.config["skopeo"]["host_limits"]["hosts"]["harbor.example.com"] = -1
+        with pytest.raises(ConfigValidationError, match="hosts.harbor.example.com"):
+            config_manager.get_host_concurrency_limit("harbor.example.com")
+
+    def test_get_schedules(self, config_manager):
+        """Test that phases without a cron expression are not scheduled and schedules are validated"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_schedules() == {}
+
+        config_manager.config["schedule"] = {
+            "scan": "0 2 * * *",
+            "plan": {"cron": "0 4 * * *", "args": ["--unused-since-days", 30]},
+            "apply": "",
+        }
+        schedules = config_manager.get_schedules()
+        assert list(schedules) == ["scan", "plan"]
+        assert str(schedules["scan"]["cron"]) == "0 2 * * *"
+        assert schedules["plan"]["args"] == ["--unused-since-days", "30"]
+
+        for schedule, match in (
+            ({"scan": "0 2 * *"}, "schedule.scan"),
+            ({"apply": {"cron": "@weekly", "args": ["--allow-signed"]}}, "schedule.apply"),
+            ({"cleanup": "@daily"}, "Unknown schedule phase"),
+        ):
+            config_manager.config["schedule"] = schedule
+            with pytest.raises(ConfigValidationError, match=match):
+                config_manager.get_schedules()
+
+    def test_get_alerting(self, config_manager, monkeypatch):
+        """Test that alerting is off without keys and its thresholds are validated"""
+        from utils.config_manager import ConfigValidationError
+
+        monkeypatch.delenv("PAGERDUTY_ROUTING_KEY", raising=False)
+        monkeypatch.delenv("OPSGENIE_API_KEY", raising=False)
+        assert config_manager.get_alert_destinations() == {}
+        assert config_manager.get_alert_thresholds() == {
+            "on_failure": True,
+            "max_error_rate": 0.0,
+            "max_reclaimable_gb": 0.0,
+        }
+
+        monkeypatch.setenv("OPSGENIE_API_KEY", "genie")
+        config_manager.config["alerting"]["pagerduty"]["routing_key"] = "pd-key"
+        config_manager.config["alerting"]["opsgenie"]["api_url"] = "https://api.eu.opsgenie.com/"
+        assert config_manager.get_alert_destinations() == {
+            "pagerduty": {"routing_key": "pd-key"},
+            "opsgenie": {"api_key": "genie", "api_url": "https://api.eu.opsgenie.com"},
+        }
+
+        config_manager.config["alerting"]["max_error_rate"] = 5
+        with pytest.raises(ConfigValidationError, match="max_error_rate"):
+            config_manager.get_alert_thresholds()
+
+    def test_get_statsd_settings(self, config_manager, monkeypatch):
+        """Test that StatsD is off without a host and that the port and tags are validated"""
+        from utils.config_manager import ConfigValidationError
+
+        monkeypatch.delenv("STATSD_HOST", raising=False)
+        monkeypatch.delenv("STATSD_PORT", raising=False)
+        assert config_manager.get_statsd_settings() is None
+
+        config_manager.config["metrics"]["statsd"].update({"host": "10.0.0.12", "tags": {"env": "prod"}})
+        monkeypatch.setenv("STATSD_PORT", "9125")
+        assert config_manager.get_statsd_settings() == {
+            "host": "10.0.0.12",
+            "port": 9125,
+            "prefix": "registry_cleaner",
+            "dogstatsd": True,
+            "tags": {"env": "prod"},
+        }
+
+        monkeypatch.setenv("STATSD_PORT", "statsd")
+        with pytest.raises(ConfigValidationError, match="metrics.statsd.port"):
+            config_manager.get_statsd_settings()
+
+    def test_get_snapshot_retention(self, config_manager):
+        """Test that snapshot retention keeps everything by default and rejects negative counts"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_snapshot_retention() == {"keep_last": 0, "keep_weekly": 0, "keep_monthly": 0}
+
+        config_manager.config["reports"]["snapshot_retention"] = {"keep_last": 7, "keep_monthly": 12}
+        assert config_manager.get_snapshot_retention() == {"keep_last": 7, "keep_weekly": 0, "keep_monthly": 12}
+
+        config_manager.config["reports"]["snapshot_retention"] = {"keep_weekly": -1}
+        with pytest.raises(ConfigValidationError, match="keep_weekly"):
+            config_manager.get_snapshot_retention()
+
+    def test_get_tag_naming_pattern(self, config_manager):
+        """Test that the naming convention defaults to Domino's tag scheme and must be a valid regex"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_tag_naming_pattern().fullmatch("507f1f77bcf86cd799439011-3")
+
+        config_manager.config["analysis"]["tag_naming_pattern"] = "v[0-9"
+        with pytest.raises(ConfigValidationError, match="tag_naming_pattern"):
+            config_manager.get_tag_naming_pattern()
+
+    def test_get_floating_tag_patterns(self, config_manager):
+        """Test that configured floating tags extend the built-in names and must be a list of strings"""
+        from utils.config_manager import ConfigValidationError
+
+        assert "latest" in config_manager.get_floating_tag_patterns()
+
+        config_manager.config["analysis"]["floating_tags"] = ["release-*", "latest"]
+        patterns = config_manager.get_floating_tag_patterns()
+        assert patterns[-1] == "release-*" and patterns.count("latest") == 1
+
+        config_manager.config["analysis"]["floating_tags"] = "release-*"
+        with pytest.raises(ConfigValidationError, match="floating_tags"):
+            config_manager.get_floating_tag_patterns()
+
+    def test_get_reference_scan_fields(self, config_manager):
+        """Test that the reference scan is off by default and that configured fields are checked"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_reference_scan_fields() is None
+
+        extra = {"collection": "launchers", "field": "environmentId", "kind": "environment_id"}
+        config_manager.config["mongo"]["reference_scan"] = {"enabled": True, "fields": [extra]}
+        fields = config_manager.get_reference_scan_fields()
+        assert fields[0]["field"] == "activeRevisionId" and fields[-1] == extra
+
+        config_manager.config["mongo"]["reference_scan"]["fields"] = [dict(extra, kind="image")]
+        with pytest.raises(ConfigValidationError, match="kind"):
+            config_manager.get_reference_scan_fields()
+
+    def test_get_recent_run_protection_days(self, config_manager):
+        """Test that recent run protection is off by default and must be a non-negative integer"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_recent_run_protection_days() == 0
+
+        config_manager.config["analysis"]["recent_run_protection_days"] = 30
+        assert config_manager.get_recent_run_protection_days() == 30
+
+        config_manager.config["analysis"]["recent_run_protection_days"] = -1
+        with pytest.raises(ConfigValidationError, match="recent_run_protection_days"):
+            config_manager.get_recent_run_protection_days()
+
+    def test_get_storage_cost_per_gb_month(self, config_manager):
+        """Test the default storage price and that negative prices are rejected"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_storage_cost_per_gb_month() == 0.023
+
+        config_manager.config["analysis"]["storage_cost_per_gb_month"] = -1
+        with pytest.raises(ConfigValidationError, match="storage_cost_per_gb_month"):
+            config_manager.get_storage_cost_per_gb_month()
+
+    def test_get_owner_quotas(self, config_manager):
+        """Test that quotas are off by default, converted to bytes, and that negative quotas are rejected"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_owner_quotas() is None
+
+        config_manager.config["quotas"] = {"default_gb": 0, "owners": {"data-science": 1.5}}
+        assert config_manager.get_owner_quotas() == {
+            "default_bytes": 0,
+            "owners": {"data-science": 3 * 1024**3 // 2},
+            "prioritize_plans": False,
+        }
+
+        config_manager.config["quotas"]["owners"]["forecasting"] = -1
+        with pytest.raises(ConfigValidationError, match="quotas.owners.forecasting"):
+            config_manager.get_owner_quotas()
+
+    def test_get_oversized_layer_policy(self, config_manager):
+        """Test the default 5 GB warning threshold, that 0 turns it off, and that fail_run must be a boolean"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_oversized_layer_policy() == {"threshold_bytes": 5 * 1024**3, "fail_run": False}
+
+        config_manager.config["analysis"]["oversized_layers"] = {"threshold_gb": 0}
+        assert config_manager.get_oversized_layer_policy() is None
+
+        config_manager.config["analysis"]["oversized_layers"] = {"threshold_gb": 2, "fail_run": "yes"}
+        with pytest.raises(ConfigValidationError, match="fail_run"):
+            config_manager.get_oversized_layer_policy()
+
+    def test_get_toolchain_policy(self, config_manager):
+        """Test the default toolchain policy and that invalid versions are rejected"""
+        from utils.config_manager import ConfigValidationError
+
+        policy = config_manager.get_toolchain_policy()
+        assert policy["min_docker_version"] == "18.09"
+        assert "centos:7*" in policy["outdated_base_images"]
+
+        config_manager.config["analysis"]["toolchain"] = {"min_docker_version": "latest"}
+        with pytest.raises(ConfigValidationError, match="min_docker_version"):
+            config_manager.get_toolchain_policy()
+
+    def test_get_registry_event_settings(self, config_manager):
+        """Test that the registry notification listener is off by default and validated when on"""
+        from utils.config_manager import ConfigValidationError
+
+        assert config_manager.get_registry_event_settings() is None
+
+        config_manager.config["registry_events"] = {"enabled": True, "snapshot_interval_minutes": 5}
+        assert config_manager.get_registry_event_settings() == {
+            "image_types": ["environment", "model"],
+            "snapshot_interval_seconds": 300,
+        }
+
+        config_manager.config["registry_events"]["image_types"] = "environment"
+        with pytest.raises(ConfigValidationError, match="image_types"):
+            config_manager.get_registry_event_settings()
+
+    def test_get_display_timezone(self, config_manager, monkeypatch):
+        """Test that timestamps are shown in UTC by default, the environment variable wins, and unknown zones fail"""
+        from utils.config_manager import ConfigValidationError
+
+        monkeypatch.delenv("REPORT_TIMEZONE", raising=False)
+        assert config_manager.get_display_timezone().key == "UTC"
+
+        monkeypatch.setenv("REPORT_TIMEZONE", "Europe/Berlin")
+        assert config_manager.get_display_timezone().key == "Europe/Berlin"
+
+        monkeypatch.setenv("REPORT_TIMEZONE", "Mars/Olympus_Mons")
+        with pytest.raises(ConfigValidationError, match="reports.timezone"):
+            config_manager.get_display_timezone()
+
+    def test_get_report_compression(self, config_manager, monkeypatch):
+        """Test that reports are uncompressed by default, the environment variable wins, and unknown values fail"""
+        from utils.config_manager import ConfigValidationError
+
+        monkeypatch.delenv("REPORT_COMPRESS", raising=False)
+        assert config_manager.get_report_compression() == ""
+
+        monkeypatch.setenv("REPORT_COMPRESS", "zstd")
+        assert config_manager.get_report_compression() == "zstd"
+
+        monkeypatch.setenv("REPORT_COMPRESS", "bzip2")
+        with pytest.raises(ConfigValidationError, match="reports.compress"):
+            config_manager.get_report_compression()
+
+    def test_get_redact_key_patterns(self, config_manager):
+        """Test the default redaction patterns and that invalid patterns are rejected"""
+        from utils.config_manager import ConfigValidationError
+
+        assert "*password*" in config_manager.get_redact_key_patterns()
+
+        config_manager.config["reports"]["redact_keys"] = []
+        assert config_manager.get_redact_key_patterns() == []
+
+        config_manager.config["reports"]["redact_keys"] = "*token*"
+        with pytest.raises(ConfigValidationError, match="redact_keys"):
+            config_manager.get_redact_key_patterns()
+
+    def test_get_anonymize_salt(self, config_manager, monkeypatch):
+        """Test that the anonymize salt is unset by default and that the environment variable takes precedence"""
+        monkeypatch.delenv("ANONYMIZE_SALT", raising=False)
+        assert config_manager.get_anonymize_salt() is None
+
+        config_manager.config["reports"]["anonymize_salt"] = "from-config"
+        assert config_manager.get_anonymize_salt() == "from-config"
+
+        monkeypatch.setenv("ANONYMIZE_SALT", "from-env")
+        assert config_manager.get_anonymize_salt() == "from-env"
+
+    def test_get_report_upload_url(self, config_manager, monkeypatch):
+        """Test that report uploads are off by default and that unsupported destinations are rejected"""
+        from utils.config_manager import ConfigValidationError
+
+        monkeypatch.delenv("REPORT_UPLOAD_URL", raising=False)
+        assert config_manager.get_report_upload_url() is None
+
+        monkeypatch.setenv("REPORT_UPLOAD_URL", "gs://reports/registry-cleaner")
+        assert config_manager.get_report_upload_url() == "gs://reports/registry-cleaner"
+
+        monkeypatch.setenv("REPORT_UPLOAD_URL", "ftp://reports.example.com/")
+        with pytest.raises(ConfigValidationError, match="reports.upload_url"):
+            config_manager.get_report_upload_url()
+
     def test_get_output_dir(self, config_manager):
         """Test get_output_dir returns correct value"""
         assert config_manager.get_output_dir() == "reports"
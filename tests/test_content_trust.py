@@ -0,0 +1,60 @@
+"""Unit tests for utils/content_trust.py"""
+
+import os
+import sys
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.content_trust import ContentTrustChecker, delegated_roles, target_names
+
+
+def _targets(tags, roles=()):
+    """Build a TUF targets metadata document"""
+    signed = {"targets": {tag: {"length": 1, "hashes": {}} for tag in tags}}
+    if roles:
+        signed["delegations"] = {"keys": {}, "roles": [{"name": role} for role in roles]}
+    return {"signed": signed, "signatures": []}
+
+
+class TestTrustMetadata:
+    """Tests for reading TUF targets metadata"""
+
+    def test_targets_and_delegations(self):
+        """Test that signed tags and delegated roles are read from a targets document"""
+        document = _targets(["1.0", "latest"], roles=["targets/releases"])
+
+        assert target_names(document) == {"1.0", "latest"}
+        assert delegated_roles(document) == {"targets/releases"}
+        assert target_names({"signed": {}}) == set()
+        assert delegated_roles(None) == set()
+
+
+class TestContentTrustChecker:
+    """Tests for looking up signed tags on a Notary server"""
+
+    def test_signed_tags_include_delegations(self):
+        """Test that tags signed through delegated roles count as signed, and lookups are cached"""
+        documents = {
+            "/v2/registry.example.com/dominodatalab/environment/_trust/tuf/targets.json": _targets(
+                ["abc-1"], roles=["targets/releases"]
+            ),
+            "/v2/registry.example.com/dominodatalab/environment/_trust/tuf/targets/releases.json": _targets(["abc-2"]),
+        }
+        checker = ContentTrustChecker("https://notary.example.com", "registry.example.com")
+
+        with patch.object(checker._client, "get_json", side_effect=lambda path, scope: documents[path]) as get_json:
+            assert checker.is_signed("dominodatalab/environment", "abc-1") is True
+            assert checker.is_signed("dominodatalab/environment", "abc-2") is True
+            assert checker.is_signed("dominodatalab/environment", "abc-3") is False
+            assert get_json.call_count == 2
+            assert get_json.call_args[0][1] == "repository:registry.example.com/dominodatalab/environment:pull"
+
+    def test_unsigned_and_unreachable(self):
+        """Test that repositories without trust data are unsigned, and unreadable trust data is unknown"""
+        checker = ContentTrustChecker("notary.example.com", "https://registry.example.com")
+
+        with patch.object(checker._client, "get_json", return_value=None):
+            assert checker.is_signed("dominodatalab/model", "v1") is False
+        with patch.object(checker._client, "get_json", side_effect=OSError("connection refused")):
+            assert checker.is_signed("dominodatalab/environment", "v1") is None
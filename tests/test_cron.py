@@ -0,0 +1,49 @@
+"""Unit tests for cron.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cron import CronExpression
+
+
+def _utc(*args) -> datetime:
+    return datetime(*args, tzinfo=timezone.utc)
+
+
+class TestCronExpression:
+    """Tests for CronExpression"""
+
+    def test_next_after(self):
+        """Test finding the next matching minute for common schedules"""
+        # Thursday 2026-01-01 12:30
+        now = _utc(2026, 1, 1, 12, 30)
+
+        assert CronExpression("0 2 * * *").next_after(now) == _utc(2026, 1, 2, 2, 0)
+        assert CronExpression("*/15 * * * *").next_after(now) == _utc(2026, 1, 1, 12, 45)
+        assert CronExpression("0 6 * * sat").next_after(now) == _utc(2026, 1, 3, 6, 0)
+        assert CronExpression("0 0 * * 7").next_after(now) == _utc(2026, 1, 4, 0, 0)
+        assert CronExpression("30 12 * * *").next_after(now) == _utc(2026, 1, 2, 12, 30)
+        assert CronExpression("@monthly").next_after(now) == _utc(2026, 2, 1, 0, 0)
+        assert CronExpression("0 0 29 feb *").next_after(now) == _utc(2028, 2, 29, 0, 0)
+        assert CronExpression("0 9-17/4 * * mon-fri").next_after(now) == _utc(2026, 1, 1, 13, 0)
+
+    def test_day_of_month_or_day_of_week(self):
+        """Test that a day matching either restricted day field matches, as in cron"""
+        cron = CronExpression("0 0 13 * 5")
+
+        assert cron.matches(_utc(2026, 1, 2, 0, 0))  # Friday the 2nd
+        assert cron.matches(_utc(2026, 1, 13, 0, 0))  # Tuesday the 13th
+        assert not cron.matches(_utc(2026, 1, 14, 0, 0))
+
+    def test_invalid_expressions(self):
+        """Test that malformed and never-matching expressions are rejected"""
+        for expression in ("0 2 * *", "60 * * * *", "0 2 * * funday", "*/0 * * * *", "5-1 * * * *"):
+            with pytest.raises(ValueError):
+                CronExpression(expression)
+        with pytest.raises(ValueError, match="never matches"):
+            CronExpression("0 0 31 2 *").next_after(_utc(2026, 1, 1))
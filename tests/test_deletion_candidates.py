@@ -0,0 +1,142 @@
+"""Unit tests for utils/deletion_candidates.py"""
+
+import os
+import sys
+from datetime import datetime, timedelta, timezone
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from tests.helpers import add_image, make_analyzer
+from utils.deletion_candidates import rank_candidates, savings_curve, weighted_score
+
+NOW = datetime(2025, 1, 1, tzinfo=timezone.utc)
+
+
+def _created(age_days: int) -> str:
+    """Creation time of an image age_days old"""
+    return (NOW - timedelta(days=age_days)).isoformat().replace("+00:00", "Z")
+
+
+class TestRankCandidates:
+    """Tests for ranking images as deletion candidates"""
+
+    def setup_method(self):
+        """Set up an old large image, a new small image and two images sharing a layer"""
+        self.analyzer = make_analyzer()
+        add_image(self.analyzer, "environment:old", [("old-a", 4000)], created=_created(400))
+        add_image(self.analyzer, "environment:new", [("new-a", 1000)], created=_created(1))
+        add_image(self.analyzer, "environment:s1", [("shared", 3000), ("s1-a", 500)], created=_created(200))
+        add_image(self.analyzer, "environment:s2", [("shared", 3000)], created=_created(200))
+
+    def test_ranked_by_age_and_size_without_usage(self):
+        """Test that old, large images rank first when no usage data is available"""
+        candidates, protected = rank_candidates(self.analyzer, now=NOW)
+
+        assert candidates[0]["image_id"] == "environment:old"
+        assert candidates[-1]["image_id"] == "environment:new"
+        assert [c["rank"] for c in candidates] == [1, 2, 3, 4]
+        assert candidates[0]["factors"]["frequency"] is None
+        assert protected == []
+
+    def test_cumulative_savings_count_layers_shared_between_candidates(self):
+        """Test that a shared layer is freed once every image holding it is ranked"""
+        candidates, _ = rank_candidates(self.analyzer, now=NOW)
+        by_id = {c["image_id"]: c for c in candidates}
+
+        assert by_id["environment:s1"]["estimated_savings_bytes"] == 500
+        assert by_id["environment:s2"]["estimated_savings_bytes"] == 0
+        assert candidates[-1]["cumulative_savings_bytes"] == 4000 + 1000 + 3000 + 500
+        assert [c["cumulative_savings_bytes"] for c in candidates] == sorted(
+            c["cumulative_savings_bytes"] for c in candidates
+        )
+
+    def test_usage_frequency_recency_and_protection(self):
+        """Test that used images rank lower and configuration references protect images"""
+        usage = {
+            "old": {"use_count": 10, "last_used": NOW - timedelta(days=2), "protected_by": []},
+            "s1": {"use_count": 1, "last_used": NOW - timedelta(days=100), "protected_by": ["workspaces"]},
+        }
+        candidates, protected = rank_candidates(self.analyzer, usage, now=NOW)
+        by_id = {c["image_id"]: c for c in candidates}
+
+        assert [p["image_id"] for p in protected] == ["environment:s1"]
+        assert protected[0]["protected_by"] == ["workspaces"]
+        assert "environment:s1" not in by_id
+        assert by_id["environment:s2"]["factors"]["recency"] == 1.0
+        assert by_id["environment:old"]["factors"]["frequency"] < by_id["environment:s2"]["factors"]["frequency"]
+        assert candidates[0]["image_id"] == "environment:s2"
+
+    def test_vulnerabilities_and_weights(self):
+        """Test that vulnerability counts are a factor only for scanned images and weights change the ranking"""
+        vulnerabilities = {"environment:new": 40, "environment:s2": 10}
+        candidates, _ = rank_candidates(
+            self.analyzer, weights={"vulnerabilities": 1.0}, now=NOW, vulnerabilities=vulnerabilities
+        )
+        by_id = {c["image_id"]: c for c in candidates}
+
+        assert candidates[0]["image_id"] == "environment:new"
+        assert by_id["environment:new"]["vulnerabilities"] == 40
+        assert by_id["environment:s2"]["factors"]["vulnerabilities"] == 0.25
+        assert by_id["environment:old"]["factors"]["vulnerabilities"] is None
+        assert by_id["environment:old"]["score"] == 0.0
+
+    def test_outdated_toolchain_ranked_higher(self):
+        """Test that an outdated toolchain raises an image's score and unknown toolchains leave it out"""
+        baseline = {c["image_id"]: c["score"] for c in rank_candidates(self.analyzer, now=NOW)[0]}
+        candidates, _ = rank_candidates(
+            self.analyzer, now=NOW, outdated_toolchain={"environment:new": True, "environment:old": False}
+        )
+        by_id = {c["image_id"]: c for c in candidates}
+
+        assert by_id["environment:new"]["factors"]["outdated_toolchain"] == 1.0
+        assert by_id["environment:new"]["score"] > baseline["environment:new"]
+        assert by_id["environment:old"]["score"] < baseline["environment:old"]
+        assert by_id["environment:s1"]["factors"]["outdated_toolchain"] is None
+        assert by_id["environment:s1"]["score"] == baseline["environment:s1"]
+
+    def test_expired_images_ranked_first_unless_protected(self):
+        """Test that expired images rank before higher-scoring ones and protection still wins"""
+        expiry = {"key": "quay.expires-after", "value": "1w", "source": "label", "expired": True}
+        expiries = {
+            "environment:new": {**expiry, "expires_at": "2024-12-30T00:00:00+00:00"},
+            "environment:s1": {**expiry, "expires_at": "2024-06-01T00:00:00+00:00"},
+            "environment:old": {**expiry, "expires_at": "2025-06-01T00:00:00+00:00", "expired": False},
+        }
+        usage = {"s1": {"use_count": 0, "last_used": None, "protected_by": ["models"]}}
+        candidates, protected = rank_candidates(self.analyzer, usage, now=NOW, expiries=expiries)
+
+        assert candidates[0]["image_id"] == "environment:new"
+        assert candidates[0]["expired"]
+        assert candidates[0]["expires_at"] == "2024-12-30T00:00:00+00:00"
+        assert not candidates[1]["expired"]
+        assert [p["image_id"] for p in protected] == ["environment:s1"]
+
+    def test_weighted_score_skips_missing_factors(self):
+        """Test that unavailable factors do not count as zero"""
+        assert weighted_score({"age": None, "exclusive_size": 0.5}, {"age": 1.0, "exclusive_size": 1.0}) == 0.5
+        assert weighted_score({"age": 1.0}, {"age": 0.0}) == 0.0
+
+    def test_savings_curve(self):
+        """Test that the curve is sampled evenly and always ends at the last candidate"""
+        candidates, _ = rank_candidates(self.analyzer, now=NOW)
+
+        curve = savings_curve(candidates, points=3)
+
+        assert [point["candidates"] for point in curve] == [1, 2, 3, 4]
+        assert curve[-1]["savings_bytes"] == candidates[-1]["cumulative_savings_bytes"]
+        assert savings_curve([]) == []
+
+    def test_protection_providers(self):
+        """Test that provider protections apply without usage data and are recorded with Domino's references"""
+        usage = {"s1": {"use_count": 0, "last_used": None, "protected_by": ["models"]}}
+        protections = {
+            "environment:s1": {"kubernetes": ["pod default/run-1"]},
+            "environment:old": {"reference-file": ["keep.txt:1"]},
+        }
+        _, without_usage = rank_candidates(self.analyzer, now=NOW, protections=protections)
+        candidates, protected = rank_candidates(self.analyzer, usage, now=NOW, protections=protections)
+
+        assert [p["image_id"] for p in without_usage] == ["environment:old", "environment:s1"]
+        assert {c["image_id"] for c in candidates} == {"environment:new", "environment:s2"}
+        assert protected[1]["providers"] == {"domino": ["models"], "kubernetes": ["pod default/run-1"]}
+        assert protected[1]["protected_by"] == ["models", "kubernetes"]
@@ -0,0 +1,78 @@
+"""Unit tests for deletion_queue.py"""
+
+import os
+import sys
+from datetime import datetime, timedelta, timezone
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.deletion_queue import STATUS_CANCELLED, STATUS_FAILED, STATUS_PENDING, STATUS_STARTED, DeletionQueue
+
+NOW = datetime(2026, 1, 1, 12, 0, tzinfo=timezone.utc)
+
+
+class TestDeletionQueue:
+    """Tests for DeletionQueue"""
+
+    def test_items_run_after_delay(self, tmp_path):
+        """Test that items become due only once their delay has passed, and are taken once"""
+        queue = DeletionQueue(tmp_path / "queue.json")
+        item = queue.add("delete_image", {"apply": True}, timedelta(hours=24), now=NOW)
+
+        assert item["status"] == STATUS_PENDING
+        assert queue.take_due(now=NOW + timedelta(hours=23)) == []
+
+        due = queue.take_due(now=NOW + timedelta(hours=24))
+        assert [d["item_id"] for d in due] == [item["item_id"]]
+        assert due[0]["params"] == {"apply": True}
+        assert queue.get(item["item_id"])["status"] == STATUS_STARTED
+        assert queue.take_due(now=NOW + timedelta(hours=48)) == []
+
+        queue.set_job(item["item_id"], "job-1")
+        assert queue.get(item["item_id"])["job_id"] == "job-1"
+
+    def test_cancel(self, tmp_path):
+        """Test that a pending item can be cancelled, and then never runs"""
+        queue = DeletionQueue(tmp_path / "queue.json")
+        item = queue.add("delete_image", {"apply": True}, timedelta(hours=1), now=NOW)
+        other = queue.add("delete_archived_tags", {"apply": True}, timedelta(hours=1), now=NOW)
+
+        assert queue.cancel(item["item_id"], now=NOW)["status"] == STATUS_CANCELLED
+        assert queue.cancel("no-such-item") is None
+        with pytest.raises(ValueError):
+            queue.cancel(item["item_id"])
+
+        assert [d["item_id"] for d in queue.take_due(now=NOW + timedelta(hours=2))] == [other["item_id"]]
+        assert queue.list_items(pending_only=True) == []
+        with pytest.raises(ValueError):
+            queue.cancel(other["item_id"])
+
+    def test_persisted_across_restarts(self, tmp_path):
+        """Test that the queue is reloaded from its file, and that finished items are trimmed"""
+        path = tmp_path / "queue.json"
+        queue = DeletionQueue(path, max_finished=1)
+        pending = queue.add("delete_image", {"apply": True}, timedelta(hours=24), now=NOW)
+        for _ in range(3):
+            item = queue.add("delete_image", {"apply": True}, timedelta(hours=1), now=NOW)
+            queue.cancel(item["item_id"])
+        failed = queue.add("run_registry_gc", {}, timedelta(0), now=NOW)
+        queue.take_due(now=NOW)
+        queue.set_failed(failed["item_id"], "boom")
+
+        reloaded = DeletionQueue(path, max_finished=1)
+        items = reloaded.list_items()
+        assert [item["item_id"] for item in items] == [pending["item_id"], failed["item_id"]]
+        assert items[1]["status"] == STATUS_FAILED
+        assert items[1]["error"] == "boom"
+
+    def test_unreadable_file_set_aside(self, tmp_path):
+        """Test that a corrupt queue file is moved aside and the queue starts empty"""
+        path = tmp_path / "queue.json"
+        path.write_text("{not json")
+
+        queue = DeletionQueue(path)
+
+        assert queue.list_items() == []
+        assert (tmp_path / "queue.broken.json").exists()
@@ -415,6 +415,19 @@ class TestArchivedTagsFinder:
         assert id_to_type[str(model_id)] == "model"
         assert id_to_type[str(version_id)] == "version"
 
+    def test_load_archived_tags_from_compressed_report(self, tmp_path, mock_archived_tags_deps):
+        """Test that an --input report saved compressed is read back through its uncompressed path."""
+        from scripts.delete_archived_tags import ArchivedTagsFinder
+        from utils.report_utils import save_json
+
+        tag_data = {"object_id": "abc", "image_type": "environment", "tag": "abc-1", "full_image": "repo/env:abc-1"}
+        save_json(str(tmp_path / "archived-tags.json"), {"archived_tags": [tag_data]}, compress="gzip")
+        finder = ArchivedTagsFinder(registry_url="registry:5000", repository="repo", process_environments=True)
+
+        tags = finder.load_archived_tags_from_file(str(tmp_path / "archived-tags.json"))
+
+        assert [(tag.tag, tag.record_type) for tag in tags] == [("abc-1", "environment")]
+
 
 # ============================================================================
 # Tests: Filter cloned dependencies
@@ -560,3 +573,80 @@ class TestInUseEnvironmentDetection:
 
         assert str(env_id) in result
         assert result[str(env_id)] is True
+
+
+class TestDeleteImageReferences:
+    """Tests for the tag and digest references accepted by delete_image --digest and --stdin."""
+
+    REGISTRY = "registry.example.com:5000"
+    DIGEST = "sha256:" + "a" * 64
+
+    def test_parse_references(self):
+        """Test parsing tag and digest references, with and without registry and repository"""
+        from scripts.delete_image import is_digest_reference, parse_digest_reference, parse_tag_reference
+
+        assert parse_tag_reference("environment:abc-123", self.REGISTRY, "dominodatalab") == (
+            "dominodatalab/environment",
+            "abc-123",
+        )
+        assert parse_tag_reference(f"{self.REGISTRY}/dominodatalab/model:v1", self.REGISTRY, "dominodatalab") == (
+            "dominodatalab/model",
+            "v1",
+        )
+        assert parse_digest_reference(f"{self.REGISTRY}/dominodatalab/model@{self.DIGEST}", self.REGISTRY) == (
+            "dominodatalab/model",
+            self.DIGEST,
+        )
+        assert parse_digest_reference(self.DIGEST, self.REGISTRY) == (None, self.DIGEST)
+
+        assert is_digest_reference(self.DIGEST)
+        assert not is_digest_reference("environment:abc-123")
+        with pytest.raises(ValueError):
+            parse_tag_reference("abc-123", self.REGISTRY, "dominodatalab")
+        with pytest.raises(ValueError):
+            parse_digest_reference("sha256:abc", self.REGISTRY)
+
+    def test_read_references(self):
+        """Test that blank lines, comments, JSON string quotes and duplicates are skipped"""
+        import io
+
+        from scripts.delete_image import read_references
+
+        stream = io.StringIO(f'environment:a\n\n# selected with jq\n"model:b"\nenvironment:a\n{self.DIGEST}\n')
+        assert read_references(stream) == ["environment:a", "model:b", self.DIGEST]
+
+
+class TestClean:
+    """Tests for the arguments clean passes to plan and apply"""
+
+    @staticmethod
+    def clean_args(**overrides):
+        """Parsed clean arguments with defaults"""
+        import argparse
+
+        defaults = dict(policy=None, snapshot=None, tags=None, input=None, image_types=None)
+        return argparse.Namespace(**{**defaults, "apply": False, "force": False, "allow_signed": False, **overrides})
+
+    def test_tags_planned_from_candidate_file(self, tmp_path):
+        """Test that --tags are written to a candidate file that plan reads with --input"""
+        from scripts.clean import apply_arguments, plan_arguments
+
+        args = self.clean_args(tags=["environment:a", "model:b"])
+        tags_path = str(tmp_path / "tags.txt")
+
+        assert plan_arguments(args, "plan.json", tags_path) == ["--input", tags_path, "--output", "plan.json"]
+        assert (tmp_path / "tags.txt").read_text() == "environment:a\nmodel:b\n"
+        assert apply_arguments(args, "plan.json") == ["plan.json"]
+
+    def test_policy_uses_newest_snapshot(self, mocker):
+        """Test that a policy is evaluated against the newest saved snapshot, and --apply reaches apply"""
+        from pathlib import Path
+
+        from scripts.clean import apply_arguments, plan_arguments
+
+        mocker.patch("scripts.clean.find_snapshots", return_value=[(Path("new.json"), None), (Path("old.json"), None)])
+        args = self.clean_args(policy="policy.yaml", apply=True, force=True)
+
+        planned = plan_arguments(args, "plan.json", "tags.txt")
+        assert planned == ["--policy", "policy.yaml", "--snapshot", "new.json", "--output", "plan.json"]
+        assert apply_arguments(args, "plan.json") == ["plan.json", "--apply", "--force"]
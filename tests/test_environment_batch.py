@@ -0,0 +1,97 @@
+"""Unit tests for environment_batch.py"""
+
+import json
+import os
+import sys
+import tempfile
+from pathlib import Path
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.environment_batch import consolidate_reports, load_environments_file
+
+
+class TestLoadEnvironmentsFile:
+    """Tests for reading environments files"""
+
+    def setup_method(self):
+        """Create a directory with two install configs"""
+        self.tmpdir = tempfile.TemporaryDirectory()
+        self.dir = Path(self.tmpdir.name)
+        (self.dir / "configs").mkdir()
+        for name in ("prod-us.yaml", "staging.yaml"):
+            (self.dir / "configs" / name).write_text("registry:\n  url: registry:5000\n")
+
+    def teardown_method(self):
+        """Remove the directory"""
+        self.tmpdir.cleanup()
+
+    def _write(self, content: str) -> str:
+        path = self.dir / "installs.txt"
+        path.write_text(content)
+        return str(path)
+
+    def test_names_and_relative_paths(self):
+        """Test that names default to the config file name and paths are relative to the list"""
+        path = self._write("# installs\nus configs/prod-us.yaml\n\nconfigs/staging.yaml\n")
+
+        environments = load_environments_file(path)
+
+        assert environments == [
+            {"name": "us", "config_file": str(self.dir / "configs" / "prod-us.yaml")},
+            {"name": "staging", "config_file": str(self.dir / "configs" / "staging.yaml")},
+        ]
+
+    def test_invalid_files(self):
+        """Test that missing configs, repeated names, malformed lines and empty lists are rejected"""
+        for content in (
+            "us configs/missing.yaml\n",
+            "us configs/prod-us.yaml\nus configs/staging.yaml\n",
+            "us configs/prod-us.yaml extra\n",
+            "# nothing\n",
+        ):
+            with pytest.raises(ValueError):
+                load_environments_file(self._write(content))
+
+
+class TestConsolidateReports:
+    """Tests for merging the reports of every install"""
+
+    def _run(self, directory: Path, name: str, exit_code: int) -> dict:
+        report = directory / f"{name}-report.json"
+        report.write_text(json.dumps({"install": name}))
+        table = directory / f"{name}-report.csv"
+        table.write_text("a,b\n")
+        return {
+            "name": name,
+            "config_file": f"{name}.yaml",
+            "exit_code": exit_code,
+            "duration_seconds": 1.0,
+            "reports": [table, report],
+        }
+
+    def test_merges_and_removes_reports(self):
+        """Test that JSON reports are merged per install, failures listed, and report files removed"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            runs = [self._run(Path(tmpdir), "us", 0), self._run(Path(tmpdir), "eu", 2)]
+
+            consolidated = consolidate_reports(runs, "image_size_report", keep_reports=False)
+
+            assert os.listdir(tmpdir) == []
+        assert consolidated["summary"]["succeeded"] == 1
+        assert consolidated["summary"]["failed"] == ["eu"]
+        assert consolidated["environments"][0]["reports"] == {"us-report.json": {"install": "us"}}
+        assert consolidated["environments"][1]["status"] == "failure"
+        assert consolidated["environments"][1]["files"] == []
+
+    def test_keeps_reports(self):
+        """Test that kept report files are listed, CSV reports included"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            runs = [self._run(Path(tmpdir), "us", 0)]
+
+            consolidated = consolidate_reports(runs, "image_size_report", keep_reports=True)
+
+            assert len(os.listdir(tmpdir)) == 2
+        assert consolidated["environments"][0]["files"] == ["us-report.csv", "us-report.json"]
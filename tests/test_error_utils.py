@@ -0,0 +1,47 @@
+"""Unit tests for error codes in error_utils.py"""
+
+import os
+import subprocess
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.error_utils import (
+    ERROR_AUTH,
+    ERROR_NOT_FOUND,
+    ERROR_PARSE,
+    ERROR_RATE_LIMITED,
+    ERROR_TIMEOUT,
+    ERROR_UNAVAILABLE,
+    ERROR_UNKNOWN,
+    classify_error,
+    create_registry_auth_error,
+    error_exit_status,
+)
+
+
+class TestErrorCodes:
+    """Tests for classifying failures and choosing exit statuses"""
+
+    def test_classify_error(self):
+        """Test that exceptions and skopeo messages map to their error codes"""
+        assert classify_error(subprocess.TimeoutExpired(["skopeo"], 30)) == ERROR_TIMEOUT
+        assert classify_error("toomanyrequests: 429 Too Many Requests") == ERROR_RATE_LIMITED
+        assert classify_error("unauthorized: authentication required") == ERROR_AUTH
+        assert classify_error(create_registry_auth_error("registry.example.com", Exception("401"))) == ERROR_AUTH
+        assert classify_error("manifest unknown: manifest tagged by 1.0 is not found") == ERROR_NOT_FOUND
+        assert classify_error("dial tcp 10.0.0.1:5000: connect: connection refused") == ERROR_UNAVAILABLE
+        assert classify_error("received unexpected HTTP status: 503 Service Unavailable") == ERROR_UNAVAILABLE
+        assert classify_error("Failed to parse image inspection: Expecting value") == ERROR_PARSE
+        assert classify_error(ValueError("something else")) == ERROR_UNKNOWN
+        # A port is not a status
+        assert classify_error("error pinging registry:404/v2/") == ERROR_UNKNOWN
+
+    def test_exit_status_is_retryable_only_when_every_failure_is(self):
+        """Test that the exit status of mixed failures is that of the non-retryable one"""
+        assert error_exit_status([]) == 0
+        assert error_exit_status([ERROR_TIMEOUT, ERROR_TIMEOUT]) == 13
+        assert error_exit_status([ERROR_RATE_LIMITED, ERROR_UNAVAILABLE]) == 14
+        assert error_exit_status([ERROR_TIMEOUT, ERROR_AUTH]) == 10
+        assert error_exit_status([ERROR_RATE_LIMITED, ERROR_PARSE]) == 15
+        assert error_exit_status([ERROR_TIMEOUT, "SOMETHING_NEW"]) == 1
@@ -150,6 +150,12 @@ class TestRoutes:
         r = client.get("/reports/unused-environments.json")
         assert r.status_code == 200
 
+    def test_view_chargeback_report(self, client, reports_dir):
+        (reports_dir / "chargeback-report-2026-01-01-00-00-00.json").write_text('{"summary": {}, "owners": []}')
+        r = client.get("/reports/chargeback-report-2026-01-01-00-00-00.json")
+        assert r.status_code == 200
+        assert b'const reportType = "chargeback"' in r.data
+
     def test_api_reports_list(self, client, reports_dir):
         (reports_dir / "deletion-analysis.json").write_text("{}")
         (reports_dir / "archived-tags.json").write_text("{}")
@@ -233,3 +239,24 @@ class TestBackendProxy:
         mocker.patch("app.httpx.delete", side_effect=_httpx.ConnectError("refused"))
         r = client.delete("/api/jobs/abc")
         assert r.status_code == 503
+
+    def test_schedules_list(self, client, mocker):
+        schedules = {"scan": {"cron": "0 2 * * *", "next_run": "2026-01-02T02:00:00+00:00"}}
+        mocker.patch("app.httpx.get", return_value=_mock_httpx_response(schedules))
+        r = client.get("/api/schedules")
+        assert r.status_code == 200
+        assert r.get_json()["scan"]["cron"] == "0 2 * * *"
+
+    def test_deletion_queue_list(self, client, mocker):
+        queue = {"delay_hours": 24, "items": [{"item_id": "q1", "status": "pending"}]}
+        mock_get = mocker.patch("app.httpx.get", return_value=_mock_httpx_response(queue))
+        r = client.get("/api/deletion-queue?pending_only=false")
+        assert r.status_code == 200
+        assert r.get_json()["items"][0]["item_id"] == "q1"
+        assert mock_get.call_args.kwargs["params"]["pending_only"] == "false"
+
+    def test_cancel_queued_deletion(self, client, mocker):
+        mock_delete = mocker.patch("app.httpx.delete", return_value=_mock_httpx_response({"message": "cancelled"}))
+        r = client.delete("/api/deletion-queue/q1")
+        assert r.status_code == 200
+        assert mock_delete.call_args[0][0].endswith("/api/deletion-queue/q1")
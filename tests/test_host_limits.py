@@ -0,0 +1,68 @@
+"""Unit tests for utils/host_limits.py"""
+
+import os
+import sys
+import threading
+import time
+from unittest.mock import MagicMock, patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.host_limits import HostLimiter, host_slot
+from utils.registry_http import RegistryHttpClient
+
+
+class TestHostLimiter:
+    """Tests for capping the requests in flight to a host"""
+
+    def test_requests_over_limit_wait(self):
+        """Test that no more than the limit run at once, and every request eventually runs"""
+        limiter = HostLimiter("harbor.example.com", 2)
+        finished = []
+
+        def request():
+            with limiter.slot():
+                time.sleep(0.02)
+                finished.append(1)
+
+        threads = [threading.Thread(target=request) for _ in range(6)]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join(timeout=5)
+
+        assert len(finished) == 6
+        assert limiter.peak_in_flight == 2
+        assert limiter.times_waited > 0
+        assert limiter.in_flight == 0
+
+    def test_slot_released_on_error(self):
+        """Test that a failed request gives its slot back"""
+        limiter = HostLimiter("harbor.example.com", 1)
+
+        try:
+            with limiter.slot():
+                raise OSError("connection reset")
+        except OSError:
+            pass
+
+        assert limiter.in_flight == 0
+        with host_slot(None):
+            pass
+
+    def test_http_response_holds_slot_until_closed(self):
+        """Test that a native HTTP request counts against the limit while its response is open"""
+        limiter = HostLimiter("registry.example.com", 1)
+        client = RegistryHttpClient("https://registry.example.com", host_limiter=limiter)
+        response = MagicMock()
+        response.__enter__.return_value = response
+        response.headers = {"Docker-Content-Digest": "sha256:abc"}
+
+        def urlopen(*args, **kwargs):
+            assert limiter.in_flight == 1
+            return response
+
+        with patch("urllib.request.urlopen", side_effect=urlopen):
+            assert client.head_manifest_digest("dominodatalab/environment", "v1") == "sha256:abc"
+
+        assert limiter.in_flight == 0
@@ -0,0 +1,1182 @@
+"""Tests for ImageAnalyzer aggregate analysis helpers"""
+
+import os
+import sys
+from datetime import datetime, timezone
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from tests.helpers import add_image, make_analyzer
+from utils.foreign_layers import FOREIGN_LAYER_MEDIA_TYPES
+from utils.media_types import media_type_category
+from utils.ownership import UNKNOWN, UNLABELED, owner_from_labels
+
+
+class TestRepositorySummary:
+    """Tests for ImageAnalyzer.generate_repository_summary"""
+
+    def setup_method(self):
+        """Set up an analyzer with environment and model images sharing a base layer"""
+        self.analyzer = make_analyzer()
+        add_image(self.analyzer, "environment:env1", [("base", 5000), ("env-a", 1000)])
+        add_image(self.analyzer, "environment:env2", [("base", 5000), ("env-b", 3000)])
+        add_image(self.analyzer, "model:model1", [("base", 5000), ("model-a", 2000)])
+
+    def test_tag_and_layer_counts(self):
+        """Test tag count and distinct layer count per repository"""
+        summary = self.analyzer.generate_repository_summary()
+
+        assert summary["test-repo/environment"]["tag_count"] == 2
+        assert summary["test-repo/environment"]["layer_count"] == 3
+        assert summary["test-repo/model"]["tag_count"] == 1
+        assert summary["test-repo/model"]["layer_count"] == 2
+
+    def test_unique_and_shared_bytes(self):
+        """Test that layers referenced by other repositories are counted as shared"""
+        summary = self.analyzer.generate_repository_summary()
+        env = summary["test-repo/environment"]
+        model = summary["test-repo/model"]
+
+        assert env["unique_layers"] == 2
+        assert env["unique_bytes"] == 4000
+        assert env["shared_layers"] == 1
+        assert env["shared_bytes"] == 5000
+        assert env["total_bytes"] == 9000
+
+        assert model["unique_bytes"] == 2000
+        assert model["shared_bytes"] == 5000
+
+    def test_layer_shared_within_repository_is_unique(self):
+        """Test that a layer shared only between tags of the same repository is unique to it"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 5000)])
+        add_image(analyzer, "environment:env2", [("base", 5000)])
+        summary = analyzer.generate_repository_summary()
+
+        assert summary["test-repo/environment"]["unique_bytes"] == 5000
+        assert summary["test-repo/environment"]["shared_bytes"] == 0
+
+    def test_largest_image(self):
+        """Test that the largest image per repository is reported"""
+        summary = self.analyzer.generate_repository_summary()
+        largest = summary["test-repo/environment"]["largest_image"]
+
+        assert largest["image_id"] == "environment:env2"
+        assert largest["size_bytes"] == 8000
+
+    def test_oldest_and_newest_image(self):
+        """Test that the oldest and newest images per repository come from creation times"""
+        self.analyzer.created = {
+            "environment:env1": "2024-03-01T00:00:00Z",
+            "environment:env2": "2023-06-15T12:00:00Z",
+        }
+        summary = self.analyzer.generate_repository_summary()
+        env = summary["test-repo/environment"]
+
+        assert env["oldest_image"]["image_id"] == "environment:env2"
+        assert env["newest_image"]["image_id"] == "environment:env1"
+        assert summary["test-repo/model"]["oldest_image"] is None
+        assert env["oldest_image"]["age_days"] > env["newest_image"]["age_days"] > 0
+        assert self.analyzer.image_ages()["environment:env2"]["created"] == "2023-06-15T12:00:00Z"
+        assert "model:model1" not in self.analyzer.image_ages()
+
+    def test_empty_analyzer(self):
+        """Test summary with no analyzed images"""
+        assert make_analyzer().generate_repository_summary() == {}
+
+
+class TestLayerOrigins:
+    """Tests for attributing layers to the images that introduced them"""
+
+    def setup_method(self):
+        """Set up two revisions of one environment and a later environment and model on the same base"""
+        self.analyzer = make_analyzer()
+        add_image(self.analyzer, "environment:aaa-1", [("base", 5000), ("aaa-top", 100)])
+        add_image(self.analyzer, "environment:aaa-2", [("base", 5000), ("aaa-top", 100), ("aaa-2", 50)])
+        add_image(self.analyzer, "environment:bbb-1", [("base", 5000), ("bbb-top", 300)])
+        add_image(self.analyzer, "model:ccc-1", [("base", 5000), ("aaa-top", 100)])
+        self.analyzer.created = {
+            "environment:aaa-1": "2024-01-01T00:00:00Z",
+            "environment:aaa-2": "2024-02-01T00:00:00+00:00",
+            "environment:bbb-1": "2023-12-31T23:00:00-02:00",
+            "model:ccc-1": "2024-03-01T00:00:00Z",
+        }
+
+    def test_earliest_created_image_introduces_layer(self):
+        """Test that a layer's origin is its earliest image, comparing times across time zones"""
+        origins = self.analyzer.layer_origins()
+
+        assert origins["base"]["image_id"] == "environment:aaa-1"
+        assert origins["base"]["namespace"] == "test-repo/environment/aaa"
+        assert origins["base"]["consumers"] == 4
+        assert origins["base"]["consumer_namespaces"] == 3
+        assert origins["aaa-2"]["image_id"] == "environment:aaa-2"
+
+    def test_undated_layers_have_no_origin(self):
+        """Test that layers referenced only by images without a creation time are left out"""
+        del self.analyzer.created["environment:bbb-1"]
+
+        assert "bbb-top" not in self.analyzer.layer_origins()
+        assert self.analyzer.layer_origins()["base"]["image_id"] == "environment:aaa-1"
+
+    def test_shared_layers_attributed_to_introducer(self):
+        """Test that shared layers count against the namespace that introduced them, not every consumer"""
+        introducers = self.analyzer.shared_layer_introducers()
+
+        assert introducers == [
+            {
+                "namespace": "test-repo/environment/aaa",
+                "repository": "test-repo/environment",
+                "layers": 2,
+                "bytes": 5100,
+                "consumer_namespaces": 2,
+            }
+        ]
+        summary = self.analyzer.generate_repository_summary()
+        assert summary["test-repo/environment"]["introduced_bytes"] == 5000 + 100 + 50 + 300
+        assert summary["test-repo/model"]["introduced_bytes"] == 0
+
+
+class TestLayerImageMap:
+    """Tests for ImageAnalyzer.build_layer_image_map"""
+
+    def test_both_directions(self):
+        """Test that layers map to the images using them and images to their layers in manifest order"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 1000), ("env1-top", 200)])
+        add_image(analyzer, "environment:env2", [("base", 1000), ("env2-top", 300)])
+
+        mapping = analyzer.build_layer_image_map()
+
+        assert mapping["layers_to_images"]["base"] == {
+            "size_bytes": 1000,
+            "ref_count": 2,
+            "images": ["environment:env1", "environment:env2"],
+            "origin": None,
+        }
+        assert mapping["layers_to_images"]["env2-top"]["images"] == ["environment:env2"]
+        assert mapping["images_to_layers"]["environment:env1"] == {
+            "repository": "test-repo/environment",
+            "tag": "env1",
+            "digest": "sha256:environment:env1",
+            "size_bytes": 1200,
+            "layers": ["base", "env1-top"],
+        }
+        assert mapping["summary"]["total_layers"] == 3
+        assert mapping["summary"]["total_size_bytes"] == 1500
+
+
+class TestDuplicateImages:
+    """Tests for ImageAnalyzer.find_duplicate_images"""
+
+    def setup_method(self):
+        """Set up an analyzer with one digest pushed under two environment namespaces"""
+        self.analyzer = make_analyzer()
+        env_a = "507f1f77bcf86cd799439011"
+        env_b = "507f191e810c19729de860ea"
+        layers = [("base", 5000), ("top", 1000)]
+        add_image(self.analyzer, f"environment:{env_a}-1", layers, digest="sha256:same")
+        add_image(self.analyzer, f"environment:{env_a}-2", layers, digest="sha256:same")
+        add_image(self.analyzer, f"environment:{env_b}-1", layers, digest="sha256:same")
+        add_image(self.analyzer, f"environment:{env_b}-2", [("other", 700)], digest="sha256:unique")
+        self.env_a = env_a
+        self.env_b = env_b
+
+    def test_detects_cross_namespace_duplicate(self):
+        """Test that a digest shared across namespaces is reported once"""
+        groups = self.analyzer.find_duplicate_images()
+
+        assert len(groups) == 1
+        assert groups[0]["digest"] == "sha256:same"
+        assert groups[0]["namespaces"] == sorted(
+            [f"test-repo/environment/{self.env_a}", f"test-repo/environment/{self.env_b}"]
+        )
+
+    def test_duplicated_bytes(self):
+        """Test duplicated bytes count one image size per extra namespace"""
+        group = self.analyzer.find_duplicate_images()[0]
+
+        assert group["size_bytes"] == 6000
+        assert group["duplicated_bytes"] == 6000
+
+    def test_canonicalization_plan_prefers_namespace_with_most_tags(self):
+        """Test that the namespace holding the most tags is chosen as canonical"""
+        group = self.analyzer.find_duplicate_images()[0]
+
+        assert group["canonical_image_id"] == f"environment:{self.env_a}-1"
+        assert group["canonicalization_plan"] == [
+            {
+                "image_id": f"environment:{self.env_b}-1",
+                "namespace": f"test-repo/environment/{self.env_b}",
+                "replace_with": f"environment:{self.env_a}-1",
+            }
+        ]
+
+    def test_aliases_within_one_namespace_are_not_duplicates(self):
+        """Test that tags of the same namespace sharing a digest are not reported"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:abc-1", [("base", 5000)], digest="sha256:same")
+        add_image(analyzer, "environment:abc-2", [("base", 5000)], digest="sha256:same")
+
+        assert analyzer.find_duplicate_images() == []
+
+    def test_tag_aliases(self):
+        """Test that tags of one repository sharing a digest are grouped as aliases"""
+        aliases = self.analyzer.find_tag_aliases()
+
+        assert len(aliases) == 1
+        assert aliases[0]["digest"] == "sha256:same"
+        assert aliases[0]["tags"] == sorted([f"{self.env_a}-1", f"{self.env_a}-2", f"{self.env_b}-1"])
+        assert aliases[0]["size_bytes"] == 6000
+
+    def test_cross_repository_duplicates(self):
+        """Test that a digest stored in both the environment and model repositories is reported"""
+        add_image(self.analyzer, "model:m1-1", [("base", 5000), ("top", 1000)], digest="sha256:same")
+
+        duplicates = self.analyzer.find_cross_repository_duplicates()
+
+        assert len(duplicates) == 1
+        assert duplicates[0]["repositories"] == ["test-repo/environment", "test-repo/model"]
+        assert "model:m1-1" in duplicates[0]["image_ids"]
+
+    def test_duplicate_layers_by_diff_id(self):
+        """Test that differently compressed blobs of the same content are reported"""
+        from unittest.mock import MagicMock
+
+        add_image(self.analyzer, "model:m1-1", [("base-zstd", 4000), ("model-top", 10)], digest="sha256:model")
+        self.analyzer.skopeo_client = MagicMock()
+
+        def image_config(repository, tag):
+            if repository.endswith("/model"):
+                return {"rootfs": {"diff_ids": ["sha256:base-content", "sha256:model-content"]}}
+            if tag.endswith("-2") and tag.startswith(self.env_b):
+                return {"rootfs": {"diff_ids": ["sha256:other-content"]}}
+            return {"rootfs": {"diff_ids": ["sha256:base-content", "sha256:top-content"]}}
+
+        self.analyzer.skopeo_client.get_image_config.side_effect = image_config
+
+        diff_ids = self.analyzer.collect_layer_diff_ids(max_workers=1)
+        groups = self.analyzer.find_duplicate_layers(diff_ids)
+
+        assert self.analyzer.skopeo_client.get_image_config.call_count == 3
+        assert len(groups) == 1
+        assert groups[0]["layer_ids"] == ["base", "base-zstd"]
+        assert groups[0]["duplicated_bytes"] == 5000
+
+    def test_encrypted_images_skipped_for_diff_ids(self):
+        """Test that images with encrypted layers are flagged and their configs are not matched by diffID"""
+        from unittest.mock import MagicMock
+
+        add_image(self.analyzer, "model:secret", [("enc", 4000)], digest="sha256:secret")
+        self.analyzer.layer_media_types["enc"] = "application/vnd.oci.image.layer.v1.tar+gzip+encrypted"
+        self.analyzer.skopeo_client = MagicMock()
+        self.analyzer.skopeo_client.get_image_config.return_value = {"rootfs": {"diff_ids": ["sha256:content"]}}
+
+        diff_ids = self.analyzer.collect_layer_diff_ids(max_workers=1)
+
+        assert self.analyzer.images_with_encrypted_layers() == {"model:secret": ["enc"]}
+        assert "enc" not in diff_ids
+        assert ("test-repo/model", "secret") not in [
+            call.args for call in self.analyzer.skopeo_client.get_image_config.call_args_list
+        ]
+
+
+class TestSimulateDeletion:
+    """Tests for ImageAnalyzer.simulate_deletion"""
+
+    def setup_method(self):
+        """Set up an analyzer with two images sharing a base layer"""
+        self.analyzer = make_analyzer()
+        add_image(self.analyzer, "environment:env1", [("base", 5000), ("env-a", 1000)])
+        add_image(self.analyzer, "environment:env2", [("base", 5000), ("env-b", 3000)])
+        add_image(self.analyzer, "model:model1", [("base", 5000)])
+
+    def test_freed_layers(self):
+        """Test that only layers referenced solely by the deleted image are freed"""
+        simulation = self.analyzer.simulate_deletion(["environment:env1"])
+
+        assert simulation["freed_bytes"] == 1000
+        assert simulation["freed_layers"] == [{"layer_id": "env-a", "size_bytes": 1000}]
+
+    def test_retained_layers_report_remaining_refs(self):
+        """Test that shared layers report remaining references and holders"""
+        simulation = self.analyzer.simulate_deletion(["environment:env1"])
+
+        assert simulation["retained_bytes"] == 5000
+        assert simulation["retained_layers"] == [
+            {
+                "layer_id": "base",
+                "size_bytes": 5000,
+                "remaining_refs": 2,
+                "remaining_images": ["environment:env2", "model:model1"],
+            }
+        ]
+
+    def test_matches_freed_space_if_deleted(self):
+        """Test that freed bytes agree with freed_space_if_deleted"""
+        image_ids = ["environment:env1", "environment:env2"]
+        simulation = self.analyzer.simulate_deletion(image_ids)
+
+        assert simulation["freed_bytes"] == self.analyzer.freed_space_if_deleted(image_ids)
+
+    def test_missing_image(self):
+        """Test that unknown images are reported and free nothing"""
+        simulation = self.analyzer.simulate_deletion(["environment:nope"])
+
+        assert simulation["missing_image_ids"] == ["environment:nope"]
+        assert simulation["image_ids"] == []
+        assert simulation["freed_bytes"] == 0
+
+    def test_does_not_modify_state(self):
+        """Test that simulation leaves ref counts untouched"""
+        self.analyzer.simulate_deletion(["environment:env1", "environment:env2", "model:model1"])
+
+        assert self.analyzer.layers["base"]["ref_count"] == 3
+
+    def test_foreign_layers_never_freed(self):
+        """Test that foreign layers are listed separately and excluded from freed bytes"""
+        add_image(self.analyzer, "environment:win1", [("windows-base", 90000), ("win-app", 200)])
+        self.analyzer.foreign_layers.add("windows-base")
+
+        simulation = self.analyzer.simulate_deletion(["environment:win1"])
+
+        assert simulation["freed_bytes"] == 200
+        assert simulation["foreign_bytes"] == 90000
+        assert simulation["foreign_layers"] == [{"layer_id": "windows-base", "size_bytes": 90000}]
+        assert self.analyzer.freed_space_if_deleted(["environment:win1"]) == 200
+        assert self.analyzer.get_image_total_size("environment:win1") == 90200
+        assert self.analyzer.images_with_foreign_layers() == {"environment:win1": ["windows-base"]}
+
+
+class TestSizeBreakdown:
+    """Tests for ImageAnalyzer.size_breakdown"""
+
+    def test_sharing_ignores_tags_of_the_same_digest(self):
+        """Test that layers count as shared only with images of another digest"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 6000), ("env-a", 2000)], digest="sha256:one")
+        add_image(analyzer, "environment:env1-alias", [("base", 6000), ("env-a", 2000)], digest="sha256:one")
+        add_image(analyzer, "environment:env2", [("base", 6000), ("env-b", 3000)])
+
+        breakdown = analyzer.size_breakdown("environment:env1")
+
+        assert [layer["digest"] for layer in breakdown["layers"]] == ["base", "env-a"]
+        assert [layer["shared_with"] for layer in breakdown["layers"]] == [1, 0]
+        assert [layer["percent"] for layer in breakdown["layers"]] == [75.0, 25.0]
+        assert breakdown["total_bytes"] == 8000
+        assert breakdown["unique_bytes"] == 2000
+
+
+class TestImageExpiries:
+    """Tests for ImageAnalyzer.image_expiries"""
+
+    def test_expiries_from_annotations_and_labels(self):
+        """Test that only images with an expiry key are listed, relative to their creation time"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:short", [("a", 1000)])
+        add_image(analyzer, "environment:dated", [("b", 1000)])
+        add_image(analyzer, "environment:plain", [("c", 1000)])
+        analyzer.created["environment:short"] = "2025-01-01T00:00:00Z"
+        analyzer.labels["environment:short"] = {"quay.expires-after": "2d"}
+        analyzer.annotations["environment:dated"] = {"com.dominodatalab.expires-after": "2026-01-01"}
+        analyzer.labels["environment:plain"] = {"owner": "ml"}
+
+        expiries = analyzer.image_expiries(now=datetime(2025, 6, 1, tzinfo=timezone.utc))
+
+        assert sorted(expiries) == ["environment:dated", "environment:short"]
+        assert expiries["environment:short"]["expires_at"] == "2025-01-03T00:00:00+00:00"
+        assert expiries["environment:short"]["expired"]
+        assert not expiries["environment:dated"]["expired"]
+        assert analyzer.image_expiries(keys=[]) == {}
+
+
+class TestOversizedLayers:
+    """Tests for ImageAnalyzer.oversized_layers"""
+
+    def test_layers_over_threshold_with_their_images(self):
+        """Test that layers over the threshold are listed largest first with the images containing them"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 5000), ("dataset", 9000)])
+        add_image(analyzer, "environment:env2", [("base", 5000), ("small", 100)])
+        add_image(analyzer, "model:model1", [("dataset", 9000), ("windows-base", 20000)])
+        analyzer.foreign_layers.add("windows-base")
+
+        assert analyzer.oversized_layers(4000) == [
+            {"layer_id": "dataset", "size_bytes": 9000, "image_ids": ["environment:env1", "model:model1"]},
+            {"layer_id": "base", "size_bytes": 5000, "image_ids": ["environment:env1", "environment:env2"]},
+        ]
+        assert analyzer.oversized_layers(9000) == []
+
+
+class TestOutdatedToolchains:
+    """Tests for ImageAnalyzer.outdated_toolchains"""
+
+    def test_outdated_images_and_status(self):
+        """Test that images are flagged by Docker version or base image, and unknown ones are left out"""
+        analyzer = make_analyzer()
+        for image_id in ("environment:old-engine", "environment:eol-base", "environment:current", "model:unknown"):
+            add_image(analyzer, image_id, [(f"{image_id}-layer", 100)])
+        analyzer.toolchain["environment:old-engine"] = {"docker_version": "1.13.1", "os": "linux"}
+        analyzer.toolchain["environment:current"] = {"docker_version": "24.0.7", "os": "linux"}
+        analyzer.annotations["environment:eol-base"] = {"org.opencontainers.image.base.name": "ubuntu:18.04"}
+        policy = {"min_docker_version": "18.09", "outdated_base_images": ["ubuntu:18.04*"]}
+
+        assert analyzer.outdated_toolchains(policy) == [
+            {
+                "image_id": "environment:eol-base",
+                "reasons": ["base_image"],
+                "docker_version": None,
+                "os": None,
+                "base_image": "ubuntu:18.04",
+            },
+            {
+                "image_id": "environment:old-engine",
+                "reasons": ["docker_version"],
+                "docker_version": "1.13.1",
+                "os": "linux",
+                "base_image": None,
+            },
+        ]
+        assert analyzer.toolchain_status(policy) == {
+            "environment:old-engine": True,
+            "environment:eol-base": True,
+            "environment:current": False,
+        }
+
+
+class TestOsReleases:
+    """Tests for ImageAnalyzer.infer_os_releases and eol_images"""
+
+    def test_releases_from_labels_and_base_layers(self):
+        """Test that labels are used first and each distinct base layer's os-release is read once"""
+        import io
+        import tarfile
+        from datetime import date
+        from unittest.mock import MagicMock
+
+        os_release = b'ID=ubuntu\nVERSION_ID="18.04"\n'
+        buffer = io.BytesIO()
+        with tarfile.open(fileobj=buffer, mode="w") as archive:
+            info = tarfile.TarInfo("etc/os-release")
+            info.size = len(os_release)
+            archive.addfile(info, io.BytesIO(os_release))
+
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1-1", [("bionic-base", 3000), ("env-a", 100)])
+        add_image(analyzer, "environment:env1-2", [("bionic-base", 3000), ("env-b", 100)])
+        add_image(analyzer, "environment:env2-1", [("other-base", 3000)])
+        analyzer.labels["environment:env2-1"] = {
+            "org.opencontainers.image.ref.name": "ubuntu",
+            "org.opencontainers.image.version": "24.04",
+        }
+        analyzer.skopeo_client = MagicMock()
+        analyzer.skopeo_client.read_blob.side_effect = lambda repository, digest, reader: reader(
+            io.BytesIO(buffer.getvalue())
+        )
+
+        analyzer.infer_os_releases("environment", max_workers=2)
+
+        analyzer.skopeo_client.read_blob.assert_called_once()
+        bionic = {"distro": "ubuntu", "version": "18.04", "source": "os-release"}
+        assert analyzer.os_releases["environment:env1-2"] == bionic
+        assert analyzer.os_releases["environment:env2-1"]["source"] == "labels"
+        assert [image["image_id"] for image in analyzer.eol_images(date(2025, 1, 1))] == [
+            "environment:env1-1",
+            "environment:env1-2",
+        ]
+        policy = {"min_docker_version": "18.09", "outdated_base_images": []}
+        assert [image["reasons"] for image in analyzer.outdated_toolchains(policy)] == [["eol_os"], ["eol_os"]]
+
+
+class TestMediaTypeBreakdown:
+    """Tests for ImageAnalyzer.media_type_breakdown"""
+
+    def test_bytes_per_media_type(self):
+        """Test that each layer is counted once under its media type, largest first"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 5000), ("env-a", 1000), ("sbom", 10)])
+        add_image(analyzer, "environment:env2", [("base", 5000), ("env-b", 3000)])
+        add_image(analyzer, "model:model1", [("old", 700)])
+        analyzer.layer_media_types.update(
+            {
+                "base": "application/vnd.oci.image.layer.v1.tar+gzip",
+                "env-a": "application/vnd.oci.image.layer.v1.tar+zstd",
+                "env-b": "application/vnd.oci.image.layer.v1.tar+zstd",
+                "sbom": "application/vnd.in-toto+json",
+            }
+        )
+
+        breakdown = analyzer.media_type_breakdown()
+
+        assert [(entry["category"], entry["layers"], entry["bytes"]) for entry in breakdown] == [
+            ("gzip", 1, 5000),
+            ("zstd", 2, 4000),
+            ("unknown", 1, 700),
+            ("provenance", 1, 10),
+        ]
+        assert breakdown[2]["media_type"] is None
+
+    def test_categories(self):
+        """Test that media types are grouped into compression, foreign and provenance categories"""
+        assert media_type_category("application/vnd.docker.image.rootfs.diff.tar.gzip") == "gzip"
+        assert media_type_category("application/vnd.oci.image.layer.v1.tar") == "uncompressed"
+        assert media_type_category(FOREIGN_LAYER_MEDIA_TYPES[0]) == "foreign"
+        assert media_type_category("application/vnd.dev.cosign.simplesigning.v1+json") == "provenance"
+        assert media_type_category("application/vnd.cncf.helm.chart.content.v1.tar+gzip") == "gzip"
+        assert media_type_category("application/vnd.oci.image.layer.v1.tar+zstd+encrypted") == "encrypted"
+        assert media_type_category("application/octet-stream") == "other"
+
+
+class TestOwnerUsage:
+    """Tests for attributing images and their bytes to owners from labels"""
+
+    def test_owner_usage_and_attribution(self):
+        """Test exclusive, shared and amortized bytes per owner, with unlabeled and unknown images"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 6000), ("env-a", 1000)])
+        add_image(analyzer, "environment:env2", [("base", 6000), ("env-b", 3000)])
+        add_image(analyzer, "environment:env3", [("base", 6000), ("env-c", 200)])
+        add_image(analyzer, "model:model1", [("windows-base", 900), ("model-a", 50)])
+        analyzer.foreign_layers.add("windows-base")
+        analyzer.labels.update(
+            {
+                "environment:env1": {"team": "forecasting"},
+                "environment:env2": {"owner": "data-science", "team": "forecasting"},
+                "environment:env3": {"maintainer": "someone@example.com"},
+            }
+        )
+
+        usage = {entry["owner"]: entry for entry in analyzer.owner_usage(["owner", "team"])}
+
+        assert usage["data-science"]["images"] == 1
+        assert usage["data-science"]["exclusive_bytes"] == 3000
+        assert usage["data-science"]["shared_bytes"] == 6000
+        assert usage["data-science"]["amortized_bytes"] == 5000
+        assert usage["forecasting"]["exclusive_bytes"] == 1000
+        assert usage["(unlabeled)"]["amortized_bytes"] == 2200
+        assert usage["(unknown)"]["total_bytes"] == 50
+        assert sum(entry["amortized_bytes"] for entry in usage.values()) == 10250
+
+        attribution = {entry["image_id"]: entry for entry in analyzer.image_attribution(["owner", "team"])}
+        assert attribution["environment:env2"]["owner"] == "data-science"
+        assert attribution["environment:env2"]["exclusive_bytes"] == 3000
+        assert attribution["model:model1"]["size_bytes"] == 950
+        assert attribution["model:model1"]["exclusive_bytes"] == 50
+
+    def test_owner_from_labels(self):
+        """Test that the first non-empty owner label wins"""
+        assert owner_from_labels({"owner": " ", "team": "ml"}, ["owner", "team"]) == "ml"
+        assert owner_from_labels({}, ["owner"]) == UNLABELED
+        assert owner_from_labels(None, ["owner"]) == UNKNOWN
+
+
+class TestPullEstimates:
+    """Tests for ImageAnalyzer.estimate_pull_times"""
+
+    def test_slowest_first_with_all_layers(self):
+        """Test that every layer counts toward a cold pull, shared or not, and images are sorted slowest first"""
+        analyzer = make_analyzer()
+        add_image(analyzer, "environment:env1", [("base", 50_000_000), ("env-a", 25_000_000)])
+        add_image(analyzer, "model:model1", [("base", 50_000_000)])
+
+        estimates = analyzer.estimate_pull_times(link_speed_mbps=100)
+
+        assert [estimate["image_id"] for estimate in estimates] == ["environment:env1", "model:model1"]
+        assert estimates[0]["pull_bytes"] == 75_000_000
+        assert estimates[0]["layer_count"] == 2
+        assert estimates[0]["largest_layer_bytes"] == 50_000_000
+        assert estimates[0]["estimated_seconds"] == 6.0
+        assert estimates[1]["estimated_seconds"] == 4.0
+
+
+class TestDigestKeyedInspection:
+    """Tests for incremental and fast (manifest-only) inspection"""
+
+    def setup_method(self):
+        """Set up an analyzer with a mocked registry client and an in-memory inspect cache"""
+        from unittest.mock import MagicMock
+
+        from utils.cache_utils import DigestInspectCache
+
+        self.analyzer = make_analyzer()
+        self.analyzer.inspect_cache = DigestInspectCache()
+        self.analyzer.skopeo_client = MagicMock()
+
+    def test_cached_digest_skips_inspection(self):
+        """Test that a tag whose digest is cached uses the cached layers"""
+        self.analyzer.inspect_cache.set("sha256:known", [{"Digest": "base", "Size": 5000}])
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:known"
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1", size_from_manifest=True)
+
+        assert result["source"] == "cache"
+        assert result["layers_data"] == [{"Digest": "base", "Size": 5000}]
+        self.analyzer.skopeo_client.get_manifest.assert_not_called()
+        self.analyzer.skopeo_client.inspect_image.assert_not_called()
+
+    def test_uncached_digest_sized_from_manifest(self):
+        """Test that an unknown digest is sized from the manifest's layer descriptors"""
+        manifest = {"layers": [{"digest": "base", "size": 5000}, {"digest": "top", "size": 10}]}
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:new"
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:new", manifest)
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1", size_from_manifest=True)
+
+        assert result["source"] == "manifest"
+        assert result["digest"] == "sha256:new"
+        assert [layer["Size"] for layer in result["layers_data"]] == [5000, 10]
+        self.analyzer.skopeo_client.inspect_image.assert_not_called()
+
+    def test_foreign_layers_marked_and_cached(self):
+        """Test that foreign layer media types are marked from inspections and manifests, and survive the cache"""
+        self.analyzer.skopeo_client.get_manifest_digest.side_effect = ["sha256:win", "sha256:win-fast"]
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:win",
+            "LayersData": [
+                {"Digest": "windows-base", "Size": 90000, "MIMEType": FOREIGN_LAYER_MEDIA_TYPES[0]},
+                {"Digest": "win-app", "Size": 200, "MIMEType": "application/vnd.docker.image.rootfs.diff.tar.gzip"},
+            ],
+        }
+        manifest = {"layers": [{"digest": "nd-base", "size": 500, "mediaType": FOREIGN_LAYER_MEDIA_TYPES[2]}]}
+
+        inspected = self.analyzer._inspect_single_tag_by_digest("environment", "win1")
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:win-fast", manifest)
+        fast = self.analyzer._inspect_single_tag_by_digest("environment", "win2", size_from_manifest=True)
+        self.analyzer._record_inspection(inspected)
+        self.analyzer._record_inspection(fast)
+
+        cached = self.analyzer.inspect_cache.get("sha256:win")
+        assert cached[0] == {
+            "Digest": "windows-base",
+            "Size": 90000,
+            "MediaType": FOREIGN_LAYER_MEDIA_TYPES[0],
+            "Foreign": True,
+        }
+        assert "Foreign" not in inspected["layers_data"][1]
+        assert self.analyzer.foreign_layers == {"windows-base", "nd-base"}
+        assert self.analyzer.layer_media_types["nd-base"] == FOREIGN_LAYER_MEDIA_TYPES[2]
+
+    def test_labels_read_and_reused_for_aliases(self):
+        """Test that image config labels are recorded and reused for alias tags of the same digest"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:labeled"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:labeled",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+            "Labels": {"owner": "data-science", "version": 2},
+        }
+
+        first = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        alias = self.analyzer._inspect_single_tag_by_digest("environment", "env1-alias")
+        self.analyzer._record_inspection(first)
+        self.analyzer._record_inspection(alias)
+
+        assert alias["source"] == "alias"
+        assert self.analyzer.labels["environment:env1-alias"] == {"owner": "data-science", "version": "2"}
+        assert self.analyzer.inspect_cache.get_labels("sha256:labeled") == {"owner": "data-science", "version": "2"}
+        assert self.analyzer.skopeo_client.inspect_image.call_count == 1
+
+    def test_manifest_list_falls_back_to_inspection(self):
+        """Test that multi-arch manifest lists get a full inspection"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:list"
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:list", {"manifests": []})
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:list",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1", size_from_manifest=True)
+
+        assert result["source"] == "inspect"
+        assert self.analyzer.inspect_cache.get("sha256:list") == [{"Digest": "base", "Size": 5000}]
+
+    def test_schema1_image_normalized_and_flagged(self):
+        """Test that a schema1 image is sized from its manifest and blobs and flagged as legacy"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:old"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:old",
+            "Layers": ["sha256:empty", "sha256:base", "sha256:empty"],
+            "LayersData": [
+                {"Digest": "sha256:empty", "Size": -1},
+                {"Digest": "sha256:base", "Size": -1},
+                {"Digest": "sha256:empty", "Size": -1},
+            ],
+        }
+        manifest = {
+            "schemaVersion": 1,
+            "fsLayers": [{"blobSum": "sha256:empty"}, {"blobSum": "sha256:top"}, {"blobSum": "sha256:base"}],
+            "history": [
+                {"v1Compatibility": '{"throwaway": true, "created": "2016-03-01T10:00:00Z"}'},
+                {"v1Compatibility": '{"Size": 70}'},
+                {"v1Compatibility": "{}"},
+            ],
+        }
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:old", manifest)
+        self.analyzer.skopeo_client.get_blob_size.side_effect = lambda repository, digest: (
+            5000 if digest == "sha256:base" else None
+        )
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        alias = self.analyzer._inspect_single_tag_by_digest("environment", "env1-latest", use_cache=False)
+        self.analyzer._record_inspection(result)
+
+        assert result["legacy_format"] == "schema1"
+        assert result["layers_data"] == [{"Digest": "sha256:base", "Size": 5000}, {"Digest": "sha256:top", "Size": 70}]
+        assert result["created"] == "2016-03-01T10:00:00Z"
+        assert alias["legacy_format"] == "schema1"
+        assert self.analyzer.legacy_formats == {"environment:env1": "schema1"}
+        assert self.analyzer.get_image_total_size("environment:env1") == 5070
+
+    def test_annotations_collected_and_cached(self):
+        """Test that manifest annotations are recorded, cached for aliases and fill in a missing creation time"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:oci"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:oci",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+        annotations = {
+            "org.opencontainers.image.created": "2025-06-01T08:00:00Z",
+            "org.opencontainers.image.source": "https://github.com/example/app",
+        }
+        self.analyzer.skopeo_client.get_manifest.return_value = (
+            "sha256:oci",
+            {"schemaVersion": 2, "mediaType": "application/vnd.oci.image.index.v1+json", "annotations": annotations},
+        )
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        alias = self.analyzer._inspect_single_tag_by_digest("environment", "env1-latest", use_cache=False)
+        self.analyzer._record_inspection(result)
+
+        assert result["annotations"] == annotations
+        assert alias["annotations"] == annotations
+        assert self.analyzer.annotations == {"environment:env1": annotations}
+        assert self.analyzer.created["environment:env1"] == "2025-06-01T08:00:00Z"
+
+    def test_incremental_scan_inspects_uncached_digest(self):
+        """Test that without size_from_manifest an unknown digest gets a full inspection"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:new"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:new",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+
+        assert result["source"] == "inspect"
+        self.analyzer.skopeo_client.inspect_image.assert_called_once()
+
+    def test_incremental_scan_reuses_cached_digest(self):
+        """Test that an unchanged digest is not inspected again"""
+        self.analyzer.inspect_cache.set("sha256:known", [{"Digest": "base", "Size": 5000}])
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:known"
+
+        result = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+
+        assert result["source"] == "cache"
+        self.analyzer.skopeo_client.get_manifest.assert_not_called()
+        self.analyzer.skopeo_client.inspect_image.assert_not_called()
+
+    def test_alias_tags_share_one_inspection(self):
+        """Test that tags pointing at the same digest are inspected once per run, even without the cache"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:shared"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:shared",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        first = self.analyzer._inspect_single_tag_by_digest("environment", "env1", use_cache=False)
+        second = self.analyzer._inspect_single_tag_by_digest("environment", "env1-latest", use_cache=False)
+
+        assert first["source"] == "inspect"
+        assert second["source"] == "alias"
+        assert second["layers_data"] == first["layers_data"]
+        self.analyzer.skopeo_client.inspect_image.assert_called_once()
+
+    def test_concurrent_alias_tags_wait_for_inspection(self):
+        """Test that alias tags inspected in parallel wait for the first inspection instead of repeating it"""
+        import threading
+        import time
+
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:shared"
+
+        def slow_inspect(repository, tag):
+            time.sleep(0.05)
+            return {"Digest": "sha256:shared", "LayersData": [{"Digest": "base", "Size": 5000}]}
+
+        self.analyzer.skopeo_client.inspect_image.side_effect = slow_inspect
+        results = []
+        threads = [
+            threading.Thread(
+                target=lambda t=tag: results.append(self.analyzer._inspect_single_tag_by_digest("environment", t))
+            )
+            for tag in ["a", "b", "c", "d"]
+        ]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join()
+
+        assert sorted(r["source"] for r in results) == ["alias", "alias", "alias", "inspect"]
+        self.analyzer.skopeo_client.inspect_image.assert_called_once()
+
+    def test_analyze_image_skips_signature_tags(self):
+        """Test that cosign signature, attestation and SBOM tags are not analyzed as images"""
+        self.analyzer.skopeo_client.list_tags.return_value = ["env1", "sha256-abc.sig", "sha256-abc.att", "env1.sbom"]
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:abc"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:abc",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        assert self.analyzer.analyze_image("environment", max_workers=1) is True
+
+        assert list(self.analyzer.images) == ["environment:env1"]
+
+    def test_reference_tags_attached_to_subject(self):
+        """Test that sha256-<digest>.<kind> tags are attached to the image with that digest"""
+        digest_hex = "a" * 64
+        self.analyzer.skopeo_client.list_tags.return_value = [
+            "env1",
+            f"sha256-{digest_hex}.sig",
+            f"sha256-{digest_hex}.att",
+            f"sha256-{'b' * 64}.sig",
+        ]
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = f"sha256:{digest_hex}"
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": f"sha256:{digest_hex}",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        assert self.analyzer.analyze_image("environment", max_workers=1) is True
+
+        assert list(self.analyzer.images) == ["environment:env1"]
+        artifacts = self.analyzer.attached_artifacts["environment:env1"]
+        assert [(a["tag"], a["kind"]) for a in artifacts] == [
+            (f"sha256-{digest_hex}.att", "att"),
+            (f"sha256-{digest_hex}.sig", "sig"),
+        ]
+        unused = self.analyzer.get_unused_images([])
+        assert len(unused[0]["attachedArtifacts"]) == 2
+
+    def test_provenance_read_from_attestation_tag(self):
+        """Test that SLSA provenance is read once per digest from an attached .att manifest"""
+        import json
+
+        add_image(self.analyzer, "environment:env1", [("base", 5000)], digest="sha256:built")
+        add_image(self.analyzer, "environment:env1-alias", [("base", 5000)], digest="sha256:built")
+        add_image(self.analyzer, "environment:env2", [("base", 5000)], digest="sha256:manual")
+        self.analyzer.attached_artifacts["environment:env1"] = [
+            {
+                "repository": "test-repo/environment",
+                "tag": "sha256-built.att",
+                "kind": "att",
+                "subject_digest": "sha256:built",
+            }
+        ]
+        statement = {
+            "predicateType": "https://slsa.dev/provenance/v0.2",
+            "predicate": {
+                "builder": {"id": "https://ci.example.com"},
+                "materials": [{"uri": "git+https://example/app"}],
+            },
+        }
+        self.analyzer.skopeo_client.get_referrers.return_value = None
+        self.analyzer.skopeo_client.get_manifest.return_value = (
+            "sha256:att",
+            {"layers": [{"mediaType": "application/vnd.dsse.envelope.v1+json", "digest": "sha256:statement"}]},
+        )
+        self.analyzer.skopeo_client.get_blob.return_value = json.dumps(statement).encode()
+
+        self.analyzer.collect_provenance("environment", max_workers=1)
+
+        assert self.analyzer.provenance["environment:env1"]["builder_id"] == "https://ci.example.com"
+        assert self.analyzer.provenance["environment:env1-alias"]["source"] == "git+https://example/app"
+        assert self.analyzer.provenance["environment:env2"] is None
+        self.analyzer.skopeo_client.get_manifest.assert_called_once_with("test-repo/environment", "sha256-built.att")
+
+    def test_tag_changes_since_last_scan(self):
+        """Test that each tag's digest is compared with the previous scan's snapshot"""
+        self.analyzer.inspect_cache.set("sha256:v1", [{"Digest": "base", "Size": 5000}])
+        self.analyzer.inspect_cache.set("sha256:v2", [{"Digest": "base", "Size": 5000}])
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:v1"
+
+        first = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        second = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:v2"
+        third = self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+
+        assert [first["change"], second["change"], third["change"]] == ["new", "unchanged", "changed"]
+
+    def test_inspect_cache_persists_between_runs(self):
+        """Test that the inspect cache is saved to and reloaded from disk"""
+        import tempfile
+
+        from utils.cache_utils import DigestInspectCache
+
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = os.path.join(tmpdir, "cache", "inspect-cache.json")
+            cache = DigestInspectCache(path)
+            cache.set("sha256:aaa", [{"Digest": "base", "Size": 5000, "MIMEType": "ignored"}], "2024-01-01T00:00:00Z")
+            cache.save()
+
+            reloaded = DigestInspectCache(path)
+
+        assert reloaded.get("sha256:aaa") == [{"Digest": "base", "Size": 5000}]
+        assert reloaded.get_created("sha256:aaa") == "2024-01-01T00:00:00Z"
+        assert reloaded.size() == 1
+
+    def test_failed_tags_recorded_with_error_code(self):
+        """Test that tags that cannot be inspected are recorded with a code, and cleared once inspected"""
+        import subprocess
+
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:new"
+        self.analyzer.skopeo_client.inspect_image.side_effect = subprocess.TimeoutExpired(["skopeo"], 30)
+        assert self.analyzer._inspect_single_tag_by_digest("environment", "env1") is None
+
+        self.analyzer.skopeo_client.inspect_image.side_effect = None
+        self.analyzer.skopeo_client.inspect_image.return_value = None
+        self.analyzer.skopeo_client.last_error_code.return_value = "AUTH"
+        assert self.analyzer._inspect_single_tag_by_digest("environment", "env2") is None
+
+        assert self.analyzer.failures["environment:env1"]["code"] == "TIMEOUT"
+        assert self.analyzer.failures["environment:env1"]["retryable"] is True
+        assert self.analyzer.failures["environment:env2"] == {
+            "code": "AUTH",
+            "retryable": False,
+            "message": "inspection failed",
+        }
+
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:new",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+        self.analyzer._record_inspection(self.analyzer._inspect_single_tag_by_digest("environment", "env1"))
+        assert list(self.analyzer.failures) == ["environment:env2"]
+
+    def test_retry_failed_tags_second_pass(self):
+        """Test that only retryable failures are inspected again, and are cleared when they succeed"""
+        import subprocess
+
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:new"
+        self.analyzer.skopeo_client.inspect_image.side_effect = subprocess.TimeoutExpired(["skopeo"], 30)
+        self.analyzer._inspect_single_tag_by_digest("environment", "env1")
+        self.analyzer._record_failure("environment", "env2", "inspection failed", "AUTH")
+
+        self.analyzer.skopeo_client.inspect_image.side_effect = None
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:new",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+        self.analyzer.skopeo_client.inspect_image.reset_mock()
+
+        assert self.analyzer.retry_failed_tags(max_workers=2) == 1
+        self.analyzer.skopeo_client.inspect_image.assert_called_once_with("test-repo/environment", "env1")
+        assert list(self.analyzer.failures) == ["environment:env2"]
+        assert self.analyzer.retry_failed_tags(max_workers=2) == 0
+
+    def test_sizes_only_uses_one_manifest_request(self):
+        """Test that sizes-only mode sizes a tag from its manifest without resolving or inspecting it"""
+        manifest = {
+            "schemaVersion": 2,
+            "layers": [{"digest": "base", "size": 5000, "mediaType": "application/vnd.oci.image.layer.v1.tar+gzip"}],
+        }
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:m1", manifest)
+
+        result = self.analyzer._inspect_tag_sizes_only("environment", "env1")
+
+        assert result["source"] == "manifest"
+        assert result["digest"] == "sha256:m1"
+        assert [(layer["Digest"], layer["Size"]) for layer in result["layers_data"]] == [("base", 5000)]
+        assert result["labels"] is None and result["created"] is None
+        self.analyzer.skopeo_client.get_manifest_digest.assert_not_called()
+        self.analyzer.skopeo_client.inspect_image.assert_not_called()
+
+    def test_sizes_only_inspects_manifest_lists(self):
+        """Test that a manifest list, which has no layers of its own, is fully inspected in sizes-only mode"""
+        self.analyzer.skopeo_client.get_manifest.return_value = ("sha256:index", {"manifests": []})
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:index",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+        }
+
+        result = self.analyzer._inspect_tag_sizes_only("environment", "env1")
+
+        assert result["source"] == "inspect"
+        self.analyzer.skopeo_client.inspect_image.assert_called_once()
+
+    def test_deep_scan_reads_each_config_once(self):
+        """Test that a deep scan reads one config per digest and records it for every tag of the digest"""
+        add_image(self.analyzer, "environment:env1", [("base", 5000)], digest="sha256:same")
+        add_image(self.analyzer, "environment:env2", [("base", 5000)], digest="sha256:same")
+        self.analyzer.skopeo_client.get_image_config.return_value = {
+            "config": {"User": "domino"},
+            "rootfs": {"diff_ids": ["sha256:d1"]},
+        }
+
+        self.analyzer.collect_config_details("environment", max_workers=2)
+
+        self.analyzer.skopeo_client.get_image_config.assert_called_once()
+        assert self.analyzer.config_details["environment:env1"]["user"] == "domino"
+        assert self.analyzer.config_details["environment:env2"] is self.analyzer.config_details["environment:env1"]
+        assert self.analyzer.collect_layer_diff_ids(max_workers=2) == {"base": "sha256:d1"}
+        self.analyzer.skopeo_client.get_image_config.assert_called_once()
+
+    def test_secrets_found_before_redaction_and_cached(self):
+        """Test that Env and label secrets are found on inspection and reused for cached digests"""
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = "sha256:leaky"
+        self.analyzer.skopeo_client.get_manifest.return_value = None
+        self.analyzer.skopeo_client.inspect_image.return_value = {
+            "Digest": "sha256:leaky",
+            "LayersData": [{"Digest": "base", "Size": 5000}],
+            "Env": ["PATH=/usr/bin", "API_TOKEN=ghp_" + "a" * 36],
+            "Labels": {"owner": "ml"},
+        }
+
+        self.analyzer._record_inspection(self.analyzer._inspect_single_tag_by_digest("environment", "env1"))
+        self.analyzer._record_inspection(self.analyzer._inspect_single_tag_by_digest("environment", "env2"))
+
+        self.analyzer.skopeo_client.inspect_image.assert_called_once()
+        assert [f["rule"] for f in self.analyzer.secret_findings["environment:env1"]] == ["github_token"]
+        assert self.analyzer.secret_findings["environment:env2"] == self.analyzer.secret_findings["environment:env1"]
+        assert self.analyzer.inspect_cache.get_secret_findings("sha256:leaky")[0]["key"] == "API_TOKEN"
+
+
+class TestAnalyzeImagesPipeline:
+    """Tests for listing and inspecting several repositories as one pipeline"""
+
+    def setup_method(self):
+        """Set up an analyzer whose registry client serves two repositories"""
+        from unittest.mock import MagicMock
+
+        from utils.cache_utils import DigestInspectCache
+
+        self.analyzer = make_analyzer()
+        self.analyzer.inspect_cache = DigestInspectCache()
+        self.analyzer.skopeo_client = MagicMock()
+        self.analyzer.skopeo_client.get_manifest_digest.return_value = None
+        self.analyzer.skopeo_client.get_manifest.return_value = None
+        self.analyzer.skopeo_client.inspect_image.side_effect = lambda repository, tag: {
+            "Digest": f"sha256:{tag}",
+            "LayersData": [{"Digest": f"layer-{tag}", "Size": 100}],
+        }
+
+    def test_inspection_overlaps_slow_listing(self):
+        """Test that one repository's tags are inspected while another repository is still being listed"""
+        import threading
+
+        model_inspected = threading.Event()
+
+        def list_tags(repository):
+            if repository.endswith("/environment"):
+                assert model_inspected.wait(timeout=5), "environment listing blocked model inspection"
+                return ["env1", "env2"]
+            return ["model1"]
+
+        def inspect_image(repository, tag):
+            if tag == "model1":
+                model_inspected.set()
+            return {"Digest": f"sha256:{tag}", "LayersData": [{"Digest": f"layer-{tag}", "Size": 100}]}
+
+        self.analyzer.skopeo_client.list_tags.side_effect = list_tags
+        self.analyzer.skopeo_client.inspect_image.side_effect = inspect_image
+
+        assert self.analyzer.analyze_images(["environment", "model"], max_workers=2) == ["environment", "model"]
+        assert sorted(self.analyzer.images) == ["environment:env1", "environment:env2", "model:model1"]
+
+    def test_failed_listing_does_not_stop_other_repositories(self):
+        """Test that a repository whose tags cannot be listed is left out of the analyzed types"""
+
+        def list_tags(repository):
+            if repository.endswith("/environment"):
+                raise RuntimeError("registry unavailable")
+            return ["model1", "buildcache"]
+
+        self.analyzer.skopeo_client.list_tags.side_effect = list_tags
+
+        assert self.analyzer.analyze_images(["environment", "model"], max_workers=2) == ["model"]
+        assert list(self.analyzer.images) == ["model:model1"]
+
+    def test_object_id_filter_per_repository(self):
+        """Test that ObjectID filters apply to their own repository only"""
+        env_id = "507f1f77bcf86cd799439011"
+        self.analyzer.skopeo_client.list_tags.side_effect = lambda repository: (
+            [f"{env_id}-1", "507f191e810c19729de860ea-1"] if repository.endswith("/environment") else ["model1"]
+        )
+
+        analyzed = self.analyzer.analyze_images(["environment", "model"], {"environment": [env_id]}, max_workers=2)
+
+        assert analyzed == ["environment", "model"]
+        assert sorted(self.analyzer.images) == [f"environment:{env_id}-1", "model:model1"]
+
+    def test_results_recorded_in_listing_order(self):
+        """Test that inspections finishing out of order are recorded as a one-worker scan records them"""
+        import threading
+
+        later_done = threading.Event()
+        finished = []
+
+        def inspect_image(repository, tag):
+            if tag == "env1":
+                assert later_done.wait(timeout=5), "env1 was not held back"
+            finished.append(tag)
+            if len(finished) == 2:
+                later_done.set()
+            return {"Digest": f"sha256:{tag}", "LayersData": [{"Digest": f"layer-{tag}", "Size": 100}]}
+
+        self.analyzer.skopeo_client.list_tags.side_effect = lambda repository: (
+            ["env1", "env2"] if repository.endswith("/environment") else ["model1"]
+        )
+        self.analyzer.skopeo_client.inspect_image.side_effect = inspect_image
+
+        assert self.analyzer.analyze_images(["environment", "model"], max_workers=3) == ["environment", "model"]
+        assert finished[-1] == "env1"
+        assert list(self.analyzer.images) == ["environment:env1", "environment:env2", "model:model1"]
+        assert list(self.analyzer.layers) == ["layer-env1", "layer-env2", "layer-model1"]
+
+
+class TestRegistryEventUpdates:
+    """Tests for updating the index one tag at a time, as registry notifications do"""
+
+    def setup_method(self):
+        """Set up an analyzer whose registry client inspects tags by digest"""
+        from unittest.mock import MagicMock
+
+        from utils.cache_utils import DigestInspectCache
+
+        self.analyzer = make_analyzer()
+        self.analyzer.inspect_cache = DigestInspectCache()
+        self.analyzer.skopeo_client = MagicMock()
+        self.analyzer.skopeo_client.get_manifest_digest.side_effect = lambda repository, tag: f"sha256:{tag}-v2"
+        self.analyzer.skopeo_client.inspect_image.side_effect = lambda repository, tag: {
+            "Digest": f"sha256:{tag}-v2",
+            "LayersData": [{"Digest": "base", "Size": 1000}, {"Digest": f"layer-{tag}-v2", "Size": 10}],
+        }
+
+    def test_refresh_replaces_repushed_tag(self):
+        """Test that refreshing a re-pushed tag replaces its digest, layers and per-image results"""
+        add_image(self.analyzer, "environment:env1", [("base", 1000), ("layer-env1", 5)], digest="sha256:env1")
+        self.analyzer.labels["environment:env1"] = {"stale": "label"}
+
+        assert self.analyzer.refresh_tag("environment", "env1")
+
+        assert self.analyzer.images["environment:env1"]["digest"] == "sha256:env1-v2"
+        assert "layer-env1" not in self.analyzer.layers
+        assert self.analyzer.layers["base"]["ref_count"] == 1
+        assert self.analyzer.labels.get("environment:env1", {}) == {}
+
+    def test_forget_images_drops_unshared_layers(self):
+        """Test that forgetting an image drops the layers only it referenced and its per-image results"""
+        add_image(self.analyzer, "environment:env1", [("base", 1000), ("only1", 5)])
+        add_image(self.analyzer, "environment:env2", [("base", 1000)])
+        self.analyzer.created["environment:env1"] = "2026-01-01T00:00:00Z"
+
+        assert self.analyzer.forget_images(["environment:env1", "environment:missing"]) == 1
+
+        assert list(self.analyzer.images) == ["environment:env2"]
+        assert set(self.analyzer.layers) == {"base"}
+        assert self.analyzer.created == {}
+
+    def test_is_scanned_tag(self):
+        """Test that buildcache and reference tags are not indexed"""
+        assert self.analyzer.is_scanned_tag("507f1f77bcf86cd799439011-3")
+        assert not self.analyzer.is_scanned_tag("buildcache")
+        assert not self.analyzer.is_scanned_tag("sha256-" + "a" * 64 + ".sig")
@@ -0,0 +1,67 @@
+"""Unit tests for utils/image_config.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_config import config_details
+from utils.redaction import REDACTED
+
+CONFIG = {
+    "architecture": "amd64",
+    "config": {
+        "User": "domino",
+        "Entrypoint": ["/opt/entrypoint.sh"],
+        "Cmd": ["bash"],
+        "WorkingDir": "/mnt",
+        "Env": ["PATH=/usr/bin", "API_TOKEN=abc123"],
+    },
+    "rootfs": {"type": "layers", "diff_ids": ["sha256:d1", "sha256:d2"]},
+    "history": [
+        {"created": "2024-05-01T12:00:00Z", "created_by": "/bin/sh -c #(nop) ADD file:abc in /"},
+        {"created": "2024-05-01T12:01:00Z", "created_by": "ENV LANG=C.UTF-8", "empty_layer": True},
+        {"created": "2024-05-01T12:02:00Z", "created_by": "RUN pip install numpy", "comment": "buildkit"},
+    ],
+}
+
+
+class TestConfigDetails:
+    """Tests for extracting deep-scan fields from an image config"""
+
+    def test_runtime_fields_and_diff_ids(self):
+        """Test that user, entrypoint, cmd, working dir and diffIDs are kept"""
+        details = config_details(CONFIG, [])
+
+        assert details["user"] == "domino"
+        assert details["entrypoint"] == ["/opt/entrypoint.sh"]
+        assert details["cmd"] == ["bash"]
+        assert details["working_dir"] == "/mnt"
+        assert details["diff_ids"] == ["sha256:d1", "sha256:d2"]
+
+    def test_history(self):
+        """Test that every build step is kept, with metadata-only steps flagged"""
+        history = config_details(CONFIG, [])["history"]
+
+        assert [entry["created_by"] for entry in history] == [
+            "/bin/sh -c #(nop) ADD file:abc in /",
+            "ENV LANG=C.UTF-8",
+            "RUN pip install numpy",
+        ]
+        assert [entry["empty_layer"] for entry in history] == [False, True, False]
+        assert history[2]["comment"] == "buildkit"
+
+    def test_sensitive_env_values_redacted(self):
+        """Test that Env values of names matching the redaction patterns are replaced"""
+        details = config_details(CONFIG, ["*token*"])
+
+        assert details["env"] == ["PATH=/usr/bin", f"API_TOKEN={REDACTED}"]
+
+    def test_missing_fields(self):
+        """Test a config without runtime settings or history, and something that is not a config"""
+        details = config_details({"rootfs": {"diff_ids": ["sha256:d1"]}}, [])
+
+        assert details["history"] == []
+        assert details["user"] is None and details["entrypoint"] is None and details["env"] is None
+        assert config_details(None, []) is None
+        assert config_details(["not", "a", "config"], []) is None
@@ -0,0 +1,62 @@
+"""Unit tests for utils/image_expiry.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_expiry import DEFAULT_EXPIRY_KEYS, image_expiry, parse_expiry
+
+NOW = datetime(2025, 1, 15, tzinfo=timezone.utc)
+CREATED = "2025-01-01T00:00:00Z"
+
+
+class TestParseExpiry:
+    """Tests for reading expiry values"""
+
+    def test_durations_count_from_creation(self):
+        """Test each duration unit, relative to the creation time"""
+        assert parse_expiry("2w", CREATED) == datetime(2025, 1, 15, tzinfo=timezone.utc)
+        assert parse_expiry("36h", CREATED) == datetime(2025, 1, 2, 12, tzinfo=timezone.utc)
+        assert parse_expiry("3d", CREATED) == datetime(2025, 1, 4, tzinfo=timezone.utc)
+        assert parse_expiry("90m", CREATED) == datetime(2025, 1, 1, 1, 30, tzinfo=timezone.utc)
+
+    def test_duration_without_creation_time_is_unknown(self):
+        """Test that a relative expiry cannot be placed without a creation time"""
+        assert parse_expiry("2w", None) is None
+
+    def test_absolute_dates(self):
+        """Test ISO dates and times, taken as UTC without a zone"""
+        assert parse_expiry("2025-06-30", None) == datetime(2025, 6, 30, tzinfo=timezone.utc)
+        assert parse_expiry("2025-06-30T12:00:00+02:00", CREATED) == datetime(2025, 6, 30, 10, tzinfo=timezone.utc)
+        assert parse_expiry("next tuesday", CREATED) is None
+
+
+class TestImageExpiry:
+    """Tests for finding an image's expiry in its annotations and labels"""
+
+    def test_annotation_before_label_and_key_order(self):
+        """Test that keys are tried in order and annotations win over labels for the same key"""
+        annotations = {"com.dominodatalab.expires-after": "2025-02-01"}
+        labels = {"quay.expires-after": "1w", "com.dominodatalab.expires-after": "2024-01-01"}
+
+        expiry = image_expiry(annotations, labels, CREATED, DEFAULT_EXPIRY_KEYS, NOW)
+        assert expiry["key"] == "quay.expires-after"
+        assert expiry["source"] == "label"
+        assert expiry["expired"]
+
+        expiry = image_expiry(annotations, labels, CREATED, ["com.dominodatalab.expires-after"], NOW)
+        assert expiry["source"] == "annotation"
+        assert expiry["expires_at"] == "2025-02-01T00:00:00+00:00"
+        assert not expiry["expired"]
+
+    def test_no_expiry(self):
+        """Test images without the keys, with unknown metadata, or with an unparseable value"""
+        assert image_expiry({}, {"owner": "ml"}, CREATED, DEFAULT_EXPIRY_KEYS, NOW) is None
+        assert image_expiry(None, None, CREATED, DEFAULT_EXPIRY_KEYS, NOW) is None
+        assert image_expiry(None, {"quay.expires-after": "1w"}, CREATED, [], NOW) is None
+
+        expiry = image_expiry(None, {"quay.expires-after": "soon"}, CREATED, DEFAULT_EXPIRY_KEYS, NOW)
+        assert expiry["expires_at"] is None
+        assert not expiry["expired"]
@@ -0,0 +1,188 @@
+"""Unit tests for utils/image_index.py"""
+
+import os
+import sys
+import threading
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_index import ImageIndex, InMemoryImageIndex, SqliteImageIndex
+
+
+def _add(index: ImageIndex, image_id: str, layers: list, digest: str = None):
+    """Add an image with the given (layer_id, size_bytes) layers to the index"""
+    image_type, tag = image_id.split(":", 1)
+    index.add_image(image_id, f"test-repo/{image_type}", tag, digest or f"sha256:{image_id}", layers)
+
+
+class TestImageIndexWrites:
+    """Tests for adding and removing images"""
+
+    def test_add_image_counts_shared_layers(self):
+        """Test that a layer used by two images has ref_count 2"""
+        index = InMemoryImageIndex()
+        _add(index, "environment:tag1", [("base", 1000), ("a", 10)])
+        _add(index, "environment:tag2", [("base", 1000), ("b", 20)])
+
+        assert len(index) == 2
+        assert index.layers["base"] == {"size_bytes": 1000, "ref_count": 2}
+        assert index.layers["a"]["ref_count"] == 1
+        assert len(index.image_layers) == 4
+
+    def test_add_image_preserves_layer_order(self):
+        """Test that order_index follows the order layers were given in"""
+        index = InMemoryImageIndex()
+        _add(index, "environment:tag1", [("l1", 1), ("l2", 2), ("l3", 3)])
+
+        ordered = [(m["layer_id"], m["order_index"]) for m in index.image_layers]
+        assert ordered == [("l1", 0), ("l2", 1), ("l3", 2)]
+
+    def test_re_adding_image_replaces_it(self):
+        """Test that recording the same image twice does not double-count its layers"""
+        index = InMemoryImageIndex()
+        _add(index, "environment:tag1", [("base", 1000), ("old", 10)])
+        _add(index, "environment:tag1", [("base", 1000), ("new", 20)], digest="sha256:repushed")
+
+        assert index.layers["base"]["ref_count"] == 1
+        assert "old" not in index.layers
+        assert index.images["environment:tag1"]["digest"] == "sha256:repushed"
+        assert len(index.image_layers) == 2
+
+    def test_remove_image_drops_unreferenced_layers(self):
+        """Test that removing an image releases its references"""
+        index = InMemoryImageIndex()
+        _add(index, "environment:tag1", [("base", 1000), ("a", 10)])
+        _add(index, "environment:tag2", [("base", 1000)])
+
+        assert index.remove_image("environment:tag1") is True
+        assert index.remove_image("environment:tag1") is False
+        assert index.layers == {"base": {"size_bytes": 1000, "ref_count": 1}}
+        assert [m["image_id"] for m in index.image_layers] == ["environment:tag2"]
+
+
+class TestImageIndexConcurrency:
+    """Tests for concurrent use of the index"""
+
+    def test_concurrent_adds_keep_ref_counts_consistent(self):
+        """Test that many threads recording images sharing a layer produce exact counts"""
+        index = InMemoryImageIndex()
+        threads = [
+            threading.Thread(target=_add, args=(index, f"environment:tag{i}", [("base", 1000), (f"own{i}", i)]))
+            for i in range(50)
+        ]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join()
+
+        assert len(index) == 50
+        assert index.layers["base"]["ref_count"] == 50
+        assert len(index.image_layers) == 100
+
+    def test_snapshot_is_independent(self):
+        """Test that a snapshot is not affected by later writes"""
+        index = InMemoryImageIndex()
+        _add(index, "environment:tag1", [("base", 1000)])
+
+        layers, images, image_layers = index.snapshot()
+        _add(index, "environment:tag2", [("base", 1000)])
+
+        assert layers["base"]["ref_count"] == 1
+        assert list(images) == ["environment:tag1"]
+        assert len(image_layers) == 1
+
+
+class TestSqliteImageIndex:
+    """Tests for the on-disk index backend"""
+
+    def setup_method(self):
+        """Create a temporary on-disk index"""
+        self.index = SqliteImageIndex()
+
+    def teardown_method(self):
+        """Close and remove the temporary database"""
+        self.index.close()
+
+    def test_views_behave_like_in_memory_maps(self):
+        """Test that layers/images/image_layers read like the in-memory structures"""
+        _add(self.index, "environment:tag1", [("base", 1000), ("a", 10)])
+        _add(self.index, "environment:tag2", [("base", 1000), ("b", 20)])
+
+        assert len(self.index) == 2
+        assert self.index.layers["base"] == {"size_bytes": 1000, "ref_count": 2}
+        assert "a" in self.index.layers
+        assert self.index.layers.get("missing") is None
+        assert self.index.images["environment:tag2"]["tag"] == "tag2"
+        assert dict(self.index.layers.items())["b"]["ref_count"] == 1
+        assert [(m["image_id"], m["layer_id"]) for m in self.index.image_layers] == [
+            ("environment:tag1", "base"),
+            ("environment:tag1", "a"),
+            ("environment:tag2", "base"),
+            ("environment:tag2", "b"),
+        ]
+
+    def test_re_adding_and_removing_images(self):
+        """Test that replace and remove keep reference counts exact"""
+        _add(self.index, "environment:tag1", [("base", 1000), ("old", 10)])
+        _add(self.index, "environment:tag2", [("base", 1000)])
+        _add(self.index, "environment:tag1", [("base", 1000), ("new", 20)])
+
+        assert self.index.layers["base"]["ref_count"] == 2
+        assert "old" not in self.index.layers
+
+        assert self.index.remove_image("environment:tag2") is True
+        assert self.index.layers["base"]["ref_count"] == 1
+        assert len(self.index.image_layers) == 2
+
+    def test_copy_from_in_memory_index(self):
+        """Test that migrating from the in-memory index preserves everything"""
+        source = InMemoryImageIndex()
+        _add(source, "environment:tag1", [("base", 1000), ("a", 10)])
+        _add(source, "model:m1", [("base", 1000)])
+
+        self.index.copy_from(source)
+
+        assert self.index.snapshot() == source.snapshot()
+
+    def test_close_removes_temporary_database(self):
+        """Test that closing a temporary index deletes its file"""
+        path = self.index.path
+        assert os.path.exists(path)
+
+        self.index.close()
+
+        assert not os.path.exists(path)
+
+
+class TestAnalyzerIndexSelection:
+    """Tests for ImageAnalyzer switching to the on-disk index"""
+
+    def test_switches_above_threshold(self, monkeypatch):
+        """Test that the analyzer moves existing data to SQLite when the tag count exceeds the threshold"""
+        from utils.config_manager import config_manager
+        from utils.image_data_analysis import ImageAnalyzer
+
+        monkeypatch.setattr(config_manager, "get_disk_index_threshold", lambda: 2)
+        analyzer = ImageAnalyzer("http://test-registry", "test-repo")
+        _add(analyzer.index, "environment:tag1", [("base", 1000)])
+
+        analyzer._ensure_index_capacity(1)
+        assert isinstance(analyzer.index, InMemoryImageIndex)
+
+        analyzer._ensure_index_capacity(2)
+        assert isinstance(analyzer.index, SqliteImageIndex)
+        assert analyzer.layers["base"]["ref_count"] == 1
+        assert analyzer.freed_space_if_deleted(["environment:tag1"]) == 1000
+        analyzer.index.close()
+
+    def test_disabled_with_zero_threshold(self, monkeypatch):
+        """Test that a threshold of 0 keeps the in-memory index"""
+        from utils.config_manager import config_manager
+        from utils.image_data_analysis import ImageAnalyzer
+
+        monkeypatch.setattr(config_manager, "get_disk_index_threshold", lambda: 0)
+        analyzer = ImageAnalyzer("http://test-registry", "test-repo")
+
+        analyzer._ensure_index_capacity(10_000_000)
+
+        assert isinstance(analyzer.index, InMemoryImageIndex)
@@ -0,0 +1,107 @@
+"""Unit tests for utils/image_metadata.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_metadata import (
+    DEPLOYMENT_NOT_DEPLOYED,
+    DEPLOYMENT_RUNNING,
+    DEPLOYMENT_STOPPED,
+    REVISION_ENVIRONMENT_ARCHIVED,
+    REVISION_NO_RECORD,
+    REVISION_NOT_BUILT,
+    REVISION_SELECTABLE,
+    map_environment_revisions,
+    map_model_versions,
+)
+
+ENV_ID = "507f1f77bcf86cd799439011"
+ARCHIVED_ENV_ID = "507f1f77bcf86cd799439012"
+MODEL_ID = "607f1f77bcf86cd799439011"
+
+
+def revision(rev_id, env_id, tag, number, built=True):
+    """environment_revisions document"""
+    return {
+        "_id": rev_id,
+        "environmentId": env_id,
+        "metadata": {"number": number, "isBuilt": built, "dockerImageName": {"tag": tag}},
+    }
+
+
+class TestMapEnvironmentRevisions:
+    """Tests for mapping environment tags to Domino environment revisions"""
+
+    def test_statuses(self):
+        """Test that revisions are selectable only when built and their environment is not archived"""
+        revisions = [
+            revision("rev1", ENV_ID, f"{ENV_ID}-1", 1, built=False),
+            revision("rev2", ENV_ID, f"{ENV_ID}-2", 2),
+            revision("rev3", ARCHIVED_ENV_ID, f"{ARCHIVED_ENV_ID}-1", 1),
+        ]
+        environments = [
+            {"_id": ENV_ID, "name": "Python 3.11", "isArchived": False, "activeRevisionId": "rev2"},
+            {"_id": ARCHIVED_ENV_ID, "name": "Old R", "isArchived": True},
+        ]
+        tags = [f"{ENV_ID}-1", f"{ENV_ID}-2", f"{ENV_ID}-7", f"{ARCHIVED_ENV_ID}-1", "latest"]
+
+        mapping = map_environment_revisions(tags, revisions, environments)
+
+        assert sorted(mapping) == sorted(tags[:4])
+        assert mapping[f"{ENV_ID}-1"]["status"] == REVISION_NOT_BUILT
+        assert mapping[f"{ENV_ID}-2"] == {
+            "environment_id": ENV_ID,
+            "environment_name": "Python 3.11",
+            "revision": 2,
+            "revision_id": "rev2",
+            "status": REVISION_SELECTABLE,
+            "selectable": True,
+            "active": True,
+        }
+        assert mapping[f"{ENV_ID}-7"]["status"] == REVISION_NO_RECORD
+        assert mapping[f"{ENV_ID}-7"]["revision"] == 7
+        assert mapping[f"{ARCHIVED_ENV_ID}-1"]["status"] == REVISION_ENVIRONMENT_ARCHIVED
+        assert not mapping[f"{ARCHIVED_ENV_ID}-1"]["selectable"]
+
+
+def model_version(version_id, number):
+    """model_versions document whose build pushed <MODEL_ID>-v<number>"""
+    return {
+        "_id": version_id,
+        "modelId": {"value": MODEL_ID},
+        "metadata": {"number": number, "builds": [{"slug": {"image": {"tag": f"{MODEL_ID}-v{number}"}}}]},
+    }
+
+
+class TestMapModelVersions:
+    """Tests for mapping model tags to Domino model versions"""
+
+    def test_deployment_status(self):
+        """Test that only versions whose latest deployment saga started them are pinned"""
+        versions = [model_version("ver1", 1), model_version("ver2", 2), model_version("ver3", 3)]
+        models = [{"_id": MODEL_ID, "name": "churn", "isArchived": False}]
+        sagas = [  # Newest first
+            {"parameters": {"modelVersionId": "ver1"}, "sagaName": "StopModelVersion"},
+            {"parameters": {"modelVersionId": "ver2"}, "sagaName": "StartModelVersion"},
+            {"parameters": {"modelVersionId": "ver2"}, "sagaName": "StopModelVersion"},
+            {"parameters": {"modelVersionId": "ver1"}, "sagaName": "ModelVersionDeployment"},
+        ]
+        tags = [f"{MODEL_ID}-v1-1700000000_abc", f"{MODEL_ID}-v2", f"{MODEL_ID}-v3", "latest"]
+
+        mapping = map_model_versions(tags, versions, models, sagas)
+
+        assert sorted(mapping) == sorted(tags[:3])
+        assert mapping[f"{MODEL_ID}-v1-1700000000_abc"]["deployment_status"] == DEPLOYMENT_STOPPED
+        assert mapping[f"{MODEL_ID}-v2"] == {
+            "model_id": MODEL_ID,
+            "model_name": "churn",
+            "model_archived": False,
+            "version_id": "ver2",
+            "version": 2,
+            "deployment_status": DEPLOYMENT_RUNNING,
+            "pinned": True,
+        }
+        assert mapping[f"{MODEL_ID}-v3"]["deployment_status"] == DEPLOYMENT_NOT_DEPLOYED
+        assert not mapping[f"{MODEL_ID}-v3"]["pinned"]
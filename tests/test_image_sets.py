@@ -0,0 +1,124 @@
+"""Unit tests for utils/image_sets.py"""
+
+import json
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_sets import SelectionFormatError, combine, load_selection, parse_reference
+
+DIGEST_A = "sha256:" + "a" * 64
+DIGEST_B = "sha256:" + "b" * 64
+
+
+def _write(tmp_path, name: str, content) -> str:
+    """Write a file (JSON for dicts) and return its path"""
+    path = tmp_path / name
+    path.write_text(json.dumps(content) if isinstance(content, dict) else content)
+    return str(path)
+
+
+class TestLoadSelection:
+    """Tests for reading the images of saved selections"""
+
+    def test_snapshot_plan_and_candidates_report(self, tmp_path):
+        """Test that snapshots, plans and candidates reports are recognized by their content"""
+        snapshot = _write(
+            tmp_path,
+            "snapshot.json",
+            {
+                "format_version": 1,
+                "images": {"environment:v1": {"repository": "domino/environment", "tag": "v1", "digest": DIGEST_A}},
+                "layers": {},
+            },
+        )
+        plan = _write(
+            tmp_path,
+            "plan.json",
+            {"policy": {}, "items": [{"image_id": "model:m1", "repository": "domino/model", "tag": "m1"}]},
+        )
+        report = _write(
+            tmp_path,
+            "candidates.json",
+            {
+                "candidates": [{"image_id": "environment:v1", "repository": "domino/environment", "tag": "v1"}],
+                "protected": [{"image_id": "model:m1", "repository": "domino/model", "tag": "m1"}],
+            },
+        )
+
+        assert load_selection(snapshot) == [
+            {"image_id": "environment:v1", "repository": "domino/environment", "tag": "v1", "digest": DIGEST_A}
+        ]
+        assert [m["image_id"] for m in load_selection(plan)] == ["model:m1"]
+        assert [m["image_id"] for m in load_selection(report)] == ["environment:v1"]
+        assert [m["image_id"] for m in load_selection(report + "#protected")] == ["model:m1"]
+
+    def test_configmap_and_text_file(self, tmp_path):
+        """Test that the protected images ConfigMap and candidate files are read"""
+        images = [{"image": "registry:5000/domino/environment:v1", "digest": DIGEST_A, "protected_by": []}]
+        configmap = _write(
+            tmp_path,
+            "protected.yaml",
+            f"apiVersion: v1\nkind: ConfigMap\ndata:\n  images.json: '{json.dumps(images)}'\n",
+        )
+        listing = _write(tmp_path, "list.txt", f"# reviewed\nenvironment:v1  keep\n\ndomino/model:m1@{DIGEST_B}\n")
+
+        assert [(m["image_id"], m["digest"]) for m in load_selection(configmap)] == [("environment:v1", DIGEST_A)]
+        assert [(m["image_id"], m["digest"]) for m in load_selection(listing)] == [
+            ("environment:v1", ""),
+            ("model:m1", DIGEST_B),
+        ]
+
+    def test_unrecognized_files(self, tmp_path):
+        """Test that reports without a list of images and references without a tag are rejected"""
+        with pytest.raises(SelectionFormatError, match="FILE#KEY"):
+            load_selection(_write(tmp_path, "other.json", {"summary": {}}))
+        with pytest.raises(SelectionFormatError):
+            parse_reference("62798b9bee0eb12322fc97e8-3")
+
+
+class TestCombine:
+    """Tests for set operations"""
+
+    def setup_method(self):
+        """Set up two selections sharing one image and one manifest under another tag"""
+        self.a = [
+            {"image_id": "environment:v1", "repository": "environment", "tag": "v1", "digest": DIGEST_A},
+            {"image_id": "environment:v2", "repository": "environment", "tag": "v2", "digest": DIGEST_B},
+        ]
+        self.b = [
+            {"image_id": "environment:v2", "repository": "environment", "tag": "v2", "digest": DIGEST_B},
+            {"image_id": "environment:v1-alias", "repository": "environment", "tag": "v1-alias", "digest": DIGEST_A},
+        ]
+
+    def test_by_tag(self):
+        """Test union, intersect and subtract by <type>:<tag>"""
+        operands = [("a", self.a), ("b", self.b)]
+
+        assert [m["image_id"] for m in combine("union", operands)] == [
+            "environment:v1",
+            "environment:v2",
+            "environment:v1-alias",
+        ]
+        assert [m["image_id"] for m in combine("intersect", operands)] == ["environment:v2"]
+        assert [m["image_id"] for m in combine("subtract", operands)] == ["environment:v1"]
+
+    def test_by_digest(self):
+        """Test that comparing by digest matches other tags of the same manifest"""
+        operands = [("a", self.a), ("b", self.b)]
+
+        assert combine("subtract", operands, by="digest") == []
+        assert [m["image_id"] for m in combine("intersect", operands, by="digest")] == [
+            "environment:v1",
+            "environment:v2",
+        ]
+
+    def test_by_digest_needs_digests(self):
+        """Test that an operand without digests cannot be compared by digest"""
+        without_digest = [{"image_id": "environment:v1", "repository": "environment", "tag": "v1", "digest": ""}]
+
+        with pytest.raises(SelectionFormatError, match="compare by tag"):
+            combine("subtract", [("a", self.a), ("list.txt", without_digest)], by="digest")
@@ -0,0 +1,115 @@
+"""Unit tests for index_query.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.index_query import parse_age, parse_size, query_images, query_layers
+
+NOW = datetime(2026, 1, 1, tzinfo=timezone.utc)
+
+
+def _snapshot():
+    """Snapshot of three images: env1 and env2 share a base layer, env1 is old, the model image has no creation time"""
+    return {
+        "images": {
+            "environment:env1": {
+                "repository": "dominodatalab/environment",
+                "tag": "env1",
+                "digest": "sha256:e1",
+                "created": "2025-01-01T00:00:00Z",
+                "layers": ["base", "env1-top"],
+            },
+            "environment:env2": {
+                "repository": "dominodatalab/environment",
+                "tag": "env2",
+                "digest": "sha256:e2",
+                "created": "2025-12-20T00:00:00Z",
+                "layers": ["base", "env2-top"],
+            },
+            "model:latest-1": {
+                "repository": "dominodatalab/model",
+                "tag": "latest-1",
+                "digest": "sha256:m1",
+                "created": None,
+                "layers": ["model-top"],
+            },
+        },
+        "layers": {"base": 8000, "env1-top": 3000, "env2-top": 500, "model-top": 2000},
+    }
+
+
+class TestParsing:
+    """Tests for parsing sizes and ages"""
+
+    def test_parse_size(self):
+        """Decimal and binary units are supported; no unit means bytes"""
+        assert parse_size("10GB") == 10 * 1000**3
+        assert parse_size("500MiB") == 500 * 1024**2
+        assert parse_size("1.5k") == 1500
+        assert parse_size("2048") == 2048
+        for value in ("", "GB", "10XB", "-1GB"):
+            with pytest.raises(ValueError):
+                parse_size(value)
+
+    def test_parse_age(self):
+        """Days, weeks and years are supported; no unit means days"""
+        assert parse_age("180d") == 180
+        assert parse_age("26w") == 182
+        assert parse_age("1y") == 365
+        assert parse_age("30") == 30
+        for value in ("", "d", "1h", "1.5d"):
+            with pytest.raises(ValueError):
+                parse_age(value)
+
+
+class TestQueryImages:
+    """Tests for image queries"""
+
+    def test_sizes_and_unique_bytes(self):
+        """Image sizes count every layer, unique bytes only layers no other image uses; largest first"""
+        results = query_images(_snapshot(), now=NOW)
+
+        assert [image["image_id"] for image in results] == ["environment:env1", "environment:env2", "model:latest-1"]
+        assert results[0]["size_bytes"] == 11000
+        assert results[0]["unique_bytes"] == 3000
+        assert results[0]["age_days"] == 365
+        assert results[2]["age_days"] is None
+
+    def test_filters(self):
+        """Size, age, repository and tag filters must all match; unknown ages match no age filter"""
+        snapshot = _snapshot()
+
+        assert [image["tag"] for image in query_images(snapshot, min_size=8000, now=NOW)] == ["env1", "env2"]
+        assert [image["tag"] for image in query_images(snapshot, older_than_days=180, now=NOW)] == ["env1"]
+        assert [image["tag"] for image in query_images(snapshot, newer_than_days=30, now=NOW)] == ["env2"]
+        assert [image["tag"] for image in query_images(snapshot, repository="model", now=NOW)] == ["latest-1"]
+        assert [image["tag"] for image in query_images(snapshot, tag="env*", max_size=9000, now=NOW)] == ["env2"]
+
+    def test_sort_by_age(self):
+        """Oldest first, images of unknown age last"""
+        results = query_images(_snapshot(), sort="age", now=NOW)
+
+        assert [image["tag"] for image in results] == ["env1", "env2", "latest-1"]
+
+
+class TestQueryLayers:
+    """Tests for layer queries"""
+
+    def test_frequency(self):
+        """Layers used by exactly N images, largest first"""
+        results = query_layers(_snapshot(), frequency=1)
+
+        assert [layer["layer_id"] for layer in results] == ["env1-top", "model-top", "env2-top"]
+        assert results[0]["images"] == ["environment:env1"]
+
+    def test_repository_and_sort(self):
+        """Layers used by a matching repository, most used first"""
+        results = query_layers(_snapshot(), repository="dominodatalab/environment", sort="frequency")
+
+        assert [layer["layer_id"] for layer in results] == ["base", "env1-top", "env2-top"]
+        assert results[0]["frequency"] == 2
@@ -0,0 +1,92 @@
+"""Unit tests for keep_set.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+import yaml
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.keep_set import (
+    ACTION_COPY,
+    FORMAT_SKOPEO_SYNC,
+    keep_set,
+    render_keep_set,
+    render_script,
+    render_skopeo_sync,
+)
+
+
+def _analyzer() -> MagicMock:
+    """Analyzer with two environment images and one model image"""
+    analyzer = MagicMock()
+    analyzer.registry_url = "registry.example.com"
+    analyzer.images = {
+        "environment:b-1": {"repository": "domino/environment", "tag": "b-1", "digest": "sha256:bbb"},
+        "environment:a-1": {"repository": "domino/environment", "tag": "a-1", "digest": "sha256:aaa"},
+        "model:m-1": {"repository": "domino/model", "tag": "m-1"},
+    }
+    return analyzer
+
+
+class TestKeepSet:
+    """Tests for selecting the keep-set of a plan"""
+
+    def test_excludes_deleted_images(self):
+        """Images deleted by the plan are not in the keep-set"""
+        images = keep_set(_analyzer(), ["environment:b-1"])
+        assert [image["image_id"] for image in images] == ["environment:a-1", "model:m-1"]
+
+    def test_sorted_by_repository_and_tag(self):
+        """The keep-set is ordered by repository, then tag"""
+        images = keep_set(_analyzer(), [])
+        assert [image["tag"] for image in images] == ["a-1", "b-1", "m-1"]
+
+
+class TestRenderScript:
+    """Tests for rendering the keep-set as a script"""
+
+    def setup_method(self):
+        """Keep-set of every image"""
+        self.images = keep_set(_analyzer(), [])
+
+    def test_pull_pins_digests(self):
+        """Pull commands reference the digest, or the tag when the digest is unknown"""
+        script = render_script(self.images, "registry.example.com")
+        assert script.startswith("#!/usr/bin/env bash\n")
+        assert "set -euo pipefail" in script
+        assert "docker pull registry.example.com/domino/environment@sha256:aaa" in script
+        assert "docker pull registry.example.com/domino/model:m-1" in script
+
+    def test_copy_to_destination_tag(self):
+        """Copy commands copy the pinned source to the same repository and tag in the destination"""
+        script = render_script(self.images, "registry.example.com", ACTION_COPY, "dr.example.com")
+        assert (
+            "skopeo copy --all docker://registry.example.com/domino/environment@sha256:aaa "
+            "docker://dr.example.com/domino/environment:a-1"
+        ) in script
+
+    def test_copy_requires_destination(self):
+        """Copying without a destination registry is rejected"""
+        with pytest.raises(ValueError):
+            render_script(self.images, "registry.example.com", ACTION_COPY)
+
+    def test_unknown_format_rejected(self):
+        """Unknown output formats are rejected"""
+        with pytest.raises(ValueError):
+            render_keep_set(self.images, "registry.example.com", "csv")
+
+
+class TestRenderSkopeoSync:
+    """Tests for rendering the keep-set as a skopeo sync file"""
+
+    def test_tags_grouped_by_repository(self):
+        """The file lists the kept tags of every repository under the source registry"""
+        images = keep_set(_analyzer(), ["environment:a-1"])
+        content = render_keep_set(images, "registry.example.com", FORMAT_SKOPEO_SYNC)
+        assert content == render_skopeo_sync(images, "registry.example.com")
+        assert yaml.safe_load(content) == {
+            "registry.example.com": {"images": {"domino/environment": ["b-1"], "domino/model": ["m-1"]}}
+        }
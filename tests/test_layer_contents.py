@@ -0,0 +1,130 @@
+"""Unit tests for utils/layer_contents.py"""
+
+import gzip
+import io
+import os
+import sys
+import tarfile
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.layer_contents import (
+    ENTRY_DIR,
+    ENTRY_FILE,
+    ENTRY_SYMLINK,
+    ENTRY_WHITEOUT,
+    find_duplicate_files,
+    find_files,
+    list_layer_entries,
+    select_layers,
+)
+
+
+def _layer(files: dict, compress: bool = True) -> io.BytesIO:
+    """Build a layer blob from {path: bytes, or None for a directory, or "->target" for a symlink}"""
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode="w") as archive:
+        for path, content in files.items():
+            info = tarfile.TarInfo(path)
+            if content is None:
+                info.type = tarfile.DIRTYPE
+                archive.addfile(info)
+            elif isinstance(content, str):
+                info.type = tarfile.SYMTYPE
+                info.linkname = content[2:]
+                archive.addfile(info)
+            else:
+                info.size = len(content)
+                archive.addfile(info, io.BytesIO(content))
+    data = buffer.getvalue()
+    return io.BytesIO(gzip.compress(data) if compress else data)
+
+
+class TestListLayerEntries:
+    """Tests for listing the entries of a layer tar stream"""
+
+    def test_entries_types_and_hashes(self):
+        """Test that files, directories, symlinks and whiteouts are listed with absolute paths"""
+        blob = _layer(
+            {"./etc": None, "./etc/app.conf": b"key=1", "usr/bin/python": "->python3", "opt/.wh.old": b""}
+        )
+
+        listing = list_layer_entries(blob, max_entries=100, hash_max_bytes=1024)
+
+        assert listing["truncated"] is False
+        entries = {entry["path"]: entry for entry in listing["entries"]}
+        assert entries["/etc"]["type"] == ENTRY_DIR
+        assert entries["/etc/app.conf"]["type"] == ENTRY_FILE
+        assert entries["/etc/app.conf"]["size"] == 5
+        assert entries["/etc/app.conf"]["sha256"] is not None
+        assert entries["/usr/bin/python"]["type"] == ENTRY_SYMLINK
+        assert entries["/opt/.wh.old"]["type"] == ENTRY_WHITEOUT
+
+    def test_uncompressed_layer(self):
+        """Test that uncompressed tar layers are read too"""
+        listing = list_layer_entries(_layer({"a": b"x"}, compress=False), max_entries=100, hash_max_bytes=1024)
+
+        assert [entry["path"] for entry in listing["entries"]] == ["/a"]
+
+    def test_safeguards(self):
+        """Test that listings stop at max_entries and large files are not hashed"""
+        blob = _layer({"small": b"x", "large": b"y" * 100, "third": b"z"})
+
+        listing = list_layer_entries(blob, max_entries=2, hash_max_bytes=10)
+
+        assert listing["truncated"] is True
+        assert [entry["path"] for entry in listing["entries"]] == ["/small", "/large"]
+        assert listing["entries"][0]["sha256"] is not None
+        assert listing["entries"][1]["sha256"] is None
+
+
+class TestSelectLayers:
+    """Tests for choosing the layers a contents scan reads"""
+
+    def test_largest_layers_within_limits(self):
+        """Test that oversized, excluded and empty layers are skipped and the largest come first"""
+        sizes = {"huge": 5000, "big": 900, "medium": 500, "foreign": 800, "small": 100, "empty": 0}
+
+        assert select_layers(sizes, ["foreign"], max_layers=2, max_layer_bytes=1000) == ["big", "medium"]
+
+
+class TestFindFiles:
+    """Tests for finding files and the images containing them"""
+
+    def test_name_and_path_patterns(self):
+        """Test that bare names match anywhere and patterns with a slash match full paths"""
+        contents = {
+            "layer-a": list_layer_entries(_layer({"root/.ssh/id_rsa": b"k", "root/.ssh": None}), 100, 1024),
+            "layer-b": list_layer_entries(_layer({"home/u/.aws/credentials": b"c"}), 100, 1024),
+        }
+        layer_images = {"layer-a": ["environment:b", "environment:a"], "layer-b": ["model:m"]}
+
+        matches = find_files(contents, layer_images, ["id_rsa", "/home/*/.aws/*"])
+
+        assert [(m["path"], m["image_ids"]) for m in matches] == [
+            ("/home/u/.aws/credentials", ["model:m"]),
+            ("/root/.ssh/id_rsa", ["environment:a", "environment:b"]),
+        ]
+
+
+class TestFindDuplicateFiles:
+    """Tests for detecting identical files stored in several layers"""
+
+    def test_duplicates_across_layers_only(self):
+        """Test that content repeated in another layer is reported and copies within one layer are not"""
+        payload = b"0123456789"
+        layer_a = _layer({"lib/a.so": payload, "lib/copy.so": b"same", "x": b"same"})
+        contents = {
+            "layer-a": list_layer_entries(layer_a, 100, 1024),
+            "layer-b": list_layer_entries(_layer({"opt/a.so": payload}), 100, 1024),
+        }
+
+        groups = find_duplicate_files(contents)
+
+        assert len(groups) == 1
+        assert groups[0]["size"] == 10
+        assert groups[0]["wasted_bytes"] == 10
+        assert groups[0]["occurrences"] == [
+            {"layer_id": "layer-a", "path": "/lib/a.so"},
+            {"layer_id": "layer-b", "path": "/opt/a.so"},
+        ]
@@ -0,0 +1,87 @@
+"""Unit tests for utils/manifest_schema1.py"""
+
+import base64
+import hashlib
+import json
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.manifest_schema1 import (
+    EMPTY_LAYER_DIGEST,
+    is_schema1,
+    is_schema1_inspection,
+    manifest_digest,
+    normalize_schema1,
+)
+
+
+def _v1(**fields) -> dict:
+    """A schema1 history entry"""
+    return {"v1Compatibility": json.dumps(fields)}
+
+
+def _b64url(data: bytes) -> str:
+    return base64.urlsafe_b64encode(data).decode("ascii").rstrip("=")
+
+
+class TestNormalizeSchema1:
+    """Tests for converting schema1 manifests to the analyzer's layer list"""
+
+    def test_layers_base_first_without_empty_or_repeated_blobs(self):
+        """Test that layers are reversed and empty, throwaway and repeated blobs are dropped"""
+        manifest = {
+            "schemaVersion": 1,
+            "fsLayers": [
+                {"blobSum": EMPTY_LAYER_DIGEST},
+                {"blobSum": "sha256:top"},
+                {"blobSum": "sha256:meta"},
+                {"blobSum": "sha256:base"},
+                {"blobSum": "sha256:base"},
+            ],
+            "history": [
+                _v1(id="5", created="2016-03-01T10:00:00Z"),
+                _v1(id="4", Size=300),
+                _v1(id="3", throwaway=True),
+                _v1(id="2", Size=5000),
+                _v1(id="1"),
+            ],
+        }
+
+        normalized = normalize_schema1(manifest)
+
+        assert normalized["layers"] == [
+            {"digest": "sha256:base", "size": None},
+            {"digest": "sha256:top", "size": 300},
+        ]
+        assert normalized["created"] == "2016-03-01T10:00:00Z"
+
+    def test_detection(self):
+        """Test detecting schema1 from manifests and from skopeo inspect results"""
+        assert is_schema1({"schemaVersion": 1, "fsLayers": []})
+        assert is_schema1({"mediaType": "application/vnd.docker.distribution.manifest.v1+prettyjws"})
+        assert not is_schema1({"schemaVersion": 2, "layers": []})
+
+        assert is_schema1_inspection({"LayersData": [{"Digest": "sha256:a", "Size": -1}]})
+        assert is_schema1_inspection({"Layers": ["sha256:a", EMPTY_LAYER_DIGEST]})
+        assert not is_schema1_inspection({"LayersData": [{"Digest": "sha256:a", "Size": 10}]})
+
+
+class TestManifestDigest:
+    """Tests for computing manifest digests the way the registry does"""
+
+    def test_signed_schema1_digest_excludes_signatures(self):
+        """Test that a signed schema1 manifest's digest is that of its payload"""
+        payload = '{\n   "schemaVersion": 1,\n   "name": "domino/environment"\n}'
+        format_length = len(payload) - 2
+        protected = _b64url(json.dumps({"formatLength": format_length, "formatTail": _b64url(b"\n}")}).encode())
+        raw = payload[:format_length] + f',\n   "signatures": [{{"protected": "{protected}"}}]' + "\n}"
+
+        assert manifest_digest(raw) == "sha256:" + hashlib.sha256(payload.encode()).hexdigest()
+
+    def test_other_manifests_use_raw_bytes(self):
+        """Test that unsigned manifests are digested as-is"""
+        raw = json.dumps({"schemaVersion": 2, "layers": []})
+
+        assert manifest_digest(raw) == "sha256:" + hashlib.sha256(raw.encode()).hexdigest()
@@ -0,0 +1,103 @@
+"""Unit tests for utils/mirror.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock
+
+import yaml
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.mirror import mirror_images, sync_images
+
+
+def _image(tag: str, digest: str = "") -> dict:
+    """Build a kept image of the environment repository"""
+    return {
+        "image_id": f"environment:{tag}",
+        "repository": "dominodatalab/environment",
+        "tag": tag,
+        "digest": digest or f"sha256:{tag}",
+    }
+
+
+class TestMirrorImages:
+    """Tests for copying kept images one at a time"""
+
+    def setup_method(self):
+        """Set up a destination holding env1 at its current digest and env2 at an old one"""
+        self.mirrored = {"env1": "sha256:env1", "env2": "sha256:old"}
+        self.source = MagicMock()
+        self.source.registry_url = "registry:5000"
+
+        def copy_image(repository, digest, registry_url, tag):
+            self.mirrored[tag] = digest
+            return True
+
+        self.source.copy_image.side_effect = copy_image
+        self.destination = MagicMock()
+        self.destination.registry_url = "mirror:5000"
+        self.destination.get_manifest_digest.side_effect = lambda repository, tag: self.mirrored.get(tag)
+
+    def test_copies_missing_and_outdated_images(self):
+        """Test that only images the destination lacks at their digest are copied, and copies are verified"""
+        result = mirror_images([_image("env1"), _image("env2"), _image("env3")], self.source, self.destination, 2)
+
+        assert result["copied"] == ["environment:env2", "environment:env3"]
+        assert result["up_to_date"] == ["environment:env1"]
+        assert result["failed"] == []
+        assert self.mirrored["env2"] == "sha256:env2"
+        self.source.copy_image.assert_any_call("dominodatalab/environment", "sha256:env3", "mirror:5000", "env3")
+
+    def test_dry_run_copies_nothing(self):
+        """Test that a dry run reports what would be copied"""
+        result = mirror_images([_image("env1"), _image("env3")], self.source, self.destination, 1, dry_run=True)
+
+        assert result["copied"] == ["environment:env3"]
+        self.source.copy_image.assert_not_called()
+
+    def test_unverified_copy_fails(self):
+        """Test that a copy whose digest cannot be read back is reported as failed"""
+        self.source.copy_image.side_effect = None
+        self.source.copy_image.return_value = True
+
+        result = mirror_images([_image("env3")], self.source, self.destination, 1)
+
+        assert result["copied"] == []
+        assert result["failed"][0]["image_id"] == "environment:env3"
+        assert "could not be verified" in result["failed"][0]["reason"]
+
+
+class TestSyncImages:
+    """Tests for mirroring with one skopeo sync"""
+
+    def test_sync_source_file(self):
+        """Test that the generated source file lists every kept tag and is removed afterwards"""
+        source = MagicMock()
+        source.registry_url = "registry:5000"
+        seen = {}
+
+        def sync(source_file, registry_url):
+            with open(source_file) as f:
+                seen["content"] = yaml.safe_load(f)
+            seen["path"] = source_file
+            return True
+
+        source.sync_images.side_effect = sync
+
+        result = sync_images([_image("env2"), _image("env1")], source, "mirror:5000")
+
+        assert result["copied"] == ["environment:env1", "environment:env2"]
+        assert seen["content"] == {"registry:5000": {"images": {"dominodatalab/environment": ["env2", "env1"]}}}
+        assert not os.path.exists(seen["path"])
+
+    def test_failed_sync_fails_every_image(self):
+        """Test that a failed sync reports every image as failed"""
+        source = MagicMock()
+        source.registry_url = "registry:5000"
+        source.sync_images.return_value = False
+
+        result = sync_images([_image("env1")], source, "mirror:5000")
+
+        assert result["copied"] == []
+        assert result["failed"] == [{"image_id": "environment:env1", "reason": "skopeo sync to mirror:5000 failed"}]
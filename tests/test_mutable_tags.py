@@ -0,0 +1,83 @@
+"""Unit tests for utils/mutable_tags.py"""
+
+import os
+import re
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.mutable_tags import ALIAS, DIGEST_CHANGED, FLOATING_NAME, find_mutable_tags, rules_depending_on
+from utils.retention_policy import policy_from_dict
+from utils.tag_matching import DEFAULT_FLOATING_TAGS, DOMINO_TAG_PATTERN
+
+ENV_ID = "507f1f77bcf86cd799439011"
+
+
+def images(*entries):
+    """Snapshot images with (repository, tag, digest) entries"""
+    return {
+        f"{repo.rsplit('/', 1)[-1]}:{tag}": {"repository": repo, "tag": tag, "digest": digest}
+        for repo, tag, digest in entries
+    }
+
+
+class TestFindMutableTags:
+    """Tests for finding tags whose image is not fixed"""
+
+    def test_reasons(self):
+        """Test that re-pushed, floating and aliasing tags are reported, but not conventional duplicates"""
+        latest = images(
+            ("domino/environment", f"{ENV_ID}-1", "sha256:a"),
+            ("domino/environment", f"{ENV_ID}-2", "sha256:a"),
+            ("domino/environment", f"{ENV_ID}-3", "sha256:b"),
+            ("domino/environment", "latest", "sha256:b"),
+            ("domino/environment", "team-build", "sha256:b"),
+            ("domino/model", "v1", "sha256:m2"),
+        )
+        history = {
+            "domino/environment": {"latest": ["sha256:a", "sha256:b"], f"{ENV_ID}-3": ["sha256:b"]},
+            "domino/model": {"v1": ["sha256:m1", "sha256:m2"]},
+        }
+
+        mutable = find_mutable_tags(history, latest, DEFAULT_FLOATING_TAGS, re.compile(DOMINO_TAG_PATTERN))
+
+        by_tag = {entry["tag"]: entry for entry in mutable}
+        assert sorted(by_tag) == ["latest", "team-build", "v1"]
+        assert by_tag["latest"]["reasons"] == [DIGEST_CHANGED, FLOATING_NAME, ALIAS]
+        assert by_tag["latest"]["aliases"] == [f"{ENV_ID}-3", "team-build"]
+        assert by_tag["team-build"]["reasons"] == [ALIAS]
+        assert by_tag["v1"]["reasons"] == [DIGEST_CHANGED]
+        assert by_tag["v1"]["digests"] == ["sha256:m1", "sha256:m2"]
+
+
+class TestRulesDependingOn:
+    """Tests for flagging policy rules that select mutable tags"""
+
+    def test_dependent_rules(self):
+        """Test that rules selecting mutable tags are listed age-based first, and rules without tags are not"""
+        policy = policy_from_dict(
+            {
+                "rules": [
+                    {"name": "keep-latest", "action": "keep", "tags": ["latest"]},
+                    {"name": "expire-stable", "action": "delete", "tags": ["stable*"], "older_than_days": 90},
+                    {"name": "expire-models", "action": "delete", "repositories": ["model"], "tags": ["*"]},
+                    {"name": "expire-old", "action": "delete", "older_than_days": 180},
+                ]
+            }
+        )
+        mutable = find_mutable_tags(
+            {},
+            images(("domino/environment", "latest", "sha256:a"), ("domino/environment", "stable", "sha256:b")),
+            DEFAULT_FLOATING_TAGS,
+            re.compile(DOMINO_TAG_PATTERN),
+        )
+
+        dependent = rules_depending_on(policy, mutable)
+
+        assert [rule["rule"] for rule in dependent] == ["expire-stable", "keep-latest"]
+        assert dependent[0] == {
+            "rule": "expire-stable",
+            "action": "delete",
+            "age_based": True,
+            "tags": ["domino/environment:stable"],
+        }
@@ -0,0 +1,122 @@
+"""Unit tests for native_registry.py"""
+
+import json
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.manifest_schema1 import manifest_digest
+from utils.native_registry import ManifestNotFoundError, delete_tag, get_image_config, inspect_image
+from utils.skopeo_output import normalize_inspect
+
+CONFIG = {
+    "created": "2025-06-01T12:00:00Z",
+    "architecture": "amd64",
+    "os": "linux",
+    "config": {"Labels": {"team": "ml"}, "Env": ["PATH=/usr/bin"]},
+    "rootfs": {"type": "layers", "diff_ids": ["sha256:d1"]},
+}
+
+
+class FakeRegistry:
+    """Registry HTTP client serving manifests and blobs from dicts"""
+
+    host = "registry.example.com"
+
+    def __init__(self, manifests, blobs=None):
+        self.manifests = {reference: json.dumps(manifest) for reference, manifest in manifests.items()}
+        self.blobs = blobs or {}
+        self.deleted = []
+
+    def get_manifest(self, repository, reference):
+        raw = self.manifests.get(reference)
+        return (raw.encode("utf-8"), "application/json") if raw is not None else None
+
+    def get_blob(self, repository, digest, max_bytes):
+        blob = self.blobs.get(digest)
+        return json.dumps(blob).encode("utf-8") if blob is not None else None
+
+    def head_manifest_digest(self, repository, tag):
+        return None
+
+    def delete_manifest(self, repository, digest):
+        self.deleted.append(digest)
+        return True
+
+
+def _image_manifest(config_digest="sha256:config"):
+    return {
+        "schemaVersion": 2,
+        "mediaType": "application/vnd.oci.image.manifest.v1+json",
+        "config": {"mediaType": "application/vnd.oci.image.config.v1+json", "digest": config_digest, "size": 10},
+        "layers": [
+            {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:l1", "size": 100},
+            {"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": "sha256:l2", "size": 200},
+        ],
+    }
+
+
+class TestInspectImage:
+    """Tests for inspecting images without skopeo"""
+
+    def test_image_inspection_matches_skopeo(self):
+        """Test that an image is inspected with the fields skopeo inspect prints"""
+        registry = FakeRegistry({"v1": _image_manifest()}, {"sha256:config": CONFIG})
+
+        info = normalize_inspect(inspect_image(registry, "env", "v1"), "inspection")
+
+        assert info["Name"] == "registry.example.com/env"
+        assert info["Digest"] == manifest_digest(registry.manifests["v1"])
+        assert (info["Created"], info["Architecture"], info["Os"]) == ("2025-06-01T12:00:00Z", "amd64", "linux")
+        assert info["Labels"] == {"team": "ml"} and info["Env"] == ["PATH=/usr/bin"]
+        assert info["Layers"] == ["sha256:l1", "sha256:l2"]
+        assert [layer["Size"] for layer in info["LayersData"]] == [100, 200]
+        assert get_image_config(registry, "env", "v1") == CONFIG
+
+    def test_index_inspects_linux_amd64_with_index_digest(self):
+        """Test that an index is inspected through its linux/amd64 image, skipping attestations"""
+        index = {
+            "schemaVersion": 2,
+            "mediaType": "application/vnd.oci.image.index.v1+json",
+            "manifests": [
+                {"digest": "sha256:att", "platform": {"os": "unknown", "architecture": "unknown"}},
+                {"digest": "sha256:arm", "platform": {"os": "linux", "architecture": "arm64"}},
+                {"digest": "sha256:amd", "platform": {"os": "linux", "architecture": "amd64"}},
+            ],
+        }
+        manifests = {"multi": index, "sha256:amd": _image_manifest(), "sha256:arm": _image_manifest("sha256:none")}
+        registry = FakeRegistry(manifests, {"sha256:config": CONFIG})
+
+        info = inspect_image(registry, "env", "multi")
+
+        assert info["Digest"] == manifest_digest(registry.manifests["multi"])
+        assert info["Labels"] == {"team": "ml"}
+
+    def test_schema1_and_missing_tags(self):
+        """Test that schema1 layers are listed oldest first without sizes, and missing tags raise"""
+        schema1 = {
+            "schemaVersion": 1,
+            "fsLayers": [{"blobSum": "sha256:top"}, {"blobSum": "sha256:base"}],
+            "history": [{"v1Compatibility": json.dumps({"created": "2016-01-01T00:00:00Z", "os": "linux"})}],
+        }
+        registry = FakeRegistry({"old": schema1})
+
+        info = inspect_image(registry, "env", "old")
+
+        assert info["Layers"] == ["sha256:base", "sha256:top"]
+        assert [layer["Size"] for layer in info["LayersData"]] == [-1, -1]
+        assert info["Created"] == "2016-01-01T00:00:00Z"
+        with pytest.raises(ManifestNotFoundError, match="not found"):
+            inspect_image(registry, "env", "gone")
+
+    def test_delete_tag_resolves_digest(self):
+        """Test that deleting a tag deletes its manifest by digest, computed when HEAD returns none"""
+        registry = FakeRegistry({"v1": _image_manifest()})
+
+        assert delete_tag(registry, "env", "v1") is True
+        assert registry.deleted == [manifest_digest(registry.manifests["v1"])]
+        with pytest.raises(ManifestNotFoundError):
+            delete_tag(registry, "env", "gone")
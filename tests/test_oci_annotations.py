@@ -0,0 +1,51 @@
+"""Unit tests for utils/oci_annotations.py"""
+
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.oci_annotations import (
+    ANNOTATION_REVISION,
+    ANNOTATION_SOURCE,
+    annotations_match,
+    manifest_annotations,
+    parse_annotation_filters,
+)
+
+
+class TestParseAnnotationFilters:
+    """Tests for parsing KEY=PATTERN filters"""
+
+    def test_short_names_resolve_to_full_keys(self):
+        """Test that created/source/revision expand and other keys are kept as-is"""
+        filters = parse_annotation_filters(["source=https://github.com/example/*", "com.example.team=ml=ops"])
+
+        assert filters == {ANNOTATION_SOURCE: "https://github.com/example/*", "com.example.team": "ml=ops"}
+
+    @pytest.mark.parametrize("value", ["source", "=x", "source="])
+    def test_malformed_filters(self, value):
+        """Test that filters without a key or pattern are rejected"""
+        with pytest.raises(ValueError, match="KEY=PATTERN"):
+            parse_annotation_filters([value])
+
+
+class TestAnnotationsMatch:
+    """Tests for matching annotations against filters"""
+
+    def test_every_filter_must_match(self):
+        """Test matching, missing keys and unknown annotations"""
+        annotations = {ANNOTATION_SOURCE: "https://github.com/example/app", ANNOTATION_REVISION: "v1.2.0"}
+
+        assert annotations_match(annotations, {ANNOTATION_SOURCE: "*/app", ANNOTATION_REVISION: "v1.*"}) is True
+        assert annotations_match(annotations, {ANNOTATION_REVISION: "v2.*"}) is False
+        assert annotations_match({}, {ANNOTATION_SOURCE: "*"}) is False
+        assert annotations_match(None, {ANNOTATION_SOURCE: "*"}) is None
+        assert annotations_match(None, {}) is True
+
+    def test_manifest_annotations(self):
+        """Test reading top-level annotations from manifests with and without them"""
+        assert manifest_annotations({"annotations": {ANNOTATION_REVISION: "abc"}}) == {ANNOTATION_REVISION: "abc"}
+        assert manifest_annotations({"schemaVersion": 2, "layers": []}) == {}
@@ -0,0 +1,95 @@
+"""Unit tests for utils/os_release.py"""
+
+import gzip
+import io
+import os
+import sys
+import tarfile
+from datetime import date
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.os_release import (
+    SOURCE_BASE_IMAGE,
+    SOURCE_LABELS,
+    eol_date,
+    infer_release,
+    is_eol,
+    normalize_release,
+    parse_os_release,
+    read_os_release,
+    release_from_base_image,
+)
+
+UBUNTU_OS_RELEASE = 'NAME="Ubuntu"\nVERSION="18.04.6 LTS (Bionic Beaver)"\nID=ubuntu\nVERSION_ID="18.04"\n'
+
+
+def _layer(files: dict) -> io.BytesIO:
+    """Build a gzip layer blob from {path: bytes, or "->target" for a symlink}"""
+    buffer = io.BytesIO()
+    with tarfile.open(fileobj=buffer, mode="w") as archive:
+        for path, content in files.items():
+            info = tarfile.TarInfo(path)
+            if isinstance(content, str):
+                info.type = tarfile.SYMTYPE
+                info.linkname = content[2:]
+                archive.addfile(info)
+            else:
+                info.size = len(content)
+                archive.addfile(info, io.BytesIO(content))
+    return io.BytesIO(gzip.compress(buffer.getvalue()))
+
+
+class TestInferRelease:
+    """Tests for reading releases from labels, base images and os-release files"""
+
+    def test_normalize_release(self):
+        """Test point releases, codenames and image tag suffixes"""
+        assert normalize_release("Ubuntu", "18.04.6") == ("ubuntu", "18.04")
+        assert normalize_release("ubuntu", "focal-20240101") == ("ubuntu", "20.04")
+        assert normalize_release("debian", "bullseye-slim") == ("debian", "11")
+        assert normalize_release("debian", "9.13") == ("debian", "9")
+        assert normalize_release("centos", "7.9.2009") == ("centos", "7")
+        assert normalize_release("ubuntu", "latest") is None
+
+    def test_base_images(self):
+        """Test that only distribution base images name a release"""
+        assert release_from_base_image("docker.io/library/ubuntu:20.04") == ("ubuntu", "20.04")
+        assert release_from_base_image("debian:stretch@sha256:abc") == ("debian", "9")
+        assert release_from_base_image("quay.io/domino/base:2024") is None
+        assert release_from_base_image("ubuntu") is None
+
+    def test_labels_before_base_image(self):
+        """Test that Ubuntu's ref.name and version labels win over the base image annotation"""
+        labels = {"org.opencontainers.image.ref.name": "ubuntu", "org.opencontainers.image.version": "22.04"}
+        annotations = {"org.opencontainers.image.base.name": "debian:10"}
+
+        assert infer_release(labels, annotations) == {"distro": "ubuntu", "version": "22.04", "source": SOURCE_LABELS}
+        assert infer_release({}, annotations) == {"distro": "debian", "version": "10", "source": SOURCE_BASE_IMAGE}
+        assert infer_release({"maintainer": "ml"}, None) is None
+
+    def test_os_release_file(self):
+        """Test parsing os-release and reading it from a layer, preferring /etc over /usr/lib"""
+        assert parse_os_release(UBUNTU_OS_RELEASE) == ("ubuntu", "18.04")
+        assert parse_os_release('ID=debian\nVERSION_CODENAME=buster\n') == ("debian", "10")
+
+        layer = _layer({"./usr/lib/os-release": b"ID=centos\nVERSION_ID=7\n", "./etc/os-release": b"ID=debian\n"})
+        assert read_os_release(layer) == "ID=debian\n"
+        symlinked = _layer({"etc/os-release": "->../usr/lib/os-release", "usr/lib/os-release": b"ID=centos\n"})
+        assert read_os_release(symlinked) == "ID=centos\n"
+        assert read_os_release(_layer({"bin/sh": b"#!"})) is None
+
+
+class TestEndOfLife:
+    """Tests for the end-of-life table"""
+
+    def test_eol(self):
+        """Test releases before and after their end of life, and releases missing from the table"""
+        bionic = {"distro": "ubuntu", "version": "18.04", "source": SOURCE_LABELS}
+        alpine = {"distro": "alpine", "version": "3", "source": SOURCE_LABELS}
+
+        assert eol_date(bionic) == "2023-05-31"
+        assert is_eol(bionic, date(2024, 1, 1))
+        assert not is_eol(bionic, date(2023, 1, 1))
+        assert eol_date(alpine) is None
+        assert not is_eol(alpine, date(2030, 1, 1))
@@ -0,0 +1,93 @@
+"""Unit tests for partial_results.py"""
+
+import json
+import os
+import sys
+from datetime import datetime, timezone
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cache_utils import DigestInspectCache
+from utils.image_data_analysis import ImageAnalyzer
+from utils.partial_results import PartialResultWriter
+from utils.synthetic_registry import SyntheticSkopeoClient, generate_dataset
+
+NOW = datetime(2025, 7, 1, tzinfo=timezone.utc)
+
+
+def _make_analyzer(repositories: int = 2, tags: int = 6):
+    """Create an analyzer over a synthetic registry"""
+    dataset = generate_dataset(repositories, tags, seed=3, now=NOW)
+    analyzer = ImageAnalyzer("synthetic", "bench", skopeo_client=SyntheticSkopeoClient(dataset, "bench"))
+    analyzer.inspect_cache = DigestInspectCache(None)
+    return analyzer, dataset
+
+
+class TestPartialResults:
+    """Tests for writing the results of a scan in progress"""
+
+    def test_flushes_every_n_images_and_per_repository(self, tmp_path):
+        """Test that the file is rewritten every N images and when a repository is done"""
+        analyzer, dataset = _make_analyzer()
+        path = str(tmp_path / "scan-partial.json")
+        analyzer.partial_results = PartialResultWriter(path, analyzer, every_images=4)
+        documents = []
+        flush = PartialResultWriter.flush
+
+        def recording_flush(writer, status="in_progress"):
+            saved = flush(writer, status)
+            with open(saved) as f:
+                documents.append(json.load(f))
+            return saved
+
+        with patch.object(PartialResultWriter, "flush", recording_flush):
+            for name in dataset.repositories:
+                assert analyzer.analyze_image(name, max_workers=1)
+            analyzer.partial_results.finish()
+
+        # 6 tags per repository: one flush after 4 images and one when the repository is done
+        assert [d["progress"]["images_inspected"] for d in documents] == [4, 6, 10, 12, 12]
+        first = documents[0]
+        assert first["status"] == "in_progress"
+        assert first["repositories"]["repo-000"]["status"] == "in_progress"
+        assert first["progress"]["current_repository"] == "repo-000"
+        assert first["progress"]["tags_total"] == 6
+        assert documents[2]["repositories"]["repo-000"]["status"] == "complete"
+        assert documents[2]["progress"]["repositories_complete"] == ["repo-000"]
+        final = documents[-1]
+        assert final["status"] == "complete"
+        assert final["progress"]["current_repository"] is None
+        section = final["repositories"]["repo-001"]
+        assert section["summary"]["tag_count"] == 6
+        image = dataset.repositories["repo-001"][0]
+        assert section["images"][f"repo-001:{image.tag}"]["digest"] == image.digest
+        assert not os.path.exists(f"{path}.tmp")
+
+    def test_flushes_on_timer(self, tmp_path):
+        """Test that the file is rewritten when the interval has passed since the last flush"""
+        analyzer, _ = _make_analyzer()
+        writer = PartialResultWriter(str(tmp_path / "partial.json"), analyzer, interval_seconds=60)
+        writer.repository_started("repo-000", 6)
+
+        with patch("utils.partial_results.time.monotonic", return_value=writer._last_flush + 30):
+            writer.tag_completed("repo-000", 1, inspected=True)
+        assert writer.flushes == 0
+        with patch("utils.partial_results.time.monotonic", return_value=writer._last_flush + 61):
+            writer.tag_completed("repo-000", 2, inspected=False)
+        assert writer.flushes == 1
+
+    def test_failed_tags_and_repositories(self, tmp_path):
+        """Test that failed tags are listed in their section and unlisted repositories are marked failed"""
+        analyzer, _ = _make_analyzer()
+        writer = PartialResultWriter(str(tmp_path / "partial.json"), analyzer)
+        analyzer.partial_results = writer
+        analyzer.failures["repo-000:abc"] = {"code": "TIMEOUT", "retryable": True, "message": "timed out"}
+
+        with patch.object(analyzer.skopeo_client, "list_tags", side_effect=RuntimeError("unreachable")):
+            assert not analyzer.analyze_image("repo-000")
+
+        document = writer.document()
+        assert document["repositories"]["repo-000"]["status"] == "failed"
+        assert document["repositories"]["repo-000"]["failures"]["repo-000:abc"]["code"] == "TIMEOUT"
+        assert document["progress"]["repositories_complete"] == []
@@ -0,0 +1,112 @@
+"""Unit tests for profiles.py"""
+
+import os
+import sys
+from unittest.mock import patch
+
+import pytest
+import yaml
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.config_manager import ConfigManager, ConfigValidationError
+from utils.profiles import DOMINO_PROFILE, ProfileError, profile_drift, resolve_profile
+from utils.tag_matching import DOMINO_TAG_PATTERN
+
+CUSTOM = {
+    "ml-platform": {
+        "registry_url": "registry.{namespace}.svc:5000",
+        "repository": "ml",
+        "image_types": ["notebook", "serving"],
+    },
+    "ml-staging": {"extends": "ml-platform", "registry_url": "staging.{namespace}.svc:5000"},
+}
+
+
+class TestResolveProfile:
+    """Tests for resolving built-in and custom profiles"""
+
+    def test_domino_profile(self):
+        """Test that the built-in profile holds Domino's layout"""
+        profile = resolve_profile(DOMINO_PROFILE)
+
+        assert profile["registry_url"] == "docker-registry:5000"
+        assert profile["repository"] == "dominodatalab"
+        assert profile["image_types"] == ["environment", "model"]
+        assert profile["tag_naming_pattern"] == DOMINO_TAG_PATTERN
+
+    def test_custom_profiles_extend(self):
+        """Test that custom profiles inherit what they leave out, from domino or the profile they extend"""
+        profile = resolve_profile("ml-staging", CUSTOM)
+
+        assert profile["registry_url"] == "staging.{namespace}.svc:5000"
+        assert profile["repository"] == "ml"
+        assert profile["image_types"] == ["notebook", "serving"]
+        assert profile["tag_naming_pattern"] == DOMINO_TAG_PATTERN
+
+    def test_invalid_profiles_rejected(self):
+        """Test that unknown profiles and settings, cycles and empty image types are errors"""
+        with pytest.raises(ProfileError, match="Unknown profile 'other'"):
+            resolve_profile("other", CUSTOM)
+        with pytest.raises(ProfileError, match="extends itself"):
+            resolve_profile("a", {"a": {"extends": "b"}, "b": {"extends": "a"}})
+        with pytest.raises(ProfileError, match="unknown settings: registry"):
+            resolve_profile("a", {"a": {"registry": "x"}})
+        with pytest.raises(ProfileError, match="image_types"):
+            resolve_profile("a", {"a": {"image_types": []}})
+
+    def test_drift(self):
+        """Test that config.yaml settings differing from the profile are reported, matching ones are not"""
+        profile = resolve_profile("ml-platform", CUSTOM)
+        user_config = {"registry": {"repository": "ml", "image_types": ["notebook"]}}
+
+        drift = profile_drift("ml-platform", profile, user_config)
+
+        assert len(drift) == 1
+        assert drift[0].startswith("registry.image_types in config.yaml (['notebook']) overrides image_types")
+
+
+class TestConfigManagerProfiles:
+    """Tests for profiles in the config manager"""
+
+    def load(self, tmp_path, config, profile=""):
+        """A config manager for a config.yaml, with CONFIG_PROFILE set to profile"""
+        path = tmp_path / "config.yaml"
+        path.write_text(yaml.dump(config))
+        with patch.dict(os.environ, {"CONFIG_PROFILE": profile, "REGISTRY_URL": "", "REPOSITORY": ""}):
+            return ConfigManager(config_file=str(path), validate=False)
+
+    def test_profile_defaults(self, tmp_path):
+        """Test that a selected profile provides the registry, repository, image types and tag pattern"""
+        config = {"profiles": CUSTOM, "kubernetes": {"domino_platform_namespace": "ml-ns"}}
+        cm = self.load(tmp_path, config, "ml-platform")
+
+        assert cm.get_profile() == "ml-platform"
+        assert cm.get_registry_url() == "registry.ml-ns.svc:5000"
+        assert cm.get_repository() == "ml"
+        assert cm.get_image_types() == ["notebook", "serving"]
+        assert cm.get_tag_naming_pattern().pattern == DOMINO_TAG_PATTERN
+        assert cm.profile_drift == []
+
+    def test_config_overrides_profile_with_warning(self, tmp_path):
+        """Test that config.yaml still wins over a selected profile, and the difference is reported as drift"""
+        cm = self.load(tmp_path, {"profile": "ml-platform", "profiles": CUSTOM, "registry": {"repository": "other"}})
+
+        assert cm.get_repository() == "other"
+        assert cm.get_image_types() == ["notebook", "serving"]
+        assert len(cm.profile_drift) == 1
+
+    def test_implicit_domino_profile_has_no_drift(self, tmp_path):
+        """Test that overriding the default profile, as most installs do, is not reported"""
+        cm = self.load(tmp_path, {"registry": {"url": "123.dkr.ecr.us-west-2.amazonaws.com"}})
+
+        assert cm.get_profile() == DOMINO_PROFILE
+        assert cm.get_image_types() == ["environment", "model"]
+        assert cm.profile_drift == []
+
+    def test_unknown_profile_fails_validation(self, tmp_path):
+        """Test that an unknown profile is a configuration error"""
+        cm = self.load(tmp_path, {}, "missing")
+
+        with pytest.raises(ConfigValidationError, match="Unknown profile 'missing'"):
+            cm.get_profile()
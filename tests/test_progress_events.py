@@ -0,0 +1,94 @@
+"""Unit tests for progress_events.py"""
+
+import os
+import sys
+import threading
+from datetime import datetime, timezone
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cache_utils import DigestInspectCache
+from utils.image_data_analysis import ImageAnalyzer
+from utils.progress_events import (
+    ERROR,
+    PHASE_CHANGED,
+    PHASE_FINISH_REPOSITORY,
+    PHASE_SCAN,
+    REPOSITORY_DONE,
+    REPOSITORY_STARTED,
+    TAG_DONE,
+    ProgressEvents,
+)
+from utils.synthetic_registry import SyntheticSkopeoClient, generate_dataset
+
+NOW = datetime(2025, 7, 1, tzinfo=timezone.utc)
+
+
+class TestProgressEvents:
+    """Tests for the progress events channel"""
+
+    def test_events_from_many_threads_are_delivered_in_sequence(self):
+        """Test that concurrent emitters produce one gapless, ordered sequence for every subscriber"""
+        events = ProgressEvents()
+        received = []
+        events.subscribe(received.append)
+        queued = events.queue()
+
+        def emit_tags(repository):
+            for number in range(50):
+                events.tag_done(repository, f"t{number}", number + 1, 50, "inspected")
+
+        threads = [threading.Thread(target=emit_tags, args=(f"repo-{n}",)) for n in range(4)]
+        for thread in threads:
+            thread.start()
+        for thread in threads:
+            thread.join()
+
+        assert [event.sequence for event in received] == list(range(1, 201))
+        assert [queued.get_nowait().sequence for _ in range(200)] == list(range(1, 201))
+
+    def test_failing_subscriber_does_not_interrupt(self):
+        """Test that a raising subscriber is skipped, an unsubscribed one gets nothing, and to_dict drops unset fields"""
+        events = ProgressEvents()
+        received = []
+        events.subscribe(lambda event: 1 / 0)
+        unsubscribe = events.subscribe(received.append)
+
+        event = events.phase_changed(PHASE_SCAN)
+        unsubscribe()
+        events.phase_changed(PHASE_SCAN)
+
+        assert received == [event]
+        assert set(event.to_dict()) == {"kind", "sequence", "timestamp", "phase"}
+
+    def test_scan_events(self):
+        """Test that a scan reports its phases, repositories, tags and failures"""
+        dataset = generate_dataset(1, 3, seed=3, now=NOW)
+        analyzer = ImageAnalyzer("synthetic", "bench", skopeo_client=SyntheticSkopeoClient(dataset, "bench"))
+        analyzer.inspect_cache = DigestInspectCache(None)
+        analyzer.progress = ProgressEvents()
+        received = []
+        analyzer.progress.subscribe(received.append)
+        failing = dataset.repositories["repo-000"][0].tag
+        inspect = analyzer._inspect_single_tag_by_digest
+
+        def flaky_inspect(image_type, tag, *args):
+            if tag == failing:
+                raise TimeoutError("timed out")
+            return inspect(image_type, tag, *args)
+
+        with patch.object(analyzer, "_inspect_single_tag_by_digest", flaky_inspect):
+            assert analyzer.analyze_images(["repo-000"], max_workers=1) == ["repo-000"]
+
+        kinds = [event.kind for event in received]
+        assert kinds[:2] == [PHASE_CHANGED, REPOSITORY_STARTED]
+        assert kinds.count(TAG_DONE) == 3 and kinds.count(ERROR) == 1
+        assert kinds[-2:] == [PHASE_CHANGED, REPOSITORY_DONE]
+        assert received[-2].phase == PHASE_FINISH_REPOSITORY
+        assert received[-1].status == "complete"
+        tags = [event for event in received if event.kind == TAG_DONE]
+        assert [event.completed for event in tags] == [1, 2, 3]
+        assert {event.tag: event.status for event in tags}[failing] == "failed"
+        error = next(event for event in received if event.kind == ERROR)
+        assert (error.repository, error.tag) == ("repo-000", failing)
@@ -0,0 +1,89 @@
+"""Unit tests for protection_manifest.py"""
+
+import json
+import os
+import sys
+import tempfile
+from datetime import datetime, timezone
+
+import yaml
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.protection_manifest import (
+    ANNOTATION_IMAGE_COUNT,
+    DEFAULT_CONFIGMAP_NAME,
+    protected_references,
+    protection_configmap,
+    save_manifest,
+)
+
+PROTECTED = [
+    {
+        "image_id": "model:m-1",
+        "repository": "domino/model",
+        "tag": "m-1",
+        "protected_by": ["models"],
+        "estimated_savings_bytes": 10,
+    },
+    {
+        "image_id": "environment:e-1",
+        "repository": "domino/environment",
+        "tag": "e-1",
+        "protected_by": ["workspaces", "projects"],
+        "estimated_savings_bytes": 20,
+    },
+]
+
+
+class TestProtectedReferences:
+    """Tests for listing protected images as full references"""
+
+    def test_references_sorted_with_digests(self):
+        """References are prefixed with the registry, sorted, and carry known digests"""
+        references = protected_references(PROTECTED, "registry:5000", {"environment:e-1": "sha256:eee"})
+        assert references == [
+            {
+                "image": "registry:5000/domino/environment:e-1",
+                "digest": "sha256:eee",
+                "protected_by": ["workspaces", "projects"],
+            },
+            {"image": "registry:5000/domino/model:m-1", "digest": "", "protected_by": ["models"]},
+        ]
+
+
+class TestProtectionConfigMap:
+    """Tests for the ConfigMap manifest"""
+
+    def setup_method(self):
+        """Manifest of the two protected images"""
+        self.references = protected_references(PROTECTED, "registry:5000")
+        self.manifest = protection_configmap(
+            self.references, "registry:5000", "domino-platform", generated_at=datetime(2026, 1, 1, tzinfo=timezone.utc)
+        )
+
+    def test_metadata(self):
+        """The ConfigMap is named, namespaced and annotated with the image count"""
+        assert self.manifest["apiVersion"] == "v1"
+        assert self.manifest["kind"] == "ConfigMap"
+        assert self.manifest["metadata"]["name"] == DEFAULT_CONFIGMAP_NAME
+        assert self.manifest["metadata"]["namespace"] == "domino-platform"
+        assert self.manifest["metadata"]["annotations"][ANNOTATION_IMAGE_COUNT] == "2"
+
+    def test_data_lists_images(self):
+        """images.txt lists one reference per line, images.json the full entries"""
+        data = self.manifest["data"]
+        assert data["images.txt"] == "registry:5000/domino/environment:e-1\nregistry:5000/domino/model:m-1\n"
+        assert json.loads(data["images.json"]) == self.references
+
+    def test_saved_as_yaml(self):
+        """The saved manifest loads back as the same ConfigMap"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = save_manifest(self.manifest, os.path.join(tmpdir, "protected.yaml"))
+            with open(path) as f:
+                assert yaml.safe_load(f) == self.manifest
+
+    def test_empty_protection_set(self):
+        """Without protected images, the data is empty but still valid"""
+        manifest = protection_configmap([], "registry:5000", "domino-platform")
+        assert manifest["data"] == {"images.txt": "", "images.json": "[]"}
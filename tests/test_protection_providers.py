@@ -0,0 +1,144 @@
+"""Unit tests for utils/protection_providers.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+from types import SimpleNamespace
+from unittest.mock import MagicMock
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from tests.helpers import add_image, make_analyzer
+from utils.image_data_analysis import ImageAnalyzer
+from utils.protection_providers import (
+    DominoUsageProvider,
+    EcrPullTimeProvider,
+    ImageMatcher,
+    KubernetesProvider,
+    ProtectionProviderError,
+    ReferenceFileProvider,
+    collect_protections,
+    describe_protection,
+)
+
+NOW = datetime(2025, 1, 1, tzinfo=timezone.utc)
+DIGEST_A = "sha256:" + "a" * 64
+DIGEST_B = "sha256:" + "b" * 64
+
+
+def _make_analyzer() -> ImageAnalyzer:
+    """Create an ImageAnalyzer with an environment image and a model image"""
+    analyzer = make_analyzer("registry.example.com:5000", "domino")
+    add_image(analyzer, "environment:v1", [], digest=DIGEST_A)
+    add_image(analyzer, "model:m1", [], digest=DIGEST_B)
+    return analyzer
+
+
+def _pod(namespace: str, name: str, images: list, image_ids: list = ()) -> SimpleNamespace:
+    """A pod as returned by the Kubernetes client"""
+    return SimpleNamespace(
+        metadata=SimpleNamespace(namespace=namespace, name=name),
+        spec=SimpleNamespace(containers=[SimpleNamespace(image=image) for image in images], init_containers=None),
+        status=SimpleNamespace(
+            container_statuses=[SimpleNamespace(image_id=image_id) for image_id in image_ids],
+            init_container_statuses=None,
+        ),
+    )
+
+
+class TestImageMatcher:
+    """Tests for matching outside references to analyzed images"""
+
+    def test_references(self):
+        """Test tag, repository, full and digest references, and references to other registries"""
+        matcher = ImageMatcher(_make_analyzer())
+
+        assert matcher.match("environment:v1") == ["environment:v1"]
+        assert matcher.match("m1") == ["model:m1"]
+        assert matcher.match("domino/model:m1") == ["model:m1"]
+        assert matcher.match("registry.example.com:5000/domino/environment:v1") == ["environment:v1"]
+        assert matcher.match(f"docker-pullable://registry.example.com:5000/domino/model@{DIGEST_B}") == ["model:m1"]
+        assert matcher.match(DIGEST_A) == ["environment:v1"]
+        assert matcher.match("quay.io/domino/environment:v1") == []
+        assert matcher.match(f"domino/environment@{DIGEST_B}") == []
+
+
+class TestProviders:
+    """Tests for the individual protection providers"""
+
+    def setup_method(self):
+        """Set up the analyzer"""
+        self.analyzer = _make_analyzer()
+
+    def test_domino_usage(self):
+        """Test that only tags current configuration references are protected"""
+        usage = {
+            "v1": {"use_count": 3, "last_used": None, "protected_by": []},
+            "m1": {"use_count": 0, "last_used": None, "protected_by": ["models"]},
+        }
+
+        assert DominoUsageProvider(usage).protected_images(self.analyzer) == {"model:m1": ["models"]}
+
+    def test_kubernetes_pods(self):
+        """Test that pods protect images by tag and by the digest they run, in the configured namespaces"""
+        core_v1 = MagicMock()
+        core_v1.list_namespaced_pod.return_value.items = [
+            _pod("compute", "run-1", ["registry.example.com:5000/domino/environment:v1"]),
+            _pod("compute", "model-1", ["registry.example.com:5000/domino/model:latest"], [f"docker://{DIGEST_B}"]),
+            _pod("compute", "other", ["nginx:1.25"]),
+        ]
+
+        protected = KubernetesProvider(["compute"], core_v1).protected_images(self.analyzer)
+
+        assert protected == {"environment:v1": ["pod compute/run-1"], "model:m1": ["pod compute/model-1"]}
+        core_v1.list_namespaced_pod.assert_called_once_with("compute")
+
+    def test_reference_file(self, tmp_path):
+        """Test that listed images are protected with their line, skipping comments"""
+        path = tmp_path / "keep.txt"
+        path.write_text("# keep these\n\nenvironment:v1  release candidate\nunknown:tag\n")
+
+        protected = ReferenceFileProvider(str(path)).protected_images(self.analyzer)
+
+        assert protected == {"environment:v1": [f"{path}:3"]}
+
+    def test_ecr_pull_time(self):
+        """Test that images pulled within the window are protected and older or never-pulled ones are not"""
+        ecr = MagicMock()
+        pages = {
+            "domino/environment": [{"imageDetails": [{"imageDigest": DIGEST_A, "lastRecordedPullTime": NOW}]}],
+            "domino/model": [{"imageDetails": [{"imageDigest": DIGEST_B}]}],
+        }
+        ecr.get_paginator.return_value.paginate.side_effect = lambda repositoryName: pages[repositoryName]
+
+        assert EcrPullTimeProvider(ecr, 30, now=NOW).protected_images(self.analyzer) == {
+            "environment:v1": ["pulled 2025-01-01"]
+        }
+        assert EcrPullTimeProvider(ecr, 30, now=datetime(2025, 3, 1, tzinfo=timezone.utc)).protected_images(
+            self.analyzer
+        ) == {}
+
+
+class TestCollectProtections:
+    """Tests for combining providers"""
+
+    def test_combined_by_provider(self):
+        """Test that every provider protecting an image is recorded"""
+        usage = {"v1": {"use_count": 0, "last_used": None, "protected_by": ["workspaces"]}}
+        core_v1 = MagicMock()
+        core_v1.list_pod_for_all_namespaces.return_value.items = [_pod("compute", "run-1", ["domino/environment:v1"])]
+
+        protections = collect_protections(
+            _make_analyzer(), [DominoUsageProvider(usage), KubernetesProvider(core_v1=core_v1)]
+        )
+
+        assert protections == {"environment:v1": {"domino": ["workspaces"], "kubernetes": ["pod compute/run-1"]}}
+        description = describe_protection(protections["environment:v1"])
+        assert description == "domino (workspaces); kubernetes (pod compute/run-1)"
+
+    def test_failing_provider_fails_closed(self, tmp_path):
+        """Test that a provider that cannot be asked raises instead of protecting nothing"""
+        with pytest.raises(ProtectionProviderError, match="reference-file"):
+            collect_protections(_make_analyzer(), [ReferenceFileProvider(str(tmp_path / "missing.txt"))])
@@ -0,0 +1,98 @@
+"""Unit tests for utils/provenance.py"""
+
+import base64
+import json
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.provenance import attestation_references, is_provenance_layer, parse_provenance
+
+
+def _envelope(statement: dict) -> bytes:
+    """Wrap an in-toto statement in a DSSE envelope"""
+    payload = base64.b64encode(json.dumps(statement).encode()).decode()
+    return json.dumps({"payloadType": "application/vnd.in-toto+json", "payload": payload, "signatures": []}).encode()
+
+
+class TestParseProvenance:
+    """Tests for extracting builder and source from attestation layers"""
+
+    def test_slsa_v02_in_dsse_envelope(self):
+        """Test that a DSSE-wrapped SLSA v0.2 statement yields its builder and config source"""
+        statement = {
+            "_type": "https://in-toto.io/Statement/v0.1",
+            "predicateType": "https://slsa.dev/provenance/v0.2",
+            "predicate": {
+                "builder": {"id": "https://github.com/slsa-framework/slsa-github-generator"},
+                "buildType": "https://github.com/slsa-framework/slsa-github-generator/container@v1",
+                "invocation": {
+                    "configSource": {
+                        "uri": "git+https://github.com/example/app@refs/heads/main",
+                        "digest": {"sha1": "4f1c"},
+                    }
+                },
+            },
+        }
+
+        provenance = parse_provenance(_envelope(statement))
+
+        assert provenance["builder_id"] == "https://github.com/slsa-framework/slsa-github-generator"
+        assert provenance["source"] == "git+https://github.com/example/app@refs/heads/main"
+        assert provenance["revision"] == "4f1c"
+
+    def test_slsa_v1_statement(self):
+        """Test that a bare SLSA v1 statement yields its builder and first resolved dependency"""
+        statement = {
+            "predicateType": "https://slsa.dev/provenance/v1",
+            "predicate": {
+                "buildDefinition": {
+                    "buildType": "https://mobyproject.org/buildkit@v1",
+                    "externalParameters": {},
+                    "resolvedDependencies": [
+                        {"uri": "https://github.com/example/app.git#main", "digest": {"gitCommit": "9e2b"}}
+                    ],
+                },
+                "runDetails": {"builder": {"id": "https://ci.example.com/runner"}},
+            },
+        }
+
+        provenance = parse_provenance(json.dumps(statement).encode())
+
+        assert provenance == {
+            "predicate_type": "https://slsa.dev/provenance/v1",
+            "builder_id": "https://ci.example.com/runner",
+            "build_type": "https://mobyproject.org/buildkit@v1",
+            "source": "https://github.com/example/app.git#main",
+            "revision": "9e2b",
+        }
+
+    def test_other_predicates_and_garbage_are_ignored(self):
+        """Test that SBOM statements and unparseable blobs are not provenance"""
+        sbom = {"predicateType": "https://spdx.dev/Document", "predicate": {}}
+
+        assert parse_provenance(_envelope(sbom)) is None
+        assert parse_provenance(b"not json") is None
+
+
+class TestAttestationLookup:
+    """Tests for choosing which attestations and layers to read"""
+
+    def test_layers_and_references(self):
+        """Test that annotated non-provenance layers are skipped and referrers come before reference tags"""
+        dsse = "application/vnd.dsse.envelope.v1+json"
+        referrers = [
+            {"digest": "sha256:att", "artifactType": "application/vnd.dev.sigstore.bundle.v0.3+json+dsse"},
+            {"digest": "sha256:sig", "artifactType": "application/vnd.dev.cosign.artifact.sig.v1+json"},
+        ]
+
+        slsa = {"predicateType": "https://slsa.dev/provenance/v1"}
+        spdx = {"predicateType": "https://spdx.dev/Document"}
+
+        assert is_provenance_layer({"mediaType": dsse})
+        assert is_provenance_layer({"mediaType": dsse, "annotations": slsa})
+        assert not is_provenance_layer({"mediaType": dsse, "annotations": spdx})
+        assert not is_provenance_layer({"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip"})
+        assert attestation_references(referrers, ["sha256-abc.att"]) == ["sha256:att", "sha256-abc.att"]
+        assert attestation_references(None, []) == []
@@ -0,0 +1,44 @@
+"""Unit tests for pull_availability.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from tests.helpers import add_image, make_analyzer
+from utils.pull_availability import pull_alternatives
+
+BASE = ("base", 5000)
+ENV = ("env", 1000)
+OTHER = ("other", 2000)
+
+
+class TestPullAlternatives:
+    """Tests for finding surviving images that provide deleted content"""
+
+    def setup_method(self):
+        """Set up images sharing a digest or layers across and within repositories"""
+        self.analyzer = make_analyzer()
+        add_image(self.analyzer, "environment:e1", [BASE, ENV], digest="sha256:a")
+        add_image(self.analyzer, "environment:e1-alias", [BASE, ENV], digest="sha256:a")
+        add_image(self.analyzer, "model:m1", [BASE, ENV], digest="sha256:a")
+        add_image(self.analyzer, "environment:e2", [BASE, OTHER], digest="sha256:b")
+        add_image(self.analyzer, "environment:e2-rebuild", [BASE, OTHER], digest="sha256:c")
+        add_image(self.analyzer, "environment:e3", [OTHER, BASE], digest="sha256:d")
+
+    def test_same_digest_elsewhere_or_same_layers(self):
+        """Test that a copy in another repository or an image with the same layers keeps the content pullable"""
+        alternatives = pull_alternatives(self.analyzer, ["environment:e1", "environment:e2"])
+
+        assert alternatives == {
+            "environment:e1": ["test-repo/model:m1"],
+            "environment:e2": ["test-repo/environment:e2-rebuild"],
+        }
+
+    def test_same_manifest_alias_and_deleted_images_do_not_count(self):
+        """Test that aliases of the deleted manifest, deleted images and reordered layers are not alternatives"""
+        deleted = ["environment:e1", "model:m1", "environment:e3"]
+
+        # The alias names e1's manifest, which is deleted with e1, so it is no alternative for m1 either
+        assert pull_alternatives(self.analyzer, deleted) == {}
+        assert pull_alternatives(self.analyzer, ["environment:e1"], survivors=["environment:e1-alias"]) == {}
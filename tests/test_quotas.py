@@ -0,0 +1,93 @@
+"""Unit tests for utils/quotas.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.chargeback import build_chargeback
+from utils.cleanup_plan import PlanItem
+from utils.quotas import BYTES_PER_GB, check_owner_quotas, over_quota_owners, prioritize_over_quota
+
+QUOTAS = {"default_bytes": 10 * BYTES_PER_GB, "owners": {"data-science": 50 * BYTES_PER_GB, "retired": BYTES_PER_GB}}
+
+
+def usage(owner, amortized_bytes):
+    """Owner usage entry"""
+    return {
+        "owner": owner,
+        "images": 1,
+        "total_bytes": amortized_bytes,
+        "exclusive_bytes": 0,
+        "shared_bytes": amortized_bytes,
+        "amortized_bytes": amortized_bytes,
+    }
+
+
+def item(image_id, reason="not in use"):
+    """Plan item"""
+    return PlanItem(
+        image_id=image_id,
+        repository="dominodatalab/environment",
+        tag=image_id.split(":")[1],
+        digest="sha256:" + image_id.split(":")[1],
+        size_bytes=1,
+        expected_freed_bytes=1,
+        reason=reason,
+    )
+
+
+class TestCheckOwnerQuotas:
+    """Tests for measuring owners against their quotas"""
+
+    def test_owners_over_quota(self):
+        """Test owner and default quotas, shares, and that configured owners without images are included"""
+        statuses = check_owner_quotas(
+            [usage("forecasting", 25 * BYTES_PER_GB), usage("data-science", 75 * BYTES_PER_GB)],
+            {**QUOTAS, "prioritize_plans": False},
+        )
+
+        assert [status["owner"] for status in statuses] == ["data-science", "forecasting", "retired"]
+        assert statuses[0]["over_bytes"] == 25 * BYTES_PER_GB
+        assert statuses[1]["over_bytes"] == 15 * BYTES_PER_GB
+        assert statuses[1]["share"] == 0.25
+        assert statuses[2]["used_bytes"] == 0 and statuses[2]["over_bytes"] == 0
+        assert over_quota_owners(statuses) == ["data-science", "forecasting"]
+
+    def test_owners_without_quota_are_left_out(self):
+        """Test that owners are not measured when there is no default quota"""
+        quotas = {"default_bytes": 0, "owners": {"data-science": BYTES_PER_GB}, "prioritize_plans": False}
+
+        statuses = check_owner_quotas([usage("forecasting", 20 * BYTES_PER_GB)], quotas)
+
+        assert [status["owner"] for status in statuses] == ["data-science"]
+        assert over_quota_owners(statuses) == []
+
+    def test_chargeback_lists_quotas(self):
+        """Test that the chargeback bill shows each owner's quota and overage"""
+        charges = build_chargeback(
+            [usage("data-science", 60 * BYTES_PER_GB), usage("forecasting", 5 * BYTES_PER_GB)],
+            None,
+            0.023,
+            {"default_bytes": 0, "owners": {"data-science": 50 * BYTES_PER_GB}, "prioritize_plans": False},
+        )
+
+        assert charges[0]["quota_bytes"] == 50 * BYTES_PER_GB
+        assert charges[0]["over_quota_bytes"] == 10 * BYTES_PER_GB
+        assert charges[1]["quota_bytes"] is None and charges[1]["over_quota_bytes"] is None
+
+
+class TestPrioritizeOverQuota:
+    """Tests for putting images of owners over quota first in plans"""
+
+    def test_order_and_reason(self):
+        """Test that items are grouped by how far their owner is over quota, keeping the order otherwise"""
+        items = [item("environment:a"), item("environment:b"), item("environment:c", reason=""), item("model:d")]
+        owners = {"environment:a": "ok", "environment:b": "second", "environment:c": "first", "model:d": "second"}
+
+        ordered = prioritize_over_quota(items, owners, ["first", "second"])
+
+        assert [entry.image_id for entry in ordered] == ["environment:c", "environment:b", "model:d", "environment:a"]
+        assert ordered[0].reason == "owner first over quota"
+        assert ordered[1].reason == "not in use, owner second over quota"
+        assert ordered[3].reason == "not in use"
@@ -0,0 +1,113 @@
+"""Unit tests for read_only.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.read_only import ReadOnlyError, command_refusal, ensure_writable, read_only_mongo_client
+from utils.registry_http import RegistryHttpClient
+from utils.skopeo_client import _writes_to_registry
+
+
+class TestCommandRefusal:
+    """Tests for refusing mutating commands before they start"""
+
+    def test_mutating_commands_refused(self):
+        """Test that --apply, mirroring, restores and registry GC are refused"""
+        assert command_refusal("delete_unused_environments", ["--apply", "--force"])
+        assert command_refusal("apply", ["--plan", "plan.json", "--apply"])
+        assert command_refusal("mirror", ["--to", "archive.example.com"])
+        assert command_refusal("backup_restore", ["restore", "--tags", "v1"])
+        assert command_refusal("backup_restore", ["backup", "--delete"])
+        assert command_refusal("run_registry_gc", [])
+
+    def test_read_only_commands_allowed(self):
+        """Test that reports, dry runs and backups to S3 may run"""
+        assert command_refusal("image_size_report", []) is None
+        assert command_refusal("delete_unused_environments", []) is None
+        assert command_refusal("mirror", ["--dry-run"]) is None
+        assert command_refusal("backup_restore", ["backup", "--tags", "v1"]) is None
+        assert command_refusal("backup_restore", ["restore", "--dry-run"]) is None
+
+    def test_top_level_apply_refused(self):
+        """Test that the top-level --apply, forwarded to the script after the check, is refused before it runs"""
+        pytest.importorskip("pymongo")
+        import main
+
+        argv = ["main.py", "--read-only", "--apply", "delete_image", "environment:v1"]
+        with patch.object(sys, "argv", argv), patch.dict(os.environ), patch.object(main.subprocess, "run") as run:
+            with pytest.raises(SystemExit) as exit_info:
+                main.main()
+
+        assert exit_info.value.code == 1
+        run.assert_not_called()
+
+
+class TestEnsureWritable:
+    """Tests for the check mutating code paths make"""
+
+    def test_refused_in_read_only_mode(self):
+        """Test that READ_ONLY refuses changes and cannot be undone by config"""
+        with patch.dict(os.environ, {"READ_ONLY": "true"}):
+            with pytest.raises(ReadOnlyError):
+                ensure_writable("skopeo delete")
+
+    def test_allowed_otherwise(self):
+        """Test that changes are allowed without read-only mode"""
+        with patch.dict(os.environ, {"READ_ONLY": ""}):
+            ensure_writable("skopeo delete")
+
+    def test_skopeo_commands_that_write(self):
+        """Test that deletes and copies or syncs to a registry count as changes, but not copies to a file"""
+        assert _writes_to_registry("delete", ["docker://registry/env:v1"])
+        assert _writes_to_registry("copy", ["--all", "docker://registry/env@sha256:a", "docker://archive/env:v1"])
+        assert _writes_to_registry("sync", ["--src", "yaml", "--dest", "docker", "images.yaml", "archive"])
+        assert not _writes_to_registry("copy", ["docker://registry/env:v1", "docker-archive:/tmp/env.tar"])
+        assert not _writes_to_registry("inspect", ["docker://registry/env:v1"])
+
+    def test_registry_http_refuses_writes(self):
+        """Test that the native client refuses a manifest push before sending anything"""
+        client = RegistryHttpClient("registry:5000")
+        with patch.dict(os.environ, {"READ_ONLY": "true"}), patch.object(client, "_open") as send:
+            with pytest.raises(ReadOnlyError):
+                client.put_manifest("env", "v1", b"{}", "application/vnd.oci.image.manifest.v1+json")
+            send.assert_not_called()
+
+
+class TestReadOnlyMongoClient:
+    """Tests for the MongoDB client used in read-only mode"""
+
+    def setup_method(self):
+        """Set up a client whose collections are mocks"""
+        pymongo_collection = pytest.importorskip("pymongo.collection")
+        pymongo_database = pytest.importorskip("pymongo.database")
+        self.collection = MagicMock(spec=pymongo_collection.Collection)
+        self.collection.full_name = "domino.environments_v2"
+        database = MagicMock(spec=pymongo_database.Database)
+        database.name = "domino"
+        database.__getitem__.return_value = self.collection
+        client = MagicMock()
+        client.__getitem__.return_value = database
+        self.client = read_only_mongo_client(client)
+
+    def test_reads_pass_through(self):
+        """Test that queries reach the collection"""
+        self.collection.find_one.return_value = {"_id": 1}
+
+        with patch.dict(os.environ, {"READ_ONLY": "true"}):
+            assert self.client["domino"]["environments_v2"].find_one({"_id": 1}) == {"_id": 1}
+
+    def test_writes_refused(self):
+        """Test that writes, and aggregations writing to a collection, are refused"""
+        with patch.dict(os.environ, {"READ_ONLY": "true"}):
+            collection = self.client["domino"]["environments_v2"]
+            with pytest.raises(ReadOnlyError):
+                collection.delete_one({"_id": 1})
+            with pytest.raises(ReadOnlyError):
+                collection.aggregate([{"$match": {}}, {"$out": "copy"}])
+        self.collection.delete_one.assert_not_called()
+        self.collection.aggregate.assert_not_called()
@@ -0,0 +1,34 @@
+"""Unit tests for redaction.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.redaction import DEFAULT_REDACT_KEYS, REDACTED, is_sensitive_key, redact_env, redact_labels
+
+
+class TestRedaction:
+    """Tests for redacting sensitive image labels and Env values"""
+
+    def test_default_patterns(self):
+        """Test that credential-like keys match the default patterns regardless of case"""
+        assert is_sensitive_key("DB_PASSWORD", DEFAULT_REDACT_KEYS)
+        assert is_sensitive_key("com.example.api-token", DEFAULT_REDACT_KEYS)
+        assert is_sensitive_key("AWS_SECRET_ACCESS_KEY", DEFAULT_REDACT_KEYS)
+        assert not is_sensitive_key("owner", DEFAULT_REDACT_KEYS)
+        assert not is_sensitive_key("PATH", DEFAULT_REDACT_KEYS)
+
+    def test_redact_labels(self):
+        """Test that only the values of matching labels are replaced, and unknown labels stay unknown"""
+        labels = {"owner": "data-science", "internal.registry_url": "https://registry.corp"}
+
+        assert redact_labels(labels, ["*_url"]) == {"owner": "data-science", "internal.registry_url": REDACTED}
+        assert redact_labels(None, ["*_url"]) is None
+
+    def test_redact_env(self):
+        """Test that matching Env entries keep their name, and entries without a value are kept"""
+        env = ["PATH=/usr/bin", "API_TOKEN=abc=def", "DEBUG"]
+
+        assert redact_env(env, DEFAULT_REDACT_KEYS) == ["PATH=/usr/bin", f"API_TOKEN={REDACTED}", "DEBUG"]
+        assert redact_env(None, DEFAULT_REDACT_KEYS) is None
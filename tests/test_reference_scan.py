@@ -0,0 +1,50 @@
+"""Unit tests for utils/reference_scan.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.reference_scan import ENVIRONMENT_ID, REVISION_ID, TAG, field_values, resolve_references
+
+ENV_ID = "507f1f77bcf86cd799439011"
+REV_ID = "507f1f77bcf86cd799439021"
+
+
+class TestFieldValues:
+    """Tests for reading dotted field paths"""
+
+    def test_lists_along_the_path(self):
+        """Test that lists along the path are searched and empty values are dropped"""
+        document = {
+            "steps": [{"environment": {"id": "a"}}, {"environment": {"id": ["b", "c"]}}, {"other": 1}],
+            "empty": None,
+        }
+
+        assert field_values(document, "steps.environment.id") == ["a", "b", "c"]
+        assert field_values(document, "empty") == []
+        assert field_values(document, "missing.path") == []
+
+
+class TestResolveReferences:
+    """Tests for resolving field values to Docker tags"""
+
+    def test_kinds(self):
+        """Test that revision IDs, environment IDs and tags resolve, and unknown IDs are left out"""
+        revision_field = {"collection": "environments_v2", "field": "activeRevisionId", "kind": REVISION_ID}
+        environment_field = {"collection": "launchers", "field": "environmentId", "kind": ENVIRONMENT_ID}
+        tag_field = {"collection": "custom", "field": "image.tag", "kind": TAG}
+        raw_references = [
+            (revision_field, "env1", REV_ID),
+            (environment_field, "launcher1", ENV_ID),
+            (environment_field, "launcher2", "507f1f77bcf86cd799439099"),
+            (tag_field, "doc1", "hand-pushed"),
+        ]
+
+        references = resolve_references(raw_references, {REV_ID: f"{ENV_ID}-4"}, {ENV_ID: REV_ID})
+
+        assert [(r["collection"], r["document_id"], r["environment_docker_tag"]) for r in references] == [
+            ("environments_v2", "env1", f"{ENV_ID}-4"),
+            ("launchers", "launcher1", f"{ENV_ID}-4"),
+            ("custom", "doc1", "hand-pushed"),
+        ]
@@ -0,0 +1,89 @@
+"""Unit tests for utils/registry_compare.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cleanup_plan import PlanItem
+from utils.registry_compare import DIFFERS, MATCH, ONLY_IN_A, compare_plan, compare_repository, compare_tags, summarize
+
+
+class FakeRegistry:
+    """Registry client with fixed tags and digests"""
+
+    def __init__(self, repositories):
+        self.repositories = repositories
+
+    def list_tags(self, repository):
+        return list(self.repositories.get(repository, {}))
+
+    def get_manifest_digest(self, repository, tag):
+        digest = self.repositories[repository][tag]
+        if digest == "error":
+            raise OSError("connection reset")
+        return digest
+
+
+class TestCompareTags:
+    """Tests for comparing the tags of one repository"""
+
+    def test_missing_and_mismatched_tags(self):
+        """Test that tags on one side only and differing digests are reported, and unreadable ones kept apart"""
+        comparison = compare_tags(
+            "repo/environment",
+            ["a", "b", "c", "d"],
+            ["b", "c", "d", "e"],
+            {"b": "sha256:1", "c": "sha256:2", "d": None},
+            {"b": "sha256:1", "c": "sha256:3", "d": "sha256:4"},
+        )
+
+        assert comparison["status"] == DIFFERS
+        assert comparison["tags_only_in_a"] == ["a"]
+        assert comparison["tags_only_in_b"] == ["e"]
+        assert comparison["digest_mismatches"] == [{"tag": "c", "digest_a": "sha256:2", "digest_b": "sha256:3"}]
+        assert comparison["unreadable_tags"] == ["d"]
+
+    def test_statuses(self):
+        """Test identical repositories, repositories on one side only, and tag-only comparisons"""
+        assert compare_tags("r", ["a"], ["a"], {"a": "sha256:1"}, {"a": "sha256:1"})["status"] == MATCH
+        assert compare_tags("r", ["a"], [])["status"] == ONLY_IN_A
+        assert compare_tags("r", ["a"], ["a"])["digest_mismatches"] == []
+
+
+class TestCompareRepository:
+    """Tests for comparing repositories through registry clients"""
+
+    def test_compares_common_tags_by_digest(self):
+        """Test that common tags are compared by digest and failed lookups are unreadable"""
+        primary = FakeRegistry({"repo/model": {"v1": "sha256:1", "v2": "sha256:2", "v3": "sha256:3"}})
+        mirror = FakeRegistry({"repo/model": {"v1": "sha256:1", "v2": "sha256:old", "v3": "error"}})
+
+        comparison = compare_repository(primary, mirror, "repo/model", max_workers=2)
+        missing = compare_repository(primary, mirror, "repo/environment", max_workers=2)
+        summary = summarize([comparison, missing])
+
+        assert comparison["digest_mismatches"] == [{"tag": "v2", "digest_a": "sha256:2", "digest_b": "sha256:old"}]
+        assert comparison["unreadable_tags"] == ["v3"]
+        assert missing["status"] == "empty"
+        assert summary["digest_mismatches"] == 1
+        assert summary["repositories_matching"] == 1
+        assert summary["identical"] is False
+
+    def test_compare_plan(self):
+        """Test that a backup is checked for every planned image at its planned digest, ignoring other tags"""
+        backup = FakeRegistry({"repo/model": {"v1": "sha256:1", "v2": "sha256:old", "v9": "sha256:9"}})
+        items = [
+            PlanItem("model:v1", "repo/model", "v1", "sha256:1"),
+            PlanItem("model:v2", "repo/model", "v2", "sha256:2"),
+            PlanItem("model:v3", "repo/model", "v3", "sha256:3"),
+            PlanItem("environment:e1", "repo/environment", "e1", "sha256:e1"),
+        ]
+
+        environment, model = compare_plan(backup, items, max_workers=2)
+
+        assert environment["status"] == ONLY_IN_A
+        assert model["tags_only_in_a"] == ["v3"]
+        assert model["tags_only_in_b"] == []
+        assert model["digest_mismatches"] == [{"tag": "v2", "digest_a": "sha256:2", "digest_b": "sha256:old"}]
+        assert summarize([environment, model])["identical"] is False
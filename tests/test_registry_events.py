@@ -0,0 +1,128 @@
+"""Unit tests for registry_events.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.registry_events import RegistryEventListener, image_type_of, parse_events
+
+MANIFEST_V2 = "application/vnd.docker.distribution.manifest.v2+json"
+
+
+def _event(action, repository="dominodatalab/environment", tag="abc-1", digest="sha256:aaa", media_type=MANIFEST_V2):
+    """A notification event as the registry sends it"""
+    target = {"repository": repository, "digest": digest, "mediaType": media_type}
+    if tag:
+        target["tag"] = tag
+    return {"id": "1", "action": action, "target": target}
+
+
+def _listener(images=None):
+    """Listener over a mocked analyzer of the dominodatalab repository"""
+    analyzer = MagicMock()
+    analyzer.repository = "dominodatalab"
+    analyzer.images = images or {}
+    analyzer.is_scanned_tag.return_value = True
+    analyzer.refresh_tag.return_value = True
+    analyzer.forget_images.side_effect = lambda image_ids: len(image_ids)
+    return RegistryEventListener(analyzer, ["environment", "model"])
+
+
+class TestParseEvents:
+    """Tests for reading notification envelopes"""
+
+    def test_manifest_push_and_delete(self):
+        """Manifest pushes and deletes are kept; blob pushes and pulls are not"""
+        envelope = {
+            "events": [
+                _event("push"),
+                _event("push", tag=None, media_type="application/octet-stream"),
+                _event("pull"),
+                _event("delete", tag=None, media_type=None),
+            ]
+        }
+        assert parse_events(envelope) == [
+            {"action": "push", "repository": "dominodatalab/environment", "tag": "abc-1", "digest": "sha256:aaa"},
+            {"action": "delete", "repository": "dominodatalab/environment", "tag": None, "digest": "sha256:aaa"},
+        ]
+
+    def test_malformed_envelope(self):
+        """Envelopes without an events list are rejected"""
+        with pytest.raises(ValueError):
+            parse_events({"event": []})
+
+    def test_image_type_of(self):
+        """Only direct children of the configured repository have an image type"""
+        assert image_type_of("dominodatalab/environment", "dominodatalab") == "environment"
+        assert image_type_of("other/environment", "dominodatalab") is None
+        assert image_type_of("dominodatalab/a/b", "dominodatalab") is None
+
+
+class TestRegistryEventListener:
+    """Tests for applying events to the index"""
+
+    def test_submit_filters_repositories(self):
+        """Only events for the followed image types are queued"""
+        listener = _listener()
+        envelope = {"events": [_event("push"), _event("push", repository="dominodatalab/other"), _event("pull")]}
+        assert listener.submit(envelope) == 1
+        assert listener.status()["events_received"] == 1
+
+    def test_push_refreshes_tag(self):
+        """A tagged manifest push inspects that tag"""
+        listener = _listener()
+        assert listener.apply(parse_events({"events": [_event("push")]})[0])
+        listener.analyzer.refresh_tag.assert_called_once_with("environment", "abc-1")
+
+    def test_push_of_skipped_tag_ignored(self):
+        """Pushes by digest alone and of tags a scan skips leave the index alone"""
+        listener = _listener()
+        assert not listener.apply({"action": "push", "repository": "dominodatalab/model", "tag": None, "digest": "d"})
+        listener.analyzer.is_scanned_tag.return_value = False
+        assert not listener.apply({"action": "push", "repository": "dominodatalab/model", "tag": "x.sig", "digest": "d"})
+        listener.analyzer.refresh_tag.assert_not_called()
+
+    def test_failed_push_raises(self):
+        """A pushed tag that cannot be inspected is reported as a failure"""
+        listener = _listener()
+        listener.analyzer.refresh_tag.return_value = False
+        with pytest.raises(RuntimeError):
+            listener.apply(parse_events({"events": [_event("push")]})[0])
+
+    def test_delete_by_digest_removes_every_tag(self):
+        """Deleting a manifest removes every tag of the repository pointing to it"""
+        listener = _listener(
+            {
+                "environment:a": {"repository": "dominodatalab/environment", "tag": "a", "digest": "sha256:aaa"},
+                "environment:b": {"repository": "dominodatalab/environment", "tag": "b", "digest": "sha256:aaa"},
+                "environment:c": {"repository": "dominodatalab/environment", "tag": "c", "digest": "sha256:ccc"},
+                "model:a": {"repository": "dominodatalab/model", "tag": "a", "digest": "sha256:aaa"},
+            }
+        )
+        assert listener.apply(parse_events({"events": [_event("delete", tag=None, media_type=None)]})[0])
+        listener.analyzer.forget_images.assert_called_once_with(["environment:a", "environment:b"])
+
+    def test_delete_by_tag(self):
+        """Deleting a tag removes only that tag"""
+        listener = _listener()
+        listener.apply(parse_events({"events": [_event("delete", tag="old-1", media_type=None)]})[0])
+        listener.analyzer.forget_images.assert_called_once_with(["environment:old-1"])
+
+    def test_snapshot_saved_when_changed(self):
+        """Snapshots are saved only after a change, and not more often than the interval"""
+        save_snapshot = MagicMock(return_value="scan-snapshot.json")
+        listener = _listener()
+        listener._save_snapshot = save_snapshot
+        listener.snapshot_interval_seconds = 3600
+
+        assert listener.maybe_save_snapshot() is None
+        listener._dirty = True
+        assert listener.maybe_save_snapshot(force=True) == "scan-snapshot.json"
+        listener._dirty = True
+        assert listener.maybe_save_snapshot() is None
+        assert save_snapshot.call_count == 1
+        assert listener.status()["last_snapshot"] == "scan-snapshot.json"
@@ -0,0 +1,282 @@
+"""Unit tests for registry_http.py"""
+
+import base64
+import io
+import json
+import os
+import sys
+import tempfile
+import urllib.error
+from email.message import Message
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.registry_http import RegistryHttpClient, read_auth_file_credentials
+
+
+def _response(headers: dict = None, body: bytes = b""):
+    """Create a mock urlopen response usable as a context manager"""
+    response = MagicMock()
+    response.headers = headers or {}
+    response.read.return_value = body
+    response.__enter__.return_value = response
+    return response
+
+
+def _http_error(url: str, code: int, headers: dict = None):
+    """Create an HTTPError with the given status and headers"""
+    message = Message()
+    for name, value in (headers or {}).items():
+        message[name] = value
+    return urllib.error.HTTPError(url, code, "error", message, io.BytesIO(b""))
+
+
+class TestReadAuthFileCredentials:
+    """Tests for reading credentials from a containers auth.json file"""
+
+    def test_reads_matching_registry(self):
+        """Test that the base64 auth entry for the registry is decoded"""
+        auth = base64.b64encode(b"AWS:secret:with:colons").decode("ascii")
+        with tempfile.TemporaryDirectory() as tmpdir:
+            path = os.path.join(tmpdir, "auth.json")
+            with open(path, "w") as f:
+                json.dump({"auths": {"registry.example.com": {"auth": auth}}}, f)
+
+            assert read_auth_file_credentials(path, "registry.example.com") == ("AWS", "secret:with:colons")
+            assert read_auth_file_credentials(path, "other.example.com") is None
+
+    def test_missing_file(self):
+        """Test that a missing auth file yields no credentials"""
+        assert read_auth_file_credentials("/nonexistent/auth.json", "registry.example.com") is None
+
+
+class TestHeadManifestDigest:
+    """Tests for resolving tag digests with manifest HEAD requests"""
+
+    def test_returns_docker_content_digest(self):
+        """Test that the digest is read from the Docker-Content-Digest header"""
+        client = RegistryHttpClient("registry.example.com")
+
+        with patch("urllib.request.urlopen") as mock_urlopen:
+            mock_urlopen.return_value = _response({"Docker-Content-Digest": "sha256:abc"})
+            digest = client.head_manifest_digest("myrepo/environment", "v1")
+
+            request = mock_urlopen.call_args[0][0]
+            assert digest == "sha256:abc"
+            assert request.get_method() == "HEAD"
+            assert request.full_url == "https://registry.example.com/v2/myrepo/environment/manifests/v1"
+
+    def test_missing_tag_returns_none(self):
+        """Test that a 404 means the tag does not exist"""
+        client = RegistryHttpClient("registry.example.com")
+
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            assert client.head_manifest_digest("myrepo/environment", "gone") is None
+
+    def test_answers_bearer_challenge(self):
+        """Test that a Bearer challenge is answered with a token fetched using the credentials"""
+        client = RegistryHttpClient("registry.example.com", "user", "pass")
+        challenge = _http_error(
+            "url",
+            401,
+            {"WWW-Authenticate": 'Bearer realm="https://auth.example.com/token",service="registry.example.com"'},
+        )
+        responses = [
+            challenge,
+            _response(body=json.dumps({"token": "tok123"}).encode("utf-8")),
+            _response({"Docker-Content-Digest": "sha256:abc"}),
+        ]
+
+        with patch("urllib.request.urlopen", side_effect=responses) as mock_urlopen:
+            digest = client.head_manifest_digest("myrepo/environment", "v1")
+
+            token_request = mock_urlopen.call_args_list[1][0][0]
+            retried_request = mock_urlopen.call_args_list[2][0][0]
+            assert digest == "sha256:abc"
+            assert "scope=repository%3Amyrepo%2Fenvironment%3Apull" in token_request.full_url
+            assert token_request.get_header("Authorization").startswith("Basic ")
+            assert retried_request.get_header("Authorization") == "Bearer tok123"
+
+    def test_falls_back_to_http(self):
+        """Test that a registry without TLS is reached over plain http and the scheme is remembered"""
+        client = RegistryHttpClient("registry.example.com:5000")
+        responses = [
+            urllib.error.URLError("wrong version number"),
+            _response({"Docker-Content-Digest": "sha256:abc"}),
+            _response({"Docker-Content-Digest": "sha256:def"}),
+        ]
+
+        with patch("urllib.request.urlopen", side_effect=responses) as mock_urlopen:
+            client.head_manifest_digest("myrepo/environment", "v1")
+            client.head_manifest_digest("myrepo/environment", "v2")
+
+            urls = [call[0][0].full_url for call in mock_urlopen.call_args_list]
+            assert urls[1].startswith("http://")
+            assert urls[2].startswith("http://")
+
+
+class TestHeadBlobSize:
+    """Tests for sizing blobs with blob HEAD requests"""
+
+    def test_returns_content_length(self):
+        """Test that the blob size is read from the Content-Length header"""
+        client = RegistryHttpClient("registry.example.com")
+
+        with patch("urllib.request.urlopen") as mock_urlopen:
+            mock_urlopen.return_value = _response({"Content-Length": "5000"})
+            size = client.head_blob_size("myrepo/environment", "sha256:base")
+
+            request = mock_urlopen.call_args[0][0]
+            assert size == 5000
+            assert request.full_url == "https://registry.example.com/v2/myrepo/environment/blobs/sha256:base"
+
+    def test_missing_blob_returns_none(self):
+        """Test that a 404 means the blob does not exist"""
+        client = RegistryHttpClient("registry.example.com")
+
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            assert client.head_blob_size("myrepo/environment", "sha256:gone") is None
+
+
+class TestReferrersAndBlobs:
+    """Tests for listing referrers and reading small blobs"""
+
+    def test_referrers_listed_or_unsupported(self):
+        """Test that referrer descriptors are returned, and None when the registry lacks the API"""
+        client = RegistryHttpClient("registry.example.com")
+        index = {"manifests": [{"digest": "sha256:att", "artifactType": "application/vnd.in-toto+json"}]}
+
+        with patch("urllib.request.urlopen", return_value=_response(body=json.dumps(index).encode())) as mock_urlopen:
+            assert client.get_referrers("myrepo/environment", "sha256:abc") == index["manifests"]
+            request = mock_urlopen.call_args[0][0]
+            assert request.full_url == "https://registry.example.com/v2/myrepo/environment/referrers/sha256:abc"
+
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            assert client.get_referrers("myrepo/environment", "sha256:abc") is None
+
+    def test_catalog_follows_pagination(self):
+        """Test that repositories are listed across catalog pages, and None when the catalog is unavailable"""
+        client = RegistryHttpClient("registry.example.com")
+        pages = [
+            _response(
+                {"Link": '</v2/_catalog?last=myrepo%2Fenvironment&n=1000>; rel="next"'},
+                json.dumps({"repositories": ["myrepo/environment"]}).encode(),
+            ),
+            _response(body=json.dumps({"repositories": ["myrepo/model"]}).encode()),
+        ]
+
+        with patch("urllib.request.urlopen", side_effect=pages) as mock_urlopen:
+            assert client.list_repositories() == ["myrepo/environment", "myrepo/model"]
+            urls = [call[0][0].full_url for call in mock_urlopen.call_args_list]
+            assert urls[1] == "https://registry.example.com/v2/_catalog?last=myrepo%2Fenvironment&n=1000"
+
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            assert client.list_repositories() is None
+
+    def test_blob_size_limit(self):
+        """Test that blobs larger than max_bytes are not returned"""
+        client = RegistryHttpClient("https://registry.example.com")
+
+        with patch("urllib.request.urlopen", return_value=_response(body=b"0123456789")):
+            assert client.get_blob("myrepo/environment", "sha256:small", max_bytes=10) == b"0123456789"
+            assert client.get_blob("myrepo/environment", "sha256:large", max_bytes=5) is None
+
+    def test_blob_streamed_through_reader(self):
+        """Test that read_blob passes the open response to the reader, and missing blobs give None"""
+        client = RegistryHttpClient("https://registry.example.com")
+        response = _response(body=b"layer")
+
+        with patch("urllib.request.urlopen", return_value=response):
+            assert client.read_blob("myrepo/environment", "sha256:layer", lambda stream: stream.read()) == b"layer"
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            assert client.read_blob("myrepo/environment", "sha256:gone", lambda stream: stream.read()) is None
+
+
+class TestManifestCopy:
+    """Tests for copying manifests within a registry by mounting blobs"""
+
+    def test_manifest_fetched_and_pushed_as_stored(self):
+        """Test that manifests are read with their media type and pushed with the same bytes"""
+        client = RegistryHttpClient("https://registry.example.com")
+        manifest = b'{"schemaVersion": 2}'
+
+        with patch("urllib.request.urlopen") as mock_urlopen:
+            media_type = "application/vnd.oci.image.manifest.v1+json"
+            mock_urlopen.return_value = _response({"Content-Type": media_type}, manifest)
+            fetched = client.get_manifest("myrepo/environment", "sha256:abc")
+            mock_urlopen.return_value = _response({"Docker-Content-Digest": "sha256:abc"})
+            pushed = client.put_manifest("archive/environment", "v1", manifest, fetched[1])
+
+            request = mock_urlopen.call_args[0][0]
+        assert fetched == (manifest, "application/vnd.oci.image.manifest.v1+json")
+        assert pushed == "sha256:abc"
+        assert request.get_method() == "PUT"
+        assert request.data == manifest
+        assert request.full_url == "https://registry.example.com/v2/archive/environment/manifests/v1"
+
+    def test_mount_accepted(self):
+        """Test that a 201 response means the blob was mounted"""
+        client = RegistryHttpClient("https://registry.example.com")
+        response = _response()
+        response.status = 201
+
+        with patch("urllib.request.urlopen", return_value=response) as mock_urlopen:
+            assert client.mount_blob("archive/environment", "sha256:layer", "myrepo/environment") is True
+
+            request = mock_urlopen.call_args[0][0]
+        assert request.get_method() == "POST"
+        assert request.full_url == (
+            "https://registry.example.com/v2/archive/environment/blobs/uploads/"
+            "?mount=sha256%3Alayer&from=myrepo%2Fenvironment"
+        )
+
+    def test_refused_mount_cancels_upload(self):
+        """Test that the upload a refused mount starts is deleted"""
+        client = RegistryHttpClient("https://registry.example.com")
+        started = _response({"Location": "/v2/archive/environment/blobs/uploads/1234?_state=x"})
+        started.status = 202
+
+        with patch("urllib.request.urlopen", side_effect=[started, _response()]) as mock_urlopen:
+            assert client.mount_blob("archive/environment", "sha256:layer", "myrepo/environment") is False
+
+            cancel = mock_urlopen.call_args[0][0]
+        assert cancel.get_method() == "DELETE"
+        assert cancel.full_url == "https://registry.example.com/v2/archive/environment/blobs/uploads/1234?_state=x"
+
+    def test_token_requested_for_both_repositories(self):
+        """Test that a mount asks for push access to the destination and pull access to the source"""
+        client = RegistryHttpClient("https://registry.example.com", "user", "pass")
+        challenge = _http_error("url", 401, {"WWW-Authenticate": 'Bearer realm="https://auth.example.com/token"'})
+        mounted = _response()
+        mounted.status = 201
+
+        with patch(
+            "urllib.request.urlopen", side_effect=[challenge, _response(body=b'{"token": "t"}'), mounted]
+        ) as mock_urlopen:
+            assert client.mount_blob("archive/environment", "sha256:layer", "myrepo/environment") is True
+
+            token_request = mock_urlopen.call_args_list[1][0][0]
+        assert "scope=repository%3Aarchive%2Fenvironment%3Apull%2Cpush" in token_request.full_url
+        assert "scope=repository%3Amyrepo%2Fenvironment%3Apull" in token_request.full_url
+
+
+class TestCircuitBreakerReporting:
+    """Tests for reporting request outcomes to the circuit breaker"""
+
+    def test_server_errors_fail_and_not_found_succeeds(self):
+        """Test that 503 counts as a failure while 404 shows the registry is answering"""
+        breaker = MagicMock()
+        client = RegistryHttpClient("registry.example.com", circuit_breaker=breaker)
+
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 503)):
+            with pytest.raises(urllib.error.HTTPError):
+                client.head_manifest_digest("myrepo/environment", "v1")
+        with patch("urllib.request.urlopen", side_effect=_http_error("url", 404)):
+            client.head_manifest_digest("myrepo/environment", "gone")
+
+        assert breaker.before_request.call_count == 2
+        breaker.record_failure.assert_called_once()
+        breaker.record_success.assert_called_once()
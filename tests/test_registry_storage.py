@@ -0,0 +1,120 @@
+"""Unit tests for utils/registry_storage.py"""
+
+import hashlib
+import json
+import os
+import sys
+import tempfile
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.registry_storage import FilesystemRegistryStorage, find_orphans
+
+
+class _RegistryLayout:
+    """Writes a registry storage layout (docker/registry/v2) into a directory"""
+
+    def __init__(self, path: str):
+        self.root = os.path.join(path, "docker", "registry", "v2")
+
+    def _write(self, relative_path: str, data: bytes) -> None:
+        full_path = os.path.join(self.root, relative_path)
+        os.makedirs(os.path.dirname(full_path), exist_ok=True)
+        with open(full_path, "wb") as f:
+            f.write(data)
+
+    def blob(self, data: bytes) -> str:
+        """Store a blob and return its digest"""
+        hex_digest = hashlib.sha256(data).hexdigest()
+        self._write(f"blobs/sha256/{hex_digest[:2]}/{hex_digest}/data", data)
+        return f"sha256:{hex_digest}"
+
+    def manifest(self, repository: str, blobs: list, tags: list = (), children: list = ()) -> str:
+        """Store a manifest referencing the given blob digests and point the given tags at it"""
+        body = {"schemaVersion": 2, "layers": [{"digest": d, "size": 1} for d in blobs]}
+        if children:
+            body = {"schemaVersion": 2, "manifests": [{"digest": d} for d in children]}
+        digest = self.blob(json.dumps(body).encode("utf-8"))
+        hex_digest = digest.split(":", 1)[1]
+        self._write(f"repositories/{repository}/_manifests/revisions/sha256/{hex_digest}/link", digest.encode())
+        for tag in tags:
+            self.tag(repository, tag, digest)
+        return digest
+
+    def tag(self, repository: str, tag: str, digest: str) -> None:
+        """Point a tag at a manifest digest"""
+        self._write(f"repositories/{repository}/_manifests/tags/{tag}/current/link", digest.encode())
+
+    def remove_blob(self, digest: str) -> None:
+        """Delete a blob's data file"""
+        hex_digest = digest.split(":", 1)[1]
+        os.remove(os.path.join(self.root, f"blobs/sha256/{hex_digest[:2]}/{hex_digest}/data"))
+
+
+class TestFindOrphans:
+    """Tests for scanning registry storage for orphaned content"""
+
+    def setup_method(self):
+        """Create an empty registry layout in a temporary directory"""
+        self.tmpdir = tempfile.TemporaryDirectory()
+        self.layout = _RegistryLayout(self.tmpdir.name)
+        self.storage = FilesystemRegistryStorage(self.tmpdir.name)
+
+    def teardown_method(self):
+        """Remove the temporary directory"""
+        self.tmpdir.cleanup()
+
+    def test_tagged_content_is_not_orphaned(self):
+        """Test that tagged manifests and their blobs are not reported"""
+        layer = self.layout.blob(b"layer")
+        self.layout.manifest("dominodatalab/environment", [layer], tags=["env1"])
+
+        report = find_orphans(self.storage)
+
+        assert report["summary"]["manifests"] == 1
+        assert report["untagged_manifests"] == []
+        assert report["broken_manifests"] == []
+        assert report["unreferenced_blobs"] == []
+
+    def test_untagged_manifest(self):
+        """Test that a manifest no tag points to is reported as untagged"""
+        layer = self.layout.blob(b"layer")
+        self.layout.manifest("dominodatalab/environment", [layer], tags=["env1"])
+        old = self.layout.manifest("dominodatalab/environment", [layer, self.layout.blob(b"old")])
+
+        report = find_orphans(self.storage)
+
+        assert [m["digest"] for m in report["untagged_manifests"]] == [old]
+        assert report["unreferenced_blobs"] == []
+
+    def test_manifest_list_children_are_tagged(self):
+        """Test that platform manifests of a tagged manifest list are not untagged"""
+        child = self.layout.manifest("dominodatalab/model", [self.layout.blob(b"amd64")])
+        self.layout.manifest("dominodatalab/model", [], tags=["m1"], children=[child])
+
+        report = find_orphans(self.storage)
+
+        assert report["untagged_manifests"] == []
+
+    def test_unreferenced_blob(self):
+        """Test that a blob no manifest references is reported with its size"""
+        self.layout.manifest("dominodatalab/environment", [self.layout.blob(b"layer")], tags=["env1"])
+        stray = self.layout.blob(b"stray-upload")
+
+        report = find_orphans(self.storage)
+
+        assert report["unreferenced_blobs"] == [{"digest": stray, "size_bytes": len(b"stray-upload")}]
+        assert report["summary"]["unreferenced_bytes"] == len(b"stray-upload")
+
+    def test_broken_manifests(self):
+        """Test that missing layer blobs and dangling tags are reported as broken"""
+        layer = self.layout.blob(b"layer")
+        digest = self.layout.manifest("dominodatalab/environment", [layer], tags=["env1"])
+        self.layout.remove_blob(layer)
+        self.layout.tag("dominodatalab/environment", "dangling", "sha256:" + "0" * 64)
+
+        report = find_orphans(self.storage)
+        reasons = {(b["digest"], tuple(b["tags"])): b["reason"] for b in report["broken_manifests"]}
+
+        assert "missing blob" in reasons[(digest, ("env1",))]
+        assert "not in the repository" in reasons[("sha256:" + "0" * 64, ("dangling",))]
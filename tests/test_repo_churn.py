@@ -0,0 +1,80 @@
+"""Unit tests for repo_churn.py"""
+
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.repo_churn import SORT_BY_TAGS, rank_repositories, repository_churn
+
+
+def snapshot(created_at, images, layers):
+    """A snapshot document with images given as (repository, tag, layers)"""
+    return {
+        "created_at": created_at,
+        "images": {
+            f"{repository.rsplit('/', 1)[-1]}:{tag}": {"repository": repository, "tag": tag, "layers": image_layers}
+            for repository, tag, image_layers in images
+        },
+        "layers": layers,
+    }
+
+
+LAYERS = {"base": 1000, "env-a": 200, "env-b": 300, "model-a": 50}
+
+
+class TestRepositoryChurn:
+    """Tests for churn across snapshots"""
+
+    def setup_method(self):
+        """Set up three snapshots a week apart"""
+        self.snapshots = [
+            snapshot(
+                "2026-01-01T00:00:00+00:00",
+                [("repo/environment", "e1", ["base", "env-a"]), ("repo/model", "m1", ["base", "model-a"])],
+                LAYERS,
+            ),
+            snapshot(
+                "2026-01-08T00:00:00+00:00",
+                [("repo/environment", "e1", ["base", "env-a"]), ("repo/environment", "e2", ["base", "env-b"])],
+                LAYERS,
+            ),
+            snapshot("2026-01-15T00:00:00+00:00", [("repo/environment", "e2", ["base", "env-b"])], LAYERS),
+        ]
+
+    def test_weekly_rates(self):
+        """Test that tags added and removed and new layer bytes are totalled and divided by the weeks covered"""
+        churn = repository_churn(self.snapshots)
+
+        assert churn["snapshots"] == 3
+        assert churn["weeks"] == 2
+        environment = churn["repositories"]["repo/environment"]
+        assert (environment["tags_added"], environment["tags_removed"], environment["bytes_added"]) == (1, 1, 300)
+        assert environment["tags_added_per_week"] == 0.5
+        assert environment["bytes_added_per_week"] == 150
+        assert (environment["tags"], environment["bytes"]) == (1, 1300)
+        model = churn["repositories"]["repo/model"]
+        assert (model["tags_added"], model["tags_removed"], model["bytes_added"], model["tags"]) == (0, 1, 0, 0)
+
+    def test_needs_two_snapshots_apart(self):
+        """Test that a single snapshot, or snapshots taken at once, give no rate"""
+        with pytest.raises(ValueError):
+            repository_churn(self.snapshots[:1])
+        with pytest.raises(ValueError):
+            repository_churn([self.snapshots[0], self.snapshots[0]])
+
+    def test_ranking(self):
+        """Test that repositories rank by bytes or by tags changed, and unchanged ones are left out"""
+        for number, document in enumerate(self.snapshots):
+            # A repository re-tagging the same layers every week, and one that never changes
+            document["images"][f"busy:b{number}"] = {"repository": "repo/busy", "tag": f"b{number}", "layers": ["base"]}
+            document["images"]["other:o1"] = {"repository": "repo/other", "tag": "o1", "layers": ["base"]}
+        churn = repository_churn(self.snapshots)["repositories"]
+
+        by_bytes = [entry["repository"] for entry in rank_repositories(churn)]
+        by_tags = [entry["repository"] for entry in rank_repositories(churn, SORT_BY_TAGS)]
+
+        assert by_bytes == ["repo/environment", "repo/busy", "repo/model"]
+        assert by_tags == ["repo/busy", "repo/environment", "repo/model"]
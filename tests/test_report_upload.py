@@ -0,0 +1,105 @@
+"""Unit tests for report_upload.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+from unittest.mock import MagicMock, patch
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.report_upload import (
+    ReportUploadError,
+    UploadTarget,
+    find_run_reports,
+    parse_upload_url,
+    report_key,
+    upload_reports,
+)
+
+
+class TestParseUploadUrl:
+    """Tests for parsing report upload destinations"""
+
+    def test_supported_destinations(self):
+        """Test S3, GCS and both forms of Azure Blob URLs"""
+        assert parse_upload_url("s3://bucket/registry-cleaner/") == UploadTarget("s3", "bucket", "registry-cleaner")
+        assert parse_upload_url("gs://bucket") == UploadTarget("gs", "bucket", "")
+        assert parse_upload_url("az://account/reports/cleaner") == UploadTarget(
+            "az", "reports", "cleaner", account="account"
+        )
+        assert parse_upload_url("https://account.blob.core.windows.net/reports") == UploadTarget(
+            "az", "reports", "", account="account"
+        )
+
+    def test_unsupported_destinations(self):
+        """Test that other schemes and incomplete URLs are rejected"""
+        for url in ("https://example.com/reports", "s3://", "az://account", "/tmp/reports"):
+            with pytest.raises(ReportUploadError):
+                parse_upload_url(url)
+
+
+class TestRunReports:
+    """Tests for selecting and naming a run's reports"""
+
+    def test_find_run_reports(self, tmp_path):
+        """Test that only reports written during the run are selected, without checkpoints or hidden files"""
+        old = tmp_path / "final-report.json"
+        old.write_text("{}")
+        os.utime(old, (1000, 1000))
+        for name in ("cleanup-plan-2026-01-01-02-00-07.json", "report.html", ".state.json", "run.log"):
+            (tmp_path / name).write_text("{}")
+        (tmp_path / "checkpoints").mkdir()
+        (tmp_path / "checkpoints" / "scan.json").write_text("{}")
+
+        reports = find_run_reports(tmp_path, since=2000)
+
+        assert [path.name for path in reports] == ["cleanup-plan-2026-01-01-02-00-07.json", "report.html"]
+        assert find_run_reports(tmp_path / "missing", since=0) == []
+
+    def test_report_key(self, tmp_path):
+        """Test that reports are stored per command and run start in UTC"""
+        run_started = datetime(2026, 1, 1, 2, 0, 0, tzinfo=timezone.utc)
+        path = tmp_path / "final-report.json"
+
+        assert report_key(parse_upload_url("s3://bucket/cleaner/"), "plan", run_started, path) == (
+            "cleaner/plan/2026-01-01T02-00-00Z/final-report.json"
+        )
+        assert report_key(parse_upload_url("gs://bucket"), "plan", run_started, path) == (
+            "plan/2026-01-01T02-00-00Z/final-report.json"
+        )
+
+
+class TestUploadReports:
+    """Tests for uploading reports to S3"""
+
+    def test_upload_to_s3(self, tmp_path):
+        """Test that every report is uploaded, and that a failed upload is reported after trying the rest"""
+        first = tmp_path / "a.json"
+        second = tmp_path / "b.json"
+        first.write_text("{}")
+        second.write_text("{}")
+        run_started = datetime(2026, 1, 1, tzinfo=timezone.utc)
+        s3 = MagicMock()
+        boto3 = MagicMock()
+        boto3.client.return_value = s3
+
+        with patch.dict(sys.modules, {"boto3": boto3}):
+            uploaded = upload_reports("s3://bucket/cleaner", [first, second], "scan", run_started)
+
+            assert uploaded == [
+                "s3://bucket/cleaner/scan/2026-01-01T00-00-00Z/a.json",
+                "s3://bucket/cleaner/scan/2026-01-01T00-00-00Z/b.json",
+            ]
+            s3.upload_file.assert_any_call(
+                str(first),
+                "bucket",
+                "cleaner/scan/2026-01-01T00-00-00Z/a.json",
+                ExtraArgs={"ContentType": "application/json"},
+            )
+
+            s3.upload_file.side_effect = [Exception("AccessDenied"), None]
+            with pytest.raises(ReportUploadError, match="1 of 2 report"):
+                upload_reports("s3://bucket/cleaner", [first, second], "scan", run_started)
+            assert s3.upload_file.call_count == 4
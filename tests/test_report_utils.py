@@ -1,5 +1,6 @@
 """Unit tests for report_utils.py"""
 
+import gzip
 import json
 import os
 
@@ -9,7 +10,15 @@ import tempfile
 
 sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
 
-from utils.report_utils import save_json, save_table_and_json
+from pathlib import Path
+from unittest.mock import ANY
+
+from utils.report_utils import find_report_file, get_latest_report, load_json, save_json, save_table_and_json
+
+
+def with_run_metadata(data):
+    """A saved document: the data after its run metadata block"""
+    return {"runMetadata": ANY, **data}
 
 
 class TestSaveJson:
@@ -26,7 +35,7 @@ class TestSaveJson:
             assert os.path.exists(file_path)
             with open(file_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == data
+            assert loaded == with_run_metadata(data)
 
     def test_save_nested_dict(self):
         """Test saving a nested dictionary"""
@@ -38,7 +47,7 @@ class TestSaveJson:
 
             with open(file_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == data
+            assert loaded == with_run_metadata(data)
             assert loaded["level1"]["level2"]["level3"] == "value"
             assert loaded["list"] == [1, 2, 3]
 
@@ -92,7 +101,7 @@ class TestSaveJson:
 
             with open(file_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == data2
+            assert loaded == with_run_metadata(data2)
             assert "old" not in loaded
 
     def test_save_complex_data(self):
@@ -109,7 +118,7 @@ class TestSaveJson:
 
             with open(file_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == data
+            assert loaded == with_run_metadata(data)
             assert len(loaded["details"]) == 2
             assert loaded["summary"]["total"] == 100
 
@@ -123,7 +132,7 @@ class TestSaveJson:
 
             with open(file_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == {}
+            assert loaded == with_run_metadata({})
 
     def test_save_empty_list(self):
         """Test saving an empty list"""
@@ -137,6 +146,90 @@ class TestSaveJson:
                 loaded = json.load(f)
             assert loaded == []
 
+    def test_matches_json_dump_output(self):
+        """Test that streamed output is byte-identical to json.dumps with indent=2"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            file_path = os.path.join(tmpdir, "test.json")
+            data = {
+                "summary": {"total": 2, "ratio": 0.5, "ok": True, "missing": None},
+                "empty_dict": {},
+                "empty_list": [],
+                "items": [{"id": 1, "tags": ["a", "b"]}, {"id": 2, "tags": []}, "caf\u00e9"],
+                3: "int key",
+            }
+
+            save_json(file_path, data, run_metadata=False)
+
+            with open(file_path, "r") as f:
+                assert f.read() == json.dumps(data, indent=2)
+
+    def test_run_metadata_first(self):
+        """Test that a saved object starts with the run metadata block, and a list gets none"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            file_path = os.path.join(tmpdir, "test.json")
+
+            save_json(file_path, {"summary": {"total": 1}})
+
+            with open(file_path, "r") as f:
+                loaded = json.load(f)
+            assert list(loaded) == ["runMetadata", "summary"]
+            assert loaded["runMetadata"]["runId"]
+
+            save_json(file_path, [{"runMetadata": "not added"}])
+            with open(file_path, "r") as f:
+                assert json.load(f) == [{"runMetadata": "not added"}]
+
+    def test_generators_written_as_arrays(self):
+        """Test that generators are streamed as JSON arrays without being materialized first"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            file_path = os.path.join(tmpdir, "test.json")
+            data = {
+                "images": ({"image_id": f"environment:tag{i}"} for i in range(3)),
+                "none": (x for x in []),
+            }
+
+            save_json(file_path, data)
+
+            with open(file_path, "r") as f:
+                loaded = json.load(f)
+            assert [image["image_id"] for image in loaded["images"]] == [
+                "environment:tag0",
+                "environment:tag1",
+                "environment:tag2",
+            ]
+            assert loaded["none"] == []
+
+
+class TestCompressedReports:
+    """Tests for saving and reading compressed reports"""
+
+    def test_gzip_round_trip(self):
+        """Test that a gzip report gets a .gz suffix and reads back through load_json"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            file_path = os.path.join(tmpdir, "report.json")
+            data = {"images": [{"tag": "v1"}]}
+
+            saved_path = save_json(file_path, data, compress="gzip")
+
+            assert saved_path == file_path + ".gz"
+            assert not os.path.exists(file_path)
+            with gzip.open(saved_path, "rt") as f:
+                assert json.load(f) == with_run_metadata(data)
+            assert find_report_file(Path(file_path)) == Path(saved_path)
+            assert load_json(Path(saved_path)) == with_run_metadata(data)
+
+    def test_latest_report_includes_compressed(self):
+        """Test that the newest report is found whether or not it was compressed"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            plain = save_json(os.path.join(tmpdir, "report-1.json"), {"n": 1}, compress="")
+            compressed = save_json(os.path.join(tmpdir, "report-2.json"), {"n": 2}, compress="gzip")
+            os.utime(plain, (1, 1))
+
+            latest = get_latest_report("report-*.json", Path(tmpdir))
+
+            assert latest == Path(compressed)
+            assert load_json(latest) == with_run_metadata({"n": 2})
+
 
 class TestSaveTableAndJson:
     """Tests for save_table_and_json function"""
@@ -184,7 +277,7 @@ class TestSaveTableAndJson:
             json_path = base_path + ".json"
             with open(json_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == json_obj
+            assert loaded == with_run_metadata(json_obj)
 
     def test_creates_parent_directories(self):
         """Test that parent directories are created"""
@@ -225,7 +318,7 @@ class TestSaveTableAndJson:
             json_path = base_path + ".json"
             with open(json_path, "r") as f:
                 loaded = json.load(f)
-            assert loaded == {}
+            assert loaded == with_run_metadata({})
 
     def test_overwrites_existing_files(self):
         """Test that existing files are overwritten"""
@@ -246,7 +339,7 @@ class TestSaveTableAndJson:
                 assert "New table" in f.read()
             with open(json_path, "r") as f:
                 loaded = json.load(f)
-                assert loaded == json_obj2
+                assert loaded == with_run_metadata(json_obj2)
 
     def test_complex_table_and_json(self):
         """Test saving complex table and JSON data"""
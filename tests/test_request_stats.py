@@ -0,0 +1,70 @@
+"""Unit tests for utils/request_stats.py"""
+
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.request_stats import RequestStats, _percentile
+from utils.skopeo_client import _operation_name
+
+
+class TestRequestStats:
+    """Tests for recording requests and summarizing them"""
+
+    def test_summary_counts_and_percentiles(self):
+        """Summary gives counts, errors and nearest-rank percentiles in milliseconds"""
+        stats = RequestStats()
+        for i in range(1, 101):
+            stats.record("inspect", i / 1000, error=(i % 25 == 0))
+        stats.record("list-tags", 0.5)
+
+        summary = stats.summary()
+
+        assert list(summary) == ["inspect", "list-tags"]
+        assert summary["inspect"]["count"] == 100
+        assert summary["inspect"]["errors"] == 4
+        assert summary["inspect"]["p50_ms"] == 50.0
+        assert summary["inspect"]["p90_ms"] == 90.0
+        assert summary["inspect"]["p99_ms"] == 99.0
+        assert summary["inspect"]["max_ms"] == 100.0
+        assert summary["inspect"]["total_seconds"] == 5.05
+        assert summary["list-tags"]["errors"] == 0
+        assert summary["list-tags"]["p99_ms"] == 500.0
+
+    def test_percentile_of_small_samples(self):
+        """Percentiles of one or no value do not fail"""
+        assert _percentile([], 50) == 0.0
+        assert _percentile([2.0], 99) == 2.0
+        assert _percentile([1.0, 2.0], 50) == 1.0
+
+    def test_track_records_outcome(self):
+        """track() times a request and counts it as an error when the caller says so or it raises"""
+        stats = RequestStats()
+        with stats.track("delete"):
+            pass
+        with stats.track("delete") as outcome:
+            outcome["error"] = True
+        with pytest.raises(RuntimeError):
+            with stats.track("delete"):
+                raise RuntimeError("boom")
+
+        summary = stats.summary()
+        assert summary["delete"]["count"] == 3
+        assert summary["delete"]["errors"] == 2
+
+        stats.reset()
+        assert stats.summary() == {}
+
+
+class TestOperationName:
+    """Tests for naming skopeo commands in telemetry"""
+
+    def test_inspect_variants(self):
+        """Raw and config inspects are reported separately from full inspects"""
+        assert _operation_name("inspect", ["--raw", "docker://r/x:1"]) == "inspect-raw"
+        assert _operation_name("inspect", ["--config", "docker://r/x:1"]) == "inspect-config"
+        assert _operation_name("inspect", ["docker://r/x:1"]) == "inspect"
+        assert _operation_name("list-tags", ["docker://r/x"]) == "list-tags"
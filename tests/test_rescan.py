@@ -0,0 +1,84 @@
+"""Unit tests for rescan.py"""
+
+import os
+import sys
+from dataclasses import replace
+from datetime import datetime, timezone
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cache_utils import DigestInspectCache
+from utils.error_utils import ERROR_NOT_FOUND
+from utils.image_data_analysis import ImageAnalyzer
+from utils.rescan import rescan, restore_cached_results
+from utils.synthetic_registry import SyntheticSkopeoClient, generate_dataset
+
+NOW = datetime(2025, 7, 1, tzinfo=timezone.utc)
+
+
+class RegistryClient(SyntheticSkopeoClient):
+    """Synthetic registry that can re-push and delete tags, reporting missing tags as not found"""
+
+    def last_error_code(self):
+        return ERROR_NOT_FOUND
+
+    def push(self, image_type, image):
+        path = f"{self.repository}/{image_type}"
+        if image.tag not in self._tags[path]:
+            self._tags[path].append(image.tag)
+        self._images[(path, image.tag)] = image
+        for layer in image.layers:
+            self._blob_sizes[layer.digest] = layer.size
+
+    def delete(self, image_type, tag):
+        path = f"{self.repository}/{image_type}"
+        self._tags[path].remove(tag)
+        del self._images[(path, tag)]
+
+
+class TestRescan:
+    """Tests for refreshing selected repositories and tags of a previous scan"""
+
+    def setup_method(self):
+        self.dataset = generate_dataset(2, 3, seed=3, now=NOW)
+        self.client = RegistryClient(self.dataset, "bench")
+        self.analyzer = ImageAnalyzer("synthetic", "bench", skopeo_client=self.client)
+        self.analyzer.inspect_cache = DigestInspectCache(None)
+        assert self.analyzer.analyze_images(["repo-000", "repo-001"], max_workers=1) == ["repo-000", "repo-001"]
+        self.images = self.dataset.repositories["repo-000"]
+
+    def test_tags_refreshed_and_dropped(self):
+        """Test that a re-pushed tag gets its new digest, a deleted one is dropped, and other repositories stay"""
+        repushed, deleted = self.images[0].tag, self.images[1].tag
+        self.client.push("repo-000", replace(self.dataset.repositories["repo-001"][0], tag=repushed))
+        self.client.delete("repo-000", deleted)
+        self.client.delete("repo-001", self.dataset.repositories["repo-001"][1].tag)
+
+        result = rescan(self.analyzer, ["repo-000"], tags=[repushed, deleted, "never-pushed"])
+
+        assert result["changed"] == [f"repo-000:{repushed}"]
+        assert result["removed"] == [f"repo-000:{deleted}"]
+        assert result["added"] == [] and result["failed"] == []
+        assert self.analyzer.images[f"repo-000:{repushed}"]["digest"] == self.dataset.repositories["repo-001"][0].digest
+        assert len([image_id for image_id in self.analyzer.images if image_id.startswith("repo-001:")]) == 3
+        assert self.analyzer.failures == {}
+
+    def test_repository_rescanned(self):
+        """Test that rescanning a repository without tags replaces the images the scan held for it"""
+        self.client.delete("repo-000", self.images[2].tag)
+        self.client.push("repo-000", replace(self.images[0], tag="new-1"))
+
+        result = rescan(self.analyzer, ["repo-000"], max_workers=1)
+
+        assert result["added"] == ["repo-000:new-1"]
+        assert result["removed"] == [f"repo-000:{self.images[2].tag}"]
+        # new-1 shares every layer of an existing image, so only the deleted image's layers count
+        assert result["failed"] == [] and result["size_delta_bytes"] < 0
+
+    def test_restore_cached_results(self):
+        """Test that the per-digest results of the inspection cache are restored for images loaded without them"""
+        toolchain = dict(self.analyzer.toolchain)
+        self.analyzer.toolchain.clear()
+
+        assert restore_cached_results(self.analyzer) == len(toolchain) == 6
+        assert self.analyzer.toolchain == toolchain
@@ -0,0 +1,329 @@
+"""Unit tests for utils/retention_policy.py"""
+
+import os
+import sys
+from datetime import datetime, timedelta, timezone
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from tests.helpers import add_image, make_analyzer
+from utils.image_data_analysis import ImageAnalyzer
+from utils.retention_policy import (
+    RECENT_RUN_PROTECTION_RULE,
+    PolicyFormatError,
+    evaluate_policy,
+    lint_policy,
+    policy_from_dict,
+)
+
+NOW = datetime(2025, 1, 1, tzinfo=timezone.utc)
+
+
+def _make_analyzer(images: dict) -> ImageAnalyzer:
+    """Create an ImageAnalyzer holding single-layer images of the given ages in days"""
+    analyzer = make_analyzer()
+    for image_id, age_days in images.items():
+        tag = image_id.split(":", 1)[1]
+        created = (NOW - timedelta(days=age_days)).isoformat() if age_days is not None else None
+        add_image(analyzer, image_id, [(f"{tag}-layer", 100)], digest=f"sha256:{tag}", created=created)
+    return analyzer
+
+
+def _actions(decisions: list) -> dict:
+    """Map image_id -> action"""
+    return {d["image_id"]: d["action"] for d in decisions}
+
+
+class TestEvaluatePolicy:
+    """Tests for deciding which images a policy keeps and deletes"""
+
+    def setup_method(self):
+        """Set up old and new environment images and an old model image"""
+        self.analyzer = _make_analyzer(
+            {"environment:old": 400, "environment:old-release": 400, "environment:new": 5, "model:m1": 400}
+        )
+
+    def test_delete_rule_with_keep_override(self):
+        """Test that keep rules win over delete rules that match the same image"""
+        policy = policy_from_dict(
+            {
+                "rules": [
+                    {"name": "keep-releases", "action": "keep", "tags": ["*-release"]},
+                    {"name": "expire", "action": "delete", "repositories": ["environment"], "older_than_days": 180},
+                ]
+            }
+        )
+
+        decisions = evaluate_policy(policy, self.analyzer, now=NOW)
+        by_id = {d["image_id"]: d for d in decisions}
+
+        assert _actions(decisions) == {
+            "environment:new": "keep",
+            "environment:old": "delete",
+            "environment:old-release": "keep",
+            "model:m1": "keep",
+        }
+        assert by_id["environment:old-release"]["rule"] == "keep-releases"
+        assert by_id["environment:old-release"]["matched_rules"] == ["keep-releases", "expire"]
+        assert by_id["model:m1"]["rule"] == "default"
+
+    def test_default_delete(self):
+        """Test that images no rule matches get the default action"""
+        policy = policy_from_dict({"default": "delete", "rules": [{"action": "keep", "newer_than_days": 30}]})
+
+        assert _actions(evaluate_policy(policy, self.analyzer, now=NOW))["environment:new"] == "keep"
+        assert _actions(evaluate_policy(policy, self.analyzer, now=NOW))["model:m1"] == "delete"
+
+    def test_usage_conditions(self):
+        """Test in_use and unused_for_days against tag usage"""
+        usage = {
+            "old": {"use_count": 3, "last_used": NOW - timedelta(days=10), "protected_by": []},
+            "m1": {"use_count": 0, "last_used": None, "protected_by": ["models"]},
+        }
+        policy = policy_from_dict({"rules": [{"action": "delete", "unused_for_days": 30}]})
+
+        actions = _actions(evaluate_policy(policy, self.analyzer, usage, now=NOW))
+
+        assert actions["environment:old"] == "keep"
+        assert actions["model:m1"] == "keep"
+        assert actions["environment:new"] == "delete"
+
+    def test_recent_run_protection(self):
+        """Test that images used within the protection window are kept even when a delete rule matches"""
+        usage = {"old": {"use_count": 1, "last_used": NOW - timedelta(days=10), "protected_by": []}}
+        policy = policy_from_dict({"rules": [{"name": "expire", "action": "delete", "older_than_days": 180}]})
+
+        unprotected = {d["image_id"]: d for d in evaluate_policy(policy, self.analyzer, usage, now=NOW)}
+        protected = {
+            d["image_id"]: d for d in evaluate_policy(policy, self.analyzer, usage, now=NOW, protection_days=30)
+        }
+
+        assert unprotected["environment:old"]["action"] == "delete"
+        assert protected["environment:old"]["action"] == "keep"
+        assert protected["environment:old"]["rule"] == RECENT_RUN_PROTECTION_RULE
+        assert protected["environment:old-release"]["action"] == "delete"
+
+    def test_missing_usage_data_never_deletes(self):
+        """Test that usage conditions without usage data keep images instead of deleting them"""
+        policy = policy_from_dict(
+            {
+                "rules": [
+                    {"name": "keep-used", "action": "keep", "in_use": True},
+                    {"name": "expire-unused", "action": "delete", "in_use": False},
+                ]
+            }
+        )
+
+        decisions = evaluate_policy(policy, self.analyzer, usage=None, now=NOW)
+
+        assert set(_actions(decisions).values()) == {"keep"}
+        assert decisions[0]["rule"] == "keep-used"
+        assert decisions[0]["undetermined_rules"] == ["keep-used", "expire-unused"]
+
+    def test_annotation_conditions(self):
+        """Test annotation rules by short name, and that images without collected annotations are never deleted"""
+        self.analyzer.annotations = {
+            "environment:old": {"org.opencontainers.image.source": "https://github.com/example/app"},
+            "environment:new": {"org.opencontainers.image.source": "https://github.com/example/other"},
+            "model:m1": {},
+        }
+        policy = policy_from_dict(
+            {"rules": [{"name": "expire-app", "action": "delete", "annotations": {"source": "*/example/app"}}]}
+        )
+
+        decisions = evaluate_policy(policy, self.analyzer, now=NOW)
+        by_id = {d["image_id"]: d for d in decisions}
+
+        assert _actions(decisions) == {
+            "environment:new": "keep",
+            "environment:old": "delete",
+            "environment:old-release": "keep",
+            "model:m1": "keep",
+        }
+        assert by_id["environment:old-release"]["undetermined_rules"] == ["expire-app"]
+        assert by_id["model:m1"]["undetermined_rules"] == []
+
+    def test_provenance_conditions(self):
+        """Test has_provenance rules, and that images whose provenance was not collected are never deleted"""
+        self.analyzer.provenance = {
+            "environment:old": None,
+            "environment:new": None,
+            "model:m1": {"predicate_type": "https://slsa.dev/provenance/v1", "builder_id": "ci"},
+        }
+        policy = policy_from_dict(
+            {"rules": [{"name": "expire-unattested", "action": "delete", "has_provenance": False}]}
+        )
+
+        decisions = evaluate_policy(policy, self.analyzer, now=NOW)
+        by_id = {d["image_id"]: d for d in decisions}
+
+        assert _actions(decisions) == {
+            "environment:new": "delete",
+            "environment:old": "delete",
+            "environment:old-release": "keep",
+            "model:m1": "keep",
+        }
+        assert by_id["environment:old-release"]["undetermined_rules"] == ["expire-unattested"]
+        assert lint_policy({"rules": [{"action": "keep", "has_provenance": "yes"}]})[0].message == (
+            "'has_provenance' must be true or false, got: 'yes'"
+        )
+
+    def test_replicate_to(self):
+        """Test that images deleted by a delete rule with replicate_to carry its archive registry"""
+        policy = policy_from_dict(
+            {
+                "rules": [
+                    {"name": "keep-releases", "action": "keep", "tags": ["*-release"], "older_than_days": 1},
+                    {"name": "expire-models", "action": "delete", "repositories": ["model"]},
+                    {"name": "archive-old", "action": "delete", "older_than_days": 180, "replicate_to": "archive:5000"},
+                ]
+            }
+        )
+
+        by_id = {d["image_id"]: d for d in evaluate_policy(policy, self.analyzer, now=NOW)}
+
+        assert by_id["environment:old"]["replicate_to"] == "archive:5000"
+        assert by_id["model:m1"]["rule"] == "expire-models"
+        assert by_id["model:m1"]["replicate_to"] == "archive:5000"
+        assert by_id["environment:old-release"]["replicate_to"] == ""
+        assert by_id["environment:new"]["replicate_to"] == ""
+        assert lint_policy({"rules": [{"action": "keep", "replicate_to": "archive:5000"}]})[0].message == (
+            "'replicate_to' only applies to delete rules"
+        )
+
+
+class TestPolicyFormat:
+    """Tests for parsing policy documents"""
+
+    def test_rule_names_default_to_position(self):
+        """Test that unnamed rules are named after their position"""
+        policy = policy_from_dict({"version": 1, "rules": [{"action": "keep"}, {"action": "delete"}]})
+
+        assert [rule.name for rule in policy.rules] == ["rule-1", "rule-2"]
+
+    def test_invalid_documents(self):
+        """Test that unknown fields, actions and versions are rejected"""
+        with pytest.raises(PolicyFormatError, match="unknown field 'older_than'"):
+            policy_from_dict({"rules": [{"action": "delete", "older_than": 30}]})
+        with pytest.raises(PolicyFormatError, match="action must be one of"):
+            policy_from_dict({"rules": [{"action": "remove"}]})
+        with pytest.raises(PolicyFormatError, match="unsupported policy version"):
+            policy_from_dict({"version": 2})
+        with pytest.raises(PolicyFormatError, match="mapping"):
+            policy_from_dict(["not", "a", "policy"])
+
+
+class TestLintPolicy:
+    """Tests for policy validation"""
+
+    def _messages(self, data: dict, severity: str) -> list:
+        """Issue messages of one severity"""
+        return [f"{i.location}: {i.message}" for i in lint_policy(data) if i.severity == severity]
+
+    def test_field_errors_are_actionable(self):
+        """Test that typos, wrong types and missing actions are reported with a fix"""
+        errors = self._messages(
+            {
+                "rules": [
+                    {"name": "expire", "action": "delete", "older_then_days": 30, "tags": "*-tmp"},
+                    {"name": "expire", "older_than_days": -1},
+                ]
+            },
+            "error",
+        )
+
+        assert "rule 1 (expire): unknown field 'older_then_days' (did you mean 'older_than_days'?)" in errors
+        assert any("write [\"*-tmp\"]" in e for e in errors)
+        assert any(e.startswith("rule 2 (expire): 'action' is required") for e in errors)
+        assert any("whole number of days" in e for e in errors)
+        assert any("duplicate rule name" in e for e in errors)
+
+    def test_contradictory_age_range(self):
+        """Test that a rule whose age range is empty is an error"""
+        errors = self._messages({"rules": [{"action": "delete", "older_than_days": 90, "newer_than_days": 30}]}, "error")
+
+        assert len(errors) == 1
+        assert "never matches" in errors[0]
+
+    def test_keep_and_delete_overlap(self):
+        """Test that a delete rule overlapping a keep rule is a warning"""
+        warnings = self._messages(
+            {
+                "rules": [
+                    {"name": "keep-releases", "action": "keep", "tags": ["*-release"]},
+                    {"name": "expire", "action": "delete", "older_than_days": 180},
+                ]
+            },
+            "warning",
+        )
+
+        assert len(warnings) == 1
+        assert warnings[0].startswith("rule 2 (expire): overlaps keep rule 'keep-releases'")
+
+    def test_delete_rule_shadowed_by_keep_rule(self):
+        """Test that a delete rule fully covered by a keep rule is reported as never deleting"""
+        warnings = self._messages(
+            {
+                "rules": [
+                    {"name": "keep-envs", "action": "keep", "repositories": ["environment"]},
+                    {"name": "expire-envs", "action": "delete", "repositories": ["environment"], "older_than_days": 30},
+                ]
+            },
+            "warning",
+        )
+
+        assert "never deletes anything" in warnings[0]
+
+    def test_annotation_rules(self):
+        """Test annotation validation and overlap checks across short and full keys"""
+        errors = self._messages(
+            {
+                "rules": [
+                    {"action": "delete", "annotations": {"source": "a*", "org.opencontainers.image.source": "b*"}},
+                ]
+            },
+            "error",
+        )
+        issues = lint_policy(
+            {
+                "rules": [
+                    {
+                        "name": "keep-app",
+                        "action": "keep",
+                        "annotations": {"org.opencontainers.image.source": "git.example/app*"},
+                    },
+                    {"name": "expire-other", "action": "delete", "annotations": {"source": "git.example/other*"}},
+                    {
+                        "name": "expire-app-tmp",
+                        "action": "delete",
+                        "annotations": {"source": "git.example/app*"},
+                        "tags": ["tmp-*"],
+                    },
+                ]
+            }
+        )
+
+        assert errors == [
+            "rule 1 (rule-1): annotation 'org.opencontainers.image.source' repeats 'source' "
+            "(both mean org.opencontainers.image.source)"
+        ]
+        assert lint_policy({"rules": [{"action": "keep", "annotations": ["source"]}]})[0].severity == "error"
+        assert [i.location for i in issues] == ["rule 3 (expire-app-tmp)"]
+        assert "never deletes anything" in issues[0].message
+
+    def test_disjoint_rules_are_clean(self):
+        """Test that keep and delete rules that cannot match the same image produce no issues"""
+        issues = lint_policy(
+            {
+                "rules": [
+                    {"action": "keep", "repositories": ["model"]},
+                    {"action": "delete", "repositories": ["environment"], "tags": ["tmp-*"]},
+                    {"action": "delete", "in_use": False, "repositories": ["model*"], "newer_than_days": 1},
+                    {"action": "keep", "in_use": True, "repositories": ["model"]},
+                ]
+            }
+        )
+
+        assert [str(i) for i in issues if "tmp-*" in str(i)] == []
@@ -0,0 +1,87 @@
+"""Unit tests for run_metadata.py"""
+
+import os
+import sys
+from datetime import datetime, timedelta, timezone
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils import run_metadata
+from utils.run_metadata import get_run_id, get_run_metadata, redact_options, reset_run, start_run
+
+
+class TestRunIdentity:
+    """Tests for the run ID and start time shared by the processes of a run"""
+
+    def setup_method(self):
+        """Start each test without a run"""
+        reset_run()
+
+    def teardown_method(self):
+        """Forget the run of the test"""
+        reset_run()
+
+    def test_generated_once_per_process(self):
+        """Test that a process without RUN_ID gets one ID for all its documents"""
+        with patch.dict(os.environ, {}, clear=True):
+            run_id = get_run_id()
+            assert get_run_id() == run_id
+            reset_run()
+            assert get_run_id() != run_id
+
+    def test_inherited_from_parent(self):
+        """Test that a script started by main.py uses the run ID and start time it was given"""
+        environ = {"RUN_ID": "run-1", "RUN_STARTED_AT": "2025-06-01T02:00:00+00:00"}
+        with patch.dict(os.environ, environ, clear=True):
+            metadata = get_run_metadata(now=datetime(2025, 6, 1, 2, 10, 30, tzinfo=timezone.utc))
+
+        assert metadata["runId"] == "run-1"
+        assert metadata["startedAt"] == "2025-06-01T02:00:00+00:00"
+        assert metadata["duration"] == 630.0
+
+    def test_start_run_exports_to_children(self):
+        """Test that starting a run puts its ID and start time in the environment"""
+        with patch.dict(os.environ, {}, clear=True):
+            run_id = start_run()
+
+            assert os.environ["RUN_ID"] == run_id
+            started_at = datetime.fromisoformat(os.environ["RUN_STARTED_AT"])
+            assert datetime.now(timezone.utc) - started_at < timedelta(minutes=1)
+
+
+class TestRunMetadata:
+    """Tests for the metadata block of output documents"""
+
+    def setup_method(self):
+        """Start each test without a run"""
+        reset_run()
+
+    def teardown_method(self):
+        """Forget the run of the test"""
+        reset_run()
+
+    def test_fields(self):
+        """Test that the block names the registry, install, tool version and options of the run"""
+        environ = {"RUN_ENVIRONMENT": "prod-us", "REGISTRY_URL": "registry.example.com:5000"}
+        argv = ["delete_image.py", "environment:abc-1", "--apply"]
+        with patch.dict(os.environ, environ, clear=True), patch.object(run_metadata.sys, "argv", argv):
+            metadata = get_run_metadata()
+
+        assert metadata["registry"] == "registry.example.com:5000"
+        assert metadata["environment"] == "prod-us"
+        assert metadata["toolVersion"]
+        assert metadata["options"] == ["environment:abc-1", "--apply"]
+
+    def test_credentials_redacted(self):
+        """Test that credential option values are not written to reports"""
+        args = ["--creds", "user:secret", "--password=secret", "--password-stdin", "--dest", "archive"]
+
+        assert redact_options(args) == [
+            "--creds",
+            "<redacted>",
+            "--password=<redacted>",
+            "--password-stdin",
+            "--dest",
+            "archive",
+        ]
@@ -0,0 +1,149 @@
+"""Unit tests for utils/s3_inventory.py"""
+
+import gzip
+import hashlib
+import json
+import os
+import sys
+import tempfile
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.registry_storage import RegistryStorage, find_orphans
+from utils.s3_inventory import InventoryRegistryStorage, load_inventory, parse_inventory_csv, reconcile
+
+PREFIX = "docker/registry/v2"
+SCHEMA = "Bucket, Key, VersionId, IsLatest, IsDeleteMarker, Size, ReplicationStatus"
+
+
+class _DictStorage(RegistryStorage):
+    """Registry storage reading files from a dict of path -> bytes"""
+
+    def __init__(self, files: dict):
+        self.files = files
+
+    def list_files(self, prefix):
+        raise AssertionError("the inventory storage must not list the bucket")
+
+    def read(self, path):
+        return self.files.get(path)
+
+
+def _obj(key, size, is_latest=True, is_delete_marker=False, replication_status=""):
+    """Build an inventory object"""
+    return {
+        "key": key,
+        "size_bytes": size,
+        "is_latest": is_latest,
+        "is_delete_marker": is_delete_marker,
+        "replication_status": replication_status,
+    }
+
+
+class TestParseInventory:
+    """Tests for reading S3 Inventory reports"""
+
+    def test_parse_csv_rows(self):
+        """Test that keys are URL-decoded and version fields parsed"""
+        lines = [
+            '"bucket","docker/registry/v2/blobs/sha256/ab/ab%2Bc/data","v1","true","false","10","REPLICA"\n',
+            '"bucket","docker/registry/v2/old","v0","false","false","","COMPLETED"\n',
+        ]
+
+        objects = list(parse_inventory_csv(lines, SCHEMA))
+
+        assert objects[0] == _obj("docker/registry/v2/blobs/sha256/ab/ab+c/data", 10, replication_status="REPLICA")
+        assert objects[1]["is_latest"] is False
+        assert objects[1]["size_bytes"] == 0
+
+    def test_schema_without_size_is_rejected(self):
+        """Test that an inventory without sizes cannot be reconciled"""
+        with pytest.raises(ValueError):
+            list(parse_inventory_csv([], "Bucket, Key"))
+
+    def test_load_downloaded_inventory(self):
+        """Test that a downloaded manifest finds its gzipped data files in the data/ directory beside it"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            os.makedirs(os.path.join(tmpdir, "2026-01-01T01-00Z"))
+            os.makedirs(os.path.join(tmpdir, "data"))
+            with open(os.path.join(tmpdir, "data", "part-1.csv.gz"), "wb") as f:
+                f.write(gzip.compress(b'"bucket","docker/registry/v2/a","","","","5",""\n'))
+            manifest_path = os.path.join(tmpdir, "2026-01-01T01-00Z", "manifest.json")
+            with open(manifest_path, "w") as f:
+                json.dump(
+                    {
+                        "sourceBucket": "registry-bucket",
+                        "fileFormat": "CSV",
+                        "fileSchema": SCHEMA,
+                        "files": [{"key": "registry-bucket/daily/data/part-1.csv.gz"}],
+                    },
+                    f,
+                )
+
+            manifest, objects = load_inventory(manifest_path)
+
+        assert manifest["sourceBucket"] == "registry-bucket"
+        assert objects == [_obj("docker/registry/v2/a", 5)]
+
+    def test_parquet_is_rejected(self):
+        """Test that non-CSV inventories are reported as unsupported"""
+        with tempfile.TemporaryDirectory() as tmpdir:
+            manifest_path = os.path.join(tmpdir, "manifest.json")
+            with open(manifest_path, "w") as f:
+                json.dump({"fileFormat": "Parquet", "files": []}, f)
+
+            with pytest.raises(ValueError, match="CSV"):
+                load_inventory(manifest_path)
+
+
+class TestReconcile:
+    """Tests for attributing stored bytes"""
+
+    def setup_method(self):
+        """Build a bucket with one tagged image, an orphaned blob, upload debris and replication copies"""
+        self.files = {}
+        self.objects = []
+        layer = self._blob(b"layer" * 100)
+        self.orphan = self._blob(b"orphan" * 10)
+        manifest = self._blob(json.dumps({"schemaVersion": 2, "layers": [{"digest": layer}]}).encode())
+        hex_digest = manifest.split(":", 1)[1]
+        self._file(f"repositories/env/_manifests/revisions/sha256/{hex_digest}/link", manifest.encode())
+        self._file("repositories/env/_manifests/tags/v1/current/link", manifest.encode())
+        self.objects.append(_obj(f"{PREFIX}/repositories/env/_uploads/u1/data", 700))
+        self.objects.append(_obj(f"{PREFIX}/repositories/env/_uploads/u1/startedat", 20))
+        self.objects.append(_obj(f"{PREFIX}/blobs/sha256/00/old/data", 300, is_latest=False))
+        self.objects.append(_obj("replica/docker/registry/v2/blobs/x", 50, replication_status="REPLICA"))
+        self.storage = InventoryRegistryStorage(self.objects, _DictStorage(self.files), PREFIX)
+
+    def _file(self, path: str, data: bytes) -> None:
+        self.files[path] = data
+        self.objects.append(_obj(f"{PREFIX}/{path}", len(data)))
+
+    def _blob(self, data: bytes) -> str:
+        hex_digest = hashlib.sha256(data).hexdigest()
+        self._file(f"blobs/sha256/{hex_digest[:2]}/{hex_digest}/data", data)
+        return f"sha256:{hex_digest}"
+
+    def test_gap_is_attributed(self):
+        """Test that the gap between stored and referenced bytes is split into its causes"""
+        orphans = find_orphans(self.storage)
+
+        result = reconcile(self.objects, PREFIX, orphans)
+
+        categories = result["categories"]
+        assert [blob["digest"] for blob in orphans["unreferenced_blobs"]] == [self.orphan]
+        assert categories["orphaned_blobs"]["bytes"] == 60
+        assert categories["upload_debris"] == {"objects": 2, "bytes": 720}
+        assert categories["replication_copies"] == {"objects": 2, "bytes": 350}
+        assert result["summary"]["noncurrent_bytes"] == 300
+        assert result["summary"]["gap_bytes"] == result["summary"]["stored_bytes"] - categories["referenced"]["bytes"]
+        assert result["upload_debris"] == [{"repository": "env", "uploads": 1, "bytes": 720}]
+
+    def test_inventory_storage_lists_current_objects_only(self):
+        """Test that noncurrent versions and objects outside the registry are not listed as registry files"""
+        paths = [path for path, _size in self.storage.list_files("blobs/")]
+
+        assert "blobs/sha256/00/old/data" not in paths
+        assert len(paths) == 3
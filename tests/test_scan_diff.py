@@ -0,0 +1,83 @@
+"""Unit tests for scan_diff.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.scan_diff import diff_scans, has_changes, parse_interval, scan_state
+
+
+def _state(images, total_bytes):
+    """ScanState of (image_id, digest) pairs"""
+    return {
+        "images": {
+            image_id: {"repository": "domino/environment", "tag": image_id, "digest": digest, "size_bytes": 100}
+            for image_id, digest in images
+        },
+        "total_bytes": total_bytes,
+    }
+
+
+class TestParseInterval:
+    """Tests for parsing watch intervals"""
+
+    def test_units(self):
+        """Seconds, minutes, hours and days are supported; no unit means seconds"""
+        assert parse_interval("90s") == 90
+        assert parse_interval("15m") == 900
+        assert parse_interval("1h") == 3600
+        assert parse_interval("1.5h") == 5400
+        assert parse_interval("2d") == 172800
+        assert parse_interval("30") == 30
+
+    def test_invalid(self):
+        """Malformed and zero intervals are rejected"""
+        for value in ("", "1w", "h", "-5m", "0s"):
+            with pytest.raises(ValueError):
+                parse_interval(value)
+
+
+class TestDiffScans:
+    """Tests for comparing two scans"""
+
+    def test_added_removed_changed(self):
+        """New, removed and re-pushed tags are reported with the storage delta"""
+        previous = _state([("a", "sha256:1"), ("b", "sha256:2"), ("c", "sha256:3")], 1000)
+        current = _state([("b", "sha256:2"), ("c", "sha256:9"), ("d", "sha256:4")], 1500)
+        diff = diff_scans(previous, current)
+        assert diff == {"added": ["d"], "removed": ["a"], "changed": ["c"], "size_delta_bytes": 500}
+        assert has_changes(diff)
+
+    def test_no_changes(self):
+        """Identical scans have no changes"""
+        state = _state([("a", "sha256:1")], 1000)
+        assert not has_changes(diff_scans(state, state))
+
+    def test_storage_change_alone(self):
+        """A change in stored bytes alone counts as a change"""
+        diff = diff_scans(_state([("a", "sha256:1")], 1000), _state([("a", "sha256:1")], 900))
+        assert diff["size_delta_bytes"] == -100
+        assert has_changes(diff)
+
+
+class TestScanState:
+    """Tests for condensing an analyzer into a scan state"""
+
+    def test_images_and_total_bytes(self):
+        """Each image keeps its digest and total size; shared layers count once in the total"""
+        analyzer = MagicMock()
+        analyzer.images = {"environment:a": {"repository": "domino/environment", "tag": "a", "digest": "sha256:1"}}
+        analyzer.layers = {"l1": {"size_bytes": 300, "ref_count": 2}, "l2": {"size_bytes": 200, "ref_count": 1}}
+        analyzer.get_image_total_size.return_value = 500
+        state = scan_state(analyzer)
+        assert state["images"]["environment:a"] == {
+            "repository": "domino/environment",
+            "tag": "a",
+            "digest": "sha256:1",
+            "size_bytes": 500,
+        }
+        assert state["total_bytes"] == 500
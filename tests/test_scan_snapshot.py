@@ -0,0 +1,138 @@
+"""Unit tests for utils/scan_snapshot.py"""
+
+import json
+import os
+import sys
+import tempfile
+from datetime import datetime, timedelta, timezone
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.image_data_analysis import ImageAnalyzer
+from utils.scan_snapshot import (
+    SnapshotFormatError,
+    find_snapshots,
+    load_snapshot,
+    prune_snapshots,
+    save_snapshot,
+    select_snapshots_to_prune,
+)
+
+
+class TestScanSnapshot:
+    """Tests for saving and loading scan snapshots"""
+
+    def setup_method(self):
+        """Create a temporary directory for snapshot files"""
+        self.tmpdir = tempfile.TemporaryDirectory()
+        self.path = os.path.join(self.tmpdir.name, "snapshot.json")
+
+    def teardown_method(self):
+        """Remove the temporary directory"""
+        self.tmpdir.cleanup()
+
+    def test_round_trip(self):
+        """Test that images, layer order, image and layer metadata, and usage survive a save and load"""
+        analyzer = ImageAnalyzer("http://test-registry", "test-repo")
+        analyzer.index.add_image("environment:e1", "test-repo/environment", "e1", "sha256:e1", [("b", 10), ("a", 5)])
+        analyzer.index.add_image("model:m1", "test-repo/model", "m1", "sha256:m1", [("b", 10)])
+        analyzer.created["environment:e1"] = "2024-06-01T00:00:00Z"
+        analyzer.annotations["environment:e1"] = {"org.opencontainers.image.revision": "4f1c2a9"}
+        analyzer.labels["model:m1"] = {"owner": "data-science"}
+        analyzer.foreign_layers.add("b")
+        analyzer.layer_media_types["a"] = "application/vnd.oci.image.layer.v1.tar+zstd"
+        last_used = datetime(2024, 12, 1, tzinfo=timezone.utc)
+        usage = {"e1": {"use_count": 2, "last_used": last_used, "protected_by": ["workspaces"]}}
+
+        save_snapshot(analyzer, self.path, usage)
+        loaded, loaded_usage, metadata = load_snapshot(self.path)
+
+        assert loaded.index.snapshot() == analyzer.index.snapshot()
+        assert loaded.created == {"environment:e1": "2024-06-01T00:00:00Z"}
+        assert loaded.annotations == {"environment:e1": {"org.opencontainers.image.revision": "4f1c2a9"}}
+        assert loaded.labels == {"model:m1": {"owner": "data-science"}}
+        assert loaded.foreign_layers == {"b"}
+        assert loaded.layer_media_types == {"a": "application/vnd.oci.image.layer.v1.tar+zstd"}
+        assert loaded.freed_space_if_deleted(["environment:e1"]) == 5
+        assert loaded_usage == usage
+        assert metadata["registry_url"] == "http://test-registry"
+
+    def test_labels_are_redacted_on_load(self):
+        """Test that sensitive labels saved in an older snapshot are redacted when it is loaded"""
+        analyzer = ImageAnalyzer("http://test-registry", "test-repo")
+        analyzer.index.add_image("model:m1", "test-repo/model", "m1", "sha256:m1", [("a", 5)])
+        analyzer.labels["model:m1"] = {"owner": "data-science", "DB_PASSWORD": "hunter2"}
+
+        save_snapshot(analyzer, self.path)
+
+        loaded = load_snapshot(self.path)[0]
+        assert loaded.labels == {"model:m1": {"owner": "data-science", "DB_PASSWORD": "[REDACTED]"}}
+
+    def test_without_usage(self):
+        """Test that a snapshot saved without usage data loads with usage None"""
+        analyzer = ImageAnalyzer("http://test-registry", "test-repo")
+
+        save_snapshot(analyzer, self.path)
+
+        assert load_snapshot(self.path)[1] is None
+
+    def test_unsupported_version(self):
+        """Test that snapshots of another format version are rejected"""
+        with open(self.path, "w") as f:
+            json.dump({"format_version": 99}, f)
+
+        with pytest.raises(SnapshotFormatError, match="format_version"):
+            load_snapshot(self.path)
+
+
+class TestSnapshotRetention:
+    """Tests for pruning the snapshot history"""
+
+    def test_select_snapshots_to_prune(self):
+        """Test keep_last, weekly and monthly retention, and that the newest snapshot is always kept"""
+        # One snapshot a day from 2026-03-31 back to 2026-01-01, newest first
+        snapshots = [(f"s{day}", datetime(2026, 1, 1) + timedelta(days=day)) for day in range(89, -1, -1)]
+
+        assert select_snapshots_to_prune(snapshots) == []
+        assert len(select_snapshots_to_prune(snapshots, keep_last=3)) == 87
+
+        pruned = select_snapshots_to_prune(snapshots, keep_last=2, keep_weekly=2, keep_monthly=3)
+        kept = [name for name, _ in snapshots if name not in pruned]
+        # Newest two (March 31 and 30); newest of the previous week (Sunday March 29); newest of February and January
+        assert kept == ["s89", "s88", "s87", "s58", "s30"]
+
+        assert select_snapshots_to_prune(snapshots[:1], keep_monthly=0, keep_weekly=1) == []
+
+    def test_prune_snapshots(self, tmp_path):
+        """Test that only timestamped snapshots of the configured name are deleted"""
+        for name in (
+            "scan-snapshot-2026-03-01-02-00-00.json",
+            "scan-snapshot-2026-03-02-02-00-00.json",
+            "scan-snapshot-2026-03-03-02-00-00.json",
+            "scan-snapshot.json",
+            "scan-snapshot-manual.json",
+            "other-2026-03-01-02-00-00.json",
+        ):
+            (tmp_path / name).write_text("{}")
+
+        assert [path.name for path, _ in find_snapshots(str(tmp_path / "scan-snapshot.json"))] == [
+            "scan-snapshot-2026-03-03-02-00-00.json",
+            "scan-snapshot-2026-03-02-02-00-00.json",
+            "scan-snapshot-2026-03-01-02-00-00.json",
+        ]
+
+        retention = {"keep_last": 1, "keep_weekly": 0, "keep_monthly": 0}
+        deleted = prune_snapshots(str(tmp_path / "scan-snapshot.json"), retention)
+
+        assert sorted(os.path.basename(path) for path in deleted) == [
+            "scan-snapshot-2026-03-01-02-00-00.json",
+            "scan-snapshot-2026-03-02-02-00-00.json",
+        ]
+        assert sorted(path.name for path in tmp_path.iterdir()) == [
+            "other-2026-03-01-02-00-00.json",
+            "scan-snapshot-2026-03-03-02-00-00.json",
+            "scan-snapshot-manual.json",
+            "scan-snapshot.json",
+        ]
@@ -0,0 +1,115 @@
+"""Unit tests for utils/shell_completion.py"""
+
+import json
+import os
+import sys
+
+import pytest
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.shell_completion import collect_scripts, generate_completion, list_cached, script_options
+
+SCRIPT_SOURCE = '''
+import argparse
+
+def parse_arguments():
+    parser = argparse.ArgumentParser()
+    subparsers = parser.add_subparsers(dest="command")
+    check = subparsers.add_parser("check", help="Check things")
+    check.add_argument("--strict", action="store_true", help="Fail on warnings. Useful in CI")
+    check.add_argument("-o", "--output", help="Output file (default: %(default)s)")
+    check.add_argument("image", nargs="?")
+    run = subparsers.add_parser("run")
+    run.add_argument("--output", help="Output file")
+    return parser.parse_args()
+'''
+
+
+@pytest.fixture
+def script_dir(tmp_path):
+    """A python directory with one argparse script"""
+    (tmp_path / "scripts").mkdir()
+    (tmp_path / "scripts" / "tool.py").write_text(SCRIPT_SOURCE)
+    return tmp_path
+
+
+class TestScriptOptions:
+    """Tests for reading argparse definitions from script source"""
+
+    def test_options_and_subcommands(self, script_dir):
+        """Test that flags, value-taking and help are read and repeated flags are listed once"""
+        completion = script_options(str(script_dir / "scripts" / "tool.py"))
+
+        assert completion.subcommands == ["check", "run"]
+        assert completion.flags == ["--strict", "-o", "--output"]
+        strict, output = completion.options
+        assert strict.takes_value is False
+        assert strict.help == "Fail on warnings"
+        assert output.takes_value is True
+        assert output.help == "Output file"
+
+    def test_collect_scripts(self, script_dir):
+        """Test that built-in keywords without a script are still completed"""
+        scripts = collect_scripts(
+            {"tool": "scripts/tool.py", "completion": None, "health_check": None},
+            {"tool": "A tool"},
+            str(script_dir),
+        )
+
+        by_name = {script.name: script for script in scripts}
+        assert [script.name for script in scripts] == ["completion", "health_check", "tool"]
+        assert by_name["completion"].subcommands == ["bash", "zsh", "fish"]
+        assert by_name["health_check"].flags == []
+        assert by_name["tool"].description == "A tool"
+
+
+class TestListCached:
+    """Tests for completing repositories and tags from the inspect cache"""
+
+    def test_lists_from_cache(self, tmp_path):
+        """Test the three forms of cached names"""
+        cache_path = tmp_path / "inspect-cache.json"
+        cache_path.write_text(
+            json.dumps(
+                {
+                    "format_version": 1,
+                    "digests": {},
+                    "tags": {"domino/environment:abc-1": "sha256:a", "domino/model:m-2": "sha256:b"},
+                    "created": {},
+                }
+            )
+        )
+
+        assert list_cached("repositories", str(cache_path)) == ["environment", "model"]
+        assert list_cached("images", str(cache_path)) == ["environment:abc-1", "model:m-2"]
+        assert list_cached("references", str(cache_path)) == ["domino/environment:abc-1", "domino/model:m-2"]
+
+    def test_missing_cache_lists_nothing(self, tmp_path):
+        """Test that completion without a cache is empty rather than an error"""
+        assert list_cached("images", str(tmp_path / "missing.json")) == []
+        with pytest.raises(ValueError, match="Unknown list kind"):
+            list_cached("digests", str(tmp_path / "missing.json"))
+
+
+class TestGenerateCompletion:
+    """Tests for generating completion scripts"""
+
+    @pytest.mark.parametrize("shell", ["bash", "zsh", "fish"])
+    def test_scripts_and_flags_included(self, script_dir, shell):
+        """Test that each shell's script covers keywords, subcommands, flags and dynamic image completion"""
+        scripts = collect_scripts({"simulate_deletion": "scripts/tool.py"}, {}, str(script_dir))
+
+        source = generate_completion(shell, scripts, ["--config"])
+
+        assert "simulate_deletion" in source
+        assert "check" in source
+        assert "strict" in source
+        assert "config" in source
+        assert "completion --list" in source
+        assert "images" in source
+
+    def test_unsupported_shell(self):
+        """Test that an unknown shell is rejected"""
+        with pytest.raises(ValueError, match="Unsupported shell"):
+            generate_completion("powershell", [], [])
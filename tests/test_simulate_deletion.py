@@ -0,0 +1,62 @@
+"""Unit tests for scripts/simulate_deletion.py"""
+
+import json
+import os
+import sys
+from unittest.mock import MagicMock, patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from scripts.simulate_deletion import main, simulate_candidates
+from tests.helpers import add_image, make_analyzer
+from utils.image_data_analysis import ImageAnalyzer
+
+
+def _analyzer() -> ImageAnalyzer:
+    """Two candidates sharing a layer no survivor uses, and a survivor sharing their base layer"""
+    analyzer = make_analyzer()
+    add_image(analyzer, "environment:c1", [("base", 5000), ("shared", 3000), ("c1-a", 1000)])
+    add_image(analyzer, "environment:c2", [("base", 5000), ("shared", 3000), ("c2-a", 500)])
+    add_image(analyzer, "environment:kept", [("base", 5000)])
+    return analyzer
+
+
+class TestSimulateCandidates:
+    """Tests for simulating the deletion of a batch of candidates"""
+
+    def test_layers_shared_between_candidates_freed_together(self):
+        """Test that a layer only candidates share is freed once, unlike in either naive total"""
+        result = simulate_candidates(_analyzer(), ["environment:c1", "environment:c2"])
+
+        summary = result["summary"]
+        assert summary["combined_freed_bytes"] == 3000 + 1000 + 500
+        assert summary["sum_of_individual_freed_bytes"] == 1000 + 500
+        assert summary["sum_of_image_sizes_bytes"] == 9000 + 8500
+        assert summary["freed_only_together_bytes"] == 3000
+        assert sorted(layer["layer_id"] for layer in result["combined"]["freed_layers"]) == ["c1-a", "c2-a", "shared"]
+        assert [c["image_id"] for c in result["candidates"]] == ["environment:c1", "environment:c2"]
+        assert [layer["layer_id"] for layer in result["combined"]["retained_layers"]] == ["base"]
+
+    def test_input_file_simulated_as_batch(self, tmp_path):
+        """Test that --input candidates are resolved once each, unknown ones skipped, and the batch result saved"""
+        (tmp_path / "candidates.txt").write_text("environment:c1\nc2\nenvironment:missing\nc1\n")
+        analyzer = _analyzer()
+        analyzer.analyze_image = MagicMock(return_value=True)
+        argv = [
+            "simulate_deletion.py",
+            "--input",
+            str(tmp_path / "candidates.txt"),
+            "--image-types",
+            "environment",
+            "--output",
+            str(tmp_path / "what-if.json"),
+        ]
+
+        with patch.object(sys, "argv", argv), patch("scripts.simulate_deletion.ImageAnalyzer", return_value=analyzer):
+            main()
+
+        saved = json.loads((tmp_path / "what-if.json").read_text())
+        assert saved["summary"]["candidates"] == 2
+        assert saved["summary"]["combined_freed_bytes"] == 4500
+        assert saved["summary"]["sum_of_image_sizes_bytes"] == 9000 + 8500
+        assert saved["summary"]["freed_only_together_bytes"] == 3000
@@ -0,0 +1,75 @@
+"""Unit tests for size_breakdown.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.size_breakdown import break_down_image, layer_commands
+
+MB = 1000**2
+
+HISTORY = [
+    {"created_by": "/bin/sh -c #(nop) ADD file:abc in / ", "empty_layer": False},
+    {"created_by": "/bin/sh -c #(nop)  ENV LANG=C.UTF-8", "empty_layer": True},
+    {"created_by": "/bin/sh -c apt-get update && apt-get install -y build-essential", "empty_layer": False},
+    {"created_by": "RUN /bin/sh -c pip install -r requirements.txt # buildkit", "empty_layer": False},
+    {"created_by": "/bin/sh -c rm -rf /root/.cache", "empty_layer": False},
+]
+
+LAYERS = [("sha256:base", 30 * MB), ("sha256:apt", 200 * MB), ("sha256:pip", 700 * MB), ("sha256:rm", 1000)]
+
+
+class TestLayerCommands:
+    """Tests for matching build steps to layers"""
+
+    def test_metadata_steps_skipped(self):
+        """Test that steps without a layer are skipped and a mismatched history gives nothing"""
+        steps = layer_commands(HISTORY, 4)
+
+        assert [step["created_by"][:20] for step in steps] == [
+            "/bin/sh -c #(nop) AD",
+            "/bin/sh -c apt-get u",
+            "RUN /bin/sh -c pip i",
+            "/bin/sh -c rm -rf /r",
+        ]
+        assert layer_commands(HISTORY, 5) is None
+
+
+class TestBreakDownImage:
+    """Tests for size breakdowns and their suggestions"""
+
+    def test_layers_and_sharing(self):
+        """Test that each layer gets its share, sharing and cleaned-up build step"""
+        breakdown = break_down_image(
+            "environment:env1", "repo/environment", "env1", "sha256:image", LAYERS, HISTORY, {"sha256:base": ["x"]}
+        )
+
+        assert [layer["shared_with"] for layer in breakdown["layers"]] == [1, 0, 0, 0]
+        assert breakdown["layers"][0]["command"] == "ADD file:abc in /"
+        assert breakdown["layers"][1]["command"].startswith("RUN apt-get update")
+        assert breakdown["layers"][2]["percent"] == 75.3
+        assert breakdown["unique_bytes"] == breakdown["total_bytes"] - 30 * MB
+        assert breakdown["history_matched"]
+
+    def test_suggestions(self):
+        """Test that uncleaned package caches, large unshared layers and late deletions are suggested, largest first"""
+        breakdown = break_down_image(
+            "environment:env1", "repo/environment", "env1", "sha256:image", LAYERS, HISTORY, {}
+        )
+
+        suggestions = breakdown["suggestions"]
+        assert "Layer 2" in suggestions[0] and "--no-cache-dir" in suggestions[0]
+        assert "Layer 2" in suggestions[1] and "shared base image" in suggestions[1]
+        assert "Layer 1" in suggestions[2] and "/var/lib/apt/lists" in suggestions[2]
+        assert "Layer 3" in suggestions[3] and "does not make the image smaller" in suggestions[3]
+        assert len(suggestions) == 4
+
+    def test_without_history_or_sharing(self):
+        """Test that a breakdown without deep mode or scan data has no commands and unknown sharing"""
+        breakdown = break_down_image("model:m1", "repo/model", "m1", "sha256:image", LAYERS)
+
+        assert breakdown["unique_bytes"] is None
+        assert all(layer["command"] is None and layer["shared_with"] is None for layer in breakdown["layers"])
+        assert not breakdown["history_matched"]
+        assert breakdown["suggestions"] == []
@@ -109,6 +109,7 @@ class TestSkopeoClientInitialization:
         mock.get_domino_platform_namespace.return_value = "domino-platform"
         mock.get_output_dir.return_value = "/tmp/output"
         mock.auth_file = "/tmp/.registry-auth.json"
+        mock.get_credential_profile.return_value = None
         mock.get_skopeo_rate_limit_enabled.return_value = True
         mock.get_skopeo_rate_limit_rps.return_value = 10.0
         mock.get_skopeo_rate_limit_burst.return_value = 20
@@ -174,6 +175,7 @@ class TestSkopeoClientCredentials:
         mock.get_domino_platform_namespace.return_value = "domino-platform"
         mock.get_output_dir.return_value = "/tmp/output"
         mock.auth_file = "/tmp/.registry-auth.json"
+        mock.get_credential_profile.return_value = None
         mock.get_skopeo_rate_limit_enabled.return_value = False
         mock.get_max_retries.return_value = 3
         mock.get_retry_initial_delay.return_value = 1.0
@@ -255,6 +257,7 @@ class TestSkopeoClientOperations:
         mock_config.get_domino_platform_namespace.return_value = "domino-platform"
         mock_config.get_output_dir.return_value = "/tmp/output"
         mock_config.auth_file = "/tmp/.registry-auth.json"
+        mock_config.get_credential_profile.return_value = None
         mock_config.get_skopeo_rate_limit_enabled.return_value = False
         mock_config.get_max_retries.return_value = 3
         mock_config.get_retry_initial_delay.return_value = 1.0
@@ -262,6 +265,7 @@ class TestSkopeoClientOperations:
         mock_config.get_retry_exponential_base.return_value = 2.0
         mock_config.get_retry_jitter.return_value = True
         mock_config.get_retry_timeout.return_value = 300
+        mock_config.get_notary_url.return_value = None
 
         with patch("utils.skopeo_client.get_credentials_from_k8s_secret", return_value=("user", "pass")):
             with patch.object(SkopeoClient, "_ensure_logged_in"):
@@ -337,6 +341,84 @@ class TestSkopeoClientOperations:
 
             assert result is None
 
+    def test_inspect_image_malformed_output(self, skopeo_client):
+        """Test that malformed inspect output gives None and is reported with skopeo's stderr"""
+        with patch("subprocess.run") as mock_run, patch("utils.skopeo_output.logging.error") as error:
+            mock_run.return_value = MagicMock(stdout='{"Digest": "sha256:abc', stderr="unexpected EOF")
+            result = skopeo_client.inspect_image(None, "truncated")
+
+            assert result is None
+            message = error.call_args[0][0]
+            assert "myrepo:truncated" in message
+            assert "unexpected EOF" in message
+
+    def test_list_tags_null_tags(self, skopeo_client):
+        """Test tag listing when the registry reports null instead of a list"""
+        with patch("subprocess.run") as mock_run:
+            mock_run.return_value = MagicMock(stdout='{"Repository": "x", "Tags": null}')
+            tags = skopeo_client.list_tags(repository="test-repo-null-tags")
+
+            assert tags == []
+
+    def test_get_image_digest_success(self, skopeo_client):
+        """Test getting the current digest of a tag"""
+        inspect_response = {"Digest": "sha256:abc123", "Layers": ["layer1"]}
+
+        with patch("subprocess.run") as mock_run:
+            mock_run.return_value = MagicMock(stdout=json.dumps(inspect_response))
+            digest = skopeo_client.get_image_digest(None, "v1.0")
+
+            assert digest == "sha256:abc123"
+
+    def test_get_image_digest_not_found(self, skopeo_client):
+        """Test getting the digest of a tag that does not exist"""
+        with patch("subprocess.run") as mock_run:
+            mock_run.side_effect = subprocess.CalledProcessError(1, "skopeo", stderr="manifest unknown")
+            digest = skopeo_client.get_image_digest(None, "nonexistent")
+
+            assert digest is None
+
+    def test_get_manifest_computes_digest(self, skopeo_client):
+        """Test that the raw manifest is fetched and its sha256 digest computed"""
+        import hashlib
+
+        raw = json.dumps({"schemaVersion": 2, "layers": [{"digest": "sha256:l1", "size": 10}]})
+
+        with patch("subprocess.run") as mock_run:
+            mock_run.return_value = MagicMock(stdout=raw)
+            digest, manifest = skopeo_client.get_manifest(None, "v1.0")
+
+            assert digest == "sha256:" + hashlib.sha256(raw.encode("utf-8")).hexdigest()
+            assert manifest["layers"][0]["size"] == 10
+            assert "--raw" in mock_run.call_args[0][0]
+
+    def test_get_manifest_digest_uses_head_request(self, skopeo_client):
+        """Test that the digest comes from a manifest HEAD request without running skopeo"""
+        with patch("utils.skopeo_client.RegistryHttpClient.head_manifest_digest", return_value="sha256:head"):
+            with patch("subprocess.run") as mock_run:
+                digest = skopeo_client.get_manifest_digest(None, "v1.0")
+
+                assert digest == "sha256:head"
+                mock_run.assert_not_called()
+
+    def test_get_manifest_digest_falls_back_to_skopeo(self, skopeo_client):
+        """Test that an unreachable registry API falls back to skopeo and disables HEAD requests"""
+        import hashlib
+        import urllib.error
+
+        raw = json.dumps({"schemaVersion": 2, "layers": []})
+        head = MagicMock(side_effect=urllib.error.URLError("connection refused"))
+
+        with patch("utils.skopeo_client.RegistryHttpClient.head_manifest_digest", head):
+            with patch("subprocess.run") as mock_run:
+                mock_run.return_value = MagicMock(stdout=raw)
+                digest = skopeo_client.get_manifest_digest(None, "v1.0")
+                skopeo_client.get_manifest_digest(None, "v1.1")
+
+                assert digest == "sha256:" + hashlib.sha256(raw.encode("utf-8")).hexdigest()
+                assert head.call_count == 1
+                assert mock_run.call_count == 2
+
     def test_delete_image_success(self, skopeo_client):
         """Test successful image deletion"""
         with patch("subprocess.run") as mock_run:
@@ -355,6 +437,89 @@ class TestSkopeoClientOperations:
 
             assert result is False
 
+    def test_delete_signed_tag(self, skopeo_client):
+        """Test that tags signed with Docker Content Trust are refused unless forced or configured to warn"""
+        skopeo_client.config_manager.get_notary_url.return_value = "https://notary.example.com"
+        skopeo_client.config_manager.get_signed_tag_action.return_value = "refuse"
+        targets = {"signed": {"targets": {"v1.0": {"length": 1}}}}
+
+        with patch("utils.content_trust.RegistryHttpClient.get_json", return_value=targets) as get_json:
+            with patch("subprocess.run") as mock_run:
+                mock_run.return_value = MagicMock(stdout="")
+
+                assert skopeo_client.signed_tag_refusal(None, "v1.0") == "it is signed with Docker Content Trust"
+                assert skopeo_client.delete_image(None, "v1.0") is False
+                assert mock_run.call_count == 0
+                assert get_json.call_args[0][0] == "/v2/registry.example.com:5000/myrepo/_trust/tuf/targets.json"
+
+                assert skopeo_client.delete_image(None, "v2.0") is True
+
+                skopeo_client.allow_signed_deletion = True
+                assert skopeo_client.delete_image(None, "v1.0") is True
+
+                skopeo_client.allow_signed_deletion = False
+                skopeo_client.config_manager.get_signed_tag_action.return_value = "warn"
+                assert skopeo_client.delete_image(None, "v1.0") is True
+                assert mock_run.call_count == 3
+
+    def test_delete_manifest(self, skopeo_client):
+        """Test deleting a manifest by digest, refused if any of its tags is signed"""
+        digest = "sha256:" + "a" * 64
+        with patch("subprocess.run") as mock_run:
+            mock_run.return_value = MagicMock(stdout="")
+            assert skopeo_client.delete_manifest(None, digest, ["v1.0", "latest"]) is True
+            assert f"docker://registry.example.com:5000/myrepo@{digest}" in mock_run.call_args[0][0]
+
+            skopeo_client.config_manager.get_notary_url.return_value = "https://notary.example.com"
+            skopeo_client.config_manager.get_signed_tag_action.return_value = "refuse"
+            targets = {"signed": {"targets": {"latest": {"length": 1}}}}
+            with patch("utils.content_trust.RegistryHttpClient.get_json", return_value=targets):
+                assert skopeo_client.delete_manifest(None, digest, ["v1.0", "latest"]) is False
+            assert mock_run.call_count == 1
+
+
+class TestSkopeoClientNative:
+    """Tests for SkopeoClient requests over HTTP (skopeo.client: native)"""
+
+    @pytest.fixture
+    def skopeo_client(self):
+        """Create a native SkopeoClient with mocked dependencies"""
+        from utils.skopeo_client import SkopeoClient
+
+        mock_config = MagicMock()
+        mock_config.get_registry_url.return_value = "registry.example.com:5000"
+        mock_config.get_repository.return_value = "myrepo"
+        mock_config.get_domino_platform_namespace.return_value = "domino-platform"
+        mock_config.get_output_dir.return_value = "/tmp/output"
+        mock_config.auth_file = "/tmp/.registry-auth.json"
+        mock_config.get_credential_profile.return_value = None
+        mock_config.get_registry_client.return_value = "native"
+        mock_config.get_skopeo_rate_limit_enabled.return_value = False
+        mock_config.get_max_retries.return_value = 0
+        mock_config.get_retry_initial_delay.return_value = 0.0
+        mock_config.get_retry_max_delay.return_value = 0.0
+        mock_config.get_retry_exponential_base.return_value = 2.0
+        mock_config.get_retry_jitter.return_value = False
+        mock_config.get_notary_url.return_value = None
+
+        with patch("utils.skopeo_client.get_credentials_from_k8s_secret", return_value=("user", "pass")):
+            return SkopeoClient(mock_config)
+
+    def test_requests_do_not_run_skopeo(self, skopeo_client):
+        """Test that native clients neither log in with nor run skopeo, and report missing manifests"""
+        from utils.error_utils import ERROR_NOT_FOUND
+
+        with patch("subprocess.run") as mock_run:
+            with patch("utils.registry_http.RegistryHttpClient.get_manifest", return_value=(b'{"layers": []}', "")):
+                digest, manifest = skopeo_client.get_manifest("myrepo", "v1")
+            with patch("utils.registry_http.RegistryHttpClient.delete_manifest", return_value=False):
+                assert skopeo_client.delete_manifest("myrepo", "sha256:gone") is False
+
+        assert skopeo_client._logged_in is True
+        mock_run.assert_not_called()
+        assert digest.startswith("sha256:") and manifest == {"layers": []}
+        assert skopeo_client.last_error_code() == ERROR_NOT_FOUND
+
 
 class TestSkopeoClientRateLimiting:
     """Tests for SkopeoClient rate limiting"""
@@ -370,6 +535,7 @@ class TestSkopeoClientRateLimiting:
         mock_config.get_domino_platform_namespace.return_value = "domino-platform"
         mock_config.get_output_dir.return_value = "/tmp/output"
         mock_config.auth_file = "/tmp/.registry-auth.json"
+        mock_config.get_credential_profile.return_value = None
         mock_config.get_skopeo_rate_limit_enabled.return_value = True
         mock_config.get_skopeo_rate_limit_rps.return_value = 10.0
         mock_config.get_skopeo_rate_limit_burst.return_value = 5
@@ -426,6 +592,7 @@ class TestSkopeoClientRegistryDeletion:
         mock_config.get_domino_platform_namespace.return_value = "domino-platform"
         mock_config.get_output_dir.return_value = "/tmp/output"
         mock_config.auth_file = "/tmp/.registry-auth.json"
+        mock_config.get_credential_profile.return_value = None
         mock_config.get_skopeo_rate_limit_enabled.return_value = False
         mock_config.get_max_retries.return_value = 3
         mock_config.get_retry_initial_delay.return_value = 1.0
@@ -516,6 +683,7 @@ class TestSkopeoClientLogin:
         mock.get_domino_platform_namespace.return_value = "domino-platform"
         mock.get_output_dir.return_value = "/tmp/output"
         mock.auth_file = "/tmp/.registry-auth.json"
+        mock.get_credential_profile.return_value = None
         mock.get_skopeo_rate_limit_enabled.return_value = False
         mock.get_max_retries.return_value = 3
         mock.get_retry_initial_delay.return_value = 1.0
@@ -582,6 +750,7 @@ class TestSkopeoClientCommandBuilding:
         mock_config.get_domino_platform_namespace.return_value = "domino-platform"
         mock_config.get_output_dir.return_value = "/tmp/output"
         mock_config.auth_file = "/tmp/.registry-auth.json"
+        mock_config.get_credential_profile.return_value = None
         mock_config.get_skopeo_rate_limit_enabled.return_value = False
         mock_config.get_max_retries.return_value = 3
         mock_config.get_retry_initial_delay.return_value = 1.0
@@ -0,0 +1,57 @@
+"""Unit tests for skopeo_output.py"""
+
+import os
+import sys
+from unittest.mock import patch
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.skopeo_output import json_document, normalize_inspect, parse_skopeo_json
+
+
+class TestSkopeoOutput:
+    """Tests for decoding skopeo output"""
+
+    def test_noise_around_document_is_skipped(self):
+        """Test that warnings printed before the document and text after it are separated from the JSON"""
+        output = 'time="2025-07-01" level=warning msg="{not json}"\n{"Tags": ["a", "b"]}\ndone\n'
+
+        document, noise = json_document(output)
+
+        assert document == '{"Tags": ["a", "b"]}'
+        assert noise.startswith("time=") and noise.endswith("done")
+        assert parse_skopeo_json(output, "tags of repo") == {"Tags": ["a", "b"]}
+        assert json_document('{"a": 1}\n') == ('{"a": 1}', "")
+
+    def test_unusable_output_is_reported(self):
+        """Test that malformed, empty and wrongly typed output gives None and a message with stderr"""
+        with patch("utils.skopeo_output.logging.error") as error:
+            assert parse_skopeo_json('{"Digest": "sha256:ab', "image inspection", stderr="EOF reading body") is None
+            assert parse_skopeo_json("", "image inspection") is None
+            assert parse_skopeo_json("null", "image inspection") is None
+
+        messages = [call.args[0] for call in error.call_args_list]
+        assert "Failed to parse image inspection" in messages[0]
+        assert '{"Digest": "sha256:ab' in messages[0]
+        assert "skopeo stderr: EOF reading body" in messages[0]
+        assert "no output" in messages[1]
+        assert "expected a JSON object, got null" in messages[2]
+
+    def test_normalize_inspect(self):
+        """Test that fields of the wrong type are dropped, sizes are coerced, and unknown fields are kept"""
+        data = {
+            "Digest": None,
+            "Labels": None,
+            "LayersData": [{"Digest": "sha256:a", "Size": "42"}, "garbage", {"Digest": "sha256:b", "Size": -1}],
+            "NewField": {"x": 1},
+        }
+
+        normalized = normalize_inspect(data, "image inspection")
+
+        assert normalized == {
+            "LayersData": [{"Digest": "sha256:a", "Size": 42}, {"Digest": "sha256:b", "Size": -1}],
+            "NewField": {"x": 1},
+        }
+        assert data["LayersData"][0]["Size"] == "42"
+        well_formed = {"Digest": "sha256:c", "LayersData": [{"Digest": "sha256:a", "Size": 1}]}
+        assert normalize_inspect(well_formed, "image inspection") == well_formed
@@ -0,0 +1,39 @@
+"""Unit tests for statsd_metrics.py"""
+
+import os
+import socket
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.statsd_metrics import StatsdClient
+
+
+class TestStatsdClient:
+    """Tests for formatting and sending StatsD metrics"""
+
+    def test_format(self):
+        """Test metric lines with DogStatsD tags, and without them for plain StatsD"""
+        client = StatsdClient("localhost", tags={"registry": "registry.example.com"})
+        plain = StatsdClient("localhost", prefix="", tags={"registry": "registry.example.com"}, dogstatsd=False)
+
+        assert client.format("bytes_total", 1234567890123, "g", {"command": "plan"}) == (
+            "registry_cleaner.bytes_total:1234567890123|g|#command:plan,registry:registry.example.com"
+        )
+        assert client.format("run.duration", 1520.25, "ms") == (
+            "registry_cleaner.run.duration:1520.250|ms|#registry:registry.example.com"
+        )
+        assert plain.format("deletes_performed", 3, "c", {"command": "apply"}) == "deletes_performed:3|c"
+
+    def test_send(self):
+        """Test that metrics arrive over UDP and that an unreachable server is ignored"""
+        server = socket.socket(socket.AF_INET, socket.SOCK_DGRAM)
+        server.bind(("127.0.0.1", 0))
+        server.settimeout(5)
+        client = StatsdClient("127.0.0.1", server.getsockname()[1])
+
+        client.count("deletes_performed", 2)
+
+        assert server.recv(1024) == b"registry_cleaner.deletes_performed:2|c"
+        server.close()
+        StatsdClient("unresolvable.invalid", 8125).gauge("tags_scanned", 1)
@@ -0,0 +1,60 @@
+"""Unit tests for synthetic_registry.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.cache_utils import DigestInspectCache
+from utils.image_data_analysis import ImageAnalyzer
+from utils.synthetic_registry import SyntheticSkopeoClient, generate_dataset, sha256_digest
+from utils.tag_matching import parse_environment_tag
+
+NOW = datetime(2025, 7, 1, tzinfo=timezone.utc)
+
+
+class TestSyntheticRegistry:
+    """Tests for generating and serving synthetic registries"""
+
+    def test_same_seed_same_dataset(self):
+        """Test that a seed always gives the same tags and digests, and another seed different ones"""
+        first = generate_dataset(5, 12, seed=7, now=NOW)
+        second = generate_dataset(5, 12, seed=7, now=NOW)
+        other = generate_dataset(5, 12, seed=8, now=NOW)
+
+        digests = [(image.tag, image.digest) for image in first.images()]
+        assert digests == [(image.tag, image.digest) for image in second.images()]
+        assert digests != [(image.tag, image.digest) for image in other.images()]
+        assert len(digests) == 60
+        assert all(parse_environment_tag(tag) for tag, _ in digests)
+
+    def test_layers_are_shared(self):
+        """Test that base and dependency layers are shared, so far less is stored than referenced"""
+        dataset = generate_dataset(10, 20, seed=1, base_images=2, now=NOW)
+        stats = dataset.stats()
+
+        assert stats["repositories"] == 10
+        assert stats["tags"] == 200
+        assert stats["manifests"] <= stats["tags"]
+        assert stats["stored_bytes"] < stats["logical_bytes"] / 2
+        for image in dataset.images():
+            # Placeholder blobs are what the digests address
+            assert all(layer.digest == sha256_digest(layer.content) for layer in image.layers)
+
+    def test_analyzer_scans_synthetic_client(self):
+        """Test that the analyzer scans a dataset served in memory like a registry"""
+        dataset = generate_dataset(3, 10, seed=2, now=NOW)
+        client = SyntheticSkopeoClient(dataset, "bench")
+        analyzer = ImageAnalyzer("synthetic", "bench", skopeo_client=client)
+        analyzer.inspect_cache = DigestInspectCache(None)
+
+        for name in dataset.repositories:
+            assert analyzer.analyze_image(name, max_workers=2)
+
+        stats = dataset.stats()
+        assert len(analyzer.images) == stats["tags"]
+        assert len(analyzer.layers) == stats["layers"]
+        assert sum(layer["size_bytes"] for layer in analyzer.layers.values()) == stats["stored_bytes"]
+        image = dataset.repositories["repo-001"][0]
+        assert analyzer.images[f"repo-001:{image.tag}"]["digest"] == image.digest
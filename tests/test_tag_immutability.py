@@ -0,0 +1,112 @@
+"""Unit tests for utils/tag_immutability.py"""
+
+import os
+import sys
+from unittest.mock import MagicMock
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.tag_immutability import (
+    IMMUTABLE,
+    MUTABLE,
+    PARTIAL,
+    audit_repositories,
+    ecr_tag_immutability,
+    harbor_rule_coverage,
+    harbor_tag_immutability,
+    summarize_audits,
+    tag_digest_history,
+)
+
+
+def snapshot(*images):
+    """Snapshot document with (repository, tag, digest) images"""
+    return {
+        "images": {
+            f"{repo}:{tag}": {"repository": repo, "tag": tag, "digest": digest} for repo, tag, digest in images
+        }
+    }
+
+
+class TestImmutabilitySettings:
+    """Tests for reading ECR and Harbor immutability settings"""
+
+    def test_ecr(self):
+        """Test that ECR mutability is mapped, and that repositories that cannot be described are left out"""
+        ecr = MagicMock()
+        responses = {
+            "env": {"repositories": [{"repositoryName": "env", "imageTagMutability": "IMMUTABLE"}]},
+            "model": {"repositories": [{"repositoryName": "model", "imageTagMutability": "MUTABLE"}]},
+            "base": {
+                "repositories": [{"repositoryName": "base", "imageTagMutability": "IMMUTABLE_WITH_EXCLUSION"}]
+            },
+        }
+
+        def describe(repositoryNames):
+            if repositoryNames[0] not in responses:
+                raise Exception("RepositoryNotFoundException")
+            return responses[repositoryNames[0]]
+
+        ecr.describe_repositories.side_effect = describe
+
+        assert ecr_tag_immutability(ecr, ["env", "model", "base", "missing"]) == {
+            "env": IMMUTABLE,
+            "model": MUTABLE,
+            "base": PARTIAL,
+        }
+
+    def test_harbor_rules(self):
+        """Test Harbor rules by repository scope and tag pattern, ignoring disabled rules"""
+        all_tags = {"decoration": "matches", "pattern": "**", "kind": "doublestar"}
+        rules = [
+            {
+                "scope_selectors": {"repository": [{"decoration": "repoMatches", "pattern": "{environment,model}"}]},
+                "tag_selectors": [all_tags],
+            },
+            {
+                "scope_selectors": {"repository": [{"decoration": "repoMatches", "pattern": "**"}]},
+                "tag_selectors": [{"decoration": "matches", "pattern": "release-*"}],
+            },
+            {"disabled": True, "scope_selectors": {"repository": []}, "tag_selectors": [all_tags]},
+        ]
+
+        assert harbor_rule_coverage(rules, "environment") == IMMUTABLE
+        assert harbor_rule_coverage(rules, "base") == PARTIAL
+        assert harbor_rule_coverage([], "base") == MUTABLE
+
+        paths = []
+
+        def get_json(path):
+            paths.append(path)
+            return rules if "dominodatalab" in path else None
+
+        settings = harbor_tag_immutability(get_json, ["dominodatalab/environment", "dominodatalab/base", "other/x"])
+
+        assert settings == {"dominodatalab/environment": IMMUTABLE, "dominodatalab/base": PARTIAL}
+        assert len(paths) == 2
+
+
+class TestDigestDrift:
+    """Tests for cross-checking settings with digest drift"""
+
+    def test_audit(self):
+        """Test that re-pushed tags are found and classified by their repository's setting"""
+        history = tag_digest_history(
+            [
+                snapshot(("env", "v1", "sha256:a"), ("model", "m1", "sha256:m"), ("base", "latest", "sha256:1")),
+                snapshot(("env", "v1", "sha256:a"), ("model", "m1", "sha256:n"), ("base", "latest", "sha256:1")),
+                snapshot(("env", "v1", "sha256:a"), ("model", "m1", "sha256:m"), ("base", "latest", "sha256:1")),
+            ]
+        )
+
+        assert history["model"]["m1"] == ["sha256:m", "sha256:n", "sha256:m"]
+
+        audits = audit_repositories(history, {"env": IMMUTABLE, "base": MUTABLE})
+
+        assert [(audit["repository"], audit["finding"]) for audit in audits] == [
+            ("model", "mutable_drift"),
+            ("base", "mutable"),
+            ("env", "immutable"),
+        ]
+        assert audit_repositories(history, {"model": IMMUTABLE})[0]["finding"] == "immutable_drift"
+        assert summarize_audits(audits)["ambiguous_repositories"] == 1
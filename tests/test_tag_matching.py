@@ -0,0 +1,49 @@
+"""Unit tests for utils/tag_matching.py"""
+
+import os
+import re
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.tag_matching import (
+    DEFAULT_EXCLUDED_TAG_PATTERNS,
+    DOMINO_TAG_PATTERN,
+    find_nonconforming_tags,
+    parse_environment_tag,
+)
+
+
+class TestFindNonconformingTags:
+    """Tests for the tag naming audit"""
+
+    def test_domino_tag_scheme(self):
+        """Test that Domino environment and model tags conform and hand-pushed tags do not"""
+        tags = [
+            "507f1f77bcf86cd799439011-3",
+            "507f1f77bcf86cd799439011-v2-1234567890_abc123",
+            "latest",
+            "507f1f77bcf86cd799439011",
+            "test-build",
+            "sha256-" + "a" * 64 + ".sig",
+        ]
+
+        nonconforming = find_nonconforming_tags(tags, re.compile(DOMINO_TAG_PATTERN), DEFAULT_EXCLUDED_TAG_PATTERNS)
+
+        assert nonconforming == ["507f1f77bcf86cd799439011", "latest", "test-build"]
+
+    def test_pattern_matches_in_full(self):
+        """Test that a custom pattern must match the whole tag"""
+        assert find_nonconforming_tags(["v1.2.3", "v1.2.3-rc1"], re.compile(r"v[0-9]+\.[0-9]+\.[0-9]+")) == [
+            "v1.2.3-rc1"
+        ]
+
+
+class TestParseEnvironmentTag:
+    """Tests for parsing Domino environment tags"""
+
+    def test_parse(self):
+        """Test that <environmentId>-<revision> tags are parsed and other tags are not"""
+        assert parse_environment_tag("507f1f77bcf86cd799439011-3") == ("507f1f77bcf86cd799439011", 3)
+        assert parse_environment_tag("507f1f77bcf86cd799439011-v2-1234567890_abc123") is None
+        assert parse_environment_tag("latest") is None
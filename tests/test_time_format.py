@@ -0,0 +1,40 @@
+"""Unit tests for time_format.py"""
+
+import os
+import sys
+from datetime import datetime, timezone
+from zoneinfo import ZoneInfo
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.time_format import age_days, format_age, format_timestamp
+
+NOW = datetime(2026, 1, 15, 12, 0, tzinfo=timezone.utc)
+
+
+class TestTimeFormat:
+    """Tests for rendering timestamps and ages"""
+
+    def test_age_days(self):
+        """Test whole days since ISO 8601 strings and datetimes, and unknown ages"""
+        assert age_days("2026-01-01T00:00:00Z", NOW) == 14
+        assert age_days(datetime(2026, 1, 15, 11, 0), NOW) == 0
+        assert age_days("not a time", NOW) is None
+        assert age_days(None, NOW) is None
+
+    def test_format_timestamp(self):
+        """Test rendering in a time zone, naive timestamps as UTC, and values that are not timestamps"""
+        berlin = ZoneInfo("Europe/Berlin")
+
+        assert format_timestamp("2025-07-01T10:30:00Z", berlin) == "2025-07-01 12:30 CEST"
+        assert format_timestamp("2025-01-01T10:30:00", berlin) == "2025-01-01 11:30 CET"
+        assert format_timestamp(None, berlin) == "unknown"
+        assert format_timestamp("yesterday", berlin) == "yesterday"
+        assert format_timestamp("2020-01-01T00:00:00Z", timezone.utc, with_age=True).endswith("days ago)")
+
+    def test_format_age(self):
+        """Test singular, plural, same-day and unknown ages"""
+        assert format_age(1) == "1 day ago"
+        assert format_age(412) == "412 days ago"
+        assert format_age(0) == "today"
+        assert format_age(None) == "age unknown"
@@ -0,0 +1,67 @@
+"""Unit tests for utils/toolchain.py"""
+
+import os
+import sys
+
+sys.path.insert(0, os.path.join(os.path.dirname(__file__), "..", "python"))
+
+from utils.toolchain import (
+    ANNOTATION_BASE_NAME,
+    DEFAULT_OUTDATED_BASE_IMAGES,
+    REASON_BASE_IMAGE,
+    REASON_DOCKER_VERSION,
+    base_image,
+    outdated_reasons,
+    parse_version,
+    toolchain_info,
+)
+
+
+class TestToolchainInfo:
+    """Tests for reading build fields and base images"""
+
+    def test_build_fields_from_inspection(self):
+        """Test that DockerVersion, Os and Architecture are kept and empty fields left out"""
+        info = toolchain_info({"DockerVersion": "1.13.1", "Os": "linux", "Architecture": "amd64", "Labels": {}})
+        assert info == {"docker_version": "1.13.1", "os": "linux", "architecture": "amd64"}
+        assert toolchain_info({"DockerVersion": "", "Os": "linux"}) == {"os": "linux"}
+
+    def test_base_image_prefers_annotation(self):
+        """Test that the base image annotation wins over the label of the same name"""
+        labels = {ANNOTATION_BASE_NAME: "ubuntu:20.04"}
+        annotations = {ANNOTATION_BASE_NAME: "docker.io/library/ubuntu:22.04"}
+
+        assert base_image(labels, annotations) == "docker.io/library/ubuntu:22.04"
+        assert base_image(labels, None) == "ubuntu:20.04"
+        assert base_image({"maintainer": "ml"}, {}) is None
+
+    def test_parse_version(self):
+        """Test Docker version strings with build suffixes"""
+        assert parse_version("18.09.7") == (18, 9, 7)
+        assert parse_version("20.10.21+dfsg1") == (20, 10, 21)
+        assert parse_version("1.13.1-rc1") == (1, 13, 1)
+        assert parse_version("dev") is None
+
+
+class TestOutdatedReasons:
+    """Tests for deciding whether a toolchain is outdated"""
+
+    def test_old_docker_version(self):
+        """Test that engines older than the minimum are outdated and unknown versions are not"""
+        assert outdated_reasons({"docker_version": "17.06.2-ce"}, None, "18.09", []) == [REASON_DOCKER_VERSION]
+        assert outdated_reasons({"docker_version": "18.09.0"}, None, "18.09", []) == []
+        assert outdated_reasons({"os": "linux"}, None, "18.09", []) == []
+        assert outdated_reasons(None, None, "18.09", []) == []
+
+    def test_outdated_base_images(self):
+        """Test that base image patterns match both full references and Docker Hub short names"""
+        patterns = DEFAULT_OUTDATED_BASE_IMAGES + ["*/legacy-base:*"]
+
+        assert outdated_reasons(None, "docker.io/library/ubuntu:16.04", "18.09", patterns) == [REASON_BASE_IMAGE]
+        assert outdated_reasons(None, "CentOS:7.9.2009", "18.09", patterns) == [REASON_BASE_IMAGE]
+        assert outdated_reasons(None, "quay.io/acme/legacy-base:3", "18.09", patterns) == [REASON_BASE_IMAGE]
+        assert outdated_reasons(None, "ubuntu:22.04", "18.09", patterns) == []
+        assert outdated_reasons({"docker_version": "1.12.6"}, "centos:7", "18.09", patterns) == [
+            REASON_DOCKER_VERSION,
+            REASON_BASE_IMAGE,
+        ]